@@ -0,0 +1,140 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	// AffinityEnv holds the json encoded placement affinity of a container
+	AffinityEnv = "_SHIPYARD_AFFINITY"
+)
+
+var (
+	ErrInvalidAffinity = errors.New("invalid affinity")
+)
+
+// Affinity places containers relative to the containers already running
+// on an engine.  Images are matched with or without tag.
+type Affinity struct {
+	// Images must each have a running container on the engine
+	Images []string `json:"images,omitempty"`
+	// AntiImages must not have a running container on the engine
+	AntiImages []string `json:"anti_images,omitempty"`
+	// Spread never places two replicas on one engine.  Replicas are the
+	// containers of the same application, or of the same image for
+	// containers outside applications.
+	Spread bool `json:"spread,omitempty"`
+	// SpreadBy is an engine label, such as zone, whose values replicas
+	// are balanced across.  Engines without the label form a zone of
+	// their own.
+	SpreadBy string `json:"spread_by,omitempty"`
+}
+
+func (a *Affinity) Validate() error {
+	if err := validateSpreadBy(a.SpreadBy); err != nil {
+		return err
+	}
+	anti := map[string]bool{}
+	for _, i := range a.AntiImages {
+		if i == "" {
+			return fmt.Errorf("%w: empty image name", ErrInvalidAffinity)
+		}
+		anti[i] = true
+	}
+	for _, i := range a.Images {
+		if i == "" {
+			return fmt.Errorf("%w: empty image name", ErrInvalidAffinity)
+		}
+		if anti[i] {
+			return fmt.Errorf("%w: %s is both required and excluded", ErrInvalidAffinity, i)
+		}
+	}
+	return nil
+}
+
+// Allows reports whether a container of image may be placed next to the
+// running containers of an engine
+func (a *Affinity) Allows(image *citadel.Image, containers []*citadel.Container) bool {
+	for _, i := range a.Images {
+		found := false
+		for _, c := range containers {
+			if ImageMatches(i, c) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, c := range containers {
+		for _, i := range a.AntiImages {
+			if ImageMatches(i, c) {
+				return false
+			}
+		}
+		if a.Spread && isReplica(image, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// ImageMatches reports whether a container runs the named image; names
+// without tag match every tag
+func ImageMatches(name string, c *citadel.Container) bool {
+	if c.Image == nil {
+		return false
+	}
+	return c.Image.Name == name || strings.HasPrefix(c.Image.Name, name+":")
+}
+
+func isReplica(image *citadel.Image, c *citadel.Container) bool {
+	if c.Image == nil {
+		return false
+	}
+	if app := image.Environment[ApplicationEnv]; app != "" {
+		return ApplicationName(c) == app
+	}
+	if ApplicationName(c) != "" {
+		return false
+	}
+	return *citadel.ParseImageName(image.Name) == *citadel.ParseImageName(c.Image.Name)
+}
+
+// ContainerAffinity returns the placement affinity of an image or nil if it
+// has none
+func ContainerAffinity(image *citadel.Image) (*Affinity, error) {
+	if image == nil || image.Environment[AffinityEnv] == "" {
+		return nil, nil
+	}
+	var a *Affinity
+	if err := json.Unmarshal([]byte(image.Environment[AffinityEnv]), &a); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAffinity, err)
+	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// SetAffinity sets the placement affinity of containers of the image
+func SetAffinity(image *citadel.Image, a *Affinity) error {
+	if err := a.Validate(); err != nil {
+		return err
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	if image.Environment == nil {
+		image.Environment = map[string]string{}
+	}
+	image.Environment[AffinityEnv] = string(b)
+	return nil
+}
@@ -0,0 +1,57 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestAffinityAllows(t *testing.T) {
+	redis := &citadel.Container{Image: &citadel.Image{Name: "redis:2.8"}}
+	web := &citadel.Container{Image: (&Application{Name: "web", Image: "nginx"}).ContainerImage()}
+	worker := &citadel.Container{Image: &citadel.Image{Name: "worker:latest"}}
+
+	tests := []struct {
+		affinity   *Affinity
+		image      *citadel.Image
+		containers []*citadel.Container
+		allowed    bool
+	}{
+		{&Affinity{Images: []string{"redis"}}, &citadel.Image{Name: "app"}, []*citadel.Container{redis}, true},
+		{&Affinity{Images: []string{"redis:3.0"}}, &citadel.Image{Name: "app"}, []*citadel.Container{redis}, false},
+		{&Affinity{Images: []string{"redis"}}, &citadel.Image{Name: "app"}, nil, false},
+		{&Affinity{AntiImages: []string{"redis"}}, &citadel.Image{Name: "app"}, []*citadel.Container{redis}, false},
+		{&Affinity{AntiImages: []string{"redis"}}, &citadel.Image{Name: "app"}, []*citadel.Container{worker}, true},
+		{&Affinity{Spread: true}, &citadel.Image{Name: "worker"}, []*citadel.Container{worker}, false},
+		{&Affinity{Spread: true}, &citadel.Image{Name: "worker:2"}, []*citadel.Container{worker}, true},
+		{&Affinity{Spread: true}, (&Application{Name: "web", Image: "nginx:1.9"}).ContainerImage(), []*citadel.Container{web}, false},
+		{&Affinity{Spread: true}, &citadel.Image{Name: "nginx"}, []*citadel.Container{web}, true},
+	}
+	for i, test := range tests {
+		if allowed := test.affinity.Allows(test.image, test.containers); allowed != test.allowed {
+			t.Errorf("%d: expected allowed %v; got %v", i, test.allowed, allowed)
+		}
+	}
+}
+
+func TestContainerAffinity(t *testing.T) {
+	img := &citadel.Image{Name: "app"}
+	if a, err := ContainerAffinity(img); err != nil || a != nil {
+		t.Fatalf("expected no affinity; got %v, %v", a, err)
+	}
+	if err := SetAffinity(img, &Affinity{Images: []string{"redis"}, Spread: true}); err != nil {
+		t.Fatal(err)
+	}
+	a, err := ContainerAffinity(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Images) != 1 || a.Images[0] != "redis" || !a.Spread {
+		t.Fatalf("unexpected affinity %+v", a)
+	}
+	err = SetAffinity(img, &Affinity{Images: []string{"redis"}, AntiImages: []string{"redis"}})
+	if !errors.Is(err, ErrInvalidAffinity) {
+		t.Fatalf("expected ErrInvalidAffinity; got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+// Command agent registers the docker host it runs on as a shipyard engine.
+// It reads the resources and labels of the local docker daemon and joins
+// the cluster with a single use join token, so hosts can be added by
+// provisioning scripts without account credentials.
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var (
+	shipyardURL   string
+	joinToken     string
+	allowInsecure bool
+	dockerURL     string
+	advertiseAddr string
+	engineID      string
+	cpus          float64
+	memory        float64
+	sslCert       string
+	sslKey        string
+	caCert        string
+	logger        = logrus.New()
+)
+
+func init() {
+	hostname, _ := os.Hostname()
+	flag.StringVar(&shipyardURL, "shipyard-url", "http://shipyard:8080", "shipyard controller url")
+	flag.StringVar(&joinToken, "token", os.Getenv("SHIPYARD_JOIN_TOKEN"), "join token (or SHIPYARD_JOIN_TOKEN)")
+	flag.BoolVar(&allowInsecure, "allow-insecure", false, "skip controller certificate verification")
+	flag.StringVar(&dockerURL, "docker-url", "unix:///var/run/docker.sock", "local docker api url used to read resources")
+	flag.StringVar(&advertiseAddr, "addr", "", "docker api url the controller connects to, i.e. https://10.0.0.5:2376")
+	flag.StringVar(&engineID, "id", hostname, "engine name")
+	flag.Float64Var(&cpus, "cpus", 0, "cpus offered to the cluster; defaults to the cpus of the host")
+	flag.Float64Var(&memory, "memory", 0, "memory in MB offered to the cluster; defaults to the memory of the host")
+	flag.StringVar(&sslCert, "ssl-cert", "", "client certificate the controller uses for the docker api")
+	flag.StringVar(&sslKey, "ssl-key", "", "client key the controller uses for the docker api")
+	flag.StringVar(&caCert, "ca-cert", "", "ca certificate of the docker api")
+}
+
+// joinRequest describes the local docker host
+func joinRequest() (*shipyard.JoinRequest, error) {
+	docker, err := dockerclient.NewDockerClient(dockerURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	info, err := docker.Info()
+	if err != nil {
+		return nil, err
+	}
+	req := &shipyard.JoinRequest{
+		Token:  joinToken,
+		ID:     engineID,
+		Addr:   advertiseAddr,
+		Cpus:   cpus,
+		Memory: memory,
+	}
+	if req.Cpus == 0 {
+		req.Cpus = float64(info.NCPU)
+	}
+	if req.Memory == 0 {
+		req.Memory = float64(info.MemTotal / 1024 / 1024)
+	}
+	for _, f := range []struct {
+		path string
+		dest *string
+	}{
+		{sslCert, &req.SSLCertificate},
+		{sslKey, &req.SSLKey},
+		{caCert, &req.CACertificate},
+	} {
+		if f.path == "" {
+			continue
+		}
+		b, err := ioutil.ReadFile(f.path)
+		if err != nil {
+			return nil, err
+		}
+		*f.dest = string(b)
+	}
+	return req, nil
+}
+
+// permanent reports whether retrying a failed join cannot succeed
+func permanent(err error) bool {
+	if e, ok := err.(*shipyard.APIError); ok {
+		switch e.StatusCode {
+		case http.StatusBadRequest, http.StatusUnauthorized, http.StatusConflict:
+			return true
+		}
+	}
+	return false
+}
+
+func main() {
+	flag.Parse()
+	if joinToken == "" || advertiseAddr == "" {
+		logger.Fatal("-token and -addr are required")
+	}
+	if (sslCert != "" || sslKey != "" || caCert != "") && (sslCert == "" || sslKey == "" || caCert == "") {
+		logger.Fatal("-ssl-cert, -ssl-key and -ca-cert must be set together")
+	}
+	m := client.NewManager(&client.ShipyardConfig{
+		Url:           shipyardURL,
+		AllowInsecure: allowInsecure,
+	})
+	for {
+		req, err := joinRequest()
+		if err != nil {
+			logger.Warnf("error reading docker info: %s", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		engine, err := m.JoinEngine(req)
+		if err == nil {
+			logger.Infof("joined as %s addr=%s cpus=%.2f memory=%.0f", engine.ID, engine.Addr, engine.Cpus, engine.Memory)
+			return
+		}
+		if permanent(err) {
+			logger.Fatalf("error joining cluster: %s", err)
+		}
+		logger.Warnf("error joining cluster: %s", err)
+		time.Sleep(5 * time.Second)
+	}
+}
@@ -0,0 +1,199 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// AlertContainerRestarts fires for containers that died more than
+	// Threshold times within the window
+	AlertContainerRestarts = "container-restarts"
+	// AlertContainerCpu fires for containers whose latest metric sample
+	// uses more than Threshold percent of a cpu
+	AlertContainerCpu = "container-cpu"
+	// AlertContainerMemory fires for containers whose latest metric sample
+	// uses more than Threshold percent of their memory limit
+	AlertContainerMemory = "container-memory"
+	// AlertEngineMemoryReserved fires for engines with more than Threshold
+	// percent of their memory reserved by containers
+	AlertEngineMemoryReserved = "engine-memory-reserved"
+	// AlertEngineCpuReserved fires for engines with more than Threshold
+	// percent of their cpus reserved by containers
+	AlertEngineCpuReserved = "engine-cpu-reserved"
+	// AlertEvents fires when more than Threshold events of the rule event
+	// types were saved within the window
+	AlertEvents = "events"
+
+	// AlertResourceCluster is the resource of alerts not about a single
+	// container or engine
+	AlertResourceCluster = "cluster"
+
+	// DefaultAlertWindow is the window of rules counting events in minutes
+	DefaultAlertWindow = 10
+)
+
+var (
+	ErrInvalidAlertRule = errors.New("invalid alert rule")
+
+	alertTypes = map[string]bool{
+		AlertContainerRestarts:    true,
+		AlertContainerCpu:         true,
+		AlertContainerMemory:      true,
+		AlertEngineMemoryReserved: true,
+		AlertEngineCpuReserved:    true,
+		AlertEvents:               true,
+	}
+)
+
+type (
+	// AlertRule is a condition on the metrics, reservations or events of
+	// the cluster.  The controller evaluates the rules periodically and
+	// saves an alert-firing event when a rule starts firing for a resource
+	// and an alert-resolved event when it stops, so webhooks and notifiers
+	// deliver alerts like any other event.
+	AlertRule struct {
+		ID   string `json:"id,omitempty" gorethink:"id,omitempty"`
+		Name string `json:"name,omitempty" gorethink:"name"`
+		// Type is one of the Alert* rule types
+		Type      string  `json:"type,omitempty" gorethink:"type"`
+		Threshold float64 `json:"threshold" gorethink:"threshold"`
+		// Window is the minutes of events counted by AlertEvents and
+		// AlertContainerRestarts rules; 0 is DefaultAlertWindow
+		Window int `json:"window,omitempty" gorethink:"window"`
+		// EventTypes are the events counted by AlertEvents rules
+		EventTypes []string `json:"event_types,omitempty" gorethink:"event_types"`
+		// Severity of the alert-firing events; empty is warning
+		Severity string `json:"severity,omitempty" gorethink:"severity"`
+	}
+
+	// Alert is a rule firing for a resource
+	Alert struct {
+		// ID identifies the rule and resource
+		ID       string `json:"id,omitempty" gorethink:"id"`
+		RuleID   string `json:"rule_id,omitempty" gorethink:"rule_id"`
+		RuleName string `json:"rule_name,omitempty" gorethink:"rule_name"`
+		Severity string `json:"severity,omitempty" gorethink:"severity"`
+		Resource string `json:"resource,omitempty" gorethink:"resource"`
+		// ResourceID is the container id or, like in engine metric
+		// samples, the engine name
+		ResourceID string  `json:"resource_id,omitempty" gorethink:"resource_id"`
+		Value      float64 `json:"value" gorethink:"value"`
+		Threshold  float64 `json:"threshold" gorethink:"threshold"`
+		Message    string  `json:"message,omitempty" gorethink:"message"`
+		// Since is when the alert started firing
+		Since time.Time `json:"since,omitempty" gorethink:"since"`
+	}
+
+	// AlertState is the cluster state alert rules are evaluated against
+	AlertState struct {
+		Usage *ClusterUsage
+		// Events are the events of the longest rule window
+		Events []*Event
+		// Metrics are the latest samples of the running containers
+		Metrics []*MetricSample
+	}
+)
+
+func (r *AlertRule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidAlertRule)
+	}
+	if !alertTypes[r.Type] {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidAlertRule, r.Type)
+	}
+	if r.Threshold < 0 || r.Window < 0 {
+		return fmt.Errorf("%w: threshold and window must not be negative", ErrInvalidAlertRule)
+	}
+	if r.Type == AlertEvents && len(r.EventTypes) == 0 {
+		return fmt.Errorf("%w: event rules need event types", ErrInvalidAlertRule)
+	}
+	if r.Severity != "" && !ValidSeverity(r.Severity) {
+		return fmt.Errorf("%w: unknown severity %s", ErrInvalidAlertRule, r.Severity)
+	}
+	return nil
+}
+
+// WindowDuration returns the window of events the rule counts
+func (r *AlertRule) WindowDuration() time.Duration {
+	if r.Window == 0 {
+		return DefaultAlertWindow * time.Minute
+	}
+	return time.Duration(r.Window) * time.Minute
+}
+
+// Evaluate returns the alerts the rule fires for, sorted by resource id.
+// Only events saved within the rule window before now are counted.
+func (r *AlertRule) Evaluate(state *AlertState, now time.Time) []*Alert {
+	alerts := []*Alert{}
+	fire := func(resource string, id string, value float64, format string, args ...interface{}) {
+		if value <= r.Threshold {
+			return
+		}
+		severity := r.Severity
+		if severity == "" {
+			severity = SeverityWarning
+		}
+		alerts = append(alerts, &Alert{
+			ID:         r.ID + "/" + resource + "/" + id,
+			RuleID:     r.ID,
+			RuleName:   r.Name,
+			Severity:   severity,
+			Resource:   resource,
+			ResourceID: id,
+			Value:      value,
+			Threshold:  r.Threshold,
+			Message:    fmt.Sprintf(format, args...),
+		})
+	}
+	since := now.Add(-r.WindowDuration())
+	switch r.Type {
+	case AlertContainerRestarts:
+		deaths := map[string]float64{}
+		for _, e := range state.Events {
+			if e.Type == "die" && e.Container != nil && e.Time.After(since) {
+				deaths[e.Container.ID]++
+			}
+		}
+		for id, n := range deaths {
+			fire(MetricsContainers, id, n, "%s: container %s died %d times in %s", r.Name, id, int(n), r.WindowDuration())
+		}
+	case AlertContainerCpu, AlertContainerMemory:
+		for _, s := range state.Metrics {
+			if r.Type == AlertContainerCpu {
+				fire(MetricsContainers, s.ResourceID, s.CpuPercent, "%s: container %s uses %.1f%% cpu", r.Name, s.ResourceID, s.CpuPercent)
+			} else if s.MemoryLimit > 0 {
+				pct := float64(s.MemoryUsage) / float64(s.MemoryLimit) * 100
+				fire(MetricsContainers, s.ResourceID, pct, "%s: container %s uses %.1f%% of its memory", r.Name, s.ResourceID, pct)
+			}
+		}
+	case AlertEngineMemoryReserved, AlertEngineCpuReserved:
+		if state.Usage == nil {
+			break
+		}
+		for _, e := range state.Usage.Engines {
+			if r.Type == AlertEngineMemoryReserved && e.Memory > 0 {
+				pct := e.ReservedMemory / e.Memory * 100
+				fire(MetricsEngines, e.EngineID, pct, "%s: engine %s has %.1f%% of its memory reserved", r.Name, e.EngineID, pct)
+			} else if r.Type == AlertEngineCpuReserved && e.Cpus > 0 {
+				pct := e.ReservedCpus / e.Cpus * 100
+				fire(MetricsEngines, e.EngineID, pct, "%s: engine %s has %.1f%% of its cpus reserved", r.Name, e.EngineID, pct)
+			}
+		}
+	case AlertEvents:
+		f := &EventFilter{Types: r.EventTypes}
+		n := 0.0
+		for _, e := range state.Events {
+			if e.Time.After(since) && f.Match(e) {
+				n++
+			}
+		}
+		fire(AlertResourceCluster, "", n, "%s: %d events in %s", r.Name, int(n), r.WindowDuration())
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].ResourceID < alerts[j].ResourceID
+	})
+	return alerts
+}
@@ -0,0 +1,106 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/citadel/citadel"
+)
+
+func TestAlertRuleValidate(t *testing.T) {
+	for _, r := range []*AlertRule{
+		{Type: AlertContainerRestarts},
+		{Name: "restarts", Type: "disk"},
+		{Name: "restarts", Type: AlertContainerRestarts, Threshold: -1},
+		{Name: "errors", Type: AlertEvents},
+		{Name: "restarts", Type: AlertContainerRestarts, Severity: "urgent"},
+	} {
+		if err := r.Validate(); !errors.Is(err, ErrInvalidAlertRule) {
+			t.Errorf("expected ErrInvalidAlertRule for %+v; received %v", r, err)
+		}
+	}
+	r := &AlertRule{Name: "errors", Type: AlertEvents, EventTypes: []string{"oom"}, Severity: SeverityCritical}
+	if err := r.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAlertRuleEvaluateRestarts(t *testing.T) {
+	now := time.Now()
+	die := func(id string, ago time.Duration) *Event {
+		return &Event{Type: "die", Time: now.Add(-ago), Container: &citadel.Container{ID: id}}
+	}
+	state := &AlertState{
+		Events: []*Event{
+			die("a", time.Minute), die("a", 2*time.Minute), die("a", 20*time.Minute),
+			die("b", time.Minute),
+			{Type: "start", Time: now, Container: &citadel.Container{ID: "b"}},
+		},
+	}
+	r := &AlertRule{ID: "r", Name: "restarts", Type: AlertContainerRestarts, Threshold: 1}
+	alerts := r.Evaluate(state, now)
+	if len(alerts) != 1 {
+		t.Fatalf("expected a single alert; received %d", len(alerts))
+	}
+	a := alerts[0]
+	if a.ID != "r/containers/a" || a.Value != 2 || a.Severity != SeverityWarning {
+		t.Errorf("unexpected alert %+v", a)
+	}
+
+	r.Window = 30
+	if alerts := r.Evaluate(state, now); len(alerts) != 1 || alerts[0].Value != 3 {
+		t.Errorf("expected the longer window to count every death; received %+v", alerts)
+	}
+}
+
+func TestAlertRuleEvaluateUsage(t *testing.T) {
+	state := &AlertState{
+		Usage: &ClusterUsage{
+			Engines: []*EngineUsage{
+				{EngineID: "full", ResourceUsage: ResourceUsage{Memory: 1024, ReservedMemory: 1000, Cpus: 4, ReservedCpus: 1}},
+				{EngineID: "idle", ResourceUsage: ResourceUsage{Memory: 1024, ReservedMemory: 128, Cpus: 4}},
+			},
+		},
+		Metrics: []*MetricSample{
+			{Resource: MetricsContainers, ResourceID: "a", MemoryUsage: 95, MemoryLimit: 100, CpuPercent: 20},
+			{Resource: MetricsContainers, ResourceID: "b", MemoryUsage: 95},
+		},
+	}
+	r := &AlertRule{ID: "r", Name: "memory", Type: AlertEngineMemoryReserved, Threshold: 90, Severity: SeverityCritical}
+	alerts := r.Evaluate(state, time.Now())
+	if len(alerts) != 1 || alerts[0].ResourceID != "full" || alerts[0].Resource != MetricsEngines || alerts[0].Severity != SeverityCritical {
+		t.Errorf("expected the full engine to fire; received %+v", alerts)
+	}
+	r.Type = AlertEngineCpuReserved
+	if alerts := r.Evaluate(state, time.Now()); len(alerts) != 0 {
+		t.Errorf("expected no cpu alerts; received %+v", alerts)
+	}
+	r.Type = AlertContainerMemory
+	if alerts := r.Evaluate(state, time.Now()); len(alerts) != 1 || alerts[0].ResourceID != "a" {
+		t.Errorf("expected only the container with a limit to fire; received %+v", alerts)
+	}
+	if alerts := r.Evaluate(&AlertState{}, time.Now()); len(alerts) != 0 {
+		t.Errorf("expected no alerts without state; received %+v", alerts)
+	}
+}
+
+func TestAlertRuleEvaluateEvents(t *testing.T) {
+	now := time.Now()
+	state := &AlertState{
+		Events: []*Event{
+			{Type: "oom", Time: now},
+			{Type: "oom", Time: now.Add(-time.Minute)},
+			{Type: "die", Time: now},
+		},
+	}
+	r := &AlertRule{ID: "r", Name: "ooms", Type: AlertEvents, EventTypes: []string{"oom"}, Threshold: 1}
+	alerts := r.Evaluate(state, now)
+	if len(alerts) != 1 || alerts[0].ID != "r/cluster/" || alerts[0].Value != 2 {
+		t.Errorf("unexpected alerts %+v", alerts)
+	}
+	r.Threshold = 2
+	if alerts := r.Evaluate(state, now); len(alerts) != 0 {
+		t.Errorf("expected no alert at the threshold; received %+v", alerts)
+	}
+}
@@ -0,0 +1,218 @@
+package shipyard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/citadel/citadel"
+)
+
+type (
+	// SpecChange is a field of an application spec that differs between
+	// the current and a proposed spec
+	SpecChange struct {
+		// Field is the changed field, such as image or environment.DB_HOST
+		Field string `json:"field"`
+		// Kind is one of ChangeModified, ChangeAdded or ChangeDeleted
+		Kind string `json:"kind"`
+		From string `json:"from,omitempty"`
+		To   string `json:"to,omitempty"`
+		// Destructive changes take away something the containers rely on,
+		// such as an env var, secret or volume; updates making them must
+		// be confirmed
+		Destructive bool `json:"destructive,omitempty"`
+	}
+
+	// ApplicationDiff is what an update would change in an application spec
+	ApplicationDiff struct {
+		Name    string        `json:"name"`
+		Changes []*SpecChange `json:"changes"`
+	}
+)
+
+func (c *SpecChange) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("%s added (%s)", c.Field, c.To)
+	case ChangeDeleted:
+		return fmt.Sprintf("%s deleted (%s)", c.Field, c.From)
+	}
+	return fmt.Sprintf("%s modified (%s -> %s)", c.Field, c.From, c.To)
+}
+
+// Destructive returns the changes of the diff that must be confirmed
+func (d *ApplicationDiff) Destructive() []*SpecChange {
+	changes := []*SpecChange{}
+	for _, c := range d.Changes {
+		if c.Destructive {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// DiffApplications returns the changes from the current to the proposed spec
+// of an application.  Removing env vars, secrets, configs, volumes, ports,
+// links or the stop hook is destructive.
+func DiffApplications(current, proposed *Application) *ApplicationDiff {
+	d := &ApplicationDiff{
+		Name:    current.Name,
+		Changes: []*SpecChange{},
+	}
+	d.value("image", current.Image, proposed.Image)
+	d.value("count", fmt.Sprint(current.Count), fmt.Sprint(proposed.Count))
+	d.value("cpus", fmt.Sprint(current.Cpus), fmt.Sprint(proposed.Cpus))
+	d.value("memory", fmt.Sprint(current.Memory), fmt.Sprint(proposed.Memory))
+	d.value("args", strings.Join(current.Args, " "), strings.Join(proposed.Args, " "))
+	d.set("environment", current.Environment, proposed.Environment, true)
+	d.set("secrets", diffSecrets(current.Secrets), diffSecrets(proposed.Secrets), true)
+	d.set("configs", diffNames(current.Configs), diffNames(proposed.Configs), true)
+	d.set("volumes", diffVolumes(current.Volumes), diffVolumes(proposed.Volumes), true)
+	d.set("ports", diffPorts(current.Ports), diffPorts(proposed.Ports), true)
+	d.set("constraints", diffNames(current.Constraints), diffNames(proposed.Constraints), false)
+	d.set("labels", current.Labels, proposed.Labels, false)
+	d.set("links", current.Links, proposed.Links, true)
+	d.value("pool", current.Pool, proposed.Pool)
+	d.value("placement", current.Placement, proposed.Placement)
+	d.set("affinity", diffAffinity(current.Affinity), diffAffinity(proposed.Affinity), false)
+	d.set("stop_hook", diffStopHook(current.StopHook), diffStopHook(proposed.StopHook), true)
+	d.value("auto_deploy", fmt.Sprint(current.AutoDeploy), fmt.Sprint(proposed.AutoDeploy))
+	return d
+}
+
+func (d *ApplicationDiff) value(field, from, to string) {
+	if from == to {
+		return
+	}
+	c := &SpecChange{
+		Field: field,
+		Kind:  ChangeModified,
+		From:  from,
+		To:    to,
+	}
+	switch {
+	case from == "":
+		c.Kind = ChangeAdded
+	case to == "":
+		c.Kind = ChangeDeleted
+	}
+	d.Changes = append(d.Changes, c)
+}
+
+// set diffs the entries of two maps by key as field.key; deleted entries
+// are destructive if destructive is set
+func (d *ApplicationDiff) set(field string, from, to map[string]string, destructive bool) {
+	keys := []string{}
+	for k := range from {
+		keys = append(keys, k)
+	}
+	for k := range to {
+		if _, ok := from[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f, inFrom := from[k]
+		t, inTo := to[k]
+		c := &SpecChange{
+			Field: field + "." + k,
+			Kind:  ChangeModified,
+			From:  f,
+			To:    t,
+		}
+		switch {
+		case !inTo:
+			c.Kind = ChangeDeleted
+			c.Destructive = destructive
+		case !inFrom:
+			c.Kind = ChangeAdded
+		case f == t:
+			continue
+		}
+		d.Changes = append(d.Changes, c)
+	}
+}
+
+func diffNames(l []string) map[string]string {
+	m := map[string]string{}
+	for _, n := range l {
+		m[n] = n
+	}
+	return m
+}
+
+func diffSecrets(refs []*SecretRef) map[string]string {
+	m := map[string]string{}
+	for _, ref := range refs {
+		switch {
+		case ref.Path != "":
+			m[ref.Name] = ref.Path
+		case ref.Env != "":
+			m[ref.Name] = ref.Env
+		default:
+			m[ref.Name] = ref.Name
+		}
+	}
+	return m
+}
+
+func diffVolumes(mounts []*VolumeMount) map[string]string {
+	m := map[string]string{}
+	for _, v := range mounts {
+		s := v.Path
+		if v.ReadOnly {
+			s += ":ro"
+		}
+		m[v.Name] = s
+	}
+	return m
+}
+
+func diffAffinity(a *Affinity) map[string]string {
+	m := map[string]string{}
+	if a == nil {
+		return m
+	}
+	if len(a.Images) > 0 {
+		m["images"] = strings.Join(a.Images, ",")
+	}
+	if len(a.AntiImages) > 0 {
+		m["anti_images"] = strings.Join(a.AntiImages, ",")
+	}
+	if a.Spread {
+		m["spread"] = "true"
+	}
+	if a.SpreadBy != "" {
+		m["spread_by"] = a.SpreadBy
+	}
+	return m
+}
+
+func diffStopHook(h *StopHook) map[string]string {
+	m := map[string]string{}
+	if h == nil {
+		return m
+	}
+	if len(h.PreStop) > 0 {
+		m["pre_stop"] = strings.Join(h.PreStop, " ")
+	}
+	if h.GracePeriod > 0 {
+		m["grace_period"] = fmt.Sprint(h.GracePeriod)
+	}
+	return m
+}
+
+// diffPorts keys the ports by protocol and container port
+func diffPorts(l []*citadel.Port) map[string]string {
+	m := map[string]string{}
+	for _, p := range l {
+		proto := p.Proto
+		if proto == "" {
+			proto = "tcp"
+		}
+		m[fmt.Sprintf("%s/%d", proto, p.ContainerPort)] = fmt.Sprintf("%s:%d", p.HostIp, p.Port)
+	}
+	return m
+}
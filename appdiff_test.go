@@ -0,0 +1,94 @@
+package shipyard
+
+import (
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestDiffApplications(t *testing.T) {
+	current := &Application{
+		Name:        "web",
+		Image:       "nginx:1.7",
+		Count:       2,
+		Environment: map[string]string{"DB_HOST": "db", "DEBUG": "1"},
+		Secrets:     []*SecretRef{{Name: "db-password", Env: "DB_PASS"}},
+		Ports:       []*citadel.Port{{Proto: "tcp", ContainerPort: 80, Port: 8080}},
+	}
+	proposed := &Application{
+		Name:        "web",
+		Image:       "nginx:1.8",
+		Count:       2,
+		Environment: map[string]string{"DB_HOST": "db2", "LOG": "info"},
+		Ports:       []*citadel.Port{{Proto: "tcp", ContainerPort: 80, Port: 8080}},
+	}
+	d := DiffApplications(current, proposed)
+	expected := []string{
+		"image modified (nginx:1.7 -> nginx:1.8)",
+		"environment.DB_HOST modified (db -> db2)",
+		"environment.DEBUG deleted (1)",
+		"environment.LOG added (info)",
+		"secrets.db-password deleted (DB_PASS)",
+	}
+	if len(d.Changes) != len(expected) {
+		t.Fatalf("expected %d changes; received %v", len(expected), d.Changes)
+	}
+	for i, c := range d.Changes {
+		if c.String() != expected[i] {
+			t.Errorf("expected %q; received %q", expected[i], c.String())
+		}
+	}
+	destructive := d.Destructive()
+	if len(destructive) != 2 || destructive[0].Field != "environment.DEBUG" || destructive[1].Field != "secrets.db-password" {
+		t.Errorf("unexpected destructive changes %v", destructive)
+	}
+
+	if d := DiffApplications(current, current); len(d.Changes) != 0 {
+		t.Errorf("expected no changes; received %v", d.Changes)
+	}
+	if d := DiffApplications(current, &Application{Name: "web", Image: "nginx:1.7", Count: 2}); len(d.Destructive()) != 4 {
+		t.Errorf("expected wiping the spec to be destructive; received %v", d.Changes)
+	}
+}
+
+func TestDiffApplicationsPlacement(t *testing.T) {
+	current := &Application{
+		Name:     "web",
+		Image:    "nginx:1.7",
+		Links:    map[string]string{"db": "database", "cache": "cache"},
+		Pool:     "frontend",
+		Affinity: &Affinity{Spread: true},
+		StopHook: &StopHook{PreStop: []string{"nginx", "-s", "quit"}, GracePeriod: 30},
+	}
+	proposed := &Application{
+		Name:       "web",
+		Image:      "nginx:1.7",
+		Links:      map[string]string{"db": "database"},
+		Pool:       "edge",
+		Placement:  "spread",
+		Affinity:   &Affinity{Spread: true, SpreadBy: "zone"},
+		AutoDeploy: true,
+	}
+	d := DiffApplications(current, proposed)
+	expected := []string{
+		"links.cache deleted (cache)",
+		"pool modified (frontend -> edge)",
+		"placement added (spread)",
+		"affinity.spread_by added (zone)",
+		"stop_hook.grace_period deleted (30)",
+		"stop_hook.pre_stop deleted (nginx -s quit)",
+		"auto_deploy modified (false -> true)",
+	}
+	if len(d.Changes) != len(expected) {
+		t.Fatalf("expected %d changes; received %v", len(expected), d.Changes)
+	}
+	for i, c := range d.Changes {
+		if c.String() != expected[i] {
+			t.Errorf("expected %q; received %q", expected[i], c.String())
+		}
+	}
+	destructive := d.Destructive()
+	if len(destructive) != 3 || destructive[0].Field != "links.cache" || destructive[1].Field != "stop_hook.grace_period" || destructive[2].Field != "stop_hook.pre_stop" {
+		t.Errorf("unexpected destructive changes %v", destructive)
+	}
+}
@@ -0,0 +1,207 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	// ApplicationEnv marks the containers of an application with its name
+	ApplicationEnv = "_SHIPYARD_APPLICATION"
+	// ProjectEnv marks the containers of the applications of a compose
+	// project with the project name
+	ProjectEnv = "_SHIPYARD_PROJECT"
+)
+
+var (
+	ErrInvalidApplication = errors.New("invalid application")
+)
+
+// Application is a named set of identical containers that the controller
+// keeps at the desired count
+type Application struct {
+	ID    string `json:"id,omitempty" gorethink:"id,omitempty"`
+	Name  string `json:"name,omitempty" gorethink:"name"`
+	Image string `json:"image,omitempty" gorethink:"image"`
+	// Count is the number of containers kept running
+	Count       int               `json:"count" gorethink:"count"`
+	Cpus        float64           `json:"cpus,omitempty" gorethink:"cpus"`
+	Memory      float64           `json:"memory,omitempty" gorethink:"memory"`
+	Args        []string          `json:"args,omitempty" gorethink:"args"`
+	Environment map[string]string `json:"environment,omitempty" gorethink:"environment"`
+	Ports       []*citadel.Port   `json:"ports,omitempty" gorethink:"ports"`
+	// Constraints are engine label expressions (see ParseConstraints)
+	Constraints []string `json:"constraints,omitempty" gorethink:"constraints"`
+	// Secrets are injected into each container at launch
+	Secrets []*SecretRef `json:"secrets,omitempty" gorethink:"secrets"`
+	// Configs are the config bundles of the containers
+	Configs []string `json:"configs,omitempty" gorethink:"configs"`
+	// Volumes are named volumes mounted into the containers, which are
+	// placed on the engine holding them
+	Volumes []*VolumeMount `json:"volumes,omitempty" gorethink:"volumes"`
+	// Links are the aliases of linked applications by application name.
+	// Containers are placed on the engine of a running container of each
+	// linked application.
+	Links map[string]string `json:"links,omitempty" gorethink:"links"`
+	// Pool is the engine pool the containers are placed in
+	Pool string `json:"pool,omitempty" gorethink:"pool"`
+	// Placement is the strategy the containers are placed with; the
+	// controller default is used when empty
+	Placement string `json:"placement,omitempty" gorethink:"placement"`
+	// Affinity places the containers relative to other containers
+	Affinity *Affinity `json:"affinity,omitempty" gorethink:"affinity"`
+	// Labels are given to every container (see SetLabels)
+	Labels map[string]string `json:"labels,omitempty" gorethink:"labels"`
+	// Project groups the applications deployed from one compose file
+	Project string `json:"project,omitempty" gorethink:"project"`
+	// Owner is the account that created the application; its containers
+	// count against the quotas of the account
+	Owner string `json:"owner,omitempty" gorethink:"owner"`
+	// Namespace is the namespace the application was created in; its
+	// containers belong to it
+	Namespace string `json:"namespace,omitempty" gorethink:"namespace"`
+	// Stopped is set while the containers are stopped as a group; they are
+	// not replaced until the group is started again
+	Stopped bool `json:"stopped,omitempty" gorethink:"stopped"`
+	// StopHook stops the containers gracefully when they are stopped or
+	// replaced
+	StopHook *StopHook `json:"stop_hook,omitempty" gorethink:"stop_hook"`
+	// AutoDeploy redeploys the application when its image tag is pushed
+	// again with a new digest
+	AutoDeploy bool `json:"auto_deploy,omitempty" gorethink:"auto_deploy"`
+}
+
+// Validate checks the application can be run
+func (a *Application) Validate() error {
+	if a.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidApplication)
+	}
+	if a.Image == "" {
+		return fmt.Errorf("%w: image is required", ErrInvalidApplication)
+	}
+	if a.Count < 0 {
+		return fmt.Errorf("%w: count must not be negative", ErrInvalidApplication)
+	}
+	if err := ValidateEnvironment(a.Environment, nil); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidApplication, err)
+	}
+	for _, c := range a.Constraints {
+		if _, err := ParseConstraints(c); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidApplication, err)
+		}
+	}
+	for _, n := range a.Configs {
+		if !secretNamePattern.MatchString(n) {
+			return fmt.Errorf("%w: invalid config name %q", ErrInvalidApplication, n)
+		}
+	}
+	for _, ref := range a.Secrets {
+		if err := ref.Validate(); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidApplication, err)
+		}
+	}
+	img := &citadel.Image{}
+	if err := SetVolumeMounts(img, a.Volumes); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidApplication, err)
+	}
+	if err := SetLabels(img, a.Labels); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidApplication, err)
+	}
+	if a.Placement != "" {
+		if err := ValidatePlacement(a.Placement); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidApplication, err)
+		}
+	}
+	if a.Affinity != nil {
+		if err := a.Affinity.Validate(); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidApplication, err)
+		}
+	}
+	if a.StopHook != nil {
+		if err := a.StopHook.Validate(); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidApplication, err)
+		}
+	}
+	for name := range a.Links {
+		if name == a.Name {
+			return fmt.Errorf("%w: an application can not link to itself", ErrInvalidApplication)
+		}
+	}
+	return nil
+}
+
+// ContainerImage returns the image used to launch the application
+// containers
+func (a *Application) ContainerImage() *citadel.Image {
+	env := map[string]string{}
+	for k, v := range a.Environment {
+		env[k] = v
+	}
+	env[ApplicationEnv] = a.Name
+	if a.Project != "" {
+		env[ProjectEnv] = a.Project
+	}
+	if a.Owner != "" {
+		env[OwnerEnv] = a.Owner
+	}
+	if a.Namespace != "" {
+		env[NamespaceEnv] = a.Namespace
+	}
+	ports := []*citadel.Port{}
+	for _, p := range a.Ports {
+		port := *p
+		ports = append(ports, &port)
+	}
+	if len(a.Secrets) > 0 {
+		b, _ := json.Marshal(a.Secrets)
+		env[SecretsEnv] = string(b)
+	}
+	if len(a.Configs) > 0 {
+		env[ConfigsEnv] = strings.Join(a.Configs, ",")
+	}
+	if len(a.Volumes) > 0 {
+		b, _ := json.Marshal(a.Volumes)
+		env[VolumesEnv] = string(b)
+	}
+	if a.Pool != "" {
+		env[PoolEnv] = a.Pool
+	}
+	if a.Placement != "" {
+		env[PlacementEnv] = a.Placement
+	}
+	if a.Affinity != nil {
+		b, _ := json.Marshal(a.Affinity)
+		env[AffinityEnv] = string(b)
+	}
+	if len(a.Labels) > 0 {
+		b, _ := json.Marshal(a.Labels)
+		env[LabelsEnv] = string(b)
+	}
+	if a.StopHook != nil {
+		b, _ := json.Marshal(a.StopHook)
+		env[StopHookEnv] = string(b)
+	}
+	return &citadel.Image{
+		Name:        a.Image,
+		Cpus:        a.Cpus,
+		Memory:      a.Memory,
+		Args:        a.Args,
+		Environment: env,
+		BindPorts:   ports,
+		Labels:      append([]string{}, a.Constraints...),
+		Type:        "service",
+		Publish:     len(ports) > 0,
+	}
+}
+
+// ApplicationName returns the application a container was launched for
+func ApplicationName(c *citadel.Container) string {
+	if c == nil || c.Image == nil {
+		return ""
+	}
+	return c.Image.Environment[ApplicationEnv]
+}
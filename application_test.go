@@ -0,0 +1,49 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestApplicationValidate(t *testing.T) {
+	valid := &Application{Name: "web", Image: "nginx", Count: 2, Constraints: []string{"region=us-east"}}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, app := range []*Application{
+		{Image: "nginx"},
+		{Name: "web"},
+		{Name: "web", Image: "nginx", Count: -1},
+		{Name: "web", Image: "nginx", StopHook: &StopHook{GracePeriod: -1}},
+	} {
+		if err := app.Validate(); !errors.Is(err, ErrInvalidApplication) {
+			t.Errorf("expected ErrInvalidApplication for %+v; received %v", app, err)
+		}
+	}
+}
+
+func TestApplicationContainerImage(t *testing.T) {
+	app := &Application{
+		Name:        "web",
+		Image:       "nginx",
+		Environment: map[string]string{"MODE": "prod"},
+		Ports:       []*citadel.Port{{Proto: "tcp", ContainerPort: 80}},
+		Namespace:   "team-a",
+	}
+	img := app.ContainerImage()
+	c := &citadel.Container{Image: img}
+	if ApplicationName(c) != "web" {
+		t.Errorf("expected container to belong to web; received %q", ApplicationName(c))
+	}
+	if ContainerNamespace(c) != "team-a" {
+		t.Errorf("expected container in namespace team-a; received %q", ContainerNamespace(c))
+	}
+	if img.Environment["MODE"] != "prod" || img.Type != "service" || !img.Publish {
+		t.Errorf("unexpected image %+v", img)
+	}
+	if _, ok := app.Environment[ApplicationEnv]; ok {
+		t.Error("expected application environment to be left unchanged")
+	}
+}
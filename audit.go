@@ -0,0 +1,59 @@
+package shipyard
+
+import (
+	"strconv"
+	"time"
+)
+
+type (
+	// AuditEntry records a state changing api request and who made it
+	AuditEntry struct {
+		ID       string    `json:"id,omitempty" gorethink:"id,omitempty"`
+		Time     time.Time `json:"time,omitempty" gorethink:"time"`
+		Username string    `json:"username,omitempty" gorethink:"username,omitempty"`
+		// ServiceKey is the description of the service key used, if any,
+		// or a fingerprint of keys without one
+		ServiceKey string `json:"service_key,omitempty" gorethink:"service_key,omitempty"`
+		RemoteAddr string `json:"remote_addr,omitempty" gorethink:"remote_addr"`
+		Method     string `json:"method,omitempty" gorethink:"method"`
+		Endpoint   string `json:"endpoint,omitempty" gorethink:"endpoint"`
+		// Summary is the request payload with secrets redacted, truncated
+		Summary    string `json:"summary,omitempty" gorethink:"summary,omitempty"`
+		StatusCode int    `json:"status_code,omitempty" gorethink:"status_code"`
+		Success    bool   `json:"success" gorethink:"success"`
+	}
+
+	// AuditFilter selects audit entries, newest first.  Zero values match
+	// everything.
+	AuditFilter struct {
+		Username string    `json:"username,omitempty"`
+		Method   string    `json:"method,omitempty"`
+		Endpoint string    `json:"endpoint,omitempty"`
+		Since    time.Time `json:"since,omitempty"`
+		Until    time.Time `json:"until,omitempty"`
+		Limit    int       `json:"limit,omitempty"`
+		Offset   int       `json:"offset,omitempty"`
+	}
+)
+
+// AuditCSVHeader is the header row of the csv audit log export
+var AuditCSVHeader = []string{"time", "username", "service_key", "remote_addr", "method", "endpoint", "status_code", "success", "summary"}
+
+// CSVRecord returns the entry as a row matching AuditCSVHeader
+func (e *AuditEntry) CSVRecord() []string {
+	success := "false"
+	if e.Success {
+		success = "true"
+	}
+	return []string{
+		e.Time.Format(time.RFC3339),
+		e.Username,
+		e.ServiceKey,
+		e.RemoteAddr,
+		e.Method,
+		e.Endpoint,
+		strconv.Itoa(e.StatusCode),
+		success,
+		e.Summary,
+	}
+}
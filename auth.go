@@ -9,6 +9,9 @@ import (
 
 var (
 	ErrUnauthorized = errors.New("unauthorized")
+	// ErrUnknownUser is returned by external authenticators for users
+	// they do not know about
+	ErrUnknownUser = errors.New("unknown user")
 )
 
 type (
@@ -18,10 +21,28 @@ type (
 		Password string       `json:"password,omitempty" gorethink:"password"`
 		Tokens   []*AuthToken `json:"-" gorethink:"tokens"`
 		Role     *Role        `json:"role,omitempty" gorethink:"role"`
+		FullName string       `json:"full_name,omitempty" gorethink:"full_name"`
+		Email    string       `json:"email,omitempty" gorethink:"email"`
+		// TOTPEnabled requires a one time code at login
+		TOTPEnabled bool   `json:"totp_enabled,omitempty" gorethink:"totp_enabled"`
+		TOTPSecret  string `json:"-" gorethink:"totp_secret"`
+		// TOTPLastStep is the time step of the last accepted code
+		TOTPLastStep int64 `json:"-" gorethink:"totp_last_step"`
+		// NamespaceRoles are role names by namespace (see Namespace)
+		NamespaceRoles map[string]string `json:"namespace_roles,omitempty" gorethink:"namespace_roles"`
+	}
+	// AccountQuery selects a page of accounts ordered by username.  An
+	// empty role matches any account and a zero limit returns every match.
+	AccountQuery struct {
+		Role   string `json:"role,omitempty"`
+		Limit  int    `json:"limit,omitempty"`
+		Offset int    `json:"offset,omitempty"`
 	}
 	Role struct {
 		ID   string `json:"id,omitempty" gorethink:"id,omitempty"`
 		Name string `json:"name,omitempty" gorethink:"name"`
+		// Permissions are resource:action pairs (see HasPermission)
+		Permissions []string `json:"permissions,omitempty" gorethink:"permissions"`
 	}
 	AuthToken struct {
 		Token     string `json:"auth_token,omitempty" gorethink:"auth_token"`
@@ -33,6 +54,19 @@ type (
 	ServiceKey struct {
 		Key         string `json:"key,omitempty" gorethink:"key"`
 		Description string `json:"description,omitempty" gorethink:"description"`
+		// ExpiresAt is when the key stops working; zero never expires
+		ExpiresAt time.Time `json:"expires_at,omitempty" gorethink:"expires_at"`
+		// ExpiresIn is the remaining lifetime in seconds.  It is reported
+		// by the controller and used as the lifetime when creating a key.
+		ExpiresIn int64 `json:"expires_in,omitempty" gorethink:"-"`
+		// Permissions limit the key to resource:action pairs (see
+		// Role.HasPermission); an empty list grants full access
+		Permissions []string `json:"permissions,omitempty" gorethink:"permissions"`
+		// Namespace confines requests made with the key to a namespace
+		Namespace string `json:"namespace,omitempty" gorethink:"namespace"`
+		// Account created the key; containers launched with it are owned
+		// by the account and count against its quotas
+		Account string `json:"account,omitempty" gorethink:"account"`
 	}
 )
 
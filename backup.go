@@ -0,0 +1,71 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// BackupFormat is the version of the backup format written by the
+	// controller
+	BackupFormat = 1
+)
+
+var (
+	ErrInvalidBackup = errors.New("invalid backup")
+)
+
+type (
+	// BackupHeader starts a backup.  A backup is a stream of json values:
+	// the header followed by one record per stored document.
+	BackupHeader struct {
+		Format  int       `json:"format"`
+		Version string    `json:"version,omitempty"`
+		Created time.Time `json:"created,omitempty"`
+		// Tables are the tables in the backup; restoring replaces their
+		// contents, including those of tables without records
+		Tables []string `json:"tables"`
+	}
+
+	// BackupRecord is a document of a table as stored by the controller
+	BackupRecord struct {
+		Table    string          `json:"table"`
+		Document json.RawMessage `json:"document"`
+	}
+)
+
+// ReadBackup reads and checks a backup
+func ReadBackup(r io.Reader) (*BackupHeader, []*BackupRecord, error) {
+	dec := json.NewDecoder(r)
+	var header *BackupHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidBackup, err)
+	}
+	if header == nil || header.Format != BackupFormat {
+		return nil, nil, fmt.Errorf("%w: unsupported format", ErrInvalidBackup)
+	}
+	tables := map[string]bool{}
+	for _, t := range header.Tables {
+		tables[t] = true
+	}
+	records := []*BackupRecord{}
+	for {
+		var rec *BackupRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", ErrInvalidBackup, err)
+		}
+		if rec == nil || !tables[rec.Table] {
+			return nil, nil, fmt.Errorf("%w: record of a table not in the backup", ErrInvalidBackup)
+		}
+		if len(rec.Document) == 0 || rec.Document[0] != '{' {
+			return nil, nil, fmt.Errorf("%w: record of %s is not a document", ErrInvalidBackup, rec.Table)
+		}
+		records = append(records, rec)
+	}
+	return header, records, nil
+}
@@ -0,0 +1,40 @@
+package shipyard
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadBackup(t *testing.T) {
+	backup := `{"format":1,"version":"1.0","tables":["accounts","roles"]}
+{"table":"accounts","document":{"id":"1","username":"admin"}}
+{"table":"accounts","document":{"id":"2","username":"ops"}}
+`
+	header, records, err := ReadBackup(strings.NewReader(backup))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != "1.0" || len(header.Tables) != 2 {
+		t.Errorf("unexpected header %+v", header)
+	}
+	if len(records) != 2 || records[1].Table != "accounts" || string(records[1].Document) != `{"id":"2","username":"ops"}` {
+		t.Errorf("unexpected records %v", records)
+	}
+
+	invalid := []string{
+		``,
+		`{"format":2,"tables":["accounts"]}`,
+		`{"format":1,"tables":["accounts"]}
+{"table":"roles","document":{"id":"1"}}`,
+		`{"format":1,"tables":["accounts"]}
+{"table":"accounts","document":[1]}`,
+		`{"format":1,"tables":["accounts"]}
+{"table":"accounts","document":{"id":`,
+	}
+	for _, b := range invalid {
+		if _, _, err := ReadBackup(strings.NewReader(b)); !errors.Is(err, ErrInvalidBackup) {
+			t.Errorf("expected invalid backup for %q; received %v", b, err)
+		}
+	}
+}
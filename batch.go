@@ -0,0 +1,117 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	BatchDestroy = "destroy"
+	BatchStop    = "stop"
+	BatchRestart = "restart"
+)
+
+var (
+	ErrInvalidBatch = errors.New("invalid batch")
+)
+
+type (
+	// BatchOperation applies an action to a container selected by id or
+	// to the containers matching a label selector
+	BatchOperation struct {
+		Action string `json:"action"`
+		// ID is the id or a unique prefix of the container
+		ID string `json:"id,omitempty"`
+		// Selector matches the container labels, i.e. "tier=web,env!=dev"
+		Selector string `json:"selector,omitempty"`
+		// Timeout is the number of seconds to wait for the container to
+		// stop before it is killed; it defaults to 10
+		Timeout int `json:"timeout,omitempty"`
+	}
+
+	// BatchItemResult is the outcome of an action on a single container
+	BatchItemResult struct {
+		// Operation is the index of the operation in the batch
+		Operation int    `json:"operation"`
+		Action    string `json:"action"`
+		// Container is the id of the container; it is the requested id
+		// when no container matched
+		Container string `json:"container"`
+		Error     string `json:"error,omitempty"`
+	}
+
+	// BatchResult reports the outcome of every container of a batch
+	BatchResult struct {
+		Results []*BatchItemResult `json:"results"`
+		Failed  int                `json:"failed"`
+	}
+)
+
+func (o *BatchOperation) Validate() error {
+	switch o.Action {
+	case BatchDestroy, BatchStop, BatchRestart:
+	default:
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidBatch, o.Action)
+	}
+	if (o.ID == "") == (o.Selector == "") {
+		return fmt.Errorf("%w: select containers by one of id or selector", ErrInvalidBatch)
+	}
+	if o.Selector != "" {
+		if _, err := ParseSelector(o.Selector); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidBatch, err)
+		}
+	}
+	if o.Timeout < 0 {
+		return fmt.Errorf("%w: timeout must not be negative", ErrInvalidBatch)
+	}
+	return nil
+}
+
+// Select returns the containers an operation applies to.  An id prefix
+// matching several containers is an error unless one has the id itself.
+func (o *BatchOperation) Select(containers []*citadel.Container) ([]*citadel.Container, error) {
+	selected := []*citadel.Container{}
+	if o.ID != "" {
+		for _, c := range containers {
+			if c.ID == o.ID {
+				return []*citadel.Container{c}, nil
+			}
+			if strings.HasPrefix(c.ID, o.ID) {
+				selected = append(selected, c)
+			}
+		}
+		if len(selected) > 1 {
+			return nil, fmt.Errorf("%w: id %s matches %d containers", ErrInvalidBatch, o.ID, len(selected))
+		}
+		return selected, nil
+	}
+	selector, err := ParseSelector(o.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidBatch, err)
+	}
+	for _, c := range containers {
+		if selector.Match(c) {
+			selected = append(selected, c)
+		}
+	}
+	return selected, nil
+}
+
+// ValidateBatch checks every operation of a batch
+func ValidateBatch(ops []*BatchOperation) error {
+	if len(ops) == 0 {
+		return fmt.Errorf("%w: no operations", ErrInvalidBatch)
+	}
+	for i, o := range ops {
+		if o == nil {
+			return fmt.Errorf("%w: operation %d is empty", ErrInvalidBatch, i)
+		}
+		if err := o.Validate(); err != nil {
+			return fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return nil
+}
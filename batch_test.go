@@ -0,0 +1,59 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestBatchOperationValidate(t *testing.T) {
+	valid := []*BatchOperation{
+		{Action: BatchDestroy, ID: "abc"},
+		{Action: BatchRestart, Selector: "tier=web"},
+	}
+	if err := ValidateBatch(valid); err != nil {
+		t.Fatal(err)
+	}
+	invalid := [][]*BatchOperation{
+		nil,
+		{{Action: "kill", ID: "abc"}},
+		{{Action: BatchStop}},
+		{{Action: BatchStop, ID: "abc", Selector: "tier=web"}},
+		{{Action: BatchStop, ID: "abc", Timeout: -1}},
+	}
+	for i, ops := range invalid {
+		if err := ValidateBatch(ops); !errors.Is(err, ErrInvalidBatch) {
+			t.Errorf("%d: expected ErrInvalidBatch; received %v", i, err)
+		}
+	}
+}
+
+func TestBatchOperationSelect(t *testing.T) {
+	web := &citadel.Image{Name: "nginx"}
+	if err := SetLabels(web, map[string]string{"tier": "web"}); err != nil {
+		t.Fatal(err)
+	}
+	containers := []*citadel.Container{
+		{ID: "aaa111", Image: web},
+		{ID: "bbb222", Image: web},
+		{ID: "ccc333", Image: &citadel.Image{Name: "redis"}},
+	}
+	if s, err := (&BatchOperation{Action: BatchStop, ID: "ccc"}).Select(containers); err != nil || len(s) != 1 || s[0].ID != "ccc333" {
+		t.Errorf("expected ccc333; received %v %v", s, err)
+	}
+	if s, err := (&BatchOperation{Action: BatchStop, Selector: "tier=web"}).Select(containers); err != nil || len(s) != 2 {
+		t.Errorf("expected 2 web containers; received %d %v", len(s), err)
+	}
+	if s, err := (&BatchOperation{Action: BatchStop, ID: "ddd"}).Select(containers); err != nil || len(s) != 0 {
+		t.Errorf("expected no containers; received %d %v", len(s), err)
+	}
+
+	containers = append(containers, &citadel.Container{ID: "ccc", Image: web}, &citadel.Container{ID: "aaa112", Image: web})
+	if _, err := (&BatchOperation{Action: BatchStop, ID: "aaa11"}).Select(containers); !errors.Is(err, ErrInvalidBatch) {
+		t.Errorf("expected an ambiguous prefix to be rejected; received %v", err)
+	}
+	if s, err := (&BatchOperation{Action: BatchStop, ID: "ccc"}).Select(containers); err != nil || len(s) != 1 || s[0].ID != "ccc" {
+		t.Errorf("expected the exact id ccc; received %v %v", s, err)
+	}
+}
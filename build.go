@@ -0,0 +1,56 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// BuildEngineLabel marks engines dedicated to image builds.  Builds
+	// without an engine run on one of them when any is available.
+	BuildEngineLabel = "build"
+)
+
+var (
+	ErrInvalidBuild = errors.New("invalid build")
+	ErrBuildFailed  = errors.New("build failed")
+)
+
+type (
+	// BuildOptions describe an image build from a tar context
+	BuildOptions struct {
+		// Tag names the image, i.e. app:1.0
+		Tag string `json:"tag,omitempty"`
+		// Engine is the id of the engine to build on; empty selects one
+		Engine string `json:"engine,omitempty"`
+		// Dockerfile is the path of the Dockerfile in the context
+		Dockerfile string `json:"dockerfile,omitempty"`
+		NoCache    bool   `json:"no_cache,omitempty"`
+		// Pull fetches newer versions of the base image
+		Pull bool `json:"pull,omitempty"`
+	}
+
+	// BuildMessage is a line of build output; a message with an Error ends
+	// a failed build
+	BuildMessage struct {
+		Stream string `json:"stream,omitempty"`
+		Error  string `json:"error,omitempty"`
+	}
+)
+
+func (o *BuildOptions) Validate() error {
+	if strings.ContainsAny(o.Tag, " @") || strings.HasSuffix(o.Tag, ":") {
+		return fmt.Errorf("%w: %q is not a valid image name", ErrInvalidBuild, o.Tag)
+	}
+	if strings.HasPrefix(o.Dockerfile, "/") || strings.HasPrefix(o.Dockerfile, "..") {
+		return fmt.Errorf("%w: dockerfile must be relative to the context", ErrInvalidBuild)
+	}
+	return nil
+}
+
+// IsBuildEngine reports whether engine labels mark a dedicated build engine
+func IsBuildEngine(labels []string) bool {
+	v, ok := ParseLabels(labels)[BuildEngineLabel]
+	return ok && v != "false"
+}
@@ -0,0 +1,37 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuildOptionsValidate(t *testing.T) {
+	for _, o := range []*BuildOptions{{}, {Tag: "app:1.0", Dockerfile: "docker/Dockerfile"}} {
+		if err := o.Validate(); err != nil {
+			t.Errorf("%+v: %s", o, err)
+		}
+	}
+	for _, o := range []*BuildOptions{{Tag: "my app"}, {Tag: "app:"}, {Dockerfile: "/Dockerfile"}, {Dockerfile: "../Dockerfile"}} {
+		if err := o.Validate(); !errors.Is(err, ErrInvalidBuild) {
+			t.Errorf("%+v: expected ErrInvalidBuild; received %v", o, err)
+		}
+	}
+}
+
+func TestIsBuildEngine(t *testing.T) {
+	tests := []struct {
+		labels []string
+		build  bool
+	}{
+		{nil, false},
+		{[]string{"region=us"}, false},
+		{[]string{"build"}, true},
+		{[]string{"ssd", "build=true"}, true},
+		{[]string{"build=false"}, false},
+	}
+	for _, test := range tests {
+		if b := IsBuildEngine(test.labels); b != test.build {
+			t.Errorf("%v: expected %v; received %v", test.labels, test.build, b)
+		}
+	}
+}
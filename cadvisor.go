@@ -0,0 +1,241 @@
+package shipyard
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultCAdvisorPort = 8080
+
+	MetricsSourceDocker   = "docker"
+	MetricsSourceCAdvisor = "cadvisor"
+)
+
+var (
+	ErrInvalidCAdvisor = errors.New("invalid cadvisor")
+
+	// dockerIDPattern finds the container id in the cgroup of a cAdvisor
+	// series, like /docker/<id> or /system.slice/docker-<id>.scope
+	dockerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+)
+
+type (
+	// CAdvisor is where cAdvisor runs on the host of an engine.  When an
+	// engine has one, container metrics are scraped from its prometheus
+	// endpoint instead of read from the docker stats api.
+	CAdvisor struct {
+		// URL is the cAdvisor address, like http://10.0.0.5:8080, on the
+		// host of the engine; when empty the host of the engine address
+		// is used with Port
+		URL string `json:"url,omitempty" gorethink:"url,omitempty"`
+		// Port defaults to DefaultCAdvisorPort
+		Port int `json:"port,omitempty" gorethink:"port,omitempty"`
+	}
+
+	// CAdvisorUsage is the cumulative resource usage of a container
+	// scraped from cAdvisor
+	CAdvisorUsage struct {
+		CpuSeconds     float64
+		MemoryUsage    uint64
+		MemoryLimit    uint64
+		DiskReadBytes  uint64
+		DiskWriteBytes uint64
+		NetworkRxBytes uint64
+		NetworkTxBytes uint64
+	}
+)
+
+// Validate checks the cAdvisor of an engine at engineAddr; a url must be on
+// the host of the engine so the controller is not made to request other
+// hosts
+func (c *CAdvisor) Validate(engineAddr string) error {
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("%w: port %d", ErrInvalidCAdvisor, c.Port)
+	}
+	if c.URL != "" {
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: url must be http(s)://host[:port]: %s", ErrInvalidCAdvisor, c.URL)
+		}
+		host, err := engineHost(engineAddr)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(u.Hostname(), host) {
+			return fmt.Errorf("%w: url must be on the engine host %s: %s", ErrInvalidCAdvisor, host, c.URL)
+		}
+	}
+	return nil
+}
+
+// engineHost returns the host of an engine address; engines on a unix
+// socket are on localhost
+func engineHost(engineAddr string) (string, error) {
+	u, err := url.Parse(engineAddr)
+	if err != nil {
+		return "", err
+	}
+	host := u.Hostname()
+	if u.Scheme == "unix" || host == "" {
+		host = "localhost"
+	}
+	return host, nil
+}
+
+// MetricsURL returns the prometheus endpoint of cAdvisor for an engine at
+// engineAddr
+func (c *CAdvisor) MetricsURL(engineAddr string) (string, error) {
+	if err := c.Validate(engineAddr); err != nil {
+		return "", err
+	}
+	if c.URL != "" {
+		return strings.TrimSuffix(c.URL, "/") + "/metrics", nil
+	}
+	host, err := engineHost(engineAddr)
+	if err != nil {
+		return "", err
+	}
+	port := c.Port
+	if port == 0 {
+		port = DefaultCAdvisorPort
+	}
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(port)) + "/metrics", nil
+}
+
+// ParseCAdvisorMetrics reads the container series of the cAdvisor
+// prometheus text format by docker container id.  Series of other cgroups
+// are ignored; per cpu, device and interface series are summed.
+func ParseCAdvisorMetrics(r io.Reader) (map[string]*CAdvisorUsage, error) {
+	usage := map[string]*CAdvisorUsage{}
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, labels, value, err := parsePrometheusLine(line)
+		if err != nil {
+			return nil, err
+		}
+		id := dockerIDPattern.FindString(labels["id"])
+		if id == "" {
+			continue
+		}
+		u, ok := usage[id]
+		if !ok {
+			u = &CAdvisorUsage{}
+			usage[id] = u
+		}
+		switch name {
+		case "container_cpu_usage_seconds_total":
+			u.CpuSeconds += value
+		case "container_memory_usage_bytes":
+			u.MemoryUsage += uint64(value)
+		case "container_spec_memory_limit_bytes":
+			u.MemoryLimit += uint64(value)
+		case "container_fs_reads_bytes_total":
+			u.DiskReadBytes += uint64(value)
+		case "container_fs_writes_bytes_total":
+			u.DiskWriteBytes += uint64(value)
+		case "container_network_receive_bytes_total":
+			u.NetworkRxBytes += uint64(value)
+		case "container_network_transmit_bytes_total":
+			u.NetworkTxBytes += uint64(value)
+		}
+	}
+	return usage, s.Err()
+}
+
+// parsePrometheusLine splits a sample line, name{label="value",...} value
+// [timestamp], of the prometheus text format
+func parsePrometheusLine(line string) (string, map[string]string, float64, error) {
+	labels := map[string]string{}
+	name := line
+	rest := ""
+	if i := strings.IndexAny(line, "{ "); i >= 0 {
+		name, rest = line[:i], line[i:]
+	}
+	if strings.HasPrefix(rest, "{") {
+		rest = rest[1:]
+		for {
+			rest = strings.TrimLeft(rest, ", ")
+			if strings.HasPrefix(rest, "}") {
+				rest = rest[1:]
+				break
+			}
+			eq := strings.Index(rest, "=")
+			if eq < 0 || len(rest) < eq+2 || rest[eq+1] != '"' {
+				return "", nil, 0, fmt.Errorf("invalid labels: %s", line)
+			}
+			key := strings.TrimSpace(rest[:eq])
+			rest = rest[eq+2:]
+			var val strings.Builder
+			closed := false
+			for i := 0; i < len(rest); i++ {
+				switch c := rest[i]; {
+				case c == '\\' && i+1 < len(rest):
+					i++
+					switch rest[i] {
+					case 'n':
+						val.WriteByte('\n')
+					default:
+						val.WriteByte(rest[i])
+					}
+				case c == '"':
+					rest = rest[i+1:]
+					closed = true
+				default:
+					val.WriteByte(c)
+				}
+				if closed {
+					break
+				}
+			}
+			if !closed {
+				return "", nil, 0, fmt.Errorf("invalid labels: %s", line)
+			}
+			labels[key] = val.String()
+		}
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", nil, 0, fmt.Errorf("missing value: %s", line)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("invalid value: %s", line)
+	}
+	return name, labels, value, nil
+}
+
+// Sample converts the usage to a metric sample.  The cpu percentage is the
+// cpu time used since prev, scraped elapsed earlier; 100 percent is one
+// fully used cpu.  Without a previous scrape it is zero.
+func (u *CAdvisorUsage) Sample(containerID string, t time.Time, prev *CAdvisorUsage, elapsed time.Duration) *MetricSample {
+	s := &MetricSample{
+		Resource:       MetricsContainers,
+		ResourceID:     containerID,
+		Time:           t,
+		Source:         MetricsSourceCAdvisor,
+		MemoryUsage:    u.MemoryUsage,
+		MemoryLimit:    u.MemoryLimit,
+		DiskReadBytes:  u.DiskReadBytes,
+		DiskWriteBytes: u.DiskWriteBytes,
+		NetworkRxBytes: u.NetworkRxBytes,
+		NetworkTxBytes: u.NetworkTxBytes,
+	}
+	if prev != nil && elapsed > 0 && u.CpuSeconds >= prev.CpuSeconds {
+		s.CpuPercent = (u.CpuSeconds - prev.CpuSeconds) / elapsed.Seconds() * 100.0
+	}
+	return s
+}
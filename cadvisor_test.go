@@ -0,0 +1,113 @@
+package shipyard
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testContainerA = "4f66ad9a0b2e4f2aa0d8e4b3b1c3d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8"
+	testContainerB = "9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b"
+)
+
+var testCAdvisorMetrics = `# HELP container_cpu_usage_seconds_total Cumulative cpu time consumed in seconds.
+# TYPE container_cpu_usage_seconds_total counter
+container_cpu_usage_seconds_total{cpu="cpu00",id="/docker/` + testContainerA + `",image="redis",name="redis"} 10.5
+container_cpu_usage_seconds_total{cpu="cpu01",id="/docker/` + testContainerA + `",image="redis",name="redis"} 2.5
+container_cpu_usage_seconds_total{id="/system.slice/docker-` + testContainerB + `.scope",name="web"} 4 1425203500000
+container_cpu_usage_seconds_total{id="/"} 1000
+container_memory_usage_bytes{id="/docker/` + testContainerA + `",name="redis"} 1.048576e+06
+container_spec_memory_limit_bytes{id="/docker/` + testContainerA + `",name="redis"} 2097152
+container_fs_reads_bytes_total{device="/dev/sda",id="/docker/` + testContainerA + `"} 100
+container_fs_writes_bytes_total{device="/dev/sda",id="/docker/` + testContainerA + `"} 200
+container_network_receive_bytes_total{id="/docker/` + testContainerA + `",interface="eth0"} 300
+container_network_receive_bytes_total{id="/docker/` + testContainerA + `",interface="eth1"} 30
+container_network_transmit_bytes_total{id="/docker/` + testContainerA + `",interface="eth0"} 400
+container_last_seen{id="/docker/` + testContainerA + `",name="a \"quoted\", name"} 1.4252035e+09
+`
+
+func TestParseCAdvisorMetrics(t *testing.T) {
+	usage, err := ParseCAdvisorMetrics(strings.NewReader(testCAdvisorMetrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 2 {
+		t.Fatalf("expected the two containers; received %d", len(usage))
+	}
+	expected := CAdvisorUsage{
+		CpuSeconds:     13,
+		MemoryUsage:    1048576,
+		MemoryLimit:    2097152,
+		DiskReadBytes:  100,
+		DiskWriteBytes: 200,
+		NetworkRxBytes: 330,
+		NetworkTxBytes: 400,
+	}
+	if *usage[testContainerA] != expected {
+		t.Errorf("expected %+v; received %+v", expected, *usage[testContainerA])
+	}
+	if usage[testContainerB].CpuSeconds != 4 {
+		t.Errorf("expected the systemd cgroup series; received %+v", *usage[testContainerB])
+	}
+
+	if _, err := ParseCAdvisorMetrics(strings.NewReader(`container_cpu_usage_seconds_total{id="/docker/x} 1`)); err == nil {
+		t.Error("expected an error for unterminated labels")
+	}
+}
+
+func TestCAdvisorUsageSample(t *testing.T) {
+	now := time.Now()
+	u := &CAdvisorUsage{CpuSeconds: 13, MemoryUsage: 10}
+	s := u.Sample(testContainerA, now, nil, 0)
+	if s.CpuPercent != 0 || s.MemoryUsage != 10 || s.Source != MetricsSourceCAdvisor || s.ResourceID != testContainerA {
+		t.Errorf("unexpected first sample %+v", s)
+	}
+	s = u.Sample(testContainerA, now, &CAdvisorUsage{CpuSeconds: 10}, 2*time.Second)
+	if s.CpuPercent != 150 {
+		t.Errorf("expected 150 percent; received %v", s.CpuPercent)
+	}
+}
+
+func TestCAdvisorMetricsURL(t *testing.T) {
+	tests := []struct {
+		cadvisor CAdvisor
+		addr     string
+		url      string
+	}{
+		{CAdvisor{}, "http://10.0.0.5:2375", "http://10.0.0.5:8080/metrics"},
+		{CAdvisor{Port: 9090}, "tcp://node-1:2376", "http://node-1:9090/metrics"},
+		{CAdvisor{}, "unix:///var/run/docker.sock", "http://localhost:8080/metrics"},
+		{CAdvisor{URL: "https://10.0.0.5/cadvisor/", Port: 9090}, "http://10.0.0.5:2375", "https://10.0.0.5/cadvisor/metrics"},
+		{CAdvisor{URL: "http://LOCALHOST:9090"}, "unix:///var/run/docker.sock", "http://LOCALHOST:9090/metrics"},
+	}
+	for _, test := range tests {
+		u, err := test.cadvisor.MetricsURL(test.addr)
+		if err != nil || u != test.url {
+			t.Errorf("%s: expected %s; received %s %v", test.addr, test.url, u, err)
+		}
+	}
+}
+
+func TestCAdvisorValidate(t *testing.T) {
+	invalid := []CAdvisor{
+		{Port: -1},
+		{Port: 70000},
+		{URL: "10.0.0.5:8080"},
+		{URL: "ftp://10.0.0.5"},
+		{URL: "http://169.254.169.254/latest"},
+		{URL: "http://10.0.0.6:8080"},
+	}
+	for _, c := range invalid {
+		if err := c.Validate("tcp://10.0.0.5:2376"); !errors.Is(err, ErrInvalidCAdvisor) {
+			t.Errorf("%+v: expected ErrInvalidCAdvisor; received %v", c, err)
+		}
+	}
+	if _, err := (&CAdvisor{URL: "http://metrics.local:8080"}).MetricsURL("tcp://10.0.0.5:2376"); !errors.Is(err, ErrInvalidCAdvisor) {
+		t.Errorf("expected no metrics url off the engine host; received %v", err)
+	}
+	if err := (&CAdvisor{URL: "http://10.0.0.5:8080", Port: 8080}).Validate("tcp://10.0.0.5:2376"); err != nil {
+		t.Error(err)
+	}
+}
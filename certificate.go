@@ -0,0 +1,113 @@
+package shipyard
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidCertificate = errors.New("invalid certificate")
+)
+
+type (
+	// EngineCertificates are the pem encoded certificates the controller
+	// uses for the docker api of an engine.  The client certificate and
+	// key authenticate the controller; the ca certificate is the authority
+	// of the engine.
+	EngineCertificates struct {
+		CACertificate  string `json:"ca_cert,omitempty"`
+		SSLCertificate string `json:"ssl_cert,omitempty"`
+		SSLKey         string `json:"ssl_key,omitempty"`
+	}
+
+	// CertificateInfo describes a certificate without its key
+	CertificateInfo struct {
+		Subject     string    `json:"subject,omitempty"`
+		Issuer      string    `json:"issuer,omitempty"`
+		NotBefore   time.Time `json:"not_before,omitempty"`
+		NotAfter    time.Time `json:"not_after,omitempty"`
+		DNSNames    []string  `json:"dns_names,omitempty"`
+		IPAddresses []string  `json:"ip_addresses,omitempty"`
+	}
+
+	// EngineCertificateInfo describes the certificates of an engine
+	EngineCertificateInfo struct {
+		Client *CertificateInfo `json:"client,omitempty"`
+		CA     *CertificateInfo `json:"ca,omitempty"`
+	}
+)
+
+// Validate checks the certificates are either all empty or a ca
+// certificate and a client certificate matching its key
+func (c *EngineCertificates) Validate() error {
+	if c.CACertificate == "" && c.SSLCertificate == "" && c.SSLKey == "" {
+		return nil
+	}
+	if c.CACertificate == "" || c.SSLCertificate == "" || c.SSLKey == "" {
+		return fmt.Errorf("%w: ca_cert, ssl_cert and ssl_key must be set together", ErrInvalidCertificate)
+	}
+	if _, err := tls.X509KeyPair([]byte(c.SSLCertificate), []byte(c.SSLKey)); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidCertificate, err)
+	}
+	if _, err := ParseCertificate(c.CACertificate); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Certificates returns the certificates of the engine
+func (e *Engine) Certificates() *EngineCertificates {
+	return &EngineCertificates{
+		CACertificate:  e.CACertificate,
+		SSLCertificate: e.SSLCertificate,
+		SSLKey:         e.SSLKey,
+	}
+}
+
+// CertificateInfo describes the certificates of the engine; engines
+// without certificates return an empty description
+func (e *Engine) CertificateInfo() (*EngineCertificateInfo, error) {
+	info := &EngineCertificateInfo{}
+	if e.SSLCertificate != "" {
+		c, err := ParseCertificate(e.SSLCertificate)
+		if err != nil {
+			return nil, err
+		}
+		info.Client = c
+	}
+	if e.CACertificate != "" {
+		c, err := ParseCertificate(e.CACertificate)
+		if err != nil {
+			return nil, err
+		}
+		info.CA = c
+	}
+	return info, nil
+}
+
+// ParseCertificate describes the first certificate of a pem bundle
+func ParseCertificate(data string) (*CertificateInfo, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%w: no pem encoded certificate", ErrInvalidCertificate)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCertificate, err)
+	}
+	info := &CertificateInfo{
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+		DNSNames:  cert.DNSNames,
+	}
+	for _, ip := range cert.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	return info, nil
+}
@@ -0,0 +1,102 @@
+package shipyard
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/citadel/citadel"
+)
+
+// testCertificate returns a pem encoded self signed certificate and key
+func testCertificate(t *testing.T, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("10.0.0.5")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+}
+
+func TestEngineCertificatesValidate(t *testing.T) {
+	ca, _ := testCertificate(t, "ca")
+	cert, key := testCertificate(t, "shipyard")
+	_, otherKey := testCertificate(t, "other")
+
+	valid := []*EngineCertificates{
+		{},
+		{CACertificate: ca, SSLCertificate: cert, SSLKey: key},
+	}
+	for _, c := range valid {
+		if err := c.Validate(); err != nil {
+			t.Error(err)
+		}
+	}
+	invalid := []*EngineCertificates{
+		{SSLCertificate: cert, SSLKey: key},
+		{CACertificate: ca, SSLCertificate: cert, SSLKey: otherKey},
+		{CACertificate: "ca", SSLCertificate: cert, SSLKey: key},
+	}
+	for i, c := range invalid {
+		if err := c.Validate(); !errors.Is(err, ErrInvalidCertificate) {
+			t.Errorf("%d: expected ErrInvalidCertificate; got %v", i, err)
+		}
+	}
+}
+
+func TestEngineCertificateInfo(t *testing.T) {
+	cert, key := testCertificate(t, "shipyard")
+	e := &Engine{SSLCertificate: cert, SSLKey: key}
+	info, err := e.CertificateInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.CA != nil || info.Client == nil || info.Client.Subject != "CN=shipyard" {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	if len(info.Client.IPAddresses) != 1 || info.Client.IPAddresses[0] != "10.0.0.5" {
+		t.Errorf("unexpected ip addresses %v", info.Client.IPAddresses)
+	}
+	if r := e.Redacted(); r.SSLKey != "" || r.SSLCertificate != cert || e.SSLKey != key {
+		t.Error("expected only the copy to lose its key")
+	}
+}
+
+func TestEnginePingVerifiesCA(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	ca := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+	eng := &Engine{Engine: &citadel.Engine{Addr: srv.URL}, CACertificate: ca}
+	if status, err := eng.Ping(); err != nil || status != http.StatusOK {
+		t.Fatalf("expected the engine to be verified with its ca; received %d %v", status, err)
+	}
+	eng.CACertificate, _ = testCertificate(t, "other")
+	if _, err := eng.Ping(); err == nil {
+		t.Error("expected an engine not signed by the ca to be refused")
+	}
+}
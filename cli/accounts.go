@@ -14,6 +14,21 @@ var accountsCommand = cli.Command{
 	Name:   "accounts",
 	Usage:  "show accounts",
 	Action: accountsAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "role",
+			Usage: "only show accounts with this role",
+		},
+		cli.IntFlag{
+			Name:  "limit",
+			Usage: "maximum number of accounts to show",
+		},
+		cli.IntFlag{
+			Name:  "offset",
+			Usage: "number of accounts to skip",
+		},
+		outputFlag,
+	},
 }
 
 func accountsAction(c *cli.Context) {
@@ -22,10 +37,17 @@ func accountsAction(c *cli.Context) {
 		logger.Fatal(err)
 	}
 	m := client.NewManager(cfg)
-	accounts, err := m.Accounts()
+	accounts, err := m.QueryAccounts(&shipyard.AccountQuery{
+		Role:   c.String("role"),
+		Limit:  c.Int("limit"),
+		Offset: c.Int("offset"),
+	})
 	if err != nil {
 		logger.Fatalf("error getting accounts: %s", err)
 	}
+	if formatted(c, accounts) {
+		return
+	}
 	if len(accounts) == 0 {
 		return
 	}
@@ -82,6 +104,72 @@ func addAccountAction(c *cli.Context) {
 	}
 }
 
+var updateAccountCommand = cli.Command{
+	Name:   "update-account",
+	Usage:  "update account",
+	Action: updateAccountAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "username, u",
+			Usage: "account username",
+		},
+		cli.StringFlag{
+			Name:  "password, p",
+			Usage: "new account password",
+		},
+		cli.StringFlag{
+			Name:  "role, r",
+			Usage: "account role (admin, user)",
+		},
+		cli.StringFlag{
+			Name:  "full-name",
+			Usage: "account full name",
+		},
+		cli.StringFlag{
+			Name:  "email",
+			Usage: "account email",
+		},
+	},
+}
+
+func updateAccountAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	user := c.String("username")
+	if user == "" {
+		logger.Fatalf("you must specify a username")
+	}
+	accounts, err := m.Accounts()
+	if err != nil {
+		logger.Fatalf("error getting accounts: %s", err)
+	}
+	var account *shipyard.Account
+	for _, a := range accounts {
+		if a.Username == user {
+			account = a
+		}
+	}
+	if account == nil {
+		logger.Fatalf("account %s does not exist", user)
+	}
+	account.Password = c.String("password")
+	if role := c.String("role"); role != "" {
+		account.Role = &shipyard.Role{Name: role}
+	}
+	if name := c.String("full-name"); name != "" {
+		account.FullName = name
+	}
+	if email := c.String("email"); email != "" {
+		account.Email = email
+	}
+	if err := m.UpdateAccount(account); err != nil {
+		logger.Fatalf("error updating account: %s", err)
+	}
+}
+
 var deleteAccountCommand = cli.Command{
 	Name:        "delete-account",
 	Usage:       "delete account",
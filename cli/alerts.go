@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var alertsListCommand = cli.Command{
+	Name:   "alerts",
+	Usage:  "list firing alerts",
+	Action: alertsListAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func alertsListAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	alerts, err := m.Alerts()
+	if err != nil {
+		logger.Fatalf("error getting alerts: %s", err)
+	}
+	if formatted(c, alerts) {
+		return
+	}
+	if len(alerts) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Rule\tSeverity\tResource\tValue\tSince\tMessage")
+	for _, a := range alerts {
+		resource := a.Resource
+		if id := a.ResourceID; id != "" {
+			if a.Resource == shipyard.MetricsContainers && len(id) > 12 {
+				id = id[:12]
+			}
+			resource = fmt.Sprintf("%s/%s", a.Resource, id)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%g\t%s\t%s\n", a.RuleName, a.Severity, resource, a.Value, a.Since.Format(time.RubyDate), a.Message)
+	}
+	w.Flush()
+}
+
+var alertRulesListCommand = cli.Command{
+	Name:   "alert-rules",
+	Usage:  "list alert rules",
+	Action: alertRulesListAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func alertRulesListAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	rules, err := m.AlertRules()
+	if err != nil {
+		logger.Fatalf("error getting alert rules: %s", err)
+	}
+	if formatted(c, rules) {
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "ID\tName\tType\tThreshold\tWindow\tSeverity\tEvents")
+	for _, r := range rules {
+		severity := r.Severity
+		if severity == "" {
+			severity = shipyard.SeverityWarning
+		}
+		events := ""
+		if len(r.EventTypes) > 0 {
+			events = strings.Join(r.EventTypes, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%g\t%s\t%s\t%s\n", r.ID, r.Name, r.Type, r.Threshold, r.WindowDuration(), severity, events)
+	}
+	w.Flush()
+}
+
+var alertRuleCreateCommand = cli.Command{
+	Name:        "add-alert-rule",
+	Usage:       "fire alerts when a metric, reservation or event count exceeds a threshold",
+	Description: "add-alert-rule [options] <name>",
+	Action:      alertRuleCreateAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "type, t",
+			Value: "",
+			Usage: "container-restarts, container-cpu, container-memory, engine-memory-reserved, engine-cpu-reserved or events",
+		},
+		cli.Float64Flag{
+			Name:  "threshold",
+			Value: 0,
+			Usage: "fire when the value exceeds this (count or percent)",
+		},
+		cli.IntFlag{
+			Name:  "window",
+			Value: shipyard.DefaultAlertWindow,
+			Usage: "minutes of events counted by container-restarts and events rules",
+		},
+		cli.StringSliceFlag{
+			Name:  "event, e",
+			Value: &cli.StringSlice{},
+			Usage: "event type counted by events rules; can be repeated",
+		},
+		cli.StringFlag{
+			Name:  "severity",
+			Value: "",
+			Usage: "severity of the alerts (info, warning, critical); defaults to warning",
+		},
+	},
+}
+
+func alertRuleCreateAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	rule := &shipyard.AlertRule{
+		Name:       c.Args().First(),
+		Type:       c.String("type"),
+		Threshold:  c.Float64("threshold"),
+		Window:     c.Int("window"),
+		EventTypes: c.StringSlice("event"),
+		Severity:   c.String("severity"),
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	created, err := m.AddAlertRule(rule)
+	if err != nil {
+		logger.Fatalf("error adding alert rule: %s", err)
+	}
+	fmt.Printf("added alert rule %s\n", created.ID)
+}
+
+var alertRuleRemoveCommand = cli.Command{
+	Name:        "remove-alert-rule",
+	Usage:       "removes an alert rule and its alerts",
+	Description: "remove-alert-rule <id> [<id>]",
+	Action:      alertRuleRemoveAction,
+}
+
+func alertRuleRemoveAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	for _, id := range c.Args() {
+		if err := m.RemoveAlertRule(id); err != nil {
+			logger.Fatalf("error removing alert rule: %s", err)
+		}
+		fmt.Printf("removed %s\n", id)
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var applicationsListCommand = cli.Command{
+	Name:   "apps",
+	Usage:  "list applications",
+	Action: applicationsListAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func applicationsListAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	apps, err := m.Applications()
+	if err != nil {
+		logger.Fatalf("error getting applications: %s", err)
+	}
+	if formatted(c, apps) {
+		return
+	}
+	if len(apps) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Name\tImage\tRunning\tCount\tConstraints")
+	for _, app := range apps {
+		containers, err := m.ApplicationContainers(app.Name)
+		if err != nil {
+			logger.Fatalf("error getting application containers: %s", err)
+		}
+		running := 0
+		for _, cnt := range containers {
+			if cnt.State == "running" {
+				running++
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", app.Name, app.Image, running, app.Count, strings.Join(app.Constraints, " "))
+	}
+	w.Flush()
+}
+
+var applicationCreateCommand = cli.Command{
+	Name:        "create-app",
+	Usage:       "create an application that is kept at a count of containers",
+	Description: "create-app [options] <name>",
+	Action:      applicationCreateAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "image",
+			Value: "",
+			Usage: "image name",
+		},
+		cli.IntFlag{
+			Name:  "count",
+			Value: 1,
+			Usage: "number of containers to keep running",
+		},
+		cli.Float64Flag{
+			Name:  "cpus",
+			Value: 0.1,
+			Usage: "cpu shares",
+		},
+		cli.Float64Flag{
+			Name:  "memory",
+			Value: 256,
+			Usage: "memory (in MB)",
+		},
+		cli.StringSliceFlag{
+			Name:  "env",
+			Usage: "environment variables (key=value pairs)",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "arg",
+			Usage: "run arguments",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "constraint",
+			Usage: "only run on engines with matching labels, i.e. --constraint region=us-east,ssd=true",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "port",
+			Usage: "expose container ports. usage: --port <proto>/<host-ip>:<host-port>:<container-port> i.e. --port tcp/::8080",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "secret",
+			Usage: "inject a secret as an env var or file, i.e. --secret db-password, --secret db-password=DB_PASS or --secret db-password=/dev/shm/db-password",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "config",
+			Usage: "add the settings of a config bundle, i.e. --config production; env values can use {{config \"production\" \"DB_HOST\"}}",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "affinity",
+			Usage: "only run on engines running a container of the image, i.e. --affinity redis",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "anti-affinity",
+			Usage: "never run on engines running a container of the image",
+			Value: &cli.StringSlice{},
+		},
+		cli.BoolFlag{
+			Name:  "spread",
+			Usage: "never run two replicas on the same engine",
+		},
+		cli.StringFlag{
+			Name:  "spread-by",
+			Usage: "balance replicas across the values of an engine label, i.e. --spread-by zone",
+		},
+		cli.StringFlag{
+			Name:  "pool",
+			Usage: "only run on the engines of an engine pool",
+		},
+		cli.StringFlag{
+			Name:  "placement",
+			Usage: "placement strategy overriding the controller default: spread, binpack, random or label-affinity",
+		},
+		cli.StringSliceFlag{
+			Name:  "container-label",
+			Usage: "label the containers for selection, i.e. --container-label tier=web",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringFlag{
+			Name:  "pre-stop",
+			Value: "",
+			Usage: "command run in the container before it is stopped, i.e. --pre-stop \"sh -c 'sleep 5 && nginx -s quit'\"",
+		},
+		cli.IntFlag{
+			Name:  "stop-grace-period",
+			Value: 0,
+			Usage: "seconds the container is given to exit when stopped or removed before it is killed",
+		},
+		cli.BoolFlag{
+			Name:  "auto-deploy",
+			Usage: "redeploy the application when its image tag is pushed again",
+		},
+	},
+}
+
+func applicationCreateAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	if c.String("image") == "" {
+		logger.Fatal("you must specify an image")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	app := &shipyard.Application{
+		Name:        c.Args().First(),
+		Image:       c.String("image"),
+		Count:       c.Int("count"),
+		Cpus:        c.Float64("cpus"),
+		Memory:      c.Float64("memory"),
+		Args:        c.StringSlice("arg"),
+		Environment: parseEnvironmentVariables(c.StringSlice("env")),
+		Ports:       parsePorts(c.StringSlice("port")),
+		Constraints: c.StringSlice("constraint"),
+		Secrets:     parseSecretRefs(c.StringSlice("secret")),
+		Configs:     c.StringSlice("config"),
+		Pool:        c.String("pool"),
+		Placement:   c.String("placement"),
+		Affinity:    parseAffinity(c),
+		Labels:      parseContainerLabels(c),
+		StopHook:    parseStopHook(c),
+		AutoDeploy:  c.Bool("auto-deploy"),
+	}
+	if _, err := m.CreateApplication(app); err != nil {
+		logger.Fatalf("error creating application: %s", err)
+	}
+	fmt.Printf("created application %s\n", app.Name)
+}
+
+var applicationScaleCommand = cli.Command{
+	Name:        "scale-app",
+	Usage:       "change the container count of an application",
+	Description: "scale-app <name> <count>",
+	Action:      applicationScaleAction,
+}
+
+func applicationScaleAction(c *cli.Context) {
+	if len(c.Args()) != 2 {
+		logger.Fatal("you must specify a name and count")
+	}
+	count, err := strconv.Atoi(c.Args().Get(1))
+	if err != nil {
+		logger.Fatalf("invalid count: %s", err)
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	app, err := m.Application(c.Args().First())
+	if err != nil {
+		logger.Fatalf("error getting application: %s", err)
+	}
+	app.Count = count
+	if err := m.UpdateApplication(app, false); err != nil {
+		logger.Fatalf("error scaling application: %s", err)
+	}
+	fmt.Printf("scaled application %s to %d\n", app.Name, count)
+}
+
+var applicationUpdateCommand = cli.Command{
+	Name:        "update-app",
+	Usage:       "replace the spec of an application",
+	Description: "update-app [options] <name>; removing env vars, secrets, configs, volumes or ports needs --confirm",
+	Action:      applicationUpdateAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "file, f",
+			Usage: "application spec (json)",
+		},
+		cli.BoolFlag{
+			Name:  "confirm",
+			Usage: "apply destructive changes",
+		},
+	},
+}
+
+func applicationUpdateAction(c *cli.Context) {
+	app := loadApplicationSpec(c)
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	if err := m.UpdateApplication(app, c.Bool("confirm")); err != nil {
+		logger.Fatalf("error updating application: %s", err)
+	}
+	fmt.Printf("updated application %s\n", app.Name)
+}
+
+var applicationDiffCommand = cli.Command{
+	Name:        "diff-app",
+	Usage:       "show what update-app would change in an application spec",
+	Description: "diff-app [options] <name>",
+	Action:      applicationDiffAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "file, f",
+			Usage: "application spec (json)",
+		},
+	},
+}
+
+func applicationDiffAction(c *cli.Context) {
+	app := loadApplicationSpec(c)
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	diff, err := m.DiffApplication(app)
+	if err != nil {
+		logger.Fatalf("error diffing application: %s", err)
+	}
+	if len(diff.Changes) == 0 {
+		fmt.Println("no changes")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Field\tChange\tFrom\tTo\tDestructive")
+	for _, ch := range diff.Changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%v\n", ch.Field, ch.Kind, ch.From, ch.To, ch.Destructive)
+	}
+	w.Flush()
+}
+
+// loadApplicationSpec reads the application spec of the --file flag and
+// names it after the first argument
+func loadApplicationSpec(c *cli.Context) *shipyard.Application {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	if c.String("file") == "" {
+		logger.Fatal("you must specify a spec file")
+	}
+	f, err := os.Open(c.String("file"))
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer f.Close()
+	var app *shipyard.Application
+	if err := json.NewDecoder(f).Decode(&app); err != nil {
+		logger.Fatalf("error reading spec: %s", err)
+	}
+	app.Name = c.Args().First()
+	return app
+}
+
+var applicationRemoveCommand = cli.Command{
+	Name:        "remove-app",
+	Usage:       "remove an application and its containers",
+	Description: "remove-app <name> [<name>]",
+	Action:      applicationRemoveAction,
+}
+
+func applicationRemoveAction(c *cli.Context) {
+	if len(c.Args()) == 0 {
+		logger.Fatal("you must specify a name")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	for _, name := range c.Args() {
+		if err := m.RemoveApplication(name); err != nil {
+			logger.Fatalf("error removing application: %s", err)
+		}
+		fmt.Printf("removed %s\n", name)
+	}
+}
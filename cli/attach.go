@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"code.google.com/p/go.crypto/ssh/terminal"
+	"github.com/codegangsta/cli"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/shipyard/shipyard/client"
+)
+
+var attachCommand = cli.Command{
+	Name:        "attach",
+	Usage:       "attach to the stdin, stdout and stderr of a running container",
+	Description: "attach <id>",
+	Action:      attachAction,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "no-stdin",
+			Usage: "do not send stdin to the container",
+		},
+	},
+}
+
+func attachAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	args := c.Args()
+	if len(args) == 0 {
+		logger.Fatal("you must specify a container id")
+	}
+	session, err := m.Attach(args[0])
+	if err != nil {
+		logger.Fatalf("error attaching to container: %s", err)
+	}
+	defer session.Close()
+
+	if session.Tty && !c.Bool("no-stdin") && terminal.IsTerminal(int(os.Stdin.Fd())) {
+		state, err := terminal.MakeRaw(int(os.Stdin.Fd()))
+		if err != nil {
+			logger.Fatalf("unable to set raw terminal: %s", err)
+		}
+		defer terminal.Restore(int(os.Stdin.Fd()), state)
+	}
+	if !c.Bool("no-stdin") {
+		go io.Copy(session, os.Stdin)
+	}
+	if session.Tty {
+		io.Copy(os.Stdout, session)
+	} else {
+		stdcopy.StdCopy(os.Stdout, os.Stderr, session)
+	}
+}
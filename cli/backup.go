@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard/client"
+)
+
+var backupCommand = cli.Command{
+	Name:        "backup",
+	Usage:       "back up the controller state",
+	Description: "backup [--output <file>]; the backup holds credentials and should be kept private",
+	Action:      backupAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "output, o",
+			Usage: "write the backup to a file instead of stdout",
+		},
+	},
+}
+
+func backupAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	backup, err := m.Backup()
+	if err != nil {
+		logger.Fatalf("error backing up controller: %s", err)
+	}
+	defer backup.Close()
+	out := os.Stdout
+	if o := c.String("output"); o != "" {
+		f, err := os.OpenFile(o, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		defer f.Close()
+		out = f
+	}
+	if _, err := io.Copy(out, backup); err != nil {
+		logger.Fatalf("error backing up controller: %s", err)
+	}
+}
+
+var restoreCommand = cli.Command{
+	Name:        "restore",
+	Usage:       "replace the controller state with a backup",
+	Description: "restore <file>; the backup is read from stdin when no file is given.  Tables are replaced one at a time; when a restore fails while loading them, run it again to complete it.",
+	Action:      restoreAction,
+}
+
+func restoreAction(c *cli.Context) {
+	in := os.Stdin
+	if len(c.Args()) > 0 {
+		f, err := os.Open(c.Args().First())
+		if err != nil {
+			logger.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	if err := m.Restore(in); err != nil {
+		logger.Fatalf("error restoring backup: %s", err)
+	}
+	fmt.Println("restored backup")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var batchCommand = cli.Command{
+	Name:        "batch",
+	Usage:       "destroy, stop or restart many containers in a single request",
+	Description: "batch <destroy|stop|restart> [<id>...]",
+	Action:      batchAction,
+	Flags: []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "selector",
+			Usage: "also apply to the containers matching a label selector, i.e. --selector tier=web",
+			Value: &cli.StringSlice{},
+		},
+		cli.IntFlag{
+			Name:  "timeout",
+			Usage: "seconds to wait for containers to stop before killing them",
+			Value: 10,
+		},
+	},
+}
+
+func batchAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	if len(c.Args()) == 0 {
+		logger.Fatal("you must specify an action")
+	}
+	action := c.Args().First()
+	ops := []*shipyard.BatchOperation{}
+	for _, id := range c.Args()[1:] {
+		ops = append(ops, &shipyard.BatchOperation{Action: action, ID: id, Timeout: c.Int("timeout")})
+	}
+	for _, s := range c.StringSlice("selector") {
+		ops = append(ops, &shipyard.BatchOperation{Action: action, Selector: s, Timeout: c.Int("timeout")})
+	}
+	if len(ops) == 0 {
+		logger.Fatal("you must specify at least one id or selector")
+	}
+	res, err := m.Batch(ops)
+	if err != nil {
+		logger.Fatalf("error running batch: %s", err)
+	}
+	for _, r := range res.Results {
+		if r.Error != "" {
+			fmt.Printf("%s %s failed: %s\n", r.Action, shortID(r.Container), r.Error)
+			continue
+		}
+		fmt.Printf("%s %s\n", r.Action, shortID(r.Container))
+	}
+	if res.Failed > 0 {
+		logger.Fatalf("%d of %d containers failed", res.Failed, len(res.Results))
+	}
+}
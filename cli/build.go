@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var buildCommand = cli.Command{
+	Name:        "build",
+	Usage:       "build an image on the cluster",
+	Description: "build [--tag <name>] <path>|-; '-' reads a tar context from stdin",
+	Action:      buildAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "tag, t",
+			Usage: "image name, i.e. app:1.0",
+		},
+		cli.StringFlag{
+			Name:  "file, f",
+			Usage: "path of the Dockerfile in the context",
+		},
+		cli.StringFlag{
+			Name:  "engine",
+			Usage: "engine to build on; defaults to a build engine",
+		},
+		cli.BoolFlag{
+			Name:  "no-cache",
+			Usage: "do not use the build cache",
+		},
+		cli.BoolFlag{
+			Name:  "pull",
+			Usage: "pull newer versions of the base image",
+		},
+	},
+}
+
+func buildAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	args := c.Args()
+	if len(args) != 1 {
+		logger.Fatalf("you must specify a build context")
+	}
+	opts := &shipyard.BuildOptions{
+		Tag:        c.String("tag"),
+		Engine:     c.String("engine"),
+		Dockerfile: c.String("file"),
+		NoCache:    c.Bool("no-cache"),
+		Pull:       c.Bool("pull"),
+	}
+	var context io.Reader = os.Stdin
+	if args[0] != "-" {
+		src := filepath.Clean(args[0])
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(writeArchive(pw, src, src))
+		}()
+		context = pr
+	}
+	if err := m.Build(context, opts, os.Stdout); err != nil {
+		logger.Fatalf("error building image: %s", err)
+	}
+}
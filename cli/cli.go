@@ -11,21 +11,12 @@ import (
 var (
 	shipyardHost string
 	logger       = logrus.New()
+	// tracer exports a span for each request when OTEL_EXPORTER_OTLP_ENDPOINT
+	// is set
+	tracer *shipyard.Tracer
 )
 
 func main() {
-	cfg, err := loadConfig(nil)
-	if err != nil {
-		if err != ErrConfigDoesNotExist {
-			logger.Fatal(err)
-		}
-	}
-	if cfg != nil {
-		sUrl := os.Getenv("SHIPYARD_URL")
-		if sUrl == "" {
-			cfg.Url = sUrl
-		}
-	}
 	app := cli.NewApp()
 	app.Name = "shipyard"
 	app.Usage = "manage a shipyard cluster"
@@ -36,25 +27,113 @@ func main() {
 			Name:  "allow-insecure",
 			Usage: "allow insecure certificates if using TLS",
 		},
+		cli.StringFlag{
+			Name:  "tls-ca-cert",
+			Value: "",
+			Usage: "path to ca certificate used to verify the controller",
+		},
+		cli.StringFlag{
+			Name:  "tls-cert",
+			Value: "",
+			Usage: "path to client ssl certificate",
+		},
+		cli.StringFlag{
+			Name:  "tls-key",
+			Value: "",
+			Usage: "path to client ssl key",
+		},
+		cli.StringFlag{
+			Name:  "context",
+			Value: "",
+			Usage: "config context to use instead of the current one",
+		},
+		cli.StringFlag{
+			Name:  "namespace",
+			Value: "",
+			Usage: "namespace to scope requests to (overrides the login namespace)",
+		},
+		cli.StringFlag{
+			Name:  "proxy",
+			Value: "",
+			Usage: "http, https or socks5 proxy url (defaults to the HTTP_PROXY and HTTPS_PROXY environment variables)",
+		},
+		cli.BoolFlag{
+			Name:  "wait-rate-limit",
+			Usage: "wait and resend requests rejected by the controller rate limits",
+		},
+		cli.BoolFlag{
+			Name:  "debug",
+			Usage: "log each request sent to the controller",
+		},
+		cli.BoolFlag{
+			Name:  "check-version",
+			Usage: "fail when the controller does not support the api version of the client",
+		},
 	}
 	app.Commands = []cli.Command{
 		loginCommand,
+		logoutCommand,
+		refreshTokenCommand,
+		enable2FACommand,
+		disable2FACommand,
 		changePasswordCommand,
 		accountsCommand,
 		addAccountCommand,
+		updateAccountCommand,
 		deleteAccountCommand,
+		rolesListCommand,
+		roleInspectCommand,
+		roleCreateCommand,
+		roleUpdateCommand,
+		roleDeleteCommand,
+		accountRoleCommand,
 		containersCommand,
 		containerInspectCommand,
+		endpointsCommand,
 		runCommand,
+		startCommand,
 		stopCommand,
 		restartCommand,
+		waitCommand,
+		pauseCommand,
+		unpauseCommand,
 		scaleCommand,
 		logsCommand,
+		execCommand,
+		attachCommand,
+		statsCommand,
+		topCommand,
 		destroyCommand,
+		batchCommand,
+		renameCommand,
+		updateContainerCommand,
+		commitCommand,
+		buildCommand,
+		copyCommand,
+		diffCommand,
+		exportCommand,
+		startGroupCommand,
+		stopGroupCommand,
+		restartGroupCommand,
+		destroyGroupCommand,
 		engineListCommand,
 		engineAddCommand,
 		engineRemoveCommand,
 		engineInspectCommand,
+		engineUpdateCommand,
+		engineLabelCommand,
+		engineCordonCommand,
+		engineUncordonCommand,
+		engineDrainCommand,
+		engineCertificatesCommand,
+		engineSetCertificatesCommand,
+		poolsListCommand,
+		poolCreateCommand,
+		poolUpdateCommand,
+		poolDeleteCommand,
+		joinTokensListCommand,
+		joinTokenCreateCommand,
+		joinTokenDeleteCommand,
 		serviceKeysListCommand,
 		serviceKeyCreateCommand,
 		serviceKeyRemoveCommand,
@@ -64,8 +143,70 @@ func main() {
 		webhookKeysListCommand,
 		webhookKeyCreateCommand,
 		webhookKeyRemoveCommand,
+		webhooksListCommand,
+		webhookCreateCommand,
+		webhookRemoveCommand,
+		notifiersListCommand,
+		notifierCreateCommand,
+		notifierRemoveCommand,
+		alertsListCommand,
+		alertRulesListCommand,
+		alertRuleCreateCommand,
+		alertRuleRemoveCommand,
 		infoCommand,
+		usageCommand,
+		rebalanceCommand,
+		applicationsListCommand,
+		applicationCreateCommand,
+		applicationScaleCommand,
+		applicationUpdateCommand,
+		applicationDiffCommand,
+		applicationRemoveCommand,
+		composeDeployCommand,
+		deployCommand,
+		rollbackCommand,
+		deploymentsCommand,
+		promoteDeploymentCommand,
+		jobsListCommand,
+		jobCreateCommand,
+		jobRemoveCommand,
+		jobRunsCommand,
+		secretsListCommand,
+		secretCreateCommand,
+		secretShowCommand,
+		secretDeleteCommand,
+		configsListCommand,
+		configCreateCommand,
+		configUpdateCommand,
+		configShowCommand,
+		configDeleteCommand,
+		configCommand,
+		templatesListCommand,
+		templateCreateCommand,
+		templateRunCommand,
+		templateDeleteCommand,
+		volumesListCommand,
+		volumeCreateCommand,
+		volumeRemoveCommand,
+		quotasListCommand,
+		quotaCreateCommand,
+		quotaDeleteCommand,
+		namespacesListCommand,
+		namespaceCreateCommand,
+		namespaceDeleteCommand,
+		namespaceRoleCommand,
+		gcCommand,
+		gcPolicyCommand,
+		setGCPolicyCommand,
+		backupCommand,
+		restoreCommand,
 		eventsCommand,
+		eventRetentionCommand,
+		setEventRetentionCommand,
+		expireEventsCommand,
+		versionCommand,
 	}
+	tracer = shipyard.NewTracerFromEnv("shipyard-cli")
 	app.Run(os.Args)
+	tracer.Close()
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var commitCommand = cli.Command{
+	Name:        "commit",
+	Usage:       "create an image from a container",
+	Description: "commit <id> <repository[:tag]>",
+	Action:      commitAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "message, m",
+			Usage: "commit message",
+		},
+		cli.StringFlag{
+			Name:  "author",
+			Usage: "image author; defaults to the logged in user",
+		},
+		cli.BoolFlag{
+			Name:  "push",
+			Usage: "push the image to its registry",
+		},
+	},
+}
+
+func commitAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	args := c.Args()
+	if len(args) != 2 {
+		logger.Fatalf("you must specify a container id and a repository")
+	}
+	container, err := m.Container(args[0])
+	if err != nil {
+		logger.Fatalf("error getting container info: %s", err)
+	}
+	repo, tag := shipyard.ParseImageName(args[1])
+	opts := &shipyard.CommitOptions{
+		Repo:    repo,
+		Tag:     tag,
+		Comment: c.String("message"),
+		Author:  c.String("author"),
+		Push:    c.Bool("push"),
+	}
+	image, err := m.Commit(container, opts)
+	if err != nil {
+		logger.Fatalf("error committing container: %s", err)
+	}
+	fmt.Printf("committed %s to %s (%s)\n", container.ID[:12], opts.Image(), image.ID)
+}
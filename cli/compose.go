@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard/client"
+)
+
+var composeDeployCommand = cli.Command{
+	Name:        "deploy-compose",
+	Usage:       "deploy the services of a compose file as applications",
+	Description: "deploy-compose [options]; applications are named <project>_<service>",
+	Action:      composeDeployAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "file, f",
+			Value: "docker-compose.yml",
+			Usage: "compose file",
+		},
+		cli.StringFlag{
+			Name:  "project, p",
+			Usage: "project name; defaults to the name of the directory of the compose file",
+		},
+	},
+}
+
+func composeDeployAction(c *cli.Context) {
+	path := c.String("file")
+	project := c.String("project")
+	if project == "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		project = strings.ToLower(filepath.Base(filepath.Dir(abs)))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer f.Close()
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	apps, err := m.DeployCompose(f, project)
+	if err != nil {
+		logger.Fatalf("error deploying compose file: %s", err)
+	}
+	for _, app := range apps {
+		fmt.Printf("deployed %s (%s)\n", app.Name, app.Image)
+	}
+}
@@ -1,14 +1,134 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	"github.com/shipyard/shipyard/client"
 )
 
 const (
-	CONFIG_PATH = ".shipyardrc" // this is joined to the user's home dir
+	CONFIG_DIR  = ".shipyard" // this is joined to the user's home dir
+	CONFIG_FILE = "config"
+	// CONFIG_PATH is the single context config of earlier versions; it is
+	// read as the default context until the config is saved
+	CONFIG_PATH     = ".shipyardrc"
+	DEFAULT_CONTEXT = "default"
 )
 
 var (
-	ErrConfigDoesNotExist = errors.New("config does not exist; try logging in")
-	ErrInvalidConfig      = errors.New("invalid config")
+	ErrConfigDoesNotExist  = errors.New("config does not exist; try logging in")
+	ErrInvalidConfig       = errors.New("invalid config")
+	ErrContextDoesNotExist = errors.New("context does not exist")
 )
+
+// Config holds named contexts, each the url and credentials of a
+// controller, and the context commands use by default
+type Config struct {
+	CurrentContext string                            `json:"current_context,omitempty"`
+	Contexts       map[string]*client.ShipyardConfig `json:"contexts,omitempty"`
+	// CredentialHelper keeps the auth tokens of the contexts out of the
+	// config; see credentialHelper
+	CredentialHelper string `json:"credential_helper,omitempty"`
+}
+
+// Context returns the named context, or the current one when name is empty
+func (cfg *Config) Context(name string) (*client.ShipyardConfig, error) {
+	if name == "" {
+		name = cfg.CurrentContext
+	}
+	if name == "" || len(cfg.Contexts) == 0 {
+		return nil, ErrConfigDoesNotExist
+	}
+	ctx, ok := cfg.Contexts[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrContextDoesNotExist, name)
+	}
+	return ctx, nil
+}
+
+// SetContext adds or replaces a context; the first context added becomes
+// the current one
+func (cfg *Config) SetContext(name string, ctx *client.ShipyardConfig) {
+	if cfg.Contexts == nil {
+		cfg.Contexts = map[string]*client.ShipyardConfig{}
+	}
+	cfg.Contexts[name] = ctx
+	if cfg.CurrentContext == "" {
+		cfg.CurrentContext = name
+	}
+}
+
+func configPaths() (string, string, error) {
+	usr, err := user.Current()
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(usr.HomeDir, CONFIG_DIR, CONFIG_FILE), filepath.Join(usr.HomeDir, CONFIG_PATH), nil
+}
+
+// readConfig reads the contexts from ~/.shipyard/config, falling back to
+// ~/.shipyardrc as the default context.  A missing config has no contexts.
+func readConfig() (*Config, error) {
+	path, legacy, err := configPaths()
+	if err != nil {
+		return nil, err
+	}
+	return readConfigFile(path, legacy)
+}
+
+func readConfigFile(path string, legacy string) (*Config, error) {
+	cfg := &Config{}
+	f, err := os.Open(path)
+	if err == nil {
+		defer f.Close()
+		if err := json.NewDecoder(f).Decode(cfg); err != nil {
+			return nil, ErrInvalidConfig
+		}
+		return cfg, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	lf, err := os.Open(legacy)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+	defer lf.Close()
+	var ctx *client.ShipyardConfig
+	if err := json.NewDecoder(lf).Decode(&ctx); err != nil || ctx == nil {
+		return nil, ErrInvalidConfig
+	}
+	cfg.SetContext(DEFAULT_CONTEXT, ctx)
+	return cfg, nil
+}
+
+// writeConfig saves the contexts to ~/.shipyard/config
+func writeConfig(cfg *Config) error {
+	path, _, err := configPaths()
+	if err != nil {
+		return err
+	}
+	return writeConfigFile(path, cfg)
+}
+
+func writeConfigFile(path string, cfg *Config) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cfg)
+}
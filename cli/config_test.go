@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shipyard/shipyard/client"
+)
+
+func TestReadConfigFileLegacy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shipyard-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, CONFIG_DIR, CONFIG_FILE)
+	legacy := filepath.Join(dir, CONFIG_PATH)
+
+	cfg, err := readConfigFile(path, legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.Context(""); err != ErrConfigDoesNotExist {
+		t.Errorf("expected ErrConfigDoesNotExist; received %v", err)
+	}
+
+	if err := ioutil.WriteFile(legacy, []byte(`{"url":"http://old:8080","token":"t"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = readConfigFile(path, legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := cfg.Context("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CurrentContext != DEFAULT_CONTEXT || ctx.Url != "http://old:8080" {
+		t.Errorf("expected the legacy config as the default context; received %+v", cfg)
+	}
+}
+
+func TestConfigContexts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shipyard-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, CONFIG_DIR, CONFIG_FILE)
+
+	cfg := &Config{}
+	cfg.SetContext("prod", &client.ShipyardConfig{Url: "http://prod:8080"})
+	cfg.SetContext("staging", &client.ShipyardConfig{Url: "http://staging:8080"})
+	if cfg.CurrentContext != "prod" {
+		t.Errorf("expected the first context to be current; received %s", cfg.CurrentContext)
+	}
+	cfg.CurrentContext = "staging"
+	if err := writeConfigFile(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := readConfigFile(path, filepath.Join(dir, CONFIG_PATH))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := saved.Context("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Url != "http://staging:8080" {
+		t.Errorf("expected the staging context; received %s", ctx.Url)
+	}
+	ctx, err = saved.Context("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Url != "http://prod:8080" {
+		t.Errorf("expected the prod context; received %s", ctx.Url)
+	}
+	if _, err := saved.Context("dev"); !errors.Is(err, ErrContextDoesNotExist) {
+		t.Errorf("expected ErrContextDoesNotExist; received %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var configFlags = []cli.Flag{
+	cli.StringSliceFlag{
+		Name:  "env, e",
+		Usage: "environment variables, i.e. --env DB_HOST=db.internal",
+		Value: &cli.StringSlice{},
+	},
+	cli.StringSliceFlag{
+		Name:  "file, f",
+		Usage: "file written into containers, i.e. --file /etc/app/app.conf=./app.conf",
+		Value: &cli.StringSlice{},
+	},
+}
+
+var configsListCommand = cli.Command{
+	Name:   "configs",
+	Usage:  "list config bundles",
+	Action: configsListAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func configsListAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	bundles, err := m.Configs()
+	if err != nil {
+		logger.Fatalf("error getting configs: %s", err)
+	}
+	if formatted(c, bundles) {
+		return
+	}
+	if len(bundles) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Name\tVars\tFiles\tUpdated")
+	for _, b := range bundles {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", b.Name, len(b.Data), len(b.Files), b.Updated.Format(time.RFC822))
+	}
+	w.Flush()
+}
+
+var configCreateCommand = cli.Command{
+	Name:        "create-config",
+	Usage:       "create a config bundle",
+	Description: "create-config <name>",
+	Action:      configCreateAction,
+	Flags:       configFlags,
+}
+
+func configCreateAction(c *cli.Context) {
+	bundle := parseConfigBundle(c)
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	if _, err := m.CreateConfig(bundle); err != nil {
+		logger.Fatalf("error creating config: %s", err)
+	}
+	fmt.Printf("created config %s\n", bundle.Name)
+}
+
+var configUpdateCommand = cli.Command{
+	Name:        "update-config",
+	Usage:       "replace the settings of a config bundle",
+	Description: "update-config <name>; containers launched afterwards get the new settings",
+	Action:      configUpdateAction,
+	Flags:       configFlags,
+}
+
+func configUpdateAction(c *cli.Context) {
+	bundle := parseConfigBundle(c)
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	if err := m.UpdateConfig(bundle); err != nil {
+		logger.Fatalf("error updating config: %s", err)
+	}
+	fmt.Printf("updated config %s\n", bundle.Name)
+}
+
+var configShowCommand = cli.Command{
+	Name:        "inspect-config",
+	Usage:       "show a config bundle",
+	Description: "inspect-config <name>",
+	Action:      configShowAction,
+}
+
+func configShowAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	bundle, err := m.Config(c.Args().First())
+	if err != nil {
+		logger.Fatalf("error getting config: %s", err)
+	}
+	keys := []string{}
+	for k := range bundle.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Printf("%s=%s\n", k, bundle.Data[k])
+	}
+	paths := []string{}
+	for p := range bundle.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		fmt.Printf("file %s (%d bytes)\n", p, len(bundle.Files[p]))
+	}
+}
+
+var configDeleteCommand = cli.Command{
+	Name:        "delete-config",
+	Usage:       "delete a config bundle",
+	Description: "delete-config <name> [<name>]",
+	Action:      configDeleteAction,
+}
+
+func configDeleteAction(c *cli.Context) {
+	if len(c.Args()) == 0 {
+		logger.Fatal("you must specify a name")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	for _, name := range c.Args() {
+		if err := m.DeleteConfig(name); err != nil {
+			logger.Fatalf("error deleting config: %s", err)
+		}
+		fmt.Printf("deleted %s\n", name)
+	}
+}
+
+// parseConfigBundle builds a config bundle from the --env and --file flags
+func parseConfigBundle(c *cli.Context) *shipyard.ConfigBundle {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	bundle := &shipyard.ConfigBundle{
+		Name:  c.Args().First(),
+		Data:  parseEnvironmentVariables(c.StringSlice("env")),
+		Files: map[string]string{},
+	}
+	for _, f := range c.StringSlice("file") {
+		parts := strings.SplitN(f, "=", 2)
+		if len(parts) != 2 {
+			logger.Fatal("files must be in <container-path>=<local-file> pairs")
+		}
+		b, err := ioutil.ReadFile(parts[1])
+		if err != nil {
+			logger.Fatal(err)
+		}
+		bundle.Files[parts[0]] = string(b)
+	}
+	return bundle
+}
@@ -8,6 +8,7 @@ import (
 	"text/tabwriter"
 
 	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
 	"github.com/shipyard/shipyard/client"
 )
 
@@ -15,6 +16,38 @@ var containersCommand = cli.Command{
 	Name:   "containers",
 	Usage:  "list containers",
 	Action: containersAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "image",
+			Usage: "only show containers of this image",
+		},
+		cli.StringFlag{
+			Name:  "engine",
+			Usage: "only show containers on this engine id",
+		},
+		cli.StringFlag{
+			Name:  "state",
+			Usage: "only show containers in this state (running, stopped)",
+		},
+		cli.StringFlag{
+			Name:  "label",
+			Usage: "only show containers with this constraint label",
+		},
+		cli.StringFlag{
+			Name:  "selector",
+			Usage: "only show containers with matching labels, i.e. --selector tier=web,env!=dev",
+		},
+		cli.IntFlag{
+			Name:  "limit",
+			Usage: "maximum number of containers to show",
+		},
+		cli.IntFlag{
+			Name:  "offset",
+			Usage: "number of containers to skip",
+		},
+		outputFlag,
+		watchFlag,
+	},
 }
 
 func containersAction(c *cli.Context) {
@@ -22,16 +55,40 @@ func containersAction(c *cli.Context) {
 	if err != nil {
 		logger.Fatal(err)
 	}
+	selector, err := shipyard.ParseSelector(c.String("selector"))
+	if err != nil {
+		logger.Fatal(err)
+	}
 	m := client.NewManager(cfg)
-	containers, err := m.Containers()
+	query := &shipyard.ContainerQuery{
+		Image:    c.String("image"),
+		Engine:   c.String("engine"),
+		State:    c.String("state"),
+		Label:    c.String("label"),
+		Selector: selector,
+		Limit:    c.Int("limit"),
+		Offset:   c.Int("offset"),
+	}
+	if c.Bool("watch") {
+		watch(c, m, nil, func() { printContainers(c, m, query) })
+		return
+	}
+	printContainers(c, m, query)
+}
+
+func printContainers(c *cli.Context, m *client.Manager, query *shipyard.ContainerQuery) {
+	containers, err := m.QueryContainers(query)
 	if err != nil {
 		logger.Fatalf("error getting containers: %s", err)
 	}
+	if formatted(c, containers) {
+		return
+	}
 	if len(containers) == 0 {
 		return
 	}
 	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
-	fmt.Fprintln(w, "ID\tImage\tName\tHost\tState\tPorts")
+	fmt.Fprintln(w, "ID\tImage\tName\tHost\tState\tHealth\tPorts\tLabels")
 	for _, c := range containers {
 		portDefs := []string{}
 		for _, port := range c.Ports {
@@ -40,7 +97,7 @@ func containersAction(c *cli.Context) {
 		}
 		ports := strings.Join(portDefs, ", ")
 		name := c.Name[1:]
-		fmt.Fprintf(w, fmt.Sprintf("%s\t%s\t%s\t%s\t%v\t%s\n", c.ID[:12], c.Image.Name, name, c.Engine.ID, c.State, ports))
+		fmt.Fprintf(w, fmt.Sprintf("%s\t%s\t%s\t%s\t%v\t%s\t%s\t%s\n", c.ID[:12], c.Image.Name, name, c.Engine.ID, c.State, shipyard.ContainerHealth(c), ports, shipyard.FormatLabels(shipyard.ContainerLabels(c))))
 	}
 	w.Flush()
 }
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard/client"
+)
+
+// configCommand manages the contexts of ~/.shipyard/config; config
+// bundles have their own commands so their names can not clash
+var configCommand = cli.Command{
+	Name:        "config",
+	Usage:       "manage cli contexts",
+	Subcommands: contextCommands,
+}
+
+// contextCommands are the config subcommands managing the contexts of
+// ~/.shipyard/config
+var contextCommands = []cli.Command{
+	contextsListCommand,
+	currentContextCommand,
+	useContextCommand,
+	setContextCommand,
+	deleteContextCommand,
+}
+
+var contextsListCommand = cli.Command{
+	Name:   "get-contexts",
+	Usage:  "list contexts",
+	Action: contextsListAction,
+}
+
+func contextsListAction(c *cli.Context) {
+	contexts, err := readConfig()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if len(contexts.Contexts) == 0 {
+		return
+	}
+	names := []string{}
+	for name := range contexts.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Current\tName\tURL\tUser\tNamespace")
+	for _, name := range names {
+		ctx := contexts.Contexts[name]
+		current := ""
+		if name == contexts.CurrentContext {
+			current = "*"
+		}
+		user := ctx.Username
+		if user == "" && ctx.ServiceKey != "" {
+			user = "(service key)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", current, name, ctx.Url, user, ctx.Namespace)
+	}
+	w.Flush()
+}
+
+var currentContextCommand = cli.Command{
+	Name:   "current-context",
+	Usage:  "show the current context",
+	Action: currentContextAction,
+}
+
+func currentContextAction(c *cli.Context) {
+	contexts, err := readConfig()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if contexts.CurrentContext == "" {
+		logger.Fatal(ErrConfigDoesNotExist)
+	}
+	fmt.Println(contexts.CurrentContext)
+}
+
+var useContextCommand = cli.Command{
+	Name:        "use-context",
+	Usage:       "set the context used by default",
+	Description: "use-context <name>",
+	Action:      useContextAction,
+}
+
+func useContextAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	name := c.Args().First()
+	contexts, err := readConfig()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if _, err := contexts.Context(name); err != nil {
+		logger.Fatal(err)
+	}
+	contexts.CurrentContext = name
+	if err := writeConfig(contexts); err != nil {
+		logger.Fatal(err)
+	}
+	fmt.Printf("switched to context %s\n", name)
+}
+
+var setContextCommand = cli.Command{
+	Name:        "set-context",
+	Usage:       "add or update a context; log in with --context to save a token",
+	Description: "set-context <name>",
+	Action:      setContextAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "url",
+			Usage: "controller url",
+		},
+		cli.StringFlag{
+			Name:  "service-key",
+			Usage: "service key used instead of a login",
+		},
+		cli.StringFlag{
+			Name:  "namespace",
+			Usage: "namespace requests of the context are scoped to",
+		},
+	},
+}
+
+func setContextAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	name := c.Args().First()
+	contexts, err := readConfig()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	ctx, ok := contexts.Contexts[name]
+	if !ok {
+		ctx = &client.ShipyardConfig{}
+	}
+	if u := c.String("url"); u != "" {
+		ctx.Url = u
+	}
+	if k := c.String("service-key"); k != "" {
+		ctx.ServiceKey = k
+	}
+	if ns := c.String("namespace"); ns != "" {
+		ctx.Namespace = ns
+	}
+	applyGlobalTLSFlags(c, ctx)
+	if ctx.Url == "" {
+		logger.Fatal("you must specify a url")
+	}
+	contexts.SetContext(name, ctx)
+	if err := writeConfig(contexts); err != nil {
+		logger.Fatal(err)
+	}
+	fmt.Printf("saved context %s\n", name)
+}
+
+var deleteContextCommand = cli.Command{
+	Name:        "delete-context",
+	Usage:       "delete a context",
+	Description: "delete-context <name>",
+	Action:      deleteContextAction,
+}
+
+func deleteContextAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	name := c.Args().First()
+	contexts, err := readConfig()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if _, err := contexts.Context(name); err != nil {
+		logger.Fatal(err)
+	}
+	delete(contexts.Contexts, name)
+	if contexts.CurrentContext == name {
+		contexts.CurrentContext = ""
+	}
+	if err := writeConfig(contexts); err != nil {
+		logger.Fatal(err)
+	}
+	fmt.Printf("deleted context %s\n", name)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard/client"
+)
+
+var copyCommand = cli.Command{
+	Name:        "cp",
+	Usage:       "copy files to or from a container",
+	Description: "cp <id>:<path> <dest>|- or cp <src>|- <id>:<path>; '-' streams a tar archive through stdout or stdin",
+	Action:      copyAction,
+}
+
+func copyAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	args := c.Args()
+	if len(args) != 2 {
+		logger.Fatalf("you must specify a source and a destination")
+	}
+	if id, path, ok := splitContainerPath(args[0]); ok {
+		if err := copyFromContainer(m, id, path, args[1]); err != nil {
+			logger.Fatalf("error copying from container: %s", err)
+		}
+		return
+	}
+	id, path, ok := splitContainerPath(args[1])
+	if !ok {
+		logger.Fatalf("either the source or the destination must be <id>:<path>")
+	}
+	if err := copyToContainer(m, args[0], id, path); err != nil {
+		logger.Fatalf("error copying to container: %s", err)
+	}
+}
+
+// splitContainerPath splits <id>:<path> arguments
+func splitContainerPath(arg string) (string, string, bool) {
+	parts := strings.SplitN(arg, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || !strings.HasPrefix(parts[1], "/") {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func copyFromContainer(m *client.Manager, id string, path string, dest string) error {
+	archive, err := m.CopyFrom(id, path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+	if dest == "-" {
+		_, err := io.Copy(os.Stdout, archive)
+		return err
+	}
+	tr := tar.NewReader(archive)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, hdr.Name)
+		if !strings.HasPrefix(target, filepath.Clean(dest)+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry %s is outside of %s", hdr.Name, dest)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, hdr.FileInfo().Mode().Perm()|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, hdr.FileInfo().Mode().Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func copyToContainer(m *client.Manager, src string, id string, path string) error {
+	if src == "-" {
+		return m.CopyTo(id, path, os.Stdin)
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeArchive(pw, src, filepath.Dir(filepath.Clean(src))))
+	}()
+	return m.CopyTo(id, path, pr)
+}
+
+// writeArchive writes a tar archive of a local file or directory with entry
+// names relative to base
+func writeArchive(w io.Writer, src string, base string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(base, p)
+		if err != nil || name == "." {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(name)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
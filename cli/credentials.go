@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// credentialsNotFound is how docker credential helpers report a missing
+// entry
+const credentialsNotFound = "credentials not found in native keychain"
+
+var ErrLoginRequired = errors.New("auth token rejected; run shipyard login")
+
+// credentialHelper keeps auth tokens in the keychain of the os through a
+// docker credential helper, i.e. "osxkeychain" runs
+// docker-credential-osxkeychain.  Tokens are stored by controller url.
+type credentialHelper string
+
+type helperCredentials struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+func (h credentialHelper) run(action string, input []byte) ([]byte, error) {
+	cmd := exec.Command("docker-credential-"+string(h), action)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		if msg == "" {
+			msg = err.Error()
+		}
+		return out, fmt.Errorf("credential helper %s: %s", h, msg)
+	}
+	return out, nil
+}
+
+// get returns the stored credentials of a url or nil when there are none
+func (h credentialHelper) get(serverURL string) (*helperCredentials, error) {
+	out, err := h.run("get", []byte(serverURL))
+	if err != nil {
+		if strings.TrimSpace(string(out)) == credentialsNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var creds *helperCredentials
+	if err := json.Unmarshal(out, &creds); err != nil {
+		return nil, fmt.Errorf("credential helper %s: %s", h, err)
+	}
+	return creds, nil
+}
+
+func (h credentialHelper) store(creds *helperCredentials) error {
+	b, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	_, err = h.run("store", b)
+	return err
+}
+
+func (h credentialHelper) erase(serverURL string) error {
+	out, err := h.run("erase", []byte(serverURL))
+	if err != nil && strings.TrimSpace(string(out)) != credentialsNotFound {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeHelper is a docker credential helper keeping one entry in a file
+const fakeHelper = `#!/bin/sh
+store="$(dirname "$0")/store"
+case "$1" in
+get)
+	if [ -f "$store" ]; then cat "$store"; else echo "credentials not found in native keychain"; exit 1; fi ;;
+store)
+	cat > "$store" ;;
+erase)
+	rm -f "$store" ;;
+esac
+`
+
+func TestCredentialHelper(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shipyard-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "docker-credential-test"), []byte(fakeHelper), 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := os.Getenv("PATH")
+	defer os.Setenv("PATH", path)
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+
+	h := credentialHelper("test")
+	creds, err := h.get("http://shipyard:8080")
+	if err != nil || creds != nil {
+		t.Fatalf("expected no credentials; received %v %v", creds, err)
+	}
+	if err := h.store(&helperCredentials{ServerURL: "http://shipyard:8080", Username: "admin", Secret: "token"}); err != nil {
+		t.Fatal(err)
+	}
+	creds, err = h.get("http://shipyard:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds == nil || creds.Username != "admin" || creds.Secret != "token" {
+		t.Errorf("unexpected credentials %+v", creds)
+	}
+	if err := h.erase("http://shipyard:8080"); err != nil {
+		t.Fatal(err)
+	}
+	if creds, err := h.get("http://shipyard:8080"); err != nil || creds != nil {
+		t.Errorf("expected erased credentials; received %v %v", creds, err)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var deployFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "strategy",
+		Value: shipyard.DeployRolling,
+		Usage: "deployment strategy (rolling, blue-green, canary)",
+	},
+	cli.StringFlag{
+		Name:  "switch-url",
+		Value: "",
+		Usage: "url notified to switch traffic to the new containers of a blue-green deployment",
+	},
+	cli.IntFlag{
+		Name:  "batch-size",
+		Value: shipyard.DefaultDeployBatchSize,
+		Usage: "number of containers replaced at a time",
+	},
+	cli.IntFlag{
+		Name:  "delay",
+		Value: 0,
+		Usage: "seconds to wait between batches",
+	},
+	cli.IntFlag{
+		Name:  "monitor",
+		Value: shipyard.DefaultDeployMonitor,
+		Usage: "seconds new containers must keep running before old ones are stopped",
+	},
+	cli.IntFlag{
+		Name:  "canary-percent",
+		Value: 0,
+		Usage: "percent of containers a canary deployment updates first (default 10)",
+	},
+	cli.IntFlag{
+		Name:  "bake-period",
+		Value: 0,
+		Usage: "seconds the canary containers run before the rest are updated; 0 waits for promote-deployment",
+	},
+	cli.BoolFlag{
+		Name:  "detach, d",
+		Usage: "return once the deployment has started",
+	},
+}
+
+var deployCommand = cli.Command{
+	Name:        "deploy",
+	Usage:       "update an application to a new image",
+	Description: "deploy [options] <application> <image>",
+	Action:      deployAction,
+	Flags:       deployFlags,
+}
+
+func deployAction(c *cli.Context) {
+	if len(c.Args()) != 2 {
+		logger.Fatal("you must specify an application and image")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	d, err := m.Deploy(c.Args().First(), c.Args().Get(1), parseDeployOptions(c))
+	if err != nil {
+		logger.Fatalf("error deploying application: %s", err)
+	}
+	fmt.Printf("started deployment %s\n", d.ID)
+	if c.Bool("detach") {
+		return
+	}
+	d = waitDeployment(m, d)
+	fmt.Printf("deployed %s to %s\n", d.Image, d.Application)
+}
+
+var rollbackCommand = cli.Command{
+	Name:        "rollback",
+	Usage:       "redeploy an application with the spec of a previous revision",
+	Description: "rollback [options] <application> [<revision>]; defaults to the revision before the current one",
+	Action:      rollbackAction,
+	Flags:       deployFlags,
+}
+
+func rollbackAction(c *cli.Context) {
+	if len(c.Args()) < 1 || len(c.Args()) > 2 {
+		logger.Fatal("you must specify an application and optionally a revision")
+	}
+	revision := 0
+	if len(c.Args()) == 2 {
+		r, err := strconv.Atoi(c.Args().Get(1))
+		if err != nil {
+			logger.Fatalf("invalid revision: %s", err)
+		}
+		revision = r
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	d, err := m.Rollback(c.Args().First(), revision, parseDeployOptions(c))
+	if err != nil {
+		logger.Fatalf("error rolling back application: %s", err)
+	}
+	fmt.Printf("started rollback to revision %d: deployment %s\n", d.RollbackTo, d.ID)
+	if c.Bool("detach") {
+		return
+	}
+	d = waitDeployment(m, d)
+	fmt.Printf("rolled back %s to revision %d (%s)\n", d.Application, d.RollbackTo, d.Image)
+}
+
+func parseDeployOptions(c *cli.Context) *shipyard.DeployOptions {
+	return &shipyard.DeployOptions{
+		Strategy:      c.String("strategy"),
+		SwitchURL:     c.String("switch-url"),
+		BatchSize:     c.Int("batch-size"),
+		Delay:         c.Int("delay"),
+		Monitor:       c.Int("monitor"),
+		CanaryPercent: c.Int("canary-percent"),
+		BakePeriod:    c.Int("bake-period"),
+	}
+}
+
+// waitDeployment prints the progress of a deployment until it is done and
+// exits unless it succeeded
+func waitDeployment(m *client.Manager, d *shipyard.Deployment) *shipyard.Deployment {
+	var err error
+	updated := -1
+	baking := false
+	for !d.Done() {
+		if d.Updated != updated {
+			fmt.Printf("%d of %d containers updated\n", d.Updated, d.Total)
+			updated = d.Updated
+		}
+		if d.Status == shipyard.DeploymentBaking && !baking {
+			fmt.Println(d.Message)
+			if d.Options.BakePeriod == 0 {
+				fmt.Printf("promote it with: promote-deployment %s\n", d.ID)
+			}
+		}
+		baking = d.Status == shipyard.DeploymentBaking
+		time.Sleep(time.Second)
+		if d, err = m.Deployment(d.ID); err != nil {
+			logger.Fatalf("error getting deployment: %s", err)
+		}
+	}
+	if d.Status != shipyard.DeploymentSucceeded {
+		logger.Fatalf("deployment %s: %s", d.Status, d.Message)
+	}
+	return d
+}
+
+var promoteDeploymentCommand = cli.Command{
+	Name:        "promote-deployment",
+	Usage:       "end the bake period of a canary deployment and update the rest of the containers",
+	Description: "promote-deployment <id>",
+	Action:      promoteDeploymentAction,
+}
+
+func promoteDeploymentAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a deployment id")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	if err := m.PromoteDeployment(c.Args().First()); err != nil {
+		logger.Fatalf("error promoting deployment: %s", err)
+	}
+	fmt.Printf("promoted deployment %s\n", c.Args().First())
+}
+
+var deploymentsCommand = cli.Command{
+	Name:        "deployments",
+	Usage:       "list application deployments",
+	Description: "deployments [<application>]",
+	Action:      deploymentsAction,
+	Flags:       []cli.Flag{outputFlag},
+}
+
+func deploymentsAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	deployments, err := m.Deployments(c.Args().First())
+	if err != nil {
+		logger.Fatalf("error getting deployments: %s", err)
+	}
+	if formatted(c, deployments) {
+		return
+	}
+	if len(deployments) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "ID\tApplication\tRevision\tImage\tStatus\tUpdated\tUser\tStarted")
+	for _, d := range deployments {
+		revision := fmt.Sprint(d.Revision)
+		if d.RollbackTo > 0 {
+			revision += fmt.Sprintf(" (rollback to %d)", d.RollbackTo)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d/%d\t%s\t%s\n", d.ID, d.Application, revision, d.Image, d.Status, d.Updated, d.Total, d.User, d.Started.Format(time.RFC822))
+	}
+	w.Flush()
+}
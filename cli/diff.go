@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard/client"
+)
+
+var diffCommand = cli.Command{
+	Name:        "diff",
+	Usage:       "show changes to the filesystem of a container",
+	Description: "diff <id>",
+	Action:      diffAction,
+}
+
+func diffAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	ids := c.Args()
+	if len(ids) == 0 {
+		logger.Fatal("you must specify an id")
+	}
+	changes, err := m.Diff(ids[0])
+	if err != nil {
+		logger.Fatalf("error getting changes: %s", err)
+	}
+	for _, ch := range changes {
+		fmt.Printf("%s %s\n", strings.ToUpper(ch.Kind[:1]), ch.Path)
+	}
+}
+
+var exportCommand = cli.Command{
+	Name:        "export",
+	Usage:       "export the filesystem of a container as a tar archive",
+	Description: "export <id> [--output <file>]",
+	Action:      exportAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "output, o",
+			Usage: "write the archive to a file instead of stdout",
+		},
+	},
+}
+
+func exportAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	ids := c.Args()
+	if len(ids) == 0 {
+		logger.Fatal("you must specify an id")
+	}
+	archive, err := m.Export(ids[0])
+	if err != nil {
+		logger.Fatalf("error exporting container: %s", err)
+	}
+	defer archive.Close()
+	out := os.Stdout
+	if o := c.String("output"); o != "" {
+		f, err := os.Create(o)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		defer f.Close()
+		out = f
+	}
+	if _, err := io.Copy(out, archive); err != nil {
+		logger.Fatalf("error exporting container: %s", err)
+	}
+}
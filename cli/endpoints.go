@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var endpointsCommand = cli.Command{
+	Name:   "endpoints",
+	Usage:  "list the addresses of published container ports",
+	Action: endpointsAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "image",
+			Usage: "only containers of the image, with or without tag",
+		},
+		cli.StringFlag{
+			Name:  "label",
+			Usage: "only containers with the constraint label",
+		},
+		cli.StringFlag{
+			Name:  "app",
+			Usage: "only containers of the application",
+		},
+		cli.StringFlag{
+			Name:  "selector",
+			Usage: "only containers with matching labels, i.e. --selector tier=web,env!=dev",
+		},
+		cli.IntFlag{
+			Name:  "port",
+			Usage: "only this container port",
+		},
+		cli.BoolFlag{
+			Name:  "healthy",
+			Usage: "leave out containers failing their health check",
+		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "only print addresses",
+		},
+		outputFlag,
+	},
+}
+
+func endpointsAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	selector, err := shipyard.ParseSelector(c.String("selector"))
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	endpoints, err := m.Endpoints(&shipyard.EndpointFilter{
+		Image:         c.String("image"),
+		Label:         c.String("label"),
+		Application:   c.String("app"),
+		Selector:      selector,
+		ContainerPort: c.Int("port"),
+		Healthy:       c.Bool("healthy"),
+	})
+	if err != nil {
+		logger.Fatalf("error getting endpoints: %s", err)
+	}
+	if formatted(c, endpoints) {
+		return
+	}
+	if c.Bool("quiet") {
+		for _, e := range endpoints {
+			fmt.Println(e.Addr)
+		}
+		return
+	}
+	if len(endpoints) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Address\tPort\tContainer\tImage\tEngine\tHealth")
+	for _, e := range endpoints {
+		fmt.Fprintf(w, "%s\t%d/%s\t%s\t%s\t%s\t%s\n", e.Addr, e.ContainerPort, e.Proto, e.Container[:12], e.Image, e.Engine, e.Health)
+	}
+	w.Flush()
+}
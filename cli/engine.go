@@ -19,6 +19,7 @@ var engineListCommand = cli.Command{
 	Name:   "engines",
 	Usage:  "list engines",
 	Action: engineListAction,
+	Flags:  []cli.Flag{outputFlag, watchFlag},
 }
 
 func engineListAction(c *cli.Context) {
@@ -27,11 +28,22 @@ func engineListAction(c *cli.Context) {
 		logger.Fatal(err)
 	}
 	m := client.NewManager(cfg)
+	if c.Bool("watch") {
+		watch(c, m, nil, func() { printEngines(c, m) })
+		return
+	}
+	printEngines(c, m)
+}
+
+func printEngines(c *cli.Context, m *client.Manager) {
 	engines, err := m.Engines()
 	if err != nil {
 		logger.Fatalf("error getting engines: %s", err)
 		return
 	}
+	if formatted(c, engines) {
+		return
+	}
 	if len(engines) == 0 {
 		return
 	}
@@ -100,9 +112,31 @@ var engineAddCommand = cli.Command{
 			Value: "",
 			Usage: "path to ca certificate",
 		},
+		cli.StringFlag{
+			Name:  "cadvisor-url",
+			Value: "",
+			Usage: "url of cadvisor on the engine host to scrape container metrics from",
+		},
+		cli.IntFlag{
+			Name:  "cadvisor-port",
+			Value: 0,
+			Usage: "port of cadvisor on the engine host; scrapes the host of the engine address",
+		},
 	},
 }
 
+// cadvisorFlags returns the cadvisor set with --cadvisor-url or
+// --cadvisor-port, or nil
+func cadvisorFlags(c *cli.Context) *shipyard.CAdvisor {
+	if c.String("cadvisor-url") == "" && c.Int("cadvisor-port") == 0 {
+		return nil
+	}
+	return &shipyard.CAdvisor{
+		URL:  c.String("cadvisor-url"),
+		Port: c.Int("cadvisor-port"),
+	}
+}
+
 func engineAddAction(c *cli.Context) {
 	cfg, err := loadConfig(c)
 	if err != nil {
@@ -130,7 +164,10 @@ func engineAddAction(c *cli.Context) {
 		caCertData  = []byte{}
 		sslErr      error
 	)
-	if sslCertPath != "" && sslKeyPath != "" && caCertPath != "" {
+	if sslCertPath != "" || sslKeyPath != "" || caCertPath != "" {
+		if sslCertPath == "" || sslKeyPath == "" || caCertPath == "" {
+			logger.Fatalf("--ssl-cert, --ssl-key and --ca-cert must be used together")
+		}
 		sslCert, err := os.Open(sslCertPath)
 		if err != nil {
 			logger.Fatalf("unable to open ssl certificate: %s", err)
@@ -170,6 +207,7 @@ func engineAddAction(c *cli.Context) {
 		SSLKey:         string(sslKeyData),
 		CACertificate:  string(caCertData),
 		Engine:         engine,
+		CAdvisor:       cadvisorFlags(c),
 	}
 	if err := m.AddEngine(shipyardEngine); err != nil {
 		logger.Fatalf("error adding engine: %s", err)
@@ -231,3 +269,274 @@ func engineInspectAction(c *cli.Context) {
 	b, err := json.MarshalIndent(eng, "", "    ")
 	fmt.Println(string(b))
 }
+
+var engineCordonCommand = cli.Command{
+	Name:        "cordon-engine",
+	Usage:       "exclude an engine from new container placements",
+	Description: "cordon-engine <id> [<id>]",
+	Action:      engineCordonAction,
+}
+
+func engineCordonAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	for _, id := range c.Args() {
+		if err := m.CordonEngine(id); err != nil {
+			logger.Fatalf("error cordoning engine: %s", err)
+		}
+		fmt.Printf("cordoned %s\n", id)
+	}
+}
+
+var engineUncordonCommand = cli.Command{
+	Name:        "uncordon-engine",
+	Usage:       "allow new container placements on an engine",
+	Description: "uncordon-engine <id> [<id>]",
+	Action:      engineUncordonAction,
+}
+
+func engineUncordonAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	for _, id := range c.Args() {
+		if err := m.UncordonEngine(id); err != nil {
+			logger.Fatalf("error uncordoning engine: %s", err)
+		}
+		fmt.Printf("uncordoned %s\n", id)
+	}
+}
+
+var engineDrainCommand = cli.Command{
+	Name:        "drain-engine",
+	Usage:       "move all containers off an engine",
+	Description: "drain-engine <id> [<id>]",
+	Action:      engineDrainAction,
+}
+
+func engineDrainAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	for _, id := range c.Args() {
+		if err := m.DrainEngine(id); err != nil {
+			logger.Fatalf("error draining engine: %s", err)
+		}
+		fmt.Printf("drained %s\n", id)
+	}
+}
+
+var engineLabelCommand = cli.Command{
+	Name:        "label-engine",
+	Usage:       "replace the labels of an engine",
+	Description: "label-engine <id> [<label>] (labels are names or key=value pairs)",
+	Action:      engineLabelAction,
+}
+
+func engineLabelAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	if len(c.Args()) == 0 {
+		logger.Fatal("you must specify an id")
+	}
+	id := c.Args()[0]
+	labels := []string(c.Args()[1:])
+	if err := m.UpdateEngineLabels(id, labels); err != nil {
+		logger.Fatalf("error updating engine labels: %s", err)
+	}
+	fmt.Printf("updated %s\n", id)
+}
+
+var engineUpdateCommand = cli.Command{
+	Name:        "update-engine",
+	Usage:       "update the resources or address of an engine",
+	Description: "update-engine [options] <id>",
+	Action:      engineUpdateAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "addr",
+			Value: "",
+			Usage: "engine address",
+		},
+		cli.StringFlag{
+			Name:  "cpus",
+			Value: "",
+			Usage: "engine cpus",
+		},
+		cli.StringFlag{
+			Name:  "memory",
+			Value: "",
+			Usage: "engine memory",
+		},
+		cli.StringFlag{
+			Name:  "cadvisor-url",
+			Value: "",
+			Usage: "url of cadvisor on the engine host to scrape container metrics from",
+		},
+		cli.IntFlag{
+			Name:  "cadvisor-port",
+			Value: 0,
+			Usage: "port of cadvisor on the engine host; scrapes the host of the engine address",
+		},
+		cli.BoolFlag{
+			Name:  "no-cadvisor",
+			Usage: "stop scraping cadvisor and read container metrics from docker",
+		},
+	},
+}
+
+func engineUpdateAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	if len(c.Args()) == 0 {
+		logger.Fatal("you must specify an id")
+	}
+	id := c.Args()[0]
+	eng, err := m.GetEngine(id)
+	if err != nil {
+		logger.Fatalf("error getting engine: %s", err)
+	}
+	if addr := c.String("addr"); addr != "" {
+		eng.Engine.Addr = addr
+	}
+	if cpus := c.String("cpus"); cpus != "" {
+		v, err := strconv.ParseFloat(cpus, 64)
+		if err != nil {
+			logger.Fatalf("invalid cpus: %s", err)
+		}
+		eng.Engine.Cpus = v
+	}
+	if memory := c.String("memory"); memory != "" {
+		v, err := strconv.ParseFloat(memory, 64)
+		if err != nil {
+			logger.Fatalf("invalid memory: %s", err)
+		}
+		eng.Engine.Memory = v
+	}
+	if cadvisor := cadvisorFlags(c); cadvisor != nil {
+		eng.CAdvisor = cadvisor
+	}
+	if c.Bool("no-cadvisor") {
+		eng.CAdvisor = nil
+	}
+	if err := m.UpdateEngine(eng); err != nil {
+		logger.Fatalf("error updating engine: %s", err)
+	}
+	fmt.Printf("updated %s\n", eng.Engine.ID)
+}
+
+var engineCertificatesCommand = cli.Command{
+	Name:        "engine-certs",
+	Usage:       "show the certificates used for the docker api of an engine",
+	Description: "engine-certs <id>",
+	Action:      engineCertificatesAction,
+}
+
+func engineCertificatesAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	if len(c.Args()) == 0 {
+		logger.Fatal("you must specify an id")
+	}
+	info, err := m.EngineCertificates(c.Args()[0])
+	if err != nil {
+		logger.Fatalf("error getting engine certificates: %s", err)
+	}
+	if info.Client == nil && info.CA == nil {
+		fmt.Println("no certificates")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Certificate\tSubject\tIssuer\tExpires")
+	for _, cert := range []struct {
+		name string
+		info *shipyard.CertificateInfo
+	}{
+		{"client", info.Client},
+		{"ca", info.CA},
+	} {
+		if cert.info == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", cert.name, cert.info.Subject, cert.info.Issuer, cert.info.NotAfter.Format("2006-01-02"))
+	}
+	w.Flush()
+}
+
+var engineSetCertificatesCommand = cli.Command{
+	Name:        "set-engine-certs",
+	Usage:       "replace the certificates used for the docker api of an engine",
+	Description: "set-engine-certs <id>",
+	Action:      engineSetCertificatesAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "ssl-cert",
+			Usage: "path to ssl certificate",
+		},
+		cli.StringFlag{
+			Name:  "ssl-key",
+			Usage: "path to ssl key",
+		},
+		cli.StringFlag{
+			Name:  "ca-cert",
+			Usage: "path to ca certificate",
+		},
+		cli.BoolFlag{
+			Name:  "remove",
+			Usage: "remove the certificates",
+		},
+	},
+}
+
+func engineSetCertificatesAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	if len(c.Args()) == 0 {
+		logger.Fatal("you must specify an id")
+	}
+	id := c.Args()[0]
+	certs := &shipyard.EngineCertificates{}
+	if !c.Bool("remove") {
+		for _, f := range []struct {
+			flag string
+			dest *string
+		}{
+			{"ssl-cert", &certs.SSLCertificate},
+			{"ssl-key", &certs.SSLKey},
+			{"ca-cert", &certs.CACertificate},
+		} {
+			path := c.String(f.flag)
+			if path == "" {
+				logger.Fatalf("you must specify --ssl-cert, --ssl-key and --ca-cert or --remove")
+			}
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				logger.Fatalf("unable to read %s: %s", f.flag, err)
+			}
+			*f.dest = string(b)
+		}
+	}
+	if err := m.SetEngineCertificates(id, certs); err != nil {
+		logger.Fatalf("error updating engine certificates: %s", err)
+	}
+	fmt.Printf("updated certificates of %s\n", id)
+}
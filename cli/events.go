@@ -2,12 +2,14 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
 	"github.com/shipyard/shipyard/client"
 )
 
@@ -15,6 +17,58 @@ var eventsCommand = cli.Command{
 	Name:   "events",
 	Usage:  "show cluster events",
 	Action: eventsAction,
+	Flags: []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "type",
+			Usage: "only show events of this type",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringFlag{
+			Name:  "engine",
+			Usage: "only show events for this engine id",
+		},
+		cli.StringFlag{
+			Name:  "container",
+			Usage: "only show events for this container id",
+		},
+		cli.StringFlag{
+			Name:  "since",
+			Usage: "show events after this time (RFC3339 or duration ago, e.g. 1h)",
+		},
+		cli.StringFlag{
+			Name:  "until",
+			Usage: "show events before this time (RFC3339 or duration ago, e.g. 1h)",
+		},
+		cli.IntFlag{
+			Name:  "limit",
+			Usage: "maximum number of events to show",
+		},
+		cli.IntFlag{
+			Name:  "offset",
+			Usage: "number of newest events to skip",
+		},
+		cli.StringFlag{
+			Name:  "export",
+			Usage: "write the events to stdout as csv or jsonl",
+		},
+		outputFlag,
+		cli.BoolFlag{
+			Name:  "follow, f",
+			Usage: "keep printing new events as they are saved",
+		},
+	},
+}
+
+// parseEventTime accepts either an RFC3339 timestamp or a duration relative
+// to now
+func parseEventTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
 }
 
 func eventsAction(c *cli.Context) {
@@ -23,27 +77,143 @@ func eventsAction(c *cli.Context) {
 		logger.Fatal(err)
 	}
 	m := client.NewManager(cfg)
-	events, err := m.Events()
+	since, err := parseEventTime(c.String("since"))
+	if err != nil {
+		logger.Fatalf("invalid since: %s", err)
+	}
+	until, err := parseEventTime(c.String("until"))
+	if err != nil {
+		logger.Fatalf("invalid until: %s", err)
+	}
+	query := &shipyard.EventQuery{
+		EventFilter: shipyard.EventFilter{
+			Types:       c.StringSlice("type"),
+			EngineID:    c.String("engine"),
+			ContainerID: c.String("container"),
+		},
+		Since:  since,
+		Until:  until,
+		Limit:  c.Int("limit"),
+		Offset: c.Int("offset"),
+	}
+	if format := c.String("export"); format != "" {
+		export, err := m.ExportEvents(query, format)
+		if err != nil {
+			logger.Fatalf("error exporting events: %s", err)
+		}
+		defer export.Close()
+		if _, err := io.Copy(os.Stdout, export); err != nil {
+			logger.Fatalf("error exporting events: %s", err)
+		}
+		return
+	}
+	var stream <-chan *shipyard.Event
+	if c.Bool("follow") {
+		if !until.IsZero() {
+			logger.Fatal("--until can not be used with --follow")
+		}
+		// the stream is opened first so no event is missed between the
+		// query and the stream
+		stream, err = m.StreamEvents(&query.EventFilter)
+		if err != nil {
+			logger.Fatalf("error following events: %s", err)
+		}
+	}
+	events, err := m.Events(query)
 	if err != nil {
 		logger.Fatalf("error getting events: %s", err)
 	}
+	if stream != nil {
+		followEvents(c, stream, events)
+		return
+	}
+	if formatted(c, events) {
+		return
+	}
 	if len(events) == 0 {
 		return
 	}
 	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
-	fmt.Fprintln(w, "Time\tMessage\tEngine\tType\tTags")
+	fmt.Fprintln(w, eventHeader)
 	for _, e := range events {
-		tags := strings.Join(e.Tags, ",")
-		message := e.Message
-		engine := ""
-		if e.Container.ID != "" {
-			cntId := e.Container.ID[:12]
-			message = fmt.Sprintf("container:%s %s", cntId, e.Message)
+		fmt.Fprint(w, eventRow(e))
+	}
+	w.Flush()
+}
+
+const eventHeader = "Time\tMessage\tEngine\tType\tTags"
+
+func eventRow(e *shipyard.Event) string {
+	tags := strings.Join(e.Tags, ",")
+	message := e.Message
+	engine := ""
+	if e.Container.ID != "" {
+		cntId := e.Container.ID[:12]
+		message = fmt.Sprintf("container:%s %s", cntId, e.Message)
+	}
+	if e.Engine.ID != "" {
+		engine = e.Engine.ID
+	}
+	return fmt.Sprintf("%s\t%s\t%s\t%s\t%s\n", e.Time.Format(time.RubyDate), message, engine, e.Type, tags)
+}
+
+// followEvents prints events oldest first, like tail, and then each event
+// of the stream until it ends.  Other output formats than table print one
+// document per event.
+func followEvents(c *cli.Context, stream <-chan *shipyard.Event, events []*shipyard.Event) {
+	table := c.String("output") == "" || c.String("output") == "table"
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	show := func(e *shipyard.Event) {
+		if !table {
+			formatted(c, e)
+			return
 		}
-		if e.Engine.ID != "" {
-			engine = e.Engine.ID
+		fmt.Fprint(w, eventRow(e))
+		w.Flush()
+	}
+	if table {
+		fmt.Fprintln(w, eventHeader)
+	}
+	for i := len(events) - 1; i >= 0; i-- {
+		show(events[i])
+	}
+	queried := newSeenEvents(events)
+	for e := range stream {
+		// skip events of the query that were also streamed
+		if queried.seen(e) {
+			continue
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.Time.Format(time.RubyDate), message, engine, e.Type, tags)
+		show(e)
 	}
-	w.Flush()
+	logger.Fatal("event stream ended")
+}
+
+// seenEvents tells the streamed events that were already queried
+type seenEvents struct {
+	ids    map[string]bool
+	newest time.Time
+}
+
+func newSeenEvents(events []*shipyard.Event) *seenEvents {
+	s := &seenEvents{ids: map[string]bool{}}
+	for _, e := range events {
+		s.ids[e.ID] = true
+		if e.Time.After(s.newest) {
+			s.newest = e.Time
+		}
+	}
+	return s
+}
+
+// seen reports whether a streamed event was queried.  Events saved after
+// the newest queried one were not; events of controllers that do not send
+// ids are told by their time only.
+func (s *seenEvents) seen(e *shipyard.Event) bool {
+	if e.Time.After(s.newest) {
+		return false
+	}
+	if e.ID == "" {
+		return true
+	}
+	return s.ids[e.ID]
 }
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shipyard/shipyard"
+)
+
+func TestSeenEvents(t *testing.T) {
+	now := time.Date(2015, 3, 1, 12, 0, 0, 0, time.UTC)
+	queried := newSeenEvents([]*shipyard.Event{
+		{ID: "b", Time: now},
+		{ID: "a", Time: now.Add(-time.Second)},
+	})
+	tests := []struct {
+		event *shipyard.Event
+		seen  bool
+	}{
+		{&shipyard.Event{ID: "a", Time: now.Add(-time.Second)}, true},
+		{&shipyard.Event{ID: "b", Time: now}, true},
+		// saved in the same instant as the newest queried event
+		{&shipyard.Event{ID: "c", Time: now}, false},
+		{&shipyard.Event{ID: "d", Time: now.Add(time.Second)}, false},
+		{&shipyard.Event{Time: now}, true},
+		{&shipyard.Event{Time: now.Add(time.Second)}, false},
+	}
+	for _, test := range tests {
+		if seen := queried.seen(test.event); seen != test.seen {
+			t.Errorf("%s at %s: expected seen %v; received %v", test.event.ID, test.event.Time, test.seen, seen)
+		}
+	}
+	if newSeenEvents(nil).seen(&shipyard.Event{ID: "a", Time: now}) {
+		t.Error("expected no event seen without a query")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"code.google.com/p/go.crypto/ssh/terminal"
+	"github.com/codegangsta/cli"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var execCommand = cli.Command{
+	Name:        "exec",
+	Usage:       "run a command in a container",
+	Description: "exec [-i] [-t] <id> <command> [<arg>...]",
+	Action:      execAction,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "interactive, i",
+			Usage: "attach stdin",
+		},
+		cli.BoolFlag{
+			Name:  "tty, t",
+			Usage: "allocate a pseudo-tty",
+		},
+	},
+}
+
+func execAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	args := c.Args()
+	if len(args) < 2 {
+		logger.Fatal("you must specify a container id and command")
+	}
+	execConfig := &shipyard.ExecConfig{
+		Cmd:         args[1:],
+		Tty:         c.Bool("tty"),
+		Interactive: c.Bool("interactive"),
+	}
+	session, err := m.Exec(args[0], execConfig)
+	if err != nil {
+		logger.Fatalf("error running exec: %s", err)
+	}
+	defer session.Close()
+
+	if execConfig.Tty && terminal.IsTerminal(int(os.Stdin.Fd())) {
+		state, err := terminal.MakeRaw(int(os.Stdin.Fd()))
+		if err != nil {
+			logger.Fatalf("unable to set raw terminal: %s", err)
+		}
+		defer terminal.Restore(int(os.Stdin.Fd()), state)
+	}
+	if execConfig.Interactive {
+		go io.Copy(session, os.Stdin)
+	}
+	if execConfig.Tty {
+		io.Copy(os.Stdout, session)
+	} else {
+		stdcopy.StdCopy(os.Stdout, os.Stderr, session)
+	}
+
+	info, err := m.ExecInspect(args[0], session.ID)
+	if err != nil {
+		logger.Fatalf("error getting exec status: %s", err)
+	}
+	if info.ExitCode != 0 {
+		session.Close()
+		os.Exit(info.ExitCode)
+	}
+}
@@ -17,6 +17,7 @@ var extensionsCommand = cli.Command{
 	Name:   "extensions",
 	Usage:  "show extensions",
 	Action: extensionsAction,
+	Flags:  []cli.Flag{outputFlag},
 }
 
 func extensionsAction(c *cli.Context) {
@@ -29,6 +30,9 @@ func extensionsAction(c *cli.Context) {
 	if err != nil {
 		logger.Fatalf("error getting extensions: %s", err)
 	}
+	if formatted(c, exts) {
+		return
+	}
 	if len(exts) == 0 {
 		return
 	}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+)
+
+const goTemplatePrefix = "go-template="
+
+var ErrInvalidOutputFormat = errors.New("invalid output format")
+
+// outputFlag selects the output of list commands; see formatted
+var outputFlag = cli.StringFlag{
+	Name:  "output, o",
+	Usage: "output format: table, json, yaml or go-template=<template>",
+}
+
+// formatted writes v in the format of the output flag and reports whether
+// it did.  Commands print their table when it returns false.
+func formatted(c *cli.Context, v interface{}) bool {
+	format := c.String("output")
+	if format == "" || format == "table" {
+		return false
+	}
+	if err := writeFormatted(os.Stdout, format, v); err != nil {
+		logger.Fatal(err)
+	}
+	return true
+}
+
+// writeFormatted writes v as indented json, yaml or through a go template
+// executed with v
+func writeFormatted(w io.Writer, format string, v interface{}) error {
+	switch {
+	case format == "json":
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case format == "yaml":
+		data, err := shipyard.MarshalYAML(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case strings.HasPrefix(format, goTemplatePrefix):
+		tmpl, err := template.New("output").Parse(strings.TrimPrefix(format, goTemplatePrefix))
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidOutputFormat, err)
+		}
+		return tmpl.Execute(w, v)
+	}
+	return fmt.Errorf("%w %q: use table, json, yaml or %s<template>", ErrInvalidOutputFormat, format, goTemplatePrefix)
+}
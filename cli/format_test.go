@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type formatItem struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestWriteFormatted(t *testing.T) {
+	items := []*formatItem{{Name: "web", Count: 2}, {Name: "db", Count: 1}}
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"json", "[\n  {\n    \"name\": \"web\",\n    \"count\": 2\n  },\n  {\n    \"name\": \"db\",\n    \"count\": 1\n  }\n]\n"},
+		{"yaml", "- count: 2\n  name: web\n- count: 1\n  name: db\n"},
+		{"go-template={{range .}}{{.Name}}={{.Count}}\n{{end}}", "web=2\ndb=1\n"},
+	}
+	for _, test := range tests {
+		b := &bytes.Buffer{}
+		if err := writeFormatted(b, test.format, items); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != test.expected {
+			t.Errorf("%s: expected %q; received %q", test.format, test.expected, b.String())
+		}
+	}
+	for _, format := range []string{"xml", "go-template={{.Name"} {
+		if err := writeFormatted(&bytes.Buffer{}, format, items); !errors.Is(err, ErrInvalidOutputFormat) {
+			t.Errorf("%s: expected ErrInvalidOutputFormat; received %v", format, err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard/client"
+)
+
+var gcCommand = cli.Command{
+	Name:   "gc",
+	Usage:  "remove exited containers and dangling images using the gc policy",
+	Action: gcAction,
+}
+
+func gcAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	result, err := m.RunGC()
+	if err != nil {
+		logger.Fatalf("error collecting garbage: %s", err)
+	}
+	for _, id := range result.Containers {
+		fmt.Printf("removed container %s\n", shortID(id))
+	}
+	for _, id := range result.Images {
+		fmt.Printf("removed image %s\n", id)
+	}
+	for _, e := range result.Errors {
+		fmt.Fprintf(os.Stderr, "error: %s\n", e)
+	}
+}
+
+var gcPolicyCommand = cli.Command{
+	Name:   "gc-policy",
+	Usage:  "show the garbage collection policy",
+	Action: gcPolicyAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func gcPolicyAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	policy, err := m.GCPolicy()
+	if err != nil {
+		logger.Fatalf("error getting gc policy: %s", err)
+	}
+	if formatted(c, policy) {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(w, "Enabled:\t%v\n", policy.Enabled)
+	fmt.Fprintf(w, "Interval:\t%dm\n", int(policy.Period().Minutes()))
+	fmt.Fprintf(w, "Containers:\t%v\n", policy.Containers)
+	fmt.Fprintf(w, "Container Age:\t%dh\n", policy.ContainerAge)
+	fmt.Fprintf(w, "Keep Exited:\t%d\n", policy.KeepExited)
+	fmt.Fprintf(w, "Dangling Images:\t%v\n", policy.DanglingImages)
+	w.Flush()
+}
+
+var setGCPolicyCommand = cli.Command{
+	Name:   "set-gc-policy",
+	Usage:  "update the garbage collection policy",
+	Action: setGCPolicyAction,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "enable",
+			Usage: "run collections automatically",
+		},
+		cli.BoolFlag{
+			Name:  "disable",
+			Usage: "stop automatic collections",
+		},
+		cli.IntFlag{
+			Name:  "interval",
+			Usage: "minutes between automatic collections",
+		},
+		cli.StringSliceFlag{
+			Name:  "collect",
+			Usage: "what to collect: containers, images",
+			Value: &cli.StringSlice{},
+		},
+		cli.IntFlag{
+			Name:  "container-age",
+			Usage: "hours exited containers are kept",
+		},
+		cli.IntFlag{
+			Name:  "keep-exited",
+			Usage: "most recently exited containers kept per image",
+		},
+	},
+}
+
+func setGCPolicyAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	policy, err := m.GCPolicy()
+	if err != nil {
+		logger.Fatalf("error getting gc policy: %s", err)
+	}
+	switch {
+	case c.Bool("enable") && c.Bool("disable"):
+		logger.Fatalf("--enable and --disable cannot be used together")
+	case c.Bool("enable"):
+		policy.Enabled = true
+	case c.Bool("disable"):
+		policy.Enabled = false
+	}
+	if c.IsSet("interval") {
+		policy.Interval = c.Int("interval")
+	}
+	if c.IsSet("container-age") {
+		policy.ContainerAge = c.Int("container-age")
+	}
+	if c.IsSet("keep-exited") {
+		policy.KeepExited = c.Int("keep-exited")
+	}
+	if collect := c.StringSlice("collect"); len(collect) > 0 {
+		policy.Containers = false
+		policy.DanglingImages = false
+		for _, v := range collect {
+			switch v {
+			case "containers":
+				policy.Containers = true
+			case "images":
+				policy.DanglingImages = true
+			default:
+				logger.Fatalf("unknown --collect value %s", v)
+			}
+		}
+	}
+	if err := m.SetGCPolicy(policy); err != nil {
+		logger.Fatalf("error updating gc policy: %s", err)
+	}
+	fmt.Println("gc policy updated")
+}
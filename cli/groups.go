@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var groupFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "label",
+		Usage: "containers with the constraint label",
+	},
+	cli.StringFlag{
+		Name:  "app",
+		Usage: "containers of the application",
+	},
+	cli.StringFlag{
+		Name:  "project",
+		Usage: "containers of the applications of the compose project",
+	},
+	cli.StringFlag{
+		Name:  "selector",
+		Usage: "containers with matching labels, i.e. --selector tier=web,env!=dev",
+	},
+}
+
+var groupTimeoutFlag = cli.IntFlag{
+	Name:  "timeout",
+	Value: 10,
+	Usage: "seconds to wait for each container to stop before killing it",
+}
+
+var startGroupCommand = cli.Command{
+	Name:   "start-group",
+	Usage:  "start a group of containers, linked applications first",
+	Action: groupAction("start"),
+	Flags:  groupFlags,
+}
+
+var stopGroupCommand = cli.Command{
+	Name:   "stop-group",
+	Usage:  "stop a group of containers; its applications stay stopped until started again",
+	Action: groupAction("stop"),
+	Flags:  append([]cli.Flag{groupTimeoutFlag}, groupFlags...),
+}
+
+var restartGroupCommand = cli.Command{
+	Name:   "restart-group",
+	Usage:  "restart a group of containers",
+	Action: groupAction("restart"),
+	Flags:  append([]cli.Flag{groupTimeoutFlag}, groupFlags...),
+}
+
+var destroyGroupCommand = cli.Command{
+	Name:   "destroy-group",
+	Usage:  "destroy a group of containers and remove its applications",
+	Action: groupAction("destroy"),
+	Flags:  groupFlags,
+}
+
+func groupAction(action string) func(c *cli.Context) {
+	return func(c *cli.Context) {
+		cfg, err := loadConfig(c)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		m := client.NewManager(cfg)
+		group := &shipyard.ContainerGroup{
+			Label:       c.String("label"),
+			Application: c.String("app"),
+			Project:     c.String("project"),
+			Selector:    c.String("selector"),
+		}
+		if err := group.Validate(); err != nil {
+			logger.Fatal(err)
+		}
+		var res *shipyard.GroupResult
+		switch action {
+		case "start":
+			res, err = m.StartGroup(group)
+		case "stop":
+			res, err = m.StopGroup(group, c.Int("timeout"))
+		case "restart":
+			res, err = m.RestartGroup(group, c.Int("timeout"))
+		case "destroy":
+			res, err = m.DestroyGroup(group)
+		}
+		if err != nil {
+			logger.Fatalf("error applying %s to group: %s", action, err)
+		}
+		for _, id := range res.Containers {
+			fmt.Printf("%s %s\n", action, shortID(id))
+		}
+	}
+}
@@ -13,6 +13,7 @@ var infoCommand = cli.Command{
 	Name:   "info",
 	Usage:  "show cluster info",
 	Action: infoAction,
+	Flags:  []cli.Flag{outputFlag},
 }
 
 func infoAction(c *cli.Context) {
@@ -34,13 +35,23 @@ func infoAction(c *cli.Context) {
 		memPercentage = (info.ReservedMemory / info.Memory) * 100
 	}
 
+	if formatted(c, info) {
+		return
+	}
 	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
 	fmt.Fprintf(w, "Controller Version: %s\n", info.Version)
+	if info.Leader != "" {
+		fmt.Fprintf(w, "Controller: %s\n", info.Instance)
+		fmt.Fprintf(w, "Leader: %s\n", info.Leader)
+	}
 	fmt.Fprintf(w, "Cpus: %.2f\n", info.Cpus)
 	fmt.Fprintf(w, "Memory: %.2f MB\n", info.Memory)
 	fmt.Fprintf(w, "Containers: %d\n", info.ContainerCount)
 	fmt.Fprintf(w, "Images: %d\n", info.ImageCount)
 	fmt.Fprintf(w, "Engines: %d\n", info.EngineCount)
+	if info.Placement != "" {
+		fmt.Fprintf(w, "Placement: %s\n", info.Placement)
+	}
 	fmt.Fprintf(w, "Reserved Cpus: %.2f%% (%.2f)\n", cpuPercentage, info.ReservedCpus)
 	fmt.Fprintf(w, "Reserved Memory: %.2f%% (%.2f MB)\n", memPercentage, info.ReservedMemory)
 	w.Flush()
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var jobsListCommand = cli.Command{
+	Name:   "jobs",
+	Usage:  "list scheduled jobs",
+	Action: jobsListAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func jobsListAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	jobs, err := m.Jobs()
+	if err != nil {
+		logger.Fatalf("error getting jobs: %s", err)
+	}
+	if formatted(c, jobs) {
+		return
+	}
+	if len(jobs) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "ID\tSchedule\tImage\tCount\tNext Run")
+	for _, job := range jobs {
+		next := ""
+		if !job.NextRun.IsZero() {
+			next = job.NextRun.Format(time.RFC822)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", job.ID, job.Schedule, job.Image.Name, job.Count, next)
+	}
+	w.Flush()
+}
+
+var jobCreateCommand = cli.Command{
+	Name:        "create-job",
+	Usage:       "launch containers on a cron schedule",
+	Description: "create-job [options] <schedule> <image>, i.e. create-job \"0 3 * * *\" backup",
+	Action:      jobCreateAction,
+	Flags: []cli.Flag{
+		cli.IntFlag{
+			Name:  "count",
+			Value: 1,
+			Usage: "number of containers launched per run",
+		},
+		cli.Float64Flag{
+			Name:  "cpus",
+			Value: 0.1,
+			Usage: "cpu shares",
+		},
+		cli.Float64Flag{
+			Name:  "memory",
+			Value: 256,
+			Usage: "memory (in MB)",
+		},
+		cli.StringSliceFlag{
+			Name:  "env",
+			Usage: "environment variables (key=value pairs)",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "arg",
+			Usage: "run arguments",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "secret",
+			Usage: "inject a secret as an env var or file, i.e. --secret db-password, --secret db-password=DB_PASS or --secret db-password=/dev/shm/db-password",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "config",
+			Usage: "add the settings of a config bundle, i.e. --config production; env values can use {{config \"production\" \"DB_HOST\"}}",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "constraint",
+			Usage: "only run on engines with matching labels, i.e. --constraint region=us-east,ssd=true",
+			Value: &cli.StringSlice{},
+		},
+	},
+}
+
+func jobCreateAction(c *cli.Context) {
+	if len(c.Args()) != 2 {
+		logger.Fatal("you must specify a schedule and image")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	image := &citadel.Image{
+		Name:        c.Args().Get(1),
+		Cpus:        c.Float64("cpus"),
+		Memory:      c.Float64("memory"),
+		Args:        c.StringSlice("arg"),
+		Environment: parseEnvironmentVariables(c.StringSlice("env")),
+		Labels:      c.StringSlice("constraint"),
+		Type:        "service",
+	}
+	if err := shipyard.SetSecretRefs(image, parseSecretRefs(c.StringSlice("secret"))); err != nil {
+		logger.Fatal(err)
+	}
+	if err := shipyard.SetConfigNames(image, c.StringSlice("config")); err != nil {
+		logger.Fatal(err)
+	}
+	job, err := m.CreateJob(c.Args().First(), image, c.Int("count"))
+	if err != nil {
+		logger.Fatalf("error creating job: %s", err)
+	}
+	fmt.Printf("created job %s; next run %s\n", job.ID, job.NextRun.Format(time.RFC822))
+}
+
+var jobRemoveCommand = cli.Command{
+	Name:        "remove-job",
+	Usage:       "remove a scheduled job",
+	Description: "remove-job <id> [<id>]",
+	Action:      jobRemoveAction,
+}
+
+func jobRemoveAction(c *cli.Context) {
+	if len(c.Args()) == 0 {
+		logger.Fatal("you must specify an id")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	for _, id := range c.Args() {
+		if err := m.RemoveJob(id); err != nil {
+			logger.Fatalf("error removing job: %s", err)
+		}
+		fmt.Printf("removed %s\n", id)
+	}
+}
+
+var jobRunsCommand = cli.Command{
+	Name:        "job-runs",
+	Usage:       "show the run history of a job",
+	Description: "job-runs <id>",
+	Action:      jobRunsAction,
+	Flags:       []cli.Flag{outputFlag},
+}
+
+func jobRunsAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a job id")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	runs, err := m.JobRuns(c.Args().First())
+	if err != nil {
+		logger.Fatalf("error getting job runs: %s", err)
+	}
+	if formatted(c, runs) {
+		return
+	}
+	if len(runs) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "ID\tScheduled\tStatus\tContainers\tError")
+	for _, run := range runs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", run.ID, run.Scheduled.Format(time.RFC822), run.Status, len(run.Containers), run.Error)
+	}
+	w.Flush()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard/client"
+)
+
+var joinTokensListCommand = cli.Command{
+	Name:   "join-tokens",
+	Usage:  "list unused engine join tokens",
+	Action: joinTokensListAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func joinTokensListAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	tokens, err := m.JoinTokens()
+	if err != nil {
+		logger.Fatalf("error getting join tokens: %s", err)
+	}
+	if formatted(c, tokens) {
+		return
+	}
+	if len(tokens) == 0 {
+		return
+	}
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "ID\tCreated By\tExpires\tLabels")
+	for _, t := range tokens {
+		expires := "never"
+		switch {
+		case t.ExpiresAt.IsZero():
+		case t.Expired(now):
+			expires = "expired"
+		default:
+			expires = "in " + t.ExpiresAt.Sub(now).Truncate(time.Second).String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.ID, t.CreatedBy, expires, strings.Join(t.Labels, ","))
+	}
+	w.Flush()
+}
+
+var joinTokenCreateCommand = cli.Command{
+	Name:   "create-join-token",
+	Usage:  "create a single use token for registering a docker host with the agent",
+	Action: joinTokenCreateAction,
+	Flags: []cli.Flag{
+		cli.DurationFlag{
+			Name:  "expires, e",
+			Usage: "token lifetime (e.g. 1h); the token never expires when unset",
+		},
+		cli.StringSliceFlag{
+			Name:  "label",
+			Usage: "label added to the engine joining with the token; can be repeated",
+			Value: &cli.StringSlice{},
+		},
+	},
+}
+
+func joinTokenCreateAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	token, err := m.CreateJoinToken(c.Duration("expires"), c.StringSlice("label"))
+	if err != nil {
+		logger.Fatalf("error creating join token: %s", err)
+	}
+	fmt.Printf("created join token: %s\n", token.Token)
+}
+
+var joinTokenDeleteCommand = cli.Command{
+	Name:        "delete-join-token",
+	Usage:       "revoke an unused join token",
+	Description: "delete-join-token <id>",
+	Action:      joinTokenDeleteAction,
+}
+
+func joinTokenDeleteAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	args := c.Args()
+	if len(args) == 0 {
+		logger.Fatalf("you must specify a join token id")
+	}
+	for _, id := range args {
+		if err := m.DeleteJoinToken(id); err != nil {
+			logger.Fatalf("error deleting join token: %s", err)
+		}
+		fmt.Printf("deleted %s\n", id)
+	}
+}
@@ -2,15 +2,15 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"os/user"
-	"path/filepath"
 	"strings"
 
+	"code.google.com/p/go.crypto/ssh/terminal"
 	"github.com/codegangsta/cli"
 	"github.com/howeyc/gopass"
+	"github.com/shipyard/shipyard"
 	"github.com/shipyard/shipyard/client"
 )
 
@@ -18,32 +18,75 @@ var loginCommand = cli.Command{
 	Name:   "login",
 	Usage:  "login to a shipyard cluster",
 	Action: loginAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "credential-helper",
+			Usage: "docker credential helper tokens are stored with, i.e. osxkeychain, secretservice or wincred; none stores them in ~/.shipyard/config",
+		},
+	},
 }
 
-func saveConfig(cfg *client.ShipyardConfig) error {
-	usr, err := user.Current()
+// saveConfig stores cfg as the context selected by the --context flag, the
+// current context or the default one
+func saveConfig(c *cli.Context, cfg *client.ShipyardConfig) error {
+	contexts, err := readConfig()
 	if err != nil {
 		return err
 	}
-	path := filepath.Join(usr.HomeDir, CONFIG_PATH)
-	f, err := os.OpenFile(path, os.O_WRONLY, 0600)
+	return storeContext(contexts, contextName(c, contexts), cfg)
+}
+
+// saveToken replaces the token of the selected context, leaving out the
+// global flags applied to loaded configs
+func saveToken(c *cli.Context, token string) error {
+	contexts, err := readConfig()
+	if err != nil {
+		return err
+	}
+	name := contextName(c, contexts)
+	cfg, err := contexts.Context(name)
 	if err != nil {
-		if os.IsNotExist(err) {
-			fc, fErr := os.Create(path)
-			if fErr != nil {
+		return err
+	}
+	updated := *cfg
+	updated.Token = token
+	return storeContext(contexts, name, &updated)
+}
+
+func contextName(c *cli.Context, contexts *Config) string {
+	if name := c.GlobalString("context"); name != "" {
+		return name
+	}
+	if contexts.CurrentContext != "" {
+		return contexts.CurrentContext
+	}
+	return DEFAULT_CONTEXT
+}
+
+// storeContext writes a context, handing its token to the credential
+// helper when one is configured
+func storeContext(contexts *Config, name string, cfg *client.ShipyardConfig) error {
+	stored := *cfg
+	if contexts.CredentialHelper != "" && cfg.ServiceKey == "" {
+		h := credentialHelper(contexts.CredentialHelper)
+		if cfg.Token == "" {
+			if err := h.erase(cfg.Url); err != nil {
 				return err
 			}
-			fc.Chmod(0600)
-			f = fc
 		} else {
-			return err
+			creds := &helperCredentials{
+				ServerURL: cfg.Url,
+				Username:  cfg.Username,
+				Secret:    cfg.Token,
+			}
+			if err := h.store(creds); err != nil {
+				return err
+			}
 		}
+		stored.Token = ""
 	}
-	defer f.Close()
-	if err := json.NewEncoder(f).Encode(cfg); err != nil {
-		return err
-	}
-	return nil
+	contexts.SetContext(name, &stored)
+	return writeConfig(contexts)
 }
 
 func loginAction(c *cli.Context) {
@@ -65,21 +108,172 @@ func loginAction(c *cli.Context) {
 	pass := strings.TrimSpace(string(p[:]))
 
 	cfg := &client.ShipyardConfig{
-		Url:           sUrl,
-		Username:      username,
-		AllowInsecure: c.GlobalBool("allow-insecure"),
+		Url:       sUrl,
+		Username:  username,
+		Namespace: c.GlobalString("namespace"),
 	}
+	applyGlobalTLSFlags(c, cfg)
 	m := client.NewManager(cfg)
-	token, err := m.Login(username, pass)
+	token, err := login(m, reader, username, pass)
 	if err != nil {
 		logger.Fatal(err)
 	}
 	cfg.Token = token.Token
-	if err := saveConfig(cfg); err != nil {
+	if h := c.String("credential-helper"); h != "" {
+		if err := setCredentialHelper(h); err != nil {
+			logger.Fatal(err)
+		}
+	}
+	if err := saveConfig(c, cfg); err != nil {
 		logger.Fatal(err)
 	}
 }
 
+// login logs in, prompting for a one time code when the account has
+// two-factor auth enabled
+func login(m *client.Manager, reader *bufio.Reader, username string, pass string) (*shipyard.AuthToken, error) {
+	token, err := m.Login(username, pass)
+	if errors.Is(err, shipyard.ErrOTPRequired) {
+		fmt.Printf("OTP: ")
+		otp, rErr := reader.ReadString('\n')
+		if rErr != nil {
+			return nil, rErr
+		}
+		return m.LoginWithOTP(username, pass, strings.TrimSpace(otp))
+	}
+	return token, err
+}
+
+// setCredentialHelper stores tokens with a credential helper from now on;
+// "none" stores them in the config again
+func setCredentialHelper(name string) error {
+	contexts, err := readConfig()
+	if err != nil {
+		return err
+	}
+	if name == "none" {
+		name = ""
+	}
+	contexts.CredentialHelper = name
+	return writeConfig(contexts)
+}
+
+// reauthenticate logs in again when the controller rejects the saved
+// token, prompting for the password of the context account, and saves the
+// new token
+func reauthenticate(c *cli.Context, cfg *client.ShipyardConfig) (string, error) {
+	if cfg.Username == "" || !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return "", ErrLoginRequired
+	}
+	fmt.Fprintf(os.Stderr, "auth token of %s was rejected; log in again\nPassword: ", cfg.Username)
+	pass := strings.TrimSpace(string(gopass.GetPasswd()))
+	loginCfg := *cfg
+	loginCfg.Token = ""
+	loginCfg.Reauthenticate = nil
+	token, err := login(client.NewManager(&loginCfg), bufio.NewReader(os.Stdin), cfg.Username, pass)
+	if err != nil {
+		return "", err
+	}
+	if err := saveToken(c, token.Token); err != nil {
+		return "", err
+	}
+	return token.Token, nil
+}
+
+var logoutCommand = cli.Command{
+	Name:   "logout",
+	Usage:  "logout of a shipyard cluster and revoke the saved token",
+	Action: logoutAction,
+}
+
+func logoutAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	// a rejected token is logged out already
+	cfg.Reauthenticate = nil
+	m := client.NewManager(cfg)
+	if err := m.Logout(); err != nil {
+		logger.Fatal(err)
+	}
+	if err := saveToken(c, ""); err != nil {
+		logger.Fatal(err)
+	}
+}
+
+var refreshTokenCommand = cli.Command{
+	Name:   "refresh-token",
+	Usage:  "replace the saved auth token with a new one",
+	Action: refreshTokenAction,
+}
+
+func refreshTokenAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	token, err := m.RefreshToken()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if err := saveToken(c, token.Token); err != nil {
+		logger.Fatal(err)
+	}
+}
+
+var enable2FACommand = cli.Command{
+	Name:   "enable-2fa",
+	Usage:  "require a one time code from an authenticator app at login",
+	Action: enable2FAAction,
+}
+
+func enable2FAAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	enrollment, err := m.Enable2FA()
+	if err != nil {
+		logger.Fatalf("error enabling two-factor auth: %s", err)
+	}
+	fmt.Printf("Add this account to your authenticator app:\n\n  %s\n\nSecret: %s\n\n", enrollment.URI, enrollment.Secret)
+	fmt.Printf("Code: ")
+	code, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if err := m.Confirm2FA(strings.TrimSpace(code)); err != nil {
+		logger.Fatalf("error confirming two-factor auth: %s", err)
+	}
+	fmt.Println("two-factor auth enabled")
+}
+
+var disable2FACommand = cli.Command{
+	Name:   "disable-2fa",
+	Usage:  "stop requiring a one time code at login",
+	Action: disable2FAAction,
+}
+
+func disable2FAAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	fmt.Printf("Code: ")
+	code, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if err := m.Disable2FA(strings.TrimSpace(code)); err != nil {
+		logger.Fatalf("error disabling two-factor auth: %s", err)
+	}
+	fmt.Println("two-factor auth disabled")
+}
+
 var changePasswordCommand = cli.Command{
 	Name:   "change-password",
 	Usage:  "update your password",
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"io"
 	"os"
 
@@ -12,16 +11,17 @@ import (
 var logsCommand = cli.Command{
 	Name:        "logs",
 	Usage:       "show container logs",
-	Description: "logs <id> [--stdout] [--stderr]",
+	Description: "logs <id> [--follow] [--tail <n>]",
 	Action:      logsAction,
 	Flags: []cli.Flag{
 		cli.BoolFlag{
-			Name:  "stdout",
-			Usage: "show stdout",
+			Name:  "follow, f",
+			Usage: "follow log output",
 		},
-		cli.BoolFlag{
-			Name:  "stderr",
-			Usage: "show stderr",
+		cli.IntFlag{
+			Name:  "tail",
+			Value: 0,
+			Usage: "number of lines to show from the end of the logs (0 for all)",
 		},
 	},
 }
@@ -39,23 +39,11 @@ func logsAction(c *cli.Context) {
 	}
 	id := ids[0]
 
-	container, err := m.Container(id)
-	stdout := c.Bool("stdout")
-	stderr := c.Bool("stderr")
-
-	// if output not specified, use both
-	if stdout == false && stderr == false {
-		stdout = true
-		stderr = true
-	}
-
-	data, err := m.Logs(container, stdout, stderr)
+	data, err := m.Logs(id, c.Bool("follow"), c.Int("tail"))
 	if err != nil {
 		logger.Fatalf("error reading logs: %s", err)
 	}
+	defer data.Close()
 
-	buf := new(bytes.Buffer)
-	buf.ReadFrom(data)
-
-	io.Copy(os.Stdout, buf)
+	io.Copy(os.Stdout, data)
 }
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var namespacesListCommand = cli.Command{
+	Name:   "namespaces",
+	Usage:  "list namespaces",
+	Action: namespacesListAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func namespacesListAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	namespaces, err := m.Namespaces()
+	if err != nil {
+		logger.Fatalf("error getting namespaces: %s", err)
+	}
+	if formatted(c, namespaces) {
+		return
+	}
+	if len(namespaces) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Name\tDescription\tCreated")
+	for _, ns := range namespaces {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", ns.Name, ns.Description, ns.Created.Format(time.RFC822))
+	}
+	w.Flush()
+}
+
+var namespaceCreateCommand = cli.Command{
+	Name:        "create-namespace",
+	Usage:       "create a namespace",
+	Description: "create-namespace <name>",
+	Action:      namespaceCreateAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "description",
+			Usage: "namespace description",
+		},
+	},
+}
+
+func namespaceCreateAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	ns, err := m.CreateNamespace(&shipyard.Namespace{
+		Name:        c.Args().First(),
+		Description: c.String("description"),
+	})
+	if err != nil {
+		logger.Fatalf("error creating namespace: %s", err)
+	}
+	fmt.Printf("created namespace %s\n", ns.Name)
+}
+
+var namespaceDeleteCommand = cli.Command{
+	Name:        "delete-namespace",
+	Usage:       "delete a namespace without containers",
+	Description: "delete-namespace <name> [<name>]",
+	Action:      namespaceDeleteAction,
+}
+
+func namespaceDeleteAction(c *cli.Context) {
+	if len(c.Args()) == 0 {
+		logger.Fatal("you must specify a name")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	for _, name := range c.Args() {
+		if err := m.DeleteNamespace(name); err != nil {
+			logger.Fatalf("error deleting namespace: %s", err)
+		}
+		fmt.Printf("deleted %s\n", name)
+	}
+}
+
+var namespaceRoleCommand = cli.Command{
+	Name:        "set-namespace-role",
+	Usage:       "grant an account a role in a namespace",
+	Description: "set-namespace-role <username> <namespace> <role>; omit the role to revoke it",
+	Action:      namespaceRoleAction,
+}
+
+func namespaceRoleAction(c *cli.Context) {
+	args := c.Args()
+	if len(args) != 2 && len(args) != 3 {
+		logger.Fatal("you must specify a username, a namespace and a role")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	role := ""
+	if len(args) == 3 {
+		role = args[2]
+	}
+	if err := m.SetAccountNamespaceRole(args[0], args[1], role); err != nil {
+		logger.Fatalf("error setting namespace role: %s", err)
+	}
+	if role == "" {
+		fmt.Printf("revoked role of %s in %s\n", args[0], args[1])
+		return
+	}
+	fmt.Printf("granted %s role %s in %s\n", args[0], role, args[1])
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var notifiersListCommand = cli.Command{
+	Name:   "notifiers",
+	Usage:  "list slack and email notifiers",
+	Action: notifiersListAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func notifiersListAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	notifiers, err := m.Notifiers()
+	if err != nil {
+		logger.Fatalf("error getting notifiers: %s", err)
+	}
+	if formatted(c, notifiers) {
+		return
+	}
+	if len(notifiers) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "ID\tName\tType\tSeverity\tEvents")
+	for _, n := range notifiers {
+		severity := n.MinSeverity
+		if severity == "" {
+			severity = shipyard.SeverityInfo
+		}
+		events := "all"
+		if len(n.EventTypes) > 0 {
+			events = strings.Join(n.EventTypes, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", n.ID, n.Name, n.Type, severity, events)
+	}
+	w.Flush()
+}
+
+var notifierCreateCommand = cli.Command{
+	Name:        "add-notifier",
+	Usage:       "send cluster events to slack or email",
+	Description: "add-notifier [options] <name>",
+	Action:      notifierCreateAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "min-severity",
+			Value: "",
+			Usage: "only send events of this severity or above (info, warning, critical)",
+		},
+		cli.StringSliceFlag{
+			Name:  "event, e",
+			Value: &cli.StringSlice{},
+			Usage: "only send events of this type; can be repeated",
+		},
+		cli.StringFlag{
+			Name:  "slack-url",
+			Value: "",
+			Usage: "slack incoming webhook url",
+		},
+		cli.StringFlag{
+			Name:  "slack-channel",
+			Value: "",
+			Usage: "slack channel; defaults to the webhook channel",
+		},
+		cli.StringFlag{
+			Name:  "smtp-addr",
+			Value: "",
+			Usage: "smtp server (host:port)",
+		},
+		cli.StringFlag{
+			Name:  "smtp-username",
+			Value: "",
+			Usage: "smtp username",
+		},
+		cli.StringFlag{
+			Name:  "smtp-password",
+			Value: "",
+			Usage: "smtp password",
+		},
+		cli.StringFlag{
+			Name:  "from",
+			Value: "",
+			Usage: "email sender",
+		},
+		cli.StringSliceFlag{
+			Name:  "to",
+			Value: &cli.StringSlice{},
+			Usage: "email recipient; can be repeated",
+		},
+	},
+}
+
+func notifierCreateAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	n := &shipyard.Notifier{
+		Name:        c.Args().First(),
+		MinSeverity: c.String("min-severity"),
+		EventTypes:  c.StringSlice("event"),
+	}
+	switch {
+	case c.String("slack-url") != "":
+		n.Type = shipyard.NotifierSlack
+		n.Slack = &shipyard.SlackConfig{
+			WebhookURL: c.String("slack-url"),
+			Channel:    c.String("slack-channel"),
+		}
+	case c.String("smtp-addr") != "":
+		n.Type = shipyard.NotifierEmail
+		n.Email = &shipyard.EmailConfig{
+			SMTPAddr: c.String("smtp-addr"),
+			Username: c.String("smtp-username"),
+			Password: c.String("smtp-password"),
+			From:     c.String("from"),
+			To:       c.StringSlice("to"),
+		}
+	default:
+		logger.Fatal("you must specify --slack-url or --smtp-addr")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	created, err := m.AddNotifier(n)
+	if err != nil {
+		logger.Fatalf("error adding notifier: %s", err)
+	}
+	fmt.Printf("added notifier %s\n", created.ID)
+}
+
+var notifierRemoveCommand = cli.Command{
+	Name:        "remove-notifier",
+	Usage:       "removes a notifier",
+	Description: "remove-notifier <id> [<id>]",
+	Action:      notifierRemoveAction,
+}
+
+func notifierRemoveAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	for _, id := range c.Args() {
+		if err := m.RemoveNotifier(id); err != nil {
+			logger.Fatalf("error removing notifier: %s", err)
+		}
+		fmt.Printf("removed %s\n", id)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard/client"
+)
+
+var pauseCommand = cli.Command{
+	Name:        "pause",
+	Usage:       "pause a container",
+	Description: "pause <id> [<id>]",
+	Action:      pauseAction,
+}
+
+func pauseAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	containers, err := m.Containers()
+	if err != nil {
+		logger.Fatalf("error getting container info: %s", err)
+	}
+	ids := c.Args()
+	if len(ids) == 0 {
+		logger.Fatalf("you must specify at least one id")
+	}
+	for _, cnt := range containers {
+		// this can probably be more efficient
+		for _, i := range ids {
+			if strings.HasPrefix(cnt.ID, i) {
+				if err := m.Pause(cnt); err != nil {
+					logger.Fatalf("error pausing container: %s\n", err)
+				}
+				fmt.Printf("paused %s\n", cnt.ID[:12])
+			}
+		}
+	}
+}
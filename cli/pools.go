@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var poolsListCommand = cli.Command{
+	Name:   "pools",
+	Usage:  "list engine pools",
+	Action: poolsListAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func poolsListAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	pools, err := m.EnginePools()
+	if err != nil {
+		logger.Fatalf("error getting engine pools: %s", err)
+	}
+	if formatted(c, pools) {
+		return
+	}
+	if len(pools) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Name\tConstraints\tEngines\tDescription")
+	for _, p := range pools {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Name, strings.Join(p.Constraints, " "), strings.Join(p.Engines, ","), p.Description)
+	}
+	w.Flush()
+}
+
+var poolFlags = []cli.Flag{
+	cli.StringSliceFlag{
+		Name:  "constraint",
+		Usage: "engine label expression engines of the pool satisfy, i.e. --constraint zone=a",
+		Value: &cli.StringSlice{},
+	},
+	cli.StringFlag{
+		Name:  "description",
+		Usage: "pool description",
+	},
+}
+
+var poolCreateCommand = cli.Command{
+	Name:        "create-pool",
+	Usage:       "group the engines matching label constraints into a named pool",
+	Description: "create-pool <name>",
+	Action:      poolCreateAction,
+	Flags:       poolFlags,
+}
+
+func poolCreateAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	pool, err := m.CreateEnginePool(&shipyard.EnginePool{
+		Name:        c.Args().First(),
+		Description: c.String("description"),
+		Constraints: c.StringSlice("constraint"),
+	})
+	if err != nil {
+		logger.Fatalf("error creating engine pool: %s", err)
+	}
+	fmt.Printf("created engine pool %s with %d engines\n", pool.Name, len(pool.Engines))
+}
+
+var poolUpdateCommand = cli.Command{
+	Name:        "update-pool",
+	Usage:       "change the constraints or description of an engine pool",
+	Description: "update-pool <name>",
+	Action:      poolUpdateAction,
+	Flags:       poolFlags,
+}
+
+func poolUpdateAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	pool, err := m.EnginePool(c.Args().First())
+	if err != nil {
+		logger.Fatalf("error getting engine pool: %s", err)
+	}
+	if constraints := c.StringSlice("constraint"); len(constraints) > 0 {
+		pool.Constraints = constraints
+	}
+	if c.IsSet("description") {
+		pool.Description = c.String("description")
+	}
+	if err := m.UpdateEnginePool(pool); err != nil {
+		logger.Fatalf("error updating engine pool: %s", err)
+	}
+	fmt.Printf("updated engine pool %s\n", pool.Name)
+}
+
+var poolDeleteCommand = cli.Command{
+	Name:        "delete-pool",
+	Usage:       "delete an engine pool",
+	Description: "delete-pool <name>",
+	Action:      poolDeleteAction,
+}
+
+func poolDeleteAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	if len(c.Args()) == 0 {
+		logger.Fatal("you must specify a name")
+	}
+	for _, name := range c.Args() {
+		if err := m.DeleteEnginePool(name); err != nil {
+			logger.Fatalf("error deleting engine pool: %s", err)
+		}
+		fmt.Printf("deleted engine pool %s\n", name)
+	}
+}
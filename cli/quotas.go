@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var quotasListCommand = cli.Command{
+	Name:   "quotas",
+	Usage:  "list quotas and their usage",
+	Action: quotasListAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func quotasListAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	quotas, err := m.Quotas()
+	if err != nil {
+		logger.Fatalf("error getting quotas: %s", err)
+	}
+	if formatted(c, quotas) {
+		return
+	}
+	if len(quotas) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Name\tApplies To\tContainers\tCpus\tMemory")
+	for _, u := range quotas {
+		q := u.Quota
+		to := "account " + q.Account
+		switch {
+		case q.Role != "":
+			to = "role " + q.Role
+		case q.Namespace != "":
+			to = "namespace " + q.Namespace
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", q.Name, to,
+			quotaLimit(float64(u.Containers), float64(q.MaxContainers), "%.0f"),
+			quotaLimit(u.Cpus, q.MaxCpus, "%.2f"),
+			quotaLimit(u.Memory, q.MaxMemory, "%.0f"))
+	}
+	w.Flush()
+}
+
+// quotaLimit formats usage against a limit; zero limits are unlimited
+func quotaLimit(used float64, max float64, format string) string {
+	if max == 0 {
+		return fmt.Sprintf(format+"/-", used)
+	}
+	return fmt.Sprintf(format+"/"+format, used, max)
+}
+
+var quotaCreateCommand = cli.Command{
+	Name:        "create-quota",
+	Usage:       "limit the running containers of an account, role or namespace",
+	Description: "create-quota <name>",
+	Action:      quotaCreateAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "account",
+			Usage: "username the quota applies to",
+		},
+		cli.StringFlag{
+			Name:  "role",
+			Usage: "role whose accounts share the quota",
+		},
+		cli.StringFlag{
+			Name:  "namespace",
+			Usage: "namespace whose containers share the quota",
+		},
+		cli.IntFlag{
+			Name:  "containers",
+			Usage: "maximum running containers",
+		},
+		cli.Float64Flag{
+			Name:  "cpus",
+			Usage: "maximum reserved cpus",
+		},
+		cli.Float64Flag{
+			Name:  "memory",
+			Usage: "maximum reserved memory (in MB)",
+		},
+	},
+}
+
+func quotaCreateAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	quota, err := m.CreateQuota(&shipyard.Quota{
+		Name:          c.Args().First(),
+		Account:       c.String("account"),
+		Role:          c.String("role"),
+		Namespace:     c.String("namespace"),
+		MaxContainers: c.Int("containers"),
+		MaxCpus:       c.Float64("cpus"),
+		MaxMemory:     c.Float64("memory"),
+	})
+	if err != nil {
+		logger.Fatalf("error creating quota: %s", err)
+	}
+	fmt.Printf("created quota %s\n", quota.Name)
+}
+
+var quotaDeleteCommand = cli.Command{
+	Name:        "delete-quota",
+	Usage:       "delete a quota",
+	Description: "delete-quota <name> [<name>]",
+	Action:      quotaDeleteAction,
+}
+
+func quotaDeleteAction(c *cli.Context) {
+	if len(c.Args()) == 0 {
+		logger.Fatal("you must specify a name")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	for _, name := range c.Args() {
+		if err := m.DeleteQuota(name); err != nil {
+			logger.Fatalf("error deleting quota: %s", err)
+		}
+		fmt.Printf("deleted %s\n", name)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var rebalanceCommand = cli.Command{
+	Name:   "rebalance",
+	Usage:  "move containers from over utilized engines to under utilized ones",
+	Action: rebalanceAction,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "show the planned moves without running them",
+		},
+		cli.Float64Flag{
+			Name:  "threshold",
+			Usage: "utilization difference in percent between engines above which containers are moved",
+			Value: shipyard.DefaultRebalanceThreshold,
+		},
+		cli.IntFlag{
+			Name:  "max-moves",
+			Usage: "maximum number of containers to move (0 for no limit)",
+		},
+	},
+}
+
+func rebalanceAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	moves, err := m.Rebalance(&shipyard.RebalanceOptions{
+		DryRun:    c.Bool("dry-run"),
+		Threshold: c.Float64("threshold"),
+		MaxMoves:  c.Int("max-moves"),
+	})
+	if err != nil {
+		logger.Fatalf("error rebalancing cluster: %s", err)
+	}
+	if len(moves) == 0 {
+		fmt.Println("cluster is balanced")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Container\tImage\tFrom\tTo\tReplacement")
+	for _, mv := range moves {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", shortID(mv.Container), mv.Image, mv.From, mv.To, shortID(mv.Replacement))
+	}
+	w.Flush()
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
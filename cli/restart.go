@@ -13,6 +13,13 @@ var restartCommand = cli.Command{
 	Usage:       "restart a container",
 	Description: "restart <id> [<id>]",
 	Action:      restartAction,
+	Flags: []cli.Flag{
+		cli.IntFlag{
+			Name:  "timeout",
+			Value: 10,
+			Usage: "seconds to wait for the container to stop before killing it",
+		},
+	},
 }
 
 func restartAction(c *cli.Context) {
@@ -33,7 +40,7 @@ func restartAction(c *cli.Context) {
 		// this can probably be more efficient
 		for _, i := range ids {
 			if strings.HasPrefix(cnt.ID, i) {
-				if err := m.Restart(cnt); err != nil {
+				if err := m.Restart(cnt, c.Int("timeout")); err != nil {
 					logger.Fatalf("error restarting container: %s\n", err)
 				}
 				fmt.Printf("restarted %s\n", cnt.ID[:12])
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var expireEventsCommand = cli.Command{
+	Name:   "expire-events",
+	Usage:  "archive and remove the events expired by the retention policy",
+	Action: expireEventsAction,
+}
+
+func expireEventsAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	result, err := m.ApplyEventRetention()
+	if err != nil {
+		logger.Fatalf("error expiring events: %s", err)
+	}
+	for _, o := range result.Objects {
+		fmt.Printf("archived %s\n", o)
+	}
+	fmt.Printf("removed %d events\n", result.Removed)
+}
+
+var eventRetentionCommand = cli.Command{
+	Name:   "event-retention",
+	Usage:  "show the event retention policy",
+	Action: eventRetentionAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func eventRetentionAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	policy, err := m.EventRetention()
+	if err != nil {
+		logger.Fatalf("error getting event retention: %s", err)
+	}
+	if formatted(c, policy) {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(w, "Enabled:\t%v\n", policy.Enabled)
+	fmt.Fprintf(w, "Interval:\t%dm\n", int(policy.Period().Minutes()))
+	fmt.Fprintf(w, "Max Age:\t%dh\n", policy.MaxAge)
+	fmt.Fprintf(w, "Max Count:\t%d\n", policy.MaxCount)
+	if a := policy.Archive; a != nil {
+		fmt.Fprintf(w, "Archive:\t%s://%s/%s\n", a.Type, a.Bucket, a.Prefix)
+		if a.Endpoint != "" {
+			fmt.Fprintf(w, "Archive Endpoint:\t%s\n", a.Endpoint)
+		}
+	} else {
+		fmt.Fprintf(w, "Archive:\tnone\n")
+	}
+	w.Flush()
+}
+
+var setEventRetentionCommand = cli.Command{
+	Name:   "set-event-retention",
+	Usage:  "update the event retention policy",
+	Action: setEventRetentionAction,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "enable",
+			Usage: "apply the retention automatically",
+		},
+		cli.BoolFlag{
+			Name:  "disable",
+			Usage: "stop applying the retention automatically",
+		},
+		cli.IntFlag{
+			Name:  "interval",
+			Usage: "minutes between automatic runs",
+		},
+		cli.IntFlag{
+			Name:  "max-age",
+			Usage: "hours events are kept; 0 keeps them regardless of age",
+		},
+		cli.IntFlag{
+			Name:  "max-count",
+			Usage: "most recent events kept; 0 keeps them regardless of count",
+		},
+		cli.StringFlag{
+			Name:  "archive",
+			Usage: "archive expired events to s3 or gcs; none stops archiving",
+		},
+		cli.StringFlag{
+			Name:  "archive-bucket",
+			Usage: "bucket of the archives",
+		},
+		cli.StringFlag{
+			Name:  "archive-prefix",
+			Usage: "prefix of the archive object names",
+		},
+		cli.StringFlag{
+			Name:  "archive-region",
+			Usage: "region of the bucket",
+		},
+		cli.StringFlag{
+			Name:  "archive-endpoint",
+			Usage: "https url of an s3 compatible service",
+		},
+		cli.StringFlag{
+			Name:  "archive-access-key",
+			Usage: "access key of the bucket (hmac key for gcs)",
+		},
+		cli.StringFlag{
+			Name:  "archive-secret-key",
+			Usage: "secret key of the bucket; the current one is kept when empty",
+		},
+	},
+}
+
+func setEventRetentionAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	policy, err := m.EventRetention()
+	if err != nil {
+		logger.Fatalf("error getting event retention: %s", err)
+	}
+	switch {
+	case c.Bool("enable") && c.Bool("disable"):
+		logger.Fatalf("--enable and --disable cannot be used together")
+	case c.Bool("enable"):
+		policy.Enabled = true
+	case c.Bool("disable"):
+		policy.Enabled = false
+	}
+	if c.IsSet("interval") {
+		policy.Interval = c.Int("interval")
+	}
+	if c.IsSet("max-age") {
+		policy.MaxAge = c.Int("max-age")
+	}
+	if c.IsSet("max-count") {
+		policy.MaxCount = c.Int("max-count")
+	}
+	switch t := c.String("archive"); t {
+	case "":
+	case "none":
+		policy.Archive = nil
+	default:
+		if policy.Archive == nil {
+			policy.Archive = &shipyard.EventArchive{}
+		}
+		policy.Archive.Type = t
+	}
+	if a := policy.Archive; a != nil {
+		if c.IsSet("archive-bucket") {
+			a.Bucket = c.String("archive-bucket")
+		}
+		if c.IsSet("archive-prefix") {
+			a.Prefix = c.String("archive-prefix")
+		}
+		if c.IsSet("archive-region") {
+			a.Region = c.String("archive-region")
+		}
+		if c.IsSet("archive-endpoint") {
+			a.Endpoint = c.String("archive-endpoint")
+		}
+		if v := c.String("archive-access-key"); v != "" {
+			a.AccessKey = v
+		}
+		a.SecretKey = c.String("archive-secret-key")
+	}
+	if err := m.SetEventRetention(policy); err != nil {
+		logger.Fatalf("error updating event retention: %s", err)
+	}
+	fmt.Println("event retention updated")
+}
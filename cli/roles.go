@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var rolesListCommand = cli.Command{
+	Name:   "roles",
+	Usage:  "list roles",
+	Action: rolesListAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func rolesListAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	roles, err := m.Roles()
+	if err != nil {
+		logger.Fatalf("error getting roles: %s", err)
+	}
+	if formatted(c, roles) {
+		return
+	}
+	if len(roles) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Name\tPermissions")
+	for _, r := range roles {
+		fmt.Fprintf(w, "%s\t%s\n", r.Name, strings.Join(r.EffectivePermissions(), ","))
+	}
+	w.Flush()
+}
+
+var roleInspectCommand = cli.Command{
+	Name:        "role",
+	Usage:       "show the permissions of a role",
+	Description: "role <name>",
+	Action:      roleInspectAction,
+}
+
+func roleInspectAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	role, err := m.Role(c.Args().First())
+	if err != nil {
+		logger.Fatalf("error getting role: %s", err)
+	}
+	for _, p := range role.EffectivePermissions() {
+		fmt.Println(p)
+	}
+}
+
+var roleCreateCommand = cli.Command{
+	Name:        "create-role",
+	Usage:       "create a role",
+	Description: "create-role <name>",
+	Action:      roleCreateAction,
+	Flags: []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "permission, p",
+			Usage: "resource:action permission granted by the role (e.g. containers:read)",
+			Value: &cli.StringSlice{},
+		},
+	},
+}
+
+func roleCreateAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	role := &shipyard.Role{
+		Name:        c.Args().First(),
+		Permissions: c.StringSlice("permission"),
+	}
+	if err := m.CreateRole(role); err != nil {
+		logger.Fatalf("error creating role: %s", err)
+	}
+	fmt.Printf("created role %s\n", role.Name)
+}
+
+var roleUpdateCommand = cli.Command{
+	Name:        "update-role",
+	Usage:       "replace the permissions of a role",
+	Description: "update-role <name>",
+	Action:      roleUpdateAction,
+	Flags: []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "permission, p",
+			Usage: "resource:action permission granted by the role (e.g. containers:read)",
+			Value: &cli.StringSlice{},
+		},
+	},
+}
+
+func roleUpdateAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	role := &shipyard.Role{
+		Name:        c.Args().First(),
+		Permissions: c.StringSlice("permission"),
+	}
+	if err := m.UpdateRole(role); err != nil {
+		logger.Fatalf("error updating role: %s", err)
+	}
+	fmt.Printf("updated role %s\n", role.Name)
+}
+
+var roleDeleteCommand = cli.Command{
+	Name:        "delete-role",
+	Usage:       "delete a role",
+	Description: "delete-role <name> [<name>]",
+	Action:      roleDeleteAction,
+}
+
+func roleDeleteAction(c *cli.Context) {
+	if len(c.Args()) == 0 {
+		logger.Fatal("you must specify a name")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	for _, name := range c.Args() {
+		if err := m.DeleteRole(&shipyard.Role{Name: name}); err != nil {
+			logger.Fatalf("error deleting role: %s", err)
+		}
+		fmt.Printf("deleted %s\n", name)
+	}
+}
+
+var accountRoleCommand = cli.Command{
+	Name:        "set-role",
+	Usage:       "set the role of an account",
+	Description: "set-role <username> <role>",
+	Action:      accountRoleAction,
+}
+
+func accountRoleAction(c *cli.Context) {
+	args := c.Args()
+	if len(args) != 2 {
+		logger.Fatal("you must specify a username and a role")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	if err := m.SetAccountRole(args[0], args[1]); err != nil {
+		logger.Fatalf("error setting role: %s", err)
+	}
+	fmt.Printf("set role of %s to %s\n", args[0], args[1])
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"text/tabwriter"
 
 	"github.com/citadel/citadel"
 	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
 	"github.com/shipyard/shipyard/client"
 )
 
@@ -76,9 +79,24 @@ var runCommand = cli.Command{
 			Usage: "volume (/host/path:/container/path or /container/path)",
 			Value: &cli.StringSlice{},
 		},
+		cli.StringSliceFlag{
+			Name:  "volume",
+			Usage: "mount a named volume and run on its engine, i.e. --volume pgdata:/var/lib/postgresql/data or --volume assets:/srv:ro",
+			Value: &cli.StringSlice{},
+		},
 		cli.StringSliceFlag{
 			Name:  "label",
-			Usage: "labels",
+			Usage: "only run on engines with the label",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "container-label",
+			Usage: "label the containers for selection, i.e. --container-label tier=web",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "constraint",
+			Usage: "only run on engines with matching labels, i.e. --constraint region=us-east,ssd=true --constraint zone!=b",
 			Value: &cli.StringSlice{},
 		},
 		cli.StringSliceFlag{
@@ -104,6 +122,86 @@ var runCommand = cli.Command{
 			Value: "no",
 			Usage: "restart policy for container (on-failure, always, on-failure:5, etc.)",
 		},
+		cli.StringFlag{
+			Name:  "auto-restart",
+			Value: "no",
+			Usage: "restart the container in place from the controller with a backoff and restart events when it exits (on-failure, always, on-failure:5); not with --restart",
+		},
+		cli.StringFlag{
+			Name:  "reschedule",
+			Value: "no",
+			Usage: "move the container to another engine when its engine fails (on-engine-failure) or also when it exits (on-failure, always)",
+		},
+		cli.StringSliceFlag{
+			Name:  "secret",
+			Usage: "inject a secret as an env var or file, i.e. --secret db-password, --secret db-password=DB_PASS or --secret db-password=/dev/shm/db-password",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "config",
+			Usage: "add the settings of a config bundle, i.e. --config production; env values can use {{config \"production\" \"DB_HOST\"}}",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "affinity",
+			Usage: "only run on engines running a container of the image, i.e. --affinity redis",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "anti-affinity",
+			Usage: "never run on engines running a container of the image",
+			Value: &cli.StringSlice{},
+		},
+		cli.BoolFlag{
+			Name:  "spread",
+			Usage: "never run two replicas on the same engine",
+		},
+		cli.StringFlag{
+			Name:  "spread-by",
+			Usage: "balance replicas across the values of an engine label, i.e. --spread-by zone",
+		},
+		cli.StringFlag{
+			Name:  "pool",
+			Usage: "only run on the engines of an engine pool",
+		},
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "show the engines the containers would be placed on without starting them",
+		},
+		cli.StringFlag{
+			Name:  "placement",
+			Usage: "placement strategy overriding the controller default: spread, binpack, random or label-affinity",
+		},
+		cli.StringFlag{
+			Name:  "health-check",
+			Value: "",
+			Usage: "health check probed by the controller (http:<port><path>, tcp:<port> or exec:<command>)",
+		},
+		cli.IntFlag{
+			Name:  "health-interval",
+			Value: shipyard.DefaultHealthCheckInterval,
+			Usage: "seconds between health checks",
+		},
+		cli.IntFlag{
+			Name:  "health-timeout",
+			Value: shipyard.DefaultHealthCheckTimeout,
+			Usage: "seconds a health check may take",
+		},
+		cli.IntFlag{
+			Name:  "health-retries",
+			Value: shipyard.DefaultHealthCheckRetries,
+			Usage: "consecutive failed health checks before the container is unhealthy",
+		},
+		cli.StringFlag{
+			Name:  "pre-stop",
+			Value: "",
+			Usage: "command run in the container before it is stopped, i.e. --pre-stop \"sh -c 'sleep 5 && nginx -s quit'\"",
+		},
+		cli.IntFlag{
+			Name:  "stop-grace-period",
+			Value: 0,
+			Usage: "seconds the container is given to exit when stopped or removed before it is killed",
+		},
 	},
 }
 
@@ -137,7 +235,7 @@ func runAction(c *cli.Context) {
 		Hostname:      c.String("hostname"),
 		Domainname:    c.String("domain"),
 		NetworkMode:   c.String("network"),
-		Labels:        c.StringSlice("label"),
+		Labels:        append(c.StringSlice("label"), c.StringSlice("constraint")...),
 		Args:          c.StringSlice("arg"),
 		Environment:   env,
 		Links:         links,
@@ -147,6 +245,65 @@ func runAction(c *cli.Context) {
 		RestartPolicy: rp,
 		Type:          c.String("type"),
 	}
+	restart, err := shipyard.ParseRestartPolicy(c.String("auto-restart"))
+	if err != nil {
+		logger.Fatalf("error parsing auto restart policy: %s", err)
+	}
+	if restart.Name != shipyard.RestartNo {
+		if policy != "" && policy != shipyard.RestartNo {
+			logger.Fatal("--restart and --auto-restart cannot be used together")
+		}
+		if err := shipyard.SetRestartPolicy(image, restart); err != nil {
+			logger.Fatal(err)
+		}
+	}
+	if p := c.String("reschedule"); p != shipyard.RescheduleNo {
+		if err := shipyard.SetReschedulePolicy(image, p); err != nil {
+			logger.Fatal(err)
+		}
+	}
+	if err := shipyard.SetSecretRefs(image, parseSecretRefs(c.StringSlice("secret"))); err != nil {
+		logger.Fatal(err)
+	}
+	if err := shipyard.SetConfigNames(image, c.StringSlice("config")); err != nil {
+		logger.Fatal(err)
+	}
+	if err := shipyard.SetVolumeMounts(image, parseVolumeMounts(c.StringSlice("volume"))); err != nil {
+		logger.Fatal(err)
+	}
+	if affinity := parseAffinity(c); affinity != nil {
+		if err := shipyard.SetAffinity(image, affinity); err != nil {
+			logger.Fatal(err)
+		}
+	}
+	if err := shipyard.SetLabels(image, parseContainerLabels(c)); err != nil {
+		logger.Fatal(err)
+	}
+	shipyard.SetPool(image, c.String("pool"))
+	if err := shipyard.SetPlacement(image, c.String("placement")); err != nil {
+		logger.Fatal(err)
+	}
+	if spec := c.String("health-check"); spec != "" {
+		hc, err := shipyard.ParseHealthCheck(spec)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		hc.Interval = c.Int("health-interval")
+		hc.Timeout = c.Int("health-timeout")
+		hc.Retries = c.Int("health-retries")
+		if err := shipyard.SetHealthCheck(image, hc); err != nil {
+			logger.Fatal(err)
+		}
+	}
+	if h := parseStopHook(c); h != nil {
+		if err := shipyard.SetStopHook(image, h); err != nil {
+			logger.Fatal(err)
+		}
+	}
+	if c.Bool("dry-run") {
+		previewRun(m, image, c.Int("count"))
+		return
+	}
 	containers, err := m.Run(image, c.Int("count"), c.Bool("pull"))
 	if err != nil {
 		logger.Fatalf("error running container: %s\n", err)
@@ -155,3 +312,61 @@ func runAction(c *cli.Context) {
 		fmt.Printf("started %s on %s\n", c.ID[:12], c.Engine.ID)
 	}
 }
+
+func previewRun(m *client.Manager, image *citadel.Image, count int) {
+	preview, err := m.PreviewRun(image, count)
+	if err != nil {
+		logger.Fatalf("error previewing run: %s", err)
+	}
+	for _, p := range preview.Placements {
+		fmt.Printf("would start on %s (cpus=%.2f memory=%.2f MB)\n", p.Engine, p.Cpus, p.Memory)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Engine\tCpus\tReserved Cpus\tMemory\tReserved Memory\tUtilization")
+	for _, e := range preview.Engines {
+		fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%.2f MB\t%.2f MB\t%.0f%%\n", e.ID, e.Cpus, e.ReservedCpus, e.Memory, e.ReservedMemory, e.Utilization())
+	}
+	w.Flush()
+	if preview.Unplaced > 0 {
+		logger.Fatalf("%d of %d containers can not be placed: %s", preview.Unplaced, count, preview.Error)
+	}
+}
+
+// parseContainerLabels returns the labels given by the container-label
+// flags; labels without a value are set to an empty string
+func parseContainerLabels(c *cli.Context) map[string]string {
+	return shipyard.ParseLabels(c.StringSlice("container-label"))
+}
+
+// parseAffinity returns the placement affinity given by the affinity flags
+// or nil if none were set
+func parseAffinity(c *cli.Context) *shipyard.Affinity {
+	affinity := &shipyard.Affinity{
+		Images:     c.StringSlice("affinity"),
+		AntiImages: c.StringSlice("anti-affinity"),
+		Spread:     c.Bool("spread"),
+		SpreadBy:   c.String("spread-by"),
+	}
+	if len(affinity.Images) == 0 && len(affinity.AntiImages) == 0 && !affinity.Spread && affinity.SpreadBy == "" {
+		return nil
+	}
+	return affinity
+}
+
+// parseStopHook returns the stop hook given by the pre-stop and
+// stop-grace-period flags or nil if none were set.  The pre-stop command is
+// split like a shell would so quoted arguments are kept together.
+func parseStopHook(c *cli.Context) *shipyard.StopHook {
+	preStop, err := shipyard.SplitCommand(c.String("pre-stop"))
+	if err != nil {
+		logger.Fatal(err)
+	}
+	h := &shipyard.StopHook{
+		PreStop:     preStop,
+		GracePeriod: c.Int("stop-grace-period"),
+	}
+	if len(h.PreStop) == 0 && h.GracePeriod == 0 {
+		return nil
+	}
+	return h
+}
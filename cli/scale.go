@@ -33,14 +33,14 @@ func scaleAction(c *cli.Context) {
 	m := client.NewManager(cfg)
 	containerId := c.String("id")
 	count := c.Int("count")
+	if containerId == "" {
+		logger.Fatalf("you must specify a container id")
+	}
 	container, err := m.Container(containerId)
 	if err != nil {
 		logger.Fatalf("error getting container info: %s", err)
 	}
-	if containerId == "" {
-		logger.Fatalf("you must specify a container id")
-	}
-	if err := m.Scale(container, count); err != nil {
+	if err := m.Scale(container.Image, count); err != nil {
 		logger.Fatalf("error scaling container: %s\n", err)
 	}
 	fmt.Printf("scaled %s to %d\n", container.ID[:12], count)
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"code.google.com/p/go.crypto/ssh/terminal"
+	"github.com/codegangsta/cli"
+	"github.com/howeyc/gopass"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var secretsListCommand = cli.Command{
+	Name:   "secrets",
+	Usage:  "list secrets",
+	Action: secretsListAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func secretsListAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	secrets, err := m.Secrets()
+	if err != nil {
+		logger.Fatalf("error getting secrets: %s", err)
+	}
+	if formatted(c, secrets) {
+		return
+	}
+	if len(secrets) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Name\tCreated")
+	for _, s := range secrets {
+		fmt.Fprintf(w, "%s\t%s\n", s.Name, s.Created.Format(time.RFC822))
+	}
+	w.Flush()
+}
+
+var secretCreateCommand = cli.Command{
+	Name:        "create-secret",
+	Usage:       "store an encrypted secret",
+	Description: "create-secret <name>; the value is prompted for or read from stdin",
+	Action:      secretCreateAction,
+}
+
+func secretCreateAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	var value string
+	if terminal.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Printf("Value: ")
+		value = string(gopass.GetPasswd())
+	} else {
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		value = strings.TrimSuffix(string(b), "\n")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	if _, err := m.CreateSecret(c.Args().First(), value); err != nil {
+		logger.Fatalf("error creating secret: %s", err)
+	}
+	fmt.Printf("created secret %s\n", c.Args().First())
+}
+
+var secretShowCommand = cli.Command{
+	Name:        "secret",
+	Usage:       "print the value of a secret",
+	Description: "secret <name>",
+	Action:      secretShowAction,
+}
+
+func secretShowAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	secret, err := m.Secret(c.Args().First())
+	if err != nil {
+		logger.Fatalf("error getting secret: %s", err)
+	}
+	fmt.Println(secret.Value)
+}
+
+var secretDeleteCommand = cli.Command{
+	Name:        "delete-secret",
+	Usage:       "delete a secret",
+	Description: "delete-secret <name> [<name>]",
+	Action:      secretDeleteAction,
+}
+
+func secretDeleteAction(c *cli.Context) {
+	if len(c.Args()) == 0 {
+		logger.Fatal("you must specify a name")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	for _, name := range c.Args() {
+		if err := m.DeleteSecret(name); err != nil {
+			logger.Fatalf("error deleting secret: %s", err)
+		}
+		fmt.Printf("deleted %s\n", name)
+	}
+}
+
+// parseSecretRefs parses --secret flags
+func parseSecretRefs(values []string) []*shipyard.SecretRef {
+	refs := []*shipyard.SecretRef{}
+	for _, v := range values {
+		ref, err := shipyard.ParseSecretRef(v)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/codegangsta/cli"
 	"github.com/shipyard/shipyard"
@@ -14,6 +16,7 @@ var serviceKeysListCommand = cli.Command{
 	Name:   "service-keys",
 	Usage:  "list service keys",
 	Action: serviceKeysListAction,
+	Flags:  []cli.Flag{outputFlag},
 }
 
 func serviceKeysListAction(c *cli.Context) {
@@ -27,13 +30,28 @@ func serviceKeysListAction(c *cli.Context) {
 		logger.Fatalf("error getting service keys: %s", err)
 		return
 	}
+	if formatted(c, keys) {
+		return
+	}
 	if len(keys) == 0 {
 		return
 	}
 	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
-	fmt.Fprintln(w, "Key\tDescription")
+	fmt.Fprintln(w, "Key\tDescription\tExpires\tPermissions")
 	for _, k := range keys {
-		fmt.Fprintf(w, "%s\t%s\n", k.Key, k.Description)
+		expires := "never"
+		switch {
+		case k.ExpiresAt.IsZero():
+		case k.ExpiresIn <= 0:
+			expires = "expired"
+		default:
+			expires = "in " + (time.Duration(k.ExpiresIn) * time.Second).String()
+		}
+		perms := "all"
+		if len(k.Permissions) > 0 {
+			perms = strings.Join(k.Permissions, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", k.Key, k.Description, expires, perms)
 	}
 	w.Flush()
 }
@@ -48,6 +66,15 @@ var serviceKeyCreateCommand = cli.Command{
 			Value: "",
 			Usage: "service key description",
 		},
+		cli.DurationFlag{
+			Name:  "expires, e",
+			Usage: "key lifetime (e.g. 720h); the key never expires when unset",
+		},
+		cli.StringSliceFlag{
+			Name:  "permission, p",
+			Value: &cli.StringSlice{},
+			Usage: "limit the key to a permission (resource:action); can be repeated",
+		},
 	},
 }
 
@@ -57,7 +84,7 @@ func serviceKeyCreateAction(c *cli.Context) {
 		logger.Fatal(err)
 	}
 	m := client.NewManager(cfg)
-	key, err := m.NewServiceKey(c.String("description"))
+	key, err := m.NewServiceKey(c.String("description"), c.Duration("expires"), c.StringSlice("permission"))
 	if err != nil {
 		logger.Fatalf("error generating service key: %s\n", err)
 	}
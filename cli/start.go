@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard/client"
+)
+
+var startCommand = cli.Command{
+	Name:        "start",
+	Usage:       "start a container",
+	Description: "start <id> [<id>]",
+	Action:      startAction,
+}
+
+func startAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	containers, err := m.Containers()
+	if err != nil {
+		logger.Fatalf("error getting container info: %s", err)
+	}
+	ids := c.Args()
+	if len(ids) == 0 {
+		logger.Fatalf("you must specify at least one id")
+	}
+	for _, cnt := range containers {
+		// this can probably be more efficient
+		for _, i := range ids {
+			if strings.HasPrefix(cnt.ID, i) {
+				if err := m.Start(cnt); err != nil {
+					logger.Fatalf("error starting container: %s\n", err)
+				}
+				fmt.Printf("started %s\n", cnt.ID[:12])
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard/client"
+)
+
+var statsCommand = cli.Command{
+	Name:        "stats",
+	Usage:       "stream container resource usage",
+	Description: "stats <id>",
+	Action:      statsAction,
+}
+
+func statsAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	ids := c.Args()
+	if len(ids) == 0 {
+		logger.Fatal("you must specify an id")
+	}
+	stats, err := m.Stats(ids[0])
+	if err != nil {
+		logger.Fatalf("error getting stats: %s", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "CPU %\tMemory (MB)\tLimit (MB)\tNet RX (KB)\tNet TX (KB)")
+	w.Flush()
+	for s := range stats {
+		fmt.Fprintf(w, "%.2f\t%.2f\t%.2f\t%.1f\t%.1f\n", s.CpuPercent,
+			float64(s.MemoryUsage)/1024/1024, float64(s.MemoryLimit)/1024/1024,
+			float64(s.NetworkRxBytes)/1024, float64(s.NetworkTxBytes)/1024)
+		w.Flush()
+	}
+}
@@ -13,6 +13,13 @@ var stopCommand = cli.Command{
 	Usage:       "stop a container",
 	Description: "stop <id> [<id>]",
 	Action:      stopAction,
+	Flags: []cli.Flag{
+		cli.IntFlag{
+			Name:  "timeout",
+			Value: 10,
+			Usage: "seconds to wait for the container to stop before killing it",
+		},
+	},
 }
 
 func stopAction(c *cli.Context) {
@@ -33,7 +40,7 @@ func stopAction(c *cli.Context) {
 		// this can probably be more efficient
 		for _, i := range ids {
 			if strings.HasPrefix(cnt.ID, i) {
-				if err := m.Stop(cnt); err != nil {
+				if err := m.Stop(cnt, c.Int("timeout")); err != nil {
 					logger.Fatalf("error stopping container: %s\n", err)
 				}
 				fmt.Printf("stopped %s\n", cnt.ID[:12])
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var templatesListCommand = cli.Command{
+	Name:   "templates",
+	Usage:  "list launch templates",
+	Action: templatesListAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func templatesListAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	templates, err := m.Templates()
+	if err != nil {
+		logger.Fatalf("error getting templates: %s", err)
+	}
+	if formatted(c, templates) {
+		return
+	}
+	if len(templates) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Name\tImage\tCpus\tMemory\tUpdated\tDescription")
+	for _, t := range templates {
+		fmt.Fprintf(w, "%s\t%s\t%.2f\t%.2f\t%s\t%s\n", t.Name, t.Image, t.Cpus, t.Memory, t.Updated.Format(time.RFC822), t.Description)
+	}
+	w.Flush()
+}
+
+var templateCreateCommand = cli.Command{
+	Name:        "create-template",
+	Usage:       "create a launch template",
+	Description: "create-template [options] <name>",
+	Action:      templateCreateAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "image",
+			Value: "",
+			Usage: "image name",
+		},
+		cli.StringFlag{
+			Name:  "description",
+			Value: "",
+			Usage: "what the template launches",
+		},
+		cli.Float64Flag{
+			Name:  "cpus",
+			Value: 0.1,
+			Usage: "cpu shares",
+		},
+		cli.Float64Flag{
+			Name:  "memory",
+			Value: 256,
+			Usage: "memory (in MB)",
+		},
+		cli.StringSliceFlag{
+			Name:  "env",
+			Usage: "environment variables (key=value pairs)",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "arg",
+			Usage: "run arguments",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "constraint",
+			Usage: "only run on engines with matching labels, i.e. --constraint region=us-east,ssd=true",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "port",
+			Usage: "expose container ports. usage: --port <proto>/<host-ip>:<host-port>:<container-port> i.e. --port tcp/::8080",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "container-label",
+			Usage: "label the containers for selection, i.e. --container-label tier=web",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringFlag{
+			Name:  "restart",
+			Value: "",
+			Usage: "restart policy of the containers (on-failure, always, on-failure:5, etc.)",
+		},
+		cli.StringFlag{
+			Name:  "pool",
+			Usage: "only run on the engines of an engine pool",
+		},
+	},
+}
+
+func templateCreateAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	if c.String("image") == "" {
+		logger.Fatal("you must specify an image")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	t := &shipyard.LaunchTemplate{
+		Name:          c.Args().First(),
+		Description:   c.String("description"),
+		Image:         c.String("image"),
+		Cpus:          c.Float64("cpus"),
+		Memory:        c.Float64("memory"),
+		Args:          c.StringSlice("arg"),
+		Environment:   parseEnvironmentVariables(c.StringSlice("env")),
+		Ports:         parsePorts(c.StringSlice("port")),
+		Constraints:   c.StringSlice("constraint"),
+		Labels:        parseContainerLabels(c),
+		RestartPolicy: c.String("restart"),
+		Pool:          c.String("pool"),
+	}
+	if _, err := m.CreateTemplate(t); err != nil {
+		logger.Fatalf("error creating template: %s", err)
+	}
+	fmt.Printf("created template %s\n", t.Name)
+}
+
+var templateDeleteCommand = cli.Command{
+	Name:        "delete-template",
+	Usage:       "delete a launch template",
+	Description: "delete-template <name> [<name>]",
+	Action:      templateDeleteAction,
+}
+
+func templateDeleteAction(c *cli.Context) {
+	if len(c.Args()) == 0 {
+		logger.Fatal("you must specify a name")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	for _, name := range c.Args() {
+		if err := m.DeleteTemplate(name); err != nil {
+			logger.Fatalf("error deleting template: %s", err)
+		}
+		fmt.Printf("deleted template %s\n", name)
+	}
+}
+
+var templateRunCommand = cli.Command{
+	Name:        "run-template",
+	Usage:       "run containers from a launch template",
+	Description: "run-template [options] <name>",
+	Action:      templateRunAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "image",
+			Value: "",
+			Usage: "image overriding the template image, i.e. a newer tag",
+		},
+		cli.StringFlag{
+			Name:  "container-name",
+			Value: "",
+			Usage: "container name",
+		},
+		cli.Float64Flag{
+			Name:  "cpus",
+			Value: 0,
+			Usage: "cpu shares overriding the template",
+		},
+		cli.Float64Flag{
+			Name:  "memory",
+			Value: 0,
+			Usage: "memory (in MB) overriding the template",
+		},
+		cli.StringSliceFlag{
+			Name:  "env",
+			Usage: "environment variables added to those of the template (key=value pairs)",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "arg",
+			Usage: "run arguments replacing those of the template",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "constraint",
+			Usage: "constraints added to those of the template",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "container-label",
+			Usage: "labels added to those of the template",
+			Value: &cli.StringSlice{},
+		},
+		cli.IntFlag{
+			Name:  "count",
+			Usage: "number of instances",
+			Value: 1,
+		},
+		cli.BoolFlag{
+			Name:  "pull",
+			Usage: "pull the image from the repository",
+		},
+	},
+}
+
+func templateRunAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a template")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	overrides := &shipyard.TemplateOverrides{
+		Image:         c.String("image"),
+		ContainerName: c.String("container-name"),
+		Cpus:          c.Float64("cpus"),
+		Memory:        c.Float64("memory"),
+		Args:          c.StringSlice("arg"),
+		Environment:   parseEnvironmentVariables(c.StringSlice("env")),
+		Constraints:   c.StringSlice("constraint"),
+		Labels:        parseContainerLabels(c),
+	}
+	containers, err := m.RunTemplate(c.Args().First(), overrides, c.Int("count"), c.Bool("pull"))
+	if err != nil {
+		logger.Fatalf("error running template: %s", err)
+	}
+	for _, c := range containers {
+		fmt.Printf("started %s on %s\n", c.ID[:12], c.Engine.ID)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var topCommand = cli.Command{
+	Name:        "top",
+	Usage:       "show cluster resource usage by engine, or the processes running in a container",
+	Description: "top [<id> [--ps-args <args>]]",
+	Action:      topAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "ps-args",
+			Usage: "arguments passed to ps on the engine, i.e. aux",
+		},
+		cli.DurationFlag{
+			Name:  "interval",
+			Value: 2 * time.Second,
+			Usage: "time between refreshes of the cluster view",
+		},
+		cli.StringFlag{
+			Name:  "sort",
+			Value: "cpu",
+			Usage: "order engines by cpu, memory, containers or engine",
+		},
+	},
+}
+
+func topAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	ids := c.Args()
+	if len(ids) == 0 {
+		clusterTop(c, m)
+		return
+	}
+	processes, err := m.Top(ids[0], c.String("ps-args"))
+	if err != nil {
+		logger.Fatalf("error listing processes: %s", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, strings.Join(processes.Titles, "\t"))
+	for _, p := range processes.Processes {
+		fmt.Fprintln(w, strings.Join(p, "\t"))
+	}
+	w.Flush()
+}
+
+// clusterTop redraws the reservations of each engine every interval until
+// interrupted
+func clusterTop(c *cli.Context, m *client.Manager) {
+	interval := c.Duration("interval")
+	if interval <= 0 {
+		logger.Fatal("interval must be positive")
+	}
+	sortBy := c.String("sort")
+	if _, ok := topSorts[sortBy]; !ok {
+		logger.Fatalf("unknown sort %s; use cpu, memory, containers or engine", sortBy)
+	}
+	for {
+		usage, err := m.Usage()
+		if err != nil {
+			logger.Fatalf("error getting cluster usage: %s", err)
+		}
+		// move to the top left and clear the screen
+		fmt.Print("\033[H\033[2J")
+		writeClusterTop(os.Stdout, usage, sortBy, time.Now())
+		time.Sleep(interval)
+	}
+}
+
+// topSorts order engines with the highest usage first
+var topSorts = map[string]func(a, b *shipyard.EngineUsage) bool{
+	"cpu": func(a, b *shipyard.EngineUsage) bool {
+		return percent(a.ReservedCpus, a.Cpus) > percent(b.ReservedCpus, b.Cpus)
+	},
+	"memory": func(a, b *shipyard.EngineUsage) bool {
+		return percent(a.ReservedMemory, a.Memory) > percent(b.ReservedMemory, b.Memory)
+	},
+	"containers": func(a, b *shipyard.EngineUsage) bool {
+		return a.RunningContainers > b.RunningContainers
+	},
+	"engine": func(a, b *shipyard.EngineUsage) bool {
+		return a.EngineID < b.EngineID
+	},
+}
+
+func writeClusterTop(out io.Writer, usage *shipyard.ClusterUsage, sortBy string, now time.Time) {
+	engines := append([]*shipyard.EngineUsage{}, usage.Engines...)
+	less := topSorts[sortBy]
+	sort.SliceStable(engines, func(i, j int) bool {
+		return less(engines[i], engines[j])
+	})
+	t := usage.Total
+	fmt.Fprintf(out, "shipyard top - %s\n", now.Format("15:04:05"))
+	fmt.Fprintf(out, "Engines: %d  Containers: %d, %d running\n", len(engines), t.Containers, t.RunningContainers)
+	fmt.Fprintf(out, "Cpus: %.2f of %.2f reserved (%.1f%%)  Memory: %.2f of %.2f MB reserved (%.1f%%)\n\n",
+		t.ReservedCpus, t.Cpus, percent(t.ReservedCpus, t.Cpus), t.ReservedMemory, t.Memory, percent(t.ReservedMemory, t.Memory))
+	w := tabwriter.NewWriter(out, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Engine\tCpu %\tCpus\tMemory %\tMemory\tContainers\tRunning")
+	for _, e := range engines {
+		fmt.Fprintf(w, "%s\t%.1f\t%.2f/%.2f\t%.1f\t%.0f/%.0f MB\t%d\t%d\n", e.EngineID,
+			percent(e.ReservedCpus, e.Cpus), e.ReservedCpus, e.Cpus,
+			percent(e.ReservedMemory, e.Memory), e.ReservedMemory, e.Memory,
+			e.Containers, e.RunningContainers)
+	}
+	w.Flush()
+}
+
+func percent(part float64, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return part / total * 100
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shipyard/shipyard"
+)
+
+func TestWriteClusterTop(t *testing.T) {
+	usage := &shipyard.ClusterUsage{
+		Total: shipyard.ResourceUsage{Cpus: 4, ReservedCpus: 2, Memory: 2048, ReservedMemory: 512, Containers: 3, RunningContainers: 2},
+		Engines: []*shipyard.EngineUsage{
+			{EngineID: "a", ResourceUsage: shipyard.ResourceUsage{Cpus: 2, ReservedCpus: 0.5, Memory: 1024, ReservedMemory: 512, Containers: 1, RunningContainers: 1}},
+			{EngineID: "b", ResourceUsage: shipyard.ResourceUsage{Cpus: 2, ReservedCpus: 1.5, Memory: 1024, Containers: 2, RunningContainers: 1}},
+		},
+	}
+	tests := map[string]string{
+		"cpu":    "b",
+		"memory": "a",
+		"engine": "a",
+	}
+	for sortBy, first := range tests {
+		b := &bytes.Buffer{}
+		writeClusterTop(b, usage, sortBy, time.Date(2015, 3, 1, 12, 0, 0, 0, time.UTC))
+		lines := strings.Split(b.String(), "\n")
+		if lines[0] != "shipyard top - 12:00:00" {
+			t.Errorf("unexpected title %q", lines[0])
+		}
+		if !strings.Contains(lines[2], "(50.0%)") || !strings.Contains(lines[2], "(25.0%)") {
+			t.Errorf("unexpected totals %q", lines[2])
+		}
+		if !strings.HasPrefix(lines[5], first+"\t") {
+			t.Errorf("%s: expected engine %s first; received %q", sortBy, first, lines[5])
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard/client"
+)
+
+var unpauseCommand = cli.Command{
+	Name:        "unpause",
+	Usage:       "unpause a container",
+	Description: "unpause <id> [<id>]",
+	Action:      unpauseAction,
+}
+
+func unpauseAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	containers, err := m.Containers()
+	if err != nil {
+		logger.Fatalf("error getting container info: %s", err)
+	}
+	ids := c.Args()
+	if len(ids) == 0 {
+		logger.Fatalf("you must specify at least one id")
+	}
+	for _, cnt := range containers {
+		// this can probably be more efficient
+		for _, i := range ids {
+			if strings.HasPrefix(cnt.ID, i) {
+				if err := m.Unpause(cnt); err != nil {
+					logger.Fatalf("error unpausing container: %s\n", err)
+				}
+				fmt.Printf("unpaused %s\n", cnt.ID[:12])
+			}
+		}
+	}
+}
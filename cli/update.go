@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var renameCommand = cli.Command{
+	Name:        "rename",
+	Usage:       "rename a container",
+	Description: "rename <id> <name>",
+	Action:      renameAction,
+}
+
+func renameAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	args := c.Args()
+	if len(args) != 2 {
+		logger.Fatalf("you must specify a container id and a name")
+	}
+	container, err := m.Container(args[0])
+	if err != nil {
+		logger.Fatalf("error getting container info: %s", err)
+	}
+	if err := m.RenameContainer(container, args[1]); err != nil {
+		logger.Fatalf("error renaming container: %s", err)
+	}
+	fmt.Printf("renamed %s to %s\n", container.ID[:12], args[1])
+}
+
+var updateContainerCommand = cli.Command{
+	Name:        "update-container",
+	Usage:       "update the labels and description of a container",
+	Description: "update-container <id>",
+	Action:      updateContainerAction,
+	Flags: []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "label",
+			Usage: "set a container label, i.e. --label tier=web",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringSliceFlag{
+			Name:  "remove-label",
+			Usage: "remove a container label",
+			Value: &cli.StringSlice{},
+		},
+		cli.StringFlag{
+			Name:  "description",
+			Usage: "container description",
+		},
+	},
+}
+
+func updateContainerAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	args := c.Args()
+	if len(args) == 0 {
+		logger.Fatalf("you must specify a container id")
+	}
+	container, err := m.Container(args[0])
+	if err != nil {
+		logger.Fatalf("error getting container info: %s", err)
+	}
+	patch := &shipyard.ContainerPatch{
+		Labels: map[string]*string{},
+	}
+	for k, v := range shipyard.ParseLabels(c.StringSlice("label")) {
+		value := v
+		patch.Labels[k] = &value
+	}
+	for _, k := range c.StringSlice("remove-label") {
+		patch.Labels[k] = nil
+	}
+	if c.IsSet("description") {
+		description := c.String("description")
+		patch.Description = &description
+	}
+	if _, err := m.UpdateContainer(container, patch); err != nil {
+		logger.Fatalf("error updating container: %s", err)
+	}
+	fmt.Printf("updated %s\n", container.ID[:12])
+}
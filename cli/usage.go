@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard/client"
+)
+
+var usageCommand = cli.Command{
+	Name:   "usage",
+	Usage:  "show resource usage by engine and image",
+	Action: usageAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func usageAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	usage, err := m.Usage()
+	if err != nil {
+		logger.Fatalf("error getting cluster usage: %s", err)
+	}
+
+	if formatted(c, usage) {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Engine\tCpus\tReserved Cpus\tFree Cpus\tMemory\tReserved Memory\tFree Memory\tContainers\tRunning")
+	for _, e := range usage.Engines {
+		fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%.2f\t%.2f MB\t%.2f MB\t%.2f MB\t%d\t%d\n", e.EngineID, e.Cpus, e.ReservedCpus, e.FreeCpus, e.Memory, e.ReservedMemory, e.FreeMemory, e.Containers, e.RunningContainers)
+	}
+	t := usage.Total
+	fmt.Fprintf(w, "total\t%.2f\t%.2f\t%.2f\t%.2f MB\t%.2f MB\t%.2f MB\t%d\t%d\n", t.Cpus, t.ReservedCpus, t.FreeCpus, t.Memory, t.ReservedMemory, t.FreeMemory, t.Containers, t.RunningContainers)
+	w.Flush()
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Image\tContainers\tRunning\tReserved Cpus\tReserved Memory")
+	for _, i := range usage.Images {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.2f\t%.2f MB\n", i.Image, i.Containers, i.RunningContainers, i.ReservedCpus, i.ReservedMemory)
+	}
+	w.Flush()
+}
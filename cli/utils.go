@@ -1,10 +1,7 @@
 package main
 
 import (
-	"encoding/json"
 	"os"
-	"os/user"
-	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -105,27 +102,67 @@ func parsePorts(pairs []string) []*citadel.Port {
 	return ports
 }
 
+// loadConfig returns the context selected by the --context flag or the
+// current context, with the global flags applied
 func loadConfig(c *cli.Context) (*client.ShipyardConfig, error) {
-	usr, err := user.Current()
+	contexts, err := readConfig()
 	if err != nil {
 		return nil, err
 	}
-	path := filepath.Join(usr.HomeDir, CONFIG_PATH)
-	f, err := os.OpenFile(path, os.O_RDONLY, 0600)
+	name := ""
+	if c != nil {
+		name = c.GlobalString("context")
+	}
+	cfg, err := contexts.Context(name)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, ErrConfigDoesNotExist
-		} else {
+		return nil, err
+	}
+	if contexts.CredentialHelper != "" && cfg.ServiceKey == "" && cfg.Token == "" {
+		creds, err := credentialHelper(contexts.CredentialHelper).get(cfg.Url)
+		if err != nil {
 			return nil, err
 		}
+		if creds != nil {
+			cfg.Token = creds.Secret
+		}
 	}
-	defer f.Close()
-	var cfg *client.ShipyardConfig
-	if err := json.NewDecoder(f).Decode(&cfg); err != nil {
-		return nil, ErrInvalidConfig
+	if c != nil {
+		applyGlobalTLSFlags(c, cfg)
+		if ns := c.GlobalString("namespace"); ns != "" {
+			cfg.Namespace = ns
+		}
+		if proxy := c.GlobalString("proxy"); proxy != "" {
+			cfg.Proxy = proxy
+		}
+		if c.GlobalBool("wait-rate-limit") {
+			cfg.WaitOnRateLimit = true
+		}
+		if c.GlobalBool("debug") {
+			cfg.Logger = logger
+		}
+		if c.GlobalBool("check-version") {
+			cfg.CheckVersion = true
+		}
+		cfg.Reauthenticate = func() (string, error) {
+			return reauthenticate(c, cfg)
+		}
+		cfg.Tracer = tracer
+		cfg.Traceparent = os.Getenv("TRACEPARENT")
 	}
-	if c != nil && c.GlobalBool("allow-insecure") {
+	return cfg, nil
+}
+
+func applyGlobalTLSFlags(c *cli.Context, cfg *client.ShipyardConfig) {
+	if c.GlobalBool("allow-insecure") {
 		cfg.AllowInsecure = true
 	}
-	return cfg, nil
+	if p := c.GlobalString("tls-ca-cert"); p != "" {
+		cfg.CACertificatePath = p
+	}
+	if p := c.GlobalString("tls-cert"); p != "" {
+		cfg.SSLCertificatePath = p
+	}
+	if p := c.GlobalString("tls-key"); p != "" {
+		cfg.SSLKeyPath = p
+	}
 }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var versionCommand = cli.Command{
+	Name:   "version",
+	Usage:  "show client and controller versions",
+	Action: versionAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func versionAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	v, err := m.Version()
+	if err != nil {
+		logger.Fatalf("error getting controller version: %s", err)
+	}
+
+	if formatted(c, v) {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(w, "Client Version: %s\n", shipyard.VERSION)
+	fmt.Fprintf(w, "Client API Version: %s\n", shipyard.APIVersion)
+	fmt.Fprintf(w, "Controller Version: %s\n", v.Version)
+	fmt.Fprintf(w, "Controller API Versions: %s\n", strings.Join(v.APIVersions, ", "))
+	w.Flush()
+	if err := shipyard.CheckAPIVersion(v); err != nil {
+		logger.Fatal(err)
+	}
+}
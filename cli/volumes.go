@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var volumesListCommand = cli.Command{
+	Name:   "volumes",
+	Usage:  "list volumes",
+	Action: volumesListAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func volumesListAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	volumes, err := m.Volumes()
+	if err != nil {
+		logger.Fatalf("error getting volumes: %s", err)
+	}
+	if formatted(c, volumes) {
+		return
+	}
+	if len(volumes) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Name\tEngine\tPath\tCreated")
+	for _, v := range volumes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.Name, v.Engine, v.Path, v.Created.Format(time.RFC822))
+	}
+	w.Flush()
+}
+
+var volumeCreateCommand = cli.Command{
+	Name:        "create-volume",
+	Usage:       "create a named volume on an engine",
+	Description: "create-volume <name>",
+	Action:      volumeCreateAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "engine",
+			Usage: "id or name of the engine holding the volume",
+		},
+		cli.StringFlag{
+			Name:  "path",
+			Usage: "directory on the engine host; defaults to " + shipyard.VolumeRoot + "/<name>",
+		},
+	},
+}
+
+func volumeCreateAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a name")
+	}
+	if c.String("engine") == "" {
+		logger.Fatal("you must specify an engine")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	volume, err := m.CreateVolume(&shipyard.Volume{
+		Name:   c.Args().First(),
+		Engine: c.String("engine"),
+		Path:   c.String("path"),
+	})
+	if err != nil {
+		logger.Fatalf("error creating volume: %s", err)
+	}
+	fmt.Printf("created volume %s at %s on engine %s\n", volume.Name, volume.Path, volume.Engine)
+}
+
+var volumeRemoveCommand = cli.Command{
+	Name:        "remove-volume",
+	Usage:       "remove a volume; its data is kept on the engine",
+	Description: "remove-volume <name> [<name>]",
+	Action:      volumeRemoveAction,
+}
+
+func volumeRemoveAction(c *cli.Context) {
+	if len(c.Args()) == 0 {
+		logger.Fatal("you must specify a name")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	for _, name := range c.Args() {
+		if err := m.RemoveVolume(name); err != nil {
+			logger.Fatalf("error removing volume: %s", err)
+		}
+		fmt.Printf("removed %s\n", name)
+	}
+}
+
+// parseVolumeMounts parses --volume flags
+func parseVolumeMounts(values []string) []*shipyard.VolumeMount {
+	mounts := []*shipyard.VolumeMount{}
+	for _, v := range values {
+		mount, err := shipyard.ParseVolumeMount(v)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		mounts = append(mounts, mount)
+	}
+	return mounts
+}
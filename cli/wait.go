@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard/client"
+)
+
+var waitCommand = cli.Command{
+	Name:        "wait",
+	Usage:       "wait for containers to stop and print their exit codes",
+	Description: "wait <id> [<id>]",
+	Action:      waitAction,
+}
+
+func waitAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	ids := c.Args()
+	if len(ids) == 0 {
+		logger.Fatalf("you must specify at least one id")
+	}
+	for _, id := range ids {
+		code, err := m.Wait(id)
+		if err != nil {
+			logger.Fatalf("error waiting for container: %s", err)
+		}
+		fmt.Println(code)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+// watchDelay groups bursts of events, i.e. a scale or drain, into a single
+// refresh
+const watchDelay = 500 * time.Millisecond
+
+var watchFlag = cli.BoolFlag{
+	Name:  "watch, w",
+	Usage: "refresh the list as cluster events arrive",
+}
+
+// watch calls render and calls it again after the events matching filter,
+// until the event stream ends.  Tables are redrawn on a cleared screen;
+// other output formats are appended.
+func watch(c *cli.Context, m *client.Manager, filter *shipyard.EventFilter, render func()) {
+	events, err := m.StreamEvents(filter)
+	if err != nil {
+		logger.Fatalf("error watching events: %s", err)
+	}
+	table := c.String("output") == "" || c.String("output") == "table"
+	for {
+		if table {
+			// move to the top left and clear the screen
+			fmt.Print("\033[H\033[2J")
+		}
+		render()
+		if _, ok := <-events; !ok {
+			logger.Fatal("event stream ended")
+		}
+		timeout := time.After(watchDelay)
+	burst:
+		for {
+			select {
+			case _, ok := <-events:
+				if !ok {
+					logger.Fatal("event stream ended")
+				}
+			case <-timeout:
+				break burst
+			}
+		}
+	}
+}
@@ -13,6 +13,7 @@ var webhookKeysListCommand = cli.Command{
 	Name:   "webhook-keys",
 	Usage:  "list webhook keys",
 	Action: webhookKeysListAction,
+	Flags:  []cli.Flag{outputFlag},
 }
 
 func webhookKeysListAction(c *cli.Context) {
@@ -26,6 +27,9 @@ func webhookKeysListAction(c *cli.Context) {
 		logger.Fatalf("error getting webhook keys: %s", err)
 		return
 	}
+	if formatted(c, keys) {
+		return
+	}
 	if len(keys) == 0 {
 		return
 	}
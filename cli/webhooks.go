@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/codegangsta/cli"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+var webhooksListCommand = cli.Command{
+	Name:   "webhooks",
+	Usage:  "list event webhooks",
+	Action: webhooksListAction,
+	Flags:  []cli.Flag{outputFlag},
+}
+
+func webhooksListAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	hooks, err := m.Webhooks()
+	if err != nil {
+		logger.Fatalf("error getting webhooks: %s", err)
+	}
+	if formatted(c, hooks) {
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "ID\tURL\tEvents")
+	for _, h := range hooks {
+		events := "all"
+		if len(h.EventTypes) > 0 {
+			events = strings.Join(h.EventTypes, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", h.ID, h.URL, events)
+	}
+	w.Flush()
+}
+
+var webhookCreateCommand = cli.Command{
+	Name:        "add-webhook",
+	Usage:       "post cluster events to a url",
+	Description: "add-webhook [options] <url>",
+	Action:      webhookCreateAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "secret, s",
+			Value: "",
+			Usage: "secret used to sign deliveries; generated when empty",
+		},
+		cli.StringSliceFlag{
+			Name:  "event, e",
+			Value: &cli.StringSlice{},
+			Usage: "only deliver events of this type; can be repeated",
+		},
+	},
+}
+
+func webhookCreateAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		logger.Fatal("you must specify a url")
+	}
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	hook, err := m.AddWebhook(&shipyard.Webhook{
+		URL:        c.Args().First(),
+		Secret:     c.String("secret"),
+		EventTypes: c.StringSlice("event"),
+	})
+	if err != nil {
+		logger.Fatalf("error adding webhook: %s", err)
+	}
+	fmt.Printf("added webhook %s\nsecret: %s\n", hook.ID, hook.Secret)
+}
+
+var webhookRemoveCommand = cli.Command{
+	Name:        "remove-webhook",
+	Usage:       "removes an event webhook",
+	Description: "remove-webhook <id> [<id>]",
+	Action:      webhookRemoveAction,
+}
+
+func webhookRemoveAction(c *cli.Context) {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	m := client.NewManager(cfg)
+	for _, id := range c.Args() {
+		if err := m.RemoveWebhook(id); err != nil {
+			logger.Fatalf("error removing webhook: %s", err)
+		}
+		fmt.Printf("removed %s\n", id)
+	}
+}
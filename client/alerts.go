@@ -0,0 +1,57 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/shipyard/shipyard"
+)
+
+// Alerts returns the firing alerts, newest first
+func (m *Manager) Alerts() ([]*shipyard.Alert, error) {
+	alerts := []*shipyard.Alert{}
+	resp, err := m.doRequest(alertsPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&alerts); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+func (m *Manager) AlertRules() ([]*shipyard.AlertRule, error) {
+	rules := []*shipyard.AlertRule{}
+	resp, err := m.doRequest(alertRulesPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// AddAlertRule has the controller evaluate a rule and fire alerts through
+// events, webhooks and notifiers
+func (m *Manager) AddAlertRule(rule *shipyard.AlertRule) (*shipyard.AlertRule, error) {
+	b, err := json.Marshal(rule)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(addAlertRulePath(), "POST", 200, b)
+	if err != nil {
+		return nil, err
+	}
+	var created *shipyard.AlertRule
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (m *Manager) RemoveAlertRule(id string) error {
+	if _, err := m.doRequest(removeAlertRulePath(id), "DELETE", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+func (m *Manager) Applications() ([]*shipyard.Application, error) {
+	apps := []*shipyard.Application{}
+	resp, err := m.doRequest(applicationsPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apps); err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
+func (m *Manager) Application(name string) (*shipyard.Application, error) {
+	var app *shipyard.Application
+	resp, err := m.doRequest(applicationPath(name), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&app); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// ApplicationContainers returns the running and stopped containers of an
+// application
+func (m *Manager) ApplicationContainers(name string) ([]*citadel.Container, error) {
+	containers := []*citadel.Container{}
+	resp, err := m.doRequest(applicationContainersPath(name), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, err
+	}
+	return containers, nil
+}
+
+// CreateApplication stores the application; the controller then keeps its
+// count of containers running
+func (m *Manager) CreateApplication(app *shipyard.Application) (*shipyard.Application, error) {
+	b, err := json.Marshal(app)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(createApplicationPath(), "POST", 201, b)
+	if err != nil {
+		return nil, err
+	}
+	var created *shipyard.Application
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateApplication replaces the spec of an application.  Updates removing
+// env vars, secrets, configs, volumes or ports fail with a conflict unless
+// confirm is set; see DiffApplication.
+func (m *Manager) UpdateApplication(app *shipyard.Application, confirm bool) error {
+	b, err := json.Marshal(app)
+	if err != nil {
+		return err
+	}
+	path := updateApplicationPath(app.Name)
+	if confirm {
+		path += "?confirm=true"
+	}
+	if _, err := m.doRequest(path, "PUT", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DiffApplication returns what updating an application to app would change
+func (m *Manager) DiffApplication(app *shipyard.Application) (*shipyard.ApplicationDiff, error) {
+	b, err := json.Marshal(app)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(diffApplicationPath(app.Name), "POST", 200, b)
+	if err != nil {
+		return nil, err
+	}
+	var d *shipyard.ApplicationDiff
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// RemoveApplication deletes the application and its containers
+func (m *Manager) RemoveApplication(name string) error {
+	if _, err := m.doRequest(removeApplicationPath(name), "DELETE", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Deploy starts a rolling update of an application to image.  The returned
+// deployment is running; poll Deployment for its progress.
+func (m *Manager) Deploy(name string, image string, opts *shipyard.DeployOptions) (*shipyard.Deployment, error) {
+	req := &shipyard.DeployRequest{
+		Image: image,
+	}
+	if opts != nil {
+		req.Options = *opts
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(deployApplicationPath(name), "POST", 202, b)
+	if err != nil {
+		return nil, err
+	}
+	var d *shipyard.Deployment
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Rollback starts redeploying an application with the spec of a previous
+// revision; revision 0 is the last revision that succeeded before the
+// current one.  Poll Deployment for its progress.
+func (m *Manager) Rollback(name string, revision int, opts *shipyard.DeployOptions) (*shipyard.Deployment, error) {
+	req := &shipyard.RollbackRequest{
+		Revision: revision,
+	}
+	if opts != nil {
+		req.Options = *opts
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(rollbackApplicationPath(name), "POST", 202, b)
+	if err != nil {
+		return nil, err
+	}
+	var d *shipyard.Deployment
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Deployments returns the deployments of an application, or of all
+// applications if name is empty, newest first
+func (m *Manager) Deployments(name string) ([]*shipyard.Deployment, error) {
+	deployments := []*shipyard.Deployment{}
+	path := deploymentsPath()
+	if name != "" {
+		path += "?application=" + url.QueryEscape(name)
+	}
+	resp, err := m.doRequest(path, "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&deployments); err != nil {
+		return nil, err
+	}
+	return deployments, nil
+}
+
+func (m *Manager) Deployment(id string) (*shipyard.Deployment, error) {
+	var d *shipyard.Deployment
+	resp, err := m.doRequest(deploymentPath(id), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// PromoteDeployment ends the bake period of a canary deployment, which then
+// updates the rest of the containers
+func (m *Manager) PromoteDeployment(id string) error {
+	if _, err := m.doRequest(promoteDeploymentPath(id), "POST", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeployCompose creates or updates the applications of a compose project
+// from a compose file and returns them in start order
+func (m *Manager) DeployCompose(r io.Reader, project string) ([]*shipyard.Application, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(fmt.Sprintf("%s?project=%s", deployComposePath(), url.QueryEscape(project)), "POST", 201, b)
+	if err != nil {
+		return nil, err
+	}
+	apps := []*shipyard.Application{}
+	if err := json.NewDecoder(resp.Body).Decode(&apps); err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/shipyard/shipyard"
+)
+
+// AuditLog returns recorded api calls, newest first, selected by filter.  A
+// nil filter returns every entry.
+func (m *Manager) AuditLog(filter *shipyard.AuditFilter) ([]*shipyard.AuditEntry, error) {
+	entries := []*shipyard.AuditEntry{}
+	resp, err := m.doRequest(auditPath(filter, ""), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ExportAuditLog returns the audit log selected by filter encoded as format
+// ("json", "csv" or "jsonl").  The caller must close the returned reader.
+func (m *Manager) ExportAuditLog(filter *shipyard.AuditFilter, format string) (io.ReadCloser, error) {
+	resp, err := m.doRequest(auditPath(filter, format), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func auditPath(filter *shipyard.AuditFilter, format string) string {
+	v := url.Values{}
+	if format != "" {
+		v.Set("format", format)
+	}
+	if filter != nil {
+		for name, val := range map[string]string{
+			"username": filter.Username,
+			"method":   filter.Method,
+			"endpoint": filter.Endpoint,
+		} {
+			if val != "" {
+				v.Set(name, val)
+			}
+		}
+		if !filter.Since.IsZero() {
+			v.Set("since", filter.Since.Format(time.RFC3339))
+		}
+		if !filter.Until.IsZero() {
+			v.Set("until", filter.Until.Format(time.RFC3339))
+		}
+		if filter.Limit > 0 {
+			v.Set("limit", strconv.Itoa(filter.Limit))
+		}
+		if filter.Offset > 0 {
+			v.Set("offset", strconv.Itoa(filter.Offset))
+		}
+	}
+	path := auditLogPath()
+	if len(v) > 0 {
+		path += "?" + v.Encode()
+	}
+	return path
+}
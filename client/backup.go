@@ -0,0 +1,31 @@
+package client
+
+import (
+	"io"
+)
+
+// Backup returns a snapshot of the controller state; see
+// shipyard.BackupHeader
+func (m *Manager) Backup() (io.ReadCloser, error) {
+	resp, err := m.doRequest(backupPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Restore replaces the controller state with a backup.  The backup is
+// streamed so the request is not retried.
+func (m *Manager) Restore(r io.Reader) error {
+	req, err := m.newRequest(restoreBackupPath(), "POST", r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	resp, err := m.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp, 204)
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// maxCachedResponses bounds the number of paths a manager caches
+const maxCachedResponses = 256
+
+// responseCache keeps GET responses the controller tagged with an ETag.
+// Requests for a cached path ask the controller to only send the body when
+// it changed; a 304 Not Modified is answered from the cache.
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedResponse
+}
+
+type cachedResponse struct {
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{
+		entries: map[string]*cachedResponse{},
+	}
+}
+
+// cacheKey separates the responses of namespaces and accounts sharing a
+// manager config
+func (m *Manager) cacheKey(path string) string {
+	return m.config.Namespace + "\x00" + m.config.Username + "\x00" + path
+}
+
+// prepare asks for the body of key only when it changed
+func (c *responseCache) prepare(key string, req *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		req.Header.Set("If-None-Match", e.etag)
+	}
+}
+
+// update stores a tagged response and replaces a 304 with the cached one
+func (c *responseCache) update(key string, resp *http.Response) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		e, ok := c.entries[key]
+		if !ok {
+			return resp, nil
+		}
+		resp.Body.Close()
+		cached := *resp
+		cached.StatusCode = http.StatusOK
+		cached.Status = "200 OK"
+		cached.Header = e.header.Clone()
+		cached.Body = ioutil.NopCloser(bytes.NewReader(e.body))
+		cached.ContentLength = int64(len(e.body))
+		return &cached, nil
+	case http.StatusOK:
+		tag := resp.Header.Get("ETag")
+		if tag == "" {
+			delete(c.entries, key)
+			return resp, nil
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := c.entries[key]; !ok && len(c.entries) >= maxCachedResponses {
+			for k := range c.entries {
+				delete(c.entries, k)
+				break
+			}
+		}
+		c.entries[key] = &cachedResponse{
+			etag:   tag,
+			header: resp.Header.Clone(),
+			body:   body,
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return resp, nil
+}
@@ -1,16 +1,21 @@
 package client
 
+//go:generate sh -c "go run ../controller -spec | go run genroutes.go > routes_gen.go"
+
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/citadel/citadel"
 	"github.com/shipyard/shipyard"
@@ -21,32 +26,114 @@ type (
 	Manager struct {
 		baseUrl string
 		config  *ShipyardConfig
+		client  *http.Client
+		ctx     context.Context
+		err     error
+		// requestHooks and responseHooks are called around every
+		// request; see OnRequest
+		requestHooks  []RequestHook
+		responseHooks []ResponseHook
+		// idempotencyKey is sent instead of a generated key; see
+		// WithIdempotencyKey
+		idempotencyKey string
+		// cache is set when the config enables caching
+		cache *responseCache
 	}
 )
 
 func NewManager(cfg *ShipyardConfig) *Manager {
 	m := &Manager{
 		config: cfg,
+		client: cfg.HTTPClient,
+		ctx:    context.Background(),
+	}
+	if m.client == nil {
+		// a bad tls configuration is reported by the first request
+		m.client, m.err = newHTTPClient(cfg)
+	}
+	if cfg.Cache {
+		m.cache = newResponseCache()
+	}
+	if cfg.CheckVersion && m.err == nil {
+		// like a bad tls configuration, an incompatible controller is
+		// reported by the first request; other errors are left to the
+		// requests themselves
+		if err := m.CheckVersion(); errors.Is(err, shipyard.ErrIncompatibleAPIVersion) {
+			m.err = err
+		}
 	}
 	return m
 }
 
+// newHTTPClient builds the client used when the config does not supply one
+func newHTTPClient(cfg *ShipyardConfig) (*http.Client, error) {
+	transport := &http.Transport{
+		DialContext: cfg.DialContext,
+	}
+	proxy, err := cfg.ProxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	transport.Proxy = proxy
+	if path, ok := shipyard.UnixSocketPath(cfg.Url); ok {
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		}
+		transport.Proxy = nil
+	}
+	if strings.Index(cfg.Url, "https") != -1 {
+		tlsConfig, err := cfg.TLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// WithContext returns a shallow copy of the manager whose requests are
+// bound to ctx; cancelling ctx aborts any in-flight call made through it.
+func (m *Manager) WithContext(ctx context.Context) *Manager {
+	if ctx == nil {
+		panic("nil context")
+	}
+	m2 := *m
+	m2.ctx = ctx
+	return &m2
+}
+
+// WithIdempotencyKey returns a shallow copy of the manager sending key
+// with its POST, PUT and PATCH requests, i.e. to resend a request of a
+// previous run of a deploy pipeline without repeating it.  Managers
+// otherwise generate a key per request when retries are enabled, sent on
+// each of its attempts.
+func (m *Manager) WithIdempotencyKey(key string) *Manager {
+	m2 := *m
+	m2.idempotencyKey = key
+	return &m2
+}
+
+// Context returns the context requests are bound to.
+func (m *Manager) Context() context.Context {
+	return m.ctx
+}
+
 func (m *Manager) buildUrl(path string) string {
+	if _, ok := shipyard.UnixSocketPath(m.config.Url); ok && m.config.HTTPClient == nil {
+		// the host is ignored by the unix socket dialer; a custom client
+		// gets the url as configured
+		return "http://unix" + path
+	}
 	return fmt.Sprintf("%s%s", m.config.Url, path)
 }
 
-func (m *Manager) doRequest(path string, method string, expectedStatus int, b []byte) (*http.Response, error) {
-	url := m.buildUrl(path)
-	buf := bytes.NewBuffer(b)
-	transport := &http.Transport{}
-	if strings.Index(url, "https") != -1 {
-		if m.config.AllowInsecure {
-			transport.TLSClientConfig = &tls.Config{
-				InsecureSkipVerify: true,
-			}
-		}
+func (m *Manager) newRequest(path string, method string, body io.Reader) (*http.Request, error) {
+	if m.err != nil {
+		return nil, m.err
 	}
-	req, err := http.NewRequest(method, url, buf)
+	url := m.buildUrl(path)
+	req, err := http.NewRequestWithContext(m.ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
@@ -55,31 +142,156 @@ func (m *Manager) doRequest(path string, method string, expectedStatus int, b []
 	} else {
 		req.Header.Add("X-Access-Token", fmt.Sprintf("%s:%s", m.config.Username, m.config.Token))
 	}
-	req.Header.Set("User-Agent", "shipyard-cli")
-
-	client := &http.Client{Transport: transport}
-	//resp, err := http.DefaultClient.Do(req)
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+	if m.config.Namespace != "" {
+		req.Header.Set(shipyard.NamespaceHeader, m.config.Namespace)
 	}
-	if resp.StatusCode == 401 {
-		return resp, shipyard.ErrUnauthorized
+	req.Header.Set("User-Agent", "shipyard-cli")
+	if !m.config.DisableCompression {
+		req.Header.Set("Accept-Encoding", "gzip")
 	}
+	return req, nil
+}
+
+func (m *Manager) doRequest(path string, method string, expectedStatus int, b []byte) (*http.Response, error) {
+	key := m.idempotencyKey
+	if key == "" && !m.config.DisableRetry {
+		// the key lets the controller recognize retries of requests
+		// that are not idempotent by themselves
+		key = shipyard.NewIdempotencyKey()
+	}
+	if !keyedMethod(method) {
+		key = ""
+	}
+	attempts := m.maxAttempts(method, key != "")
+	waited := time.Duration(0)
+	reauthenticated := false
+	for attempt := 1; ; attempt++ {
+		req, err := m.newRequest(path, method, bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		if key != "" {
+			req.Header.Set(shipyard.IdempotencyKeyHeader, key)
+		}
+		if m.cache != nil && method == "GET" {
+			m.cache.prepare(m.cacheKey(path), req)
+		}
 
-	if resp.StatusCode != expectedStatus {
-		c, err := ioutil.ReadAll(resp.Body)
+		resp, err := m.do(req)
+		if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+			if d, ok := m.rateLimitWait(resp, waited); ok {
+				m.logf("shipyard: rate limited; retrying %s %s in %s", method, path, d)
+				if err := m.wait(d); err != nil {
+					return nil, err
+				}
+				// the controller did not process the request so it is
+				// not an attempt
+				waited += d
+				attempt--
+				continue
+			}
+		}
+		if err == nil && resp.StatusCode == http.StatusUnauthorized && !reauthenticated && m.canReauthenticate(path) {
+			resp.Body.Close()
+			token, err := m.config.Reauthenticate()
+			if err != nil {
+				return nil, err
+			}
+			m.config.Token = token
+			m.logf("shipyard: reauthenticated %s; retrying %s %s", m.config.Username, method, path)
+			reauthenticated = true
+			attempt--
+			continue
+		}
+		if attempt < attempts && m.shouldRetry(resp, err) {
+			backoff := m.retryPolicy().backoff(attempt)
+			m.logf("shipyard: retrying %s %s in %s (attempt %d of %d)", method, path, backoff, attempt+1, attempts)
+			if err := m.wait(backoff); err != nil {
+				return nil, err
+			}
+			continue
+		}
 		if err != nil {
 			return nil, err
 		}
-		return resp, errors.New(string(c))
+		if m.cache != nil && method == "GET" {
+			if resp, err = m.cache.update(m.cacheKey(path), resp); err != nil {
+				return nil, err
+			}
+		}
+		if err := checkResponse(resp, expectedStatus); err != nil {
+			return resp, err
+		}
+		return resp, nil
+	}
+}
+
+// canReauthenticate reports whether a request rejected with 401 may be
+// sent again with a new token.  Logins fail with 401 on bad credentials.
+func (m *Manager) canReauthenticate(path string) bool {
+	return m.config.Reauthenticate != nil && m.config.ServiceKey == "" && m.config.Token != "" && path != loginPath()
+}
+
+// checkResponse returns a *shipyard.APIError when resp does not have the
+// expected status
+func checkResponse(resp *http.Response, expectedStatus int) error {
+	if resp.StatusCode == expectedStatus {
+		return nil
+	}
+	c, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	apiErr := &shipyard.APIError{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(c)),
+		RetryAfter: retryAfter(resp),
+	}
+	if resp.Request != nil {
+		apiErr.Method = resp.Request.Method
+		apiErr.Endpoint = resp.Request.URL.Path
 	}
-	return resp, nil
+	return apiErr
 }
 
 func (m *Manager) Containers() ([]*citadel.Container, error) {
+	return m.QueryContainers(nil)
+}
+
+// ContainersByLabel returns the containers whose labels match a selector
+// such as "tier=web,env!=dev"
+func (m *Manager) ContainersByLabel(selector string) ([]*citadel.Container, error) {
+	s, err := shipyard.ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return m.QueryContainers(&shipyard.ContainerQuery{Selector: s})
+}
+
+// QueryContainers returns a page of the containers selected by query; use
+// the limit and offset to fetch large clusters incrementally
+func (m *Manager) QueryContainers(query *shipyard.ContainerQuery) ([]*citadel.Container, error) {
+	path := containersPath()
+	if query != nil {
+		v := url.Values{}
+		for name, val := range map[string]string{
+			"image":    query.Image,
+			"engine":   query.Engine,
+			"state":    query.State,
+			"label":    query.Label,
+			"selector": query.Selector.String(),
+		} {
+			if val != "" {
+				v.Set(name, val)
+			}
+		}
+		setPageValues(v, query.Limit, query.Offset)
+		if len(v) > 0 {
+			path += "?" + v.Encode()
+		}
+	}
 	containers := []*citadel.Container{}
-	resp, err := m.doRequest("/api/containers", "GET", 200, nil)
+	resp, err := m.doRequest(path, "GET", 200, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -91,7 +303,7 @@ func (m *Manager) Containers() ([]*citadel.Container, error) {
 
 func (m *Manager) Container(id string) (*citadel.Container, error) {
 	container := &citadel.Container{}
-	resp, err := m.doRequest(fmt.Sprintf("/api/containers/%s", id), "GET", 200, nil)
+	resp, err := m.doRequest(inspectContainerPath(id), "GET", 200, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -107,7 +319,7 @@ func (m *Manager) Run(image *citadel.Image, count int, pull bool) ([]*citadel.Co
 		return nil, err
 	}
 	var containers []*citadel.Container
-	resp, err := m.doRequest(fmt.Sprintf("/api/containers?count=%d&pull=%v", count, pull), "POST", 201, b)
+	resp, err := m.doRequest(fmt.Sprintf("%s?count=%d&pull=%v", runPath(), count, pull), "POST", 201, b)
 	if err != nil {
 		return nil, err
 	}
@@ -117,84 +329,240 @@ func (m *Manager) Run(image *citadel.Image, count int, pull bool) ([]*citadel.Co
 	return containers, nil
 }
 
+// Batch applies the operations on the controller and returns the outcome
+// of every container
+func (m *Manager) Batch(ops []*shipyard.BatchOperation) (*shipyard.BatchResult, error) {
+	b, err := json.Marshal(ops)
+	if err != nil {
+		return nil, err
+	}
+	var res *shipyard.BatchResult
+	resp, err := m.doRequest(batchContainersPath(), "POST", 200, b)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// PreviewRun returns the engines count containers of image would be placed
+// on without starting them
+func (m *Manager) PreviewRun(image *citadel.Image, count int) (*shipyard.RunPreview, error) {
+	b, err := json.Marshal(image)
+	if err != nil {
+		return nil, err
+	}
+	var preview *shipyard.RunPreview
+	resp, err := m.doRequest(fmt.Sprintf("%s?count=%d&dryRun=true", runPath(), count), "POST", 200, b)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&preview); err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
 func (m *Manager) Destroy(container *citadel.Container) error {
 	b, err := json.Marshal(container)
 	if err != nil {
 		return err
 	}
-	if _, err := m.doRequest(fmt.Sprintf("/api/containers/%s", container.ID), "DELETE", 204, b); err != nil {
+	if _, err := m.doRequest(destroyPath(container.ID), "DELETE", 204, b); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (m *Manager) Stop(container *citadel.Container) error {
-	b, err := json.Marshal(container)
+// UpdateContainer changes the labels and description of a container
+func (m *Manager) UpdateContainer(container *citadel.Container, patch *shipyard.ContainerPatch) (*citadel.Container, error) {
+	b, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(updateContainerPath(container.ID), "PATCH", 200, b)
+	if err != nil {
+		return nil, err
+	}
+	var updated *citadel.Container
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (m *Manager) RenameContainer(container *citadel.Container, name string) error {
+	v := url.Values{}
+	v.Add("name", name)
+	if _, err := m.doRequest(fmt.Sprintf("%s?%s", renameContainerPath(container.ID), v.Encode()), "POST", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Commit creates an image from a container
+func (m *Manager) Commit(container *citadel.Container, opts *shipyard.CommitOptions) (*shipyard.Image, error) {
+	b, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(commitContainerPath(container.ID), "POST", 201, b)
 	if err != nil {
+		return nil, err
+	}
+	var image *shipyard.Image
+	if err := json.NewDecoder(resp.Body).Decode(&image); err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+// Wait blocks until a container stops and returns its exit code.  Use
+// WithContext to give up waiting.
+func (m *Manager) Wait(containerID string) (int, error) {
+	resp, err := m.doRequest(waitContainerPath(containerID), "GET", 200, nil)
+	if err != nil {
+		return 0, err
+	}
+	var exit *shipyard.ContainerExit
+	if err := json.NewDecoder(resp.Body).Decode(&exit); err != nil {
+		return 0, err
+	}
+	return exit.ExitCode, nil
+}
+
+func (m *Manager) Start(container *citadel.Container) error {
+	if _, err := m.doRequest(startContainerPath(container.ID), "GET", 204, nil); err != nil {
 		return err
 	}
-	if _, err := m.doRequest(fmt.Sprintf("/api/containers/%s/stop", container.ID), "GET", 204, b); err != nil {
+	return nil
+}
+
+func (m *Manager) Pause(container *citadel.Container) error {
+	if _, err := m.doRequest(pauseContainerPath(container.ID), "GET", 204, nil); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (m *Manager) Restart(container *citadel.Container) error {
+func (m *Manager) Unpause(container *citadel.Container) error {
+	if _, err := m.doRequest(unpauseContainerPath(container.ID), "GET", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Stop stops a container, killing it if it has not exited after timeout seconds
+func (m *Manager) Stop(container *citadel.Container, timeout int) error {
 	b, err := json.Marshal(container)
 	if err != nil {
 		return err
 	}
-	if _, err := m.doRequest(fmt.Sprintf("/api/containers/%s/restart", container.ID), "GET", 204, b); err != nil {
+	if _, err := m.doRequest(fmt.Sprintf("%s?timeout=%d", stopContainerPath(container.ID), timeout), "GET", 204, b); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (m *Manager) Scale(container *citadel.Container, count int) error {
+func (m *Manager) Restart(container *citadel.Container, timeout int) error {
 	b, err := json.Marshal(container)
 	if err != nil {
 		return err
 	}
-	if _, err := m.doRequest(fmt.Sprintf("/api/containers/%s/scale?count=%d", container.ID, count), "GET", 204, b); err != nil {
+	if _, err := m.doRequest(fmt.Sprintf("%s?timeout=%d", restartContainerPath(container.ID), timeout), "GET", 204, b); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (m *Manager) Logs(container *citadel.Container, stdout bool, stderr bool) (io.ReadCloser, error) {
+// Scale launches or destroys containers so that desiredCount instances of
+// the image are running in the cluster
+func (m *Manager) Scale(image *citadel.Image, desiredCount int) error {
+	b, err := json.Marshal(image)
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(fmt.Sprintf("%s?count=%d", scaleImagePath(), desiredCount), "POST", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Logs streams the stdout and stderr of a container; when follow is set the
+// stream stays open until the container exits or the context is cancelled.
+// A tail greater than zero limits output to the last lines of the log.
+func (m *Manager) Logs(containerID string, follow bool, tail int) (io.ReadCloser, error) {
 	v := url.Values{}
-	if stdout {
-		v.Add("stdout", "1")
+	v.Add("stdout", "1")
+	v.Add("stderr", "1")
+	v.Add("follow", strconv.FormatBool(follow))
+	if tail > 0 {
+		v.Add("tail", strconv.Itoa(tail))
 	}
-	if stderr {
-		v.Add("stderr", "1")
+	resp, err := m.doRequest(fmt.Sprintf("%s?%s", containerLogsPath(containerID), v.Encode()), "GET", 200, nil)
+	if err != nil {
+		return nil, err
 	}
+	return resp.Body, nil
+}
 
-	path := fmt.Sprintf("/api/containers/%s/logs?%s", container.ID, v.Encode())
-	url := m.buildUrl(path)
-	req, err := http.NewRequest("GET", url, nil)
+// CopyFrom returns a tar archive of a file or directory in a container
+func (m *Manager) CopyFrom(containerID string, path string) (io.ReadCloser, error) {
+	v := url.Values{}
+	v.Set("path", path)
+	resp, err := m.doRequest(fmt.Sprintf("%s?%s", copyFromContainerPath(containerID), v.Encode()), "GET", 200, nil)
 	if err != nil {
 		return nil, err
 	}
+	return resp.Body, nil
+}
 
-	if m.config.ServiceKey != "" {
-		req.Header.Add("X-Service-Key", m.config.ServiceKey)
-	} else {
-		req.Header.Add("X-Access-Token", fmt.Sprintf("%s:%s", m.config.Username, m.config.Token))
+// CopyTo extracts a tar archive into a directory of a container.  The
+// archive is streamed so the request is not retried.
+func (m *Manager) CopyTo(containerID string, path string, archive io.Reader) error {
+	v := url.Values{}
+	v.Set("path", path)
+	req, err := m.newRequest(fmt.Sprintf("%s?%s", copyToContainerPath(containerID), v.Encode()), "PUT", archive)
+	if err != nil {
+		return err
 	}
-	req.Header.Set("User-Agent", "shipyard-cli")
+	req.Header.Set("Content-Type", "application/x-tar")
+	resp, err := m.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp, 204)
+}
 
-	resp, err := http.DefaultClient.Do(req)
+// Diff returns the paths of a container filesystem changed since it was
+// created
+func (m *Manager) Diff(containerID string) ([]*shipyard.ContainerChange, error) {
+	resp, err := m.doRequest(containerChangesPath(containerID), "GET", 200, nil)
 	if err != nil {
 		return nil, err
 	}
+	var changes []*shipyard.ContainerChange
+	if err := json.NewDecoder(resp.Body).Decode(&changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
 
+// Export returns a tar archive of the filesystem of a container
+func (m *Manager) Export(containerID string) (io.ReadCloser, error) {
+	resp, err := m.doRequest(exportContainerPath(containerID), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
 	return resp.Body, nil
 }
 
 func (m *Manager) Engines() ([]*shipyard.Engine, error) {
 	engines := []*shipyard.Engine{}
-	resp, err := m.doRequest("/api/engines", "GET", 200, nil)
+	resp, err := m.doRequest(enginesPath(), "GET", 200, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -209,14 +577,14 @@ func (m *Manager) AddEngine(engine *shipyard.Engine) error {
 	if err != nil {
 		return err
 	}
-	if _, err := m.doRequest("/api/engines", "POST", 201, b); err != nil {
+	if _, err := m.doRequest(addEnginePath(), "POST", 201, b); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (m *Manager) RemoveEngine(engine *shipyard.Engine) error {
-	if _, err := m.doRequest(fmt.Sprintf("/api/engines/%s", engine.ID), "DELETE", 204, nil); err != nil {
+	if _, err := m.doRequest(removeEnginePath(engine.ID), "DELETE", 204, nil); err != nil {
 		return err
 	}
 	return nil
@@ -224,7 +592,7 @@ func (m *Manager) RemoveEngine(engine *shipyard.Engine) error {
 
 func (m *Manager) GetContainer(id string) (*citadel.Container, error) {
 	var container *citadel.Container
-	resp, err := m.doRequest(fmt.Sprintf("/api/containers/%s", id), "GET", 200, nil)
+	resp, err := m.doRequest(inspectContainerPath(id), "GET", 200, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -236,7 +604,7 @@ func (m *Manager) GetContainer(id string) (*citadel.Container, error) {
 
 func (m *Manager) GetEngine(id string) (*shipyard.Engine, error) {
 	var engine *shipyard.Engine
-	resp, err := m.doRequest(fmt.Sprintf("/api/engines/%s", id), "GET", 200, nil)
+	resp, err := m.doRequest(inspectEnginePath(id), "GET", 200, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -246,9 +614,149 @@ func (m *Manager) GetEngine(id string) (*shipyard.Engine, error) {
 	return engine, nil
 }
 
+// EngineHealth returns the result of the latest health check of an engine
+func (m *Manager) EngineHealth(id string) (*shipyard.Health, error) {
+	var health *shipyard.Health
+	resp, err := m.doRequest(engineHealthPath(id), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return nil, err
+	}
+	return health, nil
+}
+
+// UpdateEngine changes the resources, labels, address or certificates of
+// an existing engine in place
+func (m *Manager) UpdateEngine(engine *shipyard.Engine) error {
+	b, err := json.Marshal(engine)
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(updateEnginePath(engine.ID), "PUT", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateEngineLabels replaces the labels of an engine.  Labels are plain
+// names or key=value pairs matched by image constraints.
+func (m *Manager) UpdateEngineLabels(id string, labels []string) error {
+	b, err := json.Marshal(labels)
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(updateEngineLabelsPath(id), "PUT", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+// EngineCertificates describes the certificates the controller uses for
+// the docker api of an engine
+func (m *Manager) EngineCertificates(id string) (*shipyard.EngineCertificateInfo, error) {
+	var info *shipyard.EngineCertificateInfo
+	resp, err := m.doRequest(engineCertificatesPath(id), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// SetEngineCertificates replaces the certificates of an engine; empty
+// certificates remove them
+func (m *Manager) SetEngineCertificates(id string, certs *shipyard.EngineCertificates) error {
+	b, err := json.Marshal(certs)
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(setEngineCertificatesPath(id), "PUT", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+// CordonEngine excludes an engine from new container placements
+func (m *Manager) CordonEngine(id string) error {
+	if _, err := m.doRequest(cordonEnginePath(id), "GET", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) UncordonEngine(id string) error {
+	if _, err := m.doRequest(uncordonEnginePath(id), "GET", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DrainEngine cordons an engine and moves its running containers to other
+// engines
+func (m *Manager) DrainEngine(id string) error {
+	if _, err := m.doRequest(drainEnginePath(id), "GET", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Version returns the version of the controller and the api versions it
+// supports
+func (m *Manager) Version() (*shipyard.VersionInfo, error) {
+	var v *shipyard.VersionInfo
+	resp, err := m.doRequest(versionInfoPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// CheckVersion returns shipyard.ErrIncompatibleAPIVersion when the
+// controller does not support the api version of this client.  Controllers
+// predating the version endpoint are incompatible.
+func (m *Manager) CheckVersion() error {
+	v, err := m.Version()
+	if err != nil {
+		if errors.Is(err, shipyard.ErrNotFound) {
+			return fmt.Errorf("%w: controller does not report its api version", shipyard.ErrIncompatibleAPIVersion)
+		}
+		return err
+	}
+	return shipyard.CheckAPIVersion(v)
+}
+
+// Ping checks that the controller is reachable and accepts the credentials
+// of the config and returns the round trip time.  It makes a single attempt:
+// connection failures wrap ErrUnreachable and rejected credentials
+// shipyard.ErrUnauthorized.
+func (m *Manager) Ping() (time.Duration, error) {
+	req, err := m.newRequest(pingPath(), "GET", nil)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	resp, err := m.do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrUnreachable, err)
+	}
+	latency := time.Since(start)
+	defer resp.Body.Close()
+	if err := checkResponse(resp, http.StatusNoContent); err != nil {
+		return 0, err
+	}
+	return latency, nil
+}
+
 func (m *Manager) Info() (*shipyard.ClusterInfo, error) {
 	var info *shipyard.ClusterInfo
-	resp, err := m.doRequest("/api/cluster/info", "GET", 200, nil)
+	resp, err := m.doRequest(clusterInfoPath(), "GET", 200, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -258,21 +766,56 @@ func (m *Manager) Info() (*shipyard.ClusterInfo, error) {
 	return info, nil
 }
 
-func (m *Manager) Events() ([]*shipyard.Event, error) {
-	events := []*shipyard.Event{}
-	resp, err := m.doRequest("/api/events", "GET", 200, nil)
+// Usage returns the resource usage of each engine and image
+func (m *Manager) Usage() (*shipyard.ClusterUsage, error) {
+	var usage *shipyard.ClusterUsage
+	resp, err := m.doRequest(clusterUsagePath(), "GET", 200, nil)
 	if err != nil {
 		return nil, err
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&events); err != nil {
+	if err := json.NewDecoder(resp.Body).Decode(&usage); err != nil {
 		return nil, err
 	}
-	return events, nil
+	return usage, nil
+}
+
+// Rebalance moves containers from over utilized engines to under utilized
+// ones and returns the moves; dry runs only plan them
+func (m *Manager) Rebalance(opts *shipyard.RebalanceOptions) ([]*shipyard.RebalanceMove, error) {
+	b, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	var moves []*shipyard.RebalanceMove
+	resp, err := m.doRequest(rebalancePath(), "POST", 200, b)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&moves); err != nil {
+		return nil, err
+	}
+	return moves, nil
 }
 
 func (m *Manager) Accounts() ([]*shipyard.Account, error) {
+	return m.QueryAccounts(nil)
+}
+
+// QueryAccounts returns a page of the accounts selected by query
+func (m *Manager) QueryAccounts(query *shipyard.AccountQuery) ([]*shipyard.Account, error) {
+	path := accountsPath()
+	if query != nil {
+		v := url.Values{}
+		if query.Role != "" {
+			v.Set("role", query.Role)
+		}
+		setPageValues(v, query.Limit, query.Offset)
+		if len(v) > 0 {
+			path += "?" + v.Encode()
+		}
+	}
 	accounts := []*shipyard.Account{}
-	resp, err := m.doRequest("/api/accounts", "GET", 200, nil)
+	resp, err := m.doRequest(path, "GET", 200, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -282,9 +825,19 @@ func (m *Manager) Accounts() ([]*shipyard.Account, error) {
 	return accounts, nil
 }
 
+// setPageValues adds the limit and offset query parameters of paged lists
+func setPageValues(v url.Values, limit, offset int) {
+	if limit > 0 {
+		v.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		v.Set("offset", strconv.Itoa(offset))
+	}
+}
+
 func (m *Manager) Roles() ([]*shipyard.Role, error) {
 	roles := []*shipyard.Role{}
-	resp, err := m.doRequest("/api/roles", "GET", 200, nil)
+	resp, err := m.doRequest(rolesPath(), "GET", 200, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -296,7 +849,7 @@ func (m *Manager) Roles() ([]*shipyard.Role, error) {
 
 func (m *Manager) Role(name string) (*shipyard.Role, error) {
 	role := &shipyard.Role{}
-	resp, err := m.doRequest(fmt.Sprintf("/api/roles/%s", name), "GET", 200, nil)
+	resp, err := m.doRequest(rolePath(name), "GET", 200, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -306,12 +859,86 @@ func (m *Manager) Role(name string) (*shipyard.Role, error) {
 	return role, nil
 }
 
+// CreateRole adds a role with a set of resource:action permissions
+func (m *Manager) CreateRole(role *shipyard.Role) error {
+	b, err := json.Marshal(role)
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(addRolePath(), "POST", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateRole replaces the permissions of an existing role
+func (m *Manager) UpdateRole(role *shipyard.Role) error {
+	b, err := json.Marshal(role)
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(updateRolePath(), "PUT", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteRole removes the role with the name of role
+func (m *Manager) DeleteRole(role *shipyard.Role) error {
+	b, err := json.Marshal(role)
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(deleteRolePath(), "DELETE", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (m *Manager) AddAccount(account *shipyard.Account) error {
 	b, err := json.Marshal(account)
 	if err != nil {
 		return err
 	}
-	if _, err := m.doRequest("/api/accounts", "POST", 204, b); err != nil {
+	if _, err := m.doRequest(addAccountPath(), "POST", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateAccount changes the role, name, email and, if set, the password of
+// an existing account without invalidating its tokens
+func (m *Manager) UpdateAccount(account *shipyard.Account) error {
+	b, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(updateAccountPath(), "PUT", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetAccountRole assigns an existing role to an account
+func (m *Manager) SetAccountRole(username string, role string) error {
+	b, err := json.Marshal(&shipyard.Role{Name: role})
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(setAccountRolePath(username), "PUT", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetAccountNamespaceRole grants an account a role in a namespace; an empty
+// role revokes it
+func (m *Manager) SetAccountNamespaceRole(username string, namespace string, role string) error {
+	b, err := json.Marshal(&shipyard.Role{Name: role})
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(setAccountNamespaceRolePath(username, namespace), "PUT", 204, b); err != nil {
 		return err
 	}
 	return nil
@@ -322,21 +949,45 @@ func (m *Manager) DeleteAccount(account *shipyard.Account) error {
 	if err != nil {
 		return err
 	}
-	if _, err := m.doRequest("/api/accounts", "DELETE", 204, b); err != nil {
+	if _, err := m.doRequest(deleteAccountPath(), "DELETE", 204, b); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (m *Manager) Login(username, password string) (*shipyard.AuthToken, error) {
+	return m.LoginWithOTP(username, password, "")
+}
+
+// LoginWithOTP logs in to an account with two-factor auth enabled.  Login
+// fails with an error matching shipyard.ErrOTPRequired when otp is needed
+// and shipyard.ErrInvalidOTP when it is wrong.
+func (m *Manager) LoginWithOTP(username, password, otp string) (*shipyard.AuthToken, error) {
 	creds := map[string]string{}
 	creds["username"] = username
 	creds["password"] = password
+	if otp != "" {
+		creds["otp"] = otp
+	}
 	b, err := json.Marshal(creds)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := m.doRequest("/auth/login", "POST", 200, b)
+	resp, err := m.doRequest(loginPath(), "POST", 200, b)
+	if err != nil {
+		return nil, err
+	}
+	var token *shipyard.AuthToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// RefreshToken replaces the configured auth token with a new one.  The
+// config is updated so later calls use the new token.
+func (m *Manager) RefreshToken() (*shipyard.AuthToken, error) {
+	resp, err := m.doRequest(refreshTokenPath(), "POST", 200, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -344,9 +995,60 @@ func (m *Manager) Login(username, password string) (*shipyard.AuthToken, error)
 	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
 		return nil, err
 	}
+	m.config.Token = token.Token
+	m.logf("shipyard: refreshed auth token for %s", m.config.Username)
 	return token, nil
 }
 
+// Logout revokes the configured auth token and clears it from the config
+func (m *Manager) Logout() error {
+	if _, err := m.doRequest(logoutPath(), "POST", 204, nil); err != nil {
+		return err
+	}
+	m.config.Token = ""
+	m.logf("shipyard: logged out %s", m.config.Username)
+	return nil
+}
+
+// Enable2FA starts two-factor enrollment for the logged in user.  The
+// returned uri is added to an authenticator app and a code from it passed
+// to Confirm2FA.
+func (m *Manager) Enable2FA() (*shipyard.TOTPEnrollment, error) {
+	resp, err := m.doRequest(enable2FAPath(), "POST", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	var enrollment *shipyard.TOTPEnrollment
+	if err := json.NewDecoder(resp.Body).Decode(&enrollment); err != nil {
+		return nil, err
+	}
+	return enrollment, nil
+}
+
+// Confirm2FA requires one time codes at login from now on
+func (m *Manager) Confirm2FA(code string) error {
+	b, err := json.Marshal(map[string]string{"code": code})
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(confirm2FAPath(), "POST", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Disable2FA turns off two-factor auth for the logged in user
+func (m *Manager) Disable2FA(code string) error {
+	b, err := json.Marshal(map[string]string{"code": code})
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(disable2FAPath(), "DELETE", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (m *Manager) ChangePassword(password string) error {
 	creds := map[string]string{}
 	creds["password"] = password
@@ -354,7 +1056,7 @@ func (m *Manager) ChangePassword(password string) error {
 	if err != nil {
 		return err
 	}
-	if _, err := m.doRequest("/account/changepassword", "POST", 200, b); err != nil {
+	if _, err := m.doRequest(changePasswordPath(), "POST", 200, b); err != nil {
 		return err
 	}
 	return nil
@@ -362,7 +1064,7 @@ func (m *Manager) ChangePassword(password string) error {
 
 func (m *Manager) ServiceKeys() ([]*shipyard.ServiceKey, error) {
 	keys := []*shipyard.ServiceKey{}
-	resp, err := m.doRequest("/api/servicekeys", "GET", 200, nil)
+	resp, err := m.doRequest(serviceKeysPath(), "GET", 200, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -372,15 +1074,19 @@ func (m *Manager) ServiceKeys() ([]*shipyard.ServiceKey, error) {
 	return keys, nil
 }
 
-func (m *Manager) NewServiceKey(description string) (*shipyard.ServiceKey, error) {
+// NewServiceKey creates a key that expires after ttl (zero never expires)
+// and is limited to permissions (empty grants full access)
+func (m *Manager) NewServiceKey(description string, ttl time.Duration, permissions []string) (*shipyard.ServiceKey, error) {
 	k := &shipyard.ServiceKey{
 		Description: description,
+		ExpiresIn:   int64(ttl / time.Second),
+		Permissions: permissions,
 	}
 	b, err := json.Marshal(k)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := m.doRequest("/api/servicekeys", "POST", 200, b)
+	resp, err := m.doRequest(addServiceKeyPath(), "POST", 200, b)
 	if err != nil {
 		return nil, err
 	}
@@ -396,7 +1102,7 @@ func (m *Manager) RemoveServiceKey(key *shipyard.ServiceKey) error {
 	if err != nil {
 		return err
 	}
-	if _, err := m.doRequest("/api/servicekeys", "DELETE", 204, b); err != nil {
+	if _, err := m.doRequest(removeServiceKeyPath(), "DELETE", 204, b); err != nil {
 		return err
 	}
 	return nil
@@ -404,7 +1110,7 @@ func (m *Manager) RemoveServiceKey(key *shipyard.ServiceKey) error {
 
 func (m *Manager) Extensions() ([]*shipyard.Extension, error) {
 	exts := []*shipyard.Extension{}
-	resp, err := m.doRequest("/api/extensions", "GET", 200, nil)
+	resp, err := m.doRequest(extensionsPath(), "GET", 200, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -419,14 +1125,14 @@ func (m *Manager) AddExtension(ext *shipyard.Extension) error {
 	if err != nil {
 		return err
 	}
-	if _, err := m.doRequest("/api/extensions", "POST", 204, b); err != nil {
+	if _, err := m.doRequest(addExtensionPath(), "POST", 204, b); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (m *Manager) RemoveExtension(id string) error {
-	if _, err := m.doRequest(fmt.Sprintf("/api/extensions/%s", id), "DELETE", 204, nil); err != nil {
+	if _, err := m.doRequest(deleteExtensionPath(id), "DELETE", 204, nil); err != nil {
 		return err
 	}
 	return nil
@@ -434,7 +1140,7 @@ func (m *Manager) RemoveExtension(id string) error {
 
 func (m *Manager) WebhookKeys() ([]*dockerhub.WebhookKey, error) {
 	keys := []*dockerhub.WebhookKey{}
-	resp, err := m.doRequest("/api/webhookkeys", "GET", 200, nil)
+	resp, err := m.doRequest(webhookKeysPath(), "GET", 200, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -452,7 +1158,7 @@ func (m *Manager) NewWebhookKey(image string) (*dockerhub.WebhookKey, error) {
 	if err != nil {
 		return nil, err
 	}
-	resp, err := m.doRequest("/api/webhookkeys", "POST", 200, b)
+	resp, err := m.doRequest(addWebhookKeyPath(), "POST", 200, b)
 	if err != nil {
 		return nil, err
 	}
@@ -464,7 +1170,7 @@ func (m *Manager) NewWebhookKey(image string) (*dockerhub.WebhookKey, error) {
 }
 
 func (m *Manager) RemoveWebhookKey(key string) error {
-	if _, err := m.doRequest(fmt.Sprintf("/api/webhookkeys/%s", key), "DELETE", 204, nil); err != nil {
+	if _, err := m.doRequest(deleteWebhookKeyPath(key), "DELETE", 204, nil); err != nil {
 		return err
 	}
 	return nil
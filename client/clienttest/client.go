@@ -0,0 +1,3826 @@
+// Package clienttest provides an in-memory client.ShipyardClient for unit
+// testing code built on the shipyard client without a running controller.
+package clienttest
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+	"github.com/shipyard/shipyard/dockerhub"
+)
+
+var (
+	ErrNotSupported = errors.New("not supported by the test client")
+)
+
+// Client keeps containers, engines, accounts and keys in memory.  Every
+// mutating call records an event like the controller does.  The zero value
+// is not usable; create one with NewClient.
+type Client struct {
+	// ExecFunc, when set, handles Exec calls
+	ExecFunc func(containerID string, cfg *shipyard.ExecConfig) (*client.ExecSession, error)
+	// AttachFunc, when set, handles Attach calls
+	AttachFunc func(containerID string) (*client.AttachSession, error)
+	// Namespace scopes containers, events and service keys like the
+	// Namespace field of the client config
+	Namespace string
+
+	mu          sync.Mutex
+	username    string
+	containers  []*citadel.Container
+	engines     []*shipyard.Engine
+	events      []*shipyard.Event
+	audit       []*shipyard.AuditEntry
+	subscribers []chan *shipyard.Event
+	done        chan struct{}
+	closed      bool
+	accounts    []*shipyard.Account
+	passwords   map[string]string
+	roles       []*shipyard.Role
+	serviceKeys []*shipyard.ServiceKey
+	extensions  []*shipyard.Extension
+	webhookKeys []*dockerhub.WebhookKey
+	webhooks    []*shipyard.Webhook
+	notifiers   []*shipyard.Notifier
+	alertRules  []*shipyard.AlertRule
+	alerts      []*shipyard.Alert
+	apps        []*shipyard.Application
+	deployments []*shipyard.Deployment
+	jobs        []*shipyard.Job
+	jobRuns     []*shipyard.JobRun
+	secrets     []*shipyard.Secret
+	configs     []*shipyard.ConfigBundle
+	templates   []*shipyard.LaunchTemplate
+	volumes     []*shipyard.Volume
+	quotas      []*shipyard.Quota
+	namespaces  []*shipyard.Namespace
+	images      []*shipyard.Image
+	registries  []*shipyard.Registry
+	gcPolicy    *shipyard.GCPolicy
+	retention   *shipyard.EventRetention
+	joinTokens  []*shipyard.JoinToken
+	pools       []*shipyard.EnginePool
+	logs        map[string]string
+	files       map[string]map[string][]byte
+	stats       map[string][]*shipyard.ContainerStats
+	metrics     []*shipyard.MetricSample
+	processes   map[string]*shipyard.ProcessList
+	exitCodes   map[string]int
+	execs       map[string]*shipyard.ExecInfo
+	totp        map[string]*totpState
+}
+
+var _ client.ShipyardClient = (*Client)(nil)
+
+// NewClient returns an empty client with the default admin and user roles
+func NewClient() *Client {
+	return &Client{
+		passwords: make(map[string]string),
+		done:      make(chan struct{}),
+		roles: []*shipyard.Role{
+			{ID: newID(), Name: "admin", Permissions: shipyard.DefaultRolePermissions["admin"]},
+			{ID: newID(), Name: "user", Permissions: shipyard.DefaultRolePermissions["user"]},
+		},
+		logs:      make(map[string]string),
+		files:     make(map[string]map[string][]byte),
+		stats:     make(map[string][]*shipyard.ContainerStats),
+		processes: make(map[string]*shipyard.ProcessList),
+		exitCodes: make(map[string]int),
+		execs:     make(map[string]*shipyard.ExecInfo),
+		totp:      make(map[string]*totpState),
+		gcPolicy:  &shipyard.GCPolicy{},
+		retention: &shipyard.EventRetention{Interval: shipyard.DefaultRetentionInterval},
+	}
+}
+
+// SetLogs sets the output returned by Logs for a container
+func (c *Client) SetLogs(containerID string, logs string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logs[containerID] = logs
+}
+
+// SetFile sets the content of a file in a container for CopyFrom
+func (c *Client) SetFile(containerID string, path string, content []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.files[containerID] == nil {
+		c.files[containerID] = make(map[string][]byte)
+	}
+	c.files[containerID][path] = content
+}
+
+// AddAuditEntry records an entry returned by AuditLog
+func (c *Client) AddAuditEntry(entry *shipyard.AuditEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.audit = append(c.audit, entry)
+}
+
+// SetStats sets the samples delivered by Stats for a container
+func (c *Client) SetStats(containerID string, stats []*shipyard.ContainerStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats[containerID] = stats
+}
+
+// SetAlerts sets the firing alerts returned by Alerts; the test client
+// does not evaluate rules
+func (c *Client) SetAlerts(alerts []*shipyard.Alert) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.alerts = alerts
+}
+
+// AddMetrics adds samples returned by Metrics; their resource, resource id
+// and time select them
+func (c *Client) AddMetrics(samples ...*shipyard.MetricSample) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metrics = append(c.metrics, samples...)
+}
+
+// SetExitCode sets the exit code returned by Wait for a container
+func (c *Client) SetExitCode(containerID string, code int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.exitCodes[containerID] = code
+}
+
+// SetProcesses sets the process table returned by Top for a container
+func (c *Client) SetProcesses(containerID string, processes *shipyard.ProcessList) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.processes[containerID] = processes
+}
+
+func newID() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func notFound(endpoint string, what string) error {
+	return &shipyard.APIError{
+		StatusCode: http.StatusNotFound,
+		Endpoint:   endpoint,
+		Message:    what + " not found",
+	}
+}
+
+// recordEvent must be called with the lock held
+func (c *Client) recordEvent(typ string, container *citadel.Container, engine *citadel.Engine, message string) {
+	evt := &shipyard.Event{
+		Type:      typ,
+		Container: container,
+		Engine:    engine,
+		Time:      time.Now(),
+		Message:   message,
+		Tags:      []string{"cluster"},
+		Severity:  shipyard.EventSeverity(typ),
+		Namespace: shipyard.ContainerNamespace(container),
+	}
+	c.events = append(c.events, evt)
+	for _, s := range c.subscribers {
+		select {
+		case s <- evt:
+		default:
+		}
+	}
+}
+
+// findContainer must be called with the lock held
+func (c *Client) findContainer(id string) *citadel.Container {
+	for _, cnt := range c.containers {
+		if id != "" && strings.HasPrefix(cnt.ID, id) {
+			return cnt
+		}
+	}
+	return nil
+}
+
+func (c *Client) setState(container *citadel.Container, state string, evt string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cnt := c.findContainer(container.ID)
+	if cnt == nil {
+		return notFound("/api/containers/"+container.ID, "container")
+	}
+	cnt.State = state
+	c.recordEvent(evt, cnt, cnt.Engine, "")
+	return nil
+}
+
+func (c *Client) Containers() ([]*citadel.Container, error) {
+	return c.QueryContainers(nil)
+}
+
+func (c *Client) ContainersByLabel(selector string) ([]*citadel.Container, error) {
+	s, err := shipyard.ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return c.QueryContainers(&shipyard.ContainerQuery{Selector: s})
+}
+
+func (c *Client) QueryContainers(query *shipyard.ContainerQuery) ([]*citadel.Container, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	scoped := shipyard.ContainerQuery{}
+	if query != nil {
+		scoped = *query
+	}
+	if c.Namespace != "" {
+		scoped.Namespace = c.Namespace
+	}
+	return scoped.Select(c.containers), nil
+}
+
+func (c *Client) Container(id string) (*citadel.Container, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cnt := c.findContainer(id)
+	if cnt == nil {
+		return nil, notFound("/api/containers/"+id, "container")
+	}
+	return cnt, nil
+}
+
+func (c *Client) GetContainer(id string) (*citadel.Container, error) {
+	return c.Container(id)
+}
+
+// Run places containers round robin across the added engines
+func (c *Client) Run(image *citadel.Image, count int, pull bool) ([]*citadel.Container, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	img := *image
+	img.Environment = map[string]string{}
+	for k, v := range image.Environment {
+		img.Environment[k] = v
+	}
+	shipyard.SetOwner(&img, c.username)
+	shipyard.SetNamespace(&img, c.Namespace)
+	if err := c.checkQuota(&img, count); err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusForbidden,
+			Method:     "POST",
+			Endpoint:   "/api/containers",
+			Message:    err.Error(),
+		}
+	}
+	return c.launch(&img, count)
+}
+
+// Batch applies the operations one container at a time
+func (c *Client) Batch(ops []*shipyard.BatchOperation) (*shipyard.BatchResult, error) {
+	if err := shipyard.ValidateBatch(ops); err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/containers/batch",
+			Message:    err.Error(),
+		}
+	}
+	c.mu.Lock()
+	containers := []*citadel.Container{}
+	for _, cnt := range c.containers {
+		if c.Namespace == "" || shipyard.ContainerNamespace(cnt) == c.Namespace {
+			containers = append(containers, cnt)
+		}
+	}
+	c.mu.Unlock()
+
+	res := &shipyard.BatchResult{Results: []*shipyard.BatchItemResult{}}
+	for i, op := range ops {
+		selected, err := op.Select(containers)
+		if err != nil || len(selected) == 0 {
+			what := op.ID
+			if what == "" {
+				what = op.Selector
+			}
+			msg := "no such container"
+			if err != nil {
+				msg = err.Error()
+			}
+			res.Results = append(res.Results, &shipyard.BatchItemResult{Operation: i, Action: op.Action, Container: what, Error: msg})
+			res.Failed++
+			continue
+		}
+		for _, cnt := range selected {
+			var err error
+			switch op.Action {
+			case shipyard.BatchDestroy:
+				err = c.Destroy(cnt)
+			case shipyard.BatchStop:
+				err = c.Stop(cnt, op.Timeout)
+			case shipyard.BatchRestart:
+				err = c.Restart(cnt, op.Timeout)
+			}
+			r := &shipyard.BatchItemResult{Operation: i, Action: op.Action, Container: cnt.ID}
+			if err != nil {
+				r.Error = err.Error()
+				res.Failed++
+			}
+			res.Results = append(res.Results, r)
+		}
+	}
+	return res, nil
+}
+
+// PreviewRun places count containers of image on the uncordoned engines by
+// reservation, ignoring affinities and volumes
+func (c *Client) PreviewRun(image *citadel.Image, count int) (*shipyard.RunPreview, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	img := *image
+	shipyard.SetNamespace(&img, c.Namespace)
+	if err := c.checkQuota(&img, count); err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusForbidden,
+			Method:     "POST",
+			Endpoint:   "/api/containers",
+			Message:    err.Error(),
+		}
+	}
+	strategy := shipyard.ContainerPlacement(image)
+	if strategy == "" {
+		strategy = shipyard.DefaultPlacement
+	}
+	preview := &shipyard.RunPreview{
+		Placements: []*shipyard.RunPlacement{},
+		Engines:    []*shipyard.EngineLoad{},
+	}
+	loads := map[string]*shipyard.EngineLoad{}
+	for _, e := range c.engines {
+		if e.Engine == nil || e.Cordoned {
+			continue
+		}
+		l := &shipyard.EngineLoad{ID: e.Engine.ID, Cpus: e.Engine.Cpus, Memory: e.Engine.Memory}
+		for _, cnt := range c.engineContainers(e.Engine) {
+			l.ReservedCpus += cnt.Image.Cpus
+			l.ReservedMemory += cnt.Image.Memory
+		}
+		loads[l.ID] = l
+		preview.Engines = append(preview.Engines, l)
+	}
+	for i := 0; i < count; i++ {
+		id, err := shipyard.PlaceContainer(strategy, preview.Engines, image.Cpus, image.Memory, nil)
+		if err != nil {
+			preview.Unplaced = count - i
+			preview.Error = err.Error()
+			break
+		}
+		loads[id].ReservedCpus += image.Cpus
+		loads[id].ReservedMemory += image.Memory
+		preview.Placements = append(preview.Placements, &shipyard.RunPlacement{Engine: id, Cpus: image.Cpus, Memory: image.Memory})
+	}
+	return preview, nil
+}
+
+// launch must be called with the lock held
+func (c *Client) launch(image *citadel.Image, count int) ([]*citadel.Container, error) {
+	if len(c.engines) == 0 {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusInternalServerError,
+			Method:     "POST",
+			Endpoint:   "/api/containers",
+			Message:    "no engines available",
+		}
+	}
+	// images mounting volumes are placed on the engine holding them
+	var pinned *citadel.Engine
+	mounts, _ := shipyard.VolumeMounts(image)
+	for _, m := range mounts {
+		var eng *shipyard.Engine
+		for _, v := range c.volumes {
+			if v.Name == m.Name {
+				eng = c.findEngine(v.Engine)
+			}
+		}
+		msg := ""
+		switch {
+		case eng == nil:
+			msg = fmt.Sprintf("volume %s: volume does not exist", m.Name)
+		case pinned != nil && pinned != eng.Engine:
+			msg = "volumes are on different engines"
+		}
+		if msg != "" {
+			return nil, &shipyard.APIError{
+				StatusCode: http.StatusInternalServerError,
+				Method:     "POST",
+				Endpoint:   "/api/containers",
+				Message:    msg,
+			}
+		}
+		pinned = eng.Engine
+	}
+	affinity, _ := shipyard.ContainerAffinity(image)
+	launched := []*citadel.Container{}
+	for i := 0; i < count; i++ {
+		img := *image
+		var eng *citadel.Engine
+		for j := range c.engines {
+			e := c.engines[(len(c.containers)+j)%len(c.engines)].Engine
+			if pinned != nil && e != pinned {
+				continue
+			}
+			if affinity != nil && !affinity.Allows(image, c.engineContainers(e)) {
+				continue
+			}
+			eng = e
+			break
+		}
+		if eng == nil {
+			return launched, &shipyard.APIError{
+				StatusCode: http.StatusInternalServerError,
+				Method:     "POST",
+				Endpoint:   "/api/containers",
+				Message:    "no eligible engines to run image",
+			}
+		}
+		cnt := &citadel.Container{
+			ID:     newID(),
+			Name:   img.ContainerName,
+			Image:  &img,
+			Engine: eng,
+			State:  "running",
+		}
+		c.containers = append(c.containers, cnt)
+		c.recordEvent("start", cnt, eng, "")
+		launched = append(launched, cnt)
+	}
+	return launched, nil
+}
+
+// engineContainers returns the running containers of an engine; it must be
+// called with the lock held
+func (c *Client) engineContainers(eng *citadel.Engine) []*citadel.Container {
+	containers := []*citadel.Container{}
+	for _, cnt := range c.containers {
+		if cnt.Engine == eng && cnt.State == "running" {
+			containers = append(containers, cnt)
+		}
+	}
+	return containers
+}
+
+func (c *Client) Destroy(container *citadel.Container) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, cnt := range c.containers {
+		if cnt.ID == container.ID {
+			c.containers = append(c.containers[:i], c.containers[i+1:]...)
+			c.recordEvent("destroy", cnt, cnt.Engine, "")
+			return nil
+		}
+	}
+	return notFound("/api/containers/"+container.ID, "container")
+}
+
+func (c *Client) UpdateContainer(container *citadel.Container, patch *shipyard.ContainerPatch) (*citadel.Container, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cnt := c.findContainer(container.ID)
+	if cnt == nil {
+		return nil, notFound("/api/containers/"+container.ID, "container")
+	}
+	md, err := patch.Apply(cnt)
+	if err == nil {
+		err = md.SetMetadata(cnt.Image)
+	}
+	if err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "PATCH",
+			Endpoint:   "/api/containers/" + container.ID,
+			Message:    err.Error(),
+		}
+	}
+	c.recordEvent("update-container", cnt, cnt.Engine, "")
+	return cnt, nil
+}
+
+func (c *Client) RenameContainer(container *citadel.Container, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cnt := c.findContainer(container.ID)
+	if cnt == nil {
+		return notFound("/api/containers/"+container.ID+"/rename", "container")
+	}
+	if err := shipyard.ValidateContainerName(name); err != nil {
+		return &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/containers/" + container.ID + "/rename",
+			Message:    err.Error(),
+		}
+	}
+	cnt.Name = "/" + name
+	c.recordEvent("rename", cnt, cnt.Engine, "name="+name)
+	return nil
+}
+
+// Commit records the image on the engine of the container.  Pushing only
+// checks a registry for the image host was added.
+func (c *Client) Commit(container *citadel.Container, opts *shipyard.CommitOptions) (*shipyard.Image, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	endpoint := "/api/containers/" + container.ID + "/commit"
+	cnt := c.findContainer(container.ID)
+	if cnt == nil {
+		return nil, notFound(endpoint, "container")
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   endpoint,
+			Message:    err.Error(),
+		}
+	}
+	if opts.Push {
+		found := false
+		for _, reg := range c.registries {
+			if reg.Host() == shipyard.RegistryHost(opts.Image()) {
+				found = true
+			}
+		}
+		if !found {
+			return nil, notFound(endpoint, "registry")
+		}
+	}
+	image := &shipyard.Image{
+		ID:       newID(),
+		RepoTags: []string{opts.Image()},
+		Created:  time.Now().Unix(),
+	}
+	if cnt.Engine != nil {
+		image.Engines = []string{cnt.Engine.ID}
+	}
+	c.images = append(c.images, image)
+	c.recordEvent("commit", cnt, cnt.Engine, "image="+opts.Image())
+	return image, nil
+}
+
+// Wait polls until the container is no longer running, i.e. after Stop,
+// and returns the exit code set with SetExitCode
+func (c *Client) Wait(containerID string) (int, error) {
+	for {
+		c.mu.Lock()
+		cnt := c.findContainer(containerID)
+		if cnt == nil {
+			c.mu.Unlock()
+			return 0, notFound("/api/containers/"+containerID+"/wait", "container")
+		}
+		if cnt.State != "running" {
+			code := c.exitCodes[cnt.ID]
+			c.mu.Unlock()
+			return code, nil
+		}
+		c.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (c *Client) Start(container *citadel.Container) error {
+	return c.setState(container, "running", "start")
+}
+
+func (c *Client) Stop(container *citadel.Container, timeout int) error {
+	return c.setState(container, "stopped", "stop")
+}
+
+func (c *Client) Restart(container *citadel.Container, timeout int) error {
+	return c.setState(container, "running", "restart")
+}
+
+func (c *Client) Pause(container *citadel.Container) error {
+	return c.setState(container, "paused", "pause")
+}
+
+func (c *Client) Unpause(container *citadel.Container) error {
+	return c.setState(container, "running", "unpause")
+}
+
+// Scale matches instances by image name
+func (c *Client) Scale(image *citadel.Image, desiredCount int) error {
+	c.mu.Lock()
+	matching := []*citadel.Container{}
+	for _, cnt := range c.containers {
+		if cnt.Image != nil && cnt.Image.Name == image.Name {
+			matching = append(matching, cnt)
+		}
+	}
+	c.mu.Unlock()
+
+	if len(matching) > desiredCount {
+		for _, cnt := range matching[:len(matching)-desiredCount] {
+			if err := c.Destroy(cnt); err != nil {
+				return err
+			}
+		}
+	} else if len(matching) < desiredCount {
+		if _, err := c.Run(image, desiredCount-len(matching), false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Logs returns the output set with SetLogs; follow and tail are ignored
+func (c *Client) Logs(containerID string, follow bool, tail int) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cnt := c.findContainer(containerID)
+	if cnt == nil {
+		return nil, notFound("/api/containers/"+containerID+"/logs", "container")
+	}
+	return ioutil.NopCloser(strings.NewReader(c.logs[cnt.ID])), nil
+}
+
+// CopyFrom archives the files set with SetFile or copied with CopyTo that
+// are at or below path
+func (c *Client) CopyFrom(containerID string, p string) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	endpoint := "/api/containers/" + containerID + "/archive"
+	cnt := c.findContainer(containerID)
+	if cnt == nil {
+		return nil, notFound(endpoint, "container")
+	}
+	if err := shipyard.ValidateContainerPath(p); err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "GET",
+			Endpoint:   endpoint,
+			Message:    err.Error(),
+		}
+	}
+	paths := []string{}
+	for f := range c.files[cnt.ID] {
+		if f == p || strings.HasPrefix(f, strings.TrimSuffix(p, "/")+"/") {
+			paths = append(paths, f)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, notFound(endpoint, "path")
+	}
+	archive, err := c.archive(cnt.ID, paths, filepath.Dir(p))
+	if err != nil {
+		return nil, err
+	}
+	c.recordEvent("copy-from", cnt, cnt.Engine, "path="+p)
+	return archive, nil
+}
+
+// archive must be called with the lock held
+func (c *Client) archive(containerID string, paths []string, dir string) (io.ReadCloser, error) {
+	sort.Strings(paths)
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, f := range paths {
+		content := c.files[containerID][f]
+		rel, _ := filepath.Rel(dir, f)
+		if err := tw.WriteHeader(&tar.Header{Name: rel, Mode: 0644, Size: int64(len(content))}); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(buf), nil
+}
+
+// Diff reports the files set with SetFile or copied with CopyTo as added
+func (c *Client) Diff(containerID string) ([]*shipyard.ContainerChange, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cnt := c.findContainer(containerID)
+	if cnt == nil {
+		return nil, notFound("/api/containers/"+containerID+"/changes", "container")
+	}
+	changes := []*shipyard.ContainerChange{}
+	for f := range c.files[cnt.ID] {
+		changes = append(changes, &shipyard.ContainerChange{Path: f, Kind: shipyard.ChangeAdded})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// Export archives every file of the container
+func (c *Client) Export(containerID string) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cnt := c.findContainer(containerID)
+	if cnt == nil {
+		return nil, notFound("/api/containers/"+containerID+"/export", "container")
+	}
+	paths := []string{}
+	for f := range c.files[cnt.ID] {
+		paths = append(paths, f)
+	}
+	archive, err := c.archive(cnt.ID, paths, "/")
+	if err != nil {
+		return nil, err
+	}
+	c.recordEvent("export", cnt, cnt.Engine, "")
+	return archive, nil
+}
+
+// CopyTo stores the regular files of the archive below path
+func (c *Client) CopyTo(containerID string, p string, archive io.Reader) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	endpoint := "/api/containers/" + containerID + "/archive"
+	cnt := c.findContainer(containerID)
+	if cnt == nil {
+		return notFound(endpoint, "container")
+	}
+	if err := shipyard.ValidateContainerPath(p); err != nil {
+		return &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "PUT",
+			Endpoint:   endpoint,
+			Message:    err.Error(),
+		}
+	}
+	if c.files[cnt.ID] == nil {
+		c.files[cnt.ID] = make(map[string][]byte)
+	}
+	tr := tar.NewReader(archive)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if !hdr.FileInfo().Mode().IsRegular() {
+			continue
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		c.files[cnt.ID][filepath.Join(p, hdr.Name)] = content
+	}
+	c.recordEvent("copy-to", cnt, cnt.Engine, "path="+p)
+	return nil
+}
+
+func (c *Client) Exec(containerID string, cfg *shipyard.ExecConfig) (*client.ExecSession, error) {
+	if c.ExecFunc == nil {
+		return nil, ErrNotSupported
+	}
+	sess, err := c.ExecFunc(containerID, cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.execs[sess.ID] = &shipyard.ExecInfo{ID: sess.ID}
+	c.mu.Unlock()
+	return sess, nil
+}
+
+func (c *Client) Attach(containerID string) (*client.AttachSession, error) {
+	if c.AttachFunc == nil {
+		return nil, ErrNotSupported
+	}
+	sess, err := c.AttachFunc(containerID)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cnt := c.findContainer(containerID); cnt != nil {
+		c.recordEvent("attach", cnt, cnt.Engine, "")
+	}
+	return sess, nil
+}
+
+func (c *Client) ExecInspect(containerID string, execID string) (*shipyard.ExecInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	info, ok := c.execs[execID]
+	if !ok {
+		return nil, notFound(fmt.Sprintf("/api/containers/%s/exec/%s", containerID, execID), "exec")
+	}
+	return info, nil
+}
+
+// Stats delivers the samples set with SetStats and closes the channel
+func (c *Client) Stats(containerID string) (<-chan *shipyard.ContainerStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cnt := c.findContainer(containerID)
+	if cnt == nil {
+		return nil, notFound("/api/containers/"+containerID+"/stats", "container")
+	}
+	stats := c.stats[cnt.ID]
+	ch := make(chan *shipyard.ContainerStats, len(stats))
+	for _, s := range stats {
+		ch <- s
+	}
+	close(ch)
+	return ch, nil
+}
+
+// Top returns the process table set with SetProcesses; psArgs are ignored
+func (c *Client) Top(containerID string, psArgs string) (*shipyard.ProcessList, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cnt := c.findContainer(containerID)
+	if cnt == nil {
+		return nil, notFound("/api/containers/"+containerID+"/top", "container")
+	}
+	if p, ok := c.processes[cnt.ID]; ok {
+		return p, nil
+	}
+	return &shipyard.ProcessList{}, nil
+}
+
+// Metrics returns the samples added with AddMetrics for a resource taken
+// since a time, oldest first
+func (c *Client) Metrics(resource string, id string, since time.Time) ([]*shipyard.MetricSample, error) {
+	if err := shipyard.ValidateMetricsResource(resource); err != nil {
+		return nil, err
+	}
+	samples := []*shipyard.MetricSample{}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.metrics {
+		if s.Resource == resource && s.ResourceID == id && !s.Time.Before(since) {
+			samples = append(samples, s)
+		}
+	}
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Time.Before(samples[j].Time)
+	})
+	return samples, nil
+}
+
+func (c *Client) Images() ([]*shipyard.Image, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*shipyard.Image{}, c.images...), nil
+}
+
+// PullImage records the image as present on every engine
+func (c *Client) PullImage(name string, tag string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if tag == "" {
+		tag = "latest"
+	}
+	ref := fmt.Sprintf("%s:%s", name, tag)
+	engines := []string{}
+	for _, e := range c.engines {
+		engines = append(engines, e.ID)
+	}
+	for _, img := range c.images {
+		for _, t := range img.RepoTags {
+			if t == ref {
+				img.Engines = engines
+				return nil
+			}
+		}
+	}
+	c.images = append(c.images, &shipyard.Image{
+		ID:       newID(),
+		RepoTags: []string{ref},
+		Created:  time.Now().Unix(),
+		Engines:  engines,
+	})
+	c.recordEvent("pull-image", nil, nil, "image="+ref)
+	return nil
+}
+
+// Build checks the context holds the Dockerfile and records the image as
+// present on the first engine
+func (c *Client) Build(context io.Reader, opts *shipyard.BuildOptions, out io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	badRequest := func(msg string) error {
+		return &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/images/build",
+			Message:    msg,
+		}
+	}
+	if err := opts.Validate(); err != nil {
+		return badRequest(err.Error())
+	}
+	dockerfile := opts.Dockerfile
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	found := false
+	tr := tar.NewReader(context)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return badRequest(err.Error())
+		}
+		if filepath.Clean(hdr.Name) == filepath.Clean(dockerfile) {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: cannot locate %s", shipyard.ErrBuildFailed, dockerfile)
+	}
+	image := &shipyard.Image{
+		ID:      newID(),
+		Created: time.Now().Unix(),
+	}
+	if opts.Tag != "" {
+		repo, tag := shipyard.ParseImageName(opts.Tag)
+		if tag == "" {
+			tag = "latest"
+		}
+		image.RepoTags = []string{repo + ":" + tag}
+	}
+	if len(c.engines) > 0 {
+		image.Engines = []string{c.engines[0].ID}
+	}
+	c.images = append(c.images, image)
+	c.recordEvent("build-image", nil, nil, "tag="+opts.Tag)
+	fmt.Fprintf(out, "Successfully built %s\n", image.ID[:12])
+	return nil
+}
+
+func (c *Client) RemoveImage(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, img := range c.images {
+		match := img.ID == name
+		for _, t := range img.RepoTags {
+			if t == name || t == name+":latest" {
+				match = true
+			}
+		}
+		if match {
+			c.images = append(c.images[:i], c.images[i+1:]...)
+			c.recordEvent("remove-image", nil, nil, "image="+name)
+			return nil
+		}
+	}
+	return notFound("/api/images/"+name, "image")
+}
+
+func (c *Client) Registries() ([]*shipyard.Registry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	registries := []*shipyard.Registry{}
+	for _, reg := range c.registries {
+		r := *reg
+		r.Password = ""
+		registries = append(registries, &r)
+	}
+	return registries, nil
+}
+
+func (c *Client) AddRegistry(registry *shipyard.Registry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, reg := range c.registries {
+		if reg.Name == registry.Name {
+			return &shipyard.APIError{
+				StatusCode: http.StatusConflict,
+				Method:     "POST",
+				Endpoint:   "/api/registries",
+				Message:    "registry already exists",
+			}
+		}
+	}
+	if registry.ID == "" {
+		registry.ID = newID()
+	}
+	c.registries = append(c.registries, registry)
+	c.recordEvent("add-registry", nil, nil, "name="+registry.Name)
+	return nil
+}
+
+func (c *Client) RemoveRegistry(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, reg := range c.registries {
+		if reg.Name == name {
+			c.registries = append(c.registries[:i], c.registries[i+1:]...)
+			c.recordEvent("remove-registry", nil, nil, "name="+name)
+			return nil
+		}
+	}
+	return notFound("/api/registries/"+name, "registry")
+}
+
+// Webhooks returns the registered webhooks.  Events are not delivered by
+// the test client.
+func (c *Client) Webhooks() ([]*shipyard.Webhook, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hooks := []*shipyard.Webhook{}
+	for _, hook := range c.webhooks {
+		h := *hook
+		h.Secret = ""
+		hooks = append(hooks, &h)
+	}
+	return hooks, nil
+}
+
+func (c *Client) AddWebhook(hook *shipyard.Webhook) (*shipyard.Webhook, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !strings.HasPrefix(hook.URL, "http://") && !strings.HasPrefix(hook.URL, "https://") {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/webhooks",
+			Message:    "webhook url must be an absolute http or https url",
+		}
+	}
+	h := *hook
+	h.ID = newID()
+	if h.Secret == "" {
+		h.Secret = newID()
+	}
+	c.webhooks = append(c.webhooks, &h)
+	c.recordEvent("add-webhook", nil, nil, "id="+h.ID)
+	created := h
+	return &created, nil
+}
+
+func (c *Client) RemoveWebhook(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, hook := range c.webhooks {
+		if hook.ID == id {
+			c.webhooks = append(c.webhooks[:i], c.webhooks[i+1:]...)
+			c.recordEvent("remove-webhook", nil, nil, "id="+id)
+			return nil
+		}
+	}
+	return notFound("/api/webhooks/"+id, "webhook")
+}
+
+// Notifiers returns the added notifiers.  Nothing is sent by the test
+// client.
+func (c *Client) Notifiers() ([]*shipyard.Notifier, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	notifiers := []*shipyard.Notifier{}
+	for _, n := range c.notifiers {
+		notifiers = append(notifiers, n.Redacted())
+	}
+	return notifiers, nil
+}
+
+func (c *Client) AddNotifier(n *shipyard.Notifier) (*shipyard.Notifier, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := n.Validate(); err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/notifiers",
+			Message:    err.Error(),
+		}
+	}
+	stored := *n
+	stored.ID = newID()
+	c.notifiers = append(c.notifiers, &stored)
+	c.recordEvent("add-notifier", nil, nil, "id="+stored.ID)
+	return stored.Redacted(), nil
+}
+
+func (c *Client) RemoveNotifier(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, n := range c.notifiers {
+		if n.ID == id {
+			c.notifiers = append(c.notifiers[:i], c.notifiers[i+1:]...)
+			c.recordEvent("remove-notifier", nil, nil, "id="+id)
+			return nil
+		}
+	}
+	return notFound("/api/notifiers/"+id, "notifier")
+}
+
+// Alerts returns the alerts set with SetAlerts
+func (c *Client) Alerts() ([]*shipyard.Alert, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*shipyard.Alert{}, c.alerts...), nil
+}
+
+func (c *Client) AlertRules() ([]*shipyard.AlertRule, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rules := []*shipyard.AlertRule{}
+	for _, r := range c.alertRules {
+		listed := *r
+		rules = append(rules, &listed)
+	}
+	return rules, nil
+}
+
+func (c *Client) AddAlertRule(rule *shipyard.AlertRule) (*shipyard.AlertRule, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := rule.Validate(); err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/alerts/rules",
+			Message:    err.Error(),
+		}
+	}
+	stored := *rule
+	stored.ID = newID()
+	c.alertRules = append(c.alertRules, &stored)
+	c.recordEvent("add-alert-rule", nil, nil, "id="+stored.ID)
+	created := stored
+	return &created, nil
+}
+
+// RemoveAlertRule removes a rule and the alerts it fired
+func (c *Client) RemoveAlertRule(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, r := range c.alertRules {
+		if r.ID == id {
+			c.alertRules = append(c.alertRules[:i], c.alertRules[i+1:]...)
+			alerts := []*shipyard.Alert{}
+			for _, a := range c.alerts {
+				if a.RuleID != id {
+					alerts = append(alerts, a)
+				}
+			}
+			c.alerts = alerts
+			c.recordEvent("remove-alert-rule", nil, nil, "id="+id)
+			return nil
+		}
+	}
+	return notFound("/api/alerts/rules/"+id, "alert rule")
+}
+
+func (c *Client) Engines() ([]*shipyard.Engine, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*shipyard.Engine{}, c.engines...), nil
+}
+
+func (c *Client) GetEngine(id string) (*shipyard.Engine, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.engines {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return nil, notFound("/api/engines/"+id, "engine")
+}
+
+// EngineHealth reports engines as up unless their health has been set
+func (c *Client) EngineHealth(id string) (*shipyard.Health, error) {
+	e, err := c.GetEngine(id)
+	if err != nil {
+		return nil, err
+	}
+	if e.Health != nil {
+		return e.Health, nil
+	}
+	now := time.Now()
+	return &shipyard.Health{Status: "up", LastSeen: now, LastChecked: now}, nil
+}
+
+func (c *Client) UpdateEngine(engine *shipyard.Engine) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, e := range c.engines {
+		if e.ID == engine.ID {
+			engine.Cordoned = e.Cordoned
+			c.engines[i] = engine
+			c.recordEvent("update-engine", nil, engine.Engine, "")
+			return nil
+		}
+	}
+	return notFound("/api/engines/"+engine.ID, "engine")
+}
+
+func (c *Client) JoinTokens() ([]*shipyard.JoinToken, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tokens := []*shipyard.JoinToken{}
+	for _, t := range c.joinTokens {
+		listed := *t
+		listed.Token = ""
+		tokens = append(tokens, &listed)
+	}
+	return tokens, nil
+}
+
+func (c *Client) CreateJoinToken(ttl time.Duration, labels []string) (*shipyard.JoinToken, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ttl < 0 {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/engines/join-tokens",
+			Message:    "join token lifetime must not be negative",
+		}
+	}
+	token, err := shipyard.NewJoinToken()
+	if err != nil {
+		return nil, err
+	}
+	t := &shipyard.JoinToken{
+		ID:        newID(),
+		Token:     token,
+		Hash:      shipyard.HashJoinToken(token),
+		Labels:    labels,
+		CreatedBy: c.username,
+		Created:   time.Now(),
+	}
+	if ttl > 0 {
+		t.ExpiresAt = t.Created.Add(ttl)
+	}
+	c.joinTokens = append(c.joinTokens, t)
+	c.recordEvent("create-join-token", nil, nil, "id="+t.ID)
+	created := *t
+	return &created, nil
+}
+
+func (c *Client) DeleteJoinToken(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, t := range c.joinTokens {
+		if t.ID == id {
+			c.joinTokens = append(c.joinTokens[:i], c.joinTokens[i+1:]...)
+			c.recordEvent("delete-join-token", nil, nil, "id="+id)
+			return nil
+		}
+	}
+	return notFound("/api/engines/join-tokens/"+id, "join token")
+}
+
+// JoinEngine adds the engine of a join request without contacting it
+func (c *Client) JoinEngine(req *shipyard.JoinRequest) (*citadel.Engine, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	apiErr := func(status int, msg string) error {
+		return &shipyard.APIError{StatusCode: status, Method: "POST", Endpoint: "/join", Message: msg}
+	}
+	if err := req.Validate(); err != nil {
+		if errors.Is(err, shipyard.ErrInvalidJoinToken) {
+			return nil, apiErr(http.StatusUnauthorized, err.Error())
+		}
+		return nil, apiErr(http.StatusBadRequest, err.Error())
+	}
+	for _, e := range c.engines {
+		if e.Engine.ID == req.ID {
+			return nil, apiErr(http.StatusConflict, "engine already exists")
+		}
+	}
+	hash := shipyard.HashJoinToken(req.Token)
+	for i, t := range c.joinTokens {
+		if t.Hash != hash || t.Expired(time.Now()) {
+			continue
+		}
+		c.joinTokens = append(c.joinTokens[:i], c.joinTokens[i+1:]...)
+		engine := req.Engine(t)
+		engine.ID = newID()
+		c.engines = append(c.engines, engine)
+		c.recordEvent("join-engine", nil, engine.Engine, "token="+t.ID)
+		joined := *engine.Engine
+		return &joined, nil
+	}
+	return nil, apiErr(http.StatusUnauthorized, shipyard.ErrInvalidJoinToken.Error())
+}
+
+func (c *Client) UpdateEngineLabels(id string, labels []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.engines {
+		if e.ID == id {
+			if e.Engine == nil {
+				e.Engine = &citadel.Engine{}
+			}
+			e.Engine.Labels = labels
+			c.recordEvent("update-engine", nil, e.Engine, "")
+			return nil
+		}
+	}
+	return notFound("/api/engines/"+id, "engine")
+}
+
+func (c *Client) EngineCertificates(id string) (*shipyard.EngineCertificateInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.engines {
+		if e.ID == id {
+			return e.CertificateInfo()
+		}
+	}
+	return nil, notFound("/api/engines/"+id+"/certificates", "engine")
+}
+
+func (c *Client) SetEngineCertificates(id string, certs *shipyard.EngineCertificates) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	endpoint := "/api/engines/" + id + "/certificates"
+	for _, e := range c.engines {
+		if e.ID != id {
+			continue
+		}
+		if err := certs.Validate(); err != nil {
+			return &shipyard.APIError{
+				StatusCode: http.StatusBadRequest,
+				Method:     "PUT",
+				Endpoint:   endpoint,
+				Message:    err.Error(),
+			}
+		}
+		e.CACertificate = certs.CACertificate
+		e.SSLCertificate = certs.SSLCertificate
+		e.SSLKey = certs.SSLKey
+		c.recordEvent("update-engine-certificates", nil, e.Engine, "")
+		return nil
+	}
+	return notFound(endpoint, "engine")
+}
+
+func (c *Client) CordonEngine(id string) error {
+	return c.setCordoned(id, true, "cordon-engine")
+}
+
+func (c *Client) UncordonEngine(id string) error {
+	return c.setCordoned(id, false, "uncordon-engine")
+}
+
+func (c *Client) setCordoned(id string, cordoned bool, evt string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.engines {
+		if e.ID == id {
+			e.Cordoned = cordoned
+			c.recordEvent(evt, nil, e.Engine, "")
+			return nil
+		}
+	}
+	return notFound("/api/engines/"+id, "engine")
+}
+
+// DrainEngine cordons the engine and moves its containers to the first
+// engine that is not cordoned
+func (c *Client) DrainEngine(id string) error {
+	if err := c.CordonEngine(id); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var drained *citadel.Engine
+	for _, e := range c.engines {
+		if e.ID == id {
+			drained = e.Engine
+		}
+	}
+	for _, cnt := range c.containers {
+		if drained == nil || cnt.Engine != drained {
+			continue
+		}
+		moved := false
+		for _, e := range c.engines {
+			if !e.Cordoned {
+				cnt.Engine = e.Engine
+				moved = true
+				break
+			}
+		}
+		if !moved {
+			return &shipyard.APIError{
+				StatusCode: http.StatusInternalServerError,
+				Method:     "GET",
+				Endpoint:   "/api/engines/" + id + "/drain",
+				Message:    "no engines available",
+			}
+		}
+	}
+	c.recordEvent("drain-engine", nil, drained, "")
+	return nil
+}
+
+func (c *Client) AddEngine(engine *shipyard.Engine) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if engine.ID == "" {
+		engine.ID = newID()
+	}
+	c.engines = append(c.engines, engine)
+	c.recordEvent("add-engine", nil, engine.Engine, "")
+	return nil
+}
+
+func (c *Client) RemoveEngine(engine *shipyard.Engine) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, e := range c.engines {
+		if e.ID == engine.ID {
+			c.engines = append(c.engines[:i], c.engines[i+1:]...)
+			c.recordEvent("remove-engine", nil, e.Engine, "")
+			return nil
+		}
+	}
+	return notFound("/api/engines/"+engine.ID, "engine")
+}
+
+func (c *Client) Version() (*shipyard.VersionInfo, error) {
+	return &shipyard.VersionInfo{
+		Version:     shipyard.VERSION,
+		APIVersion:  shipyard.APIVersion,
+		APIVersions: shipyard.SupportedAPIVersions,
+	}, nil
+}
+
+// Ping always succeeds immediately
+func (c *Client) Ping() (time.Duration, error) {
+	return 0, nil
+}
+
+func (c *Client) Info() (*shipyard.ClusterInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	info := &shipyard.ClusterInfo{
+		ContainerCount: len(c.containers),
+		EngineCount:    len(c.engines),
+		Placement:      shipyard.DefaultPlacement,
+	}
+	images := map[string]bool{}
+	for _, cnt := range c.containers {
+		if cnt.Image != nil {
+			images[cnt.Image.Name] = true
+			info.ReservedCpus += cnt.Image.Cpus
+			info.ReservedMemory += cnt.Image.Memory
+		}
+	}
+	info.ImageCount = len(images)
+	for _, e := range c.engines {
+		if e.Engine != nil {
+			info.Cpus += e.Engine.Cpus
+			info.Memory += e.Engine.Memory
+		}
+	}
+	return info, nil
+}
+
+func (c *Client) Usage() (*shipyard.ClusterUsage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	engines := []*citadel.Engine{}
+	for _, e := range c.engines {
+		if e.Engine != nil {
+			engines = append(engines, e.Engine)
+		}
+	}
+	return shipyard.ComputeUsage(engines, c.containers), nil
+}
+
+// Rebalance plans moves off over utilized engines with every uncordoned
+// engine accepting every container; moved containers keep their id
+func (c *Client) Rebalance(opts *shipyard.RebalanceOptions) ([]*shipyard.RebalanceMove, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if opts == nil {
+		opts = &shipyard.RebalanceOptions{}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/cluster/rebalance",
+			Message:    err.Error(),
+		}
+	}
+	engines := map[string]*citadel.Engine{}
+	loads := []*shipyard.EngineLoad{}
+	for _, e := range c.engines {
+		if e.Engine == nil || e.Cordoned {
+			continue
+		}
+		engines[e.Engine.ID] = e.Engine
+		loads = append(loads, &shipyard.EngineLoad{ID: e.Engine.ID, Cpus: e.Engine.Cpus, Memory: e.Engine.Memory})
+	}
+	containers := map[string]*citadel.Container{}
+	candidates := []*shipyard.RebalanceCandidate{}
+	for _, cnt := range c.containers {
+		if cnt.State == "stopped" || cnt.Engine == nil || engines[cnt.Engine.ID] == nil {
+			continue
+		}
+		for _, l := range loads {
+			if l.ID == cnt.Engine.ID {
+				l.ReservedCpus += cnt.Image.Cpus
+				l.ReservedMemory += cnt.Image.Memory
+			}
+		}
+		containers[cnt.ID] = cnt
+		candidates = append(candidates, &shipyard.RebalanceCandidate{
+			ID:     cnt.ID,
+			Image:  cnt.Image.Name,
+			Engine: cnt.Engine.ID,
+			Cpus:   cnt.Image.Cpus,
+			Memory: cnt.Image.Memory,
+		})
+	}
+	moves := shipyard.PlanRebalance(loads, candidates, opts, func(*shipyard.RebalanceCandidate, string) bool {
+		return true
+	})
+	if opts.DryRun {
+		return moves, nil
+	}
+	for _, move := range moves {
+		containers[move.Container].Engine = engines[move.To]
+		move.Replacement = move.Container
+	}
+	if len(moves) > 0 {
+		c.recordEvent("rebalance", nil, nil, fmt.Sprintf("moves=%d", len(moves)))
+	}
+	return moves, nil
+}
+
+// Snapshot returns the containers, engines, recent events and info
+func (c *Client) Snapshot() (*client.ClusterSnapshot, error) {
+	containers, err := c.Containers()
+	if err != nil {
+		return nil, err
+	}
+	engines, err := c.Engines()
+	if err != nil {
+		return nil, err
+	}
+	events, err := c.Events(&shipyard.EventQuery{Limit: client.SnapshotEvents})
+	if err != nil {
+		return nil, err
+	}
+	info, err := c.Info()
+	if err != nil {
+		return nil, err
+	}
+	return &client.ClusterSnapshot{
+		Containers: containers,
+		Engines:    engines,
+		Events:     events,
+		Info:       info,
+	}, nil
+}
+
+// Events returns recorded events newest first
+func (c *Client) Events(query *shipyard.EventQuery) ([]*shipyard.Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	scoped := shipyard.EventQuery{}
+	if query != nil {
+		scoped = *query
+	}
+	if c.Namespace != "" {
+		scoped.Namespace = c.Namespace
+	}
+	query = &scoped
+	events := []*shipyard.Event{}
+	for i := len(c.events) - 1; i >= 0; i-- {
+		e := c.events[i]
+		if !query.Match(e) {
+			continue
+		}
+		if !query.Since.IsZero() && e.Time.Before(query.Since) {
+			continue
+		}
+		if !query.Until.IsZero() && e.Time.After(query.Until) {
+			continue
+		}
+		events = append(events, e)
+	}
+	if query.Offset >= len(events) {
+		return []*shipyard.Event{}, nil
+	}
+	events = events[query.Offset:]
+	if query.Limit > 0 && query.Limit < len(events) {
+		events = events[:query.Limit]
+	}
+	return events, nil
+}
+
+// AuditLog returns entries added with AddAuditEntry newest first
+func (c *Client) AuditLog(filter *shipyard.AuditFilter) ([]*shipyard.AuditEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if filter == nil {
+		filter = &shipyard.AuditFilter{}
+	}
+	entries := []*shipyard.AuditEntry{}
+	for i := len(c.audit) - 1; i >= 0; i-- {
+		e := c.audit[i]
+		if filter.Username != "" && e.Username != filter.Username {
+			continue
+		}
+		if filter.Method != "" && e.Method != filter.Method {
+			continue
+		}
+		if filter.Endpoint != "" && !strings.HasPrefix(e.Endpoint, filter.Endpoint) {
+			continue
+		}
+		if !filter.Since.IsZero() && e.Time.Before(filter.Since) {
+			continue
+		}
+		if !filter.Until.IsZero() && e.Time.After(filter.Until) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if filter.Offset >= len(entries) {
+		return []*shipyard.AuditEntry{}, nil
+	}
+	entries = entries[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(entries) {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+// ExportAuditLog encodes the entries selected by filter as json or csv
+func (c *Client) ExportAuditLog(filter *shipyard.AuditFilter, format string) (io.ReadCloser, error) {
+	entries, err := c.AuditLog(filter)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]shipyard.CSVRecorder, len(entries))
+	for i, e := range entries {
+		records[i] = e
+	}
+	return export("/api/audit", format, shipyard.AuditCSVHeader, entries, records)
+}
+
+func (c *Client) ExportEvents(query *shipyard.EventQuery, format string) (io.ReadCloser, error) {
+	events, err := c.Events(query)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]shipyard.CSVRecorder, len(events))
+	for i, e := range events {
+		records[i] = e
+	}
+	return export("/api/events", format, shipyard.EventCSVHeader, events, records)
+}
+
+// export encodes a list as json or its records as an export format
+func export(endpoint string, format string, header []string, list interface{}, records []shipyard.CSVRecorder) (io.ReadCloser, error) {
+	buf := &bytes.Buffer{}
+	if format == "" || format == "json" {
+		if err := json.NewEncoder(buf).Encode(list); err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(buf), nil
+	}
+	ex, err := shipyard.NewExporter(buf, format, header)
+	if err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "GET",
+			Endpoint:   endpoint,
+			Message:    err.Error(),
+		}
+	}
+	for _, r := range records {
+		if err := ex.Export(r); err != nil {
+			return nil, err
+		}
+	}
+	if err := ex.Flush(); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(buf), nil
+}
+
+// StreamEvents delivers events recorded after the call that match filter.
+// The channel is closed by Close.
+func (c *Client) StreamEvents(filter *shipyard.EventFilter) (<-chan *shipyard.Event, error) {
+	src := make(chan *shipyard.Event, 64)
+	out := make(chan *shipyard.Event)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		close(out)
+		return out, nil
+	}
+	c.subscribers = append(c.subscribers, src)
+	c.mu.Unlock()
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case evt := <-src:
+				if !filter.Match(evt) {
+					continue
+				}
+				select {
+				case out <- evt:
+				case <-c.done:
+					return
+				}
+			case <-c.done:
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// Close ends the event streams of the client, like cancelling the context
+// of a client.Manager
+func (c *Client) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.subscribers = nil
+	close(c.done)
+}
+
+func (c *Client) Accounts() ([]*shipyard.Account, error) {
+	return c.QueryAccounts(nil)
+}
+
+func (c *Client) QueryAccounts(query *shipyard.AccountQuery) ([]*shipyard.Account, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if query == nil {
+		query = &shipyard.AccountQuery{}
+	}
+	accounts := []*shipyard.Account{}
+	for _, a := range c.accounts {
+		if query.Role == "" || (a.Role != nil && a.Role.Name == query.Role) {
+			accounts = append(accounts, a)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Username < accounts[j].Username })
+	if query.Offset >= len(accounts) {
+		return []*shipyard.Account{}, nil
+	}
+	accounts = accounts[query.Offset:]
+	if query.Limit > 0 && query.Limit < len(accounts) {
+		accounts = accounts[:query.Limit]
+	}
+	return accounts, nil
+}
+
+func (c *Client) AddAccount(account *shipyard.Account) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, a := range c.accounts {
+		if a.Username == account.Username {
+			c.passwords[a.Username] = account.Password
+			a.Role = account.Role
+			return nil
+		}
+	}
+	if account.ID == "" {
+		account.ID = newID()
+	}
+	c.passwords[account.Username] = account.Password
+	acct := *account
+	acct.Password = ""
+	c.accounts = append(c.accounts, &acct)
+	return nil
+}
+
+func (c *Client) UpdateAccount(account *shipyard.Account) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, a := range c.accounts {
+		if a.Username == account.Username {
+			if account.Role != nil {
+				role := c.findRole(account.Role.Name)
+				if role == nil {
+					return notFound("/api/accounts", "role")
+				}
+				a.Role = role
+			}
+			if account.Password != "" {
+				c.passwords[a.Username] = account.Password
+			}
+			a.FullName = account.FullName
+			a.Email = account.Email
+			return nil
+		}
+	}
+	return notFound("/api/accounts", "account")
+}
+
+func (c *Client) SetAccountRole(username string, role string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := c.findRole(role)
+	if r == nil {
+		return notFound("/api/accounts/"+username+"/role", "role")
+	}
+	for _, a := range c.accounts {
+		if a.Username == username {
+			a.Role = r
+			return nil
+		}
+	}
+	return notFound("/api/accounts/"+username+"/role", "account")
+}
+
+// findRole must be called with the lock held
+func (c *Client) findRole(name string) *shipyard.Role {
+	for _, r := range c.roles {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+func (c *Client) DeleteAccount(account *shipyard.Account) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, a := range c.accounts {
+		if a.Username == account.Username {
+			c.accounts = append(c.accounts[:i], c.accounts[i+1:]...)
+			delete(c.passwords, a.Username)
+			return nil
+		}
+	}
+	return notFound("/api/accounts", "account")
+}
+
+func (c *Client) Roles() ([]*shipyard.Role, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*shipyard.Role{}, c.roles...), nil
+}
+
+func (c *Client) Role(name string) (*shipyard.Role, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, r := range c.roles {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+	return nil, notFound("/api/roles/"+name, "role")
+}
+
+func (c *Client) CreateRole(role *shipyard.Role) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.findRole(role.Name) != nil {
+		return &shipyard.APIError{
+			StatusCode: http.StatusConflict,
+			Method:     "POST",
+			Endpoint:   "/api/roles",
+			Message:    "role already exists",
+		}
+	}
+	if err := validatePermissions(role.Permissions, "POST", "/api/roles"); err != nil {
+		return err
+	}
+	if role.ID == "" {
+		role.ID = newID()
+	}
+	c.roles = append(c.roles, role)
+	c.recordEvent("add-role", nil, nil, "name="+role.Name)
+	return nil
+}
+
+func (c *Client) UpdateRole(role *shipyard.Role) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	existing := c.findRole(role.Name)
+	if existing == nil {
+		return notFound("/api/roles", "role")
+	}
+	if err := validatePermissions(role.Permissions, "PUT", "/api/roles"); err != nil {
+		return err
+	}
+	existing.Permissions = role.Permissions
+	c.recordEvent("update-role", nil, nil, "name="+role.Name)
+	return nil
+}
+
+func (c *Client) DeleteRole(role *shipyard.Role) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, r := range c.roles {
+		if r.Name == role.Name {
+			c.roles = append(c.roles[:i], c.roles[i+1:]...)
+			c.recordEvent("delete-role", nil, nil, "name="+role.Name)
+			return nil
+		}
+	}
+	return notFound("/api/roles", "role")
+}
+
+func validatePermissions(permissions []string, method string, endpoint string) error {
+	for _, p := range permissions {
+		if err := shipyard.ValidatePermission(p); err != nil {
+			return &shipyard.APIError{
+				StatusCode: http.StatusBadRequest,
+				Method:     method,
+				Endpoint:   endpoint,
+				Message:    err.Error(),
+			}
+		}
+	}
+	return nil
+}
+
+// Login checks the password given to AddAccount; later calls act as the
+// logged in user
+func (c *Client) Login(username, password string) (*shipyard.AuthToken, error) {
+	return c.LoginWithOTP(username, password, "")
+}
+
+// LoginWithOTP is Login for accounts with two-factor auth enabled
+func (c *Client) LoginWithOTP(username, password, otp string) (*shipyard.AuthToken, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.passwords[username]; !ok || p != password {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusUnauthorized,
+			Method:     "POST",
+			Endpoint:   "/auth/login",
+		}
+	}
+	if t := c.totp[username]; t != nil && t.enabled {
+		msg := shipyard.ErrOTPRequired.Error()
+		if otp != "" {
+			msg = shipyard.ErrInvalidOTP.Error()
+		}
+		step, ok := shipyard.ValidateTOTP(t.secret, otp, time.Now())
+		if !ok || step <= t.lastStep {
+			return nil, &shipyard.APIError{
+				StatusCode: http.StatusForbidden,
+				Method:     "POST",
+				Endpoint:   "/auth/login",
+				Message:    msg,
+			}
+		}
+		t.lastStep = step
+	}
+	c.username = username
+	return &shipyard.AuthToken{Token: newID(), UserAgent: "shipyard-cli"}, nil
+}
+
+// RefreshToken returns a new token for the logged in user
+func (c *Client) RefreshToken() (*shipyard.AuthToken, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.username == "" {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusUnauthorized,
+			Method:     "POST",
+			Endpoint:   "/account/token",
+		}
+	}
+	return &shipyard.AuthToken{Token: newID(), UserAgent: "shipyard-cli"}, nil
+}
+
+// Logout ends the session started by Login
+func (c *Client) Logout() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.username == "" {
+		return &shipyard.APIError{
+			StatusCode: http.StatusUnauthorized,
+			Method:     "POST",
+			Endpoint:   "/account/logout",
+		}
+	}
+	c.username = ""
+	return nil
+}
+
+// totpState is the two-factor enrollment of an account
+type totpState struct {
+	secret   string
+	enabled  bool
+	lastStep int64
+}
+
+// Enable2FA starts two-factor enrollment for the logged in user
+func (c *Client) Enable2FA() (*shipyard.TOTPEnrollment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.check2FA("POST", "/account/2fa", false); err != nil {
+		return nil, err
+	}
+	secret, err := shipyard.NewTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	c.totp[c.username] = &totpState{secret: secret}
+	return &shipyard.TOTPEnrollment{
+		Secret: secret,
+		URI:    shipyard.TOTPURI("Shipyard", c.username, secret),
+	}, nil
+}
+
+// Confirm2FA requires codes at login once code matches the enrolled secret
+func (c *Client) Confirm2FA(code string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.check2FA("POST", "/account/2fa/confirm", false); err != nil {
+		return err
+	}
+	t := c.totp[c.username]
+	if t == nil {
+		return &shipyard.APIError{StatusCode: http.StatusConflict, Method: "POST", Endpoint: "/account/2fa/confirm", Message: "two-factor auth enrollment has not been started"}
+	}
+	step, ok := shipyard.ValidateTOTP(t.secret, code, time.Now())
+	if !ok {
+		return &shipyard.APIError{StatusCode: http.StatusBadRequest, Method: "POST", Endpoint: "/account/2fa/confirm", Message: "invalid one time code"}
+	}
+	t.enabled = true
+	t.lastStep = step
+	c.recordEvent("enable-2fa", nil, nil, fmt.Sprintf("username=%s", c.username))
+	return nil
+}
+
+// Disable2FA turns off two-factor auth for the logged in user
+func (c *Client) Disable2FA(code string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.check2FA("DELETE", "/account/2fa", true); err != nil {
+		return err
+	}
+	t := c.totp[c.username]
+	if step, ok := shipyard.ValidateTOTP(t.secret, code, time.Now()); !ok || step <= t.lastStep {
+		return &shipyard.APIError{StatusCode: http.StatusBadRequest, Method: "DELETE", Endpoint: "/account/2fa", Message: "invalid one time code"}
+	}
+	delete(c.totp, c.username)
+	c.recordEvent("disable-2fa", nil, nil, fmt.Sprintf("username=%s", c.username))
+	return nil
+}
+
+// check2FA requires a logged in user whose two-factor auth is enabled or
+// not as given
+func (c *Client) check2FA(method string, endpoint string, enabled bool) error {
+	if c.username == "" {
+		return &shipyard.APIError{StatusCode: http.StatusUnauthorized, Method: method, Endpoint: endpoint}
+	}
+	t := c.totp[c.username]
+	switch {
+	case enabled && (t == nil || !t.enabled):
+		return &shipyard.APIError{StatusCode: http.StatusConflict, Method: method, Endpoint: endpoint, Message: "two-factor auth is not enabled"}
+	case !enabled && t != nil && t.enabled:
+		return &shipyard.APIError{StatusCode: http.StatusConflict, Method: method, Endpoint: endpoint, Message: "two-factor auth is already enabled"}
+	}
+	return nil
+}
+
+func (c *Client) ChangePassword(password string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.username == "" {
+		return &shipyard.APIError{
+			StatusCode: http.StatusUnauthorized,
+			Method:     "POST",
+			Endpoint:   "/account/changepassword",
+		}
+	}
+	c.passwords[c.username] = password
+	return nil
+}
+
+func (c *Client) ServiceKeys() ([]*shipyard.ServiceKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	keys := []*shipyard.ServiceKey{}
+	for _, k := range c.serviceKeys {
+		k.ExpiresIn = int64(k.Remaining(now) / time.Second)
+		if c.Namespace == "" || k.Namespace == c.Namespace {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (c *Client) NewServiceKey(description string, ttl time.Duration, permissions []string) (*shipyard.ServiceKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ttl < 0 {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/servicekeys",
+			Message:    "service key lifetime must not be negative",
+		}
+	}
+	if err := validatePermissions(permissions, "POST", "/api/servicekeys"); err != nil {
+		return nil, err
+	}
+	k := &shipyard.ServiceKey{Key: newID(), Description: description, Permissions: permissions, Namespace: c.Namespace}
+	if ttl > 0 {
+		k.ExpiresAt = time.Now().Add(ttl)
+		k.ExpiresIn = int64(ttl / time.Second)
+	}
+	c.serviceKeys = append(c.serviceKeys, k)
+	return k, nil
+}
+
+func (c *Client) RemoveServiceKey(key *shipyard.ServiceKey) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, k := range c.serviceKeys {
+		if k.Key == key.Key {
+			c.serviceKeys = append(c.serviceKeys[:i], c.serviceKeys[i+1:]...)
+			return nil
+		}
+	}
+	return notFound("/api/servicekeys", "service key")
+}
+
+func (c *Client) Extensions() ([]*shipyard.Extension, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*shipyard.Extension{}, c.extensions...), nil
+}
+
+func (c *Client) AddExtension(ext *shipyard.Extension) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ext.ID == "" {
+		ext.ID = newID()
+	}
+	c.extensions = append(c.extensions, ext)
+	return nil
+}
+
+func (c *Client) RemoveExtension(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, e := range c.extensions {
+		if e.ID == id {
+			c.extensions = append(c.extensions[:i], c.extensions[i+1:]...)
+			return nil
+		}
+	}
+	return notFound("/api/extensions/"+id, "extension")
+}
+
+func (c *Client) WebhookKeys() ([]*dockerhub.WebhookKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*dockerhub.WebhookKey{}, c.webhookKeys...), nil
+}
+
+func (c *Client) NewWebhookKey(image string) (*dockerhub.WebhookKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := &dockerhub.WebhookKey{ID: newID(), Image: image, Key: newID()[:16]}
+	c.webhookKeys = append(c.webhookKeys, k)
+	return k, nil
+}
+
+func (c *Client) RemoveWebhookKey(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, k := range c.webhookKeys {
+		if k.ID == key || k.Key == key {
+			c.webhookKeys = append(c.webhookKeys[:i], c.webhookKeys[i+1:]...)
+			return nil
+		}
+	}
+	return notFound("/api/webhookkeys/"+key, "webhook key")
+}
+
+// ReconcileApplications does what the controller reconciler does
+// periodically: it replaces stopped application containers and scales
+// each application to its count
+func (c *Client) ReconcileApplications() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, app := range c.apps {
+		if err := c.reconcile(app); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcile must be called with the lock held
+func (c *Client) reconcile(app *shipyard.Application) error {
+	if app.Stopped {
+		return nil
+	}
+	running := []*citadel.Container{}
+	kept := []*citadel.Container{}
+	for _, cnt := range c.containers {
+		if shipyard.ApplicationName(cnt) != app.Name {
+			kept = append(kept, cnt)
+			continue
+		}
+		if cnt.State == "running" && len(running) < app.Count {
+			running = append(running, cnt)
+			kept = append(kept, cnt)
+			continue
+		}
+		c.recordEvent("destroy", cnt, cnt.Engine, "")
+	}
+	c.containers = kept
+	if len(running) < app.Count {
+		if _, err := c.launch(app.ContainerImage(), app.Count-len(running)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findApplication must be called with the lock held
+func (c *Client) findApplication(name string) *shipyard.Application {
+	for _, app := range c.apps {
+		if app.Name == name {
+			return app
+		}
+	}
+	return nil
+}
+
+func (c *Client) Applications() ([]*shipyard.Application, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*shipyard.Application{}, c.apps...), nil
+}
+
+func (c *Client) Application(name string) (*shipyard.Application, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	app := c.findApplication(name)
+	if app == nil {
+		return nil, notFound("/api/applications/"+name, "application")
+	}
+	return app, nil
+}
+
+func (c *Client) ApplicationContainers(name string) ([]*citadel.Container, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.findApplication(name) == nil {
+		return nil, notFound("/api/applications/"+name+"/containers", "application")
+	}
+	containers := []*citadel.Container{}
+	for _, cnt := range c.containers {
+		if shipyard.ApplicationName(cnt) == name {
+			containers = append(containers, cnt)
+		}
+	}
+	return containers, nil
+}
+
+// CreateApplication stores the application and launches its containers
+// right away
+func (c *Client) CreateApplication(app *shipyard.Application) (*shipyard.Application, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := app.Validate(); err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/applications",
+			Message:    err.Error(),
+		}
+	}
+	if c.findApplication(app.Name) != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusConflict,
+			Method:     "POST",
+			Endpoint:   "/api/applications",
+			Message:    "application already exists",
+		}
+	}
+	stored := *app
+	stored.ID = newID()
+	c.apps = append(c.apps, &stored)
+	c.recordEvent("create-application", nil, nil, "name="+stored.Name)
+	if err := c.reconcile(&stored); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// UpdateApplication replaces the application spec like the controller,
+// failing with a conflict on unconfirmed destructive changes
+func (c *Client) UpdateApplication(app *shipyard.Application, confirm bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	endpoint := "/api/applications/" + app.Name
+	current := c.findApplication(app.Name)
+	if current == nil {
+		return notFound(endpoint, "application")
+	}
+	if err := app.Validate(); err != nil {
+		return &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "PUT",
+			Endpoint:   endpoint,
+			Message:    err.Error(),
+		}
+	}
+	if changes := shipyard.DiffApplications(current, app).Destructive(); len(changes) > 0 && !confirm {
+		msg := []string{}
+		for _, ch := range changes {
+			msg = append(msg, ch.String())
+		}
+		return &shipyard.APIError{
+			StatusCode: http.StatusConflict,
+			Method:     "PUT",
+			Endpoint:   endpoint,
+			Message:    "destructive changes must be confirmed with confirm=true: " + strings.Join(msg, ", "),
+		}
+	}
+	id, stopped := current.ID, current.Stopped
+	*current = *app
+	current.ID = id
+	current.Stopped = stopped
+	c.recordEvent("update-application", nil, nil, "name="+app.Name)
+	return c.reconcile(current)
+}
+
+func (c *Client) DiffApplication(app *shipyard.Application) (*shipyard.ApplicationDiff, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	current := c.findApplication(app.Name)
+	if current == nil {
+		return nil, notFound("/api/applications/"+app.Name+"/diff", "application")
+	}
+	return shipyard.DiffApplications(current, app), nil
+}
+
+func (c *Client) RemoveApplication(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, app := range c.apps {
+		if app.Name == name {
+			c.apps = append(c.apps[:i], c.apps[i+1:]...)
+			removed := *app
+			removed.Count = 0
+			removed.Stopped = false
+			c.reconcile(&removed)
+			c.recordEvent("remove-application", nil, nil, "name="+name)
+			return nil
+		}
+	}
+	return notFound("/api/applications/"+name, "application")
+}
+
+// DeployCompose creates or updates an application per compose service and
+// removes the applications of services no longer in the file
+func (c *Client) DeployCompose(r io.Reader, project string) ([]*shipyard.Application, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	endpoint := "/api/applications/compose"
+	badRequest := func(err error) error {
+		return &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   endpoint,
+			Message:    err.Error(),
+		}
+	}
+	if err := shipyard.ValidateProjectName(project); err != nil {
+		return nil, badRequest(err)
+	}
+	services, err := shipyard.ParseCompose(r)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	apps := []*shipyard.Application{}
+	names := map[string]bool{}
+	for _, s := range services {
+		app := s.Application(project)
+		if err := app.Validate(); err != nil {
+			return nil, badRequest(err)
+		}
+		current := c.findApplication(app.Name)
+		switch {
+		case current == nil:
+			stored := *app
+			stored.ID = newID()
+			c.apps = append(c.apps, &stored)
+			c.recordEvent("create-application", nil, nil, "name="+stored.Name)
+			current = &stored
+		case current.Project != project:
+			return nil, &shipyard.APIError{
+				StatusCode: http.StatusConflict,
+				Method:     "POST",
+				Endpoint:   endpoint,
+				Message:    fmt.Sprintf("application already exists: %s is not part of project %s", app.Name, project),
+			}
+		default:
+			id, count := current.ID, current.Count
+			*current = *app
+			current.ID, current.Count = id, count
+			c.recordEvent("update-application", nil, nil, "name="+app.Name)
+		}
+		if err := c.reconcile(current); err != nil {
+			return nil, err
+		}
+		names[app.Name] = true
+		deployed := *current
+		apps = append(apps, &deployed)
+	}
+	kept := []*shipyard.Application{}
+	for _, app := range c.apps {
+		if app.Project == project && !names[app.Name] {
+			removed := *app
+			removed.Count = 0
+			c.reconcile(&removed)
+			c.recordEvent("remove-application", nil, nil, "name="+app.Name)
+			continue
+		}
+		kept = append(kept, app)
+	}
+	c.apps = kept
+	c.recordEvent("deploy-compose", nil, nil, fmt.Sprintf("project=%s services=%d", project, len(apps)))
+	return apps, nil
+}
+
+// Deploy replaces the application containers with containers of image at
+// once; the returned deployment has already succeeded
+func (c *Client) Deploy(name string, image string, opts *shipyard.DeployOptions) (*shipyard.Deployment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	endpoint := "/api/applications/" + name + "/deploy"
+	app := c.findApplication(name)
+	if app == nil {
+		return nil, notFound(endpoint, "application")
+	}
+	if opts == nil {
+		opts = &shipyard.DeployOptions{}
+	}
+	if err := opts.Validate(); err != nil || image == "" {
+		msg := "image is required"
+		if err != nil {
+			msg = err.Error()
+		}
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   endpoint,
+			Message:    msg,
+		}
+	}
+	spec := *app
+	spec.Image = image
+	return c.deploy(app, &spec, opts, 0)
+}
+
+// Rollback redeploys the application with the spec of a previous revision
+// at once; the returned deployment has already succeeded
+func (c *Client) Rollback(name string, revision int, opts *shipyard.DeployOptions) (*shipyard.Deployment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	endpoint := "/api/applications/" + name + "/rollback"
+	app := c.findApplication(name)
+	if app == nil {
+		return nil, notFound(endpoint, "application")
+	}
+	if opts == nil {
+		opts = &shipyard.DeployOptions{}
+	}
+	target, err := shipyard.RollbackRevision(c.revisions(app), revision)
+	if err == nil {
+		err = opts.Validate()
+	}
+	if err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   endpoint,
+			Message:    err.Error(),
+		}
+	}
+	spec := *target.Spec
+	return c.deploy(app, &spec, opts, target.Revision)
+}
+
+// deploy must be called with the lock held
+func (c *Client) deploy(app *shipyard.Application, spec *shipyard.Application, opts *shipyard.DeployOptions, rollbackTo int) (*shipyard.Deployment, error) {
+	history := c.revisions(app)
+	spec.ID, spec.Name, spec.Count, spec.Owner, spec.Stopped = app.ID, app.Name, app.Count, app.Owner, app.Stopped
+	now := time.Now()
+	d := &shipyard.Deployment{
+		ID:            newID(),
+		Application:   app.Name,
+		Image:         spec.Image,
+		PreviousImage: app.Image,
+		Options:       *opts,
+		Status:        shipyard.DeploymentSucceeded,
+		Updated:       app.Count,
+		Total:         app.Count,
+		Started:       now,
+		Finished:      now,
+		Revision:      len(history) + 1,
+		Spec:          spec,
+		RollbackTo:    rollbackTo,
+	}
+	removed := *app
+	removed.Count = 0
+	if err := c.reconcile(&removed); err != nil {
+		return nil, err
+	}
+	deployed := *spec
+	*app = deployed
+	if err := c.reconcile(app); err != nil {
+		return nil, err
+	}
+	c.deployments = append(c.deployments, d)
+	c.recordEvent("deploy", nil, nil, "application="+app.Name+" image="+spec.Image)
+	stored := *d
+	return &stored, nil
+}
+
+// revisions returns the deployments of the application, recording its spec
+// as a revision first when it is not that of the last revision like the
+// controller; it must be called with the lock held
+func (c *Client) revisions(app *shipyard.Application) []*shipyard.Deployment {
+	history := []*shipyard.Deployment{}
+	var current *shipyard.Deployment
+	for _, d := range c.deployments {
+		if d.Application != app.Name {
+			continue
+		}
+		history = append(history, d)
+		if d.Status == shipyard.DeploymentSucceeded && d.Spec != nil {
+			current = d
+		}
+	}
+	if current != nil && sameSpec(current.Spec, app) {
+		return history
+	}
+	spec := *app
+	now := time.Now()
+	d := &shipyard.Deployment{
+		ID:          newID(),
+		Application: app.Name,
+		Image:       app.Image,
+		Status:      shipyard.DeploymentSucceeded,
+		Updated:     app.Count,
+		Total:       app.Count,
+		Message:     "recorded the spec in use",
+		Started:     now,
+		Finished:    now,
+		Revision:    len(history) + 1,
+		Spec:        &spec,
+	}
+	c.deployments = append(c.deployments, d)
+	return append(history, d)
+}
+
+func sameSpec(a *shipyard.Application, b *shipyard.Application) bool {
+	x, y := *a, *b
+	x.ID, x.Count, x.Owner, x.Stopped = "", 0, "", false
+	y.ID, y.Count, y.Owner, y.Stopped = "", 0, "", false
+	bx, errx := json.Marshal(&x)
+	by, erry := json.Marshal(&y)
+	return errx == nil && erry == nil && bytes.Equal(bx, by)
+}
+
+func (c *Client) Deployments(name string) ([]*shipyard.Deployment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	deployments := []*shipyard.Deployment{}
+	for i := len(c.deployments) - 1; i >= 0; i-- {
+		if d := c.deployments[i]; name == "" || d.Application == name {
+			deployments = append(deployments, d)
+		}
+	}
+	return deployments, nil
+}
+
+func (c *Client) Deployment(id string) (*shipyard.Deployment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, d := range c.deployments {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return nil, notFound("/api/deployments/"+id, "deployment")
+}
+
+// PromoteDeployment fails with a conflict for existing deployments: the
+// deployments of the fake finish at once and never bake
+func (c *Client) PromoteDeployment(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	endpoint := "/api/deployments/" + id + "/promote"
+	for _, d := range c.deployments {
+		if d.ID == id {
+			return &shipyard.APIError{
+				StatusCode: http.StatusConflict,
+				Method:     "POST",
+				Endpoint:   endpoint,
+				Message:    "deployment is not waiting to be promoted",
+			}
+		}
+	}
+	return notFound(endpoint, "deployment")
+}
+
+func (c *Client) Jobs() ([]*shipyard.Job, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*shipyard.Job{}, c.jobs...), nil
+}
+
+func (c *Client) Job(id string) (*shipyard.Job, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	job := c.findJob(id)
+	if job == nil {
+		return nil, notFound("/api/jobs/"+id, "job")
+	}
+	return job, nil
+}
+
+// CreateJob stores the job; its containers are only launched by RunJob
+func (c *Client) CreateJob(schedule string, image *citadel.Image, count int) (*shipyard.Job, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	job := &shipyard.Job{
+		ID:       newID(),
+		Schedule: schedule,
+		Image:    image,
+		Count:    count,
+		Created:  time.Now(),
+	}
+	if err := job.Validate(); err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/jobs",
+			Message:    err.Error(),
+		}
+	}
+	sched, _ := shipyard.ParseCronSchedule(schedule)
+	job.NextRun = sched.Next(job.Created)
+	c.jobs = append(c.jobs, job)
+	c.recordEvent("create-job", nil, nil, "id="+job.ID)
+	stored := *job
+	return &stored, nil
+}
+
+func (c *Client) RemoveJob(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, job := range c.jobs {
+		if job.ID == id {
+			c.jobs = append(c.jobs[:i], c.jobs[i+1:]...)
+			runs := []*shipyard.JobRun{}
+			for _, run := range c.jobRuns {
+				if run.JobID != id {
+					runs = append(runs, run)
+				}
+			}
+			c.jobRuns = runs
+			c.recordEvent("remove-job", nil, nil, "id="+id)
+			return nil
+		}
+	}
+	return notFound("/api/jobs/"+id, "job")
+}
+
+func (c *Client) JobRuns(jobID string) ([]*shipyard.JobRun, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.findJob(jobID) == nil {
+		return nil, notFound("/api/jobs/"+jobID+"/runs", "job")
+	}
+	runs := []*shipyard.JobRun{}
+	for i := len(c.jobRuns) - 1; i >= 0; i-- {
+		if run := c.jobRuns[i]; run.JobID == jobID {
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+// RunJob launches the containers of a job as if its schedule were due and
+// returns the run, which stays running
+func (c *Client) RunJob(id string) (*shipyard.JobRun, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	job := c.findJob(id)
+	if job == nil {
+		return nil, notFound("/api/jobs/"+id, "job")
+	}
+	now := time.Now()
+	run := &shipyard.JobRun{
+		ID:        newID(),
+		JobID:     id,
+		Scheduled: now,
+		Started:   now,
+		Status:    shipyard.JobRunRunning,
+		ExitCodes: map[string]int{},
+	}
+	img := *job.Image
+	img.Environment = map[string]string{}
+	for k, v := range job.Image.Environment {
+		img.Environment[k] = v
+	}
+	img.Environment[shipyard.JobRunEnv] = run.ID
+	launched, err := c.launch(&img, job.Count)
+	if err != nil {
+		return nil, err
+	}
+	for _, cnt := range launched {
+		run.Containers = append(run.Containers, cnt.ID)
+	}
+	job.LastRun = now
+	c.jobRuns = append(c.jobRuns, run)
+	c.recordEvent("job-run", nil, nil, "job="+id+" run="+run.ID)
+	stored := *run
+	return &stored, nil
+}
+
+// findJob must be called with the lock held
+func (c *Client) findJob(id string) *shipyard.Job {
+	for _, job := range c.jobs {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// Secrets returns the stored secrets without their values
+func (c *Client) Secrets() ([]*shipyard.Secret, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	secrets := []*shipyard.Secret{}
+	for _, s := range c.secrets {
+		listed := *s
+		listed.Value = ""
+		secrets = append(secrets, &listed)
+	}
+	return secrets, nil
+}
+
+func (c *Client) Secret(name string) (*shipyard.Secret, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.secrets {
+		if s.Name == name {
+			secret := *s
+			return &secret, nil
+		}
+	}
+	return nil, notFound("/api/secrets/"+name+"/value", "secret")
+}
+
+func (c *Client) CreateSecret(name string, value string) (*shipyard.Secret, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	secret := &shipyard.Secret{
+		ID:      newID(),
+		Name:    name,
+		Value:   value,
+		Created: time.Now(),
+	}
+	if err := secret.Validate(); err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/secrets",
+			Message:    err.Error(),
+		}
+	}
+	for _, s := range c.secrets {
+		if s.Name == name {
+			return nil, &shipyard.APIError{
+				StatusCode: http.StatusConflict,
+				Method:     "POST",
+				Endpoint:   "/api/secrets",
+				Message:    "secret already exists",
+			}
+		}
+	}
+	c.secrets = append(c.secrets, secret)
+	c.recordEvent("create-secret", nil, nil, "name="+name)
+	created := *secret
+	created.Value = ""
+	return &created, nil
+}
+
+func (c *Client) DeleteSecret(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, s := range c.secrets {
+		if s.Name == name {
+			c.secrets = append(c.secrets[:i], c.secrets[i+1:]...)
+			c.recordEvent("delete-secret", nil, nil, "name="+name)
+			return nil
+		}
+	}
+	return notFound("/api/secrets/"+name, "secret")
+}
+
+func (c *Client) Configs() ([]*shipyard.ConfigBundle, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*shipyard.ConfigBundle{}, c.configs...), nil
+}
+
+func (c *Client) Config(name string) (*shipyard.ConfigBundle, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	bundle := c.findConfig(name)
+	if bundle == nil {
+		return nil, notFound("/api/configs/"+name, "config")
+	}
+	return bundle, nil
+}
+
+func (c *Client) CreateConfig(bundle *shipyard.ConfigBundle) (*shipyard.ConfigBundle, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := bundle.Validate(); err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/configs",
+			Message:    err.Error(),
+		}
+	}
+	if c.findConfig(bundle.Name) != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusConflict,
+			Method:     "POST",
+			Endpoint:   "/api/configs",
+			Message:    "config already exists",
+		}
+	}
+	stored := *bundle
+	stored.ID = newID()
+	stored.Created = time.Now()
+	stored.Updated = stored.Created
+	c.configs = append(c.configs, &stored)
+	c.recordEvent("create-config", nil, nil, "name="+stored.Name)
+	created := stored
+	return &created, nil
+}
+
+func (c *Client) UpdateConfig(bundle *shipyard.ConfigBundle) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	endpoint := "/api/configs/" + bundle.Name
+	current := c.findConfig(bundle.Name)
+	if current == nil {
+		return notFound(endpoint, "config")
+	}
+	if err := bundle.Validate(); err != nil {
+		return &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "PUT",
+			Endpoint:   endpoint,
+			Message:    err.Error(),
+		}
+	}
+	current.Data = bundle.Data
+	current.Files = bundle.Files
+	current.Updated = time.Now()
+	c.recordEvent("update-config", nil, nil, "name="+bundle.Name)
+	return nil
+}
+
+func (c *Client) DeleteConfig(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, bundle := range c.configs {
+		if bundle.Name == name {
+			c.configs = append(c.configs[:i], c.configs[i+1:]...)
+			c.recordEvent("delete-config", nil, nil, "name="+name)
+			return nil
+		}
+	}
+	return notFound("/api/configs/"+name, "config")
+}
+
+// findConfig must be called with the lock held
+func (c *Client) findConfig(name string) *shipyard.ConfigBundle {
+	for _, bundle := range c.configs {
+		if bundle.Name == name {
+			return bundle
+		}
+	}
+	return nil
+}
+
+func (c *Client) Templates() ([]*shipyard.LaunchTemplate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*shipyard.LaunchTemplate{}, c.templates...), nil
+}
+
+func (c *Client) Template(name string) (*shipyard.LaunchTemplate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := c.findTemplate(name)
+	if t == nil {
+		return nil, notFound("/api/templates/"+name, "template")
+	}
+	return t, nil
+}
+
+func (c *Client) CreateTemplate(t *shipyard.LaunchTemplate) (*shipyard.LaunchTemplate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := t.Validate(); err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/templates",
+			Message:    err.Error(),
+		}
+	}
+	if c.findTemplate(t.Name) != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusConflict,
+			Method:     "POST",
+			Endpoint:   "/api/templates",
+			Message:    "template already exists",
+		}
+	}
+	stored := *t
+	stored.ID = newID()
+	stored.Created = time.Now()
+	stored.Updated = stored.Created
+	c.templates = append(c.templates, &stored)
+	c.recordEvent("create-template", nil, nil, "name="+stored.Name)
+	created := stored
+	return &created, nil
+}
+
+func (c *Client) UpdateTemplate(t *shipyard.LaunchTemplate) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	endpoint := "/api/templates/" + t.Name
+	current := c.findTemplate(t.Name)
+	if current == nil {
+		return notFound(endpoint, "template")
+	}
+	if err := t.Validate(); err != nil {
+		return &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "PUT",
+			Endpoint:   endpoint,
+			Message:    err.Error(),
+		}
+	}
+	updated := *t
+	updated.ID = current.ID
+	updated.Created = current.Created
+	updated.Updated = time.Now()
+	*current = updated
+	c.recordEvent("update-template", nil, nil, "name="+t.Name)
+	return nil
+}
+
+func (c *Client) DeleteTemplate(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, t := range c.templates {
+		if t.Name == name {
+			c.templates = append(c.templates[:i], c.templates[i+1:]...)
+			c.recordEvent("delete-template", nil, nil, "name="+name)
+			return nil
+		}
+	}
+	return notFound("/api/templates/"+name, "template")
+}
+
+// RunTemplate runs the image of a template like Run
+func (c *Client) RunTemplate(name string, overrides *shipyard.TemplateOverrides, count int, pull bool) ([]*citadel.Container, error) {
+	endpoint := "/api/templates/" + name + "/run"
+	c.mu.Lock()
+	t := c.findTemplate(name)
+	c.mu.Unlock()
+	if t == nil {
+		return nil, notFound(endpoint, "template")
+	}
+	image, err := t.ContainerImage(overrides)
+	if err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   endpoint,
+			Message:    err.Error(),
+		}
+	}
+	return c.Run(image, count, pull)
+}
+
+// findTemplate must be called with the lock held
+func (c *Client) findTemplate(name string) *shipyard.LaunchTemplate {
+	for _, t := range c.templates {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func (c *Client) Volumes() ([]*shipyard.Volume, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*shipyard.Volume{}, c.volumes...), nil
+}
+
+func (c *Client) CreateVolume(volume *shipyard.Volume) (*shipyard.Volume, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stored := *volume
+	err := stored.Validate()
+	if err == nil {
+		eng := c.findEngine(stored.Engine)
+		for _, e := range c.engines {
+			if eng == nil && e.Engine != nil && e.Engine.ID == stored.Engine {
+				eng = e
+			}
+		}
+		if eng == nil {
+			err = fmt.Errorf("%w: unknown engine %s", shipyard.ErrInvalidVolume, stored.Engine)
+		} else {
+			stored.Engine = eng.ID
+		}
+	}
+	if err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/volumes",
+			Message:    err.Error(),
+		}
+	}
+	for _, v := range c.volumes {
+		if v.Name == stored.Name {
+			return nil, &shipyard.APIError{
+				StatusCode: http.StatusConflict,
+				Method:     "POST",
+				Endpoint:   "/api/volumes",
+				Message:    "volume already exists",
+			}
+		}
+	}
+	stored.ID = newID()
+	stored.Created = time.Now()
+	c.volumes = append(c.volumes, &stored)
+	c.recordEvent("create-volume", nil, nil, fmt.Sprintf("name=%s engine=%s path=%s", stored.Name, stored.Engine, stored.Path))
+	created := stored
+	return &created, nil
+}
+
+func (c *Client) RemoveVolume(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	endpoint := "/api/volumes/" + name
+	for i, v := range c.volumes {
+		if v.Name != name {
+			continue
+		}
+		for _, ctr := range c.containers {
+			mounts, _ := shipyard.VolumeMounts(ctr.Image)
+			for _, m := range mounts {
+				if m.Name == name {
+					return &shipyard.APIError{
+						StatusCode: http.StatusConflict,
+						Method:     "DELETE",
+						Endpoint:   endpoint,
+						Message:    "volume is used by containers",
+					}
+				}
+			}
+		}
+		c.volumes = append(c.volumes[:i], c.volumes[i+1:]...)
+		c.recordEvent("remove-volume", nil, nil, fmt.Sprintf("name=%s engine=%s", v.Name, v.Engine))
+		return nil
+	}
+	return notFound(endpoint, "volume")
+}
+
+func (c *Client) Quotas() ([]*shipyard.QuotaUsage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	usage := []*shipyard.QuotaUsage{}
+	for _, q := range c.quotas {
+		usage = append(usage, c.quotaUsage(q))
+	}
+	return usage, nil
+}
+
+func (c *Client) CreateQuota(quota *shipyard.Quota) (*shipyard.Quota, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := quota.Validate(); err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/quotas",
+			Message:    err.Error(),
+		}
+	}
+	for _, q := range c.quotas {
+		if q.Name == quota.Name {
+			return nil, &shipyard.APIError{
+				StatusCode: http.StatusConflict,
+				Method:     "POST",
+				Endpoint:   "/api/quotas",
+				Message:    "quota already exists",
+			}
+		}
+	}
+	stored := *quota
+	stored.ID = newID()
+	stored.Created = time.Now()
+	c.quotas = append(c.quotas, &stored)
+	c.recordEvent("create-quota", nil, nil, fmt.Sprintf("name=%s account=%s role=%s namespace=%s", stored.Name, stored.Account, stored.Role, stored.Namespace))
+	created := stored
+	return &created, nil
+}
+
+func (c *Client) DeleteQuota(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, q := range c.quotas {
+		if q.Name == name {
+			c.quotas = append(c.quotas[:i], c.quotas[i+1:]...)
+			c.recordEvent("delete-quota", nil, nil, "name="+name)
+			return nil
+		}
+	}
+	return notFound("/api/quotas/"+name, "quota")
+}
+
+func (c *Client) Namespaces() ([]*shipyard.Namespace, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*shipyard.Namespace{}, c.namespaces...), nil
+}
+
+func (c *Client) CreateNamespace(ns *shipyard.Namespace) (*shipyard.Namespace, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := ns.Validate(); err != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/namespaces",
+			Message:    err.Error(),
+		}
+	}
+	if c.findNamespace(ns.Name) != nil {
+		return nil, &shipyard.APIError{
+			StatusCode: http.StatusConflict,
+			Method:     "POST",
+			Endpoint:   "/api/namespaces",
+			Message:    "namespace already exists",
+		}
+	}
+	stored := *ns
+	stored.ID = newID()
+	stored.Created = time.Now()
+	c.namespaces = append(c.namespaces, &stored)
+	c.recordEvent("create-namespace", nil, nil, "name="+stored.Name)
+	created := stored
+	return &created, nil
+}
+
+func (c *Client) DeleteNamespace(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	endpoint := "/api/namespaces/" + name
+	for i, ns := range c.namespaces {
+		if ns.Name != name {
+			continue
+		}
+		for _, cnt := range c.containers {
+			if shipyard.ContainerNamespace(cnt) == name {
+				return &shipyard.APIError{
+					StatusCode: http.StatusConflict,
+					Method:     "DELETE",
+					Endpoint:   endpoint,
+					Message:    "namespace has containers",
+				}
+			}
+		}
+		c.namespaces = append(c.namespaces[:i], c.namespaces[i+1:]...)
+		for _, a := range c.accounts {
+			delete(a.NamespaceRoles, name)
+		}
+		c.recordEvent("delete-namespace", nil, nil, "name="+name)
+		return nil
+	}
+	return notFound(endpoint, "namespace")
+}
+
+func (c *Client) EnginePools() ([]*shipyard.EnginePool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pools := []*shipyard.EnginePool{}
+	for _, p := range c.pools {
+		pools = append(pools, c.enginePool(p))
+	}
+	return pools, nil
+}
+
+func (c *Client) EnginePool(name string) (*shipyard.EnginePool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range c.pools {
+		if p.Name == name {
+			return c.enginePool(p), nil
+		}
+	}
+	return nil, notFound("/api/pools/"+name, "engine pool")
+}
+
+// enginePool returns a copy of a stored pool with its engines
+func (c *Client) enginePool(p *shipyard.EnginePool) *shipyard.EnginePool {
+	pool := *p
+	pool.Engines = []string{}
+	for _, e := range c.engines {
+		if e.Engine != nil && pool.Match(e.Engine.Labels) {
+			pool.Engines = append(pool.Engines, e.Engine.ID)
+		}
+	}
+	return &pool
+}
+
+func (c *Client) CreateEnginePool(pool *shipyard.EnginePool) (*shipyard.EnginePool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	apiErr := func(status int, msg string) error {
+		return &shipyard.APIError{StatusCode: status, Method: "POST", Endpoint: "/api/pools", Message: msg}
+	}
+	if err := pool.Validate(); err != nil {
+		return nil, apiErr(http.StatusBadRequest, err.Error())
+	}
+	for _, p := range c.pools {
+		if p.Name == pool.Name {
+			return nil, apiErr(http.StatusConflict, "engine pool already exists")
+		}
+	}
+	stored := *pool
+	stored.ID = newID()
+	stored.Engines = nil
+	c.pools = append(c.pools, &stored)
+	c.recordEvent("create-engine-pool", nil, nil, "name="+stored.Name)
+	return c.enginePool(&stored), nil
+}
+
+func (c *Client) UpdateEnginePool(pool *shipyard.EnginePool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	endpoint := "/api/pools/" + pool.Name
+	for _, p := range c.pools {
+		if p.Name != pool.Name {
+			continue
+		}
+		if err := pool.Validate(); err != nil {
+			return &shipyard.APIError{
+				StatusCode: http.StatusBadRequest,
+				Method:     "PUT",
+				Endpoint:   endpoint,
+				Message:    err.Error(),
+			}
+		}
+		p.Description = pool.Description
+		p.Constraints = pool.Constraints
+		c.recordEvent("update-engine-pool", nil, nil, "name="+p.Name)
+		return nil
+	}
+	return notFound(endpoint, "engine pool")
+}
+
+func (c *Client) DeleteEnginePool(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	endpoint := "/api/pools/" + name
+	for i, p := range c.pools {
+		if p.Name != name {
+			continue
+		}
+		for _, app := range c.apps {
+			if app.Pool == name {
+				return &shipyard.APIError{
+					StatusCode: http.StatusConflict,
+					Method:     "DELETE",
+					Endpoint:   endpoint,
+					Message:    "engine pool is used by applications",
+				}
+			}
+		}
+		c.pools = append(c.pools[:i], c.pools[i+1:]...)
+		c.recordEvent("delete-engine-pool", nil, nil, "name="+name)
+		return nil
+	}
+	return notFound(endpoint, "engine pool")
+}
+
+func (c *Client) GCPolicy() (*shipyard.GCPolicy, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	policy := *c.gcPolicy
+	return &policy, nil
+}
+
+func (c *Client) SetGCPolicy(policy *shipyard.GCPolicy) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stored := *policy
+	if err := stored.Validate(); err != nil {
+		return &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "PUT",
+			Endpoint:   "/api/gc/policy",
+			Message:    err.Error(),
+		}
+	}
+	c.gcPolicy = &stored
+	c.recordEvent("update-gc-policy", nil, nil, fmt.Sprintf("enabled=%v", stored.Enabled))
+	return nil
+}
+
+// RunGC collects stopped containers outside of applications, compose
+// projects and jobs.  The fake does not track exit times so every stopped
+// container is old enough.
+func (c *Client) RunGC() (*shipyard.GCResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := &shipyard.GCResult{
+		Started:    time.Now(),
+		Containers: []string{},
+		Images:     []string{},
+	}
+	candidates := []*shipyard.GCCandidate{}
+	for _, cnt := range c.containers {
+		if cnt.State != "stopped" || shipyard.GCExempt(cnt) {
+			continue
+		}
+		candidates = append(candidates, &shipyard.GCCandidate{ID: cnt.ID, Image: cnt.Image.Name})
+	}
+	for _, id := range c.gcPolicy.Collect(candidates, time.Now()) {
+		for i, cnt := range c.containers {
+			if cnt.ID == id {
+				c.containers = append(c.containers[:i], c.containers[i+1:]...)
+				break
+			}
+		}
+		result.Containers = append(result.Containers, id)
+	}
+	c.recordEvent("gc", nil, nil, fmt.Sprintf("containers=%d images=%d errors=0", len(result.Containers), len(result.Images)))
+	return result, nil
+}
+
+func (c *Client) EventRetention() (*shipyard.EventRetention, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.retention.Redacted(), nil
+}
+
+func (c *Client) SetEventRetention(policy *shipyard.EventRetention) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stored := *policy
+	if stored.Archive != nil {
+		archive := *stored.Archive
+		if archive.SecretKey == "" && c.retention.Archive != nil {
+			archive.SecretKey = c.retention.Archive.SecretKey
+		}
+		stored.Archive = &archive
+	}
+	if err := stored.Validate(); err != nil {
+		return &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "PUT",
+			Endpoint:   "/api/events/retention",
+			Message:    err.Error(),
+		}
+	}
+	c.retention = &stored
+	c.recordEvent("update-event-retention", nil, nil, fmt.Sprintf("enabled=%v max_age=%dh max_count=%d", stored.Enabled, stored.MaxAge, stored.MaxCount))
+	return nil
+}
+
+// ApplyEventRetention removes the expired events.  The fake does not
+// upload archives; the objects are named as the controller names them.
+func (c *Client) ApplyEventRetention() (*shipyard.EventRetentionResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := &shipyard.EventRetentionResult{Started: time.Now()}
+	cutoff := c.retention.Cutoff(result.Started)
+	// events are kept oldest first
+	if n := c.retention.MaxCount; n > 0 && len(c.events) > n {
+		if t := c.events[len(c.events)-n-1].Time; t.After(cutoff) {
+			cutoff = t
+		}
+	}
+	if cutoff.IsZero() {
+		return result, nil
+	}
+	kept := []*shipyard.Event{}
+	expired := []*shipyard.Event{}
+	for _, e := range c.events {
+		if e.Time.After(cutoff) {
+			kept = append(kept, e)
+		} else {
+			expired = append(expired, e)
+		}
+	}
+	if len(expired) == 0 {
+		return result, nil
+	}
+	c.events = kept
+	result.Removed = len(expired)
+	if c.retention.Archive != nil {
+		result.Objects = append(result.Objects, c.retention.Archive.ObjectName(expired[0].Time, len(expired)))
+	}
+	c.recordEvent("expire-events", nil, nil, fmt.Sprintf("removed=%d archives=%d", result.Removed, len(result.Objects)))
+	return result, nil
+}
+
+// backupTables are the state of the test client in backups, keyed by the
+// tables of the controller
+func (c *Client) backupTables() map[string]interface{} {
+	return map[string]interface{}{
+		"config":         &c.engines,
+		"engine_pools":   &c.pools,
+		"accounts":       &c.accounts,
+		"roles":          &c.roles,
+		"service_keys":   &c.serviceKeys,
+		"webhook_keys":   &c.webhookKeys,
+		"extensions":     &c.extensions,
+		"registries":     &c.registries,
+		"webhooks":       &c.webhooks,
+		"notifiers":      &c.notifiers,
+		"applications":   &c.apps,
+		"deployments":    &c.deployments,
+		"jobs":           &c.jobs,
+		"secrets":        &c.secrets,
+		"config_bundles": &c.configs,
+		"volumes":        &c.volumes,
+		"quotas":         &c.quotas,
+		"namespaces":     &c.namespaces,
+	}
+}
+
+func (c *Client) Backup() (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tables := c.backupTables()
+	header := &shipyard.BackupHeader{
+		Format:  shipyard.BackupFormat,
+		Version: shipyard.VERSION,
+		Created: time.Now(),
+	}
+	for tbl := range tables {
+		header.Tables = append(header.Tables, tbl)
+	}
+	sort.Strings(header.Tables)
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	if err := enc.Encode(header); err != nil {
+		return nil, err
+	}
+	for _, tbl := range header.Tables {
+		b, err := json.Marshal(tables[tbl])
+		if err != nil {
+			return nil, err
+		}
+		docs := []json.RawMessage{}
+		if err := json.Unmarshal(b, &docs); err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			if err := enc.Encode(&shipyard.BackupRecord{Table: tbl, Document: doc}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ioutil.NopCloser(buf), nil
+}
+
+func (c *Client) Restore(r io.Reader) error {
+	header, records, err := shipyard.ReadBackup(r)
+	if err != nil {
+		return &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   "/api/backup/restore",
+			Message:    err.Error(),
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tables := c.backupTables()
+	docs := map[string][]json.RawMessage{}
+	for _, tbl := range header.Tables {
+		if tables[tbl] == nil {
+			return &shipyard.APIError{
+				StatusCode: http.StatusBadRequest,
+				Method:     "POST",
+				Endpoint:   "/api/backup/restore",
+				Message:    "unknown table " + tbl,
+			}
+		}
+		docs[tbl] = []json.RawMessage{}
+	}
+	for _, rec := range records {
+		docs[rec.Table] = append(docs[rec.Table], rec.Document)
+	}
+	for tbl, d := range docs {
+		b, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		// decode into a new slice so tables are replaced, not merged
+		v := reflect.New(reflect.TypeOf(tables[tbl]).Elem())
+		if err := json.Unmarshal(b, v.Interface()); err != nil {
+			return err
+		}
+		reflect.ValueOf(tables[tbl]).Elem().Set(v.Elem())
+	}
+	c.recordEvent("restore-backup", nil, nil, fmt.Sprintf("version=%s documents=%d", header.Version, len(records)))
+	return nil
+}
+
+func (c *Client) SetAccountNamespaceRole(username string, namespace string, role string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	endpoint := "/api/accounts/" + username + "/namespaces/" + namespace
+	if c.findNamespace(namespace) == nil {
+		return notFound(endpoint, "namespace")
+	}
+	if role != "" && c.findRole(role) == nil {
+		return notFound(endpoint, "role")
+	}
+	for _, a := range c.accounts {
+		if a.Username != username {
+			continue
+		}
+		if role == "" {
+			delete(a.NamespaceRoles, namespace)
+			return nil
+		}
+		if a.NamespaceRoles == nil {
+			a.NamespaceRoles = map[string]string{}
+		}
+		a.NamespaceRoles[namespace] = role
+		return nil
+	}
+	return notFound(endpoint, "account")
+}
+
+// findNamespace must be called with the lock held
+func (c *Client) findNamespace(name string) *shipyard.Namespace {
+	for _, ns := range c.namespaces {
+		if ns.Name == name {
+			return ns
+		}
+	}
+	return nil
+}
+
+// quotaUsage must be called with the lock held
+func (c *Client) quotaUsage(q *shipyard.Quota) *shipyard.QuotaUsage {
+	roles := map[string]string{}
+	for _, a := range c.accounts {
+		if a.Role != nil {
+			roles[a.Username] = a.Role.Name
+		}
+	}
+	u := &shipyard.QuotaUsage{Quota: q}
+	for _, cnt := range c.containers {
+		if cnt.State != "running" {
+			continue
+		}
+		if q.Namespace != "" {
+			if shipyard.ContainerNamespace(cnt) == q.Namespace {
+				u.Add(cnt.Image, 1)
+			}
+			continue
+		}
+		owner := shipyard.ContainerOwner(cnt)
+		if owner == "" {
+			continue
+		}
+		if owner == q.Account || (q.Role != "" && roles[owner] == q.Role) {
+			u.Add(cnt.Image, 1)
+		}
+	}
+	return u
+}
+
+// checkQuota must be called with the lock held
+func (c *Client) checkQuota(image *citadel.Image, count int) error {
+	owner := image.Environment[shipyard.OwnerEnv]
+	namespace := image.Environment[shipyard.NamespaceEnv]
+	role := ""
+	for _, a := range c.accounts {
+		if a.Username == owner && a.Role != nil {
+			role = a.Role.Name
+		}
+	}
+	for _, q := range c.quotas {
+		switch {
+		case namespace != "" && q.Namespace == namespace:
+		case owner != "" && q.Account == owner:
+		case owner != "" && q.Role != "" && q.Role == role:
+		default:
+			continue
+		}
+		u := c.quotaUsage(q)
+		u.Add(image, count)
+		if err := u.Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findEngine must be called with the lock held
+func (c *Client) findEngine(id string) *shipyard.Engine {
+	for _, e := range c.engines {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+func (c *Client) Endpoints(filter *shipyard.EndpointFilter) ([]*shipyard.Endpoint, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if filter == nil {
+		filter = &shipyard.EndpointFilter{}
+	}
+	endpoints := []*shipyard.Endpoint{}
+	for _, cnt := range c.containers {
+		if cnt.State != "running" || !filter.Match(cnt) {
+			continue
+		}
+		health := shipyard.ContainerHealth(cnt)
+		if filter.Healthy && health != "" && health != shipyard.HealthHealthy {
+			continue
+		}
+		for _, e := range shipyard.ContainerEndpoints(cnt) {
+			if filter.ContainerPort != 0 && e.ContainerPort != filter.ContainerPort {
+				continue
+			}
+			e.Health = health
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints, nil
+}
+
+// group returns the containers and applications of a group; it must be
+// called with the lock held
+func (c *Client) group(action string, group *shipyard.ContainerGroup) ([]*citadel.Container, []*shipyard.Application, error) {
+	endpoint := "/api/containers/groups/" + action
+	if err := group.Validate(); err != nil {
+		return nil, nil, &shipyard.APIError{
+			StatusCode: http.StatusBadRequest,
+			Method:     "POST",
+			Endpoint:   endpoint,
+			Message:    err.Error(),
+		}
+	}
+	containers := []*citadel.Container{}
+	for _, cnt := range c.containers {
+		if group.Match(cnt) {
+			containers = append(containers, cnt)
+		}
+	}
+	apps := []*shipyard.Application{}
+	for _, app := range c.apps {
+		if app.Name == group.Application || (group.Project != "" && app.Project == group.Project) {
+			apps = append(apps, app)
+		}
+	}
+	if len(containers) == 0 && len(apps) == 0 {
+		return nil, nil, notFound(endpoint, "group")
+	}
+	return containers, apps, nil
+}
+
+func (c *Client) setGroupState(action string, group *shipyard.ContainerGroup, state string) (*shipyard.GroupResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	containers, apps, err := c.group(action, group)
+	if err != nil {
+		return nil, err
+	}
+	res := &shipyard.GroupResult{Action: action, Group: group.String(), Containers: []string{}}
+	for _, cnt := range containers {
+		if cnt.State == state && action != "restart" {
+			continue
+		}
+		cnt.State = state
+		res.Containers = append(res.Containers, cnt.ID)
+		c.recordEvent(action, cnt, cnt.Engine, "")
+	}
+	for _, app := range apps {
+		app.Stopped = state != "running"
+	}
+	c.recordEvent(action+"-group", nil, nil, "group="+group.String())
+	return res, nil
+}
+
+func (c *Client) StartGroup(group *shipyard.ContainerGroup) (*shipyard.GroupResult, error) {
+	return c.setGroupState("start", group, "running")
+}
+
+func (c *Client) StopGroup(group *shipyard.ContainerGroup, timeout int) (*shipyard.GroupResult, error) {
+	return c.setGroupState("stop", group, "stopped")
+}
+
+func (c *Client) RestartGroup(group *shipyard.ContainerGroup, timeout int) (*shipyard.GroupResult, error) {
+	return c.setGroupState("restart", group, "running")
+}
+
+func (c *Client) DestroyGroup(group *shipyard.ContainerGroup) (*shipyard.GroupResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	containers, apps, err := c.group("destroy", group)
+	if err != nil {
+		return nil, err
+	}
+	if group.Label != "" || group.Selector != "" {
+		for _, cnt := range containers {
+			if shipyard.ApplicationName(cnt) != "" {
+				return nil, &shipyard.APIError{
+					StatusCode: http.StatusConflict,
+					Method:     "POST",
+					Endpoint:   "/api/containers/groups/destroy",
+					Message:    "the group contains application containers; select it by application or project",
+				}
+			}
+		}
+	}
+	removed := map[string]bool{}
+	for _, app := range apps {
+		removed[app.Name] = true
+	}
+	keptApps := []*shipyard.Application{}
+	for _, app := range c.apps {
+		if !removed[app.Name] {
+			keptApps = append(keptApps, app)
+		}
+	}
+	c.apps = keptApps
+	res := &shipyard.GroupResult{Action: "destroy", Group: group.String(), Containers: []string{}}
+	kept := []*citadel.Container{}
+	for _, cnt := range c.containers {
+		if !group.Match(cnt) {
+			kept = append(kept, cnt)
+			continue
+		}
+		res.Containers = append(res.Containers, cnt.ID)
+		c.recordEvent("destroy", cnt, cnt.Engine, "")
+	}
+	c.containers = kept
+	c.recordEvent("destroy-group", nil, nil, "group="+group.String())
+	return res, nil
+}
@@ -0,0 +1,96 @@
+package clienttest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+func newTestClient(t *testing.T) *Client {
+	c := NewClient()
+	t.Cleanup(c.Close)
+	if err := c.AddEngine(&shipyard.Engine{Engine: &citadel.Engine{ID: "local"}}); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRunAndDestroy(t *testing.T) {
+	c := newTestClient(t)
+	containers, err := c.Run(&citadel.Image{Name: "web"}, 2, false)
+	if err != nil || len(containers) != 2 {
+		t.Fatalf("expected two containers; received %v %v", containers, err)
+	}
+	cnt, err := c.Container(containers[0].ID[:12])
+	if err != nil || cnt.ID != containers[0].ID {
+		t.Fatalf("expected the container by id prefix; received %v %v", cnt, err)
+	}
+	if err := c.Destroy(cnt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Container(cnt.ID); !errors.Is(err, shipyard.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a destroyed container; received %v", err)
+	}
+	if left, _ := c.Containers(); len(left) != 1 {
+		t.Errorf("expected one container left; received %d", len(left))
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	c := newTestClient(t)
+	events, err := c.StreamEvents(&shipyard.EventFilter{Types: []string{"destroy"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers, err := c.Run(&citadel.Image{Name: "web"}, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Destroy(containers[0]); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case evt := <-events:
+		if evt.Type != "destroy" {
+			t.Errorf("expected only destroy events; received %s", evt.Type)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the destroy event")
+	}
+
+	c.Close()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("expected no more events")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the stream to end on close")
+	}
+	events, err = c.StreamEvents(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-events; ok {
+		t.Error("expected the stream of a closed client to be closed")
+	}
+}
+
+func TestStreamEventsUnread(t *testing.T) {
+	c := newTestClient(t)
+	events, err := c.StreamEvents(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the stream is not read; close must still end it
+	if _, err := c.Run(&citadel.Image{Name: "web"}, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	c.Close()
+	for range events {
+	}
+}
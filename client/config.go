@@ -1,11 +1,157 @@
 package client
 
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/shipyard/shipyard"
+)
+
+var (
+	ErrInvalidCACertificate = errors.New("unable to parse ca certificate")
+	ErrInvalidProxy         = errors.New("invalid proxy url")
+	ErrIncompleteKeyPair    = errors.New("ssl certificate and key must be given together")
+	// ErrUnreachable wraps connection errors of Ping
+	ErrUnreachable = errors.New("controller unreachable")
+)
+
 type (
 	ShipyardConfig struct {
-		Url           string `json:"url,omitempty"`
-		ServiceKey    string `json:"service_key,omitempty"`
-		Username      string `json:"username,omitempty"`
-		Token         string `json:"token,omitempty"`
+		Url        string `json:"url,omitempty"`
+		ServiceKey string `json:"service_key,omitempty"`
+		Username   string `json:"username,omitempty"`
+		Token      string `json:"token,omitempty"`
+		// Namespace scopes every request; containers, events and service
+		// keys are listed and created in the namespace
+		Namespace     string `json:"namespace,omitempty"`
 		AllowInsecure bool   `json:"allow_insecure,omitempty"`
+		// PEM encoded certificates; the *Path variants are read from disk
+		// when the PEM value is empty
+		CACertificate      string `json:"ca_cert,omitempty"`
+		CACertificatePath  string `json:"ca_cert_path,omitempty"`
+		SSLCertificate     string `json:"ssl_cert,omitempty"`
+		SSLCertificatePath string `json:"ssl_cert_path,omitempty"`
+		SSLKey             string `json:"ssl_key,omitempty"`
+		SSLKeyPath         string `json:"ssl_key_path,omitempty"`
+		// HTTPClient overrides the client used for all requests; when nil
+		// one is built from the settings above
+		HTTPClient *http.Client `json:"-"`
+		// DialContext connects to the controller instead of the default
+		// dialer, i.e. through a tunnel; Url may also be a unix://
+		// socket.  Both are ignored when HTTPClient is set.
+		DialContext func(ctx context.Context, network, addr string) (net.Conn, error) `json:"-"`
+		// Proxy is the http, https or socks5 proxy url requests are sent
+		// through; when empty the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+		// environment variables are honored
+		Proxy        string `json:"proxy,omitempty"`
+		DisableProxy bool   `json:"disable_proxy,omitempty"`
+		// Retry overrides DefaultRetryPolicy for GET and DELETE requests
+		Retry        *RetryPolicy `json:"retry,omitempty"`
+		DisableRetry bool         `json:"disable_retry,omitempty"`
+		// WaitOnRateLimit resends requests rejected by the controller rate
+		// limits after the delay it asks for, waiting at most
+		// MaxRateLimitWait in total.  Otherwise rate limited requests fail
+		// with shipyard.ErrRateLimited.
+		WaitOnRateLimit  bool          `json:"wait_on_rate_limit,omitempty"`
+		MaxRateLimitWait time.Duration `json:"max_rate_limit_wait,omitempty"`
+		// Cache keeps the GET responses the controller tags and
+		// revalidates them, so repeated calls like Engines() and Roles()
+		// only transfer data that changed
+		Cache bool `json:"cache,omitempty"`
+		// DisableCompression stops asking the controller for gzip
+		// compressed responses
+		DisableCompression bool `json:"disable_compression,omitempty"`
+		// CheckVersion makes NewManager check the controller supports the
+		// api version of the client; requests of an incompatible manager
+		// fail with shipyard.ErrIncompatibleAPIVersion
+		CheckVersion bool `json:"check_version,omitempty"`
+		// Reauthenticate is called when the controller rejects Token,
+		// i.e. after it was revoked or replaced by another login.  The
+		// request is sent again once with the token it returns.
+		Reauthenticate func() (string, error) `json:"-"`
+		// Logger receives debug lines about each request; the client is
+		// silent when nil
+		Logger Logger `json:"-"`
+		// Tracer records a client span for each request; the trace context
+		// of the manager context is propagated either way
+		Tracer *shipyard.Tracer `json:"-"`
+		// Traceparent is the parent trace context of requests whose
+		// context carries none, i.e. from the TRACEPARENT environment
+		// variable of a deploy pipeline
+		Traceparent string `json:"-"`
 	}
 )
+
+// ProxyFunc returns the proxy selection of the transport built for the
+// config; it is nil when proxies are disabled
+func (cfg *ShipyardConfig) ProxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	if cfg.DisableProxy {
+		return nil, nil
+	}
+	if cfg.Proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := url.Parse(cfg.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidProxy, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("%w: unsupported scheme %q", ErrInvalidProxy, u.Scheme)
+	}
+	return http.ProxyURL(u), nil
+}
+
+func readPEM(data string, path string) ([]byte, error) {
+	if data != "" || path == "" {
+		return []byte(data), nil
+	}
+	return ioutil.ReadFile(path)
+}
+
+// TLSConfig returns the tls configuration for connecting to the controller.
+// AllowInsecure disables server certificate verification.
+func (c *ShipyardConfig) TLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.AllowInsecure,
+	}
+	caCert, err := readPEM(c.CACertificate, c.CACertificatePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(caCert) > 0 {
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(caCert) {
+			return nil, ErrInvalidCACertificate
+		}
+		tlsConfig.RootCAs = certPool
+	}
+	sslCert, err := readPEM(c.SSLCertificate, c.SSLCertificatePath)
+	if err != nil {
+		return nil, err
+	}
+	sslKey, err := readPEM(c.SSLKey, c.SSLKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	if (len(sslCert) > 0) != (len(sslKey) > 0) {
+		return nil, ErrIncompleteKeyPair
+	}
+	if len(sslCert) > 0 {
+		cert, err := tls.X509KeyPair(sslCert, sslKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/shipyard/shipyard"
+)
+
+func (m *Manager) Configs() ([]*shipyard.ConfigBundle, error) {
+	bundles := []*shipyard.ConfigBundle{}
+	resp, err := m.doRequest(configsPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&bundles); err != nil {
+		return nil, err
+	}
+	return bundles, nil
+}
+
+func (m *Manager) Config(name string) (*shipyard.ConfigBundle, error) {
+	var bundle *shipyard.ConfigBundle
+	resp, err := m.doRequest(configPath(name), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&bundle); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+func (m *Manager) CreateConfig(bundle *shipyard.ConfigBundle) (*shipyard.ConfigBundle, error) {
+	b, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(createConfigPath(), "POST", 201, b)
+	if err != nil {
+		return nil, err
+	}
+	var created *shipyard.ConfigBundle
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateConfig replaces the data and files of a config bundle; containers
+// launched afterwards get the new values
+func (m *Manager) UpdateConfig(bundle *shipyard.ConfigBundle) error {
+	b, err := json.Marshal(bundle)
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(updateConfigPath(bundle.Name), "PUT", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) DeleteConfig(name string) error {
+	if _, err := m.doRequest(deleteConfigPath(name), "DELETE", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
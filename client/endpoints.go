@@ -0,0 +1,43 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/shipyard/shipyard"
+)
+
+// Endpoints returns the host:port addresses of the running containers
+// selected by filter; a nil filter returns all published ports
+func (m *Manager) Endpoints(filter *shipyard.EndpointFilter) ([]*shipyard.Endpoint, error) {
+	v := url.Values{}
+	if filter != nil {
+		for name, val := range map[string]string{
+			"image":       filter.Image,
+			"label":       filter.Label,
+			"application": filter.Application,
+			"selector":    filter.Selector.String(),
+		} {
+			if val != "" {
+				v.Set(name, val)
+			}
+		}
+		if filter.ContainerPort != 0 {
+			v.Set("port", strconv.Itoa(filter.ContainerPort))
+		}
+		if filter.Healthy {
+			v.Set("healthy", "true")
+		}
+	}
+	endpoints := []*shipyard.Endpoint{}
+	resp, err := m.doRequest(fmt.Sprintf("%s?%s", endpointsPath(), v.Encode()), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&endpoints); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
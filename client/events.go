@@ -0,0 +1,120 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shipyard/shipyard"
+)
+
+// maxEventSize bounds a single server-sent event line
+const maxEventSize = 1024 * 1024
+
+// Events returns stored cluster events, newest first, selected by query.  A
+// nil query returns every event.
+func (m *Manager) Events(query *shipyard.EventQuery) ([]*shipyard.Event, error) {
+	events := []*shipyard.Event{}
+	resp, err := m.doRequest(eventQueryPath(query, ""), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ExportEvents returns the events selected by query encoded as format
+// ("json", "csv" or "jsonl").  The caller must close the returned reader.
+func (m *Manager) ExportEvents(query *shipyard.EventQuery, format string) (io.ReadCloser, error) {
+	resp, err := m.doRequest(eventQueryPath(query, format), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func eventQueryPath(query *shipyard.EventQuery, format string) string {
+	v := url.Values{}
+	if format != "" {
+		v.Set("format", format)
+	}
+	if query != nil {
+		setEventFilterValues(v, &query.EventFilter)
+		if !query.Since.IsZero() {
+			v.Set("since", query.Since.Format(time.RFC3339))
+		}
+		if !query.Until.IsZero() {
+			v.Set("until", query.Until.Format(time.RFC3339))
+		}
+		if query.Limit > 0 {
+			v.Set("limit", strconv.Itoa(query.Limit))
+		}
+		if query.Offset > 0 {
+			v.Set("offset", strconv.Itoa(query.Offset))
+		}
+	}
+	path := eventsPath()
+	if len(v) > 0 {
+		path += "?" + v.Encode()
+	}
+	return path
+}
+
+// StreamEvents delivers cluster events matching filter as they are saved by
+// the controller.  The channel is closed when the stream ends or the manager
+// context is done.
+func (m *Manager) StreamEvents(filter *shipyard.EventFilter) (<-chan *shipyard.Event, error) {
+	v := url.Values{}
+	if filter != nil {
+		setEventFilterValues(v, filter)
+	}
+	path := streamEventsPath()
+	if len(v) > 0 {
+		path += "?" + v.Encode()
+	}
+	resp, err := m.doRequest(path, "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	c := make(chan *shipyard.Event)
+	go func() {
+		defer close(c)
+		defer resp.Body.Close()
+		s := bufio.NewScanner(resp.Body)
+		s.Buffer(make([]byte, 4096), maxEventSize)
+		for s.Scan() {
+			line := s.Text()
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			var evt *shipyard.Event
+			if err := json.Unmarshal([]byte(strings.TrimSpace(line[5:])), &evt); err != nil {
+				return
+			}
+			select {
+			case c <- evt:
+			case <-m.ctx.Done():
+				return
+			}
+		}
+	}()
+	return c, nil
+}
+
+func setEventFilterValues(v url.Values, filter *shipyard.EventFilter) {
+	for _, t := range filter.Types {
+		v.Add("type", t)
+	}
+	if filter.EngineID != "" {
+		v.Set("engine", filter.EngineID)
+	}
+	if filter.ContainerID != "" {
+		v.Set("container", filter.ContainerID)
+	}
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/websocket"
+)
+
+var (
+	ErrUpgradeFailed = errors.New("controller did not upgrade the connection")
+)
+
+type (
+	// ExecSession is the raw stdio stream of a command running in a
+	// container.  Writes go to the command's stdin when the exec is
+	// interactive.  Reads return its output which is multiplexed in the
+	// docker stream format unless a tty was requested.
+	ExecSession struct {
+		ID          string
+		ContainerID string
+		io.ReadWriteCloser
+	}
+
+	// AttachSession is the stdio stream of a container carried over a
+	// websocket.  Writes go to the container stdin.  Reads return its
+	// output which is multiplexed in the docker stream format unless Tty
+	// is set.
+	AttachSession struct {
+		ContainerID string
+		Tty         bool
+		io.ReadWriteCloser
+	}
+)
+
+// Exec runs a command in a container and returns its stdio stream.  The
+// stream ends when the command exits; use ExecInspect for the exit code.
+func (m *Manager) Exec(containerID string, cfg *shipyard.ExecConfig) (*ExecSession, error) {
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(createExecPath(containerID), "POST", 201, b)
+	if err != nil {
+		return nil, err
+	}
+	var info *shipyard.ExecInfo
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if info == nil || info.ID == "" {
+		return nil, fmt.Errorf("no exec created in %s", containerID)
+	}
+
+	req, err := m.newRequest(fmt.Sprintf("%s?tty=%v", startExecPath(containerID, info.ID), cfg.Tty), "POST", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+	resp, err = m.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(resp, 101); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		return nil, ErrUpgradeFailed
+	}
+	session := &ExecSession{
+		ID:              info.ID,
+		ContainerID:     containerID,
+		ReadWriteCloser: rwc,
+	}
+	return session, nil
+}
+
+// Attach connects to the stdin, stdout and stderr of a running container
+func (m *Manager) Attach(containerID string) (*AttachSession, error) {
+	req, err := m.newRequest(attachContainerPath(containerID), "GET", nil)
+	if err != nil {
+		return nil, err
+	}
+	key := websocket.SetHandshakeHeaders(req)
+	resp, err := m.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(resp, 101); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok || websocket.CheckHandshake(resp, key) != nil {
+		resp.Body.Close()
+		return nil, ErrUpgradeFailed
+	}
+	session := &AttachSession{
+		ContainerID:     containerID,
+		Tty:             resp.Header.Get(shipyard.TtyHeader) == "true",
+		ReadWriteCloser: websocket.NewClientConn(rwc),
+	}
+	return session, nil
+}
+
+func (m *Manager) ExecInspect(containerID string, execID string) (*shipyard.ExecInfo, error) {
+	var info *shipyard.ExecInfo
+	resp, err := m.doRequest(inspectExecPath(containerID, execID), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/shipyard/shipyard"
+)
+
+func (m *Manager) GCPolicy() (*shipyard.GCPolicy, error) {
+	var policy *shipyard.GCPolicy
+	resp, err := m.doRequest(gcPolicyPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (m *Manager) SetGCPolicy(policy *shipyard.GCPolicy) error {
+	b, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(setGCPolicyPath(), "PUT", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RunGC runs a collection with the current policy and returns what it
+// removed
+func (m *Manager) RunGC() (*shipyard.GCResult, error) {
+	var result *shipyard.GCResult
+	resp, err := m.doRequest(runGCPath(), "POST", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,53 @@
+//go:build ignore
+// +build ignore
+
+// genroutes writes the path helpers of routes_gen.go from the openapi
+// spec of the controller read from stdin:
+//
+//	controller -spec | go run genroutes.go > routes_gen.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"regexp"
+
+	"github.com/shipyard/shipyard"
+)
+
+var variable = regexp.MustCompile(`{([^}]+)}`)
+
+func main() {
+	var spec *shipyard.OpenAPISpec
+	if err := json.NewDecoder(os.Stdin).Decode(&spec); err != nil {
+		log.Fatal(err)
+	}
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "// Code generated by genroutes.go from the controller openapi spec; DO NOT EDIT.\n\n")
+	fmt.Fprintf(b, "package client\n\nimport \"fmt\"\n")
+	for _, r := range spec.Operations() {
+		_, vars := shipyard.OpenAPIPath(r.Path)
+		fmt.Fprintf(b, "\n// %sPath is the path of %s %s\n", r.Name, r.Method, r.Path)
+		if len(vars) == 0 {
+			fmt.Fprintf(b, "func %sPath() string {\n\treturn %q\n}\n", r.Name, r.Path)
+			continue
+		}
+		args := ""
+		for i, v := range vars {
+			if i > 0 {
+				args += ", "
+			}
+			args += v
+		}
+		fmt.Fprintf(b, "func %sPath(%s string) string {\n\treturn fmt.Sprintf(%q, %s)\n}\n", r.Name, args, variable.ReplaceAllString(r.Path, "%s"), args)
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Stdout.Write(src)
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/shipyard/shipyard"
+)
+
+// StartGroup starts the stopped containers of a group; see
+// shipyard.ContainerGroup
+func (m *Manager) StartGroup(group *shipyard.ContainerGroup) (*shipyard.GroupResult, error) {
+	return m.groupAction("start", group, 0)
+}
+
+func (m *Manager) StopGroup(group *shipyard.ContainerGroup, timeout int) (*shipyard.GroupResult, error) {
+	return m.groupAction("stop", group, timeout)
+}
+
+func (m *Manager) RestartGroup(group *shipyard.ContainerGroup, timeout int) (*shipyard.GroupResult, error) {
+	return m.groupAction("restart", group, timeout)
+}
+
+// DestroyGroup destroys the containers of a group and removes the
+// applications it selects
+func (m *Manager) DestroyGroup(group *shipyard.ContainerGroup) (*shipyard.GroupResult, error) {
+	return m.groupAction("destroy", group, 0)
+}
+
+func (m *Manager) groupAction(action string, group *shipyard.ContainerGroup, timeout int) (*shipyard.GroupResult, error) {
+	v := url.Values{}
+	for name, val := range map[string]string{
+		"label":       group.Label,
+		"application": group.Application,
+		"project":     group.Project,
+		"selector":    group.Selector,
+	} {
+		if val != "" {
+			v.Set(name, val)
+		}
+	}
+	if timeout > 0 {
+		v.Set("timeout", strconv.Itoa(timeout))
+	}
+	resp, err := m.doRequest(fmt.Sprintf("%s?%s", containerGroupPath(action), v.Encode()), "POST", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	var res *shipyard.GroupResult
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
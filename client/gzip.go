@@ -0,0 +1,35 @@
+package client
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+)
+
+// gzipBody decompresses a gzip encoded response body
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b *gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
+
+// decompress replaces a gzip encoded response body with its decompressed
+// content; the body is left as is when it can not be decompressed
+func decompress(resp *http.Response) {
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		return
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return
+	}
+	resp.Body = &gzipBody{Reader: gz, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shipyard/shipyard"
+)
+
+type (
+	// RequestHook is called with every request before it is sent, i.e.
+	// to add tracing or authentication headers
+	RequestHook func(req *http.Request)
+	// ResponseHook is called with every response received, including
+	// error responses, before the body is read
+	ResponseHook func(resp *http.Response)
+)
+
+// OnRequest registers a hook called before each request is sent.  Hooks
+// run in registration order after the shipyard headers are set; retried
+// requests run them again.  Managers returned by WithContext keep the
+// hooks registered so far.
+func (m *Manager) OnRequest(h RequestHook) {
+	m.requestHooks = append(m.requestHooks[:len(m.requestHooks):len(m.requestHooks)], h)
+}
+
+// OnResponse registers a hook called with each response received
+func (m *Manager) OnResponse(h ResponseHook) {
+	m.responseHooks = append(m.responseHooks[:len(m.responseHooks):len(m.responseHooks)], h)
+}
+
+// do sends a request through the hooks, traces and logs it
+func (m *Manager) do(req *http.Request) (*http.Response, error) {
+	ctx := m.ctx
+	if _, ok := shipyard.SpanFromContext(ctx); !ok && m.config.Traceparent != "" {
+		if sc, err := shipyard.ParseTraceparent(m.config.Traceparent); err == nil {
+			ctx = shipyard.ContextWithSpan(ctx, sc)
+		}
+	}
+	var span *shipyard.Span
+	if m.config.Tracer != nil {
+		_, span = m.config.Tracer.StartSpan(ctx, req.Method+" "+req.URL.Path, shipyard.SpanKindClient)
+		span.SetAttribute("http.method", req.Method)
+		// the query may hold tokens, i.e. the key of a join
+		u := *req.URL
+		u.User = nil
+		u.RawQuery = ""
+		span.SetAttribute("http.url", u.String())
+		defer span.Finish()
+		req.Header.Set(shipyard.TraceparentHeader, span.Traceparent())
+	} else if sc, ok := shipyard.SpanFromContext(ctx); ok {
+		req.Header.Set(shipyard.TraceparentHeader, sc.Traceparent())
+	}
+	for _, h := range m.requestHooks {
+		h(req)
+	}
+	m.logf("shipyard: %s %s", req.Method, req.URL.Path)
+	start := time.Now()
+	resp, err := m.client.Do(req)
+	if err != nil {
+		m.logf("shipyard: %s %s failed after %s: %s", req.Method, req.URL.Path, time.Since(start), err)
+		if span != nil {
+			span.SetError(err)
+		}
+		return nil, err
+	}
+	if span != nil {
+		span.SetAttribute("http.status_code", strconv.Itoa(resp.StatusCode))
+		if resp.StatusCode >= 500 {
+			span.SetError(errors.New(resp.Status))
+		}
+	}
+	m.logf("shipyard: %s %s: %d (%s)", req.Method, req.URL.Path, resp.StatusCode, time.Since(start))
+	decompress(resp)
+	for _, h := range m.responseHooks {
+		h(resp)
+	}
+	return resp, nil
+}
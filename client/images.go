@@ -0,0 +1,95 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+
+	"github.com/shipyard/shipyard"
+)
+
+// Images returns the images on all engines in the cluster
+func (m *Manager) Images() ([]*shipyard.Image, error) {
+	images := []*shipyard.Image{}
+	resp, err := m.doRequest(imagesPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&images); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// PullImage pulls an image on every engine.  An empty tag pulls latest.
+func (m *Manager) PullImage(name string, tag string) error {
+	v := url.Values{}
+	v.Set("name", name)
+	if tag != "" {
+		v.Set("tag", tag)
+	}
+	if _, err := m.doRequest(fmt.Sprintf("%s?%s", pullImagePath(), v.Encode()), "POST", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RemoveImage removes an image from every engine
+func (m *Manager) RemoveImage(name string) error {
+	if _, err := m.doRequest(removeImagePath(name), "DELETE", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Build builds an image from a tar context on the cluster and writes the
+// build output to out.  The context is streamed so the request is not
+// retried.
+func (m *Manager) Build(context io.Reader, opts *shipyard.BuildOptions, out io.Writer) error {
+	v := url.Values{}
+	if opts.Tag != "" {
+		v.Set("tag", opts.Tag)
+	}
+	if opts.Engine != "" {
+		v.Set("engine", opts.Engine)
+	}
+	if opts.Dockerfile != "" {
+		v.Set("dockerfile", opts.Dockerfile)
+	}
+	if opts.NoCache {
+		v.Set("nocache", strconv.FormatBool(opts.NoCache))
+	}
+	if opts.Pull {
+		v.Set("pull", strconv.FormatBool(opts.Pull))
+	}
+	req, err := m.newRequest(fmt.Sprintf("%s?%s", buildImagePath(), v.Encode()), "POST", context)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-tar")
+	resp, err := m.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, 200); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg *shipyard.BuildMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("%w: %s", shipyard.ErrBuildFailed, msg.Error)
+		}
+		if _, err := io.WriteString(out, msg.Stream); err != nil {
+			return err
+		}
+	}
+}
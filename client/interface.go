@@ -0,0 +1,200 @@
+package client
+
+import (
+	"io"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/dockerhub"
+)
+
+// ShipyardClient is the set of controller operations provided by Manager.
+// Code that only needs these operations can depend on the interface and use
+// the in-memory implementation from the clienttest package in tests.
+type ShipyardClient interface {
+	Containers() ([]*citadel.Container, error)
+	QueryContainers(query *shipyard.ContainerQuery) ([]*citadel.Container, error)
+	ContainersByLabel(selector string) ([]*citadel.Container, error)
+	Container(id string) (*citadel.Container, error)
+	GetContainer(id string) (*citadel.Container, error)
+	Run(image *citadel.Image, count int, pull bool) ([]*citadel.Container, error)
+	PreviewRun(image *citadel.Image, count int) (*shipyard.RunPreview, error)
+	Batch(ops []*shipyard.BatchOperation) (*shipyard.BatchResult, error)
+	Destroy(container *citadel.Container) error
+	UpdateContainer(container *citadel.Container, patch *shipyard.ContainerPatch) (*citadel.Container, error)
+	RenameContainer(container *citadel.Container, name string) error
+	Commit(container *citadel.Container, opts *shipyard.CommitOptions) (*shipyard.Image, error)
+	Start(container *citadel.Container) error
+	Stop(container *citadel.Container, timeout int) error
+	Restart(container *citadel.Container, timeout int) error
+	Wait(containerID string) (int, error)
+	Pause(container *citadel.Container) error
+	Unpause(container *citadel.Container) error
+	Scale(image *citadel.Image, desiredCount int) error
+	Logs(containerID string, follow bool, tail int) (io.ReadCloser, error)
+	CopyFrom(containerID string, path string) (io.ReadCloser, error)
+	CopyTo(containerID string, path string, archive io.Reader) error
+	Diff(containerID string) ([]*shipyard.ContainerChange, error)
+	Export(containerID string) (io.ReadCloser, error)
+	Exec(containerID string, cfg *shipyard.ExecConfig) (*ExecSession, error)
+	ExecInspect(containerID string, execID string) (*shipyard.ExecInfo, error)
+	Attach(containerID string) (*AttachSession, error)
+	Stats(containerID string) (<-chan *shipyard.ContainerStats, error)
+	Top(containerID string, psArgs string) (*shipyard.ProcessList, error)
+	Metrics(resource string, id string, since time.Time) ([]*shipyard.MetricSample, error)
+
+	Images() ([]*shipyard.Image, error)
+	PullImage(name string, tag string) error
+	RemoveImage(name string) error
+	Build(context io.Reader, opts *shipyard.BuildOptions, out io.Writer) error
+
+	Registries() ([]*shipyard.Registry, error)
+	AddRegistry(registry *shipyard.Registry) error
+	RemoveRegistry(name string) error
+
+	Engines() ([]*shipyard.Engine, error)
+	GetEngine(id string) (*shipyard.Engine, error)
+	EngineHealth(id string) (*shipyard.Health, error)
+	UpdateEngine(engine *shipyard.Engine) error
+	UpdateEngineLabels(id string, labels []string) error
+	EngineCertificates(id string) (*shipyard.EngineCertificateInfo, error)
+	SetEngineCertificates(id string, certs *shipyard.EngineCertificates) error
+	CordonEngine(id string) error
+	UncordonEngine(id string) error
+	DrainEngine(id string) error
+	AddEngine(engine *shipyard.Engine) error
+	RemoveEngine(engine *shipyard.Engine) error
+	JoinTokens() ([]*shipyard.JoinToken, error)
+	CreateJoinToken(ttl time.Duration, labels []string) (*shipyard.JoinToken, error)
+	DeleteJoinToken(id string) error
+	JoinEngine(req *shipyard.JoinRequest) (*citadel.Engine, error)
+	Version() (*shipyard.VersionInfo, error)
+	Ping() (time.Duration, error)
+	Info() (*shipyard.ClusterInfo, error)
+	Snapshot() (*ClusterSnapshot, error)
+	Usage() (*shipyard.ClusterUsage, error)
+	Rebalance(opts *shipyard.RebalanceOptions) ([]*shipyard.RebalanceMove, error)
+
+	Events(query *shipyard.EventQuery) ([]*shipyard.Event, error)
+	StreamEvents(filter *shipyard.EventFilter) (<-chan *shipyard.Event, error)
+	ExportEvents(query *shipyard.EventQuery, format string) (io.ReadCloser, error)
+	EventRetention() (*shipyard.EventRetention, error)
+	SetEventRetention(policy *shipyard.EventRetention) error
+	ApplyEventRetention() (*shipyard.EventRetentionResult, error)
+	AuditLog(filter *shipyard.AuditFilter) ([]*shipyard.AuditEntry, error)
+	ExportAuditLog(filter *shipyard.AuditFilter, format string) (io.ReadCloser, error)
+
+	Accounts() ([]*shipyard.Account, error)
+	QueryAccounts(query *shipyard.AccountQuery) ([]*shipyard.Account, error)
+	AddAccount(account *shipyard.Account) error
+	UpdateAccount(account *shipyard.Account) error
+	SetAccountRole(username string, role string) error
+	SetAccountNamespaceRole(username string, namespace string, role string) error
+	DeleteAccount(account *shipyard.Account) error
+	Roles() ([]*shipyard.Role, error)
+	Role(name string) (*shipyard.Role, error)
+	CreateRole(role *shipyard.Role) error
+	UpdateRole(role *shipyard.Role) error
+	DeleteRole(role *shipyard.Role) error
+	Login(username, password string) (*shipyard.AuthToken, error)
+	LoginWithOTP(username, password, otp string) (*shipyard.AuthToken, error)
+	Enable2FA() (*shipyard.TOTPEnrollment, error)
+	Confirm2FA(code string) error
+	Disable2FA(code string) error
+	RefreshToken() (*shipyard.AuthToken, error)
+	Logout() error
+	ChangePassword(password string) error
+
+	ServiceKeys() ([]*shipyard.ServiceKey, error)
+	NewServiceKey(description string, ttl time.Duration, permissions []string) (*shipyard.ServiceKey, error)
+	RemoveServiceKey(key *shipyard.ServiceKey) error
+
+	Extensions() ([]*shipyard.Extension, error)
+	AddExtension(ext *shipyard.Extension) error
+	RemoveExtension(id string) error
+
+	WebhookKeys() ([]*dockerhub.WebhookKey, error)
+	NewWebhookKey(image string) (*dockerhub.WebhookKey, error)
+	RemoveWebhookKey(key string) error
+	Webhooks() ([]*shipyard.Webhook, error)
+	AddWebhook(hook *shipyard.Webhook) (*shipyard.Webhook, error)
+	RemoveWebhook(id string) error
+	Notifiers() ([]*shipyard.Notifier, error)
+	AddNotifier(n *shipyard.Notifier) (*shipyard.Notifier, error)
+	RemoveNotifier(id string) error
+	Alerts() ([]*shipyard.Alert, error)
+	AlertRules() ([]*shipyard.AlertRule, error)
+	AddAlertRule(rule *shipyard.AlertRule) (*shipyard.AlertRule, error)
+	RemoveAlertRule(id string) error
+
+	Applications() ([]*shipyard.Application, error)
+	Application(name string) (*shipyard.Application, error)
+	ApplicationContainers(name string) ([]*citadel.Container, error)
+	CreateApplication(app *shipyard.Application) (*shipyard.Application, error)
+	UpdateApplication(app *shipyard.Application, confirm bool) error
+	DiffApplication(app *shipyard.Application) (*shipyard.ApplicationDiff, error)
+	RemoveApplication(name string) error
+	DeployCompose(r io.Reader, project string) ([]*shipyard.Application, error)
+	Deploy(name string, image string, opts *shipyard.DeployOptions) (*shipyard.Deployment, error)
+	Rollback(name string, revision int, opts *shipyard.DeployOptions) (*shipyard.Deployment, error)
+	Deployments(name string) ([]*shipyard.Deployment, error)
+	Deployment(id string) (*shipyard.Deployment, error)
+	PromoteDeployment(id string) error
+
+	Jobs() ([]*shipyard.Job, error)
+	Job(id string) (*shipyard.Job, error)
+	CreateJob(schedule string, image *citadel.Image, count int) (*shipyard.Job, error)
+	RemoveJob(id string) error
+	JobRuns(jobID string) ([]*shipyard.JobRun, error)
+
+	Secrets() ([]*shipyard.Secret, error)
+	Secret(name string) (*shipyard.Secret, error)
+	CreateSecret(name string, value string) (*shipyard.Secret, error)
+	DeleteSecret(name string) error
+
+	Configs() ([]*shipyard.ConfigBundle, error)
+	Config(name string) (*shipyard.ConfigBundle, error)
+	CreateConfig(bundle *shipyard.ConfigBundle) (*shipyard.ConfigBundle, error)
+	UpdateConfig(bundle *shipyard.ConfigBundle) error
+	DeleteConfig(name string) error
+	Templates() ([]*shipyard.LaunchTemplate, error)
+	Template(name string) (*shipyard.LaunchTemplate, error)
+	CreateTemplate(t *shipyard.LaunchTemplate) (*shipyard.LaunchTemplate, error)
+	UpdateTemplate(t *shipyard.LaunchTemplate) error
+	DeleteTemplate(name string) error
+	RunTemplate(name string, overrides *shipyard.TemplateOverrides, count int, pull bool) ([]*citadel.Container, error)
+
+	Volumes() ([]*shipyard.Volume, error)
+	CreateVolume(volume *shipyard.Volume) (*shipyard.Volume, error)
+	RemoveVolume(name string) error
+
+	Quotas() ([]*shipyard.QuotaUsage, error)
+	CreateQuota(quota *shipyard.Quota) (*shipyard.Quota, error)
+	DeleteQuota(name string) error
+
+	Namespaces() ([]*shipyard.Namespace, error)
+	CreateNamespace(ns *shipyard.Namespace) (*shipyard.Namespace, error)
+	DeleteNamespace(name string) error
+
+	EnginePools() ([]*shipyard.EnginePool, error)
+	EnginePool(name string) (*shipyard.EnginePool, error)
+	CreateEnginePool(pool *shipyard.EnginePool) (*shipyard.EnginePool, error)
+	UpdateEnginePool(pool *shipyard.EnginePool) error
+	DeleteEnginePool(name string) error
+
+	GCPolicy() (*shipyard.GCPolicy, error)
+	SetGCPolicy(policy *shipyard.GCPolicy) error
+	RunGC() (*shipyard.GCResult, error)
+
+	Backup() (io.ReadCloser, error)
+	Restore(r io.Reader) error
+
+	Endpoints(filter *shipyard.EndpointFilter) ([]*shipyard.Endpoint, error)
+	StartGroup(group *shipyard.ContainerGroup) (*shipyard.GroupResult, error)
+	StopGroup(group *shipyard.ContainerGroup, timeout int) (*shipyard.GroupResult, error)
+	RestartGroup(group *shipyard.ContainerGroup, timeout int) (*shipyard.GroupResult, error)
+	DestroyGroup(group *shipyard.ContainerGroup) (*shipyard.GroupResult, error)
+}
+
+var _ ShipyardClient = (*Manager)(nil)
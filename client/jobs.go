@@ -0,0 +1,77 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+func (m *Manager) Jobs() ([]*shipyard.Job, error) {
+	jobs := []*shipyard.Job{}
+	resp, err := m.doRequest(jobsPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (m *Manager) Job(id string) (*shipyard.Job, error) {
+	var job *shipyard.Job
+	resp, err := m.doRequest(jobPath(id), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// CreateJob schedules count containers of image to be launched at each time
+// matching the cron schedule
+func (m *Manager) CreateJob(schedule string, image *citadel.Image, count int) (*shipyard.Job, error) {
+	job := &shipyard.Job{
+		Schedule: schedule,
+		Image:    image,
+		Count:    count,
+	}
+	b, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(createJobPath(), "POST", 201, b)
+	if err != nil {
+		return nil, err
+	}
+	var created *shipyard.Job
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// RemoveJob deletes the job, its run history and the exited containers of
+// its runs
+func (m *Manager) RemoveJob(id string) error {
+	if _, err := m.doRequest(removeJobPath(id), "DELETE", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// JobRuns returns the run history of a job, newest first
+func (m *Manager) JobRuns(jobID string) ([]*shipyard.JobRun, error) {
+	runs := []*shipyard.JobRun{}
+	resp, err := m.doRequest(jobRunsPath(jobID), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
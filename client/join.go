@@ -0,0 +1,67 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+func (m *Manager) JoinTokens() ([]*shipyard.JoinToken, error) {
+	tokens := []*shipyard.JoinToken{}
+	resp, err := m.doRequest(joinTokensPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// CreateJoinToken returns a single use token for registering a docker
+// host.  A zero ttl never expires.
+func (m *Manager) CreateJoinToken(ttl time.Duration, labels []string) (*shipyard.JoinToken, error) {
+	b, err := json.Marshal(&shipyard.JoinToken{
+		ExpiresIn: int64(ttl / time.Second),
+		Labels:    labels,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(createJoinTokenPath(), "POST", 201, b)
+	if err != nil {
+		return nil, err
+	}
+	var token *shipyard.JoinToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (m *Manager) DeleteJoinToken(id string) error {
+	if _, err := m.doRequest(deleteJoinTokenPath(id), "DELETE", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// JoinEngine registers a docker host with a join token.  It needs no
+// account credentials.
+func (m *Manager) JoinEngine(req *shipyard.JoinRequest) (*citadel.Engine, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(joinEnginePath(), "POST", 201, b)
+	if err != nil {
+		return nil, err
+	}
+	var engine *citadel.Engine
+	if err := json.NewDecoder(resp.Body).Decode(&engine); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
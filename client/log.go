@@ -0,0 +1,13 @@
+package client
+
+// Logger receives debug lines about requests, responses, retries and auth
+// token changes.  *log.Logger and *logrus.Logger satisfy it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+func (m *Manager) logf(format string, v ...interface{}) {
+	if m.config.Logger != nil {
+		m.config.Logger.Printf(format, v...)
+	}
+}
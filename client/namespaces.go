@@ -0,0 +1,43 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/shipyard/shipyard"
+)
+
+func (m *Manager) Namespaces() ([]*shipyard.Namespace, error) {
+	namespaces := []*shipyard.Namespace{}
+	resp, err := m.doRequest(namespacesPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&namespaces); err != nil {
+		return nil, err
+	}
+	return namespaces, nil
+}
+
+func (m *Manager) CreateNamespace(ns *shipyard.Namespace) (*shipyard.Namespace, error) {
+	b, err := json.Marshal(ns)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(createNamespacePath(), "POST", 201, b)
+	if err != nil {
+		return nil, err
+	}
+	var created *shipyard.Namespace
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// DeleteNamespace removes a namespace; it must have no containers
+func (m *Manager) DeleteNamespace(name string) error {
+	if _, err := m.doRequest(deleteNamespacePath(name), "DELETE", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
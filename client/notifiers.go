@@ -0,0 +1,44 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/shipyard/shipyard"
+)
+
+// Notifiers returns the configured notifiers without credentials
+func (m *Manager) Notifiers() ([]*shipyard.Notifier, error) {
+	notifiers := []*shipyard.Notifier{}
+	resp, err := m.doRequest(notifiersPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&notifiers); err != nil {
+		return nil, err
+	}
+	return notifiers, nil
+}
+
+// AddNotifier sends matching cluster events to Slack or email
+func (m *Manager) AddNotifier(n *shipyard.Notifier) (*shipyard.Notifier, error) {
+	b, err := json.Marshal(n)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(addNotifierPath(), "POST", 200, b)
+	if err != nil {
+		return nil, err
+	}
+	var created *shipyard.Notifier
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (m *Manager) RemoveNotifier(id string) error {
+	if _, err := m.doRequest(removeNotifierPath(id), "DELETE", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
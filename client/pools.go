@@ -0,0 +1,66 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/shipyard/shipyard"
+)
+
+// EnginePools returns every engine pool with the engines in it
+func (m *Manager) EnginePools() ([]*shipyard.EnginePool, error) {
+	pools := []*shipyard.EnginePool{}
+	resp, err := m.doRequest(enginePoolsPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&pools); err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+func (m *Manager) EnginePool(name string) (*shipyard.EnginePool, error) {
+	var pool *shipyard.EnginePool
+	resp, err := m.doRequest(enginePoolPath(name), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&pool); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+func (m *Manager) CreateEnginePool(pool *shipyard.EnginePool) (*shipyard.EnginePool, error) {
+	b, err := json.Marshal(pool)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(createEnginePoolPath(), "POST", 201, b)
+	if err != nil {
+		return nil, err
+	}
+	var created *shipyard.EnginePool
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (m *Manager) UpdateEnginePool(pool *shipyard.EnginePool) error {
+	b, err := json.Marshal(pool)
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(updateEnginePoolPath(pool.Name), "PUT", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) DeleteEnginePool(name string) error {
+	if _, err := m.doRequest(deleteEnginePoolPath(name), "DELETE", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/shipyard/shipyard"
+)
+
+// Quotas returns every quota with the running containers counted against
+// it
+func (m *Manager) Quotas() ([]*shipyard.QuotaUsage, error) {
+	quotas := []*shipyard.QuotaUsage{}
+	resp, err := m.doRequest(quotasPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&quotas); err != nil {
+		return nil, err
+	}
+	return quotas, nil
+}
+
+func (m *Manager) CreateQuota(quota *shipyard.Quota) (*shipyard.Quota, error) {
+	b, err := json.Marshal(quota)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(createQuotaPath(), "POST", 201, b)
+	if err != nil {
+		return nil, err
+	}
+	var created *shipyard.Quota
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (m *Manager) DeleteQuota(name string) error {
+	if _, err := m.doRequest(deleteQuotaPath(name), "DELETE", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
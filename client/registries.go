@@ -0,0 +1,40 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/shipyard/shipyard"
+)
+
+// Registries returns the configured registries; passwords are not returned
+func (m *Manager) Registries() ([]*shipyard.Registry, error) {
+	registries := []*shipyard.Registry{}
+	resp, err := m.doRequest(registriesPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&registries); err != nil {
+		return nil, err
+	}
+	return registries, nil
+}
+
+// AddRegistry stores a registry whose credentials are used when pulling
+// images hosted on it
+func (m *Manager) AddRegistry(registry *shipyard.Registry) error {
+	b, err := json.Marshal(registry)
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(addRegistryPath(), "POST", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) RemoveRegistry(name string) error {
+	if _, err := m.doRequest(removeRegistryPath(name), "DELETE", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/shipyard/shipyard"
+)
+
+// EventRetention returns the event retention policy without the archive
+// secret key
+func (m *Manager) EventRetention() (*shipyard.EventRetention, error) {
+	var policy *shipyard.EventRetention
+	resp, err := m.doRequest(eventRetentionPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// SetEventRetention updates the event retention policy; an archive without
+// a secret key keeps the current one
+func (m *Manager) SetEventRetention(policy *shipyard.EventRetention) error {
+	b, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(setEventRetentionPath(), "PUT", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ApplyEventRetention archives and removes the expired events now
+func (m *Manager) ApplyEventRetention() (*shipyard.EventRetentionResult, error) {
+	var result *shipyard.EventRetentionResult
+	resp, err := m.doRequest(runEventRetentionPath(), "POST", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
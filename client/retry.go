@@ -0,0 +1,157 @@
+package client
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultMaxRateLimitWait is the longest a request waits for rate limits
+// when the config does not set MaxRateLimitWait
+const DefaultMaxRateLimitWait = 30 * time.Second
+
+// RetryPolicy controls how idempotent (GET and DELETE) requests, and POST,
+// PUT and PATCH requests with an idempotency key, are retried after a
+// connection error or a retryable response status
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts; values below 2 disable
+	// retries
+	MaxAttempts    int           `json:"max_attempts,omitempty"`
+	InitialBackoff time.Duration `json:"initial_backoff,omitempty"`
+	MaxBackoff     time.Duration `json:"max_backoff,omitempty"`
+	// Jitter randomizes each backoff by up to this fraction of its value
+	Jitter        float64 `json:"jitter,omitempty"`
+	RetryOnStatus []int   `json:"retry_on_status,omitempty"`
+}
+
+// DefaultRetryPolicy is used when the config does not set one
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Jitter:         0.2,
+	RetryOnStatus: []int{
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+// backoff returns the delay before the attempt following attempt n (1 based)
+func (p *RetryPolicy) backoff(n int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < n && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+	return d
+}
+
+func (p *RetryPolicy) retryStatus(code int) bool {
+	for _, s := range p.RetryOnStatus {
+		if s == code {
+			return true
+		}
+	}
+	return false
+}
+
+// maxAttempts returns how many times a request with method may be sent.
+// Requests other than GET and DELETE are only retried with an idempotency
+// key.
+func (m *Manager) maxAttempts(method string, keyed bool) int {
+	if m.config.DisableRetry || (method != "GET" && method != "DELETE" && !keyed) {
+		return 1
+	}
+	if p := m.retryPolicy(); p.MaxAttempts > 1 {
+		return p.MaxAttempts
+	}
+	return 1
+}
+
+// keyedMethod reports whether requests with method carry an idempotency
+// key
+func keyedMethod(method string) bool {
+	return method == "POST" || method == "PUT" || method == "PATCH"
+}
+
+func (m *Manager) retryPolicy() *RetryPolicy {
+	if m.config.Retry != nil {
+		return m.config.Retry
+	}
+	return &DefaultRetryPolicy
+}
+
+// shouldRetry reports whether the outcome of an attempt is worth retrying.
+// A response being discarded is drained and closed.
+func (m *Manager) shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		// a cancelled or expired context is final
+		return m.ctx.Err() == nil
+	}
+	if !m.retryPolicy().retryStatus(resp.StatusCode) {
+		return false
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	return true
+}
+
+// wait sleeps for d or until the manager context is done
+func (m *Manager) wait(d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-m.ctx.Done():
+		return m.ctx.Err()
+	}
+}
+
+// retryAfter returns the delay asked for by the Retry-After header of a
+// response, given in seconds or as a date
+func retryAfter(resp *http.Response) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(time.Now()) {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// rateLimitWait returns how long to wait before resending a rate limited
+// request when the config waits for rate limits and the total wait stays
+// within MaxRateLimitWait.  The discarded response is drained and closed.
+func (m *Manager) rateLimitWait(resp *http.Response, waited time.Duration) (time.Duration, bool) {
+	if !m.config.WaitOnRateLimit {
+		return 0, false
+	}
+	max := m.config.MaxRateLimitWait
+	if max <= 0 {
+		max = DefaultMaxRateLimitWait
+	}
+	d := retryAfter(resp)
+	if d <= 0 {
+		d = time.Second
+	}
+	if waited+d > max {
+		return 0, false
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	return d, true
+}
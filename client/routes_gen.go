@@ -0,0 +1,800 @@
+// Code generated by genroutes.go from the controller openapi spec; DO NOT EDIT.
+
+package client
+
+import "fmt"
+
+// disable2FAPath is the path of DELETE /account/2fa
+func disable2FAPath() string {
+	return "/account/2fa"
+}
+
+// enable2FAPath is the path of POST /account/2fa
+func enable2FAPath() string {
+	return "/account/2fa"
+}
+
+// confirm2FAPath is the path of POST /account/2fa/confirm
+func confirm2FAPath() string {
+	return "/account/2fa/confirm"
+}
+
+// changePasswordPath is the path of POST /account/changepassword
+func changePasswordPath() string {
+	return "/account/changepassword"
+}
+
+// logoutPath is the path of POST /account/logout
+func logoutPath() string {
+	return "/account/logout"
+}
+
+// refreshTokenPath is the path of POST /account/token
+func refreshTokenPath() string {
+	return "/account/token"
+}
+
+// deleteAccountPath is the path of DELETE /api/accounts
+func deleteAccountPath() string {
+	return "/api/accounts"
+}
+
+// accountsPath is the path of GET /api/accounts
+func accountsPath() string {
+	return "/api/accounts"
+}
+
+// addAccountPath is the path of POST /api/accounts
+func addAccountPath() string {
+	return "/api/accounts"
+}
+
+// updateAccountPath is the path of PUT /api/accounts
+func updateAccountPath() string {
+	return "/api/accounts"
+}
+
+// setAccountNamespaceRolePath is the path of PUT /api/accounts/{username}/namespaces/{namespace}
+func setAccountNamespaceRolePath(username, namespace string) string {
+	return fmt.Sprintf("/api/accounts/%s/namespaces/%s", username, namespace)
+}
+
+// setAccountRolePath is the path of PUT /api/accounts/{username}/role
+func setAccountRolePath(username string) string {
+	return fmt.Sprintf("/api/accounts/%s/role", username)
+}
+
+// alertsPath is the path of GET /api/alerts
+func alertsPath() string {
+	return "/api/alerts"
+}
+
+// alertRulesPath is the path of GET /api/alerts/rules
+func alertRulesPath() string {
+	return "/api/alerts/rules"
+}
+
+// addAlertRulePath is the path of POST /api/alerts/rules
+func addAlertRulePath() string {
+	return "/api/alerts/rules"
+}
+
+// removeAlertRulePath is the path of DELETE /api/alerts/rules/{id}
+func removeAlertRulePath(id string) string {
+	return fmt.Sprintf("/api/alerts/rules/%s", id)
+}
+
+// applicationsPath is the path of GET /api/applications
+func applicationsPath() string {
+	return "/api/applications"
+}
+
+// createApplicationPath is the path of POST /api/applications
+func createApplicationPath() string {
+	return "/api/applications"
+}
+
+// deployComposePath is the path of POST /api/applications/compose
+func deployComposePath() string {
+	return "/api/applications/compose"
+}
+
+// removeApplicationPath is the path of DELETE /api/applications/{name}
+func removeApplicationPath(name string) string {
+	return fmt.Sprintf("/api/applications/%s", name)
+}
+
+// applicationPath is the path of GET /api/applications/{name}
+func applicationPath(name string) string {
+	return fmt.Sprintf("/api/applications/%s", name)
+}
+
+// updateApplicationPath is the path of PUT /api/applications/{name}
+func updateApplicationPath(name string) string {
+	return fmt.Sprintf("/api/applications/%s", name)
+}
+
+// applicationContainersPath is the path of GET /api/applications/{name}/containers
+func applicationContainersPath(name string) string {
+	return fmt.Sprintf("/api/applications/%s/containers", name)
+}
+
+// deployApplicationPath is the path of POST /api/applications/{name}/deploy
+func deployApplicationPath(name string) string {
+	return fmt.Sprintf("/api/applications/%s/deploy", name)
+}
+
+// diffApplicationPath is the path of POST /api/applications/{name}/diff
+func diffApplicationPath(name string) string {
+	return fmt.Sprintf("/api/applications/%s/diff", name)
+}
+
+// rollbackApplicationPath is the path of POST /api/applications/{name}/rollback
+func rollbackApplicationPath(name string) string {
+	return fmt.Sprintf("/api/applications/%s/rollback", name)
+}
+
+// auditLogPath is the path of GET /api/audit
+func auditLogPath() string {
+	return "/api/audit"
+}
+
+// backupPath is the path of GET /api/backup
+func backupPath() string {
+	return "/api/backup"
+}
+
+// restoreBackupPath is the path of POST /api/backup/restore
+func restoreBackupPath() string {
+	return "/api/backup/restore"
+}
+
+// clusterInfoPath is the path of GET /api/cluster/info
+func clusterInfoPath() string {
+	return "/api/cluster/info"
+}
+
+// rebalancePath is the path of POST /api/cluster/rebalance
+func rebalancePath() string {
+	return "/api/cluster/rebalance"
+}
+
+// clusterUsagePath is the path of GET /api/cluster/usage
+func clusterUsagePath() string {
+	return "/api/cluster/usage"
+}
+
+// configsPath is the path of GET /api/configs
+func configsPath() string {
+	return "/api/configs"
+}
+
+// createConfigPath is the path of POST /api/configs
+func createConfigPath() string {
+	return "/api/configs"
+}
+
+// deleteConfigPath is the path of DELETE /api/configs/{name}
+func deleteConfigPath(name string) string {
+	return fmt.Sprintf("/api/configs/%s", name)
+}
+
+// configPath is the path of GET /api/configs/{name}
+func configPath(name string) string {
+	return fmt.Sprintf("/api/configs/%s", name)
+}
+
+// updateConfigPath is the path of PUT /api/configs/{name}
+func updateConfigPath(name string) string {
+	return fmt.Sprintf("/api/configs/%s", name)
+}
+
+// containersPath is the path of GET /api/containers
+func containersPath() string {
+	return "/api/containers"
+}
+
+// runPath is the path of POST /api/containers
+func runPath() string {
+	return "/api/containers"
+}
+
+// batchContainersPath is the path of POST /api/containers/batch
+func batchContainersPath() string {
+	return "/api/containers/batch"
+}
+
+// endpointsPath is the path of GET /api/containers/endpoints
+func endpointsPath() string {
+	return "/api/containers/endpoints"
+}
+
+// containerGroupPath is the path of POST /api/containers/groups/{action}
+func containerGroupPath(action string) string {
+	return fmt.Sprintf("/api/containers/groups/%s", action)
+}
+
+// scaleImagePath is the path of POST /api/containers/scale
+func scaleImagePath() string {
+	return "/api/containers/scale"
+}
+
+// destroyPath is the path of DELETE /api/containers/{id}
+func destroyPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s", id)
+}
+
+// inspectContainerPath is the path of GET /api/containers/{id}
+func inspectContainerPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s", id)
+}
+
+// updateContainerPath is the path of PATCH /api/containers/{id}
+func updateContainerPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s", id)
+}
+
+// copyFromContainerPath is the path of GET /api/containers/{id}/archive
+func copyFromContainerPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/archive", id)
+}
+
+// copyToContainerPath is the path of PUT /api/containers/{id}/archive
+func copyToContainerPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/archive", id)
+}
+
+// attachContainerPath is the path of GET /api/containers/{id}/attach
+func attachContainerPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/attach", id)
+}
+
+// containerChangesPath is the path of GET /api/containers/{id}/changes
+func containerChangesPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/changes", id)
+}
+
+// commitContainerPath is the path of POST /api/containers/{id}/commit
+func commitContainerPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/commit", id)
+}
+
+// createExecPath is the path of POST /api/containers/{id}/exec
+func createExecPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/exec", id)
+}
+
+// inspectExecPath is the path of GET /api/containers/{id}/exec/{execId}
+func inspectExecPath(id, execId string) string {
+	return fmt.Sprintf("/api/containers/%s/exec/%s", id, execId)
+}
+
+// startExecPath is the path of POST /api/containers/{id}/exec/{execId}/start
+func startExecPath(id, execId string) string {
+	return fmt.Sprintf("/api/containers/%s/exec/%s/start", id, execId)
+}
+
+// exportContainerPath is the path of GET /api/containers/{id}/export
+func exportContainerPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/export", id)
+}
+
+// containerLogsPath is the path of GET /api/containers/{id}/logs
+func containerLogsPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/logs", id)
+}
+
+// containerMetricsPath is the path of GET /api/containers/{id}/metrics
+func containerMetricsPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/metrics", id)
+}
+
+// pauseContainerPath is the path of GET /api/containers/{id}/pause
+func pauseContainerPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/pause", id)
+}
+
+// renameContainerPath is the path of POST /api/containers/{id}/rename
+func renameContainerPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/rename", id)
+}
+
+// restartContainerPath is the path of GET /api/containers/{id}/restart
+func restartContainerPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/restart", id)
+}
+
+// scaleContainerPath is the path of GET /api/containers/{id}/scale
+func scaleContainerPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/scale", id)
+}
+
+// startContainerPath is the path of GET /api/containers/{id}/start
+func startContainerPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/start", id)
+}
+
+// containerStatsPath is the path of GET /api/containers/{id}/stats
+func containerStatsPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/stats", id)
+}
+
+// stopContainerPath is the path of GET /api/containers/{id}/stop
+func stopContainerPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/stop", id)
+}
+
+// containerTopPath is the path of GET /api/containers/{id}/top
+func containerTopPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/top", id)
+}
+
+// unpauseContainerPath is the path of GET /api/containers/{id}/unpause
+func unpauseContainerPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/unpause", id)
+}
+
+// waitContainerPath is the path of GET /api/containers/{id}/wait
+func waitContainerPath(id string) string {
+	return fmt.Sprintf("/api/containers/%s/wait", id)
+}
+
+// deploymentsPath is the path of GET /api/deployments
+func deploymentsPath() string {
+	return "/api/deployments"
+}
+
+// deploymentPath is the path of GET /api/deployments/{id}
+func deploymentPath(id string) string {
+	return fmt.Sprintf("/api/deployments/%s", id)
+}
+
+// promoteDeploymentPath is the path of POST /api/deployments/{id}/promote
+func promoteDeploymentPath(id string) string {
+	return fmt.Sprintf("/api/deployments/%s/promote", id)
+}
+
+// enginesPath is the path of GET /api/engines
+func enginesPath() string {
+	return "/api/engines"
+}
+
+// addEnginePath is the path of POST /api/engines
+func addEnginePath() string {
+	return "/api/engines"
+}
+
+// joinTokensPath is the path of GET /api/engines/join-tokens
+func joinTokensPath() string {
+	return "/api/engines/join-tokens"
+}
+
+// createJoinTokenPath is the path of POST /api/engines/join-tokens
+func createJoinTokenPath() string {
+	return "/api/engines/join-tokens"
+}
+
+// deleteJoinTokenPath is the path of DELETE /api/engines/join-tokens/{id}
+func deleteJoinTokenPath(id string) string {
+	return fmt.Sprintf("/api/engines/join-tokens/%s", id)
+}
+
+// removeEnginePath is the path of DELETE /api/engines/{id}
+func removeEnginePath(id string) string {
+	return fmt.Sprintf("/api/engines/%s", id)
+}
+
+// inspectEnginePath is the path of GET /api/engines/{id}
+func inspectEnginePath(id string) string {
+	return fmt.Sprintf("/api/engines/%s", id)
+}
+
+// updateEnginePath is the path of PUT /api/engines/{id}
+func updateEnginePath(id string) string {
+	return fmt.Sprintf("/api/engines/%s", id)
+}
+
+// engineCertificatesPath is the path of GET /api/engines/{id}/certificates
+func engineCertificatesPath(id string) string {
+	return fmt.Sprintf("/api/engines/%s/certificates", id)
+}
+
+// setEngineCertificatesPath is the path of PUT /api/engines/{id}/certificates
+func setEngineCertificatesPath(id string) string {
+	return fmt.Sprintf("/api/engines/%s/certificates", id)
+}
+
+// cordonEnginePath is the path of GET /api/engines/{id}/cordon
+func cordonEnginePath(id string) string {
+	return fmt.Sprintf("/api/engines/%s/cordon", id)
+}
+
+// drainEnginePath is the path of GET /api/engines/{id}/drain
+func drainEnginePath(id string) string {
+	return fmt.Sprintf("/api/engines/%s/drain", id)
+}
+
+// engineHealthPath is the path of GET /api/engines/{id}/health
+func engineHealthPath(id string) string {
+	return fmt.Sprintf("/api/engines/%s/health", id)
+}
+
+// updateEngineLabelsPath is the path of PUT /api/engines/{id}/labels
+func updateEngineLabelsPath(id string) string {
+	return fmt.Sprintf("/api/engines/%s/labels", id)
+}
+
+// engineMetricsPath is the path of GET /api/engines/{id}/metrics
+func engineMetricsPath(id string) string {
+	return fmt.Sprintf("/api/engines/%s/metrics", id)
+}
+
+// uncordonEnginePath is the path of GET /api/engines/{id}/uncordon
+func uncordonEnginePath(id string) string {
+	return fmt.Sprintf("/api/engines/%s/uncordon", id)
+}
+
+// purgeEventsPath is the path of DELETE /api/events
+func purgeEventsPath() string {
+	return "/api/events"
+}
+
+// eventsPath is the path of GET /api/events
+func eventsPath() string {
+	return "/api/events"
+}
+
+// eventRetentionPath is the path of GET /api/events/retention
+func eventRetentionPath() string {
+	return "/api/events/retention"
+}
+
+// setEventRetentionPath is the path of PUT /api/events/retention
+func setEventRetentionPath() string {
+	return "/api/events/retention"
+}
+
+// runEventRetentionPath is the path of POST /api/events/retention/run
+func runEventRetentionPath() string {
+	return "/api/events/retention/run"
+}
+
+// streamEventsPath is the path of GET /api/events/stream
+func streamEventsPath() string {
+	return "/api/events/stream"
+}
+
+// extensionsPath is the path of GET /api/extensions
+func extensionsPath() string {
+	return "/api/extensions"
+}
+
+// addExtensionPath is the path of POST /api/extensions
+func addExtensionPath() string {
+	return "/api/extensions"
+}
+
+// deleteExtensionPath is the path of DELETE /api/extensions/{id}
+func deleteExtensionPath(id string) string {
+	return fmt.Sprintf("/api/extensions/%s", id)
+}
+
+// extensionPath is the path of GET /api/extensions/{id}
+func extensionPath(id string) string {
+	return fmt.Sprintf("/api/extensions/%s", id)
+}
+
+// gcPolicyPath is the path of GET /api/gc/policy
+func gcPolicyPath() string {
+	return "/api/gc/policy"
+}
+
+// setGCPolicyPath is the path of PUT /api/gc/policy
+func setGCPolicyPath() string {
+	return "/api/gc/policy"
+}
+
+// runGCPath is the path of POST /api/gc/run
+func runGCPath() string {
+	return "/api/gc/run"
+}
+
+// imagesPath is the path of GET /api/images
+func imagesPath() string {
+	return "/api/images"
+}
+
+// buildImagePath is the path of POST /api/images/build
+func buildImagePath() string {
+	return "/api/images/build"
+}
+
+// pullImagePath is the path of POST /api/images/pull
+func pullImagePath() string {
+	return "/api/images/pull"
+}
+
+// removeImagePath is the path of DELETE /api/images/{name}
+func removeImagePath(name string) string {
+	return fmt.Sprintf("/api/images/%s", name)
+}
+
+// jobsPath is the path of GET /api/jobs
+func jobsPath() string {
+	return "/api/jobs"
+}
+
+// createJobPath is the path of POST /api/jobs
+func createJobPath() string {
+	return "/api/jobs"
+}
+
+// removeJobPath is the path of DELETE /api/jobs/{id}
+func removeJobPath(id string) string {
+	return fmt.Sprintf("/api/jobs/%s", id)
+}
+
+// jobPath is the path of GET /api/jobs/{id}
+func jobPath(id string) string {
+	return fmt.Sprintf("/api/jobs/%s", id)
+}
+
+// jobRunsPath is the path of GET /api/jobs/{id}/runs
+func jobRunsPath(id string) string {
+	return fmt.Sprintf("/api/jobs/%s/runs", id)
+}
+
+// namespacesPath is the path of GET /api/namespaces
+func namespacesPath() string {
+	return "/api/namespaces"
+}
+
+// createNamespacePath is the path of POST /api/namespaces
+func createNamespacePath() string {
+	return "/api/namespaces"
+}
+
+// deleteNamespacePath is the path of DELETE /api/namespaces/{name}
+func deleteNamespacePath(name string) string {
+	return fmt.Sprintf("/api/namespaces/%s", name)
+}
+
+// notifiersPath is the path of GET /api/notifiers
+func notifiersPath() string {
+	return "/api/notifiers"
+}
+
+// addNotifierPath is the path of POST /api/notifiers
+func addNotifierPath() string {
+	return "/api/notifiers"
+}
+
+// removeNotifierPath is the path of DELETE /api/notifiers/{id}
+func removeNotifierPath(id string) string {
+	return fmt.Sprintf("/api/notifiers/%s", id)
+}
+
+// pingPath is the path of GET /api/ping
+func pingPath() string {
+	return "/api/ping"
+}
+
+// enginePoolsPath is the path of GET /api/pools
+func enginePoolsPath() string {
+	return "/api/pools"
+}
+
+// createEnginePoolPath is the path of POST /api/pools
+func createEnginePoolPath() string {
+	return "/api/pools"
+}
+
+// deleteEnginePoolPath is the path of DELETE /api/pools/{name}
+func deleteEnginePoolPath(name string) string {
+	return fmt.Sprintf("/api/pools/%s", name)
+}
+
+// enginePoolPath is the path of GET /api/pools/{name}
+func enginePoolPath(name string) string {
+	return fmt.Sprintf("/api/pools/%s", name)
+}
+
+// updateEnginePoolPath is the path of PUT /api/pools/{name}
+func updateEnginePoolPath(name string) string {
+	return fmt.Sprintf("/api/pools/%s", name)
+}
+
+// quotasPath is the path of GET /api/quotas
+func quotasPath() string {
+	return "/api/quotas"
+}
+
+// createQuotaPath is the path of POST /api/quotas
+func createQuotaPath() string {
+	return "/api/quotas"
+}
+
+// deleteQuotaPath is the path of DELETE /api/quotas/{name}
+func deleteQuotaPath(name string) string {
+	return fmt.Sprintf("/api/quotas/%s", name)
+}
+
+// registriesPath is the path of GET /api/registries
+func registriesPath() string {
+	return "/api/registries"
+}
+
+// addRegistryPath is the path of POST /api/registries
+func addRegistryPath() string {
+	return "/api/registries"
+}
+
+// removeRegistryPath is the path of DELETE /api/registries/{name}
+func removeRegistryPath(name string) string {
+	return fmt.Sprintf("/api/registries/%s", name)
+}
+
+// deleteRolePath is the path of DELETE /api/roles
+func deleteRolePath() string {
+	return "/api/roles"
+}
+
+// rolesPath is the path of GET /api/roles
+func rolesPath() string {
+	return "/api/roles"
+}
+
+// addRolePath is the path of POST /api/roles
+func addRolePath() string {
+	return "/api/roles"
+}
+
+// updateRolePath is the path of PUT /api/roles
+func updateRolePath() string {
+	return "/api/roles"
+}
+
+// rolePath is the path of GET /api/roles/{name}
+func rolePath(name string) string {
+	return fmt.Sprintf("/api/roles/%s", name)
+}
+
+// secretsPath is the path of GET /api/secrets
+func secretsPath() string {
+	return "/api/secrets"
+}
+
+// createSecretPath is the path of POST /api/secrets
+func createSecretPath() string {
+	return "/api/secrets"
+}
+
+// deleteSecretPath is the path of DELETE /api/secrets/{name}
+func deleteSecretPath(name string) string {
+	return fmt.Sprintf("/api/secrets/%s", name)
+}
+
+// secretValuePath is the path of GET /api/secrets/{name}/value
+func secretValuePath(name string) string {
+	return fmt.Sprintf("/api/secrets/%s/value", name)
+}
+
+// removeServiceKeyPath is the path of DELETE /api/servicekeys
+func removeServiceKeyPath() string {
+	return "/api/servicekeys"
+}
+
+// serviceKeysPath is the path of GET /api/servicekeys
+func serviceKeysPath() string {
+	return "/api/servicekeys"
+}
+
+// addServiceKeyPath is the path of POST /api/servicekeys
+func addServiceKeyPath() string {
+	return "/api/servicekeys"
+}
+
+// openAPISpecPath is the path of GET /api/spec
+func openAPISpecPath() string {
+	return "/api/spec"
+}
+
+// templatesPath is the path of GET /api/templates
+func templatesPath() string {
+	return "/api/templates"
+}
+
+// createTemplatePath is the path of POST /api/templates
+func createTemplatePath() string {
+	return "/api/templates"
+}
+
+// deleteTemplatePath is the path of DELETE /api/templates/{name}
+func deleteTemplatePath(name string) string {
+	return fmt.Sprintf("/api/templates/%s", name)
+}
+
+// templatePath is the path of GET /api/templates/{name}
+func templatePath(name string) string {
+	return fmt.Sprintf("/api/templates/%s", name)
+}
+
+// updateTemplatePath is the path of PUT /api/templates/{name}
+func updateTemplatePath(name string) string {
+	return fmt.Sprintf("/api/templates/%s", name)
+}
+
+// runTemplatePath is the path of POST /api/templates/{name}/run
+func runTemplatePath(name string) string {
+	return fmt.Sprintf("/api/templates/%s/run", name)
+}
+
+// versionInfoPath is the path of GET /api/version
+func versionInfoPath() string {
+	return "/api/version"
+}
+
+// volumesPath is the path of GET /api/volumes
+func volumesPath() string {
+	return "/api/volumes"
+}
+
+// createVolumePath is the path of POST /api/volumes
+func createVolumePath() string {
+	return "/api/volumes"
+}
+
+// removeVolumePath is the path of DELETE /api/volumes/{name}
+func removeVolumePath(name string) string {
+	return fmt.Sprintf("/api/volumes/%s", name)
+}
+
+// webhookKeysPath is the path of GET /api/webhookkeys
+func webhookKeysPath() string {
+	return "/api/webhookkeys"
+}
+
+// addWebhookKeyPath is the path of POST /api/webhookkeys
+func addWebhookKeyPath() string {
+	return "/api/webhookkeys"
+}
+
+// deleteWebhookKeyPath is the path of DELETE /api/webhookkeys/{id}
+func deleteWebhookKeyPath(id string) string {
+	return fmt.Sprintf("/api/webhookkeys/%s", id)
+}
+
+// webhookKeyPath is the path of GET /api/webhookkeys/{id}
+func webhookKeyPath(id string) string {
+	return fmt.Sprintf("/api/webhookkeys/%s", id)
+}
+
+// webhooksPath is the path of GET /api/webhooks
+func webhooksPath() string {
+	return "/api/webhooks"
+}
+
+// addWebhookPath is the path of POST /api/webhooks
+func addWebhookPath() string {
+	return "/api/webhooks"
+}
+
+// removeWebhookPath is the path of DELETE /api/webhooks/{id}
+func removeWebhookPath(id string) string {
+	return fmt.Sprintf("/api/webhooks/%s", id)
+}
+
+// loginPath is the path of POST /auth/login
+func loginPath() string {
+	return "/auth/login"
+}
+
+// hubWebhookPath is the path of POST /hub/webhook/{id}
+func hubWebhookPath(id string) string {
+	return fmt.Sprintf("/hub/webhook/%s", id)
+}
+
+// joinEnginePath is the path of POST /join
+func joinEnginePath() string {
+	return "/join"
+}
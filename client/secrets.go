@@ -0,0 +1,59 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/shipyard/shipyard"
+)
+
+// Secrets returns the stored secrets without their values
+func (m *Manager) Secrets() ([]*shipyard.Secret, error) {
+	secrets := []*shipyard.Secret{}
+	resp, err := m.doRequest(secretsPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secrets); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// Secret returns a secret with its value; it requires secrets:admin
+func (m *Manager) Secret(name string) (*shipyard.Secret, error) {
+	var secret *shipyard.Secret
+	resp, err := m.doRequest(secretValuePath(name), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+func (m *Manager) CreateSecret(name string, value string) (*shipyard.Secret, error) {
+	b, err := json.Marshal(&shipyard.Secret{
+		Name:  name,
+		Value: value,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(createSecretPath(), "POST", 201, b)
+	if err != nil {
+		return nil, err
+	}
+	var created *shipyard.Secret
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (m *Manager) DeleteSecret(name string) error {
+	if _, err := m.doRequest(deleteSecretPath(name), "DELETE", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
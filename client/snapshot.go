@@ -0,0 +1,66 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+// SnapshotEvents is the number of recent events in a snapshot
+const SnapshotEvents = 50
+
+// ClusterSnapshot is the state a dashboard renders: the containers, engines,
+// most recent events and info of the cluster
+type ClusterSnapshot struct {
+	Containers []*citadel.Container  `json:"containers"`
+	Engines    []*shipyard.Engine    `json:"engines"`
+	Events     []*shipyard.Event     `json:"events"`
+	Info       *shipyard.ClusterInfo `json:"info"`
+}
+
+// Snapshot fetches the containers, engines, the SnapshotEvents most recent
+// events and the cluster info concurrently.  It returns the first error of
+// the requests; the snapshot is only returned when all of them succeed.
+func (m *Manager) Snapshot() (*ClusterSnapshot, error) {
+	snapshot := &ClusterSnapshot{}
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	fetch := func(f func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f(); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	fetch(func() (err error) {
+		snapshot.Containers, err = m.Containers()
+		return err
+	})
+	fetch(func() (err error) {
+		snapshot.Engines, err = m.Engines()
+		return err
+	})
+	fetch(func() (err error) {
+		snapshot.Events, err = m.Events(&shipyard.EventQuery{Limit: SnapshotEvents})
+		return err
+	})
+	fetch(func() (err error) {
+		snapshot.Info, err = m.Info()
+		return err
+	})
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/shipyard/shipyard"
+)
+
+// Stats streams periodic resource usage samples for a container.  The
+// channel is closed when the stream ends or the manager context is done.
+func (m *Manager) Stats(containerID string) (<-chan *shipyard.ContainerStats, error) {
+	resp, err := m.doRequest(containerStatsPath(containerID), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	c := make(chan *shipyard.ContainerStats)
+	go func() {
+		defer close(c)
+		defer resp.Body.Close()
+		dec := json.NewDecoder(resp.Body)
+		for {
+			var stats *shipyard.ContainerStats
+			if err := dec.Decode(&stats); err != nil {
+				return
+			}
+			select {
+			case c <- stats:
+			case <-m.ctx.Done():
+				return
+			}
+		}
+	}()
+	return c, nil
+}
+
+// Top returns the processes running in a container.  psArgs are passed to
+// ps on the engine; empty uses the docker default.
+func (m *Manager) Top(containerID string, psArgs string) (*shipyard.ProcessList, error) {
+	path := containerTopPath(containerID)
+	if psArgs != "" {
+		v := url.Values{}
+		v.Set("ps_args", psArgs)
+		path += "?" + v.Encode()
+	}
+	resp, err := m.doRequest(path, "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	var processes *shipyard.ProcessList
+	if err := json.NewDecoder(resp.Body).Decode(&processes); err != nil {
+		return nil, err
+	}
+	return processes, nil
+}
+
+// Metrics returns the resource usage samples the controller took of a
+// container or engine since a time, oldest first.  resource is
+// shipyard.MetricsContainers or shipyard.MetricsEngines; a zero since
+// returns every kept sample.
+func (m *Manager) Metrics(resource string, id string, since time.Time) ([]*shipyard.MetricSample, error) {
+	var path string
+	switch resource {
+	case shipyard.MetricsContainers:
+		path = containerMetricsPath(id)
+	case shipyard.MetricsEngines:
+		path = engineMetricsPath(id)
+	default:
+		return nil, shipyard.ValidateMetricsResource(resource)
+	}
+	if !since.IsZero() {
+		v := url.Values{}
+		v.Set("since", since.Format(time.RFC3339))
+		path += "?" + v.Encode()
+	}
+	resp, err := m.doRequest(path, "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	samples := []*shipyard.MetricSample{}
+	if err := json.NewDecoder(resp.Body).Decode(&samples); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+func (m *Manager) Templates() ([]*shipyard.LaunchTemplate, error) {
+	templates := []*shipyard.LaunchTemplate{}
+	resp, err := m.doRequest(templatesPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (m *Manager) Template(name string) (*shipyard.LaunchTemplate, error) {
+	var t *shipyard.LaunchTemplate
+	resp, err := m.doRequest(templatePath(name), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (m *Manager) CreateTemplate(t *shipyard.LaunchTemplate) (*shipyard.LaunchTemplate, error) {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(createTemplatePath(), "POST", 201, b)
+	if err != nil {
+		return nil, err
+	}
+	var created *shipyard.LaunchTemplate
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateTemplate replaces a template; containers already run from it are
+// left unchanged
+func (m *Manager) UpdateTemplate(t *shipyard.LaunchTemplate) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if _, err := m.doRequest(updateTemplatePath(t.Name), "PUT", 204, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) DeleteTemplate(name string) error {
+	if _, err := m.doRequest(deleteTemplatePath(name), "DELETE", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RunTemplate launches count containers of a template; overrides may be nil
+func (m *Manager) RunTemplate(name string, overrides *shipyard.TemplateOverrides, count int, pull bool) ([]*citadel.Container, error) {
+	if overrides == nil {
+		overrides = &shipyard.TemplateOverrides{}
+	}
+	b, err := json.Marshal(overrides)
+	if err != nil {
+		return nil, err
+	}
+	var containers []*citadel.Container
+	resp, err := m.doRequest(fmt.Sprintf("%s?count=%d&pull=%v", runTemplatePath(name), count, pull), "POST", 201, b)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, err
+	}
+	return containers, nil
+}
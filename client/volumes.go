@@ -0,0 +1,44 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/shipyard/shipyard"
+)
+
+func (m *Manager) Volumes() ([]*shipyard.Volume, error) {
+	volumes := []*shipyard.Volume{}
+	resp, err := m.doRequest(volumesPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&volumes); err != nil {
+		return nil, err
+	}
+	return volumes, nil
+}
+
+// CreateVolume registers a volume on an engine; the path defaults to a
+// directory under shipyard.VolumeRoot
+func (m *Manager) CreateVolume(volume *shipyard.Volume) (*shipyard.Volume, error) {
+	b, err := json.Marshal(volume)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(createVolumePath(), "POST", 201, b)
+	if err != nil {
+		return nil, err
+	}
+	var created *shipyard.Volume
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (m *Manager) RemoveVolume(name string) error {
+	if _, err := m.doRequest(removeVolumePath(name), "DELETE", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
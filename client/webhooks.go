@@ -0,0 +1,46 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/shipyard/shipyard"
+)
+
+// Webhooks returns the registered event webhooks; secrets are not returned
+func (m *Manager) Webhooks() ([]*shipyard.Webhook, error) {
+	hooks := []*shipyard.Webhook{}
+	resp, err := m.doRequest(webhooksPath(), "GET", 200, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&hooks); err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// AddWebhook registers a url to receive cluster events.  The returned
+// webhook holds the secret used to sign deliveries, generated by the
+// controller if none was given.
+func (m *Manager) AddWebhook(hook *shipyard.Webhook) (*shipyard.Webhook, error) {
+	b, err := json.Marshal(hook)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.doRequest(addWebhookPath(), "POST", 200, b)
+	if err != nil {
+		return nil, err
+	}
+	var h *shipyard.Webhook
+	if err := json.NewDecoder(resp.Body).Decode(&h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (m *Manager) RemoveWebhook(id string) error {
+	if _, err := m.doRequest(removeWebhookPath(id), "DELETE", 204, nil); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidCommand = errors.New("invalid command")
+)
+
+// SplitCommand splits a command line into its arguments like a shell
+// would, without expanding anything: single quotes keep their content as
+// is, double quotes keep it but for backslash escapes of \ and " and a
+// backslash outside quotes escapes the next character
+func SplitCommand(s string) ([]string, error) {
+	var (
+		args    []string
+		arg     strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			if quote == '"' && r != '"' && r != '\\' {
+				arg.WriteRune('\\')
+			}
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if escaped || quote != 0 {
+		return nil, fmt.Errorf("%w: unterminated quote or escape in %q", ErrInvalidCommand, s)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,32 @@
+package shipyard
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	cases := map[string][]string{
+		`nginx -s quit`:                        {"nginx", "-s", "quit"},
+		`  sh   -c 'sleep 5 && kill -TERM 1' `: {"sh", "-c", "sleep 5 && kill -TERM 1"},
+		`echo "a \"b\" c\n" ''`:                {"echo", `a "b" c\n`, ""},
+		`touch my\ file`:                       {"touch", "my file"},
+		``:                                     nil,
+	}
+	for s, expected := range cases {
+		args, err := SplitCommand(s)
+		if err != nil {
+			t.Errorf("%s: %s", s, err)
+			continue
+		}
+		if !reflect.DeepEqual(args, expected) {
+			t.Errorf("%s: expected %q; received %q", s, expected, args)
+		}
+	}
+	for _, s := range []string{`echo 'a`, `echo "a`, `echo a\`} {
+		if _, err := SplitCommand(s); !errors.Is(err, ErrInvalidCommand) {
+			t.Errorf("%s: expected ErrInvalidCommand; received %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/citadel/citadel"
+)
+
+var (
+	ErrInvalidCompose = errors.New("invalid compose file")
+
+	// composeIgnored are compose options without effect on shipyard
+	// applications; the reconciler always replaces stopped containers
+	composeIgnored = map[string]bool{
+		"restart": true,
+		"expose":  true,
+	}
+)
+
+// ComposeService is a service of a compose file
+type ComposeService struct {
+	Name        string
+	Image       string
+	Command     []string
+	Environment map[string]string
+	Ports       []*citadel.Port
+	// Links are the aliases of linked services by service name
+	Links   map[string]string
+	Volumes []*VolumeMount
+	// Memory is the memory limit in MB
+	Memory float64
+	// DependsOn are the services started before this one
+	DependsOn []string
+	Labels    map[string]string
+}
+
+// ParseCompose parses a version 1 or version 2 compose file.  Services
+// must use an image; build, host path volumes and options not listed in
+// ComposeService are rejected rather than silently dropped.  Services are
+// returned in start order with linked services first.
+func ParseCompose(r io.Reader) ([]*ComposeService, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCompose, err)
+	}
+	root, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: expected a mapping of services", ErrInvalidCompose)
+	}
+	defs := root
+	if v, ok := root["version"]; ok {
+		version, _ := v.(string)
+		if version != "2" && !strings.HasPrefix(version, "2.") {
+			return nil, fmt.Errorf("%w: unsupported version %q", ErrInvalidCompose, version)
+		}
+		for k := range root {
+			if k != "version" && k != "services" {
+				return nil, fmt.Errorf("%w: top level %s is not supported", ErrInvalidCompose, k)
+			}
+		}
+		if defs, ok = root["services"].(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("%w: services must be a mapping", ErrInvalidCompose)
+		}
+	}
+	if len(defs) == 0 {
+		return nil, fmt.Errorf("%w: no services", ErrInvalidCompose)
+	}
+
+	services := map[string]*ComposeService{}
+	for name, def := range defs {
+		opts, ok := def.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: service %s must be a mapping", ErrInvalidCompose, name)
+		}
+		s, err := parseComposeService(name, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%w: service %s: %s", ErrInvalidCompose, name, err)
+		}
+		services[name] = s
+	}
+	return sortComposeServices(services)
+}
+
+func parseComposeService(name string, opts map[string]interface{}) (*ComposeService, error) {
+	if !secretNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid name")
+	}
+	s := &ComposeService{
+		Name:        name,
+		Environment: map[string]string{},
+		Links:       map[string]string{},
+	}
+	for k, v := range opts {
+		var err error
+		switch k {
+		case "image":
+			s.Image, err = composeString(v)
+		case "command":
+			s.Command, err = composeCommand(v)
+		case "environment":
+			s.Environment, err = composeEnvironment(v)
+		case "ports":
+			s.Ports, err = composePorts(v)
+		case "links":
+			s.Links, err = composeLinks(v)
+		case "volumes":
+			s.Volumes, err = composeVolumes(v)
+		case "mem_limit":
+			s.Memory, err = composeMemory(v)
+		case "depends_on":
+			s.DependsOn, err = composeList(v)
+		case "labels":
+			s.Labels, err = composeEnvironment(v)
+		case "build":
+			err = fmt.Errorf("build is not supported; push the image to a registry")
+		default:
+			if !composeIgnored[k] {
+				err = fmt.Errorf("%s is not supported", k)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.Image == "" {
+		return nil, fmt.Errorf("image is required")
+	}
+	return s, nil
+}
+
+// sortComposeServices orders services so each one follows the services it
+// links to or depends on
+func sortComposeServices(services map[string]*ComposeService) ([]*ComposeService, error) {
+	names := []string{}
+	for n := range services {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	sorted := []*ComposeService{}
+	state := map[string]int{} // 1 visiting, 2 done
+	var visit func(n string, from string) error
+	visit = func(n string, from string) error {
+		s, ok := services[n]
+		if !ok {
+			return fmt.Errorf("%w: service %s refers to unknown service %s", ErrInvalidCompose, from, n)
+		}
+		switch state[n] {
+		case 1:
+			return fmt.Errorf("%w: services %s and %s depend on each other", ErrInvalidCompose, from, n)
+		case 2:
+			return nil
+		}
+		state[n] = 1
+		deps := append([]string{}, s.DependsOn...)
+		for l := range s.Links {
+			deps = append(deps, l)
+		}
+		sort.Strings(deps)
+		for _, d := range deps {
+			if err := visit(d, n); err != nil {
+				return err
+			}
+		}
+		state[n] = 2
+		sorted = append(sorted, s)
+		return nil
+	}
+	for _, n := range names {
+		if err := visit(n, n); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// Application returns the application running a compose service of a
+// project.  Links refer to the applications of the linked services.
+func (s *ComposeService) Application(project string) *Application {
+	links := map[string]string{}
+	for service, alias := range s.Links {
+		links[ComposeApplicationName(project, service)] = alias
+	}
+	return &Application{
+		Name:        ComposeApplicationName(project, s.Name),
+		Project:     project,
+		Image:       s.Image,
+		Count:       1,
+		Memory:      s.Memory,
+		Args:        s.Command,
+		Environment: s.Environment,
+		Ports:       s.Ports,
+		Links:       links,
+		Volumes:     s.Volumes,
+		Labels:      s.Labels,
+	}
+}
+
+// ValidateProjectName checks a compose project name
+func ValidateProjectName(project string) error {
+	if !secretNamePattern.MatchString(project) {
+		return fmt.Errorf("%w: project name must be letters, digits, '_', '.' or '-'", ErrInvalidCompose)
+	}
+	return nil
+}
+
+// ComposeApplicationName is the name of the application of a compose
+// service
+func ComposeApplicationName(project string, service string) string {
+	return project + "_" + service
+}
+
+func composeString(v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("expected a string")
+	}
+	return s, nil
+}
+
+func composeList(v interface{}) ([]string, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list")
+	}
+	list := []string{}
+	for _, i := range items {
+		s, ok := i.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a list of strings")
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// composeCommand splits a command given as a string on spaces; use the
+// list form for arguments containing spaces
+func composeCommand(v interface{}) ([]string, error) {
+	if s, ok := v.(string); ok {
+		return strings.Fields(s), nil
+	}
+	return composeList(v)
+}
+
+func composeEnvironment(v interface{}) (map[string]string, error) {
+	env := map[string]string{}
+	if m, ok := v.(map[string]interface{}); ok {
+		for k, val := range m {
+			if val == nil {
+				val = ""
+			}
+			s, ok := val.(string)
+			if !ok {
+				return nil, fmt.Errorf("environment variable %s must be a string", k)
+			}
+			env[k] = s
+		}
+		return env, nil
+	}
+	list, err := composeList(v)
+	if err != nil {
+		return nil, fmt.Errorf("environment: %s", err)
+	}
+	for _, kv := range list {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("environment variable %s has no value", kv)
+		}
+		env[parts[0]] = parts[1]
+	}
+	return env, nil
+}
+
+// composePorts parses [[ip:]host-port:]container-port[/proto]; ports
+// without a host port are published on a random port
+func composePorts(v interface{}) ([]*citadel.Port, error) {
+	list, err := composeList(v)
+	if err != nil {
+		return nil, fmt.Errorf("ports: %s", err)
+	}
+	ports := []*citadel.Port{}
+	for _, spec := range list {
+		p := &citadel.Port{Proto: "tcp"}
+		def := spec
+		if i := strings.Index(def, "/"); i > -1 {
+			p.Proto = def[i+1:]
+			def = def[:i]
+		}
+		if p.Proto != "tcp" && p.Proto != "udp" {
+			return nil, fmt.Errorf("invalid port %s", spec)
+		}
+		parts := strings.Split(def, ":")
+		if len(parts) > 3 {
+			return nil, fmt.Errorf("invalid port %s", spec)
+		}
+		if len(parts) == 3 {
+			p.HostIp = parts[0]
+			parts = parts[1:]
+		}
+		if p.ContainerPort, err = strconv.Atoi(parts[len(parts)-1]); err != nil {
+			return nil, fmt.Errorf("invalid port %s; port ranges are not supported", spec)
+		}
+		if len(parts) == 2 && parts[0] != "" {
+			if p.Port, err = strconv.Atoi(parts[0]); err != nil {
+				return nil, fmt.Errorf("invalid port %s; port ranges are not supported", spec)
+			}
+		}
+		ports = append(ports, p)
+	}
+	return ports, nil
+}
+
+// composeLinks parses service[:alias]
+func composeLinks(v interface{}) (map[string]string, error) {
+	list, err := composeList(v)
+	if err != nil {
+		return nil, fmt.Errorf("links: %s", err)
+	}
+	links := map[string]string{}
+	for _, l := range list {
+		parts := strings.SplitN(l, ":", 2)
+		alias := parts[0]
+		if len(parts) == 2 {
+			alias = parts[1]
+		}
+		links[parts[0]] = alias
+	}
+	return links, nil
+}
+
+// composeVolumes parses named volumes (see Volume); host paths and
+// anonymous volumes are rejected as their data would not follow the
+// container
+func composeVolumes(v interface{}) ([]*VolumeMount, error) {
+	list, err := composeList(v)
+	if err != nil {
+		return nil, fmt.Errorf("volumes: %s", err)
+	}
+	mounts := []*VolumeMount{}
+	for _, spec := range list {
+		if strings.HasPrefix(spec, "/") || strings.HasPrefix(spec, ".") || strings.HasPrefix(spec, "~") {
+			return nil, fmt.Errorf("volume %s: only named volumes are supported; create one with create-volume", spec)
+		}
+		m, err := ParseVolumeMount(spec)
+		if err != nil {
+			return nil, err
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts, nil
+}
+
+// composeMemory parses a memory limit in bytes with an optional b, k, m or
+// g suffix into MB
+func composeMemory(v interface{}) (float64, error) {
+	s, err := composeString(v)
+	if err != nil {
+		return 0, err
+	}
+	s = strings.ToLower(s)
+	if len(s) > 2 && strings.HasSuffix(s, "b") && strings.ContainsAny(s[len(s)-2:len(s)-1], "kmg") {
+		s = s[:len(s)-1]
+	}
+	unit := 1.0 / (1024 * 1024)
+	switch {
+	case strings.HasSuffix(s, "g"):
+		unit = 1024
+	case strings.HasSuffix(s, "m"):
+		unit = 1
+	case strings.HasSuffix(s, "k"):
+		unit = 1.0 / 1024
+	}
+	n, err := strconv.ParseFloat(strings.TrimRight(s, "bkmg"), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid mem_limit %s", s)
+	}
+	return n * unit, nil
+}
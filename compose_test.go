@@ -0,0 +1,123 @@
+package shipyard
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const composeV1 = `
+# wordpress with mysql
+web:
+  image: wordpress:4.1
+  links:
+    - db:mysql
+  ports:
+    - "8080:80"
+  environment:
+    WORDPRESS_DB_PASSWORD: example # inline comment
+    DEBUG:
+  mem_limit: 512m
+  restart: always
+db:
+  image: mysql:5.6
+  command: mysqld --innodb-buffer-pool-size=64M
+  environment:
+  - MYSQL_ROOT_PASSWORD=example
+  volumes: ["mysql-data:/var/lib/mysql"]
+`
+
+const composeV2 = `
+version: "2"
+services:
+  app:
+    image: 'registry.local/app:1.0'
+    depends_on: [cache]
+    ports:
+      - 127.0.0.1:5000:5000
+      - "53/udp"
+    command: ["app", "--listen", ":5000"]
+  cache:
+    image: redis
+`
+
+func TestParseComposeV1(t *testing.T) {
+	services, err := ParseCompose(strings.NewReader(composeV1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 2 || services[0].Name != "db" || services[1].Name != "web" {
+		t.Fatalf("expected db to start before web; got %v", services)
+	}
+	db, web := services[0], services[1]
+	if len(db.Command) != 2 || db.Command[1] != "--innodb-buffer-pool-size=64M" {
+		t.Errorf("unexpected command %v", db.Command)
+	}
+	if db.Environment["MYSQL_ROOT_PASSWORD"] != "example" {
+		t.Errorf("unexpected environment %v", db.Environment)
+	}
+	if len(db.Volumes) != 1 || db.Volumes[0].Name != "mysql-data" || db.Volumes[0].Path != "/var/lib/mysql" {
+		t.Errorf("unexpected volumes %v", db.Volumes)
+	}
+	if web.Links["db"] != "mysql" || web.Memory != 512 {
+		t.Errorf("unexpected service %+v", web)
+	}
+	if v, ok := web.Environment["DEBUG"]; !ok || v != "" || web.Environment["WORDPRESS_DB_PASSWORD"] != "example" {
+		t.Errorf("unexpected environment %v", web.Environment)
+	}
+	if len(web.Ports) != 1 || web.Ports[0].Port != 8080 || web.Ports[0].ContainerPort != 80 || web.Ports[0].Proto != "tcp" {
+		t.Errorf("unexpected ports %v", web.Ports)
+	}
+
+	app := web.Application("blog")
+	if app.Name != "blog_web" || app.Project != "blog" || app.Links["blog_db"] != "mysql" || app.Count != 1 {
+		t.Fatalf("unexpected application %+v", app)
+	}
+	if err := app.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if img := app.ContainerImage(); img.Environment[ProjectEnv] != "blog" {
+		t.Fatalf("expected containers to be marked with the project")
+	}
+}
+
+func TestParseComposeV2(t *testing.T) {
+	services, err := ParseCompose(strings.NewReader(composeV2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 2 || services[0].Name != "cache" {
+		t.Fatalf("expected cache to start first; got %v", services)
+	}
+	app := services[1]
+	if app.Image != "registry.local/app:1.0" {
+		t.Errorf("unexpected image %s", app.Image)
+	}
+	if len(app.Command) != 3 || app.Command[2] != ":5000" {
+		t.Errorf("unexpected command %v", app.Command)
+	}
+	if len(app.Ports) != 2 || app.Ports[0].HostIp != "127.0.0.1" || app.Ports[0].Port != 5000 {
+		t.Fatalf("unexpected ports %v", app.Ports)
+	}
+	if p := app.Ports[1]; p.Proto != "udp" || p.ContainerPort != 53 || p.Port != 0 {
+		t.Errorf("unexpected port %+v", p)
+	}
+}
+
+func TestParseComposeErrors(t *testing.T) {
+	files := []string{
+		"web:\n  build: .\n",
+		"web:\n  image: nginx\n  volumes:\n    - ./html:/usr/share/nginx/html\n",
+		"web:\n  image: nginx\n  privileged: true\n",
+		"web:\n  image: nginx\n  links: [db]\n",
+		"a:\n  image: x\n  links: [b]\nb:\n  image: y\n  links: [a]\n",
+		"version: '3'\nservices:\n  web:\n    image: nginx\n",
+		"web:\n  image: nginx\n   ports: []\n",
+		"web:\n  image: nginx\n  command: |\n    run\n",
+	}
+	for _, f := range files {
+		if _, err := ParseCompose(strings.NewReader(f)); !errors.Is(err, ErrInvalidCompose) {
+			t.Errorf("expected %q to be rejected; got %v", f, err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package shipyard
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	// ConfigsEnv holds the comma separated config bundles of a container
+	ConfigsEnv = "_SHIPYARD_CONFIGS"
+)
+
+var (
+	ErrInvalidConfig = errors.New("invalid config")
+)
+
+// ConfigBundle is a named set of non-sensitive settings.  Containers
+// referencing it get its Data as environment variables and its Files
+// written into them.  Containers referencing any bundle can also use
+// {{config "<bundle>" "<key>"}} in their own environment values.
+type ConfigBundle struct {
+	ID   string `json:"id,omitempty" gorethink:"id,omitempty"`
+	Name string `json:"name,omitempty" gorethink:"name"`
+	// Data are environment variables
+	Data map[string]string `json:"data,omitempty" gorethink:"data"`
+	// Files are file contents by absolute path in the container
+	Files   map[string]string `json:"files,omitempty" gorethink:"files"`
+	Created time.Time         `json:"created,omitempty" gorethink:"created"`
+	Updated time.Time         `json:"updated,omitempty" gorethink:"updated"`
+}
+
+func (b *ConfigBundle) Validate() error {
+	if !secretNamePattern.MatchString(b.Name) {
+		return fmt.Errorf("%w: name must be letters, digits, '_', '.' or '-'", ErrInvalidConfig)
+	}
+	for k := range b.Data {
+		if !envNamePattern.MatchString(k) {
+			return fmt.Errorf("%w: invalid env var %q", ErrInvalidConfig, k)
+		}
+	}
+	if err := ValidateEnvironment(b.Data, nil); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+	}
+	for p := range b.Files {
+		if !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("%w: path %q must be absolute", ErrInvalidConfig, p)
+		}
+	}
+	return nil
+}
+
+// ConfigNames returns the config bundles referenced by an image
+func ConfigNames(image *citadel.Image) []string {
+	if image == nil || image.Environment[ConfigsEnv] == "" {
+		return nil
+	}
+	return strings.Split(image.Environment[ConfigsEnv], ",")
+}
+
+// SetConfigNames sets the config bundles containers of the image use; later
+// bundles override the data of earlier ones
+func SetConfigNames(image *citadel.Image, names []string) error {
+	if image.Environment == nil {
+		image.Environment = map[string]string{}
+	}
+	if len(names) == 0 {
+		delete(image.Environment, ConfigsEnv)
+		return nil
+	}
+	for _, n := range names {
+		if !secretNamePattern.MatchString(n) {
+			return fmt.Errorf("%w: invalid config name %q", ErrInvalidConfig, n)
+		}
+	}
+	image.Environment[ConfigsEnv] = strings.Join(names, ",")
+	return nil
+}
+
+// RenderEnvironment expands {{config "<bundle>" "<key>"}} in the values of
+// env using lookup
+func RenderEnvironment(env map[string]string, lookup func(bundle string, key string) (string, error)) error {
+	funcs := template.FuncMap{
+		"config": lookup,
+	}
+	for k, v := range env {
+		if !strings.Contains(v, "{{") {
+			continue
+		}
+		t, err := template.New(k).Funcs(funcs).Option("missingkey=error").Parse(v)
+		if err != nil {
+			return fmt.Errorf("%w: env var %s: %s", ErrInvalidConfig, k, err)
+		}
+		buf := &bytes.Buffer{}
+		if err := t.Execute(buf, nil); err != nil {
+			return fmt.Errorf("%w: env var %s: %s", ErrInvalidConfig, k, err)
+		}
+		env[k] = buf.String()
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestConfigBundleValidate(t *testing.T) {
+	valid := &ConfigBundle{
+		Name:  "production",
+		Data:  map[string]string{"DB_HOST": "db.internal"},
+		Files: map[string]string{"/etc/app.conf": "debug = false"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	invalid := []*ConfigBundle{
+		{Name: ""},
+		{Name: "prod", Data: map[string]string{"1HOST": "x"}},
+		{Name: "prod", Data: map[string]string{OwnerEnv: "admin"}},
+		{Name: "prod", Files: map[string]string{"app.conf": "x"}},
+	}
+	for _, b := range invalid {
+		if err := b.Validate(); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected %+v to be rejected; got %v", b, err)
+		}
+	}
+}
+
+func TestSetConfigNames(t *testing.T) {
+	img := &citadel.Image{Name: "app"}
+	if err := SetConfigNames(img, []string{"base", "production"}); err != nil {
+		t.Fatal(err)
+	}
+	names := ConfigNames(img)
+	if len(names) != 2 || names[0] != "base" || names[1] != "production" {
+		t.Fatalf("unexpected names %v", names)
+	}
+	if err := SetConfigNames(img, nil); err != nil {
+		t.Fatal(err)
+	}
+	if ConfigNames(img) != nil {
+		t.Fatal("expected names to be cleared")
+	}
+	if err := SetConfigNames(img, []string{"a,b"}); err == nil {
+		t.Fatal("expected invalid name to be rejected")
+	}
+}
+
+func TestRenderEnvironment(t *testing.T) {
+	data := map[string]map[string]string{
+		"production": {"DB_HOST": "db.internal"},
+	}
+	lookup := func(bundle string, key string) (string, error) {
+		v, ok := data[bundle][key]
+		if !ok {
+			return "", fmt.Errorf("config %s has no key %s", bundle, key)
+		}
+		return v, nil
+	}
+	env := map[string]string{
+		"DB_URL": `postgres://{{config "production" "DB_HOST"}}:5432/app`,
+		"DEBUG":  "1",
+	}
+	if err := RenderEnvironment(env, lookup); err != nil {
+		t.Fatal(err)
+	}
+	if env["DB_URL"] != "postgres://db.internal:5432/app" || env["DEBUG"] != "1" {
+		t.Fatalf("unexpected environment %v", env)
+	}
+
+	missing := map[string]string{"DB_URL": `{{config "production" "DB_PORT"}}`}
+	if err := RenderEnvironment(missing, lookup); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("expected missing key to fail; got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package shipyard
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ConstraintEqual    = "=="
+	ConstraintNotEqual = "!="
+)
+
+type (
+	// Constraint restricts placement to engines whose labels match.  Plain
+	// engine labels (without "=") are treated as a key with an empty value.
+	Constraint struct {
+		Key      string `json:"key,omitempty"`
+		Operator string `json:"operator,omitempty"`
+		Value    string `json:"value,omitempty"`
+	}
+)
+
+// ParseConstraints parses a comma separated constraint expression such as
+// "region=us-east,ssd=true,zone!=b".  "==" may be used in place of "=".
+func ParseConstraints(expr string) ([]*Constraint, error) {
+	constraints := []*Constraint{}
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		c := &Constraint{Operator: ConstraintEqual}
+		switch {
+		case strings.Contains(part, "!="):
+			kv := strings.SplitN(part, "!=", 2)
+			c.Key, c.Value, c.Operator = kv[0], kv[1], ConstraintNotEqual
+		case strings.Contains(part, "=="):
+			kv := strings.SplitN(part, "==", 2)
+			c.Key, c.Value = kv[0], kv[1]
+		case strings.Contains(part, "="):
+			kv := strings.SplitN(part, "=", 2)
+			c.Key, c.Value = kv[0], kv[1]
+		default:
+			c.Key = part
+		}
+		c.Key = strings.TrimSpace(c.Key)
+		c.Value = strings.TrimSpace(c.Value)
+		if c.Key == "" {
+			return nil, fmt.Errorf("invalid constraint: %s", part)
+		}
+		constraints = append(constraints, c)
+	}
+	return constraints, nil
+}
+
+// ParseLabels converts engine labels of the form key=value into a map.
+// Labels without a value map to an empty string.
+func ParseLabels(labels []string) map[string]string {
+	m := make(map[string]string)
+	for _, l := range labels {
+		kv := strings.SplitN(l, "=", 2)
+		if len(kv) == 2 {
+			m[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		} else {
+			m[strings.TrimSpace(l)] = ""
+		}
+	}
+	return m
+}
+
+// Match reports whether engine labels (as returned by ParseLabels) satisfy
+// the constraint.  A not equal constraint matches engines without the key.
+func (c *Constraint) Match(labels map[string]string) bool {
+	v, ok := labels[c.Key]
+	if c.Operator == ConstraintNotEqual {
+		return !ok || v != c.Value
+	}
+	return ok && v == c.Value
+}
+
+func (c *Constraint) String() string {
+	if c.Value == "" && c.Operator == ConstraintEqual {
+		return c.Key
+	}
+	op := "="
+	if c.Operator == ConstraintNotEqual {
+		op = c.Operator
+	}
+	return c.Key + op + c.Value
+}
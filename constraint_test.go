@@ -0,0 +1,61 @@
+package shipyard
+
+import (
+	"testing"
+)
+
+func TestParseConstraints(t *testing.T) {
+	constraints, err := ParseConstraints("region=us-east, ssd==true,zone!=b,gpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Constraint{
+		{"region", ConstraintEqual, "us-east"},
+		{"ssd", ConstraintEqual, "true"},
+		{"zone", ConstraintNotEqual, "b"},
+		{"gpu", ConstraintEqual, ""},
+	}
+	if len(constraints) != len(expected) {
+		t.Fatalf("expected %d constraints; received %d", len(expected), len(constraints))
+	}
+	for i, c := range constraints {
+		if *c != expected[i] {
+			t.Errorf("expected %v; received %v", expected[i], *c)
+		}
+	}
+}
+
+func TestParseConstraintsInvalid(t *testing.T) {
+	if _, err := ParseConstraints("=us-east"); err == nil {
+		t.Error("expected error for constraint without key")
+	}
+}
+
+func TestConstraintMatch(t *testing.T) {
+	labels := ParseLabels([]string{"region=us-east", "ssd=true", "gpu"})
+	tests := map[string]bool{
+		"region=us-east":           true,
+		"region=us-west":           false,
+		"region=us-east,ssd=true":  true,
+		"region=us-east,ssd=false": false,
+		"zone!=b":                  true,
+		"region!=us-east":          false,
+		"gpu":                      true,
+		"arm":                      false,
+	}
+	for expr, expected := range tests {
+		constraints, err := ParseConstraints(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		match := true
+		for _, c := range constraints {
+			if !c.Match(labels) {
+				match = false
+			}
+		}
+		if match != expected {
+			t.Errorf("expected %v for %s; received %v", expected, expr, match)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/citadel/citadel"
+)
+
+var (
+	ErrInvalidPath = errors.New("invalid container path")
+)
+
+const (
+	ChangeModified = "modified"
+	ChangeAdded    = "added"
+	ChangeDeleted  = "deleted"
+)
+
+// ContainerChange is a path of the container filesystem that differs from
+// its image
+type ContainerChange struct {
+	Path string `json:"path"`
+	// Kind is one of ChangeModified, ChangeAdded or ChangeDeleted
+	Kind string `json:"kind"`
+}
+
+// ContainerQuery selects a page of containers ordered by id.  Empty fields
+// match any container and a zero limit returns every match.
+type ContainerQuery struct {
+	// Image matches the image name with or without tag
+	Image string `json:"image,omitempty"`
+	// Engine is the id of the engine running the container
+	Engine string `json:"engine,omitempty"`
+	// State is the container state (running, stopped)
+	State string `json:"state,omitempty"`
+	// Label matches one of the constraint labels of the container
+	Label string `json:"label,omitempty"`
+	// Selector matches the container labels (see SetLabels)
+	Selector  Selector `json:"selector,omitempty"`
+	Namespace string   `json:"namespace,omitempty"`
+	Limit     int      `json:"limit,omitempty"`
+	Offset    int      `json:"offset,omitempty"`
+}
+
+// Match reports whether the query selects the container, ignoring the
+// limit and offset
+func (q *ContainerQuery) Match(c *citadel.Container) bool {
+	if q == nil {
+		return true
+	}
+	if c.Image == nil {
+		return false
+	}
+	if q.Image != "" && !ImageMatches(q.Image, c) {
+		return false
+	}
+	if q.Engine != "" && (c.Engine == nil || c.Engine.ID != q.Engine) {
+		return false
+	}
+	if q.State != "" && c.State != q.State {
+		return false
+	}
+	if q.Namespace != "" && ContainerNamespace(c) != q.Namespace {
+		return false
+	}
+	if !q.Selector.Match(c) {
+		return false
+	}
+	if q.Label != "" {
+		for _, l := range c.Image.Labels {
+			if l == q.Label {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Select returns the page of the matching containers.  Containers are
+// ordered by id so consecutive pages do not overlap.
+func (q *ContainerQuery) Select(containers []*citadel.Container) []*citadel.Container {
+	selected := []*citadel.Container{}
+	for _, c := range containers {
+		if q.Match(c) {
+			selected = append(selected, c)
+		}
+	}
+	sort.Sort(containersByID(selected))
+	if q == nil {
+		return selected
+	}
+	if q.Offset >= len(selected) {
+		return []*citadel.Container{}
+	}
+	selected = selected[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(selected) {
+		selected = selected[:q.Limit]
+	}
+	return selected
+}
+
+type containersByID []*citadel.Container
+
+func (s containersByID) Len() int           { return len(s) }
+func (s containersByID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+func (s containersByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// ValidateContainerPath checks a path used to copy files to or from a
+// container is absolute and clean
+func ValidateContainerPath(p string) error {
+	if !path.IsAbs(p) || path.Clean(p) != p {
+		return fmt.Errorf("%w: %q must be an absolute path", ErrInvalidPath, p)
+	}
+	return nil
+}
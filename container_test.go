@@ -0,0 +1,52 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestContainerQuerySelect(t *testing.T) {
+	eng := &citadel.Engine{ID: "node-1"}
+	containers := []*citadel.Container{
+		{ID: "c", State: "running", Engine: eng, Image: &citadel.Image{Name: "redis:2.8"}},
+		{ID: "a", State: "running", Engine: eng, Image: &citadel.Image{Name: "nginx", Labels: []string{"web"}}},
+		{ID: "d", State: "stopped", Engine: &citadel.Engine{ID: "node-2"}, Image: &citadel.Image{Name: "nginx"}},
+		{ID: "b", State: "running", Engine: eng, Image: &citadel.Image{Name: "nginx:1.9"}},
+	}
+	tests := []struct {
+		query *ContainerQuery
+		ids   string
+	}{
+		{nil, "abcd"},
+		{&ContainerQuery{Image: "nginx"}, "abd"},
+		{&ContainerQuery{Engine: "node-1"}, "abc"},
+		{&ContainerQuery{State: "stopped"}, "d"},
+		{&ContainerQuery{Label: "web"}, "a"},
+		{&ContainerQuery{Limit: 2}, "ab"},
+		{&ContainerQuery{Limit: 2, Offset: 2}, "cd"},
+		{&ContainerQuery{Image: "nginx", Offset: 1}, "bd"},
+		{&ContainerQuery{Offset: 4}, ""},
+	}
+	for i, test := range tests {
+		ids := ""
+		for _, c := range test.query.Select(containers) {
+			ids += c.ID
+		}
+		if ids != test.ids {
+			t.Errorf("%d: expected %q; got %q", i, test.ids, ids)
+		}
+	}
+}
+
+func TestValidateContainerPath(t *testing.T) {
+	if err := ValidateContainerPath("/var/log/app.log"); err != nil {
+		t.Error(err)
+	}
+	for _, p := range []string{"", "var/log", "/var/../etc", "/var/log/"} {
+		if err := ValidateContainerPath(p); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("%q: expected ErrInvalidPath; received %v", p, err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Package datastore stores the documents of the controller.  Documents are
+// structs or maps encoded with their gorethink tags; RethinkStore keeps
+// them in RethinkDB and EmbeddedStore in a file for single node installs.
+package datastore
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("document does not exist")
+	ErrExists   = errors.New("document already exists")
+	// ErrNotSupported is returned by stores without changefeeds; see
+	// Store.Changes
+	ErrNotSupported = errors.New("not supported by the datastore")
+	// ErrLocked is returned when opening an embedded store open in another
+	// process
+	ErrLocked = errors.New("datastore is in use by another process")
+)
+
+// Op compares a document field with a value
+type Op int
+
+const (
+	OpEq Op = iota
+	OpNe
+	OpLt
+	OpLe
+	OpGt
+	OpGe
+	// OpIn matches fields equal to one of the values of a slice
+	OpIn
+	// OpContains matches array fields holding the value
+	OpContains
+	// OpPrefix matches string fields starting with the value
+	OpPrefix
+)
+
+// Cond is a condition on a field.  Field is a path of nested fields
+// separated by dots; documents without the field never match.
+type Cond struct {
+	Field string
+	Op    Op
+	Value interface{}
+}
+
+func Eq(field string, value interface{}) Cond       { return Cond{field, OpEq, value} }
+func Ne(field string, value interface{}) Cond       { return Cond{field, OpNe, value} }
+func Lt(field string, value interface{}) Cond       { return Cond{field, OpLt, value} }
+func Le(field string, value interface{}) Cond       { return Cond{field, OpLe, value} }
+func Gt(field string, value interface{}) Cond       { return Cond{field, OpGt, value} }
+func Ge(field string, value interface{}) Cond       { return Cond{field, OpGe, value} }
+func In(field string, values interface{}) Cond      { return Cond{field, OpIn, values} }
+func Contains(field string, value interface{}) Cond { return Cond{field, OpContains, value} }
+func Prefix(field string, value string) Cond        { return Cond{field, OpPrefix, value} }
+
+// Query selects the documents matching all its conditions
+type Query struct {
+	Where []Cond
+	// OrderBy is the field documents are sorted by; unsorted when empty
+	OrderBy string
+	Desc    bool
+	Skip    int
+	// Limit is the maximum number of documents; zero is unlimited
+	Limit int
+}
+
+// Where returns a query for the documents matching conds
+func Where(conds ...Cond) *Query {
+	return &Query{Where: conds}
+}
+
+// Sort orders the documents by field
+func (q *Query) Sort(field string, desc bool) *Query {
+	q.OrderBy = field
+	q.Desc = desc
+	return q
+}
+
+// Page skips the first documents and limits the number returned
+func (q *Query) Page(skip int, limit int) *Query {
+	q.Skip = skip
+	q.Limit = limit
+	return q
+}
+
+// ByID returns a query for the document with id
+func ByID(id string) *Query {
+	return Where(Eq("id", id))
+}
+
+// Literal is a field value of Update replacing a nested object instead of
+// merging it
+type Literal struct {
+	Value interface{}
+}
+
+// Feed returns the documents inserted or replaced in a table
+type Feed interface {
+	// Next decodes the next document into v; it returns false when the
+	// feed is closed or failed
+	Next(v interface{}) bool
+	Err() error
+	Close() error
+}
+
+// Store holds the tables of the controller.  Every document has a string
+// id in its "id" field.
+type Store interface {
+	// Init creates the tables that do not exist
+	Init(tables []string) error
+	// Index creates a secondary index on a top level field of a table, if
+	// it does not exist, so queries sorted by the field do not scan the
+	// table
+	Index(table string, field string) error
+	// Get decodes the document with id into v or returns ErrNotFound
+	Get(table string, id string, v interface{}) error
+	// Find decodes the documents matching q, or all when q is nil, into
+	// the slice v points to
+	Find(table string, q *Query, v interface{}) error
+	// FindOne decodes the first document matching q into v or returns
+	// ErrNotFound
+	FindOne(table string, q *Query, v interface{}) error
+	Count(table string, q *Query) (int, error)
+	// Insert stores a new document and returns its id, generated when
+	// the document has none; it returns ErrExists when the id is taken
+	Insert(table string, doc interface{}) (string, error)
+	// Put stores doc as the document with its id, replacing any
+	Put(table string, doc interface{}) error
+	// Update sets fields of the documents matching q and returns how
+	// many matched.  Nested objects are merged unless given as a Literal.
+	Update(table string, q *Query, fields map[string]interface{}) (int, error)
+	// Delete removes the documents matching q, or all when q is nil, and
+	// returns how many were removed
+	Delete(table string, q *Query) (int, error)
+	// Lease gives the document with id to holder until ttl from now
+	// unless another holder has it, and returns the holder afterwards.
+	// Leases expire by the clock of the datastore.
+	Lease(table string, id string, holder string, ttl time.Duration) (string, error)
+	// Changes follows the documents inserted or replaced in a table, or
+	// returns ErrNotSupported for stores not shared between controllers
+	Changes(table string) (Feed, error)
+	// Dump calls fn with each document of a table in a form Load
+	// restores unchanged
+	Dump(table string, fn func(doc map[string]interface{}) error) error
+	// Load replaces the documents of a table with dumped ones
+	Load(table string, docs []map[string]interface{}) error
+	Close() error
+}
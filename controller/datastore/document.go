@@ -0,0 +1,266 @@
+package datastore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/dancannon/gorethink/encoding"
+)
+
+// document encodes v like the rethinkdb driver, with times and binary data
+// as native values and numbers as float64
+func document(v interface{}) (interface{}, error) {
+	ev, err := encoding.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return native(ev), nil
+}
+
+// decode decodes a native document into v
+func decode(v interface{}, doc interface{}) error {
+	return encoding.Decode(v, doc)
+}
+
+// documentID returns the id of a document
+func documentID(doc interface{}) (string, error) {
+	d, err := document(doc)
+	if err != nil {
+		return "", err
+	}
+	m, ok := d.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("document is a %T, not an object", doc)
+	}
+	id, _ := m["id"].(string)
+	return id, nil
+}
+
+// newID returns a random uuid like the ids generated by rethinkdb
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// native converts the TIME and BINARY pseudo types of an encoded value to
+// time.Time and []byte
+func native(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		switch t["$reql_type$"] {
+		case "TIME":
+			epoch, _ := native(t["epoch_time"]).(float64)
+			sec, frac := math.Modf(epoch)
+			return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+		case "BINARY":
+			data, _ := t["data"].(string)
+			b, err := base64.StdEncoding.DecodeString(data)
+			if err != nil {
+				return []byte{}
+			}
+			return b
+		}
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[k] = native(val)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(t))
+		for i, val := range t {
+			a[i] = native(val)
+		}
+		return a
+	case int:
+		return float64(t)
+	case int8:
+		return float64(t)
+	case int16:
+		return float64(t)
+	case int32:
+		return float64(t)
+	case int64:
+		return float64(t)
+	case uint:
+		return float64(t)
+	case uint8:
+		return float64(t)
+	case uint16:
+		return float64(t)
+	case uint32:
+		return float64(t)
+	case uint64:
+		return float64(t)
+	case float32:
+		return float64(t)
+	}
+	return v
+}
+
+// raw converts native times and binary data back to pseudo types, the form
+// documents are dumped and written in
+func raw(v interface{}) interface{} {
+	switch t := v.(type) {
+	case time.Time:
+		return map[string]interface{}{
+			"$reql_type$": "TIME",
+			"epoch_time":  float64(t.UnixNano()) / 1e9,
+			"timezone":    "+00:00",
+		}
+	case []byte:
+		return map[string]interface{}{
+			"$reql_type$": "BINARY",
+			"data":        base64.StdEncoding.EncodeToString(t),
+		}
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[k] = raw(val)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(t))
+		for i, val := range t {
+			a[i] = raw(val)
+		}
+		return a
+	}
+	return v
+}
+
+// lookup returns the value of a dotted field path
+func lookup(doc map[string]interface{}, path string) (interface{}, bool) {
+	var v interface{} = doc
+	for _, p := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[p]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// compare orders native values of the same type; ok is false for values
+// that can not be ordered
+func compare(a interface{}, b interface{}) (c int, ok bool) {
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			return cmp(x < y, x > y), true
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			return cmp(!x && y, x && !y), true
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return cmp(x.Before(y), x.After(y)), true
+		}
+	case []byte:
+		if y, ok := b.([]byte); ok {
+			return bytes.Compare(x, y), true
+		}
+	}
+	return 0, false
+}
+
+func cmp(less bool, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+func equal(a interface{}, b interface{}) bool {
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// matches reports whether a document matches a condition; the value of the
+// condition is native
+func matches(doc map[string]interface{}, c Cond, value interface{}) bool {
+	v, ok := lookup(doc, c.Field)
+	if !ok {
+		return false
+	}
+	switch c.Op {
+	case OpEq:
+		return equal(v, value)
+	case OpNe:
+		return !equal(v, value)
+	case OpLt, OpLe, OpGt, OpGe:
+		n, ok := compare(v, value)
+		if !ok {
+			return false
+		}
+		switch c.Op {
+		case OpLt:
+			return n < 0
+		case OpLe:
+			return n <= 0
+		case OpGt:
+			return n > 0
+		}
+		return n >= 0
+	case OpIn, OpContains:
+		list, item := value, v
+		if c.Op == OpContains {
+			list, item = v, value
+		}
+		a, ok := list.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, e := range a {
+			if equal(e, item) {
+				return true
+			}
+		}
+		return false
+	case OpPrefix:
+		s, ok := v.(string)
+		p, _ := value.(string)
+		return ok && strings.HasPrefix(s, p)
+	}
+	return false
+}
+
+// merge sets fields on a copy of doc, merging nested objects like rethinkdb
+// updates
+func merge(doc map[string]interface{}, fields map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(doc)+len(fields))
+	for k, v := range doc {
+		m[k] = v
+	}
+	for k, v := range fields {
+		nested, ok := v.(map[string]interface{})
+		current, isMap := m[k].(map[string]interface{})
+		if ok && isMap {
+			m[k] = merge(current, nested)
+			continue
+		}
+		m[k] = v
+	}
+	return m
+}
@@ -0,0 +1,652 @@
+package datastore
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	// compactSlack is the number of superseded entries the log of an
+	// embedded store may hold beyond its documents before it is rewritten
+	compactSlack = 1000
+)
+
+// logEntry is a line of the log of an embedded store: a document stored or
+// the id of a document removed
+type logEntry struct {
+	Table   string                 `json:"table"`
+	ID      string                 `json:"id"`
+	Doc     map[string]interface{} `json:"doc,omitempty"`
+	Deleted bool                   `json:"deleted,omitempty"`
+}
+
+// EmbeddedStore keeps the tables in memory and in a log file for single node
+// installs without a database, so every document must fit in the memory of
+// the controller.  Every change is appended to the file, which is rewritten
+// in the background with only the current documents when it has grown to
+// twice their number.  Queries scan the table unless they select an id or
+// a field indexed with Index.  The file is locked while the store is open;
+// use RethinkStore to run several controllers.
+type EmbeddedStore struct {
+	path string
+	lock *os.File
+
+	mu      sync.RWMutex
+	tables  map[string]map[string]map[string]interface{}
+	indexes map[string]map[string]*index
+	file    *os.File
+	entries int
+	docs    int
+	closed  bool
+
+	// pending is the log written while the file is compacted, appended to
+	// the compacted file before it replaces the log
+	compacting sync.WaitGroup
+	pending    []byte
+	pendingN   int
+	rewriting  bool
+}
+
+// NewEmbeddedStore opens or creates the store at path; the store can not be
+// opened twice
+func NewEmbeddedStore(path string) (*EmbeddedStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	lock, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		lock.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, fmt.Errorf("%w: %s", ErrLocked, path)
+		}
+		return nil, err
+	}
+	s := &EmbeddedStore{
+		path:    path,
+		lock:    lock,
+		tables:  map[string]map[string]map[string]interface{}{},
+		indexes: map[string]map[string]*index{},
+	}
+	if err := s.replay(); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	docs, err := s.snapshot(s.tables)
+	if err == nil {
+		err = s.replace(docs)
+	}
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+	s.docs = docs
+	return s, nil
+}
+
+// replay loads the log; an incomplete last line, left by a crash during a
+// write, is dropped
+func (s *EmbeddedStore) replay() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	rd := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		b, err := rd.ReadBytes('\n')
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		var e logEntry
+		if err := json.Unmarshal(b, &e); err != nil {
+			return fmt.Errorf("%s:%d: %s", s.path, line, err)
+		}
+		tbl := s.table(e.Table)
+		if e.Deleted {
+			delete(tbl, e.ID)
+		} else {
+			tbl[e.ID] = native(e.Doc).(map[string]interface{})
+		}
+	}
+}
+
+// snapshot writes the documents of tables to a new log and returns their
+// number; documents are never changed once stored, so the tables only need
+// to be copied
+func (s *EmbeddedStore) snapshot(tables map[string]map[string]map[string]interface{}) (int, error) {
+	f, err := os.OpenFile(s.path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	docs := 0
+	for name, tbl := range tables {
+		for id, doc := range tbl {
+			if err := enc.Encode(&logEntry{Table: name, ID: id, Doc: raw(doc).(map[string]interface{})}); err != nil {
+				f.Close()
+				return 0, err
+			}
+			docs++
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return 0, err
+	}
+	return docs, f.Close()
+}
+
+// replace appends the pending log to the new log of a snapshot of docs
+// documents and makes it the log; the lock must be held
+func (s *EmbeddedStore) replace(docs int) error {
+	tmp := s.path + ".tmp"
+	if len(s.pending) > 0 {
+		f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(s.pending); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.file = file
+	s.entries = docs + s.pendingN
+	return nil
+}
+
+// compact rewrites the log in the background; the lock must be held
+func (s *EmbeddedStore) compact() {
+	tables := make(map[string]map[string]map[string]interface{}, len(s.tables))
+	for name, tbl := range s.tables {
+		copied := make(map[string]map[string]interface{}, len(tbl))
+		for id, doc := range tbl {
+			copied[id] = doc
+		}
+		tables[name] = copied
+	}
+	s.rewriting = true
+	s.compacting.Add(1)
+	go func() {
+		defer s.compacting.Done()
+		docs, err := s.snapshot(tables)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err == nil {
+			err = s.replace(docs)
+		}
+		if err != nil {
+			log.Warnf("unable to compact %s: %s", s.path, err)
+			os.Remove(s.path + ".tmp")
+		}
+		s.rewriting = false
+		s.pending = nil
+		s.pendingN = 0
+	}()
+}
+
+// table returns a table, creating it if needed; the lock must be held
+func (s *EmbeddedStore) table(name string) map[string]map[string]interface{} {
+	tbl, ok := s.tables[name]
+	if !ok {
+		tbl = map[string]map[string]interface{}{}
+		s.tables[name] = tbl
+	}
+	return tbl
+}
+
+// write applies changes and appends them to the log; the lock must be held
+func (s *EmbeddedStore) write(entries ...*logEntry) error {
+	buf := []byte{}
+	for _, e := range entries {
+		le := *e
+		if le.Doc != nil {
+			le.Doc = raw(le.Doc).(map[string]interface{})
+		}
+		b, err := json.Marshal(&le)
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, b...), '\n')
+	}
+	if _, err := s.file.Write(buf); err != nil {
+		return err
+	}
+	if s.rewriting {
+		s.pending = append(s.pending, buf...)
+		s.pendingN += len(entries)
+	}
+	for _, e := range entries {
+		tbl := s.table(e.Table)
+		_, exists := tbl[e.ID]
+		if exists {
+			for _, x := range s.indexes[e.Table] {
+				x.remove(tbl, e.ID)
+			}
+		}
+		if e.Deleted {
+			delete(tbl, e.ID)
+			if exists {
+				s.docs--
+			}
+			continue
+		}
+		tbl[e.ID] = e.Doc
+		if !exists {
+			s.docs++
+		}
+		for _, x := range s.indexes[e.Table] {
+			x.insert(tbl, e.ID)
+		}
+	}
+	s.entries += len(entries)
+	if s.entries > 2*s.docs+compactSlack && !s.rewriting && !s.closed {
+		s.compact()
+	}
+	return nil
+}
+
+func (s *EmbeddedStore) Init(tables []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tbl := range tables {
+		s.table(tbl)
+	}
+	return nil
+}
+
+// Index keeps the documents of a table sorted by a field for the queries
+// comparing or ordered by it
+func (s *EmbeddedStore) Index(table string, field string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.indexes[table] == nil {
+		s.indexes[table] = map[string]*index{}
+	}
+	if _, ok := s.indexes[table][field]; !ok {
+		s.indexes[table][field] = newIndex(s.table(table), field)
+	}
+	return nil
+}
+
+// object encodes a document
+func object(doc interface{}) (map[string]interface{}, error) {
+	d, err := document(doc)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := d.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("document is a %T, not an object", doc)
+	}
+	return m, nil
+}
+
+// conds returns the native values of the conditions of a query
+func conds(q *Query) ([]interface{}, error) {
+	if q == nil {
+		return nil, nil
+	}
+	values := make([]interface{}, len(q.Where))
+	for i, c := range q.Where {
+		v, err := document(c.Value)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// candidates returns the ids of the documents that can match a query and
+// whether they are in the order of the query; a query for an id looks it up
+// and a query ordered by or comparing an indexed field uses the index
+func (s *EmbeddedStore) candidates(table string, q *Query, values []interface{}) ([]string, bool) {
+	tbl := s.tables[table]
+	if q != nil {
+		for i, c := range q.Where {
+			if c.Field != "id" || c.Op != OpEq {
+				continue
+			}
+			id, _ := values[i].(string)
+			if _, ok := tbl[id]; !ok {
+				return nil, true
+			}
+			return []string{id}, true
+		}
+		if x, ok := s.indexes[table][q.OrderBy]; ok {
+			lo, hi := x.bounds(tbl, q.Where, values)
+			return x.scan(tbl, lo, hi, q.Desc), true
+		}
+		for _, c := range q.Where {
+			if x, ok := s.indexes[table][c.Field]; ok {
+				lo, hi := x.bounds(tbl, q.Where, values)
+				ids := x.scan(tbl, lo, hi, false)
+				sort.Strings(ids)
+				return ids, q.OrderBy == ""
+			}
+		}
+	}
+	ids := make([]string, 0, len(tbl))
+	for id := range tbl {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, q == nil || q.OrderBy == ""
+}
+
+// find returns the documents of a query; the lock must be held
+func (s *EmbeddedStore) find(table string, q *Query) ([]map[string]interface{}, error) {
+	values, err := conds(q)
+	if err != nil {
+		return nil, err
+	}
+	tbl := s.tables[table]
+	ids, ordered := s.candidates(table, q, values)
+	docs := []map[string]interface{}{}
+docs:
+	for _, id := range ids {
+		doc := tbl[id]
+		if q != nil {
+			for i, c := range q.Where {
+				if !matches(doc, c, values[i]) {
+					continue docs
+				}
+			}
+			if ordered && q.Limit > 0 && len(docs) == q.Skip+q.Limit {
+				break
+			}
+		}
+		docs = append(docs, doc)
+	}
+	if q == nil {
+		return docs, nil
+	}
+	if q.OrderBy != "" && !ordered {
+		sort.SliceStable(docs, func(i, j int) bool {
+			a, aok := lookup(docs[i], q.OrderBy)
+			b, bok := lookup(docs[j], q.OrderBy)
+			if aok != bok {
+				// documents without the field come first
+				return bok != q.Desc
+			}
+			c, _ := compare(a, b)
+			if q.Desc {
+				return c > 0
+			}
+			return c < 0
+		})
+	}
+	if q.Skip > 0 {
+		if q.Skip >= len(docs) {
+			docs = docs[:0]
+		} else {
+			docs = docs[q.Skip:]
+		}
+	}
+	if q.Limit > 0 && len(docs) > q.Limit {
+		docs = docs[:q.Limit]
+	}
+	return docs, nil
+}
+
+func (s *EmbeddedStore) Get(table string, id string, v interface{}) error {
+	s.mu.RLock()
+	doc, ok := s.tables[table][id]
+	s.mu.RUnlock()
+	if !ok {
+		return ErrNotFound
+	}
+	return decode(v, doc)
+}
+
+func (s *EmbeddedStore) Find(table string, q *Query, v interface{}) error {
+	s.mu.RLock()
+	docs, err := s.find(table, q)
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	// fill the slice like the driver does, keeping an empty slice non nil
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("find needs a pointer to a slice, not %T", v)
+	}
+	slice := rv.Elem().Slice(0, 0)
+	for _, doc := range docs {
+		elem := reflect.New(slice.Type().Elem())
+		if err := decode(elem.Interface(), doc); err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, elem.Elem())
+	}
+	rv.Elem().Set(slice)
+	return nil
+}
+
+func (s *EmbeddedStore) FindOne(table string, q *Query, v interface{}) error {
+	s.mu.RLock()
+	docs, err := s.find(table, q)
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return ErrNotFound
+	}
+	return decode(v, docs[0])
+}
+
+func (s *EmbeddedStore) Count(table string, q *Query) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	docs, err := s.find(table, q)
+	return len(docs), err
+}
+
+func (s *EmbeddedStore) Insert(table string, doc interface{}) (string, error) {
+	m, err := object(doc)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, _ := m["id"].(string)
+	if id == "" {
+		id = newID()
+		m["id"] = id
+	}
+	if _, ok := s.tables[table][id]; ok {
+		return "", ErrExists
+	}
+	if err := s.write(&logEntry{Table: table, ID: id, Doc: m}); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (s *EmbeddedStore) Put(table string, doc interface{}) error {
+	m, err := object(doc)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, _ := m["id"].(string)
+	if id == "" {
+		id = newID()
+		m["id"] = id
+	}
+	return s.write(&logEntry{Table: table, ID: id, Doc: m})
+}
+
+func (s *EmbeddedStore) Update(table string, q *Query, fields map[string]interface{}) (int, error) {
+	literal := map[string]bool{}
+	plain := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if l, ok := v.(Literal); ok {
+			literal[k] = true
+			v = l.Value
+		}
+		plain[k] = v
+	}
+	f, err := object(plain)
+	if err != nil {
+		return 0, err
+	}
+	delete(f, "id")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	docs, err := s.find(table, q)
+	if err != nil {
+		return 0, err
+	}
+	entries := make([]*logEntry, len(docs))
+	for i, doc := range docs {
+		id, _ := doc["id"].(string)
+		updated := merge(doc, f)
+		for k := range literal {
+			updated[k] = f[k]
+		}
+		entries[i] = &logEntry{Table: table, ID: id, Doc: updated}
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	return len(entries), s.write(entries...)
+}
+
+func (s *EmbeddedStore) Delete(table string, q *Query) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	docs, err := s.find(table, q)
+	if err != nil {
+		return 0, err
+	}
+	entries := make([]*logEntry, len(docs))
+	for i, doc := range docs {
+		id, _ := doc["id"].(string)
+		entries[i] = &logEntry{Table: table, ID: id, Deleted: true}
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	return len(entries), s.write(entries...)
+}
+
+func (s *EmbeddedStore) Lease(table string, id string, holder string, ttl time.Duration) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if l, ok := s.tables[table][id]; ok {
+		current, _ := l["holder"].(string)
+		expires, _ := l["expires"].(time.Time)
+		if current != holder && now.Before(expires) {
+			return current, nil
+		}
+	}
+	doc := map[string]interface{}{
+		"id":      id,
+		"holder":  holder,
+		"expires": now.Add(ttl).UTC(),
+	}
+	if err := s.write(&logEntry{Table: table, ID: id, Doc: doc}); err != nil {
+		return "", err
+	}
+	return holder, nil
+}
+
+// Changes is not supported; an embedded store serves a single controller
+func (s *EmbeddedStore) Changes(table string) (Feed, error) {
+	return nil, ErrNotSupported
+}
+
+func (s *EmbeddedStore) Dump(table string, fn func(doc map[string]interface{}) error) error {
+	s.mu.RLock()
+	docs, err := s.find(table, nil)
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if err := fn(raw(doc).(map[string]interface{})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *EmbeddedStore) Load(table string, docs []map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := []*logEntry{}
+	for id := range s.tables[table] {
+		entries = append(entries, &logEntry{Table: table, ID: id, Deleted: true})
+	}
+	for _, d := range docs {
+		doc := native(d).(map[string]interface{})
+		id, _ := doc["id"].(string)
+		if id == "" {
+			return fmt.Errorf("document of %s without id", table)
+		}
+		entries = append(entries, &logEntry{Table: table, ID: id, Doc: doc})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return s.write(entries...)
+}
+
+// Close waits for a compaction of the log and releases the store
+func (s *EmbeddedStore) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.compacting.Wait()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.file.Close()
+	// closing the lock file releases the lock
+	s.lock.Close()
+	return err
+}
@@ -0,0 +1,349 @@
+package datastore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testDoc struct {
+	ID      string            `gorethink:"id,omitempty"`
+	Name    string            `gorethink:"name"`
+	Size    int               `gorethink:"size"`
+	Created time.Time         `gorethink:"created"`
+	Tags    []string          `gorethink:"tags"`
+	Owner   *testOwner        `gorethink:"owner,omitempty"`
+	Labels  map[string]string `gorethink:"labels,omitempty"`
+	Secret  string            `gorethink:"-"`
+}
+
+type testOwner struct {
+	Name string `gorethink:"name"`
+	Role string `gorethink:"role"`
+}
+
+func newTestStore(t *testing.T) (*EmbeddedStore, string) {
+	dir, err := ioutil.TempDir("", "datastore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "shipyard.db")
+	s, err := NewEmbeddedStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Init([]string{"docs"}); err != nil {
+		t.Fatal(err)
+	}
+	return s, path
+}
+
+func TestEmbeddedStore(t *testing.T) {
+	s, path := newTestStore(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	now := time.Now().Truncate(time.Second)
+	for i, name := range []string{"b", "a", "c"} {
+		doc := &testDoc{
+			Name:    name,
+			Size:    i,
+			Created: now.Add(time.Duration(i) * time.Hour),
+			Tags:    []string{"t" + name},
+			Owner:   &testOwner{Name: "owner-" + name, Role: "admin"},
+			Secret:  "hidden",
+		}
+		id, err := s.Insert("docs", doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id == "" {
+			t.Fatal("expected a generated id")
+		}
+	}
+	if _, err := s.Insert("docs", &testDoc{ID: "fixed", Name: "d", Size: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Insert("docs", &testDoc{ID: "fixed"}); err != ErrExists {
+		t.Errorf("expected ErrExists; received %v", err)
+	}
+
+	docs := []*testDoc{}
+	if err := s.Find("docs", Where(Ge("size", 0)).Sort("name", false), &docs); err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 4 || docs[0].Name != "a" || docs[3].Name != "d" {
+		t.Fatalf("unexpected documents %v", docs)
+	}
+	if !docs[0].Created.Equal(now.Add(time.Hour)) || docs[0].Owner.Name != "owner-a" || docs[0].Secret != "" {
+		t.Errorf("unexpected document %+v", docs[0])
+	}
+
+	tests := []struct {
+		query    *Query
+		expected []string
+	}{
+		{Where(Eq("name", "a")), []string{"a"}},
+		{Where(Ne("name", "a")).Sort("name", false), []string{"b", "c", "d"}},
+		{Where(Gt("size", 0), Lt("size", 10)).Sort("size", true), []string{"c", "a"}},
+		{Where(Ge("created", now), Le("created", now.Add(time.Hour))).Sort("created", false), []string{"b", "a"}},
+		{Where(In("name", []string{"a", "c", "x"})).Sort("name", false), []string{"a", "c"}},
+		{Where(Contains("tags", "tb")), []string{"b"}},
+		{Where(Prefix("owner.name", "owner-c")), []string{"c"}},
+		{Where(Eq("owner.role", "admin")).Sort("name", true).Page(1, 1), []string{"b"}},
+		{ByID("fixed"), []string{"d"}},
+		{Where(Eq("missing", "x")), []string{}},
+	}
+	for i, test := range tests {
+		found := []*testDoc{}
+		if err := s.Find("docs", test.query, &found); err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, d := range found {
+			names = append(names, d.Name)
+		}
+		if len(names) != len(test.expected) {
+			t.Errorf("%d: expected %v; received %v", i, test.expected, names)
+			continue
+		}
+		for j := range names {
+			if names[j] != test.expected[j] {
+				t.Errorf("%d: expected %v; received %v", i, test.expected, names)
+				break
+			}
+		}
+	}
+
+	n, err := s.Update("docs", Where(Eq("name", "a")), map[string]interface{}{
+		"size":  42,
+		"owner": map[string]interface{}{"role": "user"},
+	})
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 update; received %d %v", n, err)
+	}
+	var a *testDoc
+	if err := s.FindOne("docs", Where(Eq("name", "a")), &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Size != 42 || a.Owner.Name != "owner-a" || a.Owner.Role != "user" {
+		t.Errorf("unexpected update %+v %+v", a, a.Owner)
+	}
+	if _, err := s.Update("docs", ByID(a.ID), map[string]interface{}{
+		"owner": Literal{map[string]interface{}{"role": "admin"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var replaced *testDoc
+	if err := s.Get("docs", a.ID, &replaced); err != nil {
+		t.Fatal(err)
+	}
+	if replaced.Owner.Name != "" || replaced.Owner.Role != "admin" {
+		t.Errorf("expected the owner to be replaced; received %+v", replaced.Owner)
+	}
+	if err := s.Get("docs", "missing", &a); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound; received %v", err)
+	}
+	if n, err := s.Delete("docs", Where(Eq("name", "b"))); err != nil || n != 1 {
+		t.Errorf("expected 1 delete; received %d %v", n, err)
+	}
+
+	// reopen from the log
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s, err = NewEmbeddedStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if c, err := s.Count("docs", nil); err != nil || c != 3 {
+		t.Errorf("expected 3 documents after reopening; received %d %v", c, err)
+	}
+	var reopened *testDoc
+	if err := s.Get("docs", a.ID, &reopened); err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Size != 42 || !reopened.Created.Equal(a.Created) || reopened.Tags[0] != "ta" {
+		t.Errorf("unexpected document after reopening %+v", reopened)
+	}
+}
+
+func TestEmbeddedStoreLease(t *testing.T) {
+	s, path := newTestStore(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	defer s.Close()
+
+	if h, err := s.Lease("docs", "leader", "a", time.Minute); err != nil || h != "a" {
+		t.Fatalf("expected a to take the lease; received %s %v", h, err)
+	}
+	if h, _ := s.Lease("docs", "leader", "b", time.Minute); h != "a" {
+		t.Errorf("expected a to keep the lease; received %s", h)
+	}
+	if h, _ := s.Lease("docs", "leader", "a", -time.Second); h != "a" {
+		t.Errorf("expected a to renew the lease; received %s", h)
+	}
+	if h, _ := s.Lease("docs", "leader", "b", time.Minute); h != "b" {
+		t.Errorf("expected b to take the expired lease; received %s", h)
+	}
+}
+
+func TestEmbeddedStoreDumpLoad(t *testing.T) {
+	s, path := newTestStore(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	defer s.Close()
+
+	created := time.Now().Truncate(time.Second)
+	if err := s.Put("docs", &testDoc{ID: "1", Name: "a", Created: created}); err != nil {
+		t.Fatal(err)
+	}
+	dumped := []map[string]interface{}{}
+	if err := s.Dump("docs", func(doc map[string]interface{}) error {
+		dumped = append(dumped, doc)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(dumped) != 1 {
+		t.Fatalf("expected 1 document; received %d", len(dumped))
+	}
+	if c, ok := dumped[0]["created"].(map[string]interface{}); !ok || c["$reql_type$"] != "TIME" {
+		t.Errorf("expected a raw time; received %v", dumped[0]["created"])
+	}
+	if err := s.Put("docs", &testDoc{ID: "2", Name: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load("docs", dumped); err != nil {
+		t.Fatal(err)
+	}
+	docs := []*testDoc{}
+	if err := s.Find("docs", nil, &docs); err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || docs[0].Name != "a" || !docs[0].Created.Equal(created) {
+		t.Errorf("unexpected documents after load %v", docs)
+	}
+}
+
+func TestEmbeddedStoreIndex(t *testing.T) {
+	s, path := newTestStore(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	defer s.Close()
+
+	now := time.Now().Truncate(time.Second)
+	for i := 0; i < 20; i++ {
+		doc := &testDoc{
+			ID:      fmt.Sprintf("%02d", i),
+			Name:    fmt.Sprintf("n%d", i%5),
+			Size:    i % 7,
+			Created: now.Add(time.Duration(i%4) * time.Minute),
+			Owner:   &testOwner{Name: fmt.Sprintf("o%d", i%3)},
+		}
+		if i%6 == 0 {
+			doc.Owner = nil
+		}
+		if err := s.Put("docs", doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	queries := []*Query{
+		Where(Eq("name", "n2")),
+		Where(Ge("size", 2), Lt("size", 5)).Sort("size", false),
+		Where(Gt("created", now)).Sort("created", true).Page(1, 3),
+		Where(Le("created", now.Add(time.Minute))).Sort("name", true),
+		Where(Ne("size", 3)).Sort("owner.name", true).Page(2, 4),
+		Where(Eq("size", "3")),
+		Where(Eq("size", 3), Eq("name", "n3")).Sort("created", false).Page(0, 1),
+		ByID("07"),
+	}
+	ids := func() [][]string {
+		results := [][]string{}
+		for _, q := range queries {
+			found := []*testDoc{}
+			if err := s.Find("docs", q, &found); err != nil {
+				t.Fatal(err)
+			}
+			result := []string{}
+			for _, d := range found {
+				result = append(result, d.ID)
+			}
+			results = append(results, result)
+		}
+		return results
+	}
+
+	scanned := ids()
+	for _, field := range []string{"name", "size", "created", "owner.name"} {
+		if err := s.Index("docs", field); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if indexed := ids(); !reflect.DeepEqual(indexed, scanned) {
+		t.Fatalf("expected indexed queries to find %v; received %v", scanned, indexed)
+	}
+
+	// the indexes follow the changes
+	if _, err := s.Update("docs", Where(Eq("name", "n1")), map[string]interface{}{"size": 3}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Delete("docs", Where(Eq("size", 4))); err != nil {
+		t.Fatal(err)
+	}
+	updated := ids()
+	s.indexes = map[string]map[string]*index{}
+	if unindexed := ids(); !reflect.DeepEqual(updated, unindexed) {
+		t.Errorf("expected indexed queries after changes to find %v; received %v", unindexed, updated)
+	}
+}
+
+func TestEmbeddedStoreLocked(t *testing.T) {
+	s, path := newTestStore(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	if _, err := NewEmbeddedStore(path); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked opening an open store; received %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := NewEmbeddedStore(path)
+	if err != nil {
+		t.Fatalf("expected a closed store to open; received %v", err)
+	}
+	reopened.Close()
+}
+
+func TestEmbeddedStoreCompact(t *testing.T) {
+	s, path := newTestStore(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	for i := 0; i < 2*compactSlack; i++ {
+		if err := s.Put("docs", &testDoc{ID: "1", Name: "a", Size: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := bytes.Count(b, []byte("\n")); lines > compactSlack {
+		t.Errorf("expected the log to be compacted; %d lines", lines)
+	}
+	s, err = NewEmbeddedStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	var doc *testDoc
+	if err := s.Get("docs", "1", &doc); err != nil || doc.Size != 2*compactSlack-1 {
+		t.Errorf("expected the last write after compacting; received %+v %v", doc, err)
+	}
+}
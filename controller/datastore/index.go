@@ -0,0 +1,131 @@
+package datastore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// index keeps the ids of the documents of a table of an embedded store
+// sorted by a field, documents without the field first and documents with
+// the same value by id
+type index struct {
+	field string
+	ids   []string
+}
+
+// newIndex indexes the documents of a table
+func newIndex(tbl map[string]map[string]interface{}, field string) *index {
+	x := &index{field: field, ids: make([]string, 0, len(tbl))}
+	for id := range tbl {
+		x.ids = append(x.ids, id)
+	}
+	sort.Slice(x.ids, func(i, j int) bool {
+		a, aok := lookup(tbl[x.ids[i]], field)
+		b, bok := lookup(tbl[x.ids[j]], field)
+		if c := order(a, aok, b, bok); c != 0 {
+			return c < 0
+		}
+		return x.ids[i] < x.ids[j]
+	})
+	return x
+}
+
+// order is the order of the values of an index: missing values first, then
+// the values by type and within a type as compared
+func order(a interface{}, aok bool, b interface{}, bok bool) int {
+	if aok != bok {
+		return cmp(!aok, aok)
+	}
+	if c, ok := compare(a, b); ok {
+		return c
+	}
+	return strings.Compare(fmt.Sprintf("%T", a), fmt.Sprintf("%T", b))
+}
+
+// search returns the position of the first id whose value, or whose id for
+// the same value, is not less than the ones given; with after set ids of the
+// same value are skipped
+func (x *index) search(tbl map[string]map[string]interface{}, v interface{}, vok bool, id string, after bool) int {
+	return sort.Search(len(x.ids), func(i int) bool {
+		a, aok := lookup(tbl[x.ids[i]], x.field)
+		c := order(a, aok, v, vok)
+		if c != 0 || after {
+			return c > 0
+		}
+		return x.ids[i] >= id
+	})
+}
+
+// insert indexes a document after it is stored in the table
+func (x *index) insert(tbl map[string]map[string]interface{}, id string) {
+	v, ok := lookup(tbl[id], x.field)
+	i := x.search(tbl, v, ok, id, false)
+	x.ids = append(x.ids, "")
+	copy(x.ids[i+1:], x.ids[i:])
+	x.ids[i] = id
+}
+
+// remove drops a document from the index before it is changed or removed
+// from the table
+func (x *index) remove(tbl map[string]map[string]interface{}, id string) {
+	v, ok := lookup(tbl[id], x.field)
+	i := x.search(tbl, v, ok, id, false)
+	if i < len(x.ids) && x.ids[i] == id {
+		x.ids = append(x.ids[:i], x.ids[i+1:]...)
+	}
+}
+
+// bounds returns the range of the ids that can match the conditions on the
+// indexed field; values is the native values of the conditions
+func (x *index) bounds(tbl map[string]map[string]interface{}, where []Cond, values []interface{}) (int, int) {
+	lo, hi := 0, len(x.ids)
+	for i, c := range where {
+		if c.Field != x.field {
+			continue
+		}
+		// only values ordered by compare are contiguous in the index
+		if _, ok := compare(values[i], values[i]); !ok {
+			continue
+		}
+		switch c.Op {
+		case OpEq, OpGt, OpGe:
+			if n := x.search(tbl, values[i], true, "", false); n > lo {
+				lo = n
+			}
+		}
+		switch c.Op {
+		case OpEq, OpLt, OpLe:
+			if n := x.search(tbl, values[i], true, "", true); n < hi {
+				hi = n
+			}
+		}
+	}
+	if lo > hi {
+		lo = hi
+	}
+	return lo, hi
+}
+
+// scan returns the ids of a range in the order of the index, or reversed
+// keeping the ids of the same value in order
+func (x *index) scan(tbl map[string]map[string]interface{}, lo int, hi int, desc bool) []string {
+	if !desc {
+		return append([]string{}, x.ids[lo:hi]...)
+	}
+	ids := make([]string, 0, hi-lo)
+	for end := hi; end > lo; {
+		start := end - 1
+		v, ok := lookup(tbl[x.ids[start]], x.field)
+		for start > lo {
+			p, pok := lookup(tbl[x.ids[start-1]], x.field)
+			if order(p, pok, v, ok) != 0 {
+				break
+			}
+			start--
+		}
+		ids = append(ids, x.ids[start:end]...)
+		end = start
+	}
+	return ids
+}
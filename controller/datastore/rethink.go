@@ -0,0 +1,385 @@
+package datastore
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	r "github.com/dancannon/gorethink"
+)
+
+// rawOpts return times and binary data as stored so dumped documents load
+// unchanged
+var rawOpts = r.RunOpts{TimeFormat: "raw", BinaryFormat: "raw"}
+
+// RethinkStore keeps the tables in a RethinkDB database
+type RethinkStore struct {
+	session  *r.Session
+	database string
+
+	// indexes are the indexed fields by table; see Index
+	indexLock sync.RWMutex
+	indexes   map[string]map[string]bool
+}
+
+// NewRethinkStore connects to the database at addr, creating it if needed
+func NewRethinkStore(addr string, database string, authKey string) (*RethinkStore, error) {
+	session, err := r.Connect(r.ConnectOpts{
+		Address:     addr,
+		Database:    database,
+		AuthKey:     authKey,
+		MaxIdle:     10,
+		IdleTimeout: time.Second * 30,
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.DbCreate(database).Run(session)
+	return &RethinkStore{
+		session:  session,
+		database: database,
+		indexes:  make(map[string]map[string]bool),
+	}, nil
+}
+
+func (s *RethinkStore) Init(tables []string) error {
+	for _, tbl := range tables {
+		if _, err := r.Table(tbl).Run(s.session); err != nil {
+			if _, err := r.Db(s.database).TableCreate(tbl).Run(s.session); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *RethinkStore) Index(table string, field string) error {
+	res, err := r.Table(table).IndexList().Run(s.session)
+	if err != nil {
+		return err
+	}
+	existing := []string{}
+	if err := res.All(&existing); err != nil {
+		return err
+	}
+	found := false
+	for _, idx := range existing {
+		if idx == field {
+			found = true
+		}
+	}
+	if !found {
+		create := r.Table(table).IndexCreate(field)
+		if strings.Contains(field, ".") {
+			// an index on a nested field is named after its path
+			create = r.Table(table).IndexCreateFunc(field, func(row r.Term) interface{} {
+				return nested(row, field)
+			})
+		}
+		if _, err := create.RunWrite(s.session); err != nil {
+			return err
+		}
+	}
+	if _, err := r.Table(table).IndexWait(field).Run(s.session); err != nil {
+		return err
+	}
+	s.indexLock.Lock()
+	defer s.indexLock.Unlock()
+	if s.indexes[table] == nil {
+		s.indexes[table] = make(map[string]bool)
+	}
+	s.indexes[table][field] = true
+	return nil
+}
+
+func (s *RethinkStore) indexed(table string, field string) bool {
+	s.indexLock.RLock()
+	defer s.indexLock.RUnlock()
+	return s.indexes[table][field]
+}
+
+// nested returns the field of a row at a path of fields separated by dots
+func nested(row r.Term, path string) r.Term {
+	for _, p := range strings.Split(path, ".") {
+		row = row.Field(p)
+	}
+	return row
+}
+
+func field(path string) r.Term {
+	return nested(r.Row, path)
+}
+
+func condTerm(c Cond) r.Term {
+	f := field(c.Field)
+	switch c.Op {
+	case OpNe:
+		return f.Ne(c.Value)
+	case OpLt:
+		return f.Lt(c.Value)
+	case OpLe:
+		return f.Le(c.Value)
+	case OpGt:
+		return f.Gt(c.Value)
+	case OpGe:
+		return f.Ge(c.Value)
+	case OpIn:
+		return r.Expr(c.Value).Contains(f)
+	case OpContains:
+		return f.Contains(c.Value)
+	case OpPrefix:
+		return f.Match("^" + regexp.QuoteMeta(c.Value.(string)))
+	}
+	return f.Eq(c.Value)
+}
+
+// term selects the documents of a query; queries for a single id use the
+// primary key and queries sorted by an indexed field the index
+func (s *RethinkStore) term(table string, q *Query) r.Term {
+	t := r.Table(table)
+	if q == nil {
+		return t
+	}
+	where := q.Where
+	orderBy := q.OrderBy
+	if len(where) > 0 && where[0].Field == "id" && where[0].Op == OpEq {
+		t = t.GetAll(where[0].Value)
+		where = where[1:]
+	} else if orderBy != "" && s.indexed(table, orderBy) {
+		if q.Desc {
+			t = t.OrderBy(r.OrderByOpts{Index: r.Desc(orderBy)})
+		} else {
+			t = t.OrderBy(r.OrderByOpts{Index: r.Asc(orderBy)})
+		}
+		orderBy = ""
+	}
+	if len(where) > 0 {
+		f := r.Expr(true)
+		for _, c := range where {
+			f = f.And(condTerm(c))
+		}
+		t = t.Filter(f)
+	}
+	if orderBy != "" {
+		key := func(row r.Term) interface{} {
+			return nested(row, orderBy)
+		}
+		if q.Desc {
+			t = t.OrderBy(r.Desc(key))
+		} else {
+			t = t.OrderBy(r.Asc(key))
+		}
+	}
+	if q.Skip > 0 {
+		t = t.Skip(q.Skip)
+	}
+	if q.Limit > 0 {
+		t = t.Limit(q.Limit)
+	}
+	return t
+}
+
+// writeError returns the first error of a write
+func writeError(res r.WriteResponse) error {
+	if res.Errors == 0 {
+		return nil
+	}
+	if strings.Contains(res.FirstError, "Duplicate primary key") {
+		return ErrExists
+	}
+	return errors.New(res.FirstError)
+}
+
+func (s *RethinkStore) Get(table string, id string, v interface{}) error {
+	res, err := r.Table(table).Get(id).Run(s.session)
+	if err != nil {
+		return err
+	}
+	if res.IsNil() {
+		res.Close()
+		return ErrNotFound
+	}
+	return res.One(v)
+}
+
+func (s *RethinkStore) Find(table string, q *Query, v interface{}) error {
+	res, err := s.term(table, q).Run(s.session)
+	if err != nil {
+		return err
+	}
+	return res.All(v)
+}
+
+func (s *RethinkStore) FindOne(table string, q *Query, v interface{}) error {
+	res, err := s.term(table, q).Run(s.session)
+	if err != nil {
+		return err
+	}
+	if err := res.One(v); err != nil {
+		if err == r.ErrEmptyResult {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *RethinkStore) Count(table string, q *Query) (int, error) {
+	res, err := s.term(table, q).Count().Run(s.session)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	if err := res.One(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (s *RethinkStore) Insert(table string, doc interface{}) (string, error) {
+	res, err := r.Table(table).Insert(doc).RunWrite(s.session)
+	if err != nil {
+		return "", err
+	}
+	if err := writeError(res); err != nil {
+		return "", err
+	}
+	if len(res.GeneratedKeys) > 0 {
+		return res.GeneratedKeys[0], nil
+	}
+	return documentID(doc)
+}
+
+func (s *RethinkStore) Put(table string, doc interface{}) error {
+	res, err := r.Table(table).Insert(doc, r.InsertOpts{Conflict: "replace"}).RunWrite(s.session)
+	if err != nil {
+		return err
+	}
+	return writeError(res)
+}
+
+func (s *RethinkStore) Update(table string, q *Query, fields map[string]interface{}) (int, error) {
+	update := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if l, ok := v.(Literal); ok {
+			v = r.Literal(l.Value)
+		}
+		update[k] = v
+	}
+	res, err := s.term(table, q).Update(update).RunWrite(s.session)
+	if err != nil {
+		return 0, err
+	}
+	if err := writeError(res); err != nil {
+		return 0, err
+	}
+	return res.Replaced + res.Unchanged, nil
+}
+
+func (s *RethinkStore) Delete(table string, q *Query) (int, error) {
+	res, err := s.term(table, q).Delete().RunWrite(s.session)
+	if err != nil {
+		return 0, err
+	}
+	if err := writeError(res); err != nil {
+		return 0, err
+	}
+	return res.Deleted, nil
+}
+
+func (s *RethinkStore) Lease(table string, id string, holder string, ttl time.Duration) (string, error) {
+	held := map[string]interface{}{
+		"id":      id,
+		"holder":  holder,
+		"expires": r.Now().Add(ttl.Seconds()),
+	}
+	res, err := r.Table(table).Get(id).Replace(func(row r.Term) interface{} {
+		return r.Branch(
+			row.Eq(nil).Or(row.Field("holder").Eq(holder)).Or(row.Field("expires").Lt(r.Now())),
+			held,
+			row,
+		)
+	}).RunWrite(s.session)
+	if err != nil {
+		return "", err
+	}
+	if err := writeError(res); err != nil {
+		return "", err
+	}
+	var l struct {
+		Holder string `gorethink:"holder"`
+	}
+	if err := s.Get(table, id, &l); err != nil {
+		return "", err
+	}
+	return l.Holder, nil
+}
+
+// rethinkFeed decodes the new values of a changefeed
+type rethinkFeed struct {
+	cursor *r.Cursor
+}
+
+func (f *rethinkFeed) Next(v interface{}) bool {
+	var change struct {
+		NewVal map[string]interface{} `gorethink:"new_val"`
+	}
+	for f.cursor.Next(&change) {
+		if change.NewVal != nil {
+			return decode(v, change.NewVal) == nil
+		}
+	}
+	return false
+}
+
+func (f *rethinkFeed) Err() error {
+	return f.cursor.Err()
+}
+
+func (f *rethinkFeed) Close() error {
+	return f.cursor.Close()
+}
+
+func (s *RethinkStore) Changes(table string) (Feed, error) {
+	res, err := r.Table(table).Changes().Run(s.session)
+	if err != nil {
+		return nil, err
+	}
+	return &rethinkFeed{cursor: res}, nil
+}
+
+func (s *RethinkStore) Dump(table string, fn func(doc map[string]interface{}) error) error {
+	res, err := r.Table(table).Run(s.session, rawOpts)
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+	var doc map[string]interface{}
+	for res.Next(&doc) {
+		if err := fn(doc); err != nil {
+			return err
+		}
+		doc = nil
+	}
+	return res.Err()
+}
+
+func (s *RethinkStore) Load(table string, docs []map[string]interface{}) error {
+	if _, err := r.Table(table).Delete().RunWrite(s.session); err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+	res, err := r.Table(table).Insert(docs, r.InsertOpts{Conflict: "replace"}).RunWrite(s.session)
+	if err != nil {
+		return err
+	}
+	return writeError(res)
+}
+
+func (s *RethinkStore) Close() error {
+	return s.session.Close()
+}
@@ -0,0 +1,183 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// The subset of BER (X.690) needed to speak LDAPv3.  Only single byte tags
+// are supported; LDAP does not use higher tag numbers.
+
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagSet         = 0x31
+
+	classApplication = 0x40
+	classContext     = 0x80
+	constructed      = 0x20
+
+	// maxPacketSize bounds a single message read from the server
+	maxPacketSize = 1 << 20
+)
+
+var (
+	errMalformedPacket = errors.New("ldap: malformed packet")
+)
+
+type packet struct {
+	tag   byte
+	value []byte
+}
+
+// children parses the contents of a constructed packet
+func (p *packet) children() ([]*packet, error) {
+	var children []*packet
+	b := p.value
+	for len(b) > 0 {
+		c, n, err := decodePacket(b)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, c)
+		b = b[n:]
+	}
+	return children, nil
+}
+
+func (p *packet) int() (int, error) {
+	if len(p.value) == 0 || len(p.value) > 4 {
+		return 0, errMalformedPacket
+	}
+	n := int(int8(p.value[0]))
+	for _, b := range p.value[1:] {
+		n = n<<8 | int(b)
+	}
+	return n, nil
+}
+
+func (p *packet) string() string {
+	return string(p.value)
+}
+
+// encode returns a packet with the concatenated contents
+func encode(tag byte, contents ...[]byte) []byte {
+	size := 0
+	for _, c := range contents {
+		size += len(c)
+	}
+	b := append([]byte{tag}, encodeLength(size)...)
+	for _, c := range contents {
+		b = append(b, c...)
+	}
+	return b
+}
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var l []byte
+	for ; n > 0; n >>= 8 {
+		l = append([]byte{byte(n)}, l...)
+	}
+	return append([]byte{0x80 | byte(len(l))}, l...)
+}
+
+// encodeInt encodes a non-negative integer
+func encodeInt(tag byte, n int) []byte {
+	b := []byte{byte(n)}
+	for n >>= 8; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return encode(tag, b)
+}
+
+func encodeString(tag byte, s string) []byte {
+	return encode(tag, []byte(s))
+}
+
+func encodeBool(v bool) []byte {
+	if v {
+		return encode(tagBoolean, []byte{0xff})
+	}
+	return encode(tagBoolean, []byte{0})
+}
+
+// decodePacket parses the packet at the start of b and returns its total
+// encoded size
+func decodePacket(b []byte) (*packet, int, error) {
+	if len(b) < 2 {
+		return nil, 0, errMalformedPacket
+	}
+	length, n, err := decodeLength(b[1:])
+	if err != nil {
+		return nil, 0, err
+	}
+	start := 1 + n
+	if len(b)-start < length {
+		return nil, 0, errMalformedPacket
+	}
+	return &packet{tag: b[0], value: b[start : start+length]}, start + length, nil
+}
+
+func decodeLength(b []byte) (int, int, error) {
+	if len(b) == 0 {
+		return 0, 0, errMalformedPacket
+	}
+	if b[0] < 0x80 {
+		return int(b[0]), 1, nil
+	}
+	size := int(b[0] & 0x7f)
+	if size == 0 || size > 4 || len(b) < 1+size {
+		return 0, 0, errMalformedPacket
+	}
+	length := 0
+	for _, c := range b[1 : 1+size] {
+		length = length<<8 | int(c)
+	}
+	if length > maxPacketSize {
+		return 0, 0, errMalformedPacket
+	}
+	return length, 1 + size, nil
+}
+
+// readPacket reads a single packet from r
+func readPacket(r *bufio.Reader) (*packet, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	header := []byte{first}
+	if first >= 0x80 {
+		size := int(first & 0x7f)
+		if size == 0 || size > 4 {
+			return nil, errMalformedPacket
+		}
+		rest := make([]byte, size)
+		if _, err := io.ReadFull(r, rest); err != nil {
+			return nil, err
+		}
+		header = append(header, rest...)
+	}
+	length, _, err := decodeLength(header)
+	if err != nil {
+		return nil, err
+	}
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return nil, err
+	}
+	return &packet{tag: tag, value: value}, nil
+}
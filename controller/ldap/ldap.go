@@ -0,0 +1,406 @@
+// Package ldap authenticates controller users against an LDAP or Active
+// Directory server and maps their groups to shipyard roles.
+package ldap
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/shipyard/shipyard"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+
+	resultSuccess             = 0
+	resultSizeLimitExceeded   = 4
+	resultInvalidCredentials  = 49
+	opBindRequest             = classApplication | constructed | 0
+	opBindResponse            = classApplication | constructed | 1
+	opUnbindRequest           = classApplication | 2
+	opSearchRequest           = classApplication | constructed | 3
+	opSearchResultEntry       = classApplication | constructed | 4
+	opSearchResultDone        = classApplication | constructed | 5
+	opSearchResultReference   = classApplication | constructed | 19
+	filterEqualityMatch       = classContext | constructed | 3
+	authSimple                = classContext | 0
+	scopeWholeSubtree         = 2
+	derefNever                = 0
+	searchSizeLimit           = 2
+	protocolVersion           = 3
+	unsolicitedNotificationID = 0
+)
+
+var (
+	ErrAmbiguousUser = errors.New("ldap: more than one directory entry matches the user")
+	ErrNoRole        = errors.New("ldap: user is not in a group mapped to a role")
+)
+
+// GroupRole maps members of a directory group to a shipyard role
+type GroupRole struct {
+	Group string
+	Role  string
+}
+
+// Config describes the directory and how its users become shipyard users
+type Config struct {
+	// Addr is the server url: ldap://host[:389] or ldaps://host[:636]
+	Addr string
+	// InsecureSkipVerify disables certificate verification for ldaps
+	InsecureSkipVerify bool
+	// BindDN and BindPassword are used to search for users; the search
+	// is anonymous when BindDN is empty
+	BindDN       string
+	BindPassword string
+	// SearchBase is the subtree searched for users
+	SearchBase string
+	// UserAttribute holds the login name (uid; sAMAccountName for
+	// Active Directory)
+	UserAttribute string
+	// GroupAttribute lists the group DNs of a user entry (memberOf)
+	GroupAttribute string
+	// GroupRoles are checked in order; the first group the user is a
+	// member of decides the role
+	GroupRoles []GroupRole
+	// DefaultRole is used for users in none of GroupRoles; those users
+	// are denied when it is empty
+	DefaultRole string
+	Timeout     time.Duration
+}
+
+// ResultError is a non-success result returned by the server
+type ResultError struct {
+	Code    int
+	Message string
+}
+
+func (e *ResultError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ldap: result code %d", e.Code)
+	}
+	return fmt.Sprintf("ldap: result code %d: %s", e.Code, e.Message)
+}
+
+// Authenticator verifies credentials with a simple bind as the user
+type Authenticator struct {
+	config Config
+}
+
+func NewAuthenticator(cfg Config) *Authenticator {
+	if cfg.UserAttribute == "" {
+		cfg.UserAttribute = "uid"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	return &Authenticator{
+		config: cfg,
+	}
+}
+
+// Authenticate returns the role of the user if the directory accepts the
+// password.  It returns shipyard.ErrUnknownUser if the directory has no
+// such user and shipyard.ErrUnauthorized for a bad password.
+func (a *Authenticator) Authenticate(username string, password string) (string, error) {
+	// an empty password is an unauthenticated bind, which servers accept
+	if username == "" || password == "" {
+		return "", shipyard.ErrUnauthorized
+	}
+	c, err := a.dial()
+	if err != nil {
+		return "", err
+	}
+	defer c.close()
+
+	if a.config.BindDN != "" {
+		if err := c.bind(a.config.BindDN, a.config.BindPassword); err != nil {
+			return "", fmt.Errorf("ldap: search bind failed: %w", err)
+		}
+	}
+	entries, err := c.search(a.config.SearchBase, a.config.UserAttribute, username, []string{a.config.GroupAttribute})
+	if err != nil {
+		return "", err
+	}
+	switch len(entries) {
+	case 0:
+		return "", shipyard.ErrUnknownUser
+	case 1:
+	default:
+		return "", ErrAmbiguousUser
+	}
+	user := entries[0]
+	if err := c.bind(user.dn, password); err != nil {
+		if re, ok := err.(*ResultError); ok && re.Code == resultInvalidCredentials {
+			return "", shipyard.ErrUnauthorized
+		}
+		return "", err
+	}
+	return a.role(user.attributes[strings.ToLower(a.config.GroupAttribute)])
+}
+
+func (a *Authenticator) role(groups []string) (string, error) {
+	for _, gr := range a.config.GroupRoles {
+		for _, g := range groups {
+			if sameDN(gr.Group, g) {
+				return gr.Role, nil
+			}
+		}
+	}
+	if a.config.DefaultRole == "" {
+		return "", ErrNoRole
+	}
+	return a.config.DefaultRole, nil
+}
+
+// sameDN compares distinguished names ignoring case and spacing around
+// separators
+func sameDN(a, b string) bool {
+	normalize := func(dn string) string {
+		parts := strings.Split(dn, ",")
+		for i, p := range parts {
+			kv := strings.SplitN(p, "=", 2)
+			for j := range kv {
+				kv[j] = strings.TrimSpace(kv[j])
+			}
+			parts[i] = strings.Join(kv, "=")
+		}
+		return strings.ToLower(strings.Join(parts, ","))
+	}
+	return normalize(a) == normalize(b)
+}
+
+func (a *Authenticator) dial() (*conn, error) {
+	addr := a.config.Addr
+	useTLS := false
+	switch {
+	case strings.HasPrefix(addr, "ldaps://"):
+		addr = strings.TrimPrefix(addr, "ldaps://")
+		useTLS = true
+	case strings.HasPrefix(addr, "ldap://"):
+		addr = strings.TrimPrefix(addr, "ldap://")
+	}
+	addr = strings.TrimSuffix(addr, "/")
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		port := "389"
+		if useTLS {
+			port = "636"
+		}
+		addr = net.JoinHostPort(addr, port)
+	}
+
+	dialer := &net.Dialer{Timeout: a.config.Timeout}
+	var nc net.Conn
+	var err error
+	if useTLS {
+		nc, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
+			InsecureSkipVerify: a.config.InsecureSkipVerify,
+		})
+	} else {
+		nc, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	nc.SetDeadline(time.Now().Add(a.config.Timeout))
+	return newConn(nc), nil
+}
+
+type entry struct {
+	dn string
+	// attributes are keyed by lower case attribute name
+	attributes map[string][]string
+}
+
+// conn is a synchronous LDAPv3 connection; one operation is in flight at a
+// time
+type conn struct {
+	nc     net.Conn
+	r      *bufio.Reader
+	lastID int
+}
+
+func newConn(nc net.Conn) *conn {
+	return &conn{
+		nc: nc,
+		r:  bufio.NewReader(nc),
+	}
+}
+
+func (c *conn) send(op []byte) (int, error) {
+	c.lastID++
+	msg := encode(tagSequence, encodeInt(tagInteger, c.lastID), op)
+	if _, err := c.nc.Write(msg); err != nil {
+		return 0, err
+	}
+	return c.lastID, nil
+}
+
+// receive returns the protocol op of the next message for id
+func (c *conn) receive(id int) (*packet, error) {
+	p, err := readPacket(c.r)
+	if err != nil {
+		return nil, err
+	}
+	parts, err := p.children()
+	if err != nil {
+		return nil, err
+	}
+	if p.tag != tagSequence || len(parts) < 2 {
+		return nil, errMalformedPacket
+	}
+	msgID, err := parts[0].int()
+	if err != nil {
+		return nil, err
+	}
+	if msgID == unsolicitedNotificationID {
+		// the server is closing the connection (notice of disconnection)
+		if res := parseResult(parts[1]); res != nil {
+			return nil, res
+		}
+		return nil, errors.New("ldap: connection closed by server")
+	}
+	if msgID != id {
+		return nil, fmt.Errorf("ldap: unexpected message id %d", msgID)
+	}
+	return parts[1], nil
+}
+
+func (c *conn) bind(dn string, password string) error {
+	id, err := c.send(encode(opBindRequest,
+		encodeInt(tagInteger, protocolVersion),
+		encodeString(tagOctetString, dn),
+		encodeString(authSimple, password),
+	))
+	if err != nil {
+		return err
+	}
+	op, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if op.tag != opBindResponse {
+		return errMalformedPacket
+	}
+	if res := parseResult(op); res != nil {
+		return res
+	}
+	return nil
+}
+
+// search returns the entries below base whose attr equals value
+func (c *conn) search(base string, attr string, value string, attributes []string) ([]*entry, error) {
+	attrs := make([][]byte, len(attributes))
+	for i, a := range attributes {
+		attrs[i] = encodeString(tagOctetString, a)
+	}
+	id, err := c.send(encode(opSearchRequest,
+		encodeString(tagOctetString, base),
+		encodeInt(tagEnumerated, scopeWholeSubtree),
+		encodeInt(tagEnumerated, derefNever),
+		encodeInt(tagInteger, searchSizeLimit),
+		encodeInt(tagInteger, 0),
+		encodeBool(false),
+		encode(filterEqualityMatch,
+			encodeString(tagOctetString, attr),
+			encodeString(tagOctetString, value),
+		),
+		encode(tagSequence, attrs...),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*entry{}
+	for {
+		op, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+		switch op.tag {
+		case opSearchResultEntry:
+			e, err := parseEntry(op)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, e)
+		case opSearchResultReference:
+			// referrals to other servers are not followed
+		case opSearchResultDone:
+			res := parseResult(op)
+			if res != nil && res.Code == resultSizeLimitExceeded {
+				return nil, ErrAmbiguousUser
+			}
+			if res != nil {
+				return nil, res
+			}
+			return entries, nil
+		default:
+			return nil, errMalformedPacket
+		}
+	}
+}
+
+func (c *conn) close() error {
+	c.send(encode(opUnbindRequest))
+	return c.nc.Close()
+}
+
+// parseResult returns nil for a successful LDAPResult
+func parseResult(op *packet) *ResultError {
+	parts, err := op.children()
+	if err != nil || len(parts) < 3 {
+		return &ResultError{Code: -1, Message: "malformed result"}
+	}
+	code, err := parts[0].int()
+	if err != nil {
+		return &ResultError{Code: -1, Message: "malformed result"}
+	}
+	if code == resultSuccess {
+		return nil
+	}
+	return &ResultError{Code: code, Message: parts[2].string()}
+}
+
+func parseEntry(op *packet) (*entry, error) {
+	parts, err := op.children()
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) != 2 {
+		return nil, errMalformedPacket
+	}
+	e := &entry{
+		dn:         parts[0].string(),
+		attributes: map[string][]string{},
+	}
+	attrs, err := parts[1].children()
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range attrs {
+		kv, err := a.children()
+		if err != nil {
+			return nil, err
+		}
+		if len(kv) != 2 {
+			return nil, errMalformedPacket
+		}
+		vals, err := kv[1].children()
+		if err != nil {
+			return nil, err
+		}
+		name := strings.ToLower(kv[0].string())
+		for _, v := range vals {
+			e.attributes[name] = append(e.attributes[name], v.string())
+		}
+	}
+	return e, nil
+}
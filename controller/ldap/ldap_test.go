@@ -0,0 +1,155 @@
+package ldap
+
+import (
+	"bufio"
+	"net"
+	"testing"
+
+	"github.com/shipyard/shipyard"
+)
+
+// fakeDirectory serves binds and equality searches over users
+type fakeDirectory struct {
+	// users maps login name to dn, password and groups
+	users map[string]fakeUser
+}
+
+type fakeUser struct {
+	dn       string
+	password string
+	groups   []string
+}
+
+func (d *fakeDirectory) serve(t *testing.T, l net.Listener) {
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go d.handle(t, nc)
+	}
+}
+
+func (d *fakeDirectory) handle(t *testing.T, nc net.Conn) {
+	defer nc.Close()
+	r := bufio.NewReader(nc)
+	for {
+		p, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		parts, _ := p.children()
+		id, _ := parts[0].int()
+		op := parts[1]
+		reply := func(op []byte) {
+			nc.Write(encode(tagSequence, encodeInt(tagInteger, id), op))
+		}
+		result := func(tag byte, code int) {
+			reply(encode(tag, encodeInt(tagEnumerated, code), encodeString(tagOctetString, ""), encodeString(tagOctetString, "")))
+		}
+		switch op.tag {
+		case opBindRequest:
+			f, _ := op.children()
+			dn, password := f[1].string(), f[2].string()
+			code := resultInvalidCredentials
+			for _, u := range d.users {
+				if u.dn == dn && u.password == password {
+					code = resultSuccess
+				}
+			}
+			if dn == "cn=search" && password == "search" {
+				code = resultSuccess
+			}
+			result(opBindResponse, code)
+		case opSearchRequest:
+			f, _ := op.children()
+			filter, _ := f[6].children()
+			if u, ok := d.users[filter[1].string()]; ok && filter[0].string() == "uid" {
+				groups := [][]byte{}
+				for _, g := range u.groups {
+					groups = append(groups, encodeString(tagOctetString, g))
+				}
+				reply(encode(opSearchResultEntry,
+					encodeString(tagOctetString, u.dn),
+					encode(tagSequence, encode(tagSequence,
+						encodeString(tagOctetString, "memberOf"),
+						encode(tagSet, groups...),
+					)),
+				))
+			}
+			result(opSearchResultDone, resultSuccess)
+		case opUnbindRequest:
+			return
+		}
+	}
+}
+
+func newTestAuthenticator(t *testing.T, cfg Config) (*Authenticator, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &fakeDirectory{
+		users: map[string]fakeUser{
+			"alice": {dn: "uid=alice,ou=people,dc=example,dc=com", password: "secret", groups: []string{"cn=Ops,ou=groups,dc=example,dc=com"}},
+			"bob":   {dn: "uid=bob,ou=people,dc=example,dc=com", password: "hunter2"},
+		},
+	}
+	go d.serve(t, l)
+	cfg.Addr = "ldap://" + l.Addr().String()
+	cfg.BindDN = "cn=search"
+	cfg.BindPassword = "search"
+	return NewAuthenticator(cfg), func() { l.Close() }
+}
+
+func TestAuthenticate(t *testing.T) {
+	a, done := newTestAuthenticator(t, Config{
+		SearchBase: "dc=example,dc=com",
+		GroupRoles: []GroupRole{{Group: "cn=ops, ou=groups, dc=example, dc=com", Role: "admin"}},
+	})
+	defer done()
+
+	role, err := a.Authenticate("alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role != "admin" {
+		t.Errorf("expected role admin; received %s", role)
+	}
+	if _, err := a.Authenticate("alice", "wrong"); err != shipyard.ErrUnauthorized {
+		t.Errorf("expected ErrUnauthorized; received %v", err)
+	}
+	if _, err := a.Authenticate("carol", "secret"); err != shipyard.ErrUnknownUser {
+		t.Errorf("expected ErrUnknownUser; received %v", err)
+	}
+	if _, err := a.Authenticate("alice", ""); err != shipyard.ErrUnauthorized {
+		t.Errorf("expected empty password to be rejected; received %v", err)
+	}
+	if _, err := a.Authenticate("bob", "hunter2"); err != ErrNoRole {
+		t.Errorf("expected ErrNoRole; received %v", err)
+	}
+}
+
+func TestAuthenticateDefaultRole(t *testing.T) {
+	a, done := newTestAuthenticator(t, Config{DefaultRole: "user"})
+	defer done()
+
+	role, err := a.Authenticate("bob", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role != "user" {
+		t.Errorf("expected role user; received %s", role)
+	}
+}
+
+func TestEncodeLength(t *testing.T) {
+	b := encode(tagOctetString, make([]byte, 300))
+	p, n, err := decodePacket(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(b) || len(p.value) != 300 {
+		t.Errorf("expected 300 byte value; received %d", len(p.value))
+	}
+}
@@ -2,12 +2,15 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/citadel/citadel"
@@ -16,21 +19,51 @@ import (
 	"github.com/gorilla/context"
 	"github.com/gorilla/mux"
 	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/datastore"
+	"github.com/shipyard/shipyard/controller/ldap"
 	"github.com/shipyard/shipyard/controller/manager"
+	"github.com/shipyard/shipyard/controller/metrics"
 	"github.com/shipyard/shipyard/controller/middleware/access"
+	"github.com/shipyard/shipyard/controller/middleware/audit"
 	"github.com/shipyard/shipyard/controller/middleware/auth"
+	"github.com/shipyard/shipyard/controller/middleware/compression"
+	"github.com/shipyard/shipyard/controller/middleware/etag"
+	"github.com/shipyard/shipyard/controller/middleware/idempotency"
+	"github.com/shipyard/shipyard/controller/middleware/ratelimit"
+	"github.com/shipyard/shipyard/controller/middleware/tracing"
 	"github.com/shipyard/shipyard/dockerhub"
+	"github.com/shipyard/shipyard/websocket"
 )
 
 var (
-	listenAddr        string
-	rethinkdbAddr     string
-	rethinkdbDatabase string
-	rethinkdbAuthKey  string
-	disableUsageInfo  bool
-	showVersion       bool
-	controllerManager *manager.Manager
-	logger            = logrus.New()
+	listenAddr               string
+	datastoreType            string
+	datastorePath            string
+	rethinkdbAddr            string
+	rethinkdbDatabase        string
+	rethinkdbAuthKey         string
+	disableUsageInfo         bool
+	disableMetrics           bool
+	publicMetrics            bool
+	disableGzip              bool
+	disableETags             bool
+	rateLimits               ratelimit.Limits
+	idempotencyWindow        time.Duration
+	metricsInterval          time.Duration
+	containerMetricsInterval time.Duration
+	metricsWindow            time.Duration
+	enableHA                 bool
+	instanceID               string
+	leaderLease              time.Duration
+	showVersion              bool
+	showSpec                 bool
+	ldapConfig               ldap.Config
+	ldapGroupRoles           groupRolesFlag
+	secretKey                string
+	placement                string
+	tracer                   *shipyard.Tracer
+	controllerManager        *manager.Manager
+	logger                   = logrus.New()
 )
 
 const (
@@ -42,22 +75,54 @@ type (
 	Credentials struct {
 		Username string `json:"username,omitempty"`
 		Password string `json:"password,omitempty"`
+		// OTP is the one time code for accounts with two-factor auth
+		OTP string `json:"otp,omitempty"`
 	}
 )
 
 func init() {
-	flag.StringVar(&listenAddr, "listen", ":8080", "listen address")
+	flag.StringVar(&listenAddr, "listen", ":8080", "listen address; unix:///path/to/socket listens on a unix socket")
+	flag.StringVar(&datastoreType, "datastore", "rethinkdb", "datastore keeping the controller state: rethinkdb or embedded (a file for single node installs)")
+	flag.StringVar(&datastorePath, "datastore-path", "/data/shipyard.db", "file of the embedded datastore")
 	flag.StringVar(&rethinkdbAddr, "rethinkdb-addr", "127.0.0.1:28015", "rethinkdb address")
 	flag.StringVar(&rethinkdbDatabase, "rethinkdb-database", "shipyard", "rethinkdb database")
 	flag.StringVar(&rethinkdbAuthKey, "rethinkdb-auth-key", "", "rethinkdb auth key")
 	flag.BoolVar(&disableUsageInfo, "disable-usage-info", false, "disable anonymous usage info")
 	flag.BoolVar(&showVersion, "version", false, "show version and exit")
+	flag.BoolVar(&showSpec, "spec", false, "print the openapi spec and exit")
+	flag.BoolVar(&disableMetrics, "disable-metrics", false, "disable the prometheus /metrics endpoint")
+	flag.BoolVar(&publicMetrics, "public-metrics", false, "serve the prometheus /metrics endpoint without authentication")
+	flag.BoolVar(&disableGzip, "disable-gzip", false, "disable gzip compression of api responses")
+	flag.BoolVar(&disableETags, "disable-etags", false, "disable entity tags and 304 responses for api GET requests")
+	flag.Float64Var(&rateLimits.Global, "rate-limit", 0, "api requests per second accepted from all clients together; 0 is unlimited")
+	flag.Float64Var(&rateLimits.Account, "rate-limit-account", 0, "api requests per second accepted from each account; 0 is unlimited")
+	flag.Float64Var(&rateLimits.ServiceKey, "rate-limit-service-key", 0, "api requests per second accepted from each service key; 0 is unlimited")
+	flag.DurationVar(&idempotencyWindow, "idempotency-window", idempotency.DefaultWindow, "how long responses are replayed to requests retried with the same Idempotency-Key")
+	flag.DurationVar(&metricsInterval, "metrics-interval", shipyard.DefaultMetricsInterval, "how often container and engine resource usage is sampled; 0 disables sampling")
+	flag.DurationVar(&containerMetricsInterval, "metrics-container-interval", shipyard.DefaultContainerMetricsInterval, "how often container samples are stored; engine totals are stored every metrics-interval")
+	flag.DurationVar(&metricsWindow, "metrics-window", shipyard.DefaultMetricsWindow, "how long resource usage samples are kept")
+	hostname, _ := os.Hostname()
+	flag.BoolVar(&enableHA, "ha", false, "run as one of several controllers sharing the datastore; a leader runs the background loops")
+	flag.StringVar(&instanceID, "instance-id", hostname, "id of this controller in ha mode; must be unique")
+	flag.DurationVar(&leaderLease, "leader-lease", manager.DefaultLeaderLease, "how long the leader keeps its lease in ha mode without renewing it")
+	flag.IntVar(&rateLimits.Burst, "rate-limit-burst", 0, "api requests accepted at once above the rate limits; 0 allows one second worth")
+	flag.StringVar(&ldapConfig.Addr, "ldap-addr", "", "ldap server url (ldap://host:389 or ldaps://host:636); enables directory logins")
+	flag.BoolVar(&ldapConfig.InsecureSkipVerify, "ldap-insecure-skip-verify", false, "skip ldaps certificate verification")
+	flag.StringVar(&ldapConfig.BindDN, "ldap-bind-dn", "", "dn used to search for users; anonymous when empty")
+	flag.StringVar(&ldapConfig.BindPassword, "ldap-bind-password", "", "password for -ldap-bind-dn (or LDAP_BIND_PASSWORD)")
+	flag.StringVar(&ldapConfig.SearchBase, "ldap-search-base", "", "base dn searched for users")
+	flag.StringVar(&ldapConfig.UserAttribute, "ldap-user-attr", "uid", "attribute holding the login name (sAMAccountName for active directory)")
+	flag.StringVar(&ldapConfig.GroupAttribute, "ldap-group-attr", "memberOf", "attribute listing the groups of a user")
+	flag.Var(&ldapGroupRoles, "ldap-group-role", "map a group dn to a role (<group-dn>=<role>); can be repeated, the first matching group wins")
+	flag.StringVar(&placement, "placement", shipyard.DefaultPlacement, "default container placement strategy: binpack, spread, random or label-affinity")
+	flag.StringVar(&secretKey, "secret-key", "", "passphrase secrets are encrypted with (or SHIPYARD_SECRET_KEY); secrets are disabled when empty")
+	flag.StringVar(&ldapConfig.DefaultRole, "ldap-default-role", "", "role for directory users in no mapped group; they are denied when empty")
 }
 
 func destroy(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	container, err := controllerManager.Container(id)
+	container, err := requestContainer(r, id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -67,7 +132,10 @@ func destroy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := controllerManager.Destroy(container); err != nil {
+	err = traceEngine(r, "engine.destroy", container, func() error {
+		return controllerManager.Destroy(container)
+	})
+	if err != nil {
 		logger.Errorf("error destroying %s: %s", container.ID, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -78,41 +146,147 @@ func destroy(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func run(w http.ResponseWriter, r *http.Request) {
-	r.ParseForm()
-	p := r.FormValue("pull")
-	c := r.FormValue("count")
+// runParams returns the count, pull and dryRun query parameters of a run
+func runParams(r *http.Request) (int, bool, bool, error) {
 	count := 1
 	pull := false
-	if p != "" {
+	dryRun := false
+	if d := r.FormValue("dryRun"); d != "" {
+		dv, err := strconv.ParseBool(d)
+		if err != nil {
+			return 0, false, false, err
+		}
+		dryRun = dv
+	}
+	if p := r.FormValue("pull"); p != "" {
 		pv, err := strconv.ParseBool(p)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			return 0, false, false, err
 		}
 		pull = pv
 	}
-	if c != "" {
+	if c := r.FormValue("count"); c != "" {
 		cc, err := strconv.Atoi(c)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			return 0, false, false, err
 		}
 		count = cc
 	}
+	return count, pull, dryRun, nil
+}
+
+func run(w http.ResponseWriter, r *http.Request) {
+	count, pull, dryRun, err := runParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	var image *citadel.Image
 	if err := json.NewDecoder(r.Body).Decode(&image); err != nil {
 		logger.Warnf("error decoding image: %s", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if !checkImageEnvironment(w, image) {
+		return
+	}
+	runImage(w, r, image, count, pull, dryRun)
+}
+
+// checkRunImage responds with an error unless the request may launch
+// containers of the image, i.e. inject the secrets it refers to
+func checkRunImage(w http.ResponseWriter, r *http.Request, image *citadel.Image) bool {
+	if err := shipyard.ValidateReschedulePolicy(shipyard.ReschedulePolicy(image)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if _, err := shipyard.ContainerRestartPolicy(image); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if _, err := shipyard.ContainerStopHook(image); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if _, err := shipyard.ContainerHealthCheck(image); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if !checkSecretRefs(w, r, image) {
+		return false
+	}
+	if _, err := shipyard.VolumeMounts(image); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if _, err := shipyard.ContainerAffinity(image); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if strategy := shipyard.ContainerPlacement(image); strategy != "" {
+		if err := shipyard.ValidatePlacement(strategy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return false
+		}
+	}
+	if pool := shipyard.ContainerPool(image); pool != "" {
+		if _, err := controllerManager.EnginePool(pool); err != nil {
+			status := http.StatusInternalServerError
+			if err == manager.ErrEnginePoolDoesNotExist {
+				status = http.StatusBadRequest
+			}
+			http.Error(w, err.Error(), status)
+			return false
+		}
+	}
+	if _, err := shipyard.ImageLabels(image); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// runImage checks an image and launches count containers of it, or
+// previews where they would be placed
+func runImage(w http.ResponseWriter, r *http.Request, image *citadel.Image, count int, pull bool, dryRun bool) {
+	if !checkRunImage(w, r, image) {
+		return
+	}
+	shipyard.SetOwner(image, requestAccount(r))
+	shipyard.SetNamespace(image, requestNamespace(r))
+	if !checkQuota(w, r, image, count) {
+		return
+	}
+	if dryRun {
+		preview, err := controllerManager.PreviewRun(image, count)
+		if err != nil {
+			logger.Warnf("error previewing run: %s", err)
+			status := http.StatusInternalServerError
+			if errors.Is(err, manager.ErrUnknownImageType) {
+				status = http.StatusBadRequest
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		if err := json.NewEncoder(w).Encode(preview); err != nil {
+			logger.Error(err)
+		}
+		return
+	}
 
-	launched, err := controllerManager.Run(image, count, pull)
+	var launched []*citadel.Container
+	err := traceEngine(r, "engine.run", nil, func() error {
+		var err error
+		launched, err = controllerManager.Run(image, count, pull)
+		return err
+	})
 	if err != nil {
 		logger.Warnf("error running container: %s", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	prepareContainers(launched...)
 
 	w.Header().Set("content-type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -122,10 +296,38 @@ func run(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func stopContainer(w http.ResponseWriter, r *http.Request) {
+// traceEngine runs an engine operation in a span of the request trace
+func traceEngine(r *http.Request, name string, container *citadel.Container, fn func() error) error {
+	_, span := tracer.StartSpan(r.Context(), name, shipyard.SpanKindInternal)
+	if container != nil {
+		span.SetAttribute("container.id", container.ID)
+		if container.Engine != nil {
+			span.SetAttribute("engine.id", container.Engine.ID)
+		}
+	}
+	err := fn()
+	span.SetError(err)
+	span.Finish()
+	return err
+}
+
+// stopTimeout returns the timeout query parameter or the default of 10 seconds
+func stopTimeout(r *http.Request) (int, error) {
+	timeout := 10
+	if t := r.FormValue("timeout"); t != "" {
+		tv, err := strconv.Atoi(t)
+		if err != nil {
+			return 0, err
+		}
+		timeout = tv
+	}
+	return timeout, nil
+}
+
+func startContainer(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	container, err := controllerManager.Container(id)
+	container, err := requestContainer(r, id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -135,21 +337,24 @@ func stopContainer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := controllerManager.ClusterManager().Stop(container); err != nil {
-		logger.Errorf("error stopping %s: %s", container.ID, err)
+	err = traceEngine(r, "engine.start", container, func() error {
+		return controllerManager.Start(container)
+	})
+	if err != nil {
+		logger.Errorf("error starting %s: %s", container.ID, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	logger.Infof("stopped container %s (%s)", container.ID, container.Image.Name)
+	logger.Infof("started container %s (%s)", container.ID, container.Image.Name)
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func containerLogs(w http.ResponseWriter, r *http.Request) {
+func pauseContainer(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	container, err := controllerManager.Container(id)
+	container, err := requestContainer(r, id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -159,20 +364,21 @@ func containerLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data, err := controllerManager.ClusterManager().Logs(container, true, true)
-	if err != nil {
-		logger.Errorf("error getting logs for %s: %s", container.ID, err)
+	if err := controllerManager.Pause(container); err != nil {
+		logger.Errorf("error pausing %s: %s", container.ID, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	stdcopy.StdCopy(w, w, data)
+	logger.Infof("paused container %s (%s)", container.ID, container.Image.Name)
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func restartContainer(w http.ResponseWriter, r *http.Request) {
+func unpauseContainer(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	container, err := controllerManager.Container(id)
+	container, err := requestContainer(r, id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -182,33 +388,26 @@ func restartContainer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := controllerManager.ClusterManager().Restart(container, 10); err != nil {
-		logger.Errorf("error restarting %s: %s", container.ID, err)
+	if err := controllerManager.Unpause(container); err != nil {
+		logger.Errorf("error unpausing %s: %s", container.ID, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	logger.Infof("restarted container %s (%s)", container.ID, container.Image.Name)
+	logger.Infof("unpaused container %s (%s)", container.ID, container.Image.Name)
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func scaleContainer(w http.ResponseWriter, r *http.Request) {
+func stopContainer(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	r.ParseForm()
-	sCount := r.FormValue("count")
-	if sCount == "" {
-		http.Error(w, "you must specify a count", http.StatusBadRequest)
-		return
-	}
-	count, err := strconv.Atoi(sCount)
+	timeout, err := stopTimeout(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	container, err := controllerManager.Container(id)
+	container, err := requestContainer(r, id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -218,52 +417,116 @@ func scaleContainer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := controllerManager.Scale(container, count); err != nil {
+	err = traceEngine(r, "engine.stop", container, func() error {
+		return controllerManager.Stop(container, timeout)
+	})
+	if err != nil {
+		logger.Errorf("error stopping %s: %s", container.ID, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	logger.Infof("scaled container %s (%s) to %d", container.ID, container.Image.Name, count)
+
+	logger.Infof("stopped container %s (%s)", container.ID, container.Image.Name)
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func engines(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("content-type", "application/json")
+func containerLogs(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	container, err := requestContainer(r, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
 
-	engines := controllerManager.Engines()
-	if err := json.NewEncoder(w).Encode(engines); err != nil {
-		logger.Error(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	r.ParseForm()
+	stdout := r.FormValue("stdout") != ""
+	stderr := r.FormValue("stderr") != ""
+	// if output not specified, use both
+	if !stdout && !stderr {
+		stdout = true
+		stderr = true
+	}
+	follow := false
+	if f := r.FormValue("follow"); f != "" {
+		fv, err := strconv.ParseBool(f)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		follow = fv
+	}
+	tail := 0
+	if t := r.FormValue("tail"); t != "" {
+		tv, err := strconv.Atoi(t)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tail = tv
+	}
+
+	data, err := controllerManager.Logs(container, stdout, stderr, follow, tail)
+	if err != nil {
+		logger.Errorf("error getting logs for %s: %s", container.ID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-}
+	defer data.Close()
 
-func inspectEngine(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("content-type", "application/json")
+	out := newFlushWriter(w)
+	stdcopy.StdCopy(out, out, data)
+}
 
+// copyFromContainer streams a tar archive of a path in a container
+func copyFromContainer(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	engine := controllerManager.Engine(id)
-	if err := json.NewEncoder(w).Encode(engine); err != nil {
-		logger.Error(err)
+	path := r.FormValue("path")
+	container, err := requestContainer(r, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
 	}
-}
 
-func containers(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("content-type", "application/json")
+	archive, err := controllerManager.CopyFrom(container, path)
+	if err != nil {
+		logger.Errorf("error copying %s from %s: %s", path, container.ID, err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidPath):
+			status = http.StatusBadRequest
+		case err == manager.ErrPathDoesNotExist:
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	defer archive.Close()
+	logger.Infof("copied %s from container %s", path, container.ID)
 
-	containers := controllerManager.ClusterManager().ListContainers(true, false, "")
-	if err := json.NewEncoder(w).Encode(containers); err != nil {
+	w.Header().Set("content-type", "application/x-tar")
+	if _, err := io.Copy(w, archive); err != nil {
 		logger.Error(err)
 	}
 }
 
-func inspectContainer(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("content-type", "application/json")
-
+// copyToContainer extracts the tar archive in the request body into a
+// directory of a container
+func copyToContainer(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	container, err := controllerManager.Container(id)
+	path := r.FormValue("path")
+	container, err := requestContainer(r, id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -272,112 +535,1290 @@ func inspectContainer(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "container not found", http.StatusNotFound)
 		return
 	}
-	if err := json.NewEncoder(w).Encode(container); err != nil {
-		logger.Error(err)
+
+	if err := controllerManager.CopyTo(container, path, r.Body); err != nil {
+		logger.Errorf("error copying to %s in %s: %s", path, container.ID, err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidPath):
+			status = http.StatusBadRequest
+		case err == manager.ErrPathDoesNotExist:
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
 	}
+	logger.Infof("copied to %s in container %s", path, container.ID)
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func addEngine(w http.ResponseWriter, r *http.Request) {
-	var engine *shipyard.Engine
-	if err := json.NewDecoder(r.Body).Decode(&engine); err != nil {
+func containerChanges(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	container, err := requestContainer(r, id)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	health := &shipyard.Health{
-		Status:       "pending",
-		ResponseTime: 0,
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
 	}
-	engine.Health = health
-	if err := controllerManager.AddEngine(engine); err != nil {
+
+	changes, err := controllerManager.Diff(container)
+	if err != nil {
+		logger.Errorf("error getting changes of %s: %s", container.ID, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	logger.Infof("added engine id=%s addr=%s cpus=%f memory=%f", engine.Engine.ID, engine.Engine.Addr, engine.Engine.Cpus, engine.Engine.Memory)
-	w.WriteHeader(http.StatusCreated)
+
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(changes); err != nil {
+		logger.Error(err)
+	}
 }
 
-func removeEngine(w http.ResponseWriter, r *http.Request) {
+// exportContainer streams a tar archive of the container filesystem
+func exportContainer(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
-	engine := controllerManager.Engine(id)
-	if err := controllerManager.RemoveEngine(engine.ID); err != nil {
+	container, err := requestContainer(r, id)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	logger.Infof("removed engine id=%s", engine.Engine.ID)
-	w.WriteHeader(http.StatusNoContent)
-}
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
 
-func clusterInfo(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("content-type", "application/json")
+	archive, err := controllerManager.Export(container)
+	if err != nil {
+		logger.Errorf("error exporting %s: %s", container.ID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer archive.Close()
+	logger.Infof("exported container %s", container.ID)
 
-	info := controllerManager.ClusterInfo()
-	if err := json.NewEncoder(w).Encode(info); err != nil {
+	w.Header().Set("content-type", "application/x-tar")
+	if _, err := io.Copy(w, archive); err != nil {
 		logger.Error(err)
 	}
 }
 
-func addServiceKey(w http.ResponseWriter, r *http.Request) {
-	var k *shipyard.ServiceKey
-	if err := json.NewDecoder(r.Body).Decode(&k); err != nil {
+// waitContainer responds once the container stops with its exit code
+func waitContainer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	container, err := requestContainer(r, id)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	key, err := controllerManager.NewServiceKey(k.Description)
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
+
+	code, err := controllerManager.Wait(container)
 	if err != nil {
+		logger.Errorf("error waiting for %s: %s", container.ID, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	logger.Infof("created service key key=%s description=%s", key.Key, key.Description)
-	if err := json.NewEncoder(w).Encode(key); err != nil {
+
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(&shipyard.ContainerExit{ExitCode: code}); err != nil {
 		logger.Error(err)
 	}
 }
 
-func serviceKeys(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("content-type", "application/json")
+func containerTop(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	container, err := requestContainer(r, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
 
-	keys, err := controllerManager.ServiceKeys()
+	processes, err := controllerManager.Top(container, r.FormValue("ps_args"))
 	if err != nil {
-		logger.Error(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logger.Errorf("error listing processes of %s: %s", container.ID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if err := json.NewEncoder(w).Encode(keys); err != nil {
+
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(processes); err != nil {
 		logger.Error(err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
 	}
 }
 
-func removeServiceKey(w http.ResponseWriter, r *http.Request) {
-	var key *shipyard.ServiceKey
-	if err := json.NewDecoder(r.Body).Decode(&key); err != nil {
+func containerStats(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	container, err := requestContainer(r, id)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if err := controllerManager.RemoveServiceKey(key.Key); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
 		return
 	}
-	logger.Infof("removed service key %s", key.Key)
-	w.WriteHeader(http.StatusNoContent)
-}
 
-func events(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("content-type", "application/json")
 
-	limit := -1
-	l := r.FormValue("limit")
-	if l != "" {
-		lt, err := strconv.Atoi(l)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		limit = lt
+	enc := json.NewEncoder(newFlushWriter(w))
+	sendStats := func(stats *shipyard.ContainerStats) error {
+		return enc.Encode(stats)
+	}
+	if err := controllerManager.Stats(container, sendStats); err != nil {
+		logger.Warnf("stats stream for %s closed: %s", container.ID, err)
 	}
-	events, err := controllerManager.Events(limit)
+}
+
+func containerMetrics(w http.ResponseWriter, r *http.Request) {
+	container, err := requestContainer(r, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
+	writeMetrics(w, r, shipyard.MetricsContainers, container.ID)
+}
+
+// writeMetrics responds with the samples of a resource taken since the
+// since query parameter
+func writeMetrics(w http.ResponseWriter, r *http.Request, resource string, id string) {
+	var since time.Time
+	if v := r.FormValue("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid since: %s", v), http.StatusBadRequest)
+			return
+		}
+		since = t
+	}
+	samples, err := controllerManager.Metrics(resource, id, since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(samples); err != nil {
+		logger.Error(err)
+	}
+}
+
+func createExec(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	container, err := requestContainer(r, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
+	var cfg *shipyard.ExecConfig
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if cfg == nil || len(cfg.Cmd) == 0 {
+		http.Error(w, "you must specify a command", http.StatusBadRequest)
+		return
+	}
+
+	execID, err := controllerManager.CreateExec(container, cfg)
+	if err != nil {
+		logger.Errorf("error creating exec in %s: %s", container.ID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Infof("created exec %s in container %s (%s): %v", execID[:12], container.ID, container.Image.Name, cfg.Cmd)
+
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	info := &shipyard.ExecInfo{ID: execID}
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		logger.Error(err)
+	}
+}
+
+func startExec(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	execID := vars["execId"]
+	container, err := requestContainer(r, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
+	r.ParseForm()
+	tty := false
+	if t := r.FormValue("tty"); t != "" {
+		tv, err := strconv.ParseBool(t)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tty = tv
+	}
+
+	conn, br, err := controllerManager.StartExec(container, execID, tty)
+	if err != nil {
+		logger.Errorf("error starting exec %s: %s", execID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := proxyRawStream(w, conn, br); err != nil {
+		logger.Warnf("exec stream %s closed: %s", execID, err)
+	}
+}
+
+// attachContainer bridges the stdio of a container over a websocket.  The
+// X-Shipyard-Tty handshake header tells whether output is multiplexed in
+// the docker stream format.
+func attachContainer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if !websocket.IsUpgrade(r) {
+		http.Error(w, "a websocket connection is required", http.StatusBadRequest)
+		return
+	}
+	container, err := requestContainer(r, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
+
+	conn, br, tty, err := controllerManager.Attach(container)
+	if err != nil {
+		logger.Errorf("error attaching to %s: %s", container.ID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	header := http.Header{}
+	header.Set(shipyard.TtyHeader, strconv.FormatBool(tty))
+	ws, err := websocket.Upgrade(w, r, header)
+	if err != nil {
+		conn.Close()
+		logger.Warnf("error upgrading attach to %s: %s", container.ID, err)
+		return
+	}
+
+	if err := proxyWebSocket(ws, conn, br); err != nil {
+		logger.Warnf("attach stream %s closed: %s", container.ID, err)
+	}
+}
+
+func inspectExec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+	execID := vars["execId"]
+	container, err := requestContainer(r, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
+	info, err := controllerManager.InspectExec(container, execID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		logger.Error(err)
+	}
+}
+
+func restartContainer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	timeout, err := stopTimeout(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	container, err := requestContainer(r, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
+
+	err = traceEngine(r, "engine.restart", container, func() error {
+		return controllerManager.Restart(container, timeout)
+	})
+	if err != nil {
+		logger.Errorf("error restarting %s: %s", container.ID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Infof("restarted container %s (%s)", container.ID, container.Image.Name)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func scaleContainer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	r.ParseForm()
+	sCount := r.FormValue("count")
+	if sCount == "" {
+		http.Error(w, "you must specify a count", http.StatusBadRequest)
+		return
+	}
+	count, err := strconv.Atoi(sCount)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	container, err := requestContainer(r, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
+
+	if err := controllerManager.Scale(container, count); err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrInvalidScaleCount {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("scaled container %s (%s) to %d", container.ID, container.Image.Name, count)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func scaleImage(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	sCount := r.FormValue("count")
+	if sCount == "" {
+		http.Error(w, "you must specify a count", http.StatusBadRequest)
+		return
+	}
+	count, err := strconv.Atoi(sCount)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if count < 0 {
+		http.Error(w, "count must not be negative", http.StatusBadRequest)
+		return
+	}
+	var image *citadel.Image
+	if err := json.NewDecoder(r.Body).Decode(&image); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if image == nil {
+		http.Error(w, "an image is required", http.StatusBadRequest)
+		return
+	}
+	if !checkImageEnvironment(w, image) || !checkRunImage(w, r, image) {
+		return
+	}
+	shipyard.SetOwner(image, requestAccount(r))
+	shipyard.SetNamespace(image, requestNamespace(r))
+	// only the containers of the caller in the namespace are scaled
+	current, err := controllerManager.ScaleTargets(image)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !checkQuota(w, r, image, count-len(current)) {
+		return
+	}
+
+	if err := controllerManager.ScaleImage(image, count); err != nil {
+		logger.Errorf("error scaling %s: %s", image.Name, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Infof("scaled image %s to %d", image.Name, count)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func images(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	images, err := controllerManager.Images()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(images); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func pullImage(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	if name == "" {
+		http.Error(w, "you must specify an image name", http.StatusBadRequest)
+		return
+	}
+	tag := r.FormValue("tag")
+	if err := controllerManager.PullImage(name, tag); err != nil {
+		logger.Errorf("error pulling image %s: %s", name, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Infof("pulled image %s", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// buildImage builds the tar context in the request body and streams the
+// build output as json encoded shipyard.BuildMessage values
+func buildImage(w http.ResponseWriter, r *http.Request) {
+	opts := &shipyard.BuildOptions{
+		Tag:        r.FormValue("tag"),
+		Engine:     r.FormValue("engine"),
+		Dockerfile: r.FormValue("dockerfile"),
+	}
+	for name, v := range map[string]*bool{"nocache": &opts.NoCache, "pull": &opts.Pull} {
+		if s := r.FormValue(name); s != "" {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			*v = b
+		}
+	}
+
+	started := false
+	enc := json.NewEncoder(newFlushWriter(w))
+	send := func(msg *shipyard.BuildMessage) error {
+		if !started {
+			w.Header().Set("content-type", "application/json")
+			started = true
+		}
+		return enc.Encode(msg)
+	}
+	err := controllerManager.Build(r.Body, opts, send)
+	switch {
+	case err == nil:
+		logger.Infof("built image %s", opts.Tag)
+	case started:
+		// the engine reported build failures in the stream
+		logger.Errorf("error building image %s: %s", opts.Tag, err)
+		if !errors.Is(err, shipyard.ErrBuildFailed) {
+			send(&shipyard.BuildMessage{Error: err.Error()})
+		}
+	default:
+		logger.Errorf("error building image %s: %s", opts.Tag, err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidBuild):
+			status = http.StatusBadRequest
+		case err == manager.ErrEngineDoesNotExist:
+			status = http.StatusNotFound
+		case err == manager.ErrNoBuildEngine:
+			status = http.StatusServiceUnavailable
+		}
+		http.Error(w, err.Error(), status)
+	}
+}
+
+func removeImage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	if err := controllerManager.RemoveImage(name); err != nil {
+		logger.Errorf("error removing image %s: %s", name, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Infof("removed image %s", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func engines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	engines := []*shipyard.Engine{}
+	for _, e := range controllerManager.Engines() {
+		engines = append(engines, e.Redacted())
+	}
+	if err := json.NewEncoder(w).Encode(engines); err != nil {
+		logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func inspectEngine(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+	engine := controllerManager.Engine(id)
+	if engine == nil {
+		http.Error(w, "engine not found", http.StatusNotFound)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(engine.Redacted()); err != nil {
+		logger.Error(err)
+	}
+}
+
+func engineCertificates(w http.ResponseWriter, r *http.Request) {
+	info, err := controllerManager.EngineCertificates(mux.Vars(r)["id"])
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrEngineDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		logger.Error(err)
+	}
+}
+
+func setEngineCertificates(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var certs *shipyard.EngineCertificates
+	if err := json.NewDecoder(r.Body).Decode(&certs); err != nil || certs == nil {
+		http.Error(w, "invalid certificates", http.StatusBadRequest)
+		return
+	}
+	if err := controllerManager.SetEngineCertificates(id, certs); err != nil {
+		logger.Errorf("error updating engine certificates: %s", err)
+		status := http.StatusInternalServerError
+		switch {
+		case err == manager.ErrEngineDoesNotExist:
+			status = http.StatusNotFound
+		case errors.Is(err, shipyard.ErrInvalidCertificate):
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("updated certificates of engine %s", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func engineMetrics(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if controllerManager.Engine(id) == nil {
+		http.Error(w, manager.ErrEngineDoesNotExist.Error(), http.StatusNotFound)
+		return
+	}
+	writeMetrics(w, r, shipyard.MetricsEngines, id)
+}
+
+func engineHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+	health, err := controllerManager.EngineHealth(id)
+	if err != nil {
+		if err == manager.ErrEngineDoesNotExist {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(health); err != nil {
+		logger.Error(err)
+	}
+}
+
+func updateEngine(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	var engine *shipyard.Engine
+	if err := json.NewDecoder(r.Body).Decode(&engine); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	engine.ID = id
+	if err := controllerManager.UpdateEngine(engine); err != nil {
+		switch {
+		case err == manager.ErrEngineDoesNotExist:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case err == manager.ErrInvalidEngine, errors.Is(err, shipyard.ErrInvalidCertificate), errors.Is(err, shipyard.ErrInvalidCAdvisor):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			logger.Errorf("error updating engine: %s", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	logger.Infof("updated engine id=%s addr=%s cpus=%f memory=%f", engine.Engine.ID, engine.Engine.Addr, engine.Engine.Cpus, engine.Engine.Memory)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func updateEngineLabels(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	var labels []string
+	if err := json.NewDecoder(r.Body).Decode(&labels); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := controllerManager.UpdateEngineLabels(id, labels); err != nil {
+		if err == manager.ErrEngineDoesNotExist {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.Errorf("error updating engine labels: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Infof("updated labels for engine %s", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func cordonEngine(w http.ResponseWriter, r *http.Request) {
+	engineAction(w, r, "cordoned", controllerManager.CordonEngine)
+}
+
+func uncordonEngine(w http.ResponseWriter, r *http.Request) {
+	engineAction(w, r, "uncordoned", controllerManager.UncordonEngine)
+}
+
+func drainEngine(w http.ResponseWriter, r *http.Request) {
+	engineAction(w, r, "drained", controllerManager.DrainEngine)
+}
+
+// engineAction runs a maintenance operation on the engine in the request
+func engineAction(w http.ResponseWriter, r *http.Request, action string, fn func(id string) error) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if err := fn(id); err != nil {
+		if err == manager.ErrEngineDoesNotExist {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.Errorf("error updating engine %s: %s", id, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Infof("%s engine %s", action, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// containers returns a page of the containers selected by the image,
+// engine, state, label, selector, limit and offset query parameters
+func containers(w http.ResponseWriter, r *http.Request) {
+	selector, err := shipyard.ParseSelector(r.FormValue("selector"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query := &shipyard.ContainerQuery{
+		Image:     r.FormValue("image"),
+		Engine:    r.FormValue("engine"),
+		State:     r.FormValue("state"),
+		Label:     r.FormValue("label"),
+		Selector:  selector,
+		Namespace: requestNamespace(r),
+	}
+	if err := parsePage(r, &query.Limit, &query.Offset); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+
+	containers := controllerManager.QueryContainers(query)
+	prepareContainers(containers...)
+	if err := json.NewEncoder(w).Encode(containers); err != nil {
+		logger.Error(err)
+	}
+}
+
+// endpoints returns the published addresses of running containers; see
+// shipyard.EndpointFilter for the query parameters
+func endpoints(w http.ResponseWriter, r *http.Request) {
+	selector, err := shipyard.ParseSelector(r.FormValue("selector"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter := &shipyard.EndpointFilter{
+		Image:       r.FormValue("image"),
+		Label:       r.FormValue("label"),
+		Application: r.FormValue("application"),
+		Selector:    selector,
+		Namespace:   requestNamespace(r),
+	}
+	if p := r.FormValue("port"); p != "" {
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.ContainerPort = port
+	}
+	if h := r.FormValue("healthy"); h != "" {
+		healthy, err := strconv.ParseBool(h)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.Healthy = healthy
+	}
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(controllerManager.Endpoints(filter)); err != nil {
+		logger.Error(err)
+	}
+}
+
+// containerGroup starts, stops, restarts or destroys the containers of the
+// group selected by the label, application, project or selector query
+// parameter
+func containerGroup(w http.ResponseWriter, r *http.Request) {
+	group := &shipyard.ContainerGroup{
+		Label:       r.FormValue("label"),
+		Application: r.FormValue("application"),
+		Project:     r.FormValue("project"),
+		Selector:    r.FormValue("selector"),
+		Namespace:   requestNamespace(r),
+	}
+	timeout, err := stopTimeout(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var res *shipyard.GroupResult
+	switch mux.Vars(r)["action"] {
+	case "start":
+		res, err = controllerManager.StartGroup(group)
+	case "stop":
+		res, err = controllerManager.StopGroup(group, timeout)
+	case "restart":
+		res, err = controllerManager.RestartGroup(group, timeout)
+	case "destroy":
+		res, err = controllerManager.DestroyGroup(group)
+	default:
+		http.Error(w, "unknown group action", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidGroup):
+			code = http.StatusBadRequest
+		case err == manager.ErrGroupEmpty:
+			code = http.StatusNotFound
+		case err == manager.ErrGroupApplications, errors.Is(err, manager.ErrGroupEngineDown):
+			code = http.StatusConflict
+		}
+		logger.Errorf("error applying %s to group %s: %s", mux.Vars(r)["action"], group, err)
+		http.Error(w, err.Error(), code)
+		return
+	}
+	logger.Infof("%s group %s: %d containers", res.Action, res.Group, len(res.Containers))
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logger.Error(err)
+	}
+}
+
+// batchContainers applies a list of shipyard.BatchOperation and reports the
+// outcome of every container
+func batchContainers(w http.ResponseWriter, r *http.Request) {
+	var ops []*shipyard.BatchOperation
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res, err := controllerManager.Batch(ops, requestNamespace(r))
+	if err != nil {
+		if errors.Is(err, shipyard.ErrInvalidBatch) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.Errorf("error running batch: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Infof("batch of %d operations: %d containers, %d failed", len(ops), len(res.Results), res.Failed)
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		logger.Error(err)
+	}
+}
+
+// updateContainer applies a shipyard.ContainerPatch to the labels and
+// description of a container and returns the updated container
+func updateContainer(w http.ResponseWriter, r *http.Request) {
+	var patch *shipyard.ContainerPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	vars := mux.Vars(r)
+	id := vars["id"]
+	container, err := requestContainer(r, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
+
+	if err := controllerManager.UpdateContainer(container, patch); err != nil {
+		logger.Errorf("error updating %s: %s", container.ID, err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, shipyard.ErrInvalidLabel) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("updated container %s (%s)", container.ID, container.Image.Name)
+
+	prepareContainers(container)
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(container); err != nil {
+		logger.Error(err)
+	}
+}
+
+func renameContainer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	name := r.FormValue("name")
+	container, err := requestContainer(r, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
+
+	if err := controllerManager.RenameContainer(container, name); err != nil {
+		logger.Errorf("error renaming %s: %s", container.ID, err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, shipyard.ErrInvalidContainerName) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("renamed container %s to %s", container.ID, name)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// commitContainer creates an image from a container and returns it
+func commitContainer(w http.ResponseWriter, r *http.Request) {
+	var opts *shipyard.CommitOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	vars := mux.Vars(r)
+	id := vars["id"]
+	container, err := requestContainer(r, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
+	if opts.Author == "" {
+		opts.Author = sessionUsername(r)
+	}
+
+	image, err := controllerManager.Commit(container, opts)
+	if err != nil {
+		logger.Errorf("error committing %s: %s", container.ID, err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidCommit):
+			status = http.StatusBadRequest
+		case errors.Is(err, manager.ErrRegistryDoesNotExist):
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("committed container %s to %s", container.ID, opts.Image())
+
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(image); err != nil {
+		logger.Error(err)
+	}
+}
+
+func inspectContainer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+	container, err := requestContainer(r, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
+	prepareContainers(container)
+	if err := json.NewEncoder(w).Encode(container); err != nil {
+		logger.Error(err)
+	}
+}
+
+func addEngine(w http.ResponseWriter, r *http.Request) {
+	var engine *shipyard.Engine
+	if err := json.NewDecoder(r.Body).Decode(&engine); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	health := &shipyard.Health{
+		Status:       "pending",
+		ResponseTime: 0,
+	}
+	engine.Health = health
+	if err := controllerManager.AddEngine(engine); err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrInvalidEngine || errors.Is(err, shipyard.ErrInvalidCertificate) || errors.Is(err, shipyard.ErrInvalidCAdvisor) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("added engine id=%s addr=%s cpus=%f memory=%f", engine.Engine.ID, engine.Engine.Addr, engine.Engine.Cpus, engine.Engine.Memory)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func removeEngine(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	engine := controllerManager.Engine(id)
+	if err := controllerManager.RemoveEngine(engine.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Infof("removed engine id=%s", engine.Engine.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func joinTokens(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	tokens, err := controllerManager.JoinTokens()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(tokens); err != nil {
+		logger.Error(err)
+	}
+}
+
+func createJoinToken(w http.ResponseWriter, r *http.Request) {
+	var t *shipyard.JoinToken
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil || t == nil {
+		http.Error(w, "invalid join token", http.StatusBadRequest)
+		return
+	}
+	token, err := controllerManager.CreateJoinToken(time.Duration(t.ExpiresIn)*time.Second, t.Labels, sessionUsername(r))
+	if err != nil {
+		logger.Errorf("error creating join token: %s", err)
+		status := http.StatusInternalServerError
+		if err == manager.ErrInvalidJoinTokenTTL {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("created join token %s", token.ID)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(token); err != nil {
+		logger.Error(err)
+	}
+}
+
+func deleteJoinToken(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := controllerManager.DeleteJoinToken(id); err != nil {
+		logger.Errorf("error deleting join token: %s", err)
+		status := http.StatusInternalServerError
+		if err == manager.ErrJoinTokenDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("deleted join token %s", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// joinEngine registers the docker host sending a join request.  It is not
+// behind the api auth; the join token authorizes the request.
+func joinEngine(w http.ResponseWriter, r *http.Request) {
+	var req *shipyard.JoinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req == nil {
+		http.Error(w, "invalid join request", http.StatusBadRequest)
+		return
+	}
+	engine, err := controllerManager.JoinEngine(req)
+	if err != nil {
+		logger.Warnf("error joining engine %s from %s: %s", req.ID, r.RemoteAddr, err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidJoinToken):
+			status = http.StatusUnauthorized
+		case errors.Is(err, shipyard.ErrInvalidJoinRequest), errors.Is(err, shipyard.ErrInvalidCertificate):
+			status = http.StatusBadRequest
+		case err == manager.ErrEngineExists:
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("engine joined id=%s addr=%s cpus=%f memory=%f", engine.Engine.ID, engine.Engine.Addr, engine.Engine.Cpus, engine.Engine.Memory)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(engine.Engine); err != nil {
+		logger.Error(err)
+	}
+}
+
+func clusterInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	info := controllerManager.ClusterInfo()
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		logger.Error(err)
+	}
+}
+
+// ping lets clients check connectivity and credentials; the auth middleware
+// rejects bad credentials before it is reached
+func ping(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func versionInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	v := &shipyard.VersionInfo{
+		Version:     VERSION,
+		APIVersion:  shipyard.APIVersion,
+		APIVersions: shipyard.SupportedAPIVersions,
+	}
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error(err)
+	}
+}
+
+func openAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(apiSpec); err != nil {
+		logger.Error(err)
+	}
+}
+
+func clusterUsage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	usage := controllerManager.Usage()
+	if err := json.NewEncoder(w).Encode(usage); err != nil {
+		logger.Error(err)
+	}
+}
+
+func rebalance(w http.ResponseWriter, r *http.Request) {
+	opts := &shipyard.RebalanceOptions{}
+	if err := json.NewDecoder(r.Body).Decode(opts); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	moves, err := controllerManager.Rebalance(opts)
+	if err != nil {
+		if errors.Is(err, shipyard.ErrInvalidRebalanceOptions) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.Errorf("error rebalancing cluster: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !opts.DryRun {
+		logger.Infof("rebalanced cluster: moved %d containers", len(moves))
+	}
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(moves); err != nil {
+		logger.Error(err)
+	}
+}
+
+func addServiceKey(w http.ResponseWriter, r *http.Request) {
+	var k *shipyard.ServiceKey
+	if err := json.NewDecoder(r.Body).Decode(&k); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// keys can not be given permissions the caller does not hold; keys
+	// without a scope grant everything
+	perms := k.Permissions
+	if len(perms) == 0 {
+		perms = []string{shipyard.PermissionAll}
+	}
+	for _, p := range perms {
+		if err := shipyard.ValidatePermission(p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !hasPermission(r, p) {
+			http.Error(w, "granting "+p+" requires holding it", http.StatusForbidden)
+			return
+		}
+	}
+	key, err := controllerManager.NewServiceKey(k.Description, time.Duration(k.ExpiresIn)*time.Second, k.Permissions, requestNamespace(r), requestAccount(r))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, shipyard.ErrInvalidPermission) || err == manager.ErrInvalidServiceKeyTTL {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("created service key key=%s description=%s", key.Key, key.Description)
+	if err := json.NewEncoder(w).Encode(key); err != nil {
+		logger.Error(err)
+	}
+}
+
+func serviceKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	keys, err := controllerManager.ServiceKeys()
+	if err != nil {
+		logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if ns := requestNamespace(r); ns != "" {
+		scoped := []*shipyard.ServiceKey{}
+		for _, k := range keys {
+			if k.Namespace == ns {
+				scoped = append(scoped, k)
+			}
+		}
+		keys = scoped
+	}
+	if err := json.NewEncoder(w).Encode(keys); err != nil {
+		logger.Error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func removeServiceKey(w http.ResponseWriter, r *http.Request) {
+	var key *shipyard.ServiceKey
+	if err := json.NewDecoder(r.Body).Decode(&key); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if ns := requestNamespace(r); ns != "" {
+		current, err := controllerManager.ServiceKey(key.Key)
+		if err != nil || current.Namespace != ns {
+			http.Error(w, "service key not found", http.StatusNotFound)
+			return
+		}
+	}
+	if err := controllerManager.RemoveServiceKey(key.Key); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Infof("removed service key %s", key.Key)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// events returns the events selected by the query parameters as json, or
+// streams them as csv or json lines with format
+func events(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	query := &shipyard.EventQuery{
+		EventFilter: shipyard.EventFilter{
+			Types:       r.Form["type"],
+			EngineID:    r.FormValue("engine"),
+			ContainerID: r.FormValue("container"),
+			Namespace:   requestNamespace(r),
+		},
+	}
+	if err := parseQueryRange(r, &query.Limit, &query.Offset, &query.Since, &query.Until); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if format := r.FormValue("format"); format != "" && format != "json" {
+		if !startExport(w, format, "events") {
+			return
+		}
+		if err := controllerManager.ExportEvents(w, format, query); err != nil {
+			logger.Errorf("error exporting events: %s", err)
+		}
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	events, err := controllerManager.Events(query)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -388,186 +1829,1988 @@ func events(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func purgeEvents(w http.ResponseWriter, r *http.Request) {
+// startExport writes the headers of an export download named name; it
+// answers bad request and returns false for unsupported formats
+func startExport(w http.ResponseWriter, format string, name string) bool {
+	contentType, err := shipyard.ExportContentType(format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	w.Header().Set("content-type", contentType)
+	w.Header().Set("content-disposition", fmt.Sprintf("attachment; filename=%s.%s", name, format))
+	return true
+}
+
+// parseQueryRange reads the limit, offset, since and until query parameters
+// shared by the event and audit log queries
+func parseQueryRange(r *http.Request, limit, offset *int, since, until *time.Time) error {
+	if err := parsePage(r, limit, offset); err != nil {
+		return err
+	}
+	for _, p := range []struct {
+		name string
+		val  *time.Time
+	}{{"since", since}, {"until", until}} {
+		v := r.FormValue(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %s", p.name, v)
+		}
+		*p.val = t
+	}
+	return nil
+}
+
+// parsePage reads the limit and offset query parameters of paged lists
+func parsePage(r *http.Request, limit, offset *int) error {
+	for _, p := range []struct {
+		name string
+		val  *int
+	}{{"limit", limit}, {"offset", offset}} {
+		v := r.FormValue(p.name)
+		if v == "" {
+			continue
+		}
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 {
+			return fmt.Errorf("invalid %s: %s", p.name, v)
+		}
+		*p.val = i
+	}
+	return nil
+}
+
+func auditLog(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	filter := &shipyard.AuditFilter{
+		Username: r.FormValue("username"),
+		Method:   r.FormValue("method"),
+		Endpoint: r.FormValue("endpoint"),
+	}
+	if err := parseQueryRange(r, &filter.Limit, &filter.Offset, &filter.Since, &filter.Until); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if format := r.FormValue("format"); format != "" && format != "json" {
+		if !startExport(w, format, "audit") {
+			return
+		}
+		if err := controllerManager.ExportAuditLog(w, format, filter); err != nil {
+			logger.Errorf("error exporting audit log: %s", err)
+		}
+		return
+	}
+
+	entries, err := controllerManager.AuditLog(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func streamEvents(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	filter := &shipyard.EventFilter{
+		Types:       r.Form["type"],
+		EngineID:    r.FormValue("engine"),
+		ContainerID: r.FormValue("container"),
+		Namespace:   requestNamespace(r),
+	}
+
+	w.Header().Set("content-type", "text/event-stream")
+	w.Header().Set("cache-control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fw := newFlushWriter(w)
+	// send a comment so the client sees the stream open immediately
+	fmt.Fprint(fw, ": connected\n\n")
+
+	events := controllerManager.SubscribeEvents(filter)
+	defer controllerManager.UnsubscribeEvents(events)
+
+	for {
+		select {
+		case evt := <-events:
+			data, err := json.Marshal(evt)
+			if err != nil {
+				logger.Errorf("error encoding event: %s", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(fw, "event: %s\ndata: %s\n\n", evt.Type, data); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func purgeEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	if err := controllerManager.PurgeEvents(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Info("cluster events purged")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func eventRetention(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	policy, err := controllerManager.EventRetention()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(policy.Redacted()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func setEventRetention(w http.ResponseWriter, r *http.Request) {
+	var policy *shipyard.EventRetention
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil || policy == nil {
+		http.Error(w, "invalid event retention", http.StatusBadRequest)
+		return
+	}
+	if err := controllerManager.SetEventRetention(policy); err != nil {
+		logger.Errorf("error setting event retention: %s", err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidEventRetention), err == manager.ErrNoSecretKey:
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("updated event retention: enabled=%v", policy.Enabled)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func runEventRetention(w http.ResponseWriter, r *http.Request) {
+	result, err := controllerManager.ApplyEventRetention()
+	if err != nil {
+		logger.Errorf("error applying event retention: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Infof("expired %d events", result.Removed)
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logger.Error(err)
+	}
+}
+
+// accounts returns a page of the accounts selected by the role, limit and
+// offset query parameters
+func accounts(w http.ResponseWriter, r *http.Request) {
+	query := &shipyard.AccountQuery{
+		Role: r.FormValue("role"),
+	}
+	if err := parsePage(r, &query.Limit, &query.Offset); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+
+	accounts, err := controllerManager.QueryAccounts(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(accounts); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func addAccount(w http.ResponseWriter, r *http.Request) {
+	var account *shipyard.Account
+	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := controllerManager.SaveAccount(account); err != nil {
+		logger.Errorf("error saving account: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Infof("saved account %s", account.Username)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// accountErrorStatus maps account and role lookup errors to a status code
+func accountErrorStatus(err error) int {
+	switch err {
+	case manager.ErrAccountDoesNotExist, manager.ErrRoleDoesNotExist, manager.ErrNamespaceDoesNotExist:
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func updateAccount(w http.ResponseWriter, r *http.Request) {
+	var account *shipyard.Account
+	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := controllerManager.UpdateAccount(account); err != nil {
+		logger.Errorf("error updating account: %s", err)
+		http.Error(w, err.Error(), accountErrorStatus(err))
+		return
+	}
+
+	logger.Infof("updated account %s", account.Username)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func setAccountRole(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	username := vars["username"]
+	var role *shipyard.Role
+	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := controllerManager.SetAccountRole(username, role.Name); err != nil {
+		logger.Errorf("error setting account role: %s", err)
+		http.Error(w, err.Error(), accountErrorStatus(err))
+		return
+	}
+
+	logger.Infof("set role for account %s to %s", username, role.Name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setAccountNamespaceRole grants an account a role in a namespace; an empty
+// role name revokes it
+func setAccountNamespaceRole(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	username := vars["username"]
+	namespace := vars["namespace"]
+	var role *shipyard.Role
+	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := controllerManager.SetAccountNamespaceRole(username, namespace, role.Name); err != nil {
+		logger.Errorf("error setting account namespace role: %s", err)
+		http.Error(w, err.Error(), accountErrorStatus(err))
+		return
+	}
+
+	logger.Infof("set role for account %s in namespace %s to %s", username, namespace, role.Name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func deleteAccount(w http.ResponseWriter, r *http.Request) {
+	var acct *shipyard.Account
+	if err := json.NewDecoder(r.Body).Decode(&acct); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	account, err := controllerManager.Account(acct.Username)
+	if err != nil {
+		logger.Errorf("error deleting account: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := controllerManager.DeleteAccount(account); err != nil {
+		logger.Errorf("error deleting account: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Infof("deleted account %s (%s)", account.Username, account.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func roles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	roles, err := controllerManager.Roles()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(roles); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func role(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	vars := mux.Vars(r)
+	name := vars["name"]
+	role, err := controllerManager.Role(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(role); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func addRole(w http.ResponseWriter, r *http.Request) {
+	var role *shipyard.Role
+	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := controllerManager.CreateRole(role); err != nil {
+		logger.Errorf("error saving role: %s", err)
+		http.Error(w, err.Error(), roleErrorStatus(err))
+		return
+	}
+
+	logger.Infof("saved role %s", role.Name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func updateRole(w http.ResponseWriter, r *http.Request) {
+	var role *shipyard.Role
+	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := controllerManager.UpdateRole(role); err != nil {
+		logger.Errorf("error updating role: %s", err)
+		http.Error(w, err.Error(), roleErrorStatus(err))
+		return
+	}
+
+	logger.Infof("updated role %s", role.Name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// roleErrorStatus maps role errors to a status code
+func roleErrorStatus(err error) int {
+	switch {
+	case err == manager.ErrRoleDoesNotExist:
+		return http.StatusNotFound
+	case err == manager.ErrRoleExists:
+		return http.StatusConflict
+	case err == manager.ErrRoleNameRequired, errors.Is(err, shipyard.ErrInvalidPermission):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func deleteRole(w http.ResponseWriter, r *http.Request) {
+	var rl *shipyard.Role
+	if err := json.NewDecoder(r.Body).Decode(&rl); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	role, err := controllerManager.Role(rl.Name)
+	if err != nil {
+		logger.Errorf("error deleting role: %s", err)
+		http.Error(w, err.Error(), roleErrorStatus(err))
+		return
+	}
+	if err := controllerManager.DeleteRole(role); err != nil {
+		logger.Errorf("error deleting role: %s", err)
+		http.Error(w, err.Error(), roleErrorStatus(err))
+		return
+	}
+
+	logger.Infof("deleted role %s", role.Name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func extensions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	exts, err := controllerManager.Extensions()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(exts); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func extension(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+	ext, err := controllerManager.Extension(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(ext); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func addExtension(w http.ResponseWriter, r *http.Request) {
+	var ext *shipyard.Extension
+	if err := json.NewDecoder(r.Body).Decode(&ext); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := controllerManager.SaveExtension(ext); err != nil {
+		logger.Errorf("error saving extension: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Infof("saved extension name=%s version=%s author=%s", ext.Name, ext.Version, ext.Author)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func deleteExtension(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if err := controllerManager.DeleteExtension(id); err != nil {
+		logger.Errorf("error deleting extension: %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Infof("removed extension %s", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func registries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	registries, err := controllerManager.Registries()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// credentials are write only
+	for _, reg := range registries {
+		reg.Password = ""
+	}
+	if err := json.NewEncoder(w).Encode(registries); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func addRegistry(w http.ResponseWriter, r *http.Request) {
+	var registry *shipyard.Registry
+	if err := json.NewDecoder(r.Body).Decode(&registry); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := controllerManager.AddRegistry(registry); err != nil {
+		logger.Errorf("error saving registry: %s", err)
+		status := http.StatusInternalServerError
+		switch err {
+		case manager.ErrRegistryExists:
+			status = http.StatusConflict
+		case manager.ErrRegistryNameRequired, manager.ErrRegistryAddrRequired, manager.ErrNoSecretKey:
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	logger.Infof("added registry name=%s addr=%s", registry.Name, registry.Addr)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func removeRegistry(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	if err := controllerManager.RemoveRegistry(name); err != nil {
+		logger.Errorf("error removing registry: %s", err)
+		status := http.StatusInternalServerError
+		if err == manager.ErrRegistryDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("removed registry %s", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func webhooks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	hooks, err := controllerManager.Webhooks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// secrets are only returned when a webhook is added
+	for _, h := range hooks {
+		h.Secret = ""
+	}
+	if err := json.NewEncoder(w).Encode(hooks); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func addWebhook(w http.ResponseWriter, r *http.Request) {
+	var hook *shipyard.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := controllerManager.AddWebhook(hook); err != nil {
+		logger.Errorf("error saving webhook: %s", err)
+		status := http.StatusInternalServerError
+		if err == manager.ErrInvalidWebhookURL {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("added webhook id=%s", hook.ID)
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(hook); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func removeWebhook(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if err := controllerManager.RemoveWebhook(id); err != nil {
+		logger.Errorf("error removing webhook: %s", err)
+		status := http.StatusInternalServerError
+		if err == manager.ErrWebhookDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("removed webhook %s", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func notifiers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	notifiers, err := controllerManager.Notifiers()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	redacted := []*shipyard.Notifier{}
+	for _, n := range notifiers {
+		redacted = append(redacted, n.Redacted())
+	}
+	if err := json.NewEncoder(w).Encode(redacted); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func addNotifier(w http.ResponseWriter, r *http.Request) {
+	var n *shipyard.Notifier
+	if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := controllerManager.AddNotifier(n); err != nil {
+		logger.Errorf("error saving notifier: %s", err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, shipyard.ErrInvalidNotifier) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("added notifier id=%s name=%s type=%s", n.ID, n.Name, n.Type)
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(n.Redacted()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func removeNotifier(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if err := controllerManager.RemoveNotifier(id); err != nil {
+		logger.Errorf("error removing notifier: %s", err)
+		status := http.StatusInternalServerError
+		if err == manager.ErrNotifierDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("removed notifier %s", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func alerts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	alerts, err := controllerManager.Alerts()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(alerts); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func alertRules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	rules, err := controllerManager.AlertRules()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(rules); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func addAlertRule(w http.ResponseWriter, r *http.Request) {
+	var rule *shipyard.AlertRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil || rule == nil {
+		http.Error(w, "invalid alert rule", http.StatusBadRequest)
+		return
+	}
+	if err := controllerManager.AddAlertRule(rule); err != nil {
+		logger.Errorf("error saving alert rule: %s", err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, shipyard.ErrInvalidAlertRule) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("added alert rule id=%s name=%s type=%s", rule.ID, rule.Name, rule.Type)
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(rule); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func removeAlertRule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := controllerManager.RemoveAlertRule(id); err != nil {
+		logger.Errorf("error removing alert rule: %s", err)
+		status := http.StatusInternalServerError
+		if err == manager.ErrAlertRuleDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("removed alert rule %s", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func applications(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	all, err := controllerManager.Applications()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	apps := []*shipyard.Application{}
+	for _, app := range all {
+		if ns := requestNamespace(r); ns == "" || app.Namespace == ns {
+			apps = append(apps, app)
+		}
+	}
+	if err := json.NewEncoder(w).Encode(apps); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func application(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	app, err := requestApplication(r, name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrApplicationDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(app); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func applicationContainers(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	if _, err := requestApplication(r, name); err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrApplicationDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	containers := controllerManager.ApplicationContainers(name)
+	prepareContainers(containers...)
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(containers); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func createApplication(w http.ResponseWriter, r *http.Request) {
+	var app *shipyard.Application
+	if err := json.NewDecoder(r.Body).Decode(&app); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(app.Secrets) > 0 && !checkSecretAccess(w, r) {
+		return
+	}
+	app.Owner = requestAccount(r)
+	app.Namespace = requestNamespace(r)
+	if !checkQuota(w, r, app.ContainerImage(), app.Count) {
+		return
+	}
+	if err := controllerManager.CreateApplication(app); err != nil {
+		logger.Errorf("error creating application: %s", err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidApplication):
+			status = http.StatusBadRequest
+		case err == manager.ErrApplicationExists:
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("created application name=%s image=%s count=%d", app.Name, app.Image, app.Count)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(app); err != nil {
+		logger.Error(err)
+	}
+}
+
+// deployCompose deploys the compose file in the request body as the
+// applications of the project query parameter.  Each application is
+// checked like one created or updated on its own.
+func deployCompose(w http.ResponseWriter, r *http.Request) {
+	project := r.FormValue("project")
+	apps, err := controllerManager.ComposeApplications(r.Body, project, requestNamespace(r))
+	if err == nil {
+		launches := []*manager.QuotaLaunch{}
+		for _, app := range apps {
+			if len(app.Secrets) > 0 && !checkSecretAccess(w, r) {
+				return
+			}
+			// updated applications keep their owner
+			app.Owner = requestAccount(r)
+			running := 0
+			for _, c := range controllerManager.ApplicationContainers(app.Name) {
+				if c.State == "running" {
+					running++
+				}
+			}
+			launches = append(launches, &manager.QuotaLaunch{Image: app.ContainerImage(), Count: app.Count - running})
+		}
+		if !checkQuotaLaunches(w, r, launches) {
+			return
+		}
+		err = controllerManager.DeployCompose(apps, project, requestNamespace(r))
+	}
+	if err != nil {
+		logger.Errorf("error deploying compose project %s: %s", project, err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidCompose), errors.Is(err, shipyard.ErrInvalidApplication):
+			status = http.StatusBadRequest
+		case errors.Is(err, manager.ErrApplicationExists):
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("deployed compose project %s: %d services", project, len(apps))
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(apps); err != nil {
+		logger.Error(err)
+	}
+}
+
+func updateApplication(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	var app *shipyard.Application
+	if err := json.NewDecoder(r.Body).Decode(&app); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	app.Name = vars["name"]
+	if !checkApplication(w, r, app.Name) {
+		return
+	}
+	if len(app.Secrets) > 0 && !checkSecretAccess(w, r) {
+		return
+	}
+	running := 0
+	for _, c := range controllerManager.ApplicationContainers(app.Name) {
+		if c.State == "running" {
+			running++
+		}
+	}
+	if !checkQuota(w, r, app.ContainerImage(), app.Count-running) {
+		return
+	}
+	confirm := false
+	if c := r.FormValue("confirm"); c != "" {
+		cv, err := strconv.ParseBool(c)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		confirm = cv
+	}
+	if !confirm {
+		diff, err := controllerManager.ApplicationDiff(app)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if err == manager.ErrApplicationDoesNotExist {
+				status = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if changes := diff.Destructive(); len(changes) > 0 {
+			msg := []string{}
+			for _, c := range changes {
+				msg = append(msg, c.String())
+			}
+			http.Error(w, fmt.Sprintf("destructive changes must be confirmed with confirm=true: %s", strings.Join(msg, ", ")), http.StatusConflict)
+			return
+		}
+	}
+	if err := controllerManager.UpdateApplication(app); err != nil {
+		logger.Errorf("error updating application: %s", err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidApplication):
+			status = http.StatusBadRequest
+		case err == manager.ErrApplicationDoesNotExist:
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("updated application name=%s image=%s count=%d", app.Name, app.Image, app.Count)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func diffApplication(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	var app *shipyard.Application
+	if err := json.NewDecoder(r.Body).Decode(&app); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	app.Name = vars["name"]
+	if !checkApplication(w, r, app.Name) {
+		return
+	}
+	diff, err := controllerManager.ApplicationDiff(app)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrApplicationDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(diff); err != nil {
+		logger.Error(err)
+	}
+}
+
+func removeApplication(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	if !checkApplication(w, r, name) {
+		return
+	}
+	if err := controllerManager.RemoveApplication(name); err != nil {
+		logger.Errorf("error removing application: %s", err)
+		status := http.StatusInternalServerError
+		if err == manager.ErrApplicationDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("removed application %s", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func deployApplication(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	var req *shipyard.DeployRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req == nil {
+		http.Error(w, "deploy request required", http.StatusBadRequest)
+		return
+	}
+	if !checkApplication(w, r, name) {
+		return
+	}
+	d, err := controllerManager.Deploy(name, req.Image, &req.Options, sessionUsername(r))
+	if err != nil {
+		logger.Errorf("error deploying application: %s", err)
+		status := http.StatusInternalServerError
+		switch {
+		case err == manager.ErrApplicationDoesNotExist:
+			status = http.StatusNotFound
+		case err == manager.ErrDeploymentInProgress:
+			status = http.StatusConflict
+		case errors.Is(err, shipyard.ErrQuotaExceeded):
+			status = http.StatusForbidden
+		case err == manager.ErrDeployImageRequired, errors.Is(err, shipyard.ErrInvalidDeployOptions):
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("deploying application %s image=%s deployment=%s", name, req.Image, d.ID)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(d); err != nil {
+		logger.Error(err)
+	}
+}
+
+func rollbackApplication(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	var req *shipyard.RollbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req == nil {
+		http.Error(w, "rollback request required", http.StatusBadRequest)
+		return
+	}
+	if !checkApplication(w, r, name) {
+		return
+	}
+	var d *shipyard.Deployment
+	target, err := controllerManager.RollbackTarget(name, req.Revision)
+	if err == nil {
+		if len(target.Spec.Secrets) > 0 && !checkSecretAccess(w, r) {
+			return
+		}
+		d, err = controllerManager.Rollback(name, req.Revision, &req.Options, sessionUsername(r))
+	}
+	if err != nil {
+		logger.Errorf("error rolling back application: %s", err)
+		status := http.StatusInternalServerError
+		switch {
+		case err == manager.ErrApplicationDoesNotExist:
+			status = http.StatusNotFound
+		case err == manager.ErrDeploymentInProgress:
+			status = http.StatusConflict
+		case errors.Is(err, shipyard.ErrQuotaExceeded):
+			status = http.StatusForbidden
+		case errors.Is(err, shipyard.ErrInvalidRevision), errors.Is(err, shipyard.ErrInvalidDeployOptions), errors.Is(err, shipyard.ErrInvalidApplication):
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("rolling back application %s to revision %d deployment=%s", name, d.RollbackTo, d.ID)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(d); err != nil {
+		logger.Error(err)
+	}
+}
+
+func deployments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	all, err := controllerManager.Deployments(r.FormValue("application"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	deployments := []*shipyard.Deployment{}
+	for _, d := range all {
+		if requestNamespace(r) == "" {
+			deployments = append(deployments, d)
+		} else if _, err := requestApplication(r, d.Application); err == nil {
+			deployments = append(deployments, d)
+		}
+	}
+	if err := json.NewEncoder(w).Encode(deployments); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func deployment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	d, err := requestDeployment(r, id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrDeploymentDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(d); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func promoteDeployment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if _, err := requestDeployment(r, id); err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrDeploymentDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if err := controllerManager.PromoteDeployment(id); err != nil {
+		status := http.StatusInternalServerError
+		switch err {
+		case manager.ErrDeploymentDoesNotExist:
+			status = http.StatusNotFound
+		case manager.ErrDeploymentNotBaking:
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("promoted deployment %s", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func jobs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	all, err := controllerManager.Jobs()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	jobs := []*shipyard.Job{}
+	for _, job := range all {
+		if ns := requestNamespace(r); ns == "" || shipyard.ImageNamespace(job.Image) == ns {
+			jobs = append(jobs, job)
+		}
+	}
+	if err := json.NewEncoder(w).Encode(jobs); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func job(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	job, err := requestJob(r, id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrJobDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func createJob(w http.ResponseWriter, r *http.Request) {
+	var job *shipyard.Job
+	if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if job.Image != nil && (!checkImageEnvironment(w, job.Image) || !checkSecretRefs(w, r, job.Image)) {
+		return
+	}
+	if job.Image != nil {
+		shipyard.SetOwner(job.Image, requestAccount(r))
+		shipyard.SetNamespace(job.Image, requestNamespace(r))
+		if !checkQuota(w, r, job.Image, job.Count) {
+			return
+		}
+	}
+	if err := controllerManager.CreateJob(job); err != nil {
+		logger.Errorf("error creating job: %s", err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, shipyard.ErrInvalidJob) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("created job id=%s schedule=%q image=%s", job.ID, job.Schedule, job.Image.Name)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		logger.Error(err)
+	}
+}
+
+func removeJob(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if !checkJob(w, r, id) {
+		return
+	}
+	if err := controllerManager.RemoveJob(id); err != nil {
+		logger.Errorf("error removing job: %s", err)
+		status := http.StatusInternalServerError
+		if err == manager.ErrJobDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("removed job %s", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func jobRuns(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if !checkJob(w, r, id) {
+		return
+	}
+	runs, err := controllerManager.JobRuns(id)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrJobDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(runs); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// prepareContainers reports health and hides injected secret values on
+// containers returned by the api
+func prepareContainers(containers ...*citadel.Container) {
+	controllerManager.SetContainerHealth(containers...)
+	for _, c := range containers {
+		if c != nil && c.Image != nil {
+			shipyard.RedactSecrets(c.Image)
+		}
+	}
+}
+
+// hasPermission reports whether the user or service key of a request is
+// granted p beyond the permission checked by the access middleware.  As in
+// the access middleware, permissions on namespaced resources in a namespace
+// are granted by the role of the account in the namespace.
+func hasPermission(r *http.Request, p string) bool {
+	if key := r.Header.Get("X-Service-Key"); key != "" {
+		k, err := controllerManager.VerifyServiceKey(key)
+		return err == nil && k.HasPermission(p)
+	}
+	acct, err := controllerManager.Account(sessionUsername(r))
+	if err != nil {
+		return false
+	}
+	role := acct.Role
+	resource := strings.SplitN(p, ":", 2)[0]
+	if ns := requestNamespace(r); ns != "" && shipyard.NamespaceResources[resource] {
+		if name := acct.NamespaceRole(ns); name != "" {
+			role = &shipyard.Role{Name: name}
+		} else if role == nil || !role.HasPermission(shipyard.PermissionAll) {
+			return false
+		}
+	}
+	if role == nil {
+		return false
+	}
+	if current, err := controllerManager.Role(role.Name); err == nil {
+		role = current
+	}
+	return role.HasPermission(p)
+}
+
+// checkSecretAccess responds with 403 unless the request may inject
+// secrets into containers
+func checkSecretAccess(w http.ResponseWriter, r *http.Request) bool {
+	p := shipyard.Permission("secrets", shipyard.ActionRun)
+	if !hasPermission(r, p) {
+		http.Error(w, "injecting secrets requires "+p, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// checkQuota responds with 403 if count more containers of image would
+// exceed a quota of the account making the request
+func checkQuota(w http.ResponseWriter, r *http.Request, image *citadel.Image, count int) bool {
+	return checkQuotaLaunches(w, r, []*manager.QuotaLaunch{{Image: image, Count: count}})
+}
+
+// checkQuotaLaunches is checkQuota for launching the containers of several
+// images together
+func checkQuotaLaunches(w http.ResponseWriter, r *http.Request, launches []*manager.QuotaLaunch) bool {
+	if err := controllerManager.CheckQuotaLaunches(requestAccount(r), requestNamespace(r), launches); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, shipyard.ErrQuotaExceeded) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, err.Error(), status)
+		return false
+	}
+	return true
+}
+
+// checkImageEnvironment responds with 400 if an image sets reserved
+// environment variables other than launch settings
+func checkImageEnvironment(w http.ResponseWriter, image *citadel.Image) bool {
+	if image == nil {
+		return true
+	}
+	if err := shipyard.ValidateEnvironment(image.Environment, shipyard.ImageSettingsEnv); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// checkSecretRefs validates the secret references of an image and checks
+// the request may inject them
+func checkSecretRefs(w http.ResponseWriter, r *http.Request, image *citadel.Image) bool {
+	refs, err := shipyard.SecretRefs(image)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if len(refs) == 0 {
+		return true
+	}
+	return checkSecretAccess(w, r)
+}
+
+func secrets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	secrets, err := controllerManager.Secrets()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(secrets); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// secretValue returns a secret with its value; the access middleware
+// requires secrets:admin
+func secretValue(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	secret, err := controllerManager.Secret(name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrSecretDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	evt := &shipyard.Event{
+		Type:    "read-secret",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s user=%s", name, sessionUsername(r)),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := controllerManager.SaveEvent(evt); err != nil {
+		logger.Error(err)
+	}
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(secret); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func createSecret(w http.ResponseWriter, r *http.Request) {
+	var secret *shipyard.Secret
+	if err := json.NewDecoder(r.Body).Decode(&secret); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := controllerManager.CreateSecret(secret); err != nil {
+		logger.Errorf("error creating secret: %s", err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidSecret):
+			status = http.StatusBadRequest
+		case err == manager.ErrSecretExists:
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("created secret %s", secret.Name)
+	secret.Value = ""
 	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(secret); err != nil {
+		logger.Error(err)
+	}
+}
 
-	if err := controllerManager.PurgeEvents(); err != nil {
+func deleteSecret(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	if err := controllerManager.DeleteSecret(name); err != nil {
+		logger.Errorf("error deleting secret: %s", err)
+		status := http.StatusInternalServerError
+		if err == manager.ErrSecretDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("deleted secret %s", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func configs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	bundles, err := controllerManager.Configs()
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	logger.Info("cluster events purged")
+	if err := json.NewEncoder(w).Encode(bundles); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func config(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	bundle, err := controllerManager.Config(name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrConfigDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(bundle); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func createConfig(w http.ResponseWriter, r *http.Request) {
+	var bundle *shipyard.ConfigBundle
+	if err := json.NewDecoder(r.Body).Decode(&bundle); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := controllerManager.CreateConfig(bundle); err != nil {
+		logger.Errorf("error creating config: %s", err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidConfig):
+			status = http.StatusBadRequest
+		case err == manager.ErrConfigExists:
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("created config %s", bundle.Name)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(bundle); err != nil {
+		logger.Error(err)
+	}
+}
+
+func updateConfig(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	var bundle *shipyard.ConfigBundle
+	if err := json.NewDecoder(r.Body).Decode(&bundle); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	bundle.Name = vars["name"]
+	if err := controllerManager.UpdateConfig(bundle); err != nil {
+		logger.Errorf("error updating config: %s", err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidConfig):
+			status = http.StatusBadRequest
+		case err == manager.ErrConfigDoesNotExist:
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("updated config %s", bundle.Name)
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func accounts(w http.ResponseWriter, r *http.Request) {
+func deleteConfig(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	if err := controllerManager.DeleteConfig(name); err != nil {
+		logger.Errorf("error deleting config: %s", err)
+		status := http.StatusInternalServerError
+		if err == manager.ErrConfigDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("deleted config %s", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func templates(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("content-type", "application/json")
 
-	accounts, err := controllerManager.Accounts()
+	templates, err := controllerManager.Templates()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if err := json.NewEncoder(w).Encode(accounts); err != nil {
+	if err := json.NewEncoder(w).Encode(templates); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func template(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	t, err := controllerManager.Template(name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrTemplateDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func createTemplate(w http.ResponseWriter, r *http.Request) {
+	var t *shipyard.LaunchTemplate
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := controllerManager.CreateTemplate(t); err != nil {
+		logger.Errorf("error creating template: %s", err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidTemplate):
+			status = http.StatusBadRequest
+		case err == manager.ErrTemplateExists:
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("created template %s", t.Name)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		logger.Error(err)
+	}
+}
+
+func updateTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	var t *shipyard.LaunchTemplate
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	t.Name = vars["name"]
+	if err := controllerManager.UpdateTemplate(t); err != nil {
+		logger.Errorf("error updating template: %s", err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidTemplate):
+			status = http.StatusBadRequest
+		case err == manager.ErrTemplateDoesNotExist:
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("updated template %s", t.Name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func deleteTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	if err := controllerManager.DeleteTemplate(name); err != nil {
+		logger.Errorf("error deleting template: %s", err)
+		status := http.StatusInternalServerError
+		if err == manager.ErrTemplateDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("deleted template %s", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// runTemplate launches containers of a template with the overrides in the
+// body, like run does for a full image
+func runTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	count, pull, dryRun, err := runParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var overrides *shipyard.TemplateOverrides
+	if err := json.NewDecoder(r.Body).Decode(&overrides); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	image, err := controllerManager.TemplateImage(vars["name"], overrides)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidTemplate):
+			status = http.StatusBadRequest
+		case err == manager.ErrTemplateDoesNotExist:
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	runImage(w, r, image, count, pull, dryRun)
+}
+
+func volumes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	volumes, err := controllerManager.Volumes()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(volumes); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func createVolume(w http.ResponseWriter, r *http.Request) {
+	var volume *shipyard.Volume
+	if err := json.NewDecoder(r.Body).Decode(&volume); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := controllerManager.CreateVolume(volume); err != nil {
+		logger.Errorf("error creating volume: %s", err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidVolume):
+			status = http.StatusBadRequest
+		case err == manager.ErrVolumeExists:
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("created volume %s on engine %s", volume.Name, volume.Engine)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(volume); err != nil {
+		logger.Error(err)
+	}
+}
+
+func removeVolume(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	if err := controllerManager.RemoveVolume(name); err != nil {
+		logger.Errorf("error removing volume: %s", err)
+		status := http.StatusInternalServerError
+		switch {
+		case err == manager.ErrVolumeDoesNotExist:
+			status = http.StatusNotFound
+		case errors.Is(err, manager.ErrVolumeInUse):
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("removed volume %s", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func quotas(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	quotas, err := controllerManager.Quotas()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(quotas); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func createQuota(w http.ResponseWriter, r *http.Request) {
+	var quota *shipyard.Quota
+	if err := json.NewDecoder(r.Body).Decode(&quota); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := controllerManager.CreateQuota(quota); err != nil {
+		logger.Errorf("error creating quota: %s", err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidQuota):
+			status = http.StatusBadRequest
+		case err == manager.ErrQuotaExists:
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("created quota %s", quota.Name)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(quota); err != nil {
+		logger.Error(err)
+	}
+}
+
+func deleteQuota(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	if err := controllerManager.DeleteQuota(name); err != nil {
+		logger.Errorf("error deleting quota: %s", err)
+		status := http.StatusInternalServerError
+		if err == manager.ErrQuotaDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("deleted quota %s", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func enginePools(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	pools, err := controllerManager.EnginePools()
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if err := json.NewEncoder(w).Encode(pools); err != nil {
+		logger.Error(err)
+	}
+}
+
+func enginePool(w http.ResponseWriter, r *http.Request) {
+	pool, err := controllerManager.EnginePool(mux.Vars(r)["name"])
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrEnginePoolDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(pool); err != nil {
+		logger.Error(err)
+	}
 }
 
-func addAccount(w http.ResponseWriter, r *http.Request) {
-	var account *shipyard.Account
-	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+func createEnginePool(w http.ResponseWriter, r *http.Request) {
+	var pool *shipyard.EnginePool
+	if err := json.NewDecoder(r.Body).Decode(&pool); err != nil || pool == nil {
+		http.Error(w, "invalid engine pool", http.StatusBadRequest)
 		return
 	}
-
-	if err := controllerManager.SaveAccount(account); err != nil {
-		logger.Errorf("error saving account: %s", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := controllerManager.CreateEnginePool(pool); err != nil {
+		logger.Errorf("error creating engine pool: %s", err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidEnginePool):
+			status = http.StatusBadRequest
+		case err == manager.ErrEnginePoolExists:
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
 		return
 	}
-
-	logger.Infof("saved account %s", account.Username)
-	w.WriteHeader(http.StatusNoContent)
+	logger.Infof("created engine pool %s", pool.Name)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(pool); err != nil {
+		logger.Error(err)
+	}
 }
 
-func deleteAccount(w http.ResponseWriter, r *http.Request) {
-	var acct *shipyard.Account
-	if err := json.NewDecoder(r.Body).Decode(&acct); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	account, err := controllerManager.Account(acct.Username)
-	if err != nil {
-		logger.Errorf("error deleting account: %s", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+func updateEnginePool(w http.ResponseWriter, r *http.Request) {
+	var pool *shipyard.EnginePool
+	if err := json.NewDecoder(r.Body).Decode(&pool); err != nil || pool == nil {
+		http.Error(w, "invalid engine pool", http.StatusBadRequest)
 		return
 	}
-	if err := controllerManager.DeleteAccount(account); err != nil {
-		logger.Errorf("error deleting account: %s", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	pool.Name = mux.Vars(r)["name"]
+	if err := controllerManager.UpdateEnginePool(pool); err != nil {
+		logger.Errorf("error updating engine pool: %s", err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidEnginePool):
+			status = http.StatusBadRequest
+		case err == manager.ErrEnginePoolDoesNotExist:
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
 		return
 	}
-
-	logger.Infof("deleted account %s (%s)", account.Username, account.ID)
+	logger.Infof("updated engine pool %s", pool.Name)
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func roles(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("content-type", "application/json")
-
-	roles, err := controllerManager.Roles()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	if err := json.NewEncoder(w).Encode(roles); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+func deleteEnginePool(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if err := controllerManager.DeleteEnginePool(name); err != nil {
+		logger.Errorf("error deleting engine pool: %s", err)
+		status := http.StatusInternalServerError
+		switch err {
+		case manager.ErrEnginePoolDoesNotExist:
+			status = http.StatusNotFound
+		case manager.ErrEnginePoolInUse:
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
 		return
 	}
+	logger.Infof("deleted engine pool %s", name)
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func role(w http.ResponseWriter, r *http.Request) {
+func gcPolicy(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("content-type", "application/json")
 
-	vars := mux.Vars(r)
-	name := vars["name"]
-	role, err := controllerManager.Role(name)
+	policy, err := controllerManager.GCPolicy()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if err := json.NewEncoder(w).Encode(role); err != nil {
+	if err := json.NewEncoder(w).Encode(policy); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
 
-func addRole(w http.ResponseWriter, r *http.Request) {
-	var role *shipyard.Role
-	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+func setGCPolicy(w http.ResponseWriter, r *http.Request) {
+	var policy *shipyard.GCPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil || policy == nil {
+		http.Error(w, "invalid gc policy", http.StatusBadRequest)
 		return
 	}
-
-	if err := controllerManager.SaveRole(role); err != nil {
-		logger.Errorf("error saving role: %s", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := controllerManager.SetGCPolicy(policy); err != nil {
+		logger.Errorf("error setting gc policy: %s", err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, shipyard.ErrInvalidGCPolicy) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
 		return
 	}
-
-	logger.Infof("saved role %s", role.Name)
+	logger.Infof("updated gc policy: enabled=%v", policy.Enabled)
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func deleteRole(w http.ResponseWriter, r *http.Request) {
-	var role *shipyard.Role
-	if err := json.NewDecoder(r.Body).Decode(&role); err != nil {
+func runGC(w http.ResponseWriter, r *http.Request) {
+	result, err := controllerManager.RunGC()
+	if err != nil {
+		logger.Errorf("error collecting garbage: %s", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if err := controllerManager.DeleteRole(role); err != nil {
-		logger.Errorf("error deleting role: %s", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	logger.Infof("collected %d containers and %d images", len(result.Containers), len(result.Images))
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logger.Error(err)
 	}
 }
 
-func extensions(w http.ResponseWriter, r *http.Request) {
+func backup(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("content-type", "application/json")
-
-	exts, err := controllerManager.Extensions()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	w.Header().Set("content-disposition", "attachment; filename=shipyard-backup.json")
+	// the status is sent with the backup header; a failure after it can
+	// only end the stream
+	if err := controllerManager.Backup(w); err != nil {
+		logger.Errorf("error writing backup: %s", err)
 		return
 	}
-	if err := json.NewEncoder(w).Encode(exts); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	logger.Info("wrote backup")
+}
+
+func restoreBackup(w http.ResponseWriter, r *http.Request) {
+	if err := controllerManager.Restore(r.Body); err != nil {
+		logger.Errorf("error restoring backup: %s", err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, shipyard.ErrInvalidBackup) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
 		return
 	}
+	logger.Info("restored backup")
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func extension(w http.ResponseWriter, r *http.Request) {
+func namespaces(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("content-type", "application/json")
 
-	vars := mux.Vars(r)
-	id := vars["id"]
-	ext, err := controllerManager.Extension(id)
+	namespaces, err := controllerManager.Namespaces()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if err := json.NewEncoder(w).Encode(ext); err != nil {
+	if err := json.NewEncoder(w).Encode(namespaces); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
 
-func addExtension(w http.ResponseWriter, r *http.Request) {
-	var ext *shipyard.Extension
-	if err := json.NewDecoder(r.Body).Decode(&ext); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+func createNamespace(w http.ResponseWriter, r *http.Request) {
+	var ns *shipyard.Namespace
+	if err := json.NewDecoder(r.Body).Decode(&ns); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	if err := controllerManager.SaveExtension(ext); err != nil {
-		logger.Errorf("error saving extension: %s", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := controllerManager.CreateNamespace(ns); err != nil {
+		logger.Errorf("error creating namespace: %s", err)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, shipyard.ErrInvalidNamespace):
+			status = http.StatusBadRequest
+		case err == manager.ErrNamespaceExists:
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
 		return
 	}
-
-	logger.Infof("saved extension name=%s version=%s author=%s", ext.Name, ext.Version, ext.Author)
-	w.WriteHeader(http.StatusNoContent)
+	logger.Infof("created namespace %s", ns.Name)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(ns); err != nil {
+		logger.Error(err)
+	}
 }
 
-func deleteExtension(w http.ResponseWriter, r *http.Request) {
+func deleteNamespace(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	id := vars["id"]
-	if err := controllerManager.DeleteExtension(id); err != nil {
-		logger.Errorf("error deleting extension: %s", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	name := vars["name"]
+	if err := controllerManager.DeleteNamespace(name); err != nil {
+		logger.Errorf("error deleting namespace: %s", err)
+		status := http.StatusInternalServerError
+		switch err {
+		case manager.ErrNamespaceDoesNotExist:
+			status = http.StatusNotFound
+		case manager.ErrNamespaceInUse:
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
 		return
 	}
-	logger.Infof("removed extension %s", id)
+	logger.Infof("deleted namespace %s", name)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -643,6 +3886,11 @@ func login(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid username/password", http.StatusForbidden)
 		return
 	}
+	if err := controllerManager.VerifyOTP(creds.Username, creds.OTP); err != nil {
+		logger.Errorf("invalid one time code for %s from %s", creds.Username, r.RemoteAddr)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	// return token
 	token, err := controllerManager.NewAuthToken(creds.Username, r.UserAgent())
 	if err != nil {
@@ -673,6 +3921,239 @@ func changePassword(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// sessionUsername returns the user set by the auth middleware; it is empty
+// for service keys
+func sessionUsername(r *http.Request) string {
+	session, _ := controllerManager.Store().Get(r, controllerManager.StoreKey)
+	username, _ := session.Values["username"].(string)
+	return username
+}
+
+// requestAccount returns the account a request acts for: the account
+// logged in, or the account that created the service key of the request
+func requestAccount(r *http.Request) string {
+	if key := r.Header.Get("X-Service-Key"); key != "" {
+		k, err := controllerManager.VerifyServiceKey(key)
+		if err != nil {
+			return ""
+		}
+		return k.Account
+	}
+	return sessionUsername(r)
+}
+
+// requestNamespace returns the namespace the request is scoped to; the
+// access middleware has checked it exists
+func requestNamespace(r *http.Request) string {
+	return r.Header.Get(shipyard.NamespaceHeader)
+}
+
+// requestContainer returns the container with id, or nil if it does not
+// exist or is outside the namespace of the request
+func requestContainer(r *http.Request, id string) (*citadel.Container, error) {
+	container, err := controllerManager.Container(id)
+	if err != nil || container == nil {
+		return container, err
+	}
+	if ns := requestNamespace(r); ns != "" && shipyard.ContainerNamespace(container) != ns {
+		return nil, nil
+	}
+	return container, nil
+}
+
+// requestApplication returns the application with name, or
+// ErrApplicationDoesNotExist if it is outside the namespace of the request
+func requestApplication(r *http.Request, name string) (*shipyard.Application, error) {
+	app, err := controllerManager.Application(name)
+	if err != nil {
+		return nil, err
+	}
+	if ns := requestNamespace(r); ns != "" && app.Namespace != ns {
+		return nil, manager.ErrApplicationDoesNotExist
+	}
+	return app, nil
+}
+
+// checkApplication responds with 404 unless the application exists in the
+// namespace of the request
+func checkApplication(w http.ResponseWriter, r *http.Request, name string) bool {
+	if _, err := requestApplication(r, name); err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrApplicationDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return false
+	}
+	return true
+}
+
+// requestDeployment returns the deployment with id, or
+// ErrDeploymentDoesNotExist if its application is outside the namespace of
+// the request
+func requestDeployment(r *http.Request, id string) (*shipyard.Deployment, error) {
+	d, err := controllerManager.Deployment(id)
+	if err != nil {
+		return nil, err
+	}
+	if requestNamespace(r) != "" {
+		if _, err := requestApplication(r, d.Application); err != nil {
+			return nil, manager.ErrDeploymentDoesNotExist
+		}
+	}
+	return d, nil
+}
+
+// requestJob returns the job with id, or ErrJobDoesNotExist if it is
+// outside the namespace of the request
+func requestJob(r *http.Request, id string) (*shipyard.Job, error) {
+	job, err := controllerManager.Job(id)
+	if err != nil {
+		return nil, err
+	}
+	if ns := requestNamespace(r); ns != "" && shipyard.ImageNamespace(job.Image) != ns {
+		return nil, manager.ErrJobDoesNotExist
+	}
+	return job, nil
+}
+
+// checkJob responds with 404 unless the job exists in the namespace of the
+// request
+func checkJob(w http.ResponseWriter, r *http.Request, id string) bool {
+	if _, err := requestJob(r, id); err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrJobDoesNotExist {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return false
+	}
+	return true
+}
+
+// totpErrorStatus maps two-factor errors to response codes
+func totpErrorStatus(err error) int {
+	switch err {
+	case manager.ErrInvalidOTP, shipyard.ErrOTPRequired:
+		return http.StatusBadRequest
+	case manager.ErrTOTPAlreadyEnabled, manager.ErrTOTPNotEnabled, manager.ErrTOTPNotEnrolled:
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func enable2FA(w http.ResponseWriter, r *http.Request) {
+	username := sessionUsername(r)
+	if username == "" {
+		http.Error(w, "two-factor auth requires a user login", http.StatusBadRequest)
+		return
+	}
+	enrollment, err := controllerManager.Enable2FA(username)
+	if err != nil {
+		http.Error(w, err.Error(), totpErrorStatus(err))
+		return
+	}
+	logger.Infof("started two-factor enrollment for %s", username)
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(enrollment); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// totpCode is the request body of the two-factor confirm and disable calls
+type totpCode struct {
+	Code string `json:"code,omitempty"`
+}
+
+func confirm2FA(w http.ResponseWriter, r *http.Request) {
+	username := sessionUsername(r)
+	if username == "" {
+		http.Error(w, "two-factor auth requires a user login", http.StatusBadRequest)
+		return
+	}
+	var c *totpCode
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := controllerManager.Confirm2FA(username, c.Code); err != nil {
+		http.Error(w, err.Error(), totpErrorStatus(err))
+		return
+	}
+	logger.Infof("enabled two-factor auth for %s", username)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func disable2FA(w http.ResponseWriter, r *http.Request) {
+	username := sessionUsername(r)
+	if username == "" {
+		http.Error(w, "two-factor auth requires a user login", http.StatusBadRequest)
+		return
+	}
+	var c *totpCode
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := controllerManager.Disable2FA(username, c.Code); err != nil {
+		http.Error(w, err.Error(), totpErrorStatus(err))
+		return
+	}
+	logger.Infof("disabled two-factor auth for %s", username)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authToken returns the user and token from the request access token
+func authToken(r *http.Request) (string, string, error) {
+	parts := strings.Split(r.Header.Get("X-Access-Token"), ":")
+	if len(parts) != 2 {
+		return "", "", errors.New("an access token is required")
+	}
+	return parts[0], parts[1], nil
+}
+
+func refreshToken(w http.ResponseWriter, r *http.Request) {
+	username, token, err := authToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	t, err := controllerManager.RefreshAuthToken(username, token)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrInvalidAuthToken {
+			status = http.StatusUnauthorized
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("refreshed auth token for %s", username)
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func logout(w http.ResponseWriter, r *http.Request) {
+	username, token, err := authToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := controllerManager.RevokeAuthToken(username, token); err != nil {
+		status := http.StatusInternalServerError
+		if err == manager.ErrInvalidAuthToken {
+			status = http.StatusUnauthorized
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	logger.Infof("logged out %s", username)
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func hubWebhook(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -699,6 +4180,19 @@ func hubWebhook(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	tag := ""
+	if webhook.PushData != nil {
+		tag = webhook.PushData.Tag
+	}
+	if err := controllerManager.ImagePushed(webhook.Repository.RepoName, tag); err != nil {
+		logger.Errorf("error deploying applications: %s", err)
+		status := http.StatusInternalServerError
+		if err == manager.ErrImageTagRequired {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
 }
 
 func main() {
@@ -720,6 +4214,13 @@ func main() {
 		fmt.Println(VERSION)
 		os.Exit(0)
 	}
+	apiSpec = newAPISpec()
+	if showSpec {
+		if err := json.NewEncoder(os.Stdout).Encode(apiSpec); err != nil {
+			logger.Fatal(err)
+		}
+		os.Exit(0)
+	}
 	var (
 		mErr      error
 		globalMux = http.NewServeMux()
@@ -727,85 +4228,150 @@ func main() {
 
 	logger.Infof("shipyard version %s", VERSION)
 
-	controllerManager, mErr = manager.NewManager(rethinkdbAddr, rethinkdbDatabase, rethinkdbAuthKey, VERSION, disableUsageInfo)
+	var db datastore.Store
+	switch datastoreType {
+	case "rethinkdb":
+		db, mErr = datastore.NewRethinkStore(rethinkdbAddr, rethinkdbDatabase, rethinkdbAuthKey)
+	case "embedded":
+		db, mErr = datastore.NewEmbeddedStore(datastorePath)
+	default:
+		mErr = fmt.Errorf("unknown datastore %q", datastoreType)
+	}
+	if mErr != nil {
+		logger.Fatal(mErr)
+	}
+	logger.Infof("using %s datastore", datastoreType)
+
+	controllerManager, mErr = manager.NewManager(db, VERSION, disableUsageInfo)
 	if mErr != nil {
 		logger.Fatal(mErr)
 	}
+	if enableHA {
+		if instanceID == "" {
+			logger.Fatal("-instance-id is required in ha mode")
+		}
+		if datastoreType != "rethinkdb" {
+			logger.Fatal("ha mode needs the rethinkdb datastore")
+		}
+		controllerManager.EnableHA(instanceID, leaderLease)
+		logger.Infof("running in ha mode as %s", instanceID)
+	}
+
+	if ldapConfig.Addr != "" {
+		if p := os.Getenv("LDAP_BIND_PASSWORD"); p != "" && ldapConfig.BindPassword == "" {
+			ldapConfig.BindPassword = p
+		}
+		ldapConfig.GroupRoles = ldapGroupRoles
+		controllerManager.SetAuthenticator(ldap.NewAuthenticator(ldapConfig))
+		logger.Infof("authenticating users with %s", ldapConfig.Addr)
+	}
+
+	if secretKey == "" {
+		secretKey = os.Getenv("SHIPYARD_SECRET_KEY")
+	}
+	if secretKey != "" {
+		controllerManager.SetSecretKey(secretKey)
+	}
+	if err := controllerManager.SetPlacement(placement); err != nil {
+		logger.Fatal(err)
+	}
+	if metricsInterval > 0 {
+		controllerManager.CollectMetrics(metricsInterval, containerMetricsInterval, metricsWindow)
+	}
 
 	apiRouter := mux.NewRouter()
-	apiRouter.HandleFunc("/api/accounts", accounts).Methods("GET")
-	apiRouter.HandleFunc("/api/accounts", addAccount).Methods("POST")
-	apiRouter.HandleFunc("/api/accounts", deleteAccount).Methods("DELETE")
-	apiRouter.HandleFunc("/api/roles", roles).Methods("GET")
-	apiRouter.HandleFunc("/api/roles/{name}", role).Methods("GET")
-	apiRouter.HandleFunc("/api/roles", addRole).Methods("POST")
-	apiRouter.HandleFunc("/api/roles", deleteRole).Methods("DELETE")
-	apiRouter.HandleFunc("/api/cluster/info", clusterInfo).Methods("GET")
-	apiRouter.HandleFunc("/api/containers", containers).Methods("GET")
-	apiRouter.HandleFunc("/api/containers", run).Methods("POST")
-	apiRouter.HandleFunc("/api/containers/{id}", inspectContainer).Methods("GET")
-	apiRouter.HandleFunc("/api/containers/{id}", destroy).Methods("DELETE")
-	apiRouter.HandleFunc("/api/containers/{id}/stop", stopContainer).Methods("GET")
-	apiRouter.HandleFunc("/api/containers/{id}/restart", restartContainer).Methods("GET")
-	apiRouter.HandleFunc("/api/containers/{id}/scale", scaleContainer).Methods("GET")
-	apiRouter.HandleFunc("/api/containers/{id}/logs", containerLogs).Methods("GET")
-	apiRouter.HandleFunc("/api/events", events).Methods("GET")
-	apiRouter.HandleFunc("/api/events", purgeEvents).Methods("DELETE")
-	apiRouter.HandleFunc("/api/engines", engines).Methods("GET")
-	apiRouter.HandleFunc("/api/engines", addEngine).Methods("POST")
-	apiRouter.HandleFunc("/api/engines/{id}", inspectEngine).Methods("GET")
-	apiRouter.HandleFunc("/api/engines/{id}", removeEngine).Methods("DELETE")
-	apiRouter.HandleFunc("/api/extensions", extensions).Methods("GET")
-	apiRouter.HandleFunc("/api/extensions/{id}", extension).Methods("GET")
-	apiRouter.HandleFunc("/api/extensions", addExtension).Methods("POST")
-	apiRouter.HandleFunc("/api/extensions/{id}", deleteExtension).Methods("DELETE")
-	apiRouter.HandleFunc("/api/servicekeys", serviceKeys).Methods("GET")
-	apiRouter.HandleFunc("/api/servicekeys", addServiceKey).Methods("POST")
-	apiRouter.HandleFunc("/api/servicekeys", removeServiceKey).Methods("DELETE")
-	apiRouter.HandleFunc("/api/webhookkeys", webhookKeys).Methods("GET")
-	apiRouter.HandleFunc("/api/webhookkeys/{id}", webhookKey).Methods("GET")
-	apiRouter.HandleFunc("/api/webhookkeys", addWebhookKey).Methods("POST")
-	apiRouter.HandleFunc("/api/webhookkeys/{id}", deleteWebhookKey).Methods("DELETE")
+	handleRoutes(apiRouter, apiRoutes)
 
 	// global handler
 	globalMux.Handle("/", http.FileServer(http.Dir("static")))
 
+	// metrics; they need cluster:read unless public
+	controllerMetrics := metrics.NewMetrics(controllerManager, apiRouter)
+	if !disableMetrics {
+		if publicMetrics {
+			globalMux.Handle("/metrics", controllerMetrics)
+		} else {
+			metricsAuthRouter := negroni.New()
+			metricsAuthRouter.Use(negroni.HandlerFunc(auth.NewAuthRequired(controllerManager).HandlerFuncWithNext))
+			metricsAuthRouter.Use(negroni.HandlerFunc(access.NewAccessRequired(controllerManager).HandlerFuncWithNext))
+			metricsAuthRouter.UseHandler(controllerMetrics)
+			globalMux.Handle("/metrics", metricsAuthRouter)
+		}
+	}
+
+	// request traces of the api, account and public routers
+	if tracer = shipyard.NewTracerFromEnv("shipyard-controller"); tracer != nil {
+		tracer.ErrorHandler = func(err error) {
+			logger.Warnf("error exporting traces: %s", err)
+		}
+		logger.Info("exporting request traces")
+	}
+
 	// api router; protected by auth
 	apiAuthRouter := negroni.New()
+	if tracer != nil {
+		apiAuthRouter.Use(negroni.HandlerFunc(tracing.NewTracing(tracer).HandlerFuncWithNext))
+	}
 	apiAuthRequired := auth.NewAuthRequired(controllerManager)
 	apiAccessRequired := access.NewAccessRequired(controllerManager)
+	apiAuditLog := audit.NewAuditLog(controllerManager)
 	apiAuthRouter.Use(negroni.HandlerFunc(apiAuthRequired.HandlerFuncWithNext))
+	// only authenticated requests are counted
+	apiAuthRouter.Use(negroni.HandlerFunc(controllerMetrics.HandlerFuncWithNext))
+	if rateLimits.Enabled() {
+		apiAuthRouter.Use(negroni.HandlerFunc(ratelimit.NewRateLimit(rateLimits).HandlerFuncWithNext))
+	}
+	apiAuthRouter.Use(negroni.HandlerFunc(apiAuditLog.HandlerFuncWithNext))
 	apiAuthRouter.Use(negroni.HandlerFunc(apiAccessRequired.HandlerFuncWithNext))
+	if !disableGzip {
+		apiAuthRouter.Use(negroni.HandlerFunc(compression.NewCompression().HandlerFuncWithNext))
+	}
+	if !disableETags {
+		apiAuthRouter.Use(negroni.HandlerFunc(etag.NewETag().HandlerFuncWithNext))
+	}
+	apiAuthRouter.Use(negroni.HandlerFunc(idempotency.NewIdempotency(controllerManager, idempotencyWindow).HandlerFuncWithNext))
 	apiAuthRouter.UseHandler(apiRouter)
 	globalMux.Handle("/api/", apiAuthRouter)
 
 	// account router ; protected by auth
 	accountRouter := mux.NewRouter()
-	accountRouter.HandleFunc("/account/changepassword", changePassword).Methods("POST")
+	handleRoutes(accountRouter, accountRoutes)
 	accountAuthRouter := negroni.New()
+	if tracer != nil {
+		accountAuthRouter.Use(negroni.HandlerFunc(tracing.NewTracing(tracer).HandlerFuncWithNext))
+	}
 	accountAuthRequired := auth.NewAuthRequired(controllerManager)
+	accountAuditLog := audit.NewAuditLog(controllerManager)
 	accountAuthRouter.Use(negroni.HandlerFunc(accountAuthRequired.HandlerFuncWithNext))
+	accountAuthRouter.Use(negroni.HandlerFunc(accountAuditLog.HandlerFuncWithNext))
 	accountAuthRouter.UseHandler(accountRouter)
 	globalMux.Handle("/account/", accountAuthRouter)
 
-	// login handler; public
-	loginRouter := mux.NewRouter()
-	loginRouter.HandleFunc("/auth/login", login).Methods("POST")
-	globalMux.Handle("/auth/", loginRouter)
-
-	// hub handler; public
-	hubRouter := mux.NewRouter()
-	hubRouter.HandleFunc("/hub/webhook/{id}", hubWebhook).Methods("POST")
-	globalMux.Handle("/hub/", hubRouter)
+	// public router: login, version, spec and docker hub webhooks; engine
+	// joins are authorized by join tokens
+	publicRouter := mux.NewRouter()
+	handleRoutes(publicRouter, publicRoutes)
+	publicHandler := negroni.New()
+	if tracer != nil {
+		publicHandler.Use(negroni.HandlerFunc(tracing.NewTracing(tracer).HandlerFuncWithNext))
+	}
+	publicHandler.UseHandler(publicRouter)
+	globalMux.Handle("/auth/", publicHandler)
+	globalMux.Handle("/hub/", publicHandler)
+	globalMux.Handle("/api/version", publicHandler)
+	globalMux.Handle("/api/spec", publicHandler)
+	globalMux.Handle("/join", publicHandler)
 
 	// check for admin user
 	if _, err := controllerManager.Account("admin"); err == manager.ErrAccountDoesNotExist {
 		// create roles
 		r := &shipyard.Role{
-			Name: "admin",
+			Name:        "admin",
+			Permissions: shipyard.DefaultRolePermissions["admin"],
 		}
 		ru := &shipyard.Role{
-			Name: "user",
+			Name:        "user",
+			Permissions: shipyard.DefaultRolePermissions["user"],
 		}
 		if err := controllerManager.SaveRole(r); err != nil {
 			logger.Fatal(err)
@@ -830,7 +4396,7 @@ func main() {
 
 	logger.Infof("controller listening on %s", listenAddr)
 
-	if err := http.ListenAndServe(listenAddr, context.ClearHandler(globalMux)); err != nil {
+	if err := listenAndServe(listenAddr, context.ClearHandler(globalMux)); err != nil {
 		logger.Fatal(err)
 	}
 }
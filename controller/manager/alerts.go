@@ -0,0 +1,219 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameAlertRules = "alert_rules"
+	tblNameAlerts     = "alerts"
+
+	// alertTick is how often the alert rules are evaluated
+	alertTick = 30 * time.Second
+)
+
+var (
+	ErrAlertRuleDoesNotExist = errors.New("alert rule does not exist")
+)
+
+func (m *Manager) AlertRules() ([]*shipyard.AlertRule, error) {
+	rules := []*shipyard.AlertRule{}
+	if err := m.db.Find(tblNameAlertRules, ds.Where().Sort("name", false), &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (m *Manager) AlertRule(id string) (*shipyard.AlertRule, error) {
+	var rule *shipyard.AlertRule
+	if err := m.db.Get(tblNameAlertRules, id, &rule); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrAlertRuleDoesNotExist
+		}
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (m *Manager) AddAlertRule(rule *shipyard.AlertRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	rule.ID = ""
+	id, err := m.db.Insert(tblNameAlertRules, rule)
+	if err != nil {
+		return err
+	}
+	rule.ID = id
+	evt := &shipyard.Event{
+		Type:    "add-alert-rule",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("id=%s name=%s type=%s threshold=%g", rule.ID, rule.Name, rule.Type, rule.Threshold),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RemoveAlertRule removes a rule and the alerts it fired without resolving
+// them
+func (m *Manager) RemoveAlertRule(id string) error {
+	rule, err := m.AlertRule(id)
+	if err != nil {
+		return err
+	}
+	if _, err := m.db.Delete(tblNameAlertRules, ds.ByID(rule.ID)); err != nil {
+		return err
+	}
+	if _, err := m.db.Delete(tblNameAlerts, ds.Where(ds.Eq("rule_id", rule.ID))); err != nil {
+		return err
+	}
+	evt := &shipyard.Event{
+		Type:    "remove-alert-rule",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("id=%s name=%s type=%s", rule.ID, rule.Name, rule.Type),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Alerts returns the firing alerts, newest first
+func (m *Manager) Alerts() ([]*shipyard.Alert, error) {
+	alerts := []*shipyard.Alert{}
+	if err := m.db.Find(tblNameAlerts, ds.Where().Sort("since", true), &alerts); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// evaluateAlerts evaluates the alert rules every alertTick
+func (m *Manager) evaluateAlerts() {
+	t := time.NewTicker(alertTick).C
+	for now := range t {
+		if !m.IsLeader() {
+			continue
+		}
+		if err := m.EvaluateAlerts(now); err != nil {
+			logger.Errorf("error evaluating alerts: %s", err)
+		}
+	}
+}
+
+// EvaluateAlerts evaluates the alert rules against the current cluster
+// state.  Alerts that start firing save an alert-firing event with the
+// severity of their rule, alerts that stop an alert-resolved event.
+func (m *Manager) EvaluateAlerts(now time.Time) error {
+	rules, err := m.AlertRules()
+	if err != nil {
+		return err
+	}
+	active, err := m.Alerts()
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 && len(active) == 0 {
+		return nil
+	}
+	state, err := m.alertState(rules, now)
+	if err != nil {
+		return err
+	}
+	firing := map[string]*shipyard.Alert{}
+	for _, r := range rules {
+		for _, a := range r.Evaluate(state, now) {
+			firing[a.ID] = a
+		}
+	}
+	for _, a := range active {
+		f, ok := firing[a.ID]
+		if !ok {
+			if _, err := m.db.Delete(tblNameAlerts, ds.ByID(a.ID)); err != nil {
+				return err
+			}
+			if err := m.saveAlertEvent("alert-resolved", shipyard.SeverityInfo, a); err != nil {
+				return err
+			}
+			continue
+		}
+		// keep when the alert started firing
+		f.Since = a.Since
+		if err := m.db.Put(tblNameAlerts, f); err != nil {
+			return err
+		}
+		delete(firing, a.ID)
+	}
+	for _, a := range firing {
+		a.Since = now
+		if err := m.db.Put(tblNameAlerts, a); err != nil {
+			return err
+		}
+		if err := m.saveAlertEvent("alert-firing", a.Severity, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// alertState collects what the rules need: the reservations, the events of
+// the longest rule window and the latest container samples
+func (m *Manager) alertState(rules []*shipyard.AlertRule, now time.Time) (*shipyard.AlertState, error) {
+	state := &shipyard.AlertState{
+		Usage: m.Usage(),
+	}
+	window := time.Duration(0)
+	for _, r := range rules {
+		if (r.Type == shipyard.AlertEvents || r.Type == shipyard.AlertContainerRestarts) && r.WindowDuration() > window {
+			window = r.WindowDuration()
+		}
+	}
+	if window > 0 {
+		events, err := m.Events(&shipyard.EventQuery{Since: now.Add(-window)})
+		if err != nil {
+			return nil, err
+		}
+		state.Events = events
+	}
+	m.metricsLock.Lock()
+	state.Metrics = m.latestSamples
+	m.metricsLock.Unlock()
+	return state, nil
+}
+
+func (m *Manager) saveAlertEvent(typ string, severity string, a *shipyard.Alert) error {
+	evt := &shipyard.Event{
+		Type:     typ,
+		Time:     time.Now(),
+		Message:  a.Message,
+		Severity: severity,
+		Tags:     []string{"alert", a.RuleName},
+	}
+	switch a.Resource {
+	case shipyard.MetricsContainers:
+		evt.Container = m.alertContainer(a.ResourceID)
+	case shipyard.MetricsEngines:
+		if e := m.clusterEngine(a.ResourceID); e != nil {
+			evt.Engine = e.Engine
+		}
+	}
+	return m.SaveEvent(evt)
+}
+
+// alertContainer returns the container an alert is about, or a container
+// with only the id when it was removed
+func (m *Manager) alertContainer(id string) *citadel.Container {
+	if c, err := m.Container(id); err == nil && c != nil {
+		return c
+	}
+	return &citadel.Container{ID: id}
+}
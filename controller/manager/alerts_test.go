@@ -0,0 +1,82 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+func TestEvaluateAlerts(t *testing.T) {
+	m := newTestManager(t)
+	rule := &shipyard.AlertRule{Name: "busy", Type: shipyard.AlertContainerCpu, Threshold: 50}
+	if err := m.AddAlertRule(rule); err != nil {
+		t.Fatal(err)
+	}
+	setCpu := func(cpu float64) {
+		m.latestSamples = []*shipyard.MetricSample{{Resource: shipyard.MetricsContainers, ResourceID: "abc", CpuPercent: cpu}}
+	}
+	events := func(typ string) int {
+		evts, err := m.Events(&shipyard.EventQuery{Types: []string{typ}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(evts)
+	}
+
+	// stored times have second precision
+	start := time.Now().Truncate(time.Second)
+	setCpu(90)
+	if err := m.EvaluateAlerts(start); err != nil {
+		t.Fatal(err)
+	}
+	alerts, err := m.Alerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 || alerts[0].ResourceID != "abc" || !alerts[0].Since.Equal(start) {
+		t.Fatalf("expected alert for abc since %s; received %+v", start, alerts)
+	}
+	if n := events("alert-firing"); n != 1 {
+		t.Errorf("expected 1 alert-firing event; received %d", n)
+	}
+
+	// a firing alert keeps when it started and fires once
+	if err := m.EvaluateAlerts(start.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if alerts, _ := m.Alerts(); len(alerts) != 1 || !alerts[0].Since.Equal(start) {
+		t.Errorf("expected alert to keep firing since %s; received %+v", start, alerts)
+	}
+	if n := events("alert-firing"); n != 1 {
+		t.Errorf("expected 1 alert-firing event; received %d", n)
+	}
+
+	setCpu(10)
+	if err := m.EvaluateAlerts(start.Add(2 * time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if alerts, _ := m.Alerts(); len(alerts) != 0 {
+		t.Errorf("expected alert to be resolved; received %+v", alerts)
+	}
+	if n := events("alert-resolved"); n != 1 {
+		t.Errorf("expected 1 alert-resolved event; received %d", n)
+	}
+}
+
+func TestSaveAlertEventEngine(t *testing.T) {
+	node := &citadel.Engine{ID: "node-1"}
+	m := newTestManager(t, &shipyard.Engine{ID: "5b0c3e9a", Engine: node})
+	a := &shipyard.Alert{Resource: shipyard.MetricsEngines, ResourceID: "node-1", RuleName: "reserved"}
+	if err := m.saveAlertEvent("alert-firing", shipyard.SeverityWarning, a); err != nil {
+		t.Fatal(err)
+	}
+	evts, err := m.Events(&shipyard.EventQuery{Types: []string{"alert-firing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evts) != 1 || evts[0].Engine == nil || evts[0].Engine.ID != "node-1" {
+		t.Errorf("expected event for engine node-1; received %+v", evts)
+	}
+}
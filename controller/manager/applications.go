@@ -0,0 +1,294 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameApplications = "applications"
+
+	reconcileInterval = 10 * time.Second
+)
+
+var (
+	ErrApplicationExists       = errors.New("application already exists")
+	ErrApplicationDoesNotExist = errors.New("application does not exist")
+)
+
+func (m *Manager) Applications() ([]*shipyard.Application, error) {
+	apps := []*shipyard.Application{}
+	if err := m.db.Find(tblNameApplications, ds.Where().Sort("name", false), &apps); err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
+func (m *Manager) Application(name string) (*shipyard.Application, error) {
+	var app *shipyard.Application
+	if err := m.db.FindOne(tblNameApplications, ds.Where(ds.Eq("name", name)), &app); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrApplicationDoesNotExist
+		}
+		return nil, err
+	}
+	return app, nil
+}
+
+// ApplicationContainers returns the containers launched for an application,
+// including stopped ones
+func (m *Manager) ApplicationContainers(name string) []*citadel.Container {
+	containers := []*citadel.Container{}
+	for _, c := range m.Containers(true) {
+		if shipyard.ApplicationName(c) == name {
+			containers = append(containers, c)
+		}
+	}
+	return containers
+}
+
+// CreateApplication stores the application and starts its containers.  The
+// application is kept even if starting fails; the reconciler retries.
+func (m *Manager) CreateApplication(app *shipyard.Application) error {
+	if err := app.Validate(); err != nil {
+		return err
+	}
+	if err := m.checkApplicationPool(app); err != nil {
+		return err
+	}
+	if _, err := m.Application(app.Name); err == nil {
+		return ErrApplicationExists
+	} else if err != ErrApplicationDoesNotExist {
+		return err
+	}
+	app.ID = ""
+	id, err := m.db.Insert(tblNameApplications, app)
+	if err != nil {
+		return err
+	}
+	app.ID = id
+	evt := &shipyard.Event{
+		Type:    "create-application",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s image=%s count=%d", app.Name, app.Image, app.Count),
+		Tags:    []string{"application"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return m.reconcileApplication(app)
+}
+
+// UpdateApplication replaces the stored spec of an application and scales
+// it to the new count.  Running containers keep their previous spec.
+func (m *Manager) UpdateApplication(app *shipyard.Application) error {
+	if err := app.Validate(); err != nil {
+		return err
+	}
+	if err := m.checkApplicationPool(app); err != nil {
+		return err
+	}
+	current, err := m.Application(app.Name)
+	if err != nil {
+		return err
+	}
+	app.ID = current.ID
+	app.Stopped = current.Stopped
+	app.Owner = current.Owner
+	app.Namespace = current.Namespace
+	if err := m.db.Put(tblNameApplications, app); err != nil {
+		return err
+	}
+	evt := &shipyard.Event{
+		Type:    "update-application",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s image=%s count=%d", app.Name, app.Image, app.Count),
+		Tags:    []string{"application"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return m.reconcileApplication(app)
+}
+
+// ApplicationDiff returns what updating an application to app would change
+// in its stored spec
+func (m *Manager) ApplicationDiff(app *shipyard.Application) (*shipyard.ApplicationDiff, error) {
+	current, err := m.Application(app.Name)
+	if err != nil {
+		return nil, err
+	}
+	return shipyard.DiffApplications(current, app), nil
+}
+
+// RemoveApplication deletes the application and destroys its containers
+func (m *Manager) RemoveApplication(name string) error {
+	app, err := m.Application(name)
+	if err != nil {
+		return err
+	}
+	m.appLock.Lock()
+	defer m.appLock.Unlock()
+	if _, err := m.db.Delete(tblNameApplications, ds.ByID(app.ID)); err != nil {
+		return err
+	}
+	if _, err := m.db.Delete(tblNameImageWatches, ds.Where(ds.Eq("application", app.Name))); err != nil {
+		return err
+	}
+	for _, c := range m.ApplicationContainers(app.Name) {
+		if err := m.removeContainer(c); err != nil {
+			return err
+		}
+	}
+	evt := &shipyard.Event{
+		Type:    "remove-application",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s", app.Name),
+		Tags:    []string{"application"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// reconcileApplications periodically brings every application back to its
+// desired count
+func (m *Manager) reconcileApplications() {
+	t := time.NewTicker(reconcileInterval).C
+	for range t {
+		if !m.IsLeader() {
+			continue
+		}
+		apps, err := m.Applications()
+		if err != nil {
+			logger.Warnf("error loading applications: %s", err)
+			continue
+		}
+		for _, app := range apps {
+			if err := m.reconcileApplication(app); err != nil {
+				logger.Warnf("error reconciling application %s: %s", app.Name, err)
+			}
+		}
+	}
+}
+
+// reconcileApplication replaces stopped containers and starts or destroys
+// containers until count are running
+func (m *Manager) reconcileApplication(app *shipyard.Application) error {
+	m.appLock.Lock()
+	defer m.appLock.Unlock()
+	if m.deploying[app.Name] {
+		return nil
+	}
+	// the application may have been stopped as part of a group since it
+	// was loaded
+	current, err := m.Application(app.Name)
+	if err != nil {
+		return err
+	}
+	if current.Stopped {
+		return nil
+	}
+
+	running := []*citadel.Container{}
+	stopped := []*citadel.Container{}
+	for _, c := range m.ApplicationContainers(app.Name) {
+		if c.State == "running" {
+			running = append(running, c)
+		} else {
+			stopped = append(stopped, c)
+		}
+	}
+	if len(running) == app.Count {
+		return nil
+	}
+
+	if len(running) > app.Count {
+		for _, c := range running[app.Count:] {
+			if err := m.Destroy(c); err != nil {
+				return err
+			}
+		}
+	} else {
+		for _, c := range stopped {
+			if err := m.removeContainer(c); err != nil {
+				return err
+			}
+		}
+		if _, err := m.runApplication(app, app.Count-len(running)); err != nil {
+			return err
+		}
+	}
+	logger.Infof("scaled application %s from %d to %d containers", app.Name, len(running), app.Count)
+	evt := &shipyard.Event{
+		Type:    "scale-application",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s running=%d count=%d", app.Name, len(running), app.Count),
+		Tags:    []string{"application"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// runApplication launches count containers of an application.  Containers
+// of applications with links are placed on the engine of the running
+// containers they link to and the applications of a compose project are
+// kept together on one engine.
+func (m *Manager) runApplication(app *shipyard.Application, count int) ([]*citadel.Container, error) {
+	img := app.ContainerImage()
+	var eng *citadel.Engine
+	if len(app.Links) > 0 {
+		img.Links = map[string]string{}
+		for name, alias := range app.Links {
+			var linked *citadel.Container
+			for _, c := range m.ApplicationContainers(name) {
+				if c.State == "running" {
+					linked = c
+					break
+				}
+			}
+			if linked == nil {
+				return nil, fmt.Errorf("linked application %s has no running containers", name)
+			}
+			if eng != nil && eng.ID != linked.Engine.ID {
+				return nil, fmt.Errorf("linked applications run on different engines")
+			}
+			eng = linked.Engine
+			img.Links[strings.TrimPrefix(linked.Name, "/")] = alias
+		}
+	}
+	if eng == nil && app.Project != "" {
+		for _, c := range m.Containers(false) {
+			if c.Image.Environment[shipyard.ProjectEnv] == app.Project {
+				eng = c.Engine
+				break
+			}
+		}
+	}
+	if eng != nil {
+		img.Type = "host"
+		img.Labels = []string{fmt.Sprintf("host:%s", eng.ID)}
+	}
+	return m.Run(img, count, true)
+}
+
+// removeContainer destroys a container, which may already have exited
+func (m *Manager) removeContainer(c *citadel.Container) error {
+	if c.State == "running" {
+		return m.Destroy(c)
+	}
+	if err := m.ClusterManager().Remove(c); err != nil {
+		return err
+	}
+	m.removeContainerMetadata(c)
+	return nil
+}
@@ -0,0 +1,51 @@
+package manager
+
+import (
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameAudit = "audit"
+)
+
+func (m *Manager) SaveAuditEntry(entry *shipyard.AuditEntry) error {
+	if _, err := m.db.Insert(tblNameAudit, entry); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AuditLog returns the audit entries matching filter, newest first
+func (m *Manager) AuditLog(filter *shipyard.AuditFilter) ([]*shipyard.AuditEntry, error) {
+	if filter == nil {
+		filter = &shipyard.AuditFilter{}
+	}
+	q := auditQueryFilter(filter).Sort("time", true).Page(filter.Offset, filter.Limit)
+	entries := []*shipyard.AuditEntry{}
+	if err := m.db.Find(tblNameAudit, q, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// auditQueryFilter builds the conditions of an audit filter
+func auditQueryFilter(filter *shipyard.AuditFilter) *ds.Query {
+	q := ds.Where()
+	if filter.Username != "" {
+		q.Where = append(q.Where, ds.Eq("username", filter.Username))
+	}
+	if filter.Method != "" {
+		q.Where = append(q.Where, ds.Eq("method", filter.Method))
+	}
+	if filter.Endpoint != "" {
+		q.Where = append(q.Where, ds.Prefix("endpoint", filter.Endpoint))
+	}
+	if !filter.Since.IsZero() {
+		q.Where = append(q.Where, ds.Ge("time", filter.Since))
+	}
+	if !filter.Until.IsZero() {
+		q.Where = append(q.Where, ds.Le("time", filter.Until))
+	}
+	return q
+}
@@ -0,0 +1,32 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shipyard/shipyard"
+)
+
+func TestAuditLogEndpointIsLiteral(t *testing.T) {
+	m := newTestManager(t)
+	for _, endpoint := range []string{"/api/containers/abc/stop", "/api/containers.json"} {
+		if err := m.SaveAuditEntry(&shipyard.AuditEntry{Time: time.Now(), Method: "POST", Endpoint: endpoint}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := map[string]int{
+		"/api/containers":  2,
+		"/api/containers.": 1,
+		"/api/c.ntainers":  0,
+		"/api/(containers": 0,
+	}
+	for prefix, expected := range tests {
+		entries, err := m.AuditLog(&shipyard.AuditFilter{Endpoint: prefix})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != expected {
+			t.Errorf("%s: expected %d entries; received %d", prefix, expected, len(entries))
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/shipyard/shipyard"
+)
+
+// backupTables hold the controller state.  Events, the audit log, job runs,
+// metrics, firing alerts, join tokens and leases are history or short lived
+// and not backed up.
+var backupTables = []string{
+	tblNameConfig,
+	tblNameEnginePools,
+	tblNameAccounts,
+	tblNameRoles,
+	tblNameServiceKeys,
+	tblNameWebhookKeys,
+	tblNameExtensions,
+	tblNameRegistries,
+	tblNameWebhooks,
+	tblNameNotifiers,
+	tblNameAlertRules,
+	tblNameApplications,
+	tblNameDeployments,
+	tblNameJobs,
+	tblNameSecrets,
+	tblNameConfigBundles,
+	tblNameTemplates,
+	tblNameVolumes,
+	tblNameQuotas,
+	tblNameNamespaces,
+	tblNameContainerMetadata,
+	tblNameSettings,
+}
+
+// Backup writes a snapshot of the controller state to w; see
+// shipyard.BackupHeader.  Secrets stay encrypted and can only be read after
+// a restore with the same secret key.
+func (m *Manager) Backup(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	header := &shipyard.BackupHeader{
+		Format:  shipyard.BackupFormat,
+		Version: m.version,
+		Created: time.Now(),
+		Tables:  backupTables,
+	}
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+	for _, tbl := range backupTables {
+		err := m.db.Dump(tbl, func(doc map[string]interface{}) error {
+			b, err := json.Marshal(doc)
+			if err != nil {
+				return err
+			}
+			return enc.Encode(&shipyard.BackupRecord{Table: tbl, Document: b})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore replaces the controller state with a backup written by Backup.
+// The whole backup is read and checked, down to the ids of its documents,
+// before anything is changed.  The restore is not atomic: tables are
+// replaced one at a time, so a datastore failure while loading them leaves
+// the tables before it restored and the others unchanged; restoring the
+// backup again completes it.  Sessions and auth tokens are those of the
+// restored accounts.
+func (m *Manager) Restore(rd io.Reader) error {
+	header, records, err := shipyard.ReadBackup(rd)
+	if err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, tbl := range backupTables {
+		known[tbl] = true
+	}
+	docs := map[string][]map[string]interface{}{}
+	for _, tbl := range header.Tables {
+		if !known[tbl] {
+			return fmt.Errorf("%w: unknown table %s", shipyard.ErrInvalidBackup, tbl)
+		}
+		docs[tbl] = []map[string]interface{}{}
+	}
+	ids := map[string]bool{}
+	for _, rec := range records {
+		var doc map[string]interface{}
+		if err := json.Unmarshal(rec.Document, &doc); err != nil {
+			return fmt.Errorf("%w: %s", shipyard.ErrInvalidBackup, err)
+		}
+		id, _ := doc["id"].(string)
+		if id == "" {
+			return fmt.Errorf("%w: document of %s without an id", shipyard.ErrInvalidBackup, rec.Table)
+		}
+		if ids[rec.Table+"/"+id] {
+			return fmt.Errorf("%w: duplicate document %s of %s", shipyard.ErrInvalidBackup, id, rec.Table)
+		}
+		ids[rec.Table+"/"+id] = true
+		docs[rec.Table] = append(docs[rec.Table], doc)
+	}
+
+	for _, tbl := range header.Tables {
+		if err := m.db.Load(tbl, docs[tbl]); err != nil {
+			return err
+		}
+	}
+	// reload the engines of the restored configuration
+	m.init()
+
+	evt := &shipyard.Event{
+		Type:    "restore-backup",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("version=%s created=%s documents=%d", header.Version, header.Created.Format(time.RFC3339), len(records)),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package manager
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shipyard/shipyard"
+)
+
+func TestRestoreChecksDocuments(t *testing.T) {
+	m := newTestManager(t)
+	if err := m.SaveRole(&shipyard.Role{Name: "ops"}); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := m.Backup(buf); err != nil {
+		t.Fatal(err)
+	}
+	role, err := m.Role("ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DeleteRole(role); err != nil {
+		t.Fatal(err)
+	}
+
+	// a document without an id at the end leaves every table unchanged
+	invalid := buf.String() + `{"table":"` + tblNameRoles + `","document":{"name":"dev"}}` + "\n"
+	if err := m.Restore(strings.NewReader(invalid)); !errors.Is(err, shipyard.ErrInvalidBackup) {
+		t.Fatalf("expected ErrInvalidBackup; received %v", err)
+	}
+	if _, err := m.Role("ops"); err != ErrRoleDoesNotExist {
+		t.Errorf("expected nothing restored from an invalid backup; received %v", err)
+	}
+
+	if err := m.Restore(buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Role("ops"); err != nil {
+		t.Errorf("expected the role to be restored; received %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package manager
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+const (
+	// batchWorkers is the number of batch actions run at once
+	batchWorkers = 10
+)
+
+// Batch applies the operations to the containers of a namespace, or of
+// every namespace when empty.  Containers are listed once and the actions
+// run concurrently; the result reports the outcome of every container.
+// Operations selecting no container are reported as failed.
+func (m *Manager) Batch(ops []*shipyard.BatchOperation, namespace string) (*shipyard.BatchResult, error) {
+	if err := shipyard.ValidateBatch(ops); err != nil {
+		return nil, err
+	}
+	containers := []*citadel.Container{}
+	for _, c := range m.Containers(true) {
+		if namespace == "" || shipyard.ContainerNamespace(c) == namespace {
+			containers = append(containers, c)
+		}
+	}
+
+	type item struct {
+		op        *shipyard.BatchOperation
+		container *citadel.Container
+		result    *shipyard.BatchItemResult
+	}
+	res := &shipyard.BatchResult{
+		Results: []*shipyard.BatchItemResult{},
+	}
+	items := []*item{}
+	for i, op := range ops {
+		selected, err := op.Select(containers)
+		if err != nil || len(selected) == 0 {
+			what := op.ID
+			if what == "" {
+				what = op.Selector
+			}
+			msg := "no such container"
+			if err != nil {
+				msg = err.Error()
+			}
+			res.Results = append(res.Results, &shipyard.BatchItemResult{
+				Operation: i,
+				Action:    op.Action,
+				Container: what,
+				Error:     msg,
+			})
+			continue
+		}
+		for _, c := range selected {
+			r := &shipyard.BatchItemResult{
+				Operation: i,
+				Action:    op.Action,
+				Container: c.ID,
+			}
+			res.Results = append(res.Results, r)
+			items = append(items, &item{op: op, container: c, result: r})
+		}
+	}
+
+	var wg sync.WaitGroup
+	queue := make(chan *item)
+	for i := 0; i < batchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for it := range queue {
+				if err := m.batchAction(it.op, it.container); err != nil {
+					it.result.Error = err.Error()
+				}
+			}
+		}()
+	}
+	for _, it := range items {
+		queue <- it
+	}
+	close(queue)
+	wg.Wait()
+
+	for _, r := range res.Results {
+		if r.Error != "" {
+			res.Failed++
+		}
+	}
+	evt := &shipyard.Event{
+		Type:    "batch",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("operations=%d containers=%d failed=%d", len(ops), len(items), res.Failed),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func (m *Manager) batchAction(op *shipyard.BatchOperation, c *citadel.Container) error {
+	timeout := op.Timeout
+	if timeout == 0 {
+		timeout = 10
+	}
+	switch op.Action {
+	case shipyard.BatchDestroy:
+		return m.Destroy(c)
+	case shipyard.BatchStop:
+		return m.Stop(c, timeout)
+	case shipyard.BatchRestart:
+		return m.Restart(c, timeout)
+	}
+	return fmt.Errorf("unknown action %q", op.Action)
+}
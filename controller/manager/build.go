@@ -0,0 +1,115 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+var (
+	ErrNoBuildEngine = fmt.Errorf("%w: no engine is available", shipyard.ErrBuildFailed)
+)
+
+// buildEngine returns the engine named by the options or else a random
+// available engine, preferring dedicated build engines
+func (m *Manager) buildEngine(opts *shipyard.BuildOptions) (*citadel.Engine, error) {
+	if opts.Engine != "" {
+		eng := m.Engine(opts.Engine)
+		if eng == nil {
+			return nil, ErrEngineDoesNotExist
+		}
+		return eng.Engine, nil
+	}
+	available := []*citadel.Engine{}
+	builders := []*citadel.Engine{}
+	for _, eng := range m.Engines() {
+		if eng.Cordoned || (eng.Health != nil && eng.Health.Status != EngineHealthUp) {
+			continue
+		}
+		available = append(available, eng.Engine)
+		if shipyard.IsBuildEngine(eng.Engine.Labels) {
+			builders = append(builders, eng.Engine)
+		}
+	}
+	if len(builders) > 0 {
+		available = builders
+	}
+	if len(available) == 0 {
+		return nil, ErrNoBuildEngine
+	}
+	return available[rand.Intn(len(available))], nil
+}
+
+// Build builds an image from a tar context on an engine and passes the
+// build output to fn as it arrives.  A failed build returns an error
+// wrapping shipyard.ErrBuildFailed.
+func (m *Manager) Build(context io.Reader, opts *shipyard.BuildOptions, fn func(*shipyard.BuildMessage) error) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	engine, err := m.buildEngine(opts)
+	if err != nil {
+		return err
+	}
+	v := url.Values{}
+	v.Set("rm", "1")
+	if opts.Tag != "" {
+		v.Set("t", opts.Tag)
+	}
+	if opts.Dockerfile != "" {
+		v.Set("dockerfile", opts.Dockerfile)
+	}
+	if opts.NoCache {
+		v.Set("nocache", "1")
+	}
+	if opts.Pull {
+		v.Set("pull", "1")
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-tar")
+	resp, err := m.engineRequestHeader(engine, "POST", fmt.Sprintf("/build?%s", v.Encode()), context, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var buildErr error
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg *shipyard.BuildMessage
+		if err := dec.Decode(&msg); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if err := fn(msg); err != nil {
+			return err
+		}
+		if msg.Error != "" {
+			buildErr = fmt.Errorf("%w: %s", shipyard.ErrBuildFailed, msg.Error)
+			break
+		}
+	}
+
+	evt := &shipyard.Event{
+		Type:    "build-image",
+		Time:    time.Now(),
+		Engine:  engine,
+		Message: fmt.Sprintf("tag=%s", opts.Tag),
+		Tags:    []string{"docker"},
+	}
+	if buildErr != nil {
+		evt.Message += " error=" + buildErr.Error()
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return buildErr
+}
@@ -0,0 +1,71 @@
+package manager
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+// SetEngineCertificates replaces the certificates used for the docker api
+// of an engine and reconnects it.  Empty certificates connect without
+// client authentication.
+func (m *Manager) SetEngineCertificates(id string, certs *shipyard.EngineCertificates) error {
+	eng := m.Engine(id)
+	if eng == nil {
+		return ErrEngineDoesNotExist
+	}
+	if err := certs.Validate(); err != nil {
+		return err
+	}
+	if u, err := url.Parse(eng.Engine.Addr); certs.SSLCertificate != "" && (err != nil || u.Scheme != "https") {
+		return fmt.Errorf("%w: the engine address must use https", shipyard.ErrInvalidCertificate)
+	}
+	updated := &shipyard.Engine{
+		ID:             eng.ID,
+		CACertificate:  certs.CACertificate,
+		SSLCertificate: certs.SSLCertificate,
+		SSLKey:         certs.SSLKey,
+		Engine: &citadel.Engine{
+			ID:     eng.Engine.ID,
+			Addr:   eng.Engine.Addr,
+			Cpus:   eng.Engine.Cpus,
+			Memory: eng.Engine.Memory,
+			Labels: eng.Engine.Labels,
+		},
+	}
+	if err := m.UpdateEngine(updated); err != nil {
+		return err
+	}
+	msg := "certificates removed"
+	if certs.SSLCertificate != "" {
+		info, err := shipyard.ParseCertificate(certs.SSLCertificate)
+		if err != nil {
+			return err
+		}
+		msg = fmt.Sprintf("subject=%s not_after=%s", info.Subject, info.NotAfter.Format(time.RFC3339))
+	}
+	evt := &shipyard.Event{
+		Type:    "update-engine-certificates",
+		Engine:  eng.Engine,
+		Message: msg,
+		Time:    time.Now(),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// EngineCertificates describes the certificates of an engine without its
+// key
+func (m *Manager) EngineCertificates(id string) (*shipyard.EngineCertificateInfo, error) {
+	eng := m.Engine(id)
+	if eng == nil {
+		return nil, ErrEngineDoesNotExist
+	}
+	return eng.CertificateInfo()
+}
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/shipyard/shipyard"
+)
+
+// ComposeApplications parses a compose file into the applications of its
+// services, in the order they are deployed with linked services first.
+// Applications of services already deployed keep their count.  The
+// applications belong to namespace, which may be empty.
+func (m *Manager) ComposeApplications(r io.Reader, projectName string, namespace string) ([]*shipyard.Application, error) {
+	if err := shipyard.ValidateProjectName(projectName); err != nil {
+		return nil, err
+	}
+	services, err := shipyard.ParseCompose(r)
+	if err != nil {
+		return nil, err
+	}
+	apps := []*shipyard.Application{}
+	for _, s := range services {
+		app := s.Application(projectName)
+		app.Namespace = namespace
+		if err := app.Validate(); err != nil {
+			return nil, fmt.Errorf("service %s: %w", s.Name, err)
+		}
+		current, err := m.Application(app.Name)
+		switch {
+		case err == ErrApplicationDoesNotExist:
+		case err != nil:
+			return nil, err
+		case current.Project != projectName || current.Namespace != namespace:
+			return nil, fmt.Errorf("service %s: %w: %s is not part of project %s", s.Name, ErrApplicationExists, app.Name, projectName)
+		default:
+			app.Count = current.Count
+		}
+		apps = append(apps, app)
+	}
+	return apps, nil
+}
+
+// DeployCompose creates or updates the applications of a compose project,
+// as returned by ComposeApplications, in order.  Applications of the
+// project whose service is no longer in the file are removed.  Updated
+// applications keep their running containers; use Deploy to roll out a new
+// image.
+func (m *Manager) DeployCompose(apps []*shipyard.Application, projectName string, namespace string) error {
+	for _, app := range apps {
+		_, err := m.Application(app.Name)
+		switch {
+		case err == ErrApplicationDoesNotExist:
+			err = m.CreateApplication(app)
+		case err == nil:
+			err = m.UpdateApplication(app)
+		}
+		if err != nil {
+			return fmt.Errorf("application %s: %w", app.Name, err)
+		}
+	}
+
+	all, err := m.Applications()
+	if err != nil {
+		return err
+	}
+	for _, app := range all {
+		if app.Project != projectName || app.Namespace != namespace || containsApplication(apps, app.Name) {
+			continue
+		}
+		if err := m.RemoveApplication(app.Name); err != nil {
+			return err
+		}
+	}
+	evt := &shipyard.Event{
+		Type:    "deploy-compose",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("project=%s services=%d", projectName, len(apps)),
+		Tags:    []string{"application"},
+	}
+	return m.SaveEvent(evt)
+}
+
+func containsApplication(apps []*shipyard.Application, name string) bool {
+	for _, a := range apps {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
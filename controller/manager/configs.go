@@ -0,0 +1,175 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameConfigBundles = "config_bundles"
+)
+
+var (
+	ErrConfigExists       = errors.New("config already exists")
+	ErrConfigDoesNotExist = errors.New("config does not exist")
+)
+
+func (m *Manager) Configs() ([]*shipyard.ConfigBundle, error) {
+	bundles := []*shipyard.ConfigBundle{}
+	if err := m.db.Find(tblNameConfigBundles, ds.Where().Sort("name", false), &bundles); err != nil {
+		return nil, err
+	}
+	return bundles, nil
+}
+
+func (m *Manager) Config(name string) (*shipyard.ConfigBundle, error) {
+	var bundle *shipyard.ConfigBundle
+	if err := m.db.FindOne(tblNameConfigBundles, ds.Where(ds.Eq("name", name)), &bundle); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrConfigDoesNotExist
+		}
+		return nil, err
+	}
+	return bundle, nil
+}
+
+func (m *Manager) CreateConfig(bundle *shipyard.ConfigBundle) error {
+	if err := bundle.Validate(); err != nil {
+		return err
+	}
+	if _, err := m.Config(bundle.Name); err == nil {
+		return ErrConfigExists
+	} else if err != ErrConfigDoesNotExist {
+		return err
+	}
+	bundle.ID = ""
+	bundle.Created = time.Now()
+	bundle.Updated = bundle.Created
+	id, err := m.db.Insert(tblNameConfigBundles, bundle)
+	if err != nil {
+		return err
+	}
+	bundle.ID = id
+	evt := &shipyard.Event{
+		Type:    "create-config",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s", bundle.Name),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateConfig replaces the data and files of a config bundle.  Running
+// containers keep the values they were launched with.
+func (m *Manager) UpdateConfig(bundle *shipyard.ConfigBundle) error {
+	if err := bundle.Validate(); err != nil {
+		return err
+	}
+	current, err := m.Config(bundle.Name)
+	if err != nil {
+		return err
+	}
+	bundle.ID = current.ID
+	bundle.Created = current.Created
+	bundle.Updated = time.Now()
+	if err := m.db.Put(tblNameConfigBundles, bundle); err != nil {
+		return err
+	}
+	evt := &shipyard.Event{
+		Type:    "update-config",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s", bundle.Name),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) DeleteConfig(name string) error {
+	n, err := m.db.Delete(tblNameConfigBundles, ds.Where(ds.Eq("name", name)))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrConfigDoesNotExist
+	}
+	evt := &shipyard.Event{
+		Type:    "delete-config",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s", name),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// injectConfigs returns a copy of the image with the data of its config
+// bundles added to the environment and config templates in environment
+// values rendered, along with the bundle files to write into its
+// containers.  Variables set on the image itself take precedence.
+// Templates are only rendered for images referencing config bundles so
+// other values containing "{{" are left alone.
+func (m *Manager) injectConfigs(image *citadel.Image) (*citadel.Image, []*containerFile, error) {
+	names := shipyard.ConfigNames(image)
+	if len(names) == 0 {
+		return image, nil, nil
+	}
+	bundles := map[string]*shipyard.ConfigBundle{}
+	lookup := func(name string) (*shipyard.ConfigBundle, error) {
+		if b, ok := bundles[name]; ok {
+			return b, nil
+		}
+		b, err := m.Config(name)
+		if err != nil {
+			return nil, fmt.Errorf("config %s: %s", name, err)
+		}
+		bundles[name] = b
+		return b, nil
+	}
+
+	img := *image
+	img.Environment = map[string]string{}
+	files := []*containerFile{}
+	for _, name := range names {
+		b, err := lookup(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		for k, v := range b.Data {
+			img.Environment[k] = v
+		}
+		for p, content := range b.Files {
+			files = append(files, &containerFile{path: p, value: content})
+		}
+	}
+	for k, v := range image.Environment {
+		img.Environment[k] = v
+	}
+	err := shipyard.RenderEnvironment(img.Environment, func(name string, key string) (string, error) {
+		b, err := lookup(name)
+		if err != nil {
+			return "", err
+		}
+		v, ok := b.Data[key]
+		if !ok {
+			return "", fmt.Errorf("config %s has no key %s", name, key)
+		}
+		return v, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &img, files, nil
+}
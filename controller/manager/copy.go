@@ -0,0 +1,123 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+var (
+	ErrPathDoesNotExist = errors.New("path does not exist in container")
+)
+
+// CopyFrom returns a tar archive of a file or directory in a container.  The
+// caller must close the archive.
+func (m *Manager) CopyFrom(container *citadel.Container, path string) (io.ReadCloser, error) {
+	if err := shipyard.ValidateContainerPath(path); err != nil {
+		return nil, err
+	}
+	resp, err := m.engineRequest(container.Engine, "GET", fmt.Sprintf("/containers/%s/archive?path=%s", container.ID, url.QueryEscape(path)), nil)
+	if err == dockerclient.ErrNotFound {
+		return nil, ErrPathDoesNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	evt := &shipyard.Event{
+		Type:      "copy-from",
+		Message:   fmt.Sprintf("path=%s", path),
+		Time:      time.Now(),
+		Container: container,
+		Engine:    container.Engine,
+		Tags:      []string{"docker", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// CopyTo extracts a tar archive into a directory of a container
+func (m *Manager) CopyTo(container *citadel.Container, path string, archive io.Reader) error {
+	if err := shipyard.ValidateContainerPath(path); err != nil {
+		return err
+	}
+	resp, err := m.engineRequest(container.Engine, "PUT", fmt.Sprintf("/containers/%s/archive?path=%s", container.ID, url.QueryEscape(path)), archive)
+	if err == dockerclient.ErrNotFound {
+		return ErrPathDoesNotExist
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	evt := &shipyard.Event{
+		Type:      "copy-to",
+		Message:   fmt.Sprintf("path=%s", path),
+		Time:      time.Now(),
+		Container: container,
+		Engine:    container.Engine,
+		Tags:      []string{"docker", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// changeKinds maps the docker change kinds to shipyard ones
+var changeKinds = []string{
+	shipyard.ChangeModified,
+	shipyard.ChangeAdded,
+	shipyard.ChangeDeleted,
+}
+
+// Diff returns the paths of a container filesystem changed since it was
+// created
+func (m *Manager) Diff(container *citadel.Container) ([]*shipyard.ContainerChange, error) {
+	var resp []struct {
+		Path string
+		Kind int
+	}
+	if err := m.engineJSON(container.Engine, "GET", fmt.Sprintf("/containers/%s/changes", container.ID), nil, &resp); err != nil {
+		return nil, err
+	}
+	changes := []*shipyard.ContainerChange{}
+	for _, c := range resp {
+		if c.Kind < 0 || c.Kind >= len(changeKinds) {
+			return nil, fmt.Errorf("unknown change kind %d for %s", c.Kind, c.Path)
+		}
+		changes = append(changes, &shipyard.ContainerChange{
+			Path: c.Path,
+			Kind: changeKinds[c.Kind],
+		})
+	}
+	return changes, nil
+}
+
+// Export returns a tar archive of the filesystem of a container.  The
+// caller must close the archive.
+func (m *Manager) Export(container *citadel.Container) (io.ReadCloser, error) {
+	resp, err := m.engineRequest(container.Engine, "GET", fmt.Sprintf("/containers/%s/export", container.ID), nil)
+	if err != nil {
+		return nil, err
+	}
+	evt := &shipyard.Event{
+		Type:      "export",
+		Time:      time.Now(),
+		Container: container,
+		Engine:    container.Engine,
+		Tags:      []string{"docker", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,569 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameDeployments = "deployments"
+
+	// canaryCheckInterval is how often the canary containers of a baking
+	// deployment are checked and whether it was promoted
+	canaryCheckInterval = 5 * time.Second
+	// maxBakePeriod is how long a canary deployment without a bake period
+	// waits to be promoted before it is rolled back
+	maxBakePeriod = 24 * time.Hour
+)
+
+var (
+	ErrDeploymentDoesNotExist = errors.New("deployment does not exist")
+	ErrDeploymentInProgress   = errors.New("application is already being deployed")
+	ErrDeployImageRequired    = errors.New("image is required")
+	ErrDeploymentNotBaking    = errors.New("deployment is not waiting to be promoted")
+)
+
+func (m *Manager) Deployments(application string) ([]*shipyard.Deployment, error) {
+	q := ds.Where().Sort("started", true)
+	if application != "" {
+		q.Where = append(q.Where, ds.Eq("application", application))
+	}
+	deployments := []*shipyard.Deployment{}
+	if err := m.db.Find(tblNameDeployments, q, &deployments); err != nil {
+		return nil, err
+	}
+	return deployments, nil
+}
+
+func (m *Manager) Deployment(id string) (*shipyard.Deployment, error) {
+	var d *shipyard.Deployment
+	if err := m.db.Get(tblNameDeployments, id, &d); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrDeploymentDoesNotExist
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+func (m *Manager) saveDeployment(d *shipyard.Deployment) error {
+	if d.ID == "" {
+		id, err := m.db.Insert(tblNameDeployments, d)
+		if err != nil {
+			return err
+		}
+		d.ID = id
+		return nil
+	}
+	if err := m.db.Put(tblNameDeployments, d); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Deploy starts updating an application to image and returns the
+// deployment, whose progress is saved as it runs.  A rolling update starts
+// and monitors batches of new containers before destroying the same number
+// of old containers; a blue-green deployment starts all new containers and
+// switches traffic before destroying the old ones.  A canary deployment
+// updates a share of the containers first and holds them until its bake
+// period has passed or it is promoted (see PromoteDeployment) before
+// rolling out to the rest.  If new containers stop
+// during the monitor period, the new containers are destroyed and the old
+// ones restored.  Every deployment is a new revision of the application.
+func (m *Manager) Deploy(name string, image string, opts *shipyard.DeployOptions, user string) (*shipyard.Deployment, error) {
+	if image == "" {
+		return nil, ErrDeployImageRequired
+	}
+	app, err := m.Application(name)
+	if err != nil {
+		return nil, err
+	}
+	spec := *app
+	spec.Image = image
+	return m.deploy(app, &spec, opts, user, 0)
+}
+
+// Rollback redeploys an application with the spec of a previous revision
+// like Deploy; revision 0 is the last revision that succeeded before the
+// current one
+func (m *Manager) Rollback(name string, revision int, opts *shipyard.DeployOptions, user string) (*shipyard.Deployment, error) {
+	app, err := m.Application(name)
+	if err != nil {
+		return nil, err
+	}
+	target, err := m.rollbackTarget(app, revision)
+	if err != nil {
+		return nil, err
+	}
+	// the spec is checked again as engine pools or the application
+	// checks may have changed since it was deployed
+	spec := *target.Spec
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if err := m.checkApplicationPool(&spec); err != nil {
+		return nil, err
+	}
+	return m.deploy(app, &spec, opts, user, target.Revision)
+}
+
+// RollbackTarget returns the deployment whose spec Rollback restores
+func (m *Manager) RollbackTarget(name string, revision int) (*shipyard.Deployment, error) {
+	app, err := m.Application(name)
+	if err != nil {
+		return nil, err
+	}
+	return m.rollbackTarget(app, revision)
+}
+
+func (m *Manager) rollbackTarget(app *shipyard.Application, revision int) (*shipyard.Deployment, error) {
+	history, err := m.revisions(app)
+	if err != nil {
+		return nil, err
+	}
+	return shipyard.RollbackRevision(history, revision)
+}
+
+func (m *Manager) deploy(app *shipyard.Application, spec *shipyard.Application, opts *shipyard.DeployOptions, user string, rollbackTo int) (*shipyard.Deployment, error) {
+	if opts == nil {
+		opts = &shipyard.DeployOptions{}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	// the containers run beyond the count while deploying count against
+	// the quotas of the application
+	if err := m.CheckQuota(app.Owner, app.Namespace, spec.ContainerImage(), opts.Surge(app.Count)); err != nil {
+		return nil, err
+	}
+	if err := m.startDeploying(app.Name); err != nil {
+		return nil, err
+	}
+	history, err := m.revisions(app)
+	if err != nil {
+		m.stopDeploying(app.Name)
+		return nil, err
+	}
+	// the spec keeps the scale and ownership of the application
+	spec.ID = app.ID
+	spec.Name = app.Name
+	spec.Count = app.Count
+	spec.Owner = app.Owner
+	spec.Namespace = app.Namespace
+	spec.Stopped = app.Stopped
+	instance, _ := m.Leadership()
+	d := &shipyard.Deployment{
+		Application:   app.Name,
+		Image:         spec.Image,
+		PreviousImage: app.Image,
+		Options:       *opts,
+		Status:        shipyard.DeploymentRunning,
+		Total:         app.Count,
+		Started:       time.Now(),
+		Revision:      nextRevision(history),
+		Spec:          spec,
+		User:          user,
+		RollbackTo:    rollbackTo,
+		Controller:    instance,
+	}
+	if err := m.saveDeployment(d); err != nil {
+		m.stopDeploying(app.Name)
+		return nil, err
+	}
+	evt := &shipyard.Event{
+		Type:    "deploy-start",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("application=%s image=%s previous=%s revision=%d", app.Name, spec.Image, app.Image, d.Revision),
+		Tags:    []string{"deploy", "application"},
+	}
+	if rollbackTo > 0 {
+		evt.Message += fmt.Sprintf(" rollback=%d", rollbackTo)
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		logger.Errorf("error saving deploy event: %s", err)
+	}
+	progress := *d
+	go func() {
+		defer m.stopDeploying(app.Name)
+		if opts.Strategy == shipyard.DeployBlueGreen {
+			m.blueGreen(app, &progress)
+		} else {
+			m.rollingUpdate(app, &progress)
+		}
+	}()
+	return d, nil
+}
+
+// revisions returns the deployments of an application.  When the spec of
+// the application is not that of its last revision, as for applications
+// created or updated since, it is recorded as a new revision first so it
+// can be rolled back to.
+func (m *Manager) revisions(app *shipyard.Application) ([]*shipyard.Deployment, error) {
+	history, err := m.Deployments(app.Name)
+	if err != nil {
+		return nil, err
+	}
+	var current *shipyard.Deployment
+	for _, d := range history {
+		if d.Status == shipyard.DeploymentSucceeded && d.Spec != nil && (current == nil || d.Revision > current.Revision) {
+			current = d
+		}
+	}
+	if current != nil && sameSpec(current.Spec, app) {
+		return history, nil
+	}
+	spec := *app
+	now := time.Now()
+	d := &shipyard.Deployment{
+		Application: app.Name,
+		Image:       app.Image,
+		Status:      shipyard.DeploymentSucceeded,
+		Updated:     app.Count,
+		Total:       app.Count,
+		Message:     "recorded the spec in use",
+		Started:     now,
+		Finished:    now,
+		Revision:    nextRevision(history),
+		Spec:        &spec,
+	}
+	if err := m.saveDeployment(d); err != nil {
+		return nil, err
+	}
+	return append([]*shipyard.Deployment{d}, history...), nil
+}
+
+func nextRevision(history []*shipyard.Deployment) int {
+	revision := 0
+	for _, d := range history {
+		if d.Revision > revision {
+			revision = d.Revision
+		}
+	}
+	return revision + 1
+}
+
+// sameSpec reports whether two application specs launch the same
+// containers, ignoring their scale and bookkeeping fields
+func sameSpec(a *shipyard.Application, b *shipyard.Application) bool {
+	specs := [][]byte{}
+	for _, app := range []*shipyard.Application{a, b} {
+		s := *app
+		s.ID, s.Count, s.Owner, s.Namespace, s.Stopped = "", 0, "", "", false
+		buf, err := json.Marshal(&s)
+		if err != nil {
+			return false
+		}
+		specs = append(specs, buf)
+	}
+	return bytes.Equal(specs[0], specs[1])
+}
+
+// startDeploying stops the reconciler from scaling the application while
+// old and new containers run side by side
+func (m *Manager) startDeploying(name string) error {
+	m.appLock.Lock()
+	defer m.appLock.Unlock()
+	if m.deploying[name] {
+		return ErrDeploymentInProgress
+	}
+	m.deploying[name] = true
+	return nil
+}
+
+func (m *Manager) stopDeploying(name string) {
+	m.appLock.Lock()
+	defer m.appLock.Unlock()
+	delete(m.deploying, name)
+}
+
+func (m *Manager) rollingUpdate(app *shipyard.Application, d *shipyard.Deployment) {
+	old := []*citadel.Container{}
+	for _, c := range m.ApplicationContainers(app.Name) {
+		if c.State == "running" {
+			old = append(old, c)
+		}
+	}
+	updated := *d.Spec
+	started := []*citadel.Container{}
+	destroyed := 0
+
+	for d.Updated < d.Total {
+		canary := d.Options.Strategy == shipyard.DeployCanary && d.Updated == 0
+		n := d.Options.BatchSize
+		if canary {
+			n = d.Options.CanaryCount(d.Total)
+		}
+		if remaining := d.Total - d.Updated; n > remaining {
+			n = remaining
+		}
+		launched, err := m.runApplication(&updated, n)
+		started = append(started, launched...)
+		if err == nil {
+			err = m.monitorContainers(launched, time.Duration(d.Options.Monitor)*time.Second)
+		}
+		if err != nil {
+			m.rollback(app, d, started, destroyed, err)
+			return
+		}
+		for i := 0; i < n && len(old) > 0; i++ {
+			if err := m.Destroy(old[0]); err != nil {
+				logger.Warnf("error destroying container %s during deploy: %s", old[0].ID[:12], err)
+			}
+			old = old[1:]
+			destroyed++
+		}
+		d.Updated += n
+		if canary {
+			if err := m.bake(d, launched); err != nil {
+				m.rollback(app, d, started, destroyed, err)
+				return
+			}
+			continue
+		}
+		d.Message = fmt.Sprintf("%d of %d containers updated", d.Updated, d.Total)
+		m.deployProgress(d, "deploy-progress")
+		if d.Updated < d.Total && d.Options.Delay > 0 {
+			time.Sleep(time.Duration(d.Options.Delay) * time.Second)
+		}
+	}
+	// containers above the count that were running before the deploy
+	for _, c := range old {
+		if err := m.Destroy(c); err != nil {
+			logger.Warnf("error destroying container %s during deploy: %s", c.ID[:12], err)
+		}
+	}
+
+	m.saveDeployedSpec(app, d)
+	d.Status = shipyard.DeploymentSucceeded
+	d.Finished = time.Now()
+	m.deployProgress(d, "deploy")
+}
+
+// bake holds a canary deployment until its bake period has passed or it is
+// promoted, failing if a canary container stops meanwhile.  Promotions are
+// read from the deployment record so any controller can promote it.
+func (m *Manager) bake(d *shipyard.Deployment, canaries []*citadel.Container) error {
+	var baked, abandoned <-chan time.Time
+	d.Status = shipyard.DeploymentBaking
+	d.Message = fmt.Sprintf("%d of %d containers updated; waiting to be promoted", d.Updated, d.Total)
+	if d.Options.BakePeriod > 0 {
+		baked = time.After(time.Duration(d.Options.BakePeriod) * time.Second)
+		d.Message = fmt.Sprintf("%d of %d containers updated; baking for %ds", d.Updated, d.Total, d.Options.BakePeriod)
+	} else {
+		abandoned = time.After(maxBakePeriod)
+	}
+	m.deployProgress(d, "deploy-canary")
+
+	check := time.NewTicker(canaryCheckInterval)
+	defer check.Stop()
+	for {
+		select {
+		case <-baked:
+		case <-abandoned:
+			return fmt.Errorf("canary was not promoted within %s", maxBakePeriod)
+		case <-check.C:
+			if err := m.monitorContainers(canaries, 0); err != nil {
+				return fmt.Errorf("canary failed: %s", err)
+			}
+			current, err := m.Deployment(d.ID)
+			if err != nil {
+				logger.Warnf("error checking promotion of deployment %s: %s", d.ID, err)
+				continue
+			}
+			if !current.Promoted {
+				continue
+			}
+			d.Promoted = true
+		}
+		d.Status = shipyard.DeploymentRunning
+		d.Message = fmt.Sprintf("canary promoted; %d of %d containers updated", d.Updated, d.Total)
+		m.deployProgress(d, "deploy-promote")
+		return nil
+	}
+}
+
+// PromoteDeployment ends the bake period of a canary deployment, which then
+// updates the rest of the containers
+func (m *Manager) PromoteDeployment(id string) error {
+	d, err := m.Deployment(id)
+	if err != nil {
+		return err
+	}
+	if d.Status != shipyard.DeploymentBaking || d.Promoted {
+		return ErrDeploymentNotBaking
+	}
+	d.Promoted = true
+	return m.saveDeployment(d)
+}
+
+// failInterruptedDeployments fails the deployments the controller with an
+// instance id was running when it stopped; the instance id is empty when ha
+// is not enabled.  Their containers are left to the reconciler.
+func (m *Manager) failInterruptedDeployments(instance string) error {
+	deployments, err := m.Deployments("")
+	if err != nil {
+		return err
+	}
+	for _, d := range deployments {
+		if d.Done() || d.Controller != instance {
+			continue
+		}
+		d.Status = shipyard.DeploymentFailed
+		d.Message = "the controller stopped during the deployment"
+		d.Finished = time.Now()
+		m.deployProgress(d, "deploy-interrupted")
+	}
+	return nil
+}
+
+func (m *Manager) blueGreen(app *shipyard.Application, d *shipyard.Deployment) {
+	old := []*citadel.Container{}
+	for _, c := range m.ApplicationContainers(app.Name) {
+		if c.State == "running" {
+			old = append(old, c)
+		}
+	}
+	updated := *d.Spec
+	launched, err := m.runApplication(&updated, d.Total)
+	if err == nil {
+		err = m.monitorContainers(launched, time.Duration(d.Options.Monitor)*time.Second)
+	}
+	if err == nil {
+		d.Updated = d.Total
+		d.Message = fmt.Sprintf("%d new containers running", d.Total)
+		m.deployProgress(d, "deploy-progress")
+		err = m.switchTraffic(d, launched)
+	}
+	if err != nil {
+		m.rollback(app, d, launched, 0, err)
+		return
+	}
+
+	m.saveDeployedSpec(app, d)
+	for _, c := range old {
+		if err := m.Destroy(c); err != nil {
+			logger.Warnf("error destroying container %s during deploy: %s", c.ID[:12], err)
+		}
+	}
+	d.Status = shipyard.DeploymentSucceeded
+	d.Finished = time.Now()
+	m.deployProgress(d, "deploy")
+}
+
+// saveDeployedSpec stores the image of a successful deployment, or the whole
+// spec of a rollback, as the application spec
+func (m *Manager) saveDeployedSpec(app *shipyard.Application, d *shipyard.Deployment) {
+	if d.RollbackTo == 0 {
+		app.Image = d.Image
+		if _, err := m.db.Update(tblNameApplications, ds.ByID(app.ID), map[string]interface{}{"image": d.Image}); err != nil {
+			logger.Errorf("error saving application %s image: %s", app.Name, err)
+		}
+		return
+	}
+	spec := *d.Spec
+	// keep the scale the application was given meanwhile
+	if current, err := m.Application(app.Name); err == nil {
+		spec.Count = current.Count
+		spec.Stopped = current.Stopped
+	}
+	if err := m.db.Put(tblNameApplications, &spec); err != nil {
+		logger.Errorf("error saving application %s spec: %s", app.Name, err)
+	}
+	*app = spec
+}
+
+// switchTraffic posts the new containers to the switch url of a blue-green
+// deployment
+func (m *Manager) switchTraffic(d *shipyard.Deployment, containers []*citadel.Container) error {
+	if d.Options.SwitchURL == "" {
+		return nil
+	}
+	payload, err := json.Marshal(&shipyard.DeploySwitch{
+		Deployment: d,
+		Containers: containers,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := webhookClient.Post(d.Options.SwitchURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("switch hook failed: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("switch hook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// monitorContainers waits for the monitor period and returns an error if
+// any of the containers is no longer running
+func (m *Manager) monitorContainers(containers []*citadel.Container, period time.Duration) error {
+	time.Sleep(period)
+	for _, c := range containers {
+		current, err := m.Container(c.ID)
+		if err != nil {
+			return err
+		}
+		if current == nil || current.State != "running" {
+			return fmt.Errorf("container %s stopped after starting", c.ID[:12])
+		}
+	}
+	return nil
+}
+
+// rollback destroys the started containers and replaces the destroyed old
+// ones with containers of the previous image
+func (m *Manager) rollback(app *shipyard.Application, d *shipyard.Deployment, started []*citadel.Container, destroyed int, cause error) {
+	logger.Warnf("rolling back deploy of %s to %s: %s", app.Name, d.Image, cause)
+	d.Status = shipyard.DeploymentRolledBack
+	d.Message = cause.Error()
+	for _, c := range started {
+		if c == nil {
+			continue
+		}
+		current, err := m.Container(c.ID)
+		if err != nil || current == nil {
+			continue
+		}
+		if err := m.removeContainer(current); err != nil {
+			logger.Warnf("error removing container %s during rollback: %s", c.ID[:12], err)
+		}
+	}
+	if destroyed > 0 {
+		if _, err := m.runApplication(app, destroyed); err != nil {
+			d.Status = shipyard.DeploymentFailed
+			d.Message = fmt.Sprintf("%s; rollback failed: %s", cause, err)
+		}
+	}
+	d.Updated = 0
+	d.Finished = time.Now()
+	m.deployProgress(d, "deploy-rollback")
+}
+
+// deployProgress saves the deployment and records an event for it
+func (m *Manager) deployProgress(d *shipyard.Deployment, typ string) {
+	if err := m.saveDeployment(d); err != nil {
+		logger.Errorf("error saving deployment %s: %s", d.ID, err)
+	}
+	evt := &shipyard.Event{
+		Type:    typ,
+		Time:    time.Now(),
+		Message: fmt.Sprintf("application=%s image=%s revision=%d updated=%d total=%d status=%s", d.Application, d.Image, d.Revision, d.Updated, d.Total, d.Status),
+		Tags:    []string{"deploy", "application"},
+	}
+	if d.Message != "" && d.Status != shipyard.DeploymentRunning && d.Status != shipyard.DeploymentSucceeded {
+		evt.Message += " error=" + d.Message
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		logger.Errorf("error saving deploy event: %s", err)
+	}
+}
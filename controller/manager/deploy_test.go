@@ -0,0 +1,97 @@
+package manager
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shipyard/shipyard"
+)
+
+func TestPromoteDeployment(t *testing.T) {
+	m := newTestManager(t)
+	d := &shipyard.Deployment{Application: "web", Status: shipyard.DeploymentBaking, Started: time.Now()}
+	if err := m.saveDeployment(d); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.PromoteDeployment(d.ID); err != nil {
+		t.Fatal(err)
+	}
+	promoted, err := m.Deployment(d.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !promoted.Promoted {
+		t.Error("expected the promotion to be recorded on the deployment")
+	}
+	if err := m.PromoteDeployment(d.ID); err != ErrDeploymentNotBaking {
+		t.Errorf("expected %v promoting twice; received %v", ErrDeploymentNotBaking, err)
+	}
+
+	running := &shipyard.Deployment{Application: "api", Status: shipyard.DeploymentRunning, Started: time.Now()}
+	if err := m.saveDeployment(running); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.PromoteDeployment(running.ID); err != ErrDeploymentNotBaking {
+		t.Errorf("expected %v; received %v", ErrDeploymentNotBaking, err)
+	}
+}
+
+func TestFailInterruptedDeployments(t *testing.T) {
+	m := newTestManager(t)
+	tests := []struct {
+		deployment *shipyard.Deployment
+		expected   string
+	}{
+		{&shipyard.Deployment{Application: "web", Status: shipyard.DeploymentRunning}, shipyard.DeploymentFailed},
+		{&shipyard.Deployment{Application: "api", Status: shipyard.DeploymentBaking}, shipyard.DeploymentFailed},
+		{&shipyard.Deployment{Application: "db", Status: shipyard.DeploymentRunning, Controller: "controller-2"}, shipyard.DeploymentRunning},
+		{&shipyard.Deployment{Application: "cache", Status: shipyard.DeploymentSucceeded}, shipyard.DeploymentSucceeded},
+	}
+	for _, test := range tests {
+		test.deployment.Started = time.Now()
+		if err := m.saveDeployment(test.deployment); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.failInterruptedDeployments(""); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		d, err := m.Deployment(test.deployment.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Status != test.expected {
+			t.Errorf("%s: expected status %s; received %s", d.Application, test.expected, d.Status)
+		}
+	}
+}
+
+func TestRollbackChecksSpec(t *testing.T) {
+	m := newTestManager(t)
+	if err := m.CreateApplication(&shipyard.Application{Name: "web", Image: "nginx:1.25"}); err != nil {
+		t.Fatal(err)
+	}
+	for i, spec := range []*shipyard.Application{
+		{Name: "web", Image: "nginx:1.23", Pool: "retired"},
+		{Name: "web", Image: ""},
+	} {
+		d := &shipyard.Deployment{
+			Application: "web",
+			Image:       spec.Image,
+			Status:      shipyard.DeploymentSucceeded,
+			Started:     time.Now(),
+			Revision:    i + 1,
+			Spec:        spec,
+		}
+		if err := m.saveDeployment(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, revision := range []int{1, 2} {
+		if _, err := m.Rollback("web", revision, nil, "admin"); !errors.Is(err, shipyard.ErrInvalidApplication) {
+			t.Errorf("revision %d: expected %v; received %v", revision, shipyard.ErrInvalidApplication, err)
+		}
+	}
+}
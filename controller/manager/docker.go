@@ -0,0 +1,177 @@
+package manager
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/samalba/dockerclient"
+)
+
+// DockerClient returns the docker remote api client for an engine; used for
+// operations citadel does not expose
+func (m *Manager) DockerClient(engine *citadel.Engine) (*dockerclient.DockerClient, error) {
+	client, ok := m.dockerClients[engine.ID]
+	if !ok {
+		return nil, ErrEngineNotConnected
+	}
+	return client, nil
+}
+
+// engineRequest performs a raw request against the docker remote api of an
+// engine.  The caller must close the response body.
+func (m *Manager) engineRequest(engine *citadel.Engine, method string, path string, body io.Reader) (*http.Response, error) {
+	return m.engineRequestHeader(engine, method, path, body, nil)
+}
+
+// engineRequestHeader is engineRequest with additional request headers
+func (m *Manager) engineRequestHeader(engine *citadel.Engine, method string, path string, body io.Reader, header http.Header) (*http.Response, error) {
+	client, err := m.DockerClient(engine)
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/%s%s", client.URL.String(), dockerclient.APIVersion, path)
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, dockerclient.ErrNotFound
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// engineJSON sends in (if not nil) as json to the engine and decodes the
+// response into out (if not nil)
+func (m *Manager) engineJSON(engine *citadel.Engine, method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewBuffer(b)
+	}
+	resp, err := m.engineRequest(engine, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// engineHijack issues a request to an engine and takes over the connection
+// so the raw stream can be used in both directions
+func (m *Manager) engineHijack(engine *citadel.Engine, method string, path string, in interface{}) (net.Conn, *bufio.Reader, error) {
+	client, err := m.DockerClient(engine)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := json.Marshal(in)
+	if err != nil {
+		return nil, nil, err
+	}
+	var conn net.Conn
+	if client.TLSConfig != nil {
+		conn, err = tls.Dial("tcp", client.URL.Host, client.TLSConfig)
+	} else {
+		conn, err = net.Dial("tcp", client.URL.Host)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("/%s%s", dockerclient.APIVersion, path), bytes.NewBuffer(b))
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	req.Host = client.URL.Host
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	// older engines answer 200 and stream on the same connection
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusSwitchingProtocols {
+		defer conn.Close()
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return nil, nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return conn, br, nil
+}
+
+// containerFile is written into a container after it starts
+type containerFile struct {
+	path  string
+	value string
+}
+
+// writeContainerFiles copies files into a container through the engine
+// archive api
+func (m *Manager) writeContainerFiles(c *citadel.Container, files []*containerFile) error {
+	if len(files) == 0 {
+		return nil
+	}
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	now := time.Now()
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:    strings.TrimPrefix(f.path, "/"),
+			Mode:    0444,
+			Size:    int64(len(f.value)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write([]byte(f.value)); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	resp, err := m.engineRequest(c.Engine, "PUT", fmt.Sprintf("/containers/%s/archive?path=%s", c.ID, url.QueryEscape("/")), buf)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+// CordonEngine excludes an engine from new container placements
+func (m *Manager) CordonEngine(id string) error {
+	return m.setCordoned(id, true)
+}
+
+// UncordonEngine makes a cordoned engine available for placements again
+func (m *Manager) UncordonEngine(id string) error {
+	return m.setCordoned(id, false)
+}
+
+func (m *Manager) setCordoned(id string, cordoned bool) error {
+	eng := m.Engine(id)
+	if eng == nil {
+		return ErrEngineDoesNotExist
+	}
+	eng.Cordoned = cordoned
+	if err := m.SaveEngine(eng); err != nil {
+		return err
+	}
+	evtType := "cordon-engine"
+	if !cordoned {
+		evtType = "uncordon-engine"
+	}
+	evt := &shipyard.Event{
+		Type:    evtType,
+		Engine:  eng.Engine,
+		Message: fmt.Sprintf("addr=%s", eng.Engine.Addr),
+		Time:    time.Now(),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DrainEngine cordons an engine and reschedules its running containers on
+// the other engines.  Each container is only destroyed once its replacement
+// has started.  Containers mounting volumes are left in place.
+func (m *Manager) DrainEngine(id string) error {
+	if err := m.CordonEngine(id); err != nil {
+		return err
+	}
+	eng := m.Engine(id)
+	for _, c := range m.Containers(false) {
+		if c.Engine == nil || c.Engine.ID != eng.Engine.ID {
+			continue
+		}
+		if mounts, _ := shipyard.VolumeMounts(c.Image); len(mounts) > 0 {
+			logger.Warnf("not rescheduling container %s; its volumes are on engine %s", c.ID[:12], eng.Engine.ID)
+			continue
+		}
+		img := drainImage(c.Image, eng.Engine.ID)
+		if _, err := m.Run(img, 1, true); err != nil {
+			return fmt.Errorf("error rescheduling container %s: %s", c.ID, err)
+		}
+		if err := m.Destroy(c); err != nil {
+			return err
+		}
+		logger.Infof("rescheduled container %s off engine %s", c.ID[:12], eng.Engine.ID)
+	}
+	evt := &shipyard.Event{
+		Type:    "drain-engine",
+		Engine:  eng.Engine,
+		Message: fmt.Sprintf("addr=%s", eng.Engine.Addr),
+		Time:    time.Now(),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// drainImage returns a copy of image without placement pinning it to the
+// engine being drained
+func drainImage(image *citadel.Image, engineID string) *citadel.Image {
+	img := *image
+	labels := []string{}
+	for _, l := range img.Labels {
+		if l == fmt.Sprintf("host:%s", engineID) {
+			continue
+		}
+		labels = append(labels, l)
+	}
+	img.Labels = labels
+	if img.Type == "host" {
+		img.Type = "service"
+	}
+	// reset hostname
+	img.Hostname = ""
+	return &img
+}
@@ -0,0 +1,43 @@
+package manager
+
+import (
+	"sort"
+
+	"github.com/shipyard/shipyard"
+)
+
+// Endpoints returns the addresses of the published ports of the running
+// containers selected by the filter across all engines, ordered by image
+// and address
+func (m *Manager) Endpoints(filter *shipyard.EndpointFilter) []*shipyard.Endpoint {
+	endpoints := []*shipyard.Endpoint{}
+	for _, c := range m.Containers(false) {
+		if !filter.Match(c) {
+			continue
+		}
+		health := m.ContainerHealth(c)
+		if filter.Healthy && health != "" && health != shipyard.HealthHealthy {
+			continue
+		}
+		for _, e := range shipyard.ContainerEndpoints(c) {
+			if filter.ContainerPort != 0 && e.ContainerPort != filter.ContainerPort {
+				continue
+			}
+			e.Health = health
+			endpoints = append(endpoints, e)
+		}
+	}
+	sort.Sort(endpointsByAddr(endpoints))
+	return endpoints
+}
+
+type endpointsByAddr []*shipyard.Endpoint
+
+func (s endpointsByAddr) Len() int      { return len(s) }
+func (s endpointsByAddr) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s endpointsByAddr) Less(i, j int) bool {
+	if s[i].Image != s[j].Image {
+		return s[i].Image < s[j].Image
+	}
+	return s[i].Addr < s[j].Addr
+}
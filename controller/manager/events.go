@@ -0,0 +1,45 @@
+package manager
+
+import (
+	"github.com/shipyard/shipyard"
+)
+
+// eventBufferSize is the number of events buffered for each subscriber
+// before new events are dropped
+const eventBufferSize = 64
+
+// SubscribeEvents returns a channel receiving every saved event that
+// matches filter.  The channel must be released with UnsubscribeEvents.
+func (m *Manager) SubscribeEvents(filter *shipyard.EventFilter) chan *shipyard.Event {
+	c := make(chan *shipyard.Event, eventBufferSize)
+	m.subscribersLock.Lock()
+	m.subscribers[c] = filter
+	m.subscribersLock.Unlock()
+	return c
+}
+
+// UnsubscribeEvents stops delivery to a channel from SubscribeEvents and
+// closes it
+func (m *Manager) UnsubscribeEvents(c chan *shipyard.Event) {
+	m.subscribersLock.Lock()
+	if _, ok := m.subscribers[c]; ok {
+		delete(m.subscribers, c)
+		close(c)
+	}
+	m.subscribersLock.Unlock()
+}
+
+func (m *Manager) publishEvent(event *shipyard.Event) {
+	m.subscribersLock.Lock()
+	defer m.subscribersLock.Unlock()
+	for c, filter := range m.subscribers {
+		if !filter.Match(event) {
+			continue
+		}
+		select {
+		case c <- event:
+		default:
+			logger.Warnf("event subscriber is not keeping up; dropping %s event", event.Type)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+func TestEventsContainerPrefixIsLiteral(t *testing.T) {
+	m := newTestManager(t)
+	for _, id := range []string{"abc123456789", "def456789012"} {
+		evt := &shipyard.Event{Type: "start", Time: time.Now(), Container: &citadel.Container{ID: id}}
+		if err := m.SaveEvent(evt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := map[string]int{
+		"abc":  1,
+		"a.c":  0,
+		".*":   0,
+		"[ad]": 0,
+	}
+	for prefix, expected := range tests {
+		query := &shipyard.EventQuery{EventFilter: shipyard.EventFilter{ContainerID: prefix}}
+		events, err := m.Events(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != expected {
+			t.Errorf("%s: expected %d events; received %d", prefix, expected, len(events))
+		}
+	}
+}
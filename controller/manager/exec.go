@@ -0,0 +1,101 @@
+package manager
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+// CreateExec sets up a command to run in the container and returns the exec id
+func (m *Manager) CreateExec(container *citadel.Container, cfg *shipyard.ExecConfig) (string, error) {
+	execConfig := &dockerclient.ExecConfig{
+		AttachStdin:  cfg.Interactive,
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          cfg.Tty,
+		Cmd:          cfg.Cmd,
+		Container:    container.ID,
+	}
+	var resp struct {
+		Id string
+	}
+	if err := m.engineJSON(container.Engine, "POST", fmt.Sprintf("/containers/%s/exec", container.ID), execConfig, &resp); err != nil {
+		return "", err
+	}
+	evt := &shipyard.Event{
+		Type:      "exec",
+		Message:   fmt.Sprintf("cmd=%v", cfg.Cmd),
+		Time:      time.Now(),
+		Container: container,
+		Engine:    container.Engine,
+		Tags:      []string{"docker"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return "", err
+	}
+	return resp.Id, nil
+}
+
+// StartExec starts a created exec and returns the engine connection carrying
+// its raw stdio stream
+func (m *Manager) StartExec(container *citadel.Container, execID string, tty bool) (net.Conn, *bufio.Reader, error) {
+	start := map[string]bool{
+		"Detach": false,
+		"Tty":    tty,
+	}
+	return m.engineHijack(container.Engine, "POST", fmt.Sprintf("/exec/%s/start", execID), start)
+}
+
+func (m *Manager) InspectExec(container *citadel.Container, execID string) (*shipyard.ExecInfo, error) {
+	var resp struct {
+		ID       string
+		Running  bool
+		ExitCode int
+	}
+	if err := m.engineJSON(container.Engine, "GET", fmt.Sprintf("/exec/%s/json", execID), nil, &resp); err != nil {
+		return nil, err
+	}
+	info := &shipyard.ExecInfo{
+		ID:       resp.ID,
+		Running:  resp.Running,
+		ExitCode: resp.ExitCode,
+	}
+	return info, nil
+}
+
+// Attach connects to the stdin, stdout and stderr of a running container and
+// returns the engine connection carrying the raw stream.  Tty reports
+// whether the container has a tty; otherwise output is multiplexed in the
+// docker stream format.
+func (m *Manager) Attach(container *citadel.Container) (net.Conn, *bufio.Reader, bool, error) {
+	client, err := m.DockerClient(container.Engine)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	info, err := client.InspectContainer(container.ID)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	path := fmt.Sprintf("/containers/%s/attach?stream=1&stdin=1&stdout=1&stderr=1", container.ID)
+	conn, br, err := m.engineHijack(container.Engine, "POST", path, nil)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	evt := &shipyard.Event{
+		Type:      "attach",
+		Time:      time.Now(),
+		Container: container,
+		Engine:    container.Engine,
+		Tags:      []string{"docker"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		conn.Close()
+		return nil, nil, false, err
+	}
+	return conn, br, info.Config.Tty, nil
+}
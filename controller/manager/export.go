@@ -0,0 +1,140 @@
+package manager
+
+import (
+	"io"
+	"time"
+
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+// exportBatch is the number of documents read at once by exports
+const exportBatch = 1000
+
+// ExportEvents writes the events selected by query, newest first, as csv or
+// json lines.  Events are read in batches so exports of any size stream.
+// Events saved after the export started are left out.
+func (m *Manager) ExportEvents(w io.Writer, format string, query *shipyard.EventQuery) error {
+	if query == nil {
+		query = &shipyard.EventQuery{}
+	}
+	ex, err := shipyard.NewExporter(w, format, shipyard.EventCSVHeader)
+	if err != nil {
+		return err
+	}
+	scoped := *query
+	if scoped.Until.IsZero() {
+		scoped.Until = time.Now()
+	}
+	where := eventQueryFilter(&scoped).Where
+	err = exportPages(where, "Time", query.Offset, query.Limit, func(q *ds.Query, export func(string, time.Time) bool) (int, error) {
+		events := []*storedEvent{}
+		if err := m.db.Find(tblNameEvents, q, &events); err != nil {
+			return 0, err
+		}
+		for _, e := range events {
+			if !export(e.ID, e.Time) {
+				continue
+			}
+			if err := ex.Export(&e.Event); err != nil {
+				return 0, err
+			}
+		}
+		return len(events), ex.Flush()
+	})
+	if err != nil {
+		return err
+	}
+	return ex.Flush()
+}
+
+// ExportAuditLog writes the audit entries selected by filter, newest first,
+// as csv or json lines; see ExportEvents
+func (m *Manager) ExportAuditLog(w io.Writer, format string, filter *shipyard.AuditFilter) error {
+	if filter == nil {
+		filter = &shipyard.AuditFilter{}
+	}
+	ex, err := shipyard.NewExporter(w, format, shipyard.AuditCSVHeader)
+	if err != nil {
+		return err
+	}
+	scoped := *filter
+	if scoped.Until.IsZero() {
+		scoped.Until = time.Now()
+	}
+	where := auditQueryFilter(&scoped).Where
+	err = exportPages(where, "time", filter.Offset, filter.Limit, func(q *ds.Query, export func(string, time.Time) bool) (int, error) {
+		entries := []*shipyard.AuditEntry{}
+		if err := m.db.Find(tblNameAudit, q, &entries); err != nil {
+			return 0, err
+		}
+		for _, e := range entries {
+			if !export(e.ID, e.Time) {
+				continue
+			}
+			if err := ex.Export(e); err != nil {
+				return 0, err
+			}
+		}
+		return len(entries), ex.Flush()
+	})
+	if err != nil {
+		return err
+	}
+	return ex.Flush()
+}
+
+// exportPages exports the documents matching where sorted by the time
+// field, newest first, from offset until limit documents, or all when
+// limit is zero, are exported.  Each page continues from the time of the
+// last document exported instead of skipping the documents before it, so
+// every page costs the same however deep the export is.  page reads the
+// documents of a query, returns how many it read and calls export with the
+// id and time of each; export reports whether to export the document,
+// false for the ones skipped by the offset and the ones sharing the last
+// time that an earlier page read.
+func exportPages(where []ds.Cond, field string, offset int, limit int, page func(q *ds.Query, export func(string, time.Time) bool) (int, error)) error {
+	var (
+		skipped  int
+		exported int
+		last     time.Time
+		// seen are the ids read with the time last
+		seen = map[string]bool{}
+	)
+	export := func(id string, t time.Time) bool {
+		if seen[id] || (limit > 0 && exported >= limit) {
+			return false
+		}
+		if !t.Equal(last) {
+			last = t
+			seen = map[string]bool{}
+		}
+		seen[id] = true
+		if skipped < offset {
+			skipped++
+			return false
+		}
+		exported++
+		return true
+	}
+	for limit == 0 || exported < limit {
+		n := exportBatch
+		if limit > 0 && limit-exported < n {
+			n = limit - exported
+		}
+		conds := where
+		if len(seen) > 0 {
+			conds = append(append([]ds.Cond{}, where...), ds.Le(field, last))
+		}
+		// the documents already read with the last time come first
+		size := n + len(seen) + offset - skipped
+		read, err := page(ds.Where(conds...).Sort(field, true).Page(0, size), export)
+		if err != nil {
+			return err
+		}
+		if read < size {
+			return nil
+		}
+	}
+	return nil
+}
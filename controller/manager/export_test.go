@@ -0,0 +1,50 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/shipyard/shipyard"
+)
+
+func TestExportEventsPages(t *testing.T) {
+	m := newTestManager(t)
+	// more events than a page, many sharing their time
+	now := time.Now().Truncate(time.Second)
+	total := exportBatch + exportBatch/2
+	for i := 0; i < total; i++ {
+		evt := &shipyard.Event{Type: "test", Message: strconv.Itoa(i), Time: now.Add(-time.Duration(i/300) * time.Second)}
+		if _, err := m.db.Insert(tblNameEvents, evt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count := func(query *shipyard.EventQuery) int {
+		buf := &bytes.Buffer{}
+		if err := m.ExportEvents(buf, shipyard.ExportJSONLines, query); err != nil {
+			t.Fatal(err)
+		}
+		// events exported twice are counted once
+		exported := map[string]bool{}
+		dec := json.NewDecoder(buf)
+		for dec.More() {
+			var e shipyard.Event
+			if err := dec.Decode(&e); err != nil {
+				t.Fatal(err)
+			}
+			exported[e.Message] = true
+		}
+		return len(exported)
+	}
+	if n := count(&shipyard.EventQuery{EventFilter: shipyard.EventFilter{Types: []string{"test"}}}); n != total {
+		t.Errorf("expected %d events; received %d", total, n)
+	}
+	if n := count(&shipyard.EventQuery{EventFilter: shipyard.EventFilter{Types: []string{"test"}}, Offset: 250, Limit: exportBatch}); n != exportBatch {
+		t.Errorf("expected %d events; received %d", exportBatch, n)
+	}
+	if n := count(&shipyard.EventQuery{EventFilter: shipyard.EventFilter{Types: []string{"test"}}, Offset: total - 10}); n != 10 {
+		t.Errorf("expected 10 events after the offset; received %d", n)
+	}
+}
@@ -0,0 +1,181 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameSettings = "settings"
+	settingGCPolicy = "gc"
+
+	gcTick = time.Minute
+)
+
+// GCPolicy returns the stored collection policy or a disabled default
+func (m *Manager) GCPolicy() (*shipyard.GCPolicy, error) {
+	var setting struct {
+		Policy *shipyard.GCPolicy `gorethink:"policy"`
+	}
+	if err := m.db.Get(tblNameSettings, settingGCPolicy, &setting); err != nil {
+		if err == ds.ErrNotFound {
+			return &shipyard.GCPolicy{}, nil
+		}
+		return nil, err
+	}
+	return setting.Policy, nil
+}
+
+func (m *Manager) SetGCPolicy(policy *shipyard.GCPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	setting := map[string]interface{}{
+		"id":     settingGCPolicy,
+		"policy": policy,
+	}
+	if err := m.db.Put(tblNameSettings, setting); err != nil {
+		return err
+	}
+	evt := &shipyard.Event{
+		Type: "update-gc-policy",
+		Time: time.Now(),
+		Message: fmt.Sprintf("enabled=%v containers=%v container_age=%dh keep_exited=%d dangling_images=%v",
+			policy.Enabled, policy.Containers, policy.ContainerAge, policy.KeepExited, policy.DanglingImages),
+		Tags: []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// collectGarbage runs collections while the policy is enabled
+func (m *Manager) collectGarbage() {
+	var last time.Time
+	t := time.NewTicker(gcTick).C
+	for range t {
+		if !m.IsLeader() {
+			continue
+		}
+		policy, err := m.GCPolicy()
+		if err != nil {
+			logger.Errorf("error getting gc policy: %s", err)
+			continue
+		}
+		if !policy.Enabled || time.Since(last) < policy.Period() {
+			continue
+		}
+		last = time.Now()
+		if _, err := m.RunGC(); err != nil {
+			logger.Errorf("error collecting garbage: %s", err)
+		}
+	}
+}
+
+// RunGC removes the exited containers and dangling images selected by the
+// policy.  Failed removals are reported in the result.
+func (m *Manager) RunGC() (*shipyard.GCResult, error) {
+	policy, err := m.GCPolicy()
+	if err != nil {
+		return nil, err
+	}
+	m.gcLock.Lock()
+	defer m.gcLock.Unlock()
+	result := &shipyard.GCResult{
+		Started:    time.Now(),
+		Containers: []string{},
+		Images:     []string{},
+	}
+	m.collectContainers(policy, result)
+	if policy.DanglingImages {
+		m.collectImages(result)
+	}
+	evt := &shipyard.Event{
+		Type:    "gc",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("containers=%d images=%d errors=%d", len(result.Containers), len(result.Images), len(result.Errors)),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// collectContainers removes exited containers that are not managed by an
+// application, compose project or job (see shipyard.GCExempt)
+func (m *Manager) collectContainers(policy *shipyard.GCPolicy, result *shipyard.GCResult) {
+	if !policy.Containers {
+		return
+	}
+	candidates := []*shipyard.GCCandidate{}
+	byID := map[string]*citadel.Container{}
+	for _, c := range m.Containers(true) {
+		if c.State != "stopped" || shipyard.GCExempt(c) {
+			continue
+		}
+		client, err := m.DockerClient(c.Engine)
+		if err != nil {
+			continue
+		}
+		info, err := client.InspectContainer(c.ID)
+		if err != nil || info.State.Running || info.State.FinishedAt.IsZero() {
+			// never started or gone
+			continue
+		}
+		byID[c.ID] = c
+		candidates = append(candidates, &shipyard.GCCandidate{
+			ID:       c.ID,
+			Image:    c.Image.Name,
+			Finished: info.State.FinishedAt,
+		})
+	}
+	for _, id := range policy.Collect(candidates, time.Now()) {
+		c := byID[id]
+		if err := m.ClusterManager().Remove(c); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("container %s: %s", id[:12], err))
+			continue
+		}
+		m.removeContainerMetadata(c)
+		result.Containers = append(result.Containers, id)
+	}
+}
+
+// collectImages removes untagged images on every engine; images still
+// used by containers are kept by the engine
+func (m *Manager) collectImages(result *shipyard.GCResult) {
+	var lock sync.Mutex
+	m.eachEngine(func(engine *shipyard.Engine, client *dockerclient.DockerClient) error {
+		var images []struct {
+			Id string
+		}
+		if err := m.engineJSON(engine.Engine, "GET", `/images/json?filters={"dangling":["true"]}`, nil, &images); err != nil {
+			lock.Lock()
+			result.Errors = append(result.Errors, fmt.Sprintf("engine %s: %s", engine.Engine.ID, err))
+			lock.Unlock()
+			return nil
+		}
+		for _, img := range images {
+			err := client.RemoveImage(img.Id)
+			lock.Lock()
+			switch {
+			case err == nil:
+				result.Images = append(result.Images, img.Id)
+			case strings.Contains(err.Error(), "409"), strings.Contains(strings.ToLower(err.Error()), "conflict"):
+				// in use by a container
+			default:
+				result.Errors = append(result.Errors, fmt.Sprintf("image %s on %s: %s", img.Id, engine.Engine.ID, err))
+			}
+			lock.Unlock()
+		}
+		return nil
+	})
+}
@@ -0,0 +1,326 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+var (
+	ErrGroupEmpty        = errors.New("no containers match the group")
+	ErrGroupApplications = errors.New("the group contains application containers; select it by application or project")
+	ErrGroupEngineDown   = errors.New("engine of a group container is down")
+)
+
+// containersByRank orders containers by the link depth of their
+// application so linked applications start first
+type containersByRank struct {
+	containers []*citadel.Container
+	rank       map[string]int
+}
+
+func (s containersByRank) Len() int { return len(s.containers) }
+func (s containersByRank) Swap(i, j int) {
+	s.containers[i], s.containers[j] = s.containers[j], s.containers[i]
+}
+func (s containersByRank) Less(i, j int) bool {
+	ri := s.rank[shipyard.ApplicationName(s.containers[i])]
+	rj := s.rank[shipyard.ApplicationName(s.containers[j])]
+	if ri != rj {
+		return ri < rj
+	}
+	return s.containers[i].ID < s.containers[j].ID
+}
+
+// GroupContainers returns the containers of a group in start order
+func (m *Manager) GroupContainers(g *shipyard.ContainerGroup) ([]*citadel.Container, error) {
+	containers, _, err := m.group(g)
+	return containers, err
+}
+
+// group returns the containers of a group in start order along with the
+// applications they belong to
+func (m *Manager) group(g *shipyard.ContainerGroup) ([]*citadel.Container, []*shipyard.Application, error) {
+	if err := g.Validate(); err != nil {
+		return nil, nil, err
+	}
+	all, err := m.Applications()
+	if err != nil {
+		return nil, nil, err
+	}
+	byName := map[string]*shipyard.Application{}
+	for _, a := range all {
+		byName[a.Name] = a
+	}
+	rank := map[string]int{}
+	var rankOf func(name string, depth int) int
+	rankOf = func(name string, depth int) int {
+		a, ok := byName[name]
+		if !ok || depth > len(all) {
+			return 0
+		}
+		if n, ok := rank[name]; ok {
+			return n
+		}
+		n := 0
+		for l := range a.Links {
+			if ln := rankOf(l, depth+1) + 1; ln > n {
+				n = ln
+			}
+		}
+		rank[name] = n
+		return n
+	}
+
+	containers := []*citadel.Container{}
+	apps := []*shipyard.Application{}
+	seen := map[string]bool{}
+	for _, c := range m.Containers(true) {
+		if !g.Match(c) {
+			continue
+		}
+		containers = append(containers, c)
+		name := shipyard.ApplicationName(c)
+		if a, ok := byName[name]; ok && !seen[name] {
+			seen[name] = true
+			apps = append(apps, a)
+			rankOf(name, 0)
+		}
+	}
+	// applications scaled to zero still belong to their group
+	for _, a := range all {
+		if !seen[a.Name] && (a.Name == g.Application || (g.Project != "" && a.Project == g.Project)) {
+			seen[a.Name] = true
+			apps = append(apps, a)
+		}
+	}
+	if len(containers) == 0 && len(apps) == 0 {
+		return nil, nil, ErrGroupEmpty
+	}
+	sort.Sort(containersByRank{containers: containers, rank: rank})
+	return containers, apps, nil
+}
+
+// StartGroup starts the stopped containers of a group, linked applications
+// first.  If a container fails to start the containers started so far are
+// stopped again.
+func (m *Manager) StartGroup(g *shipyard.ContainerGroup) (*shipyard.GroupResult, error) {
+	m.appLock.Lock()
+	defer m.appLock.Unlock()
+	containers, apps, err := m.group(g)
+	if err != nil {
+		return nil, err
+	}
+	res := newGroupResult("start", g)
+	started := []*citadel.Container{}
+	for _, c := range containers {
+		if c.State == "running" {
+			continue
+		}
+		if err := m.Start(c); err != nil {
+			res.Errors[c.ID] = err.Error()
+			break
+		}
+		started = append(started, c)
+		res.Containers = append(res.Containers, c.ID)
+	}
+	if len(res.Errors) > 0 {
+		for i := len(started) - 1; i >= 0; i-- {
+			if err := m.Stop(started[i], 10); err != nil {
+				logger.Warnf("error stopping container %s on rollback: %s", started[i].ID[:12], err)
+			}
+		}
+		res.RolledBack = true
+		return res, m.groupFailed(res)
+	}
+	if err := m.setApplicationsStopped(apps, false); err != nil {
+		return nil, err
+	}
+	return res, m.saveGroupEvent(res)
+}
+
+// StopGroup stops the running containers of a group, linked applications
+// last.  Applications of the group are not reconciled until the group is
+// started again.  If a container fails to stop the containers stopped so
+// far are started again.
+func (m *Manager) StopGroup(g *shipyard.ContainerGroup, timeout int) (*shipyard.GroupResult, error) {
+	m.appLock.Lock()
+	defer m.appLock.Unlock()
+	containers, apps, err := m.group(g)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.setApplicationsStopped(apps, true); err != nil {
+		return nil, err
+	}
+	res := newGroupResult("stop", g)
+	stopped := []*citadel.Container{}
+	for i := len(containers) - 1; i >= 0; i-- {
+		c := containers[i]
+		if c.State != "running" {
+			continue
+		}
+		if err := m.Stop(c, timeout); err != nil {
+			res.Errors[c.ID] = err.Error()
+			break
+		}
+		stopped = append(stopped, c)
+		res.Containers = append(res.Containers, c.ID)
+	}
+	if len(res.Errors) > 0 {
+		for i := len(stopped) - 1; i >= 0; i-- {
+			if err := m.Start(stopped[i]); err != nil {
+				logger.Warnf("error starting container %s on rollback: %s", stopped[i].ID[:12], err)
+			}
+		}
+		if err := m.setApplicationsStopped(apps, false); err != nil {
+			logger.Warnf("error resuming applications on rollback: %s", err)
+		}
+		res.RolledBack = true
+		return res, m.groupFailed(res)
+	}
+	return res, m.saveGroupEvent(res)
+}
+
+// RestartGroup restarts the containers of a group one at a time, linked
+// applications first.  A restart can not be undone so failures are
+// reported after every container was tried.
+func (m *Manager) RestartGroup(g *shipyard.ContainerGroup, timeout int) (*shipyard.GroupResult, error) {
+	m.appLock.Lock()
+	defer m.appLock.Unlock()
+	containers, apps, err := m.group(g)
+	if err != nil {
+		return nil, err
+	}
+	res := newGroupResult("restart", g)
+	for _, c := range containers {
+		if err := m.Restart(c, timeout); err != nil {
+			res.Errors[c.ID] = err.Error()
+			continue
+		}
+		res.Containers = append(res.Containers, c.ID)
+	}
+	if err := m.setApplicationsStopped(apps, false); err != nil {
+		return nil, err
+	}
+	if len(res.Errors) > 0 {
+		return res, m.groupFailed(res)
+	}
+	return res, m.saveGroupEvent(res)
+}
+
+// DestroyGroup destroys the containers of a group along with its
+// applications.  Nothing is destroyed unless the engines of every
+// container are up.  Label and selector groups may not contain application
+// containers as the applications would replace them.
+func (m *Manager) DestroyGroup(g *shipyard.ContainerGroup) (*shipyard.GroupResult, error) {
+	m.appLock.Lock()
+	defer m.appLock.Unlock()
+	containers, apps, err := m.group(g)
+	if err != nil {
+		return nil, err
+	}
+	if (g.Label != "" || g.Selector != "") && len(apps) > 0 {
+		return nil, ErrGroupApplications
+	}
+	for _, c := range containers {
+		if err := m.checkContainerEngine(c); err != nil {
+			return nil, err
+		}
+	}
+	for _, a := range apps {
+		if _, err := m.db.Delete(tblNameApplications, ds.ByID(a.ID)); err != nil {
+			return nil, err
+		}
+	}
+	res := newGroupResult("destroy", g)
+	for i := len(containers) - 1; i >= 0; i-- {
+		c := containers[i]
+		if err := m.removeContainer(c); err != nil {
+			res.Errors[c.ID] = err.Error()
+			continue
+		}
+		res.Containers = append(res.Containers, c.ID)
+	}
+	if len(res.Errors) > 0 {
+		return res, m.groupFailed(res)
+	}
+	return res, m.saveGroupEvent(res)
+}
+
+// checkContainerEngine returns an error unless the last health check of
+// the engine of a container succeeded
+func (m *Manager) checkContainerEngine(c *citadel.Container) error {
+	if c.Engine == nil {
+		return fmt.Errorf("%w: container %s has no engine", ErrGroupEngineDown, c.ID[:12])
+	}
+	for _, e := range m.Engines() {
+		if e.Engine.ID == c.Engine.ID {
+			if e.Health != nil && e.Health.Status == EngineHealthDown {
+				return fmt.Errorf("%w: %s", ErrGroupEngineDown, e.Engine.ID)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrGroupEngineDown, c.Engine.ID)
+}
+
+func (m *Manager) setApplicationsStopped(apps []*shipyard.Application, stopped bool) error {
+	for _, a := range apps {
+		if a.Stopped == stopped {
+			continue
+		}
+		if _, err := m.db.Update(tblNameApplications, ds.ByID(a.ID), map[string]interface{}{"stopped": stopped}); err != nil {
+			return err
+		}
+		a.Stopped = stopped
+	}
+	return nil
+}
+
+func newGroupResult(action string, g *shipyard.ContainerGroup) *shipyard.GroupResult {
+	return &shipyard.GroupResult{
+		Action:     action,
+		Group:      g.String(),
+		Containers: []string{},
+		Errors:     map[string]string{},
+	}
+}
+
+// groupFailed saves the event of a failed group operation and returns its
+// error
+func (m *Manager) groupFailed(res *shipyard.GroupResult) error {
+	if err := m.saveGroupEvent(res); err != nil {
+		logger.Warnf("error saving group event: %s", err)
+	}
+	ids := []string{}
+	for id := range res.Errors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	msgs := []string{}
+	for _, id := range ids {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", id[:12], res.Errors[id]))
+	}
+	msg := fmt.Sprintf("%s of group %s failed: %s", res.Action, res.Group, strings.Join(msgs, "; "))
+	if res.RolledBack {
+		msg += "; rolled back"
+	}
+	return errors.New(msg)
+}
+
+func (m *Manager) saveGroupEvent(res *shipyard.GroupResult) error {
+	evt := &shipyard.Event{
+		Type:    res.Action + "-group",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("group=%s containers=%d failed=%d", res.Group, len(res.Containers), len(res.Errors)),
+		Tags:    []string{"cluster"},
+	}
+	return m.SaveEvent(evt)
+}
@@ -15,8 +15,17 @@ type (
 )
 
 func (h *EventHandler) Handle(e *citadel.Event) error {
+	// every controller watches the engines; the leader records and acts
+	// on their events
+	if !h.Manager.IsLeader() {
+		return nil
+	}
 	logger.Infof("event: date=%s type=%s image=%s container=%s", e.Time.Format(time.RubyDate), e.Type, e.Container.Image.Name, e.Container.ID[:12])
 	h.logDockerEvent(e)
+	if e.Type == "die" {
+		go h.Manager.handleContainerExit(e.Container)
+		go h.Manager.handleJobExit(e.Container)
+	}
 	return nil
 }
 
@@ -0,0 +1,254 @@
+package manager
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+const (
+	// healthCheckTick is how often containers are checked for a due probe
+	healthCheckTick = 5 * time.Second
+	// healthOutputLength limits the probe output kept for events
+	healthOutputLength = 256
+)
+
+type containerHealth struct {
+	status    string
+	failures  int
+	lastCheck time.Time
+	probing   bool
+}
+
+// checkContainerHealth probes the health checks of running containers at
+// their interval
+func (m *Manager) checkContainerHealth() {
+	t := time.NewTicker(healthCheckTick).C
+	for range t {
+		if !m.IsLeader() {
+			continue
+		}
+		m.probeDue()
+	}
+}
+
+func (m *Manager) probeDue() {
+	running := map[string]bool{}
+	for _, c := range m.Containers(false) {
+		hc, err := shipyard.ContainerHealthCheck(c.Image)
+		if err != nil {
+			logger.Warnf("container %s: %s", c.ID[:12], err)
+			continue
+		}
+		if hc == nil {
+			continue
+		}
+		running[c.ID] = true
+		m.healthLock.Lock()
+		h, ok := m.health[c.ID]
+		if !ok {
+			h = &containerHealth{status: shipyard.HealthStarting}
+			m.health[c.ID] = h
+		}
+		due := !h.probing && time.Since(h.lastCheck) >= time.Duration(hc.Interval)*time.Second
+		if due {
+			h.probing = true
+		}
+		m.healthLock.Unlock()
+		if due {
+			go m.probe(c, hc)
+		}
+	}
+	// forget containers that stopped or were removed
+	m.healthLock.Lock()
+	for id := range m.health {
+		if !running[id] {
+			delete(m.health, id)
+		}
+	}
+	m.healthLock.Unlock()
+}
+
+// probe runs one check and records the result, saving an event when the
+// container becomes healthy or unhealthy
+func (m *Manager) probe(c *citadel.Container, hc *shipyard.HealthCheck) {
+	var err error
+	switch hc.Type {
+	case shipyard.HealthCheckHTTP:
+		err = m.probeHTTP(c, hc)
+	case shipyard.HealthCheckTCP:
+		err = m.probeTCP(c, hc)
+	case shipyard.HealthCheckExec:
+		err = m.probeExec(c, hc)
+	}
+
+	m.healthLock.Lock()
+	h, ok := m.health[c.ID]
+	if !ok {
+		m.healthLock.Unlock()
+		return
+	}
+	h.probing = false
+	h.lastCheck = time.Now()
+	previous := h.status
+	if err == nil {
+		h.failures = 0
+		h.status = shipyard.HealthHealthy
+	} else {
+		h.failures++
+		if h.failures >= hc.Retries {
+			h.status = shipyard.HealthUnhealthy
+		}
+	}
+	status := h.status
+	m.healthLock.Unlock()
+
+	if status == previous {
+		return
+	}
+	msg := fmt.Sprintf("container=%s check=%s status=%s", c.ID[:12], hc.Type, status)
+	if err != nil {
+		msg = fmt.Sprintf("%s error=%s", msg, truncate(err.Error(), healthOutputLength))
+	}
+	logger.Infof("health: %s", msg)
+	evt := &shipyard.Event{
+		Type:      "container-" + status,
+		Time:      time.Now(),
+		Container: c,
+		Engine:    c.Engine,
+		Message:   msg,
+		Tags:      []string{"docker", "health"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		logger.Errorf("error saving health event: %s", err)
+	}
+}
+
+func (m *Manager) probeHTTP(c *citadel.Container, hc *shipyard.HealthCheck) error {
+	addr, err := publishedAddr(c, hc.Port)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: time.Duration(hc.Timeout) * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://%s%s", addr, hc.Path))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (m *Manager) probeTCP(c *citadel.Container, hc *shipyard.HealthCheck) error {
+	addr, err := publishedAddr(c, hc.Port)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", addr, time.Duration(hc.Timeout)*time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeExec runs the check command detached and waits for its exit code.
+// It does not use CreateExec so checks are not recorded as exec events.
+func (m *Manager) probeExec(c *citadel.Container, hc *shipyard.HealthCheck) error {
+	cfg := map[string]interface{}{
+		"AttachStdout": true,
+		"AttachStderr": true,
+		"Cmd":          hc.Command,
+		"Container":    c.ID,
+	}
+	var created struct {
+		Id string
+	}
+	if err := m.engineJSON(c.Engine, "POST", fmt.Sprintf("/containers/%s/exec", c.ID), cfg, &created); err != nil {
+		return err
+	}
+	start := map[string]bool{
+		"Detach": true,
+	}
+	if err := m.engineJSON(c.Engine, "POST", fmt.Sprintf("/exec/%s/start", created.Id), start, nil); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(time.Duration(hc.Timeout) * time.Second)
+	for {
+		info, err := m.InspectExec(c, created.Id)
+		if err != nil {
+			return err
+		}
+		if !info.Running {
+			if info.ExitCode != 0 {
+				return fmt.Errorf("exit status %d", info.ExitCode)
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %ds", hc.Timeout)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// ContainerHealth returns the health status of a container with a health
+// check or an empty string
+func (m *Manager) ContainerHealth(c *citadel.Container) string {
+	hc, err := shipyard.ContainerHealthCheck(c.Image)
+	if err != nil || hc == nil {
+		return ""
+	}
+	if c.State != "running" {
+		return shipyard.HealthUnhealthy
+	}
+	m.healthLock.Lock()
+	defer m.healthLock.Unlock()
+	if h, ok := m.health[c.ID]; ok {
+		return h.status
+	}
+	return shipyard.HealthStarting
+}
+
+// SetContainerHealth reports the health of the containers on their image
+// environment (see shipyard.ContainerHealth) for api responses.  The image
+// is copied; it is shared with the cluster state.
+func (m *Manager) SetContainerHealth(containers ...*citadel.Container) {
+	for _, c := range containers {
+		status := m.ContainerHealth(c)
+		if status == "" {
+			continue
+		}
+		img := *c.Image
+		img.Environment = make(map[string]string, len(c.Image.Environment)+1)
+		for k, v := range c.Image.Environment {
+			img.Environment[k] = v
+		}
+		img.Environment[shipyard.HealthStatusEnv] = status
+		c.Image = &img
+	}
+}
+
+// publishedAddr returns the engine host and host port a container publishes
+// a container port on.  A zero port selects the only published port.
+func publishedAddr(c *citadel.Container, containerPort int) (string, error) {
+	endpoints := shipyard.ContainerEndpoints(c)
+	for _, e := range endpoints {
+		if e.ContainerPort == containerPort || (containerPort == 0 && len(endpoints) == 1) {
+			return e.Addr, nil
+		}
+	}
+	return "", fmt.Errorf("port %d is not published", containerPort)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,25 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+func TestSetContainerHealthCopiesImage(t *testing.T) {
+	m := newTestManager(t)
+	image := &citadel.Image{Name: "web", Environment: map[string]string{"PORT": "80"}}
+	if err := shipyard.SetHealthCheck(image, &shipyard.HealthCheck{Type: shipyard.HealthCheckTCP, Port: 80}); err != nil {
+		t.Fatal(err)
+	}
+	env := image.Environment
+	c := &citadel.Container{ID: "0123456789ab", Image: image, State: "running"}
+	m.SetContainerHealth(c)
+	if c.Image.Environment[shipyard.HealthStatusEnv] != shipyard.HealthStarting {
+		t.Errorf("expected the health on the container; received %v", c.Image.Environment)
+	}
+	if _, ok := env[shipyard.HealthStatusEnv]; ok || c.Image == image {
+		t.Error("expected the shared image to be left unchanged")
+	}
+}
@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameIdempotencyKeys = "idempotency_keys"
+	// maxIdempotencyKeys bounds the stored requests; the ones expiring
+	// first are dropped beyond it
+	maxIdempotencyKeys     = 10000
+	idempotencySweepPeriod = time.Minute
+)
+
+var ErrIdempotencyKeyContention = errors.New("idempotency key is claimed and released concurrently")
+
+// ClaimIdempotencyKey records a request starting with the idempotency key
+// id for ttl and returns nil, or returns the request that holds the key
+// when it has not expired.  Requests in progress expire too so the keys
+// of controllers stopped while handling them are released.
+func (m *Manager) ClaimIdempotencyKey(id string, request string, ttl time.Duration) (*shipyard.IdempotentRequest, error) {
+	// a key expiring while it is claimed is freed and claimed again once
+	for attempt := 0; attempt < 2; attempt++ {
+		now := time.Now()
+		req := &shipyard.IdempotentRequest{
+			ID:      id,
+			Request: request,
+			Expires: now.Add(ttl),
+		}
+		_, err := m.db.Insert(tblNameIdempotencyKeys, req)
+		if err == nil {
+			return nil, nil
+		}
+		if err != ds.ErrExists {
+			return nil, err
+		}
+		var held *shipyard.IdempotentRequest
+		if err := m.db.Get(tblNameIdempotencyKeys, id, &held); err != nil {
+			if err == ds.ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		if now.Before(held.Expires) {
+			return held, nil
+		}
+		if _, err := m.db.Delete(tblNameIdempotencyKeys, ds.Where(ds.Eq("id", id), ds.Lt("expires", now))); err != nil {
+			return nil, err
+		}
+	}
+	return nil, ErrIdempotencyKeyContention
+}
+
+// SaveIdempotentRequest stores a request claimed with ClaimIdempotencyKey
+// once it is done
+func (m *Manager) SaveIdempotentRequest(req *shipyard.IdempotentRequest) error {
+	return m.db.Put(tblNameIdempotencyKeys, req)
+}
+
+// ReleaseIdempotencyKey forgets the request of an idempotency key so the
+// request can be sent again
+func (m *Manager) ReleaseIdempotencyKey(id string) error {
+	_, err := m.db.Delete(tblNameIdempotencyKeys, ds.ByID(id))
+	return err
+}
+
+// expireIdempotencyKeys periodically removes the expired idempotency keys
+// and the ones expiring first beyond maxIdempotencyKeys
+func (m *Manager) expireIdempotencyKeys() {
+	t := time.NewTicker(idempotencySweepPeriod).C
+	for range t {
+		if !m.IsLeader() {
+			continue
+		}
+		if err := m.pruneIdempotencyKeys(maxIdempotencyKeys); err != nil {
+			logger.Errorf("error expiring idempotency keys: %s", err)
+		}
+	}
+}
+
+func (m *Manager) pruneIdempotencyKeys(max int) error {
+	if _, err := m.db.Delete(tblNameIdempotencyKeys, ds.Where(ds.Lt("expires", time.Now()))); err != nil {
+		return err
+	}
+	n, err := m.db.Count(tblNameIdempotencyKeys, nil)
+	if err != nil || n <= max {
+		return err
+	}
+	oldest := []*shipyard.IdempotentRequest{}
+	if err := m.db.Find(tblNameIdempotencyKeys, ds.Where().Sort("expires", false).Page(0, n-max), &oldest); err != nil {
+		return err
+	}
+	ids := []string{}
+	for _, req := range oldest {
+		ids = append(ids, req.ID)
+	}
+	_, err = m.db.Delete(tblNameIdempotencyKeys, ds.Where(ds.In("id", ids)))
+	return err
+}
@@ -0,0 +1,48 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+func TestClaimIdempotencyKey(t *testing.T) {
+	m := newTestManager(t)
+	if held, err := m.ClaimIdempotencyKey("k", "POST /api/containers", time.Hour); err != nil || held != nil {
+		t.Fatalf("expected the key to be claimed; received %v %v", held, err)
+	}
+	held, err := m.ClaimIdempotencyKey("k", "POST /api/containers", time.Hour)
+	if err != nil || held == nil || held.Done {
+		t.Fatalf("expected the request in progress; received %v %v", held, err)
+	}
+
+	if err := m.SaveIdempotentRequest(&shipyard.IdempotentRequest{ID: "old", Request: "POST /api/jobs", Done: true, Expires: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if held, err := m.ClaimIdempotencyKey("old", "POST /api/jobs", time.Hour); err != nil || held != nil {
+		t.Errorf("expected an expired key to be claimed again; received %v %v", held, err)
+	}
+}
+
+func TestPruneIdempotencyKeys(t *testing.T) {
+	m := newTestManager(t)
+	now := time.Now()
+	for i, id := range []string{"expired", "first", "second", "third"} {
+		req := &shipyard.IdempotentRequest{ID: id, Done: true, Expires: now.Add(time.Duration(2*i-1) * time.Hour)}
+		if err := m.SaveIdempotentRequest(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.pruneIdempotencyKeys(2); err != nil {
+		t.Fatal(err)
+	}
+	left := []*shipyard.IdempotentRequest{}
+	if err := m.db.Find(tblNameIdempotencyKeys, ds.Where().Sort("expires", false), &left); err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 2 || left[0].ID != "second" || left[1].ID != "third" {
+		t.Errorf("expected the expired key and the one expiring first to be removed; received %v", left)
+	}
+}
@@ -0,0 +1,212 @@
+package manager
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+// EngineErrors collects the failures of an operation run on every engine,
+// keyed by engine id
+type EngineErrors map[string]error
+
+func (e EngineErrors) Error() string {
+	ids := []string{}
+	for id := range e {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	msgs := []string{}
+	for _, id := range ids {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", id, e[id]))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// eachEngine runs fn concurrently for every engine and returns EngineErrors
+// when any of them fail
+func (m *Manager) eachEngine(fn func(engine *shipyard.Engine, client *dockerclient.DockerClient) error) error {
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs = EngineErrors{}
+	)
+	for _, e := range m.Engines() {
+		wg.Add(1)
+		go func(e *shipyard.Engine) {
+			defer wg.Done()
+			client, err := m.DockerClient(e.Engine)
+			if err == nil {
+				err = fn(e, client)
+			}
+			if err != nil {
+				lock.Lock()
+				errs[e.Engine.ID] = err
+				lock.Unlock()
+			}
+		}(e)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Images returns the images on all engines merged by id.  Engines that
+// cannot be reached are skipped.
+func (m *Manager) Images() ([]*shipyard.Image, error) {
+	var lock sync.Mutex
+	images := map[string]*shipyard.Image{}
+	err := m.eachEngine(func(engine *shipyard.Engine, client *dockerclient.DockerClient) error {
+		imgs, err := client.ListImages()
+		if err != nil {
+			return err
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		for _, i := range imgs {
+			img, ok := images[i.Id]
+			if !ok {
+				img = &shipyard.Image{
+					ID:          i.Id,
+					RepoTags:    i.RepoTags,
+					Created:     i.Created,
+					Size:        i.Size,
+					VirtualSize: i.VirtualSize,
+				}
+				images[i.Id] = img
+			}
+			img.Engines = append(img.Engines, engine.Engine.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Warnf("error listing images: %s", err)
+	}
+	res := []*shipyard.Image{}
+	for _, img := range images {
+		sort.Strings(img.Engines)
+		res = append(res, img)
+	}
+	sort.Sort(imagesByCreated(res))
+	return res, nil
+}
+
+type imagesByCreated []*shipyard.Image
+
+func (s imagesByCreated) Len() int           { return len(s) }
+func (s imagesByCreated) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s imagesByCreated) Less(i, j int) bool { return s[i].Created > s[j].Created }
+
+// PullImage pulls an image on every engine.  An empty tag pulls latest.
+func (m *Manager) PullImage(name string, tag string) error {
+	if tag == "" {
+		tag = "latest"
+	}
+	image := fmt.Sprintf("%s:%s", name, tag)
+	auth, err := m.registryAuth(image)
+	if err != nil {
+		return err
+	}
+	err = m.eachEngine(func(engine *shipyard.Engine, client *dockerclient.DockerClient) error {
+		return client.PullImage(image, auth)
+	})
+	evt := &shipyard.Event{
+		Type:    "pull-image",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("image=%s", image),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return err
+}
+
+// RemoveImage removes an image from every engine that has it
+func (m *Manager) RemoveImage(name string) error {
+	err := m.eachEngine(func(engine *shipyard.Engine, client *dockerclient.DockerClient) error {
+		if err := client.RemoveImage(name); err != nil && err != dockerclient.ErrNotFound {
+			return err
+		}
+		return nil
+	})
+	evt := &shipyard.Event{
+		Type:    "remove-image",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("image=%s", name),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return err
+}
+
+// Commit creates an image from the current state of a container on its
+// engine and pushes it when requested
+func (m *Manager) Commit(container *citadel.Container, opts *shipyard.CommitOptions) (*shipyard.Image, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	v := url.Values{}
+	v.Set("container", container.ID)
+	v.Set("repo", opts.Repo)
+	if opts.Tag != "" {
+		v.Set("tag", opts.Tag)
+	}
+	if opts.Comment != "" {
+		v.Set("comment", opts.Comment)
+	}
+	if opts.Author != "" {
+		v.Set("author", opts.Author)
+	}
+	var res struct {
+		ID string `json:"Id"`
+	}
+	if err := m.engineJSON(container.Engine, "POST", fmt.Sprintf("/commit?%s", v.Encode()), nil, &res); err != nil {
+		return nil, err
+	}
+	image := &shipyard.Image{
+		ID:       res.ID,
+		RepoTags: []string{opts.Image()},
+		Created:  time.Now().Unix(),
+		Engines:  []string{container.Engine.ID},
+	}
+	evt := &shipyard.Event{
+		Type:      "commit",
+		Time:      time.Now(),
+		Container: container,
+		Engine:    container.Engine,
+		Message:   fmt.Sprintf("image=%s id=%s", opts.Image(), res.ID),
+		Tags:      []string{"docker"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return nil, err
+	}
+	if !opts.Push {
+		return image, nil
+	}
+	if err := m.pushImage(container.Engine, opts.Image()); err != nil {
+		return nil, err
+	}
+	evt = &shipyard.Event{
+		Type:    "push-image",
+		Time:    time.Now(),
+		Engine:  container.Engine,
+		Message: fmt.Sprintf("image=%s", opts.Image()),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return nil, err
+	}
+	return image, nil
+}
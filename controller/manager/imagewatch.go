@@ -0,0 +1,315 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameImageWatches = "image_watches"
+
+	// imageWatchTick is how often the registries are polled for the image
+	// tags of auto-deploy applications
+	imageWatchTick = 5 * time.Minute
+	// manifestMediaTypes are the manifests a registry may answer with; the
+	// digest of each identifies the pushed image
+	manifestMediaTypes = "application/vnd.docker.distribution.manifest.v2+json, application/vnd.docker.distribution.manifest.list.v2+json, application/vnd.oci.image.manifest.v1+json, application/vnd.oci.image.index.v1+json"
+)
+
+var (
+	ErrImageTagRequired = errors.New("a pushed image tag is required")
+
+	registryClient = &http.Client{Timeout: 30 * time.Second}
+)
+
+// watchImages checks the image tags of auto-deploy applications every
+// imageWatchTick
+func (m *Manager) watchImages() {
+	t := time.NewTicker(imageWatchTick).C
+	for range t {
+		if !m.IsLeader() {
+			continue
+		}
+		if err := m.CheckImages(); err != nil {
+			logger.Errorf("error checking application images: %s", err)
+		}
+	}
+}
+
+// CheckImages looks up the digest of the image tag of every auto-deploy
+// application in its registry and deploys the applications whose tag moved
+// since it was last seen
+func (m *Manager) CheckImages() error {
+	apps, err := m.Applications()
+	if err != nil {
+		return err
+	}
+	for _, app := range apps {
+		if !app.AutoDeploy || app.Stopped {
+			continue
+		}
+		ref := shipyard.ParseImageReference(app.Image)
+		if !ref.Moving() {
+			continue
+		}
+		digest, err := m.imageDigest(ref)
+		if err != nil {
+			logger.Warnf("error checking image %s of application %s: %s", app.Image, app.Name, err)
+			continue
+		}
+		if err := m.imageSeen(app, digest, "registry"); err != nil {
+			logger.Errorf("error redeploying application %s: %s", app.Name, err)
+		}
+	}
+	return nil
+}
+
+// ImagePushed deploys the auto-deploy applications running the tag of the
+// repository that was pushed, such as from a registry webhook, when the tag
+// moved since it was last seen.  A webhook delivered again does not deploy
+// the applications again.  The error of the first application that could
+// not be checked or deployed is returned once all were.
+func (m *Manager) ImagePushed(repository string, tag string) error {
+	if tag == "" {
+		return ErrImageTagRequired
+	}
+	pushed := shipyard.ParseImageReference(repository)
+	apps, err := m.Applications()
+	if err != nil {
+		return err
+	}
+	var failed error
+	for _, app := range apps {
+		if !app.AutoDeploy || app.Stopped {
+			continue
+		}
+		ref := shipyard.ParseImageReference(app.Image)
+		if !ref.Moving() || ref.Host != pushed.Host || ref.Repository != pushed.Repository || ref.Tag != tag {
+			continue
+		}
+		digest, err := m.imageDigest(ref)
+		if err == nil {
+			err = m.imageSeen(app, digest, "webhook")
+		}
+		if err != nil {
+			logger.Errorf("error deploying image %s of application %s: %s", app.Image, app.Name, err)
+			if failed == nil {
+				failed = fmt.Errorf("application %s: %s", app.Name, err)
+			}
+		}
+	}
+	return failed
+}
+
+// autoDeployed reports whether a container belongs to an auto-deploy
+// application, which registry webhooks redeploy through ImagePushed
+func (m *Manager) autoDeployed(name string) bool {
+	if name == "" {
+		return false
+	}
+	app, err := m.Application(name)
+	return err == nil && app.AutoDeploy
+}
+
+func (m *Manager) imageWatch(app *shipyard.Application) (*shipyard.ImageWatch, error) {
+	var w *shipyard.ImageWatch
+	if err := m.db.FindOne(tblNameImageWatches, ds.Where(ds.Eq("application", app.Name)), &w); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return w, nil
+}
+
+func (m *Manager) saveImageWatch(app *shipyard.Application, w *shipyard.ImageWatch, digest string) error {
+	if w == nil {
+		w = &shipyard.ImageWatch{
+			Application: app.Name,
+		}
+	}
+	w.Image = app.Image
+	w.Digest = digest
+	w.Checked = time.Now()
+	if w.ID == "" {
+		id, err := m.db.Insert(tblNameImageWatches, w)
+		if err != nil {
+			return err
+		}
+		w.ID = id
+		return nil
+	}
+	return m.db.Put(tblNameImageWatches, w)
+}
+
+// imageSeen deploys an application when the tag of its image points to
+// another digest than the one it runs.  Images deployed by auto-deploy are
+// pinned to the digest they run; for other images the first digest seen is
+// only recorded.
+func (m *Manager) imageSeen(app *shipyard.Application, digest string, source string) error {
+	current := shipyard.ParseImageReference(app.Image).Digest
+	if current == "" {
+		w, err := m.imageWatch(app)
+		if err != nil {
+			return err
+		}
+		if w == nil || w.Image != app.Image || w.Digest == "" {
+			return m.saveImageWatch(app, w, digest)
+		}
+		current = w.Digest
+	}
+	if current == digest {
+		return nil
+	}
+	return m.deployImageUpdate(app, digest, source)
+}
+
+// deployImageUpdate deploys the image tag of an application pinned to the
+// digest it points to, so the deployment records the image it rolled out and
+// rolling back restores the previous one.  A failed deployment leaves the
+// application running the previous digest, so it is retried by the next
+// check.
+func (m *Manager) deployImageUpdate(app *shipyard.Application, digest string, source string) error {
+	d, err := m.Deploy(app.Name, shipyard.PinImage(app.Image, digest), nil, "")
+	if err != nil {
+		if err == ErrDeploymentInProgress {
+			logger.Infof("skipping image update of application %s: %s", app.Name, err)
+			return nil
+		}
+		return err
+	}
+	evt := &shipyard.Event{
+		Type:    "image-update",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("application=%s image=%s previous=%s source=%s deployment=%s", app.Name, d.Image, app.Image, source, d.ID),
+		Tags:    []string{"deploy", "application"},
+	}
+	return m.SaveEvent(evt)
+}
+
+// imageDigest returns the digest of the manifest the tag of an image points
+// to in its registry, using any stored registry credentials
+func (m *Manager) imageDigest(ref *shipyard.ImageReference) (string, error) {
+	scheme := "https"
+	username, password := "", ""
+	registries, err := m.Registries()
+	if err != nil {
+		return "", err
+	}
+	for _, reg := range registries {
+		if reg.Host() == ref.Host {
+			if strings.HasPrefix(reg.Addr, "http://") {
+				scheme = "http"
+			}
+			creds, err := m.registryCredentials(reg)
+			if err != nil {
+				return "", err
+			}
+			username, password = creds.Username, creds.Password
+		}
+	}
+	u := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, ref.APIHost(), ref.Repository, ref.Tag)
+	resp, err := manifestRequest(u, username, password, "")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge, ok := shipyard.ParseBearerChallenge(resp.Header.Get("WWW-Authenticate"))
+		if !ok {
+			return "", fmt.Errorf("registry %s denied access", ref.Host)
+		}
+		token, err := registryToken(challenge, username, password)
+		if err != nil {
+			return "", err
+		}
+		if resp, err = manifestRequest(u, "", "", token); err != nil {
+			return "", err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry %s returned status %d", ref.Host, resp.StatusCode)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry %s did not return a digest", ref.Host)
+	}
+	return digest, nil
+}
+
+// manifestRequest sends a HEAD request for a manifest with basic auth or a
+// bearer token
+func manifestRequest(u string, username string, password string, token string) (*http.Response, error) {
+	req, err := http.NewRequest("HEAD", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", manifestMediaTypes)
+	switch {
+	case token != "":
+		req.Header.Set("Authorization", "Bearer "+token)
+	case username != "":
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := registryClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// registryToken gets a bearer token from the auth server of a registry
+// challenge.  The realm must be served over https as the registry
+// credentials are sent to it.
+func registryToken(challenge map[string]string, username string, password string) (string, error) {
+	realm, err := url.Parse(challenge["realm"])
+	if err != nil {
+		return "", fmt.Errorf("invalid registry auth realm: %s", err)
+	}
+	if realm.Scheme != "https" || realm.Host == "" {
+		return "", fmt.Errorf("registry auth realm %s is not an https url", challenge["realm"])
+	}
+	v := url.Values{}
+	for _, k := range []string{"service", "scope"} {
+		if challenge[k] != "" {
+			v.Set(k, challenge[k])
+		}
+	}
+	req, err := http.NewRequest("GET", challenge["realm"]+"?"+v.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := registryClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry auth server returned status %d", resp.StatusCode)
+	}
+	var t struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", err
+	}
+	if t.Token != "" {
+		return t.Token, nil
+	}
+	if t.AccessToken == "" {
+		return "", fmt.Errorf("registry auth server returned no token")
+	}
+	return t.AccessToken, nil
+}
@@ -0,0 +1,133 @@
+package manager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shipyard/shipyard"
+)
+
+// newTestRegistry returns a registry answering manifest requests for
+// team/web:1.0 with the digest returned by digest
+func newTestRegistry(t *testing.T, m *Manager, digest func() string) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/team/web/manifests/1.0" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", digest())
+	}))
+	t.Cleanup(srv.Close)
+	if err := m.AddRegistry(&shipyard.Registry{Name: "local", Addr: srv.URL}); err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+// waitDeployed waits for the deployments of an application to finish and
+// returns the application
+func waitDeployed(t *testing.T, m *Manager, name string) *shipyard.Application {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deployments, err := m.Deployments(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := true
+		for _, d := range deployments {
+			if !d.Done() {
+				done = false
+			}
+		}
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for application %s to be deployed", name)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	app, err := m.Application(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func TestCheckImages(t *testing.T) {
+	m := newTestManager(t)
+	digest := "sha256:aaa"
+	host := newTestRegistry(t, m, func() string { return digest })
+	image := host + "/team/web:1.0"
+	if err := m.CreateApplication(&shipyard.Application{Name: "web", Image: image, AutoDeploy: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the first digest seen is only recorded
+	if err := m.CheckImages(); err != nil {
+		t.Fatal(err)
+	}
+	if deployments, _ := m.Deployments("web"); len(deployments) != 0 {
+		t.Fatalf("expected no deployment of the first digest; received %d", len(deployments))
+	}
+	if err := m.CheckImages(); err != nil {
+		t.Fatal(err)
+	}
+	if deployments, _ := m.Deployments("web"); len(deployments) != 0 {
+		t.Fatalf("expected no deployment of an unchanged digest; received %d", len(deployments))
+	}
+
+	// a move deploys the tag pinned to the new digest
+	digest = "sha256:bbb"
+	if err := m.CheckImages(); err != nil {
+		t.Fatal(err)
+	}
+	app := waitDeployed(t, m, "web")
+	if expected := image + "@sha256:bbb"; app.Image != expected {
+		t.Fatalf("expected image %s; received %s", expected, app.Image)
+	}
+	deployments, err := m.Deployments("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deployed *shipyard.Deployment
+	for _, d := range deployments {
+		if d.PreviousImage != "" {
+			deployed = d
+		}
+	}
+	if deployed == nil || deployed.Image != app.Image || deployed.PreviousImage != image {
+		t.Fatalf("expected a deployment from %s to %s; received %+v", image, app.Image, deployed)
+	}
+
+	// a webhook delivered again does not deploy again
+	if err := m.ImagePushed(host+"/team/web", "1.0"); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := m.Deployments("web"); len(d) != len(deployments) {
+		t.Errorf("expected %d deployments; received %d", len(deployments), len(d))
+	}
+	if err := m.ImagePushed(host+"/team/web", ""); err != ErrImageTagRequired {
+		t.Errorf("expected %v; received %v", ErrImageTagRequired, err)
+	}
+
+	// a push of the next digest deploys it
+	digest = "sha256:ccc"
+	if err := m.ImagePushed(host+"/team/web", "1.0"); err != nil {
+		t.Fatal(err)
+	}
+	if app := waitDeployed(t, m, "web"); app.Image != image+"@sha256:ccc" {
+		t.Errorf("expected image %s@sha256:ccc; received %s", image, app.Image)
+	}
+}
+
+func TestRegistryToken(t *testing.T) {
+	for _, realm := range []string{"http://auth.example.com/token", "auth.example.com/token", "file:///etc/passwd"} {
+		if _, err := registryToken(map[string]string{"realm": realm}, "user", "secret"); err == nil {
+			t.Errorf("%s: expected an error", realm)
+		}
+	}
+}
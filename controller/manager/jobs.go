@@ -0,0 +1,324 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameJobs    = "jobs"
+	tblNameJobRuns = "job_runs"
+
+	jobInterval = 15 * time.Second
+)
+
+var (
+	ErrJobDoesNotExist    = errors.New("job does not exist")
+	ErrJobRunDoesNotExist = errors.New("job run does not exist")
+)
+
+func (m *Manager) Jobs() ([]*shipyard.Job, error) {
+	jobs := []*shipyard.Job{}
+	if err := m.db.Find(tblNameJobs, ds.Where().Sort("created", false), &jobs); err != nil {
+		return nil, err
+	}
+	for _, j := range jobs {
+		setNextRun(j)
+	}
+	return jobs, nil
+}
+
+func (m *Manager) Job(id string) (*shipyard.Job, error) {
+	var job *shipyard.Job
+	if err := m.db.Get(tblNameJobs, id, &job); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrJobDoesNotExist
+		}
+		return nil, err
+	}
+	setNextRun(job)
+	return job, nil
+}
+
+// CreateJob stores a job; its containers are launched by the scheduler at
+// the next time matching its schedule
+func (m *Manager) CreateJob(job *shipyard.Job) error {
+	if err := job.Validate(); err != nil {
+		return err
+	}
+	job.ID = ""
+	job.Created = time.Now()
+	job.LastRun = time.Time{}
+	id, err := m.db.Insert(tblNameJobs, job)
+	if err != nil {
+		return err
+	}
+	job.ID = id
+	setNextRun(job)
+	evt := &shipyard.Event{
+		Type:    "create-job",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("id=%s schedule=%q image=%s count=%d", job.ID, job.Schedule, job.Image.Name, job.Count),
+		Tags:    []string{"job"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RemoveJob deletes a job and its run history along with the exited
+// containers of its runs.  Running containers are left to finish.
+func (m *Manager) RemoveJob(id string) error {
+	job, err := m.Job(id)
+	if err != nil {
+		return err
+	}
+	runs, err := m.JobRuns(job.ID)
+	if err != nil {
+		return err
+	}
+	if _, err := m.db.Delete(tblNameJobs, ds.ByID(job.ID)); err != nil {
+		return err
+	}
+	if _, err := m.db.Delete(tblNameJobRuns, ds.Where(ds.Eq("job_id", job.ID))); err != nil {
+		return err
+	}
+	for _, run := range runs {
+		m.removeJobRunContainers(run)
+	}
+	evt := &shipyard.Event{
+		Type:    "remove-job",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("id=%s", job.ID),
+		Tags:    []string{"job"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// JobRuns returns the run history of a job, newest first
+func (m *Manager) JobRuns(jobID string) ([]*shipyard.JobRun, error) {
+	if _, err := m.Job(jobID); err != nil {
+		return nil, err
+	}
+	runs := []*shipyard.JobRun{}
+	if err := m.db.Find(tblNameJobRuns, ds.Where(ds.Eq("job_id", jobID)).Sort("scheduled", true), &runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+func (m *Manager) jobRun(id string) (*shipyard.JobRun, error) {
+	var run *shipyard.JobRun
+	if err := m.db.Get(tblNameJobRuns, id, &run); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrJobRunDoesNotExist
+		}
+		return nil, err
+	}
+	return run, nil
+}
+
+func setNextRun(job *shipyard.Job) {
+	sched, err := shipyard.ParseCronSchedule(job.Schedule)
+	if err != nil {
+		return
+	}
+	job.NextRun = sched.Next(lastScheduled(job))
+}
+
+// lastScheduled is the time the schedule of a job is continued from
+func lastScheduled(job *shipyard.Job) time.Time {
+	if job.LastRun.IsZero() {
+		return job.Created
+	}
+	return job.LastRun
+}
+
+// scheduleJobs launches the runs of jobs as they become due.  Runs missed
+// while the controller was down are collapsed into a single run.
+func (m *Manager) scheduleJobs() {
+	t := time.NewTicker(jobInterval).C
+	for range t {
+		if !m.IsLeader() {
+			continue
+		}
+		jobs, err := m.Jobs()
+		if err != nil {
+			logger.Errorf("error getting jobs: %s", err)
+			continue
+		}
+		now := time.Now()
+		for _, job := range jobs {
+			if job.NextRun.IsZero() || job.NextRun.After(now) {
+				continue
+			}
+			scheduled := job.NextRun
+			if _, err := m.db.Update(tblNameJobs, ds.ByID(job.ID), map[string]interface{}{"last_run": now}); err != nil {
+				logger.Errorf("error saving job %s: %s", job.ID, err)
+				continue
+			}
+			go m.runJob(job, scheduled)
+		}
+	}
+}
+
+// runJob launches the containers of one job run.  The exited containers of
+// the previous run are removed first so only the latest run's containers
+// are kept for inspecting their logs.
+func (m *Manager) runJob(job *shipyard.Job, scheduled time.Time) {
+	runs, err := m.JobRuns(job.ID)
+	if err != nil {
+		logger.Errorf("error getting runs of job %s: %s", job.ID, err)
+		return
+	}
+	if len(runs) > 0 {
+		m.removeJobRunContainers(runs[0])
+	}
+
+	run := &shipyard.JobRun{
+		JobID:     job.ID,
+		Scheduled: scheduled,
+		Started:   time.Now(),
+		Status:    shipyard.JobRunRunning,
+		ExitCodes: map[string]int{},
+	}
+	id, err := m.db.Insert(tblNameJobRuns, run)
+	if err != nil {
+		logger.Errorf("error saving run of job %s: %s", job.ID, err)
+		return
+	}
+	run.ID = id
+
+	img := *job.Image
+	img.Environment = map[string]string{}
+	for k, v := range job.Image.Environment {
+		img.Environment[k] = v
+	}
+	img.Environment[shipyard.JobRunEnv] = run.ID
+	if img.Type == "" {
+		img.Type = "service"
+	}
+	var launched []*citadel.Container
+	runErr := m.CheckQuota(img.Environment[shipyard.OwnerEnv], img.Environment[shipyard.NamespaceEnv], &img, job.Count)
+	if runErr == nil {
+		launched, runErr = m.Run(&img, job.Count, true)
+	}
+
+	m.jobLock.Lock()
+	defer m.jobLock.Unlock()
+	// containers may already have exited and been recorded
+	if current, err := m.jobRun(run.ID); err == nil {
+		run = current
+	}
+	for _, c := range launched {
+		if c != nil {
+			run.Containers = append(run.Containers, c.ID)
+		}
+	}
+	if runErr != nil {
+		run.Status = shipyard.JobRunFailed
+		run.Error = runErr.Error()
+		run.Finished = time.Now()
+	}
+	m.finishJobRun(run)
+	if err := m.saveJobRun(run); err != nil {
+		logger.Errorf("error saving run of job %s: %s", job.ID, err)
+	}
+	logger.Infof("started job %s run %s: %d containers", job.ID, run.ID, len(run.Containers))
+	evt := &shipyard.Event{
+		Type:    "job-run",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("job=%s run=%s image=%s containers=%d status=%s", job.ID, run.ID, job.Image.Name, len(run.Containers), run.Status),
+		Tags:    []string{"job"},
+	}
+	if runErr != nil {
+		evt.Severity = shipyard.SeverityWarning
+		evt.Message = fmt.Sprintf("%s error=%s", evt.Message, runErr)
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		logger.Errorf("error saving job event: %s", err)
+	}
+}
+
+// handleJobExit records the exit code of a job container and finishes its
+// run once all of its containers have exited
+func (m *Manager) handleJobExit(c *citadel.Container) {
+	id := shipyard.JobRunID(c)
+	if id == "" {
+		return
+	}
+	client, err := m.DockerClient(c.Engine)
+	if err != nil {
+		logger.Warnf("error inspecting job container %s: %s", c.ID[:12], err)
+		return
+	}
+	info, err := client.InspectContainer(c.ID)
+	if err != nil {
+		logger.Warnf("error inspecting job container %s: %s", c.ID[:12], err)
+		return
+	}
+
+	m.jobLock.Lock()
+	defer m.jobLock.Unlock()
+	run, err := m.jobRun(id)
+	if err != nil {
+		// the job was removed
+		return
+	}
+	if run.ExitCodes == nil {
+		run.ExitCodes = map[string]int{}
+	}
+	run.ExitCodes[c.ID] = info.State.ExitCode
+	m.finishJobRun(run)
+	if err := m.saveJobRun(run); err != nil {
+		logger.Errorf("error saving job run %s: %s", run.ID, err)
+	}
+}
+
+// finishJobRun sets the status of a run whose containers have all exited
+func (m *Manager) finishJobRun(run *shipyard.JobRun) {
+	if run.Status != shipyard.JobRunRunning || len(run.Containers) == 0 {
+		return
+	}
+	for _, id := range run.Containers {
+		if _, ok := run.ExitCodes[id]; !ok {
+			return
+		}
+	}
+	run.Status = shipyard.JobRunSucceeded
+	for _, code := range run.ExitCodes {
+		if code != 0 {
+			run.Status = shipyard.JobRunFailed
+		}
+	}
+	run.Finished = time.Now()
+}
+
+func (m *Manager) saveJobRun(run *shipyard.JobRun) error {
+	if err := m.db.Put(tblNameJobRuns, run); err != nil {
+		return err
+	}
+	return nil
+}
+
+// removeJobRunContainers removes the exited containers of a run
+func (m *Manager) removeJobRunContainers(run *shipyard.JobRun) {
+	for _, id := range run.Containers {
+		c, err := m.Container(id)
+		if err != nil || c == nil || c.State == "running" {
+			continue
+		}
+		if err := m.ClusterManager().Remove(c); err != nil {
+			logger.Warnf("error removing job container %s: %s", id[:12], err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameJoinTokens = "join_tokens"
+)
+
+var (
+	ErrJoinTokenDoesNotExist = errors.New("join token does not exist")
+	ErrInvalidJoinTokenTTL   = errors.New("join token lifetime must not be negative")
+	ErrEngineExists          = errors.New("engine already exists")
+)
+
+// CreateJoinToken returns a token a docker host can register itself with
+// once.  Engines joining with the token get its labels.
+func (m *Manager) CreateJoinToken(ttl time.Duration, labels []string, createdBy string) (*shipyard.JoinToken, error) {
+	if ttl < 0 {
+		return nil, ErrInvalidJoinTokenTTL
+	}
+	token, err := shipyard.NewJoinToken()
+	if err != nil {
+		return nil, err
+	}
+	t := &shipyard.JoinToken{
+		Hash:      shipyard.HashJoinToken(token),
+		Labels:    labels,
+		CreatedBy: createdBy,
+		Created:   time.Now(),
+	}
+	if ttl > 0 {
+		t.ExpiresAt = t.Created.Add(ttl)
+	}
+	id, err := m.db.Insert(tblNameJoinTokens, t)
+	if err != nil {
+		return nil, err
+	}
+	t.ID = id
+	evt := &shipyard.Event{
+		Type:    "create-join-token",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("id=%s labels=%s", t.ID, strings.Join(labels, ",")),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return nil, err
+	}
+	t.Token = token
+	return t, nil
+}
+
+// JoinTokens returns the unused join tokens without their token
+func (m *Manager) JoinTokens() ([]*shipyard.JoinToken, error) {
+	tokens := []*shipyard.JoinToken{}
+	if err := m.db.Find(tblNameJoinTokens, ds.Where().Sort("created", false), &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (m *Manager) DeleteJoinToken(id string) error {
+	n, err := m.db.Delete(tblNameJoinTokens, ds.ByID(id))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrJoinTokenDoesNotExist
+	}
+	evt := &shipyard.Event{
+		Type:    "delete-join-token",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("id=%s", id),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// JoinEngine registers the docker host of a join request.  The token is
+// consumed by the request and restored if the engine cannot be added.
+func (m *Manager) JoinEngine(req *shipyard.JoinRequest) (*shipyard.Engine, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	for _, e := range m.Engines() {
+		if e.Engine.ID == req.ID {
+			return nil, ErrEngineExists
+		}
+	}
+	token, err := m.consumeJoinToken(req.Token)
+	if err != nil {
+		return nil, err
+	}
+	engine := req.Engine(token)
+	if err := m.AddEngine(engine); err != nil {
+		if _, rerr := m.db.Insert(tblNameJoinTokens, token); rerr != nil {
+			logger.Errorf("error restoring join token %s: %s", token.ID, rerr)
+		}
+		return nil, err
+	}
+	evt := &shipyard.Event{
+		Type:    "join-engine",
+		Time:    time.Now(),
+		Engine:  engine.Engine,
+		Message: fmt.Sprintf("addr=%s token=%s", engine.Engine.Addr, token.ID),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+// consumeJoinToken deletes and returns an unexpired join token.  Only the
+// request deleting the token may use it so a token registers one engine.
+func (m *Manager) consumeJoinToken(token string) (*shipyard.JoinToken, error) {
+	var t *shipyard.JoinToken
+	if err := m.db.FindOne(tblNameJoinTokens, ds.Where(ds.Eq("hash", shipyard.HashJoinToken(token))), &t); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, shipyard.ErrInvalidJoinToken
+		}
+		return nil, err
+	}
+	if t.Expired(time.Now()) {
+		return nil, shipyard.ErrInvalidJoinToken
+	}
+	n, err := m.db.Delete(tblNameJoinTokens, ds.ByID(t.ID))
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, shipyard.ErrInvalidJoinToken
+	}
+	return t, nil
+}
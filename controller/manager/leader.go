@@ -0,0 +1,229 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameLeases = "leases"
+	leaderLeaseID = "leader"
+	// stopMarkerPrefix prefixes the lease ids marking containers stopped
+	// through the api of any controller; see expectStop
+	stopMarkerPrefix = "stop:"
+	stopMarkerTTL    = time.Minute
+
+	// DefaultLeaderLease is how long a leader keeps its lease without
+	// renewing it
+	DefaultLeaderLease = 15 * time.Second
+	engineSyncInterval = 10 * time.Second
+	// eventFeedRetry is the delay before reopening a failed event feed
+	eventFeedRetry = 5 * time.Second
+)
+
+// EnableHA runs the controller as one of several instances sharing the
+// datastore.  The instances elect a leader holding a lease of ttl that
+// runs the background loops: engine health checks, supervision,
+// reconciliation, health checks, jobs, garbage collection and the handling
+// of docker events.  Every instance serves the api; events are shared
+// through a changefeed and engine changes are picked up from the datastore.
+func (m *Manager) EnableHA(instance string, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultLeaderLease
+	}
+	m.leaderLock.Lock()
+	m.ha = true
+	m.instance = instance
+	m.leaderLease = ttl
+	m.leaderLock.Unlock()
+	if err := m.failInterruptedDeployments(instance); err != nil {
+		logger.Errorf("error failing interrupted deployments: %s", err)
+	}
+	go m.elect()
+	go m.followEvents()
+	go m.syncEngines()
+}
+
+// IsLeader reports whether this controller runs the background loops;
+// controllers not in ha mode always do
+func (m *Manager) IsLeader() bool {
+	m.leaderLock.Lock()
+	defer m.leaderLock.Unlock()
+	return !m.ha || time.Now().Before(m.leaderUntil)
+}
+
+// Leadership returns the id of this controller and of the current leader;
+// both are empty when ha is not enabled
+func (m *Manager) Leadership() (string, string) {
+	m.leaderLock.Lock()
+	defer m.leaderLock.Unlock()
+	return m.instance, m.leader
+}
+
+func (m *Manager) haEnabled() bool {
+	m.leaderLock.Lock()
+	defer m.leaderLock.Unlock()
+	return m.ha
+}
+
+// elect takes or renews the leader lease at a third of its ttl; the leader
+// also removes the expired stop marks
+func (m *Manager) elect() {
+	m.leaderLock.Lock()
+	ttl := m.leaderLease
+	m.leaderLock.Unlock()
+	m.acquireLeadership(ttl)
+	t := time.NewTicker(ttl / 3).C
+	for range t {
+		m.acquireLeadership(ttl)
+		if m.IsLeader() {
+			m.expireStopMarkers()
+		}
+	}
+}
+
+func (m *Manager) acquireLeadership(ttl time.Duration) {
+	started := time.Now()
+	holder, err := m.acquireLease(leaderLeaseID, ttl)
+	if err != nil {
+		logger.Warnf("error acquiring leader lease: %s", err)
+		return
+	}
+	m.leaderLock.Lock()
+	was := time.Now().Before(m.leaderUntil)
+	m.leader = holder
+	if holder == m.instance {
+		// measured from before the request so the lease ends here
+		// before it can be taken elsewhere
+		m.leaderUntil = started.Add(ttl)
+	} else {
+		m.leaderUntil = time.Time{}
+	}
+	is := holder == m.instance
+	m.leaderLock.Unlock()
+	if is != was {
+		typ := "leader-lost"
+		if is {
+			typ = "leader-elected"
+		}
+		logger.Infof("controller %s: %s (leader %s)", m.instance, typ, holder)
+		m.SaveEvent(&shipyard.Event{
+			Type:    typ,
+			Message: "instance=" + m.instance,
+			Time:    time.Now(),
+			Tags:    []string{"cluster"},
+		})
+	}
+}
+
+// acquireLease takes the lease with id if it is free or expired, or
+// renews it when this controller holds it, and returns the holder of the
+// lease afterwards.  Expiry uses the datastore clock so controller clocks
+// do not need to agree.
+func (m *Manager) acquireLease(id string, ttl time.Duration) (string, error) {
+	return m.db.Lease(tblNameLeases, id, m.instance, ttl)
+}
+
+// markStopping records a container stopped through the api so the leader
+// does not replace it when it exits
+func (m *Manager) markStopping(id string) {
+	if _, err := m.acquireLease(stopMarkerPrefix+id, stopMarkerTTL); err != nil {
+		logger.Warnf("error marking container %s as stopping: %s", id, err)
+	}
+}
+
+// takeStopMarker reports whether any controller marked the container as
+// stopping and removes the mark; expired marks are not counted
+func (m *Manager) takeStopMarker(id string) bool {
+	n, err := m.db.Delete(tblNameLeases, ds.Where(ds.Eq("id", stopMarkerPrefix+id), ds.Gt("expires", time.Now())))
+	if err != nil {
+		logger.Warnf("error checking stop mark of container %s: %s", id, err)
+		return false
+	}
+	return n > 0
+}
+
+// expireStopMarkers removes the stop marks of containers that did not exit
+// before the marks expired, such as when stopping them failed
+func (m *Manager) expireStopMarkers() {
+	if _, err := m.db.Delete(tblNameLeases, ds.Where(ds.Prefix("id", stopMarkerPrefix), ds.Lt("expires", time.Now()))); err != nil {
+		logger.Warnf("error expiring stop marks: %s", err)
+	}
+}
+
+// followEvents publishes the events saved by every controller to the
+// subscribers of this one
+func (m *Manager) followEvents() {
+	for {
+		feed, err := m.db.Changes(tblNameEvents)
+		if err == ds.ErrNotSupported {
+			logger.Warn("datastore has no changefeed; events of other controllers are not published")
+			return
+		} else if err != nil {
+			logger.Warnf("error following events: %s", err)
+			time.Sleep(eventFeedRetry)
+			continue
+		}
+		for {
+			var evt *shipyard.Event
+			if !feed.Next(&evt) {
+				break
+			}
+			m.publishEvent(evt)
+		}
+		if err := feed.Err(); err != nil {
+			logger.Warnf("event feed closed: %s", err)
+		}
+		feed.Close()
+		time.Sleep(eventFeedRetry)
+	}
+}
+
+// syncEngines picks up engines added, removed or changed by other
+// controllers
+func (m *Manager) syncEngines() {
+	t := time.NewTicker(engineSyncInterval).C
+	for range t {
+		if err := m.refreshEngines(); err != nil {
+			logger.Warnf("error syncing engines: %s", err)
+		}
+	}
+}
+
+// refreshEngines updates the engines from the datastore and reloads the
+// cluster when engines were added, removed or moved
+func (m *Manager) refreshEngines() error {
+	stored := []*shipyard.Engine{}
+	if err := m.db.Find(tblNameConfig, nil, &stored); err != nil {
+		return err
+	}
+	leader := m.IsLeader()
+	m.enginesLock.Lock()
+	current := map[string]*shipyard.Engine{}
+	for _, e := range m.engines {
+		current[e.ID] = e
+	}
+	changed := len(stored) != len(current)
+	for _, s := range stored {
+		e, ok := current[s.ID]
+		if !ok || e.Engine.Addr != s.Engine.Addr {
+			changed = true
+			break
+		}
+		e.Cordoned = s.Cordoned
+		e.Engine.Labels = s.Engine.Labels
+		if !leader {
+			// the leader checks engine health itself
+			e.Health = s.Health
+			e.DockerVersion = s.DockerVersion
+		}
+	}
+	m.enginesLock.Unlock()
+	if changed {
+		logger.Info("engines changed; reloading cluster")
+		m.init()
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+// newTestControllers returns managers in ha mode sharing a datastore
+func newTestControllers(t *testing.T, instances ...string) []*Manager {
+	managers := []*Manager{}
+	for _, instance := range instances {
+		m := newTestManager(t)
+		if len(managers) > 0 {
+			m.db = managers[0].db
+		}
+		m.ha = true
+		m.instance = instance
+		managers = append(managers, m)
+	}
+	return managers
+}
+
+func TestLeaderElection(t *testing.T) {
+	ttl := time.Second
+	ms := newTestControllers(t, "a", "b")
+	a, b := ms[0], ms[1]
+
+	a.acquireLeadership(ttl)
+	b.acquireLeadership(ttl)
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatalf("expected only the first controller to lead; a=%v b=%v", a.IsLeader(), b.IsLeader())
+	}
+	if _, leader := b.Leadership(); leader != "a" {
+		t.Errorf("expected b to follow a; received %q", leader)
+	}
+
+	// a stops renewing its lease
+	time.Sleep(ttl + 100*time.Millisecond)
+	b.acquireLeadership(ttl)
+	if !b.IsLeader() {
+		t.Fatal("expected b to take the expired lease")
+	}
+	a.acquireLeadership(ttl)
+	if a.IsLeader() {
+		t.Error("expected a to lose the lease")
+	}
+	events, err := b.Events(&shipyard.EventQuery{EventFilter: shipyard.EventFilter{Types: []string{"leader-elected"}}})
+	if err != nil || len(events) != 2 {
+		t.Errorf("expected a leader-elected event per controller; received %v %v", events, err)
+	}
+}
+
+func TestStopMarkers(t *testing.T) {
+	ms := newTestControllers(t, "a", "b")
+	a, b := ms[0], ms[1]
+	c := &citadel.Container{ID: "0123456789abcdef"}
+
+	a.expectStop(c)
+	if !b.stopExpected(c.ID) {
+		t.Fatal("expected the leader to see a container stopped by another controller")
+	}
+	if b.stopExpected(c.ID) {
+		t.Error("expected the stop mark to be taken once")
+	}
+
+	stale := &citadel.Container{ID: "fedcba9876543210"}
+	if _, err := a.db.Lease(tblNameLeases, stopMarkerPrefix+stale.ID, "a", -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if b.stopExpected(stale.ID) {
+		t.Error("expected an expired stop mark to be ignored")
+	}
+	b.expireStopMarkers()
+	if n, err := b.db.Count(tblNameLeases, ds.Where(ds.Prefix("id", stopMarkerPrefix))); err != nil || n != 0 {
+		t.Errorf("expected the expired stop marks to be removed; %d left %v", n, err)
+	}
+}
+
+func TestRefreshEngines(t *testing.T) {
+	eng := &shipyard.Engine{ID: "e1", Engine: &citadel.Engine{ID: "local", Addr: "http://127.0.0.1:2375"}}
+	m := newTestControllers(t, "a")[0]
+	m.engines = []*shipyard.Engine{eng}
+
+	stored := *eng
+	stored.Engine = &citadel.Engine{ID: "local", Addr: eng.Engine.Addr, Labels: []string{"zone=a"}}
+	stored.Cordoned = true
+	if _, err := m.db.Insert(tblNameConfig, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.refreshEngines(); err != nil {
+		t.Fatal(err)
+	}
+	e := m.Engine("e1")
+	if e == nil || !e.Cordoned || len(e.Engine.Labels) != 1 {
+		t.Errorf("expected the engine changes of another controller; received %+v", e)
+	}
+}
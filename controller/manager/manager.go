@@ -2,7 +2,6 @@ package manager
 
 import (
 	"bytes"
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,9 +16,10 @@ import (
 	"github.com/citadel/citadel"
 	"github.com/citadel/citadel/cluster"
 	"github.com/citadel/citadel/scheduler"
-	r "github.com/dancannon/gorethink"
 	"github.com/gorilla/sessions"
+	"github.com/samalba/dockerclient"
 	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
 	"github.com/shipyard/shipyard/dockerhub"
 )
 
@@ -35,62 +35,165 @@ const (
 	trackerHost        = "http://tracker.shipyard-project.com"
 	EngineHealthUp     = "up"
 	EngineHealthDown   = "down"
+	// EngineHealthUnreachable is reported when the engine cannot be
+	// contacted at all; down means it answered with an error
+	EngineHealthUnreachable = "unreachable"
 )
 
 var (
 	ErrAccountExists          = errors.New("account already exists")
 	ErrAccountDoesNotExist    = errors.New("account does not exist")
 	ErrRoleDoesNotExist       = errors.New("role does not exist")
+	ErrRoleExists             = errors.New("role already exists")
+	ErrRoleNameRequired       = errors.New("role name is required")
 	ErrServiceKeyDoesNotExist = errors.New("service key does not exist")
+	ErrInvalidServiceKeyTTL   = errors.New("service key lifetime must not be negative")
 	ErrInvalidAuthToken       = errors.New("invalid auth token")
 	ErrExtensionDoesNotExist  = errors.New("extension does not exist")
 	ErrWebhookKeyDoesNotExist = errors.New("webhook key does not exist")
+	ErrEngineNotConnected     = errors.New("engine is not connected")
+	ErrEngineDoesNotExist     = errors.New("engine does not exist")
+	ErrInvalidEngine          = errors.New("invalid engine")
+	ErrUnknownImageType       = errors.New("unknown image type")
+	ErrInvalidScaleCount      = errors.New("count must not be negative")
 	logger                    = logrus.New()
 	store                     = sessions.NewCookieStore([]byte(storeKey))
 )
 
 type (
 	Manager struct {
-		address          string
-		database         string
-		authKey          string
-		session          *r.Session
-		clusterManager   *cluster.Cluster
+		db             ds.Store
+		clusterManager *cluster.Cluster
+		// schedulers are the schedulers registered with the cluster by
+		// image type
+		schedulers map[string]citadel.Scheduler
+		// enginesLock guards engines; see Engines
+		enginesLock      sync.RWMutex
 		engines          []*shipyard.Engine
+		dockerClients    map[string]*dockerclient.DockerClient
 		authenticator    *shipyard.Authenticator
 		store            *sessions.CookieStore
 		StoreKey         string
 		version          string
 		disableUsageInfo bool
+		subscribersLock  sync.Mutex
+		subscribers      map[chan *shipyard.Event]*shipyard.EventFilter
+		externalAuth     Authenticator
+		appLock          sync.Mutex
+		supervisorLock   sync.Mutex
+		// supervised are the reschedulable containers by engine id
+		supervised map[string][]*citadel.Container
+		// stopping are the ids of containers stopped through the api
+		stopping map[string]bool
+		// rescheduled are the ids of replaced containers by engine id
+		rescheduled map[string][]string
+		// stranded are the ids of containers of failed engines waiting for
+		// the engine holding their volumes by engine id
+		stranded map[string][]string
+		// deploying are the applications being deployed
+		deploying  map[string]bool
+		healthLock sync.Mutex
+		// health is the health of containers with a health check by id
+		health  map[string]*containerHealth
+		jobLock sync.Mutex
+		// gcLock serializes garbage collections
+		gcLock sync.Mutex
+		// retentionLock serializes event retention runs
+		retentionLock sync.Mutex
+		// placement is the default placement strategy; see SetPlacement
+		placement string
+		// restartsLock guards restarts, the cached restarts of containers
+		// by id loaded at restartsLoaded; see applyContainerRestarts
+		restartsLock   sync.Mutex
+		restarts       map[string]*shipyard.ContainerRestarts
+		restartsLoaded time.Time
+		// metadataLock guards metadata, the cached metadata of containers
+		// by id loaded at metadataLoaded; see applyContainerMetadata
+		metadataLock   sync.Mutex
+		metadata       map[string]*shipyard.ContainerMetadata
+		metadataLoaded time.Time
+		// poolsLock guards pools, the cached engine pools by name loaded
+		// at poolsLoaded; see schedulingPool
+		poolsLock   sync.Mutex
+		pools       map[string]*shipyard.EnginePool
+		poolsLoaded time.Time
+		// runningLock guards running, the running containers as last
+		// listed, which placement reads while the cluster is locked
+		runningLock sync.Mutex
+		running     []*citadel.Container
+		// rebalanceLock serializes rebalances
+		rebalanceLock sync.Mutex
+		// secretKey encrypts secrets; see SetSecretKey
+		secretKey []byte
+		// leaderLock guards the ha state; see EnableHA
+		leaderLock  sync.Mutex
+		ha          bool
+		instance    string
+		leader      string
+		leaderLease time.Duration
+		// leaderUntil is when the lease held by this controller ends
+		leaderUntil time.Time
+		metricsLock sync.Mutex
+		// latestSamples are the container samples of the last sampling
+		latestSamples []*shipyard.MetricSample
+		// containerMetricsInterval is how often container samples are
+		// stored and containerSamplesSaved when they last were
+		containerMetricsInterval time.Duration
+		containerSamplesSaved    time.Time
+		// cadvisorReadings are the last cAdvisor usage of containers by id
+		cadvisorReadings map[string]*cadvisorReading
+	}
+
+	// Authenticator verifies credentials against an external account
+	// store such as a directory server
+	Authenticator interface {
+		// Authenticate returns the role for valid credentials,
+		// shipyard.ErrUnknownUser if the store has no such user and
+		// shipyard.ErrUnauthorized for invalid credentials
+		Authenticate(username string, password string) (string, error)
 	}
 )
 
-func NewManager(addr string, database string, authKey string, version string, disableUsageInfo bool) (*Manager, error) {
-	session, err := r.Connect(r.ConnectOpts{
-		Address:     addr,
-		Database:    database,
-		AuthKey:     authKey,
-		MaxIdle:     10,
-		IdleTimeout: time.Second * 30,
-	})
-	if err != nil {
-		return nil, err
-	}
-	logger.Info("checking database")
-	r.DbCreate(database).Run(session)
+// NewManager returns a manager keeping its state in db
+func NewManager(db ds.Store, version string, disableUsageInfo bool) (*Manager, error) {
 	m := &Manager{
-		address:          addr,
-		database:         database,
-		authKey:          authKey,
-		session:          session,
+		db:               db,
 		authenticator:    &shipyard.Authenticator{},
 		store:            store,
 		StoreKey:         storeKey,
 		version:          version,
 		disableUsageInfo: disableUsageInfo,
+		subscribers:      make(map[chan *shipyard.Event]*shipyard.EventFilter),
+		supervised:       make(map[string][]*citadel.Container),
+		stopping:         make(map[string]bool),
+		rescheduled:      make(map[string][]string),
+		stranded:         make(map[string][]string),
+		deploying:        make(map[string]bool),
+		health:           make(map[string]*containerHealth),
+		cadvisorReadings: make(map[string]*cadvisorReading),
+	}
+	logger.Info("checking database")
+	if err := m.initdb(); err != nil {
+		return nil, err
 	}
-	m.initdb()
 	m.init()
+	if err := m.failInterruptedDeployments(""); err != nil {
+		logger.Errorf("error failing interrupted deployments: %s", err)
+	}
+	go m.dispatchWebhooks()
+	go m.dispatchNotifications()
+	go m.reconcileApplications()
+	go m.supervise()
+	go m.checkContainerHealth()
+	go m.scheduleJobs()
+	go m.collectGarbage()
+	go m.expireEvents()
+	go m.expireIdempotencyKeys()
+	go m.evaluateAlerts()
+	go m.watchImages()
+	go m.extensionHealthCheck()
+	go m.engineCheck()
+	go m.usageReport()
 	return m, nil
 }
 
@@ -102,57 +205,63 @@ func (m *Manager) Store() *sessions.CookieStore {
 	return m.store
 }
 
-func (m *Manager) initdb() {
+func (m *Manager) initdb() error {
 	// create tables if needed
-	tables := []string{tblNameConfig, tblNameEvents, tblNameAccounts, tblNameRoles, tblNameServiceKeys, tblNameExtensions, tblNameWebhookKeys}
-	for _, tbl := range tables {
-		_, err := r.Table(tbl).Run(m.session)
-		if err != nil {
-			if _, err := r.Db(m.database).TableCreate(tbl).Run(m.session); err != nil {
-				logger.Fatalf("error creating table: %s", err)
-			}
-		}
+	if err := m.db.Init([]string{tblNameLeases, tblNameConfig, tblNameEvents, tblNameAccounts, tblNameRoles, tblNameServiceKeys, tblNameExtensions, tblNameWebhookKeys, tblNameRegistries, tblNameAudit, tblNameWebhooks, tblNameNotifiers, tblNameApplications, tblNameDeployments, tblNameJobs, tblNameJobRuns, tblNameSecrets, tblNameConfigBundles, tblNameVolumes, tblNameQuotas, tblNameNamespaces, tblNameContainerMetadata, tblNameSettings, tblNameJoinTokens, tblNameEnginePools, tblNameMetrics, tblNameAlertRules, tblNameAlerts, tblNameContainerRestarts, tblNameTemplates, tblNameImageWatches, tblNameIdempotencyKeys}); err != nil {
+		return err
 	}
+	// events and audit entries are sorted by time for listing, exports
+	// and retention
+	if err := m.db.Index(tblNameEvents, "Time"); err != nil {
+		return err
+	}
+	return m.db.Index(tblNameAudit, "time")
 }
 
 func (m *Manager) init() []*shipyard.Engine {
 	engines := []*shipyard.Engine{}
-	res, err := r.Table(tblNameConfig).Run(m.session)
-	if err != nil {
-		logger.Fatalf("error getting configuration: %s", err)
-	}
-	if err := res.All(&engines); err != nil {
+	if err := m.db.Find(tblNameConfig, nil, &engines); err != nil {
 		logger.Fatalf("error loading configuration: %s", err)
 	}
+	m.enginesLock.Lock()
 	m.engines = engines
+	m.enginesLock.Unlock()
+	dockerClients := make(map[string]*dockerclient.DockerClient)
 	var engs []*citadel.Engine
 	for _, d := range engines {
-		tlsConfig := &tls.Config{}
-		if d.CACertificate != "" && d.SSLCertificate != "" && d.SSLKey != "" {
-			caCert := []byte(d.CACertificate)
-			sslCert := []byte(d.SSLCertificate)
-			sslKey := []byte(d.SSLKey)
-			c, err := getTLSConfig(caCert, sslCert, sslKey)
-			if err != nil {
-				logger.Errorf("error getting tls config: %s", err)
-			}
-			tlsConfig = c
+		tlsConfig, err := engineTLSConfig(d)
+		if err != nil {
+			logger.Errorf("error getting tls config: %s", err)
 		}
-		if err := setEngineClient(d.Engine, tlsConfig); err != nil {
+		client, err := setEngineClient(d.Engine, tlsConfig)
+		if err != nil {
 			logger.Errorf("error setting tls config for engine: %s", err)
+		} else {
+			dockerClients[d.Engine.ID] = client
 		}
 		engs = append(engs, d.Engine)
 		logger.Infof("loaded engine id=%s addr=%s", d.Engine.ID, d.Engine.Addr)
 	}
-	clusterManager, err := cluster.New(scheduler.NewResourceManager(), engs...)
+	clusterManager, err := cluster.New(&placementManager{manager: m}, engs...)
 	if err != nil {
 		logger.Fatal(err)
 	}
 	if err := clusterManager.Events(&EventHandler{Manager: m}); err != nil {
 		logger.Fatalf("unable to register event handler: %s", err)
 	}
+	m.schedulers = m.newSchedulers()
+	for typ, s := range m.schedulers {
+		clusterManager.RegisterScheduler(typ, s)
+	}
+	m.clusterManager = clusterManager
+	m.dockerClients = dockerClients
+	return engines
+}
+
+// newSchedulers returns the schedulers of each image type
+func (m *Manager) newSchedulers() map[string]citadel.Scheduler {
 	var (
-		labelScheduler  = &scheduler.LabelScheduler{}
+		labelScheduler  = &constraintScheduler{}
 		uniqueScheduler = &scheduler.UniqueScheduler{}
 		hostScheduler   = &scheduler.HostScheduler{}
 
@@ -162,29 +271,28 @@ func (m *Manager) init() []*shipyard.Engine {
 		)
 	)
 	// TODO: refactor to be configurable
-	clusterManager.RegisterScheduler("service", labelScheduler)
-	clusterManager.RegisterScheduler("unique", uniqueScheduler)
-	clusterManager.RegisterScheduler("multi", multiScheduler)
-	clusterManager.RegisterScheduler("host", hostScheduler)
-	m.clusterManager = clusterManager
-	// start extension health check
-	go m.extensionHealthCheck()
-	// start engine check
-	go m.engineCheck()
-	// anonymous usage info
-	go m.usageReport()
-	return engines
+	return map[string]citadel.Scheduler{
+		"service": m.newCordonScheduler(m.newAffinityScheduler(labelScheduler)),
+		"unique":  m.newCordonScheduler(m.newAffinityScheduler(uniqueScheduler)),
+		"multi":   m.newCordonScheduler(m.newAffinityScheduler(multiScheduler)),
+		"host":    m.newCordonScheduler(m.newAffinityScheduler(hostScheduler)),
+	}
 }
 
 func (m *Manager) usageReport() {
 	if m.disableUsageInfo {
 		return
 	}
-	m.uploadUsage()
+	if m.IsLeader() {
+		m.uploadUsage()
+	}
 	t := time.NewTicker(1 * time.Hour).C
 	for {
 		select {
 		case <-t:
+			if !m.IsLeader() {
+				continue
+			}
 			go m.uploadUsage()
 		}
 	}
@@ -227,6 +335,9 @@ func (m *Manager) extensionHealthCheck() {
 	for {
 		select {
 		case <-t:
+			if !m.IsLeader() {
+				continue
+			}
 			exts, err := m.Extensions()
 			if err != nil {
 				logger.Warnf("error running extension health check: %s", err)
@@ -271,21 +382,12 @@ func (m *Manager) engineCheck() {
 	for {
 		select {
 		case <-t:
+			if !m.IsLeader() {
+				continue
+			}
 			engs := m.Engines()
 			for _, eng := range engs {
-				health := &shipyard.Health{}
-				start_time := time.Now()
-				stat, err := eng.Ping()
-				if err != nil {
-					logger.Warnf("unable to ping engine: %s", err)
-				}
-				if stat != 200 {
-					health.Status = EngineHealthDown
-				} else {
-					health.Status = EngineHealthUp
-					health.ResponseTime = int64(time.Since(start_time) / time.Nanosecond)
-				}
-				eng.Health = health
+				m.checkEngineHealth(eng)
 				// get version
 				version, err := eng.Engine.Version()
 				if err != nil {
@@ -302,12 +404,96 @@ func (m *Manager) engineCheck() {
 	}
 }
 
+// checkEngineHealth pings an engine and updates its health
+func (m *Manager) checkEngineHealth(eng *shipyard.Engine) {
+	health := &shipyard.Health{
+		LastChecked: time.Now(),
+	}
+	if eng.Health != nil {
+		health.LastSeen = eng.Health.LastSeen
+	}
+	stat, err := eng.Ping()
+	switch {
+	case err != nil:
+		logger.Warnf("unable to ping engine: %s", err)
+		health.Status = EngineHealthUnreachable
+		health.Error = err.Error()
+	case stat != 200:
+		health.Status = EngineHealthDown
+		health.Error = fmt.Sprintf("ping returned status %d", stat)
+		health.LastSeen = health.LastChecked
+	default:
+		health.Status = EngineHealthUp
+		health.ResponseTime = int64(time.Since(health.LastChecked) / time.Nanosecond)
+		health.LastSeen = health.LastChecked
+	}
+	previous := eng.Health
+	eng.Health = health
+	if previous == nil || previous.Status != health.Status {
+		m.saveEngineHealthEvent(eng, previous, health)
+		if health.Status == EngineHealthUp {
+			go m.removeRescheduled(eng)
+			go m.restartStranded(eng)
+		} else {
+			go m.rescheduleEngine(eng)
+		}
+	}
+}
+
+// saveEngineHealthEvent records an engine changing health.  Engines coming
+// up for the first time are not recorded.
+func (m *Manager) saveEngineHealthEvent(eng *shipyard.Engine, previous *shipyard.Health, health *shipyard.Health) {
+	var typ string
+	switch health.Status {
+	case EngineHealthUnreachable:
+		typ = "engine-unreachable"
+	case EngineHealthDown:
+		typ = "engine-down"
+	case EngineHealthUp:
+		if previous == nil {
+			return
+		}
+		typ = "engine-up"
+	default:
+		return
+	}
+	msg := fmt.Sprintf("engine=%s addr=%s", eng.ID, eng.Engine.Addr)
+	if health.Error != "" {
+		msg += " error=" + health.Error
+	}
+	evt := &shipyard.Event{
+		Type:    typ,
+		Time:    health.LastChecked,
+		Message: msg,
+		Engine:  eng.Engine,
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		logger.Errorf("error saving engine health event: %s", err)
+	}
+}
+
+// EngineHealth returns the result of the latest health check of an engine
+func (m *Manager) EngineHealth(id string) (*shipyard.Health, error) {
+	eng := m.Engine(id)
+	if eng == nil {
+		return nil, ErrEngineDoesNotExist
+	}
+	if eng.Health == nil {
+		return &shipyard.Health{}, nil
+	}
+	return eng.Health, nil
+}
+
+// Engines returns the engines of the cluster
 func (m *Manager) Engines() []*shipyard.Engine {
-	return m.engines
+	m.enginesLock.RLock()
+	defer m.enginesLock.RUnlock()
+	return append([]*shipyard.Engine{}, m.engines...)
 }
 
 func (m *Manager) Engine(id string) *shipyard.Engine {
-	for _, e := range m.engines {
+	for _, e := range m.Engines() {
 		if e.ID == id {
 			return e
 		}
@@ -315,7 +501,29 @@ func (m *Manager) Engine(id string) *shipyard.Engine {
 	return nil
 }
 
+// clusterEngine returns the engine with a citadel engine id, the engine name
+// containers and schedulers refer to it by
+func (m *Manager) clusterEngine(id string) *shipyard.Engine {
+	for _, e := range m.Engines() {
+		if e.Engine != nil && e.Engine.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
 func (m *Manager) AddEngine(engine *shipyard.Engine) error {
+	if engine.Engine == nil {
+		return ErrInvalidEngine
+	}
+	if err := engine.Certificates().Validate(); err != nil {
+		return err
+	}
+	if engine.CAdvisor != nil {
+		if err := engine.CAdvisor.Validate(engine.Engine.Addr); err != nil {
+			return err
+		}
+	}
 	stat, err := engine.Ping()
 	if err != nil {
 		return err
@@ -324,7 +532,7 @@ func (m *Manager) AddEngine(engine *shipyard.Engine) error {
 		err := fmt.Errorf("Received status code '%d' when contacting %s", stat, engine.Engine.Addr)
 		return err
 	}
-	if _, err := r.Table(tblNameConfig).Insert(engine).RunWrite(m.session); err != nil {
+	if _, err := m.db.Insert(tblNameConfig, engine); err != nil {
 		return err
 	}
 	m.init()
@@ -342,20 +550,115 @@ func (m *Manager) AddEngine(engine *shipyard.Engine) error {
 }
 
 func (m *Manager) SaveEngine(engine *shipyard.Engine) error {
-	if _, err := r.Table(tblNameConfig).Replace(engine).RunWrite(m.session); err != nil {
+	if err := m.db.Put(tblNameConfig, engine); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (m *Manager) RemoveEngine(id string) error {
-	var engine *shipyard.Engine
-	res, err := r.Table(tblNameConfig).Filter(map[string]string{"id": id}).Run(m.session)
-	if err != nil {
+// UpdateEngineLabels replaces the labels of an engine.  Labels are plain
+// names or key=value pairs matched by image constraints.
+func (m *Manager) UpdateEngineLabels(id string, labels []string) error {
+	eng := m.Engine(id)
+	if eng == nil {
+		return ErrEngineDoesNotExist
+	}
+	eng.Engine.Labels = labels
+	if err := m.SaveEngine(eng); err != nil {
+		return err
+	}
+	evt := &shipyard.Event{
+		Type:    "update-engine",
+		Engine:  eng.Engine,
+		Message: fmt.Sprintf("labels=%s", strings.Join(labels, ",")),
+		Time:    time.Now(),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateEngine changes the resources, labels, address or certificates of
+// an engine in place.  The engine keeps its containers and scheduling state;
+// a changed address or certificate reconnects the engine.
+func (m *Manager) UpdateEngine(engine *shipyard.Engine) error {
+	eng := m.Engine(engine.ID)
+	if eng == nil {
+		return ErrEngineDoesNotExist
+	}
+	if engine.Engine == nil {
+		return ErrInvalidEngine
+	}
+	if engine.CAdvisor != nil {
+		if err := engine.CAdvisor.Validate(engine.Engine.Addr); err != nil {
+			return err
+		}
+	}
+	// engines are listed without their key; keep it for the same certificate
+	if engine.SSLKey == "" && engine.SSLCertificate != "" && engine.SSLCertificate == eng.SSLCertificate {
+		engine.SSLKey = eng.SSLKey
+	}
+	reconnect := engine.Engine.Addr != eng.Engine.Addr ||
+		engine.CACertificate != eng.CACertificate ||
+		engine.SSLCertificate != eng.SSLCertificate ||
+		engine.SSLKey != eng.SSLKey
+	if reconnect {
+		if err := engine.Certificates().Validate(); err != nil {
+			return err
+		}
+		stat, err := engine.Ping()
+		if err != nil {
+			return err
+		}
+		if stat != 200 {
+			return fmt.Errorf("Received status code '%d' when contacting %s", stat, engine.Engine.Addr)
+		}
+		tlsConfig, err := engineTLSConfig(engine)
+		if err != nil {
+			return err
+		}
+		// the existing citadel engine is kept so the cluster sees the change
+		eng.Engine.Addr = engine.Engine.Addr
+		client, err := setEngineClient(eng.Engine, tlsConfig)
+		if err != nil {
+			return err
+		}
+		dockerClients := make(map[string]*dockerclient.DockerClient)
+		for id, c := range m.dockerClients {
+			dockerClients[id] = c
+		}
+		dockerClients[eng.Engine.ID] = client
+		m.dockerClients = dockerClients
+		eng.CACertificate = engine.CACertificate
+		eng.SSLCertificate = engine.SSLCertificate
+		eng.SSLKey = engine.SSLKey
+	}
+	eng.Engine.Cpus = engine.Engine.Cpus
+	eng.Engine.Memory = engine.Engine.Memory
+	eng.Engine.Labels = engine.Engine.Labels
+	eng.CAdvisor = engine.CAdvisor
+	if err := m.SaveEngine(eng); err != nil {
+		return err
+	}
+	evt := &shipyard.Event{
+		Type:    "update-engine",
+		Engine:  eng.Engine,
+		Message: fmt.Sprintf("addr=%s cpus=%.2f memory=%.2f", eng.Engine.Addr, eng.Engine.Cpus, eng.Engine.Memory),
+		Time:    time.Now(),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
 		return err
 	}
-	if err := res.One(&engine); err != nil {
-		if err == r.ErrEmptyResult {
+	return nil
+}
+
+func (m *Manager) RemoveEngine(id string) error {
+	var engine *shipyard.Engine
+	if err := m.db.Get(tblNameConfig, id, &engine); err != nil {
+		if err == ds.ErrNotFound {
 			return nil
 		}
 		return err
@@ -370,7 +673,7 @@ func (m *Manager) RemoveEngine(id string) error {
 	if err := m.SaveEvent(evt); err != nil {
 		return err
 	}
-	if _, err := r.Table(tblNameConfig).Get(id).Delete().RunWrite(m.session); err != nil {
+	if _, err := m.db.Delete(tblNameConfig, ds.ByID(id)); err != nil {
 		return err
 	}
 	m.init()
@@ -378,7 +681,7 @@ func (m *Manager) RemoveEngine(id string) error {
 }
 
 func (m *Manager) Container(id string) (*citadel.Container, error) {
-	containers := m.clusterManager.ListContainers(true, false, "")
+	containers := m.Containers(true)
 	for _, cnt := range containers {
 		if strings.HasPrefix(cnt.ID, id) {
 			return cnt, nil
@@ -387,39 +690,100 @@ func (m *Manager) Container(id string) (*citadel.Container, error) {
 	return nil, nil
 }
 
-func (m *Manager) Logs(container *citadel.Container, stdout bool, stderr bool) (io.ReadCloser, error) {
-	data, err := m.clusterManager.Logs(container, stdout, stderr)
+func (m *Manager) Logs(container *citadel.Container, stdout bool, stderr bool, follow bool, tail int) (io.ReadCloser, error) {
+	client, err := m.DockerClient(container.Engine)
+	if err != nil {
+		return nil, err
+	}
+	logopts := &dockerclient.LogOptions{
+		Stdout: stdout,
+		Stderr: stderr,
+		Follow: follow,
+		Tail:   int64(tail),
+	}
+	data, err := client.ContainerLogs(container.ID, logopts)
 	if err != nil {
 		return nil, err
 	}
 	return data, nil
 }
 
+// Wait blocks until a container stops and returns its exit code
+func (m *Manager) Wait(container *citadel.Container) (int, error) {
+	var resp struct {
+		StatusCode int
+	}
+	if err := m.engineJSON(container.Engine, "POST", fmt.Sprintf("/containers/%s/wait", container.ID), nil, &resp); err != nil {
+		return 0, err
+	}
+	return resp.StatusCode, nil
+}
+
 func (m *Manager) Containers(all bool) []*citadel.Container {
-	return m.clusterManager.ListContainers(all, false, "")
+	containers := m.clusterManager.ListContainers(all, false, "")
+	m.applyContainerMetadata(containers...)
+	m.applyContainerRestarts(containers...)
+	if !all {
+		m.runningLock.Lock()
+		m.running = containers
+		m.runningLock.Unlock()
+	}
+	return containers
+}
+
+// QueryContainers returns a page of the containers, including stopped ones,
+// selected by query
+func (m *Manager) QueryContainers(query *shipyard.ContainerQuery) []*citadel.Container {
+	return query.Select(m.Containers(true))
 }
 
 func (m *Manager) ContainersByImage(name string, all bool) ([]*citadel.Container, error) {
 	allContainers := m.Containers(all)
 	imageContainers := []*citadel.Container{}
 	for _, c := range allContainers {
-		if strings.Index(c.Image.Name, name) > -1 {
+		if c.Image.Name == name {
 			imageContainers = append(imageContainers, c)
 		}
 	}
 	return imageContainers, nil
 }
 
+// ContainersByLabel returns the containers whose labels match a selector
+// such as "tier=web,env!=dev"
+func (m *Manager) ContainersByLabel(selector string, all bool) ([]*citadel.Container, error) {
+	s, err := shipyard.ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	containers := []*citadel.Container{}
+	for _, c := range m.Containers(all) {
+		if s.Match(c) {
+			containers = append(containers, c)
+		}
+	}
+	return containers, nil
+}
+
 func (m *Manager) IdenticalContainers(container *citadel.Container, all bool) ([]*citadel.Container, error) {
+	return m.IdenticalImageContainers(container.Image, all)
+}
+
+// IdenticalImageContainers returns the containers launched from the same
+// image configuration in the namespace of the image
+func (m *Manager) IdenticalImageContainers(image *citadel.Image, all bool) ([]*citadel.Container, error) {
 	containers := []*citadel.Container{}
-	imageContainers, err := m.ContainersByImage(container.Image.Name, all)
+	imageContainers, err := m.ContainersByImage(image.Name, all)
 	if err != nil {
 		return nil, err
 	}
+	namespace := shipyard.ImageNamespace(image)
 	for _, c := range imageContainers {
+		if shipyard.ContainerNamespace(c) != namespace {
+			continue
+		}
 		args := len(c.Image.Args)
-		origArgs := len(container.Image.Args)
-		if c.Image.Memory == container.Image.Memory && args == origArgs && c.Image.Type == container.Image.Type {
+		origArgs := len(image.Args)
+		if c.Image.Memory == image.Memory && args == origArgs && c.Image.Type == image.Type {
 			containers = append(containers, c)
 		}
 	}
@@ -437,22 +801,87 @@ func (m *Manager) ClusterInfo() *shipyard.ClusterInfo {
 		ReservedCpus:   info.ReservedCpus,
 		ReservedMemory: info.ReservedMemory,
 		Version:        m.version,
+		Placement:      m.Placement(),
 	}
+	clusterInfo.Instance, clusterInfo.Leader = m.Leadership()
 	return clusterInfo
 }
 
+// Usage returns the reserved and free resources of each engine and the
+// totals of each image
+func (m *Manager) Usage() *shipyard.ClusterUsage {
+	engines := []*citadel.Engine{}
+	for _, e := range m.Engines() {
+		engines = append(engines, e.Engine)
+	}
+	return shipyard.ComputeUsage(engines, m.Containers(true))
+}
+
+// Destroy removes a container.  Containers with a stop hook are stopped
+// gracefully first, others are killed.
 func (m *Manager) Destroy(container *citadel.Container) error {
-	if err := m.ClusterManager().Kill(container, 9); err != nil {
+	m.expectStop(container)
+	if stopHook(container) != nil {
+		client, err := m.DockerClient(container.Engine)
+		if err != nil {
+			return err
+		}
+		if err := client.StopContainer(container.ID, m.prepareStop(container, 0)); err != nil {
+			return err
+		}
+	} else if err := m.ClusterManager().Kill(container, 9); err != nil {
 		return err
 	}
 	if err := m.ClusterManager().Remove(container); err != nil {
 		return err
 	}
+	m.removeContainerMetadata(container)
+	return nil
+}
+
+func (m *Manager) Start(container *citadel.Container) error {
+	resp, err := m.engineRequest(container.Engine, "POST", fmt.Sprintf("/containers/%s/start", container.ID), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
 	return nil
 }
 
+// Stop stops a container, killing it if it has not exited after timeout
+// seconds or the grace period of its stop hook if longer
+func (m *Manager) Stop(container *citadel.Container, timeout int) error {
+	m.expectStop(container)
+	client, err := m.DockerClient(container.Engine)
+	if err != nil {
+		return err
+	}
+	return client.StopContainer(container.ID, m.prepareStop(container, timeout))
+}
+
+func (m *Manager) Restart(container *citadel.Container, timeout int) error {
+	m.expectStop(container)
+	return m.ClusterManager().Restart(container, m.prepareStop(container, timeout))
+}
+
+func (m *Manager) Pause(container *citadel.Container) error {
+	client, err := m.DockerClient(container.Engine)
+	if err != nil {
+		return err
+	}
+	return client.PauseContainer(container.ID)
+}
+
+func (m *Manager) Unpause(container *citadel.Container) error {
+	client, err := m.DockerClient(container.Engine)
+	if err != nil {
+		return err
+	}
+	return client.UnpauseContainer(container.ID)
+}
+
 func (m *Manager) SaveServiceKey(key *shipyard.ServiceKey) error {
-	if _, err := r.Table(tblNameServiceKeys).Insert(key).RunWrite(m.session); err != nil {
+	if _, err := m.db.Insert(tblNameServiceKeys, key); err != nil {
 		return err
 	}
 	m.init()
@@ -482,93 +911,124 @@ func (m *Manager) RemoveServiceKey(key string) error {
 	if err := m.SaveEvent(evt); err != nil {
 		return err
 	}
-	if _, err := r.Table(tblNameServiceKeys).Filter(map[string]string{"key": key}).Delete().RunWrite(m.session); err != nil {
+	if _, err := m.db.Delete(tblNameServiceKeys, ds.Where(ds.Eq("key", key))); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (m *Manager) SaveEvent(event *shipyard.Event) error {
-	if _, err := r.Table(tblNameEvents).Insert(event).RunWrite(m.session); err != nil {
+	if event.Severity == "" {
+		event.Severity = shipyard.EventSeverity(event.Type)
+	}
+	if event.Namespace == "" {
+		event.Namespace = shipyard.ContainerNamespace(event.Container)
+	}
+	event.Container = redactedContainer(event.Container)
+	id, err := m.db.Insert(tblNameEvents, event)
+	if err != nil {
 		return err
 	}
+	event.ID = id
+	// in ha mode every controller publishes the events of the changefeed
+	if !m.haEnabled() {
+		m.publishEvent(event)
+	}
 	return nil
 }
 
-func (m *Manager) Events(limit int) ([]*shipyard.Event, error) {
-	t := r.Table(tblNameEvents).OrderBy(r.Desc("Time"))
-	if limit > -1 {
-		t.Limit(limit)
-	}
-	res, err := t.Run(m.session)
-	if err != nil {
-		return nil, err
+func (m *Manager) Events(query *shipyard.EventQuery) ([]*shipyard.Event, error) {
+	if query == nil {
+		query = &shipyard.EventQuery{}
 	}
+	q := eventQueryFilter(query).Sort("Time", true).Page(query.Offset, query.Limit)
 	events := []*shipyard.Event{}
-	if err := res.All(&events); err != nil {
+	if err := m.db.Find(tblNameEvents, q, &events); err != nil {
 		return nil, err
 	}
 	return events, nil
 }
 
+// eventQueryFilter builds the conditions of an event query
+func eventQueryFilter(query *shipyard.EventQuery) *ds.Query {
+	q := ds.Where()
+	if len(query.Types) > 0 {
+		q.Where = append(q.Where, ds.In("Type", query.Types))
+	}
+	if query.EngineID != "" {
+		q.Where = append(q.Where, ds.Eq("Engine.ID", query.EngineID))
+	}
+	if query.ContainerID != "" {
+		q.Where = append(q.Where, ds.Prefix("Container.ID", query.ContainerID))
+	}
+	if query.Namespace != "" {
+		q.Where = append(q.Where, ds.Eq("Namespace", query.Namespace))
+	}
+	if !query.Since.IsZero() {
+		q.Where = append(q.Where, ds.Ge("Time", query.Since))
+	}
+	if !query.Until.IsZero() {
+		q.Where = append(q.Where, ds.Le("Time", query.Until))
+	}
+	return q
+}
+
 func (m *Manager) PurgeEvents() error {
-	if _, err := r.Table(tblNameEvents).Delete().RunWrite(m.session); err != nil {
+	if _, err := m.db.Delete(tblNameEvents, nil); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (m *Manager) ServiceKey(key string) (*shipyard.ServiceKey, error) {
-	res, err := r.Table(tblNameServiceKeys).Filter(map[string]string{"key": key}).Run(m.session)
-	if err != nil {
-		return nil, err
-
-	}
-	if res.IsNil() {
-		return nil, ErrServiceKeyDoesNotExist
-	}
 	var k *shipyard.ServiceKey
-	if err := res.One(&k); err != nil {
+	if err := m.db.FindOne(tblNameServiceKeys, ds.Where(ds.Eq("key", key)), &k); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrServiceKeyDoesNotExist
+		}
 		return nil, err
 	}
 	return k, nil
 }
 
 func (m *Manager) ServiceKeys() ([]*shipyard.ServiceKey, error) {
-	res, err := r.Table(tblNameServiceKeys).Run(m.session)
-	if err != nil {
-		return nil, err
-	}
 	keys := []*shipyard.ServiceKey{}
-	if err := res.All(&keys); err != nil {
+	if err := m.db.Find(tblNameServiceKeys, nil, &keys); err != nil {
 		return nil, err
 	}
+	now := time.Now()
+	for _, k := range keys {
+		k.ExpiresIn = int64(k.Remaining(now) / time.Second)
+	}
 	return keys, nil
 }
 
 func (m *Manager) Accounts() ([]*shipyard.Account, error) {
-	res, err := r.Table(tblNameAccounts).OrderBy(r.Asc("username")).Run(m.session)
-	if err != nil {
-		return nil, err
+	return m.QueryAccounts(nil)
+}
+
+// QueryAccounts returns a page of the accounts selected by query
+func (m *Manager) QueryAccounts(query *shipyard.AccountQuery) ([]*shipyard.Account, error) {
+	if query == nil {
+		query = &shipyard.AccountQuery{}
+	}
+	q := ds.Where().Sort("username", false).Page(query.Offset, query.Limit)
+	if query.Role != "" {
+		q.Where = append(q.Where, ds.Eq("role.name", query.Role))
 	}
 	accounts := []*shipyard.Account{}
-	if err := res.All(&accounts); err != nil {
+	if err := m.db.Find(tblNameAccounts, q, &accounts); err != nil {
 		return nil, err
 	}
 	return accounts, nil
 }
 
 func (m *Manager) Account(username string) (*shipyard.Account, error) {
-	res, err := r.Table(tblNameAccounts).Filter(map[string]string{"username": username}).Run(m.session)
-	if err != nil {
-		return nil, err
-
-	}
-	if res.IsNil() {
-		return nil, ErrAccountDoesNotExist
-	}
 	var account *shipyard.Account
-	if err := res.One(&account); err != nil {
+	if err := m.db.FindOne(tblNameAccounts, ds.Where(ds.Eq("username", username)), &account); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrAccountDoesNotExist
+		}
 		return nil, err
 	}
 	return account, nil
@@ -586,13 +1046,16 @@ func (m *Manager) SaveAccount(account *shipyard.Account) error {
 		return err
 	}
 	account.Password = hash
+	// two-factor auth is enabled by the account owner
+	account.TOTPEnabled = false
+	account.TOTPSecret = ""
 	if acct != nil {
-		if _, err := r.Table(tblNameAccounts).Filter(map[string]string{"username": account.Username}).Update(map[string]string{"password": hash}).RunWrite(m.session); err != nil {
+		if _, err := m.db.Update(tblNameAccounts, ds.Where(ds.Eq("username", account.Username)), map[string]interface{}{"password": hash}); err != nil {
 			return err
 		}
 		return nil
 	}
-	if _, err := r.Table(tblNameAccounts).Insert(account).RunWrite(m.session); err != nil {
+	if _, err := m.db.Insert(tblNameAccounts, account); err != nil {
 		return err
 	}
 	evt := &shipyard.Event{
@@ -607,12 +1070,77 @@ func (m *Manager) SaveAccount(account *shipyard.Account) error {
 	return nil
 }
 
+// UpdateAccount changes the role, name, email and, if set, the password of
+// an existing account.  Auth tokens are kept so sessions stay valid.
+func (m *Manager) UpdateAccount(account *shipyard.Account) error {
+	acct, err := m.Account(account.Username)
+	if err != nil {
+		return err
+	}
+	update := map[string]interface{}{
+		"full_name": account.FullName,
+		"email":     account.Email,
+	}
+	if account.Role != nil {
+		role, err := m.Role(account.Role.Name)
+		if err != nil {
+			return err
+		}
+		update["role"] = role
+	}
+	if account.Password != "" {
+		hash, err := m.authenticator.Hash(account.Password)
+		if err != nil {
+			return err
+		}
+		update["password"] = hash
+	}
+	if _, err := m.db.Update(tblNameAccounts, ds.ByID(acct.ID), update); err != nil {
+		return err
+	}
+	evt := &shipyard.Event{
+		Type:    "update-account",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("username=%s", acct.Username),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetAccountRole assigns an existing role to an account
+func (m *Manager) SetAccountRole(username string, roleName string) error {
+	acct, err := m.Account(username)
+	if err != nil {
+		return err
+	}
+	role, err := m.Role(roleName)
+	if err != nil {
+		return err
+	}
+	if _, err := m.db.Update(tblNameAccounts, ds.ByID(acct.ID), map[string]interface{}{"role": role}); err != nil {
+		return err
+	}
+	evt := &shipyard.Event{
+		Type:    "update-account",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("username=%s role=%s", acct.Username, role.Name),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (m *Manager) DeleteAccount(account *shipyard.Account) error {
-	res, err := r.Table(tblNameAccounts).Filter(map[string]string{"id": account.ID}).Delete().Run(m.session)
+	n, err := m.db.Delete(tblNameAccounts, ds.ByID(account.ID))
 	if err != nil {
 		return err
 	}
-	if res.IsNil() {
+	if n == 0 {
 		return ErrAccountDoesNotExist
 	}
 	evt := &shipyard.Event{
@@ -628,35 +1156,26 @@ func (m *Manager) DeleteAccount(account *shipyard.Account) error {
 }
 
 func (m *Manager) Roles() ([]*shipyard.Role, error) {
-	res, err := r.Table(tblNameRoles).OrderBy(r.Asc("name")).Run(m.session)
-	if err != nil {
-		return nil, err
-	}
 	roles := []*shipyard.Role{}
-	if err := res.All(&roles); err != nil {
+	if err := m.db.Find(tblNameRoles, ds.Where().Sort("name", false), &roles); err != nil {
 		return nil, err
 	}
 	return roles, nil
 }
 
 func (m *Manager) Role(name string) (*shipyard.Role, error) {
-	res, err := r.Table(tblNameRoles).Filter(map[string]string{"name": name}).Run(m.session)
-	if err != nil {
-		return nil, err
-
-	}
-	if res.IsNil() {
-		return nil, ErrRoleDoesNotExist
-	}
 	var role *shipyard.Role
-	if err := res.One(&role); err != nil {
+	if err := m.db.FindOne(tblNameRoles, ds.Where(ds.Eq("name", name)), &role); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrRoleDoesNotExist
+		}
 		return nil, err
 	}
 	return role, nil
 }
 
 func (m *Manager) SaveRole(role *shipyard.Role) error {
-	if _, err := r.Table(tblNameRoles).Insert(role).RunWrite(m.session); err != nil {
+	if _, err := m.db.Insert(tblNameRoles, role); err != nil {
 		return err
 	}
 	evt := &shipyard.Event{
@@ -671,12 +1190,57 @@ func (m *Manager) SaveRole(role *shipyard.Role) error {
 	return nil
 }
 
+// CreateRole validates the permissions of a new role and saves it
+func (m *Manager) CreateRole(role *shipyard.Role) error {
+	if role.Name == "" {
+		return ErrRoleNameRequired
+	}
+	if _, err := m.Role(role.Name); err == nil {
+		return ErrRoleExists
+	} else if err != ErrRoleDoesNotExist {
+		return err
+	}
+	for _, p := range role.Permissions {
+		if err := shipyard.ValidatePermission(p); err != nil {
+			return err
+		}
+	}
+	return m.SaveRole(role)
+}
+
+// UpdateRole replaces the permissions of an existing role.  Accounts with
+// the role are affected on their next request.
+func (m *Manager) UpdateRole(role *shipyard.Role) error {
+	existing, err := m.Role(role.Name)
+	if err != nil {
+		return err
+	}
+	for _, p := range role.Permissions {
+		if err := shipyard.ValidatePermission(p); err != nil {
+			return err
+		}
+	}
+	if _, err := m.db.Update(tblNameRoles, ds.ByID(existing.ID), map[string]interface{}{"permissions": role.Permissions}); err != nil {
+		return err
+	}
+	evt := &shipyard.Event{
+		Type:    "update-role",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s permissions=%s", role.Name, strings.Join(role.Permissions, ",")),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (m *Manager) DeleteRole(role *shipyard.Role) error {
-	res, err := r.Table(tblNameRoles).Get(role.ID).Delete().Run(m.session)
+	n, err := m.db.Delete(tblNameRoles, ds.ByID(role.ID))
 	if err != nil {
 		return err
 	}
-	if res.IsNil() {
+	if n == 0 {
 		return ErrRoleDoesNotExist
 	}
 	evt := &shipyard.Event{
@@ -691,7 +1255,30 @@ func (m *Manager) DeleteRole(role *shipyard.Role) error {
 	return nil
 }
 
+// SetAuthenticator checks logins against a, falling back to local accounts
+// only for users a does not know about
+func (m *Manager) SetAuthenticator(a Authenticator) {
+	m.externalAuth = a
+}
+
 func (m *Manager) Authenticate(username, password string) bool {
+	if m.externalAuth != nil {
+		role, err := m.externalAuth.Authenticate(username, password)
+		switch err {
+		case nil:
+			if err := m.syncExternalAccount(username, role); err != nil {
+				logger.Errorf("error syncing account %s: %s", username, err)
+				return false
+			}
+			return true
+		case shipyard.ErrUnknownUser:
+		default:
+			if err != shipyard.ErrUnauthorized {
+				logger.Errorf("external authentication failed for %s: %s", username, err)
+			}
+			return false
+		}
+	}
 	acct, err := m.Account(username)
 	if err != nil {
 		logger.Error(err)
@@ -700,6 +1287,35 @@ func (m *Manager) Authenticate(username, password string) bool {
 	return m.authenticator.Authenticate(password, acct.Password)
 }
 
+// syncExternalAccount creates or updates the local account of an externally
+// authenticated user.  The account holds auth tokens; its password is
+// random so it can't be used to login.
+func (m *Manager) syncExternalAccount(username string, roleName string) error {
+	acct, err := m.Account(username)
+	if err == ErrAccountDoesNotExist {
+		role, err := m.Role(roleName)
+		if err != nil {
+			return err
+		}
+		password, err := m.authenticator.GenerateToken()
+		if err != nil {
+			return err
+		}
+		return m.SaveAccount(&shipyard.Account{
+			Username: username,
+			Password: password,
+			Role:     role,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if acct.Role == nil || acct.Role.Name != roleName {
+		return m.SetAccountRole(username, roleName)
+	}
+	return nil
+}
+
 func (m *Manager) NewAuthToken(username string, userAgent string) (*shipyard.AuthToken, error) {
 	tk, err := m.authenticator.GenerateToken()
 	if err != nil {
@@ -731,11 +1347,11 @@ func (m *Manager) NewAuthToken(username string, userAgent string) (*shipyard.Aut
 		tokens = append(tokens, token)
 	}
 	// delete token
-	if _, err := r.Table(tblNameAccounts).Filter(map[string]string{"username": username}).Filter(r.Row.Field("user_agent").Eq(userAgent)).Delete().Run(m.session); err != nil {
+	if _, err := m.db.Delete(tblNameAccounts, ds.Where(ds.Eq("username", username), ds.Eq("user_agent", userAgent))); err != nil {
 		return nil, err
 	}
 	// add
-	if _, err := r.Table(tblNameAccounts).Filter(map[string]string{"username": username}).Update(map[string]interface{}{"tokens": tokens}).RunWrite(m.session); err != nil {
+	if _, err := m.db.Update(tblNameAccounts, ds.Where(ds.Eq("username", username)), map[string]interface{}{"tokens": tokens}); err != nil {
 		return nil, err
 	}
 	return token, nil
@@ -759,14 +1375,82 @@ func (m *Manager) VerifyAuthToken(username, token string) error {
 	return nil
 }
 
-func (m *Manager) VerifyServiceKey(key string) error {
-	if _, err := m.ServiceKey(key); err != nil {
+// RefreshAuthToken replaces token with a new token for the same user agent
+func (m *Manager) RefreshAuthToken(username, token string) (*shipyard.AuthToken, error) {
+	acct, err := m.Account(username)
+	if err != nil {
+		return nil, err
+	}
+	var current *shipyard.AuthToken
+	for _, t := range acct.Tokens {
+		if t.Token == token {
+			current = t
+			break
+		}
+	}
+	if current == nil {
+		return nil, ErrInvalidAuthToken
+	}
+	tk, err := m.authenticator.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	current.Token = tk
+	if err := m.saveAuthTokens(username, acct.Tokens); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// RevokeAuthToken removes token from the account so it can no longer be used
+func (m *Manager) RevokeAuthToken(username, token string) error {
+	acct, err := m.Account(username)
+	if err != nil {
+		return err
+	}
+	tokens := []*shipyard.AuthToken{}
+	for _, t := range acct.Tokens {
+		if t.Token != token {
+			tokens = append(tokens, t)
+		}
+	}
+	if len(tokens) == len(acct.Tokens) {
+		return ErrInvalidAuthToken
+	}
+	return m.saveAuthTokens(username, tokens)
+}
+
+func (m *Manager) saveAuthTokens(username string, tokens []*shipyard.AuthToken) error {
+	if _, err := m.db.Update(tblNameAccounts, ds.Where(ds.Eq("username", username)), map[string]interface{}{"tokens": tokens}); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (m *Manager) NewServiceKey(description string) (*shipyard.ServiceKey, error) {
+// VerifyServiceKey returns the key if it exists and has not expired
+func (m *Manager) VerifyServiceKey(key string) (*shipyard.ServiceKey, error) {
+	k, err := m.ServiceKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if k.Expired(time.Now()) {
+		return nil, shipyard.ErrServiceKeyExpired
+	}
+	return k, nil
+}
+
+// NewServiceKey creates a key that expires after ttl (zero never expires)
+// and is limited to permissions (empty grants full access).  Keys created in
+// a namespace are confined to it and keys act for the account creating them.
+func (m *Manager) NewServiceKey(description string, ttl time.Duration, permissions []string, namespace string, account string) (*shipyard.ServiceKey, error) {
+	if ttl < 0 {
+		return nil, ErrInvalidServiceKeyTTL
+	}
+	for _, p := range permissions {
+		if err := shipyard.ValidatePermission(p); err != nil {
+			return nil, err
+		}
+	}
 	k, err := m.authenticator.GenerateToken()
 	if err != nil {
 		return nil, err
@@ -774,6 +1458,13 @@ func (m *Manager) NewServiceKey(description string) (*shipyard.ServiceKey, error
 	key := &shipyard.ServiceKey{
 		Key:         k[24:],
 		Description: description,
+		Permissions: permissions,
+		Namespace:   namespace,
+		Account:     account,
+	}
+	if ttl > 0 {
+		key.ExpiresAt = time.Now().Add(ttl)
+		key.ExpiresIn = int64(ttl / time.Second)
 	}
 	if err := m.SaveServiceKey(key); err != nil {
 		return nil, err
@@ -786,42 +1477,33 @@ func (m *Manager) ChangePassword(username, password string) error {
 	if err != nil {
 		return err
 	}
-	if _, err := r.Table(tblNameAccounts).Filter(map[string]string{"username": username}).Update(map[string]string{"password": hash}).Run(m.session); err != nil {
+	if _, err := m.db.Update(tblNameAccounts, ds.Where(ds.Eq("username", username)), map[string]interface{}{"password": hash}); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (m *Manager) Extensions() ([]*shipyard.Extension, error) {
-	res, err := r.Table(tblNameExtensions).OrderBy(r.Asc("name")).Run(m.session)
-	if err != nil {
-		return nil, err
-	}
 	exts := []*shipyard.Extension{}
-	if err := res.All(&exts); err != nil {
+	if err := m.db.Find(tblNameExtensions, ds.Where().Sort("name", false), &exts); err != nil {
 		return nil, err
 	}
 	return exts, nil
 }
 
 func (m *Manager) Extension(id string) (*shipyard.Extension, error) {
-	res, err := r.Table(tblNameExtensions).Get(id).Run(m.session)
-	if err != nil {
-		return nil, err
-
-	}
-	if res.IsNil() {
-		return nil, ErrExtensionDoesNotExist
-	}
 	var ext *shipyard.Extension
-	if err := res.One(&ext); err != nil {
+	if err := m.db.Get(tblNameExtensions, id, &ext); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrExtensionDoesNotExist
+		}
 		return nil, err
 	}
 	return ext, nil
 }
 
 func (m *Manager) SaveExtension(ext *shipyard.Extension) error {
-	res, err := r.Table(tblNameExtensions).Insert(ext).RunWrite(m.session)
+	key, err := m.db.Insert(tblNameExtensions, ext)
 	if err != nil {
 		return err
 	}
@@ -834,7 +1516,6 @@ func (m *Manager) SaveExtension(ext *shipyard.Extension) error {
 	if err := m.SaveEvent(evt); err != nil {
 		return err
 	}
-	key := res.GeneratedKeys[0]
 	ext.ID = key
 	// register
 	if err := m.RegisterExtension(ext); err != nil {
@@ -937,11 +1618,11 @@ func (m *Manager) DeleteExtension(id string) error {
 	if err := m.UnregisterExtension(ext); err != nil {
 		return err
 	}
-	res, err := r.Table(tblNameExtensions).Get(id).Delete().Run(m.session)
+	n, err := m.db.Delete(tblNameExtensions, ds.ByID(id))
 	if err != nil {
 		return err
 	}
-	if res.IsNil() {
+	if n == 0 {
 		return ErrExtensionDoesNotExist
 	}
 	return nil
@@ -952,10 +1633,14 @@ func (m *Manager) RedeployContainers(image string) error {
 	containers := m.Containers(false)
 	deployed := false
 	for _, c := range containers {
+		// auto-deploy applications are deployed by ImagePushed
+		if m.autoDeployed(shipyard.ApplicationName(c)) {
+			continue
+		}
 		if strings.Index(c.Image.Name, image) > -1 {
 			img = c.Image
 			logger.Infof("pulling latest image for %s", image)
-			if err := c.Engine.Pull(image); err != nil {
+			if err := m.pullImage(c.Engine, image); err != nil {
 				return err
 			}
 			m.Destroy(c)
@@ -987,12 +1672,8 @@ func (m *Manager) RedeployContainers(image string) error {
 }
 
 func (m *Manager) WebhookKeys() ([]*dockerhub.WebhookKey, error) {
-	res, err := r.Table(tblNameWebhookKeys).OrderBy(r.Asc("image")).Run(m.session)
-	if err != nil {
-		return nil, err
-	}
 	keys := []*dockerhub.WebhookKey{}
-	if err := res.All(&keys); err != nil {
+	if err := m.db.Find(tblNameWebhookKeys, ds.Where().Sort("image", false), &keys); err != nil {
 		return nil, err
 	}
 	return keys, nil
@@ -1011,23 +1692,18 @@ func (m *Manager) NewWebhookKey(image string) (*dockerhub.WebhookKey, error) {
 }
 
 func (m *Manager) WebhookKey(key string) (*dockerhub.WebhookKey, error) {
-	res, err := r.Table(tblNameWebhookKeys).Filter(map[string]string{"key": key}).Run(m.session)
-	if err != nil {
-		return nil, err
-
-	}
-	if res.IsNil() {
-		return nil, ErrWebhookKeyDoesNotExist
-	}
 	var k *dockerhub.WebhookKey
-	if err := res.One(&k); err != nil {
+	if err := m.db.FindOne(tblNameWebhookKeys, ds.Where(ds.Eq("key", key)), &k); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrWebhookKeyDoesNotExist
+		}
 		return nil, err
 	}
 	return k, nil
 }
 
 func (m *Manager) SaveWebhookKey(key *dockerhub.WebhookKey) error {
-	if _, err := r.Table(tblNameWebhookKeys).Insert(key).RunWrite(m.session); err != nil {
+	if _, err := m.db.Insert(tblNameWebhookKeys, key); err != nil {
 		return err
 	}
 	evt := &shipyard.Event{
@@ -1047,11 +1723,11 @@ func (m *Manager) DeleteWebhookKey(id string) error {
 	if err != nil {
 		return err
 	}
-	res, err := r.Table(tblNameWebhookKeys).Get(key.ID).Delete().Run(m.session)
+	n, err := m.db.Delete(tblNameWebhookKeys, ds.ByID(key.ID))
 	if err != nil {
 		return err
 	}
-	if res.IsNil() {
+	if n == 0 {
 		return ErrWebhookKeyDoesNotExist
 	}
 	evt := &shipyard.Event{
@@ -1067,16 +1743,63 @@ func (m *Manager) DeleteWebhookKey(id string) error {
 }
 
 func (m *Manager) Run(image *citadel.Image, count int, pull bool) ([]*citadel.Container, error) {
+	return m.runOn(image, count, pull, "")
+}
+
+// runOn is Run starting the containers on the engine with the cluster engine
+// id engineID, which the scheduler of the image must accept; the cluster
+// places them when it is empty
+func (m *Manager) runOn(image *citadel.Image, count int, pull bool, engineID string) ([]*citadel.Container, error) {
 	launched := []*citadel.Container{}
+	// images copied from listed containers carry the reported health and
+	// restart count
+	delete(image.Environment, shipyard.HealthStatusEnv)
+	delete(image.Environment, shipyard.RestartCountEnv)
+
+	if pull {
+		// citadel pulls without credentials so images from registries
+		// with stored credentials are pulled on the engines beforehand
+		auth, err := m.registryAuth(image.Name)
+		if err != nil {
+			return nil, err
+		}
+		if auth != nil {
+			err := m.eachEngine(func(engine *shipyard.Engine, client *dockerclient.DockerClient) error {
+				return client.PullImage(image.Name, auth)
+			})
+			if err != nil {
+				return nil, err
+			}
+			pull = false
+		}
+	}
+
+	image, err := m.injectVolumes(image)
+	if err != nil {
+		return nil, err
+	}
+	// configs are rendered before secrets so secret values are never
+	// treated as templates
+	image, files, err := m.injectConfigs(image)
+	if err != nil {
+		return nil, err
+	}
+	image, secretFiles, err := m.injectSecrets(image)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, secretFiles...)
 
 	var wg sync.WaitGroup
 	wg.Add(count)
 	var runErr error
 	for i := 0; i < count; i++ {
 		go func(wg *sync.WaitGroup) {
-			container, err := m.ClusterManager().Start(image, pull)
+			container, err := m.start(image, pull, engineID)
 			if err != nil {
 				runErr = err
+			} else if err := m.writeContainerFiles(container, files); err != nil {
+				runErr = fmt.Errorf("error writing files to container %s: %s", container.ID[:12], err)
 			}
 			launched = append(launched, container)
 			wg.Done()
@@ -1086,11 +1809,86 @@ func (m *Manager) Run(image *citadel.Image, count int, pull bool) ([]*citadel.Co
 	return launched, runErr
 }
 
+// start starts a container of image on the engine with the cluster engine id
+// engineID, or on the engine the cluster places it on when it is empty
+func (m *Manager) start(image *citadel.Image, pull bool, engineID string) (*citadel.Container, error) {
+	if engineID == "" {
+		return m.ClusterManager().Start(image, pull)
+	}
+	eng := m.clusterEngine(engineID)
+	if eng == nil {
+		return nil, fmt.Errorf("engine %s is not in the cluster", engineID)
+	}
+	s := m.schedulers[image.Type]
+	if s == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownImageType, image.Type)
+	}
+	ok, err := s.Schedule(image, eng.Engine)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("engine %s cannot run image %s", engineID, image.Name)
+	}
+	container := &citadel.Container{
+		Image: image,
+		Name:  image.ContainerName,
+	}
+	if err := eng.Engine.Start(container, pull); err != nil {
+		return nil, err
+	}
+	return container, nil
+}
+
 func (m *Manager) Scale(container *citadel.Container, count int) error {
 	imageContainers, err := m.IdenticalContainers(container, true)
 	if err != nil {
 		return err
 	}
+	return m.scale(container.Image, container.Engine, imageContainers, count)
+}
+
+// ScaleImage launches or destroys containers so that count instances of the
+// image are present in the cluster
+func (m *Manager) ScaleImage(image *citadel.Image, count int) error {
+	imageContainers, err := m.ScaleTargets(image)
+	if err != nil {
+		return err
+	}
+	var eng *citadel.Engine
+	if len(imageContainers) > 0 {
+		eng = imageContainers[0].Engine
+	}
+	return m.scale(image, eng, imageContainers, count)
+}
+
+// ScaleTargets returns the containers ScaleImage reconciles: the identical
+// containers in the namespace of the image launched by its owner, if it has
+// one
+func (m *Manager) ScaleTargets(image *citadel.Image) ([]*citadel.Container, error) {
+	containers, err := m.IdenticalImageContainers(image, true)
+	if err != nil {
+		return nil, err
+	}
+	owner := image.Environment[shipyard.OwnerEnv]
+	if owner == "" {
+		return containers, nil
+	}
+	owned := []*citadel.Container{}
+	for _, c := range containers {
+		if shipyard.ContainerOwner(c) == owner {
+			owned = append(owned, c)
+		}
+	}
+	return owned, nil
+}
+
+// scale reconciles imageContainers to count; new instances of images with
+// volumes or links are placed on eng
+func (m *Manager) scale(img *citadel.Image, eng *citadel.Engine, imageContainers []*citadel.Container, count int) error {
+	if count < 0 {
+		return ErrInvalidScaleCount
+	}
 	containerCount := len(imageContainers)
 	// check which way we need to scale
 	if containerCount > count { // down
@@ -1104,9 +1902,7 @@ func (m *Manager) Scale(container *citadel.Container, count int) error {
 	} else if containerCount < count { // up
 		numAdd := count - containerCount
 		// check for vols or links -- if so, launch on same engine
-		img := container.Image
-		if len(img.Volumes) > 0 || len(img.Links) > 0 {
-			eng := container.Engine
+		if eng != nil && (len(img.Volumes) > 0 || len(img.Links) > 0) {
 			t := fmt.Sprintf("host:%s", eng.ID)
 			lbls := img.Labels
 			lbls = append(lbls, t)
@@ -2,12 +2,19 @@ package manager
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/citadel/citadel"
+	"github.com/citadel/citadel/cluster"
 	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/datastore"
 )
 
 func newManager() *Manager {
@@ -23,7 +30,12 @@ func newManager() *Manager {
 		fmt.Println("env vars needed: RETHINKDB_TEST_PORT_28015_TCP_ADDR, RETHINKDB_TEST_PORT_28015_TCP_PORT, RETHINKDB_TEST_DATABASE, DOCKER_TEST_ADDR")
 		os.Exit(1)
 	}
-	m, err := NewManager(rethinkdbAddr, rDb, "", "test", true)
+	db, err := datastore.NewRethinkStore(rethinkdbAddr, rDb, "")
+	if err != nil {
+		fmt.Printf("unable to connect to test db: %s\n", err)
+		os.Exit(1)
+	}
+	m, err := NewManager(db, "test", true)
 	if err != nil {
 		fmt.Printf("unable to connect to test db: %s\n", err)
 		os.Exit(1)
@@ -58,6 +70,85 @@ func getTestImage() *citadel.Image {
 	return img
 }
 
+// newTestManager returns a manager of engines keeping its documents in a
+// temporary embedded store.  Its cluster has no engines, so it has no
+// containers.
+func newTestManager(t *testing.T, engines ...*shipyard.Engine) *Manager {
+	dir, err := ioutil.TempDir("", "manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := datastore.NewEmbeddedStore(filepath.Join(dir, "shipyard.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.RemoveAll(dir)
+	})
+	m := &Manager{
+		db:               db,
+		engines:          engines,
+		authenticator:    &shipyard.Authenticator{},
+		subscribers:      make(map[chan *shipyard.Event]*shipyard.EventFilter),
+		supervised:       make(map[string][]*citadel.Container),
+		stopping:         make(map[string]bool),
+		rescheduled:      make(map[string][]string),
+		stranded:         make(map[string][]string),
+		deploying:        make(map[string]bool),
+		health:           make(map[string]*containerHealth),
+		cadvisorReadings: make(map[string]*cadvisorReading),
+	}
+	if err := m.initdb(); err != nil {
+		t.Fatal(err)
+	}
+	if m.clusterManager, err = cluster.New(&placementManager{manager: m}); err != nil {
+		t.Fatal(err)
+	}
+	m.schedulers = m.newSchedulers()
+	for typ, s := range m.schedulers {
+		m.clusterManager.RegisterScheduler(typ, s)
+	}
+	for _, eng := range engines {
+		if eng.Engine != nil && eng.Engine.IsConnected() {
+			m.clusterManager.AddEngine(eng.Engine)
+		}
+	}
+	return m
+}
+
+// newTestEngine returns a healthy engine connected to a docker api that
+// lists no containers and starts containers with ids prefixed by the engine
+// name
+func newTestEngine(t *testing.T, id, name string) *shipyard.Engine {
+	created := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/containers/create"):
+			created++
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"Id":"%s-%012d"}`, name, created)
+		case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/start"):
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/json") && !strings.HasSuffix(r.URL.Path, "/containers/json"):
+			fmt.Fprint(w, `{"Config":{},"HostConfig":{},"NetworkSettings":{"Ports":{}}}`)
+		case strings.HasSuffix(r.URL.Path, "/images/create"):
+			fmt.Fprint(w, `{"status":"pulled"}`)
+		case r.Method == "GET":
+			fmt.Fprint(w, "[]")
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	e := &citadel.Engine{ID: name, Addr: srv.URL, Cpus: 4, Memory: 4096}
+	if err := e.Connect(nil); err != nil {
+		t.Fatal(err)
+	}
+	return &shipyard.Engine{ID: id, Engine: e, Health: &shipyard.Health{Status: EngineHealthUp}}
+}
+
 func TestRun(t *testing.T) {
 	if os.Getenv("RUN_INTEGRATION_TEST") == "" {
 		t.Skipf("set RUN_INTEGRATION_TEST env var to run")
@@ -0,0 +1,124 @@
+package manager
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameContainerMetadata = "container_metadata"
+)
+
+// applyContainerMetadata replaces the labels and description of containers
+// with the metadata updated through the api
+func (m *Manager) applyContainerMetadata(containers ...*citadel.Container) {
+	m.metadataLock.Lock()
+	defer m.metadataLock.Unlock()
+	if m.metadata == nil || time.Since(m.metadataLoaded) > containerCacheTTL {
+		all := []*shipyard.ContainerMetadata{}
+		if err := m.db.Find(tblNameContainerMetadata, nil, &all); err != nil {
+			logger.Warnf("error loading container metadata: %s", err)
+			return
+		}
+		m.metadata = make(map[string]*shipyard.ContainerMetadata, len(all))
+		for _, md := range all {
+			m.metadata[md.ID] = md
+		}
+		m.metadataLoaded = time.Now()
+	}
+	for _, c := range containers {
+		md, ok := m.metadata[c.ID]
+		if !ok || c.Image == nil {
+			continue
+		}
+		if err := md.SetMetadata(c.Image); err != nil {
+			logger.Warnf("error applying metadata to container %s: %s", c.ID, err)
+		}
+	}
+}
+
+// UpdateContainer applies a patch to the labels and description of a
+// container without recreating it
+func (m *Manager) UpdateContainer(container *citadel.Container, patch *shipyard.ContainerPatch) error {
+	md, err := patch.Apply(container)
+	if err != nil {
+		return err
+	}
+	if err := m.db.Put(tblNameContainerMetadata, md); err != nil {
+		return err
+	}
+	m.cacheContainerMetadata(md.ID, md)
+	if err := md.SetMetadata(container.Image); err != nil {
+		return err
+	}
+	evt := &shipyard.Event{
+		Type:      "update-container",
+		Time:      time.Now(),
+		Container: container,
+		Engine:    container.Engine,
+		Message:   fmt.Sprintf("labels=%d description=%q", len(md.Labels), md.Description),
+		Tags:      []string{"docker"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// cacheContainerMetadata updates the cached metadata of a container, or
+// forgets it when md is nil
+func (m *Manager) cacheContainerMetadata(id string, md *shipyard.ContainerMetadata) {
+	m.metadataLock.Lock()
+	defer m.metadataLock.Unlock()
+	if m.metadata == nil {
+		return
+	}
+	if md == nil {
+		delete(m.metadata, id)
+		return
+	}
+	m.metadata[id] = md
+}
+
+// removeContainerMetadata forgets the metadata and restart count of a
+// removed container
+func (m *Manager) removeContainerMetadata(container *citadel.Container) {
+	if _, err := m.db.Delete(tblNameContainerMetadata, ds.ByID(container.ID)); err != nil {
+		logger.Warnf("error removing metadata of container %s: %s", container.ID, err)
+	}
+	m.cacheContainerMetadata(container.ID, nil)
+	if _, err := m.db.Delete(tblNameContainerRestarts, ds.ByID(container.ID)); err != nil {
+		logger.Warnf("error removing restarts of container %s: %s", container.ID, err)
+	}
+	m.cacheContainerRestarts(container.ID, nil)
+}
+
+// RenameContainer changes the name of a container
+func (m *Manager) RenameContainer(container *citadel.Container, name string) error {
+	if err := shipyard.ValidateContainerName(name); err != nil {
+		return err
+	}
+	resp, err := m.engineRequest(container.Engine, "POST", fmt.Sprintf("/containers/%s/rename?name=%s", container.ID, url.QueryEscape(name)), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	evt := &shipyard.Event{
+		Type:      "rename",
+		Time:      time.Now(),
+		Container: container,
+		Engine:    container.Engine,
+		Message:   fmt.Sprintf("from=%s to=%s", container.Name, name),
+		Tags:      []string{"docker"},
+	}
+	container.Name = "/" + name
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
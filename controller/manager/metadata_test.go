@@ -0,0 +1,35 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+func TestContainerMetadataCached(t *testing.T) {
+	m := newTestManager(t)
+	c := &citadel.Container{ID: "0123456789abcdef"}
+	listed := func() *citadel.Container {
+		l := &citadel.Container{ID: c.ID, Image: &citadel.Image{Environment: map[string]string{}}}
+		m.applyContainerMetadata(l)
+		return l
+	}
+	if err := m.db.Put(tblNameContainerMetadata, &shipyard.ContainerMetadata{ID: c.ID, Description: "web"}); err != nil {
+		t.Fatal(err)
+	}
+	if d := shipyard.ContainerDescription(listed()); d != "web" {
+		t.Fatalf("expected the stored description; received %q", d)
+	}
+	// changed by another controller
+	if err := m.db.Put(tblNameContainerMetadata, &shipyard.ContainerMetadata{ID: c.ID, Description: "api"}); err != nil {
+		t.Fatal(err)
+	}
+	if d := shipyard.ContainerDescription(listed()); d != "web" {
+		t.Errorf("expected the cached description; received %q", d)
+	}
+	m.removeContainerMetadata(c)
+	if d := shipyard.ContainerDescription(listed()); d != "" {
+		t.Errorf("expected the metadata of a removed container to be forgotten; received %q", d)
+	}
+}
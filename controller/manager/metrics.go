@@ -0,0 +1,268 @@
+package manager
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameMetrics = "metrics"
+
+	// metricsConcurrency bounds the stats requests sent to engines at once
+	metricsConcurrency = 8
+	cadvisorTimeout    = 10 * time.Second
+)
+
+var cadvisorClient = newCAdvisorClient(nil)
+
+// newCAdvisorClient returns a client for the cAdvisor of an engine; redirects
+// are not followed as they could lead off the engine host
+func newCAdvisorClient(tlsConfig *tls.Config) *http.Client {
+	c := &http.Client{
+		Timeout: cadvisorTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	if tlsConfig != nil {
+		c.Transport = &http.Transport{TLSClientConfig: tlsConfig, DisableKeepAlives: true}
+	}
+	return c
+}
+
+// cadvisorReading is a scraped usage kept to compute the cpu usage of the
+// next scrape
+type cadvisorReading struct {
+	usage *shipyard.CAdvisorUsage
+	time  time.Time
+}
+
+// CollectMetrics samples the resource usage of the running containers and
+// engines every interval and keeps the samples for window.  Engine totals
+// are stored every interval; container samples, of which there are many
+// more, only every containerInterval.
+func (m *Manager) CollectMetrics(interval time.Duration, containerInterval time.Duration, window time.Duration) {
+	m.metricsLock.Lock()
+	m.containerMetricsInterval = containerInterval
+	m.metricsLock.Unlock()
+	go m.sampleMetrics(interval, window)
+}
+
+func (m *Manager) sampleMetrics(interval time.Duration, window time.Duration) {
+	t := time.NewTicker(interval).C
+	for now := range t {
+		if !m.IsLeader() {
+			continue
+		}
+		if err := m.SampleMetrics(now); err != nil {
+			logger.Errorf("error sampling metrics: %s", err)
+		}
+		if _, err := m.db.Delete(tblNameMetrics, ds.Where(ds.Lt("time", now.Add(-window)))); err != nil {
+			logger.Errorf("error removing expired metrics: %s", err)
+		}
+	}
+}
+
+// SampleMetrics stores a sample of every running container and the totals
+// of every engine.  Containers of engines with a cAdvisor are sampled from
+// it, others, and those of engines whose cAdvisor does not answer, from the
+// docker stats api.  Containers whose engine does not answer are skipped.
+func (m *Manager) SampleMetrics(now time.Time) error {
+	containers := m.Containers(false)
+	scraped := m.scrapeCAdvisors(now, containers)
+	samples := make([]*shipyard.MetricSample, len(containers))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, metricsConcurrency)
+	for i, c := range containers {
+		if s, ok := scraped[c.ID]; ok {
+			samples[i] = s
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, c *citadel.Container) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			s, err := m.containerSample(c)
+			if err != nil {
+				logger.Warnf("error sampling container %s: %s", c.ID, err)
+				return
+			}
+			s.Time = now
+			samples[i] = s
+		}(i, c)
+	}
+	wg.Wait()
+	return m.saveMetricSamples(now, containers, samples)
+}
+
+// saveMetricSamples stores the samples of containers, nil for those that
+// were not sampled, and the totals of every engine.  Engine samples are
+// identified by the cluster engine id like the engines of containers.
+func (m *Manager) saveMetricSamples(now time.Time, containers []*citadel.Container, samples []*shipyard.MetricSample) error {
+	m.metricsLock.Lock()
+	saveContainers := now.Sub(m.containerSamplesSaved) >= m.containerMetricsInterval
+	if saveContainers {
+		m.containerSamplesSaved = now
+	}
+	m.metricsLock.Unlock()
+
+	byEngine := map[string][]*shipyard.MetricSample{}
+	latest := []*shipyard.MetricSample{}
+	for i, s := range samples {
+		if s == nil {
+			continue
+		}
+		if e := containers[i].Engine; e != nil {
+			byEngine[e.ID] = append(byEngine[e.ID], s)
+		}
+		latest = append(latest, s)
+		if !saveContainers {
+			continue
+		}
+		if _, err := m.db.Insert(tblNameMetrics, s); err != nil {
+			return err
+		}
+	}
+	m.metricsLock.Lock()
+	m.latestSamples = latest
+	m.metricsLock.Unlock()
+	for _, e := range m.Engines() {
+		if e.Engine == nil {
+			continue
+		}
+		s := shipyard.EngineSample(e.Engine.ID, e.Engine.Memory, now, byEngine[e.Engine.ID])
+		if _, err := m.db.Insert(tblNameMetrics, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scrapeCAdvisors samples the running containers of the engines with a
+// cAdvisor by container id
+func (m *Manager) scrapeCAdvisors(now time.Time, containers []*citadel.Container) map[string]*shipyard.MetricSample {
+	running := map[string]bool{}
+	for _, c := range containers {
+		running[c.ID] = true
+	}
+	samples := map[string]*shipyard.MetricSample{}
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, e := range m.Engines() {
+		if e.CAdvisor == nil || e.Engine == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(e *shipyard.Engine) {
+			defer wg.Done()
+			usage, err := m.scrapeCAdvisor(e)
+			if err != nil {
+				logger.Warnf("error scraping cadvisor of engine %s: %s", e.ID, err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			m.metricsLock.Lock()
+			defer m.metricsLock.Unlock()
+			for id, u := range usage {
+				if !running[id] {
+					continue
+				}
+				var (
+					prev    *shipyard.CAdvisorUsage
+					elapsed time.Duration
+				)
+				if r, ok := m.cadvisorReadings[id]; ok {
+					prev, elapsed = r.usage, now.Sub(r.time)
+				}
+				samples[id] = u.Sample(id, now, prev, elapsed)
+				m.cadvisorReadings[id] = &cadvisorReading{usage: u, time: now}
+			}
+		}(e)
+	}
+	wg.Wait()
+	// forget containers that stopped or were removed
+	m.metricsLock.Lock()
+	for id := range m.cadvisorReadings {
+		if !running[id] {
+			delete(m.cadvisorReadings, id)
+		}
+	}
+	m.metricsLock.Unlock()
+	return samples
+}
+
+// scrapeCAdvisor reads the container usage from the cAdvisor of an engine;
+// an https cAdvisor is verified with the certificates of the engine
+func (m *Manager) scrapeCAdvisor(e *shipyard.Engine) (map[string]*shipyard.CAdvisorUsage, error) {
+	u, err := e.CAdvisor.MetricsURL(e.Engine.Addr)
+	if err != nil {
+		return nil, err
+	}
+	client := cadvisorClient
+	if strings.HasPrefix(u, "https:") {
+		tlsConfig, err := engineTLSConfig(e)
+		if err != nil {
+			return nil, err
+		}
+		client = newCAdvisorClient(tlsConfig)
+	}
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", u, resp.Status)
+	}
+	return shipyard.ParseCAdvisorMetrics(resp.Body)
+}
+
+// containerSample reads a single stats sample of a container from its engine
+func (m *Manager) containerSample(c *citadel.Container) (*shipyard.MetricSample, error) {
+	var s *dockerStats
+	if err := m.engineJSON(c.Engine, "GET", fmt.Sprintf("/containers/%s/stats?stream=false", c.ID), nil, &s); err != nil {
+		return nil, err
+	}
+	sample := &shipyard.MetricSample{
+		Resource:    shipyard.MetricsContainers,
+		ResourceID:  c.ID,
+		Source:      shipyard.MetricsSourceDocker,
+		CpuPercent:  s.CpuStats.cpuPercent(&s.PreCpuStats),
+		MemoryUsage: s.MemoryStats.Usage,
+		MemoryLimit: s.MemoryStats.Limit,
+	}
+	sample.DiskReadBytes, sample.DiskWriteBytes = s.disk()
+	sample.NetworkRxBytes, sample.NetworkTxBytes = s.network()
+	return sample, nil
+}
+
+// Metrics returns the samples of a container or engine taken since, oldest
+// first
+func (m *Manager) Metrics(resource string, id string, since time.Time) ([]*shipyard.MetricSample, error) {
+	if err := shipyard.ValidateMetricsResource(resource); err != nil {
+		return nil, err
+	}
+	q := ds.Where(ds.Eq("resource", resource), ds.Eq("resource_id", id))
+	if !since.IsZero() {
+		q.Where = append(q.Where, ds.Ge("time", since))
+	}
+	samples := []*shipyard.MetricSample{}
+	if err := m.db.Find(tblNameMetrics, q.Sort("time", false), &samples); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
@@ -0,0 +1,138 @@
+package manager
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+func TestSaveMetricSamples(t *testing.T) {
+	node1 := &citadel.Engine{ID: "node-1", Memory: 1024}
+	node2 := &citadel.Engine{ID: "node-2", Memory: 2048}
+	m := newTestManager(t,
+		&shipyard.Engine{ID: "5b0c3e9a", Engine: node1},
+		&shipyard.Engine{ID: "7d41f2c8", Engine: node2},
+	)
+	m.containerMetricsInterval = time.Minute
+	containers := []*citadel.Container{
+		{ID: "a", Engine: node1},
+		{ID: "b", Engine: node1},
+		{ID: "c", Engine: node2},
+		{ID: "d", Engine: node2},
+	}
+	sample := func(id string, cpu float64) *shipyard.MetricSample {
+		return &shipyard.MetricSample{Resource: shipyard.MetricsContainers, ResourceID: id, CpuPercent: cpu}
+	}
+	now := time.Now()
+	// d was not sampled
+	samples := []*shipyard.MetricSample{sample("a", 10), sample("b", 20), sample("c", 5), nil}
+	if err := m.saveMetricSamples(now, containers, samples); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		engine     string
+		containers int
+		cpu        float64
+	}{
+		{"node-1", 2, 30},
+		{"node-2", 1, 5},
+	} {
+		s, err := m.Metrics(shipyard.MetricsEngines, test.engine, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(s) != 1 {
+			t.Fatalf("%s: expected 1 sample; received %d", test.engine, len(s))
+		}
+		if s[0].Containers != test.containers || s[0].CpuPercent != test.cpu {
+			t.Errorf("%s: unexpected sample %+v", test.engine, s[0])
+		}
+	}
+
+	// container samples are only stored every container interval
+	if err := m.saveMetricSamples(now.Add(30*time.Second), containers, samples); err != nil {
+		t.Fatal(err)
+	}
+	s, err := m.Metrics(shipyard.MetricsContainers, "a", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 1 {
+		t.Errorf("expected 1 container sample; received %d", len(s))
+	}
+	if s, _ := m.Metrics(shipyard.MetricsEngines, "node-1", time.Time{}); len(s) != 2 {
+		t.Errorf("expected 2 engine samples; received %d", len(s))
+	}
+	if latest := m.latestSamples; len(latest) != 3 {
+		t.Errorf("expected 3 latest samples; received %d", len(latest))
+	}
+}
+
+func TestScrapeCAdvisor(t *testing.T) {
+	const id = "4f66ad9a0b2e4f2aa0d8e4b3b1c3d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8"
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "container_memory_usage_bytes{id=\"/docker/%s\"} 1024\n", id)
+	})
+	m := newTestManager(t)
+	scrape := func(srv *httptest.Server, cadvisor *shipyard.CAdvisor, e *shipyard.Engine) (map[string]*shipyard.CAdvisorUsage, error) {
+		u, _ := url.Parse(srv.URL)
+		e.Engine = &citadel.Engine{ID: "node-1", Addr: "tcp://" + u.Hostname() + ":2376"}
+		e.CAdvisor = cadvisor
+		return m.scrapeCAdvisor(e)
+	}
+	port := func(srv *httptest.Server) int {
+		u, _ := url.Parse(srv.URL)
+		p, _ := strconv.Atoi(u.Port())
+		return p
+	}
+
+	srv := httptest.NewServer(metrics)
+	defer srv.Close()
+	usage, err := scrape(srv, &shipyard.CAdvisor{Port: port(srv)}, &shipyard.Engine{})
+	if err != nil || usage[id] == nil || usage[id].MemoryUsage != 1024 {
+		t.Fatalf("expected the usage of the engine cadvisor; received %v %v", usage, err)
+	}
+	if _, err := scrape(srv, &shipyard.CAdvisor{URL: "http://169.254.169.254/latest"}, &shipyard.Engine{}); !errors.Is(err, shipyard.ErrInvalidCAdvisor) {
+		t.Errorf("expected a cadvisor off the engine host to be refused; received %v", err)
+	}
+
+	redirect := httptest.NewServer(http.RedirectHandler(srv.URL+"/metrics", http.StatusFound))
+	defer redirect.Close()
+	if _, err := scrape(redirect, &shipyard.CAdvisor{Port: port(redirect)}, &shipyard.Engine{}); err == nil {
+		t.Error("expected a redirect not to be followed")
+	}
+
+	tlsSrv := httptest.NewTLSServer(metrics)
+	defer tlsSrv.Close()
+	cert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsSrv.Certificate().Raw}))
+	key, err := x509.MarshalPKCS8PrivateKey(tlsSrv.TLS.Certificates[0].PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &shipyard.Engine{
+		CACertificate:  cert,
+		SSLCertificate: cert,
+		SSLKey:         string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key})),
+	}
+	cadvisor := &shipyard.CAdvisor{URL: tlsSrv.URL}
+	if usage, err := scrape(tlsSrv, cadvisor, e); err != nil || usage[id] == nil {
+		t.Errorf("expected the cadvisor to be verified with the engine ca; received %v %v", usage, err)
+	}
+	if _, err := scrape(tlsSrv, cadvisor, &shipyard.Engine{}); err == nil {
+		t.Error("expected a cadvisor not signed by the engine ca to be refused")
+	}
+}
@@ -0,0 +1,155 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameNamespaces = "namespaces"
+)
+
+var (
+	ErrNamespaceExists       = errors.New("namespace already exists")
+	ErrNamespaceDoesNotExist = errors.New("namespace does not exist")
+	ErrNamespaceInUse        = errors.New("namespace has containers")
+)
+
+func (m *Manager) Namespaces() ([]*shipyard.Namespace, error) {
+	namespaces := []*shipyard.Namespace{}
+	if err := m.db.Find(tblNameNamespaces, ds.Where().Sort("name", false), &namespaces); err != nil {
+		return nil, err
+	}
+	return namespaces, nil
+}
+
+func (m *Manager) Namespace(name string) (*shipyard.Namespace, error) {
+	var ns *shipyard.Namespace
+	if err := m.db.FindOne(tblNameNamespaces, ds.Where(ds.Eq("name", name)), &ns); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrNamespaceDoesNotExist
+		}
+		return nil, err
+	}
+	return ns, nil
+}
+
+func (m *Manager) CreateNamespace(ns *shipyard.Namespace) error {
+	if err := ns.Validate(); err != nil {
+		return err
+	}
+	if _, err := m.Namespace(ns.Name); err == nil {
+		return ErrNamespaceExists
+	} else if err != ErrNamespaceDoesNotExist {
+		return err
+	}
+	ns.ID = ""
+	ns.Created = time.Now()
+	id, err := m.db.Insert(tblNameNamespaces, ns)
+	if err != nil {
+		return err
+	}
+	ns.ID = id
+	evt := &shipyard.Event{
+		Type:    "create-namespace",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s", ns.Name),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteNamespace removes an empty namespace and the roles granted in it
+func (m *Manager) DeleteNamespace(name string) error {
+	ns, err := m.Namespace(name)
+	if err != nil {
+		return err
+	}
+	if len(m.NamespaceContainers(name, true)) > 0 {
+		return ErrNamespaceInUse
+	}
+	if _, err := m.db.Delete(tblNameNamespaces, ds.ByID(ns.ID)); err != nil {
+		return err
+	}
+	accounts, err := m.Accounts()
+	if err != nil {
+		return err
+	}
+	for _, acct := range accounts {
+		if acct.NamespaceRole(name) == "" {
+			continue
+		}
+		delete(acct.NamespaceRoles, name)
+		if _, err := m.db.Update(tblNameAccounts, ds.ByID(acct.ID), map[string]interface{}{"namespace_roles": ds.Literal{Value: acct.NamespaceRoles}}); err != nil {
+			return err
+		}
+	}
+	evt := &shipyard.Event{
+		Type:    "delete-namespace",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s", name),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// NamespaceContainers returns the containers launched in a namespace
+func (m *Manager) NamespaceContainers(name string, all bool) []*citadel.Container {
+	containers := []*citadel.Container{}
+	for _, c := range m.Containers(all) {
+		if shipyard.ContainerNamespace(c) == name {
+			containers = append(containers, c)
+		}
+	}
+	return containers
+}
+
+// SetAccountNamespaceRole grants an account a role in a namespace; an empty
+// role name revokes it
+func (m *Manager) SetAccountNamespaceRole(username string, namespace string, roleName string) error {
+	acct, err := m.Account(username)
+	if err != nil {
+		return err
+	}
+	if _, err := m.Namespace(namespace); err != nil {
+		return err
+	}
+	if roleName != "" {
+		if _, err := m.Role(roleName); err != nil {
+			return err
+		}
+	}
+	roles := map[string]string{}
+	for ns, role := range acct.NamespaceRoles {
+		roles[ns] = role
+	}
+	if roleName == "" {
+		delete(roles, namespace)
+	} else {
+		roles[namespace] = roleName
+	}
+	if _, err := m.db.Update(tblNameAccounts, ds.ByID(acct.ID), map[string]interface{}{"namespace_roles": ds.Literal{Value: roles}}); err != nil {
+		return err
+	}
+	evt := &shipyard.Event{
+		Type:    "update-account",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("username=%s namespace=%s role=%s", acct.Username, namespace, roleName),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameNotifiers = "notifiers"
+)
+
+var (
+	ErrNotifierDoesNotExist = errors.New("notifier does not exist")
+)
+
+func (m *Manager) Notifiers() ([]*shipyard.Notifier, error) {
+	notifiers := []*shipyard.Notifier{}
+	if err := m.db.Find(tblNameNotifiers, ds.Where().Sort("name", false), &notifiers); err != nil {
+		return nil, err
+	}
+	return notifiers, nil
+}
+
+func (m *Manager) Notifier(id string) (*shipyard.Notifier, error) {
+	var n *shipyard.Notifier
+	if err := m.db.Get(tblNameNotifiers, id, &n); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrNotifierDoesNotExist
+		}
+		return nil, err
+	}
+	return n, nil
+}
+
+func (m *Manager) AddNotifier(n *shipyard.Notifier) error {
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	n.ID = ""
+	id, err := m.db.Insert(tblNameNotifiers, n)
+	if err != nil {
+		return err
+	}
+	n.ID = id
+	evt := &shipyard.Event{
+		Type:    "add-notifier",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("id=%s name=%s type=%s", n.ID, n.Name, n.Type),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) RemoveNotifier(id string) error {
+	n, err := m.Notifier(id)
+	if err != nil {
+		return err
+	}
+	if _, err := m.db.Delete(tblNameNotifiers, ds.ByID(n.ID)); err != nil {
+		return err
+	}
+	evt := &shipyard.Event{
+		Type:    "remove-notifier",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("id=%s name=%s type=%s", n.ID, n.Name, n.Type),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// dispatchNotifications sends every saved event to the matching notifiers
+func (m *Manager) dispatchNotifications() {
+	events := m.SubscribeEvents(nil)
+	for evt := range events {
+		if !m.IsLeader() {
+			continue
+		}
+		notifiers, err := m.Notifiers()
+		if err != nil {
+			logger.Errorf("error loading notifiers: %s", err)
+			continue
+		}
+		for _, n := range notifiers {
+			if !n.Match(evt) {
+				continue
+			}
+			go func(n *shipyard.Notifier) {
+				if err := notify(n, evt); err != nil {
+					logger.Warnf("error sending %s event to notifier %s: %s", evt.Type, n.Name, err)
+				}
+			}(n)
+		}
+	}
+}
+
+func notify(n *shipyard.Notifier, evt *shipyard.Event) error {
+	switch n.Type {
+	case shipyard.NotifierSlack:
+		return notifySlack(n.Slack, evt)
+	case shipyard.NotifierEmail:
+		return notifyEmail(n.Email, evt)
+	}
+	return fmt.Errorf("unknown notifier type %s", n.Type)
+}
+
+// notificationText is the one line summary of an event sent by notifiers
+func notificationText(evt *shipyard.Event) string {
+	text := fmt.Sprintf("[%s] %s: %s", evt.Severity, evt.Type, evt.Message)
+	if evt.Engine != nil {
+		text += fmt.Sprintf(" (engine %s)", evt.Engine.ID)
+	}
+	return text
+}
+
+func notifySlack(cfg *shipyard.SlackConfig, evt *shipyard.Event) error {
+	msg := map[string]string{
+		"text": notificationText(evt),
+	}
+	if cfg.Channel != "" {
+		msg["channel"] = cfg.Channel
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	resp, err := webhookClient.Post(cfg.WebhookURL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func notifyEmail(cfg *shipyard.EmailConfig, evt *shipyard.Event) error {
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		host, _, err := net.SplitHostPort(cfg.SMTPAddr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+	subject := fmt.Sprintf("[shipyard] %s %s", evt.Severity, evt.Type)
+	body := &bytes.Buffer{}
+	fmt.Fprintf(body, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(body, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(body, "Subject: %s\r\n", subject)
+	fmt.Fprintf(body, "Date: %s\r\n", evt.Time.Format(time.RFC1123Z))
+	fmt.Fprint(body, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(body, "%s\r\n\r\nTime: %s\r\n", notificationText(evt), evt.Time.Format(time.RFC3339))
+	return smtp.SendMail(cfg.SMTPAddr, auth, cfg.From, cfg.To, body.Bytes())
+}
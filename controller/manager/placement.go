@@ -0,0 +1,219 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+// SetPlacement sets the strategy containers without one of their own are
+// placed with
+func (m *Manager) SetPlacement(strategy string) error {
+	if err := shipyard.ValidatePlacement(strategy); err != nil {
+		return err
+	}
+	m.placement = strategy
+	return nil
+}
+
+// Placement returns the default placement strategy
+func (m *Manager) Placement() string {
+	if m.placement == "" {
+		return shipyard.DefaultPlacement
+	}
+	return m.placement
+}
+
+// placementManager picks the engine of a container among the engines
+// accepted by the schedulers using the placement strategy of the
+// container or the default one
+type placementManager struct {
+	manager *Manager
+}
+
+func (p *placementManager) PlaceContainer(c *citadel.Container, engines []*citadel.EngineSnapshot) (*citadel.EngineSnapshot, error) {
+	strategy := shipyard.ContainerPlacement(c.Image)
+	if strategy == "" {
+		strategy = p.manager.Placement()
+	}
+	affinity, err := shipyard.ContainerAffinity(c.Image)
+	if err != nil {
+		return nil, err
+	}
+	if affinity != nil && affinity.SpreadBy != "" {
+		if engines, err = p.spread(c.Image, affinity.SpreadBy, engines); err != nil {
+			return nil, err
+		}
+	}
+	loads := []*shipyard.EngineLoad{}
+	snapshots := map[string]*citadel.EngineSnapshot{}
+	for _, e := range engines {
+		snapshots[e.ID] = e
+		loads = append(loads, &shipyard.EngineLoad{
+			ID:             e.ID,
+			Cpus:           e.Cpus,
+			Memory:         e.Memory,
+			ReservedCpus:   e.ReservedCpus,
+			ReservedMemory: e.ReservedMemory,
+		})
+	}
+	var labelAffinity map[string]int
+	if strategy == shipyard.PlacementLabelAffinity {
+		labelAffinity = p.labelAffinity(c.Image, snapshots)
+	}
+	id, err := shipyard.PlaceContainer(strategy, loads, c.Image.Cpus, c.Image.Memory, labelAffinity)
+	if err != nil {
+		return nil, err
+	}
+	return snapshots[id], nil
+}
+
+// spread keeps the engines a replica of image may be placed on when
+// replicas are balanced across the values of the engine label key.  The
+// zones are those of the engines accepted by the schedulers, whose
+// containers are listed once per placement.
+func (p *placementManager) spread(image *citadel.Image, key string, engines []*citadel.EngineSnapshot) ([]*citadel.EngineSnapshot, error) {
+	byID := map[string]*shipyard.Engine{}
+	for _, e := range p.manager.Engines() {
+		if e.Engine != nil {
+			byID[e.Engine.ID] = e
+		}
+	}
+	zones := map[string]string{}
+	containers := []*citadel.Container{}
+	for _, s := range engines {
+		eng := byID[s.ID]
+		if eng == nil {
+			continue
+		}
+		zones[s.ID] = shipyard.ParseLabels(eng.Engine.Labels)[key]
+		running, err := eng.Engine.ListContainers(false, false, "")
+		if err != nil {
+			return nil, err
+		}
+		containers = append(containers, running...)
+	}
+	counts := shipyard.SpreadCounts(image, zones, containers)
+	allowed := []*citadel.EngineSnapshot{}
+	for _, s := range engines {
+		if zone, ok := zones[s.ID]; ok && shipyard.SpreadAllows(zone, counts) {
+			allowed = append(allowed, s)
+		}
+	}
+	return allowed, nil
+}
+
+// labelAffinity counts the running containers sharing a label with image
+// on each engine.  The cluster is locked while placing, so the containers
+// are those of the last listing of the running containers; containers
+// started since are not counted.
+func (p *placementManager) labelAffinity(image *citadel.Image, snapshots map[string]*citadel.EngineSnapshot) map[string]int {
+	p.manager.runningLock.Lock()
+	running := p.manager.running
+	p.manager.runningLock.Unlock()
+	byEngine := map[string][]*citadel.Container{}
+	for _, c := range running {
+		if c.Engine != nil && snapshots[c.Engine.ID] != nil {
+			byEngine[c.Engine.ID] = append(byEngine[c.Engine.ID], c)
+		}
+	}
+	affinity := map[string]int{}
+	for id := range snapshots {
+		affinity[id] = shipyard.LabelAffinity(image, byEngine[id])
+	}
+	return affinity
+}
+
+// PreviewRun places count containers of image like Run without starting
+// them.  Reservations of previewed containers are accounted for while
+// placing the next one; scheduler checks against running containers,
+// such as affinities, only see the containers already running.
+func (m *Manager) PreviewRun(image *citadel.Image, count int) (*shipyard.RunPreview, error) {
+	return m.previewRun(image, count, "")
+}
+
+// placeExcluding returns the cluster engine id of the engine a container of
+// image would be placed on if the engine exclude was not in the cluster
+func (m *Manager) placeExcluding(image *citadel.Image, exclude string) (string, error) {
+	preview, err := m.previewRun(image, 1, exclude)
+	if err != nil {
+		return "", err
+	}
+	if len(preview.Placements) == 0 {
+		return "", errors.New(preview.Error)
+	}
+	return preview.Placements[0].Engine, nil
+}
+
+// previewRun is PreviewRun leaving out the engine with the cluster engine
+// id exclude, which may be empty
+func (m *Manager) previewRun(image *citadel.Image, count int, exclude string) (*shipyard.RunPreview, error) {
+	s := m.schedulers[image.Type]
+	if s == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownImageType, image.Type)
+	}
+	snapshots := []*citadel.EngineSnapshot{}
+	for _, eng := range m.Engines() {
+		if eng.Engine.ID == exclude {
+			continue
+		}
+		ok, err := s.Schedule(image, eng.Engine)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		containers, err := eng.Engine.ListContainers(false, false, "")
+		if err != nil {
+			return nil, err
+		}
+		snapshot := &citadel.EngineSnapshot{
+			ID:     eng.Engine.ID,
+			Cpus:   eng.Engine.Cpus,
+			Memory: eng.Engine.Memory,
+		}
+		for _, c := range containers {
+			snapshot.ReservedCpus += c.Image.Cpus
+			snapshot.ReservedMemory += c.Image.Memory
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	preview := &shipyard.RunPreview{
+		Placements: []*shipyard.RunPlacement{},
+		Engines:    []*shipyard.EngineLoad{},
+	}
+	placer := &placementManager{manager: m}
+	for i := 0; i < count; i++ {
+		if len(snapshots) == 0 {
+			preview.Unplaced = count - i
+			preview.Error = "no eligible engines to run image"
+			break
+		}
+		snapshot, err := placer.PlaceContainer(&citadel.Container{Image: image}, snapshots)
+		if err != nil {
+			preview.Unplaced = count - i
+			preview.Error = err.Error()
+			break
+		}
+		snapshot.ReservedCpus += image.Cpus
+		snapshot.ReservedMemory += image.Memory
+		preview.Placements = append(preview.Placements, &shipyard.RunPlacement{
+			Engine: snapshot.ID,
+			Cpus:   image.Cpus,
+			Memory: image.Memory,
+		})
+	}
+	for _, snapshot := range snapshots {
+		preview.Engines = append(preview.Engines, &shipyard.EngineLoad{
+			ID:             snapshot.ID,
+			Cpus:           snapshot.Cpus,
+			Memory:         snapshot.Memory,
+			ReservedCpus:   snapshot.ReservedCpus,
+			ReservedMemory: snapshot.ReservedMemory,
+		})
+	}
+	return preview, nil
+}
@@ -0,0 +1,59 @@
+package manager
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+func TestPreviewRunSkipsCordoned(t *testing.T) {
+	cordoned := newTestEngine(t, "5b0c3e9a", "node-1")
+	cordoned.Cordoned = true
+	m := newTestManager(t, cordoned, newTestEngine(t, "7d41f2c8", "node-2"))
+
+	preview, err := m.PreviewRun(&citadel.Image{Name: "web", Type: "service", Cpus: 1, Memory: 256}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range preview.Placements {
+		if p.Engine != "node-2" {
+			t.Errorf("expected no placement on the cordoned engine; received %s", p.Engine)
+		}
+	}
+	if _, err := m.PreviewRun(&citadel.Image{Name: "web", Type: "bogus"}, 1); !errors.Is(err, ErrUnknownImageType) {
+		t.Errorf("expected ErrUnknownImageType; received %v", err)
+	}
+}
+
+func TestLabelAffinityPlacement(t *testing.T) {
+	first := newTestEngine(t, "5b0c3e9a", "node-1")
+	second := newTestEngine(t, "7d41f2c8", "node-2")
+	m := newTestManager(t, first, second)
+
+	labeled := func() *citadel.Image {
+		image := &citadel.Image{Name: "web", Type: "service", Cpus: 1, Memory: 256}
+		if err := shipyard.SetLabels(image, map[string]string{"tier": "web"}); err != nil {
+			t.Fatal(err)
+		}
+		return image
+	}
+	m.running = []*citadel.Container{
+		{ID: "node-2-000000000001", Engine: second.Engine, Image: labeled()},
+	}
+
+	image := labeled()
+	if err := shipyard.SetPlacement(image, shipyard.PlacementLabelAffinity); err != nil {
+		t.Fatal(err)
+	}
+	preview, err := m.PreviewRun(image, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range preview.Placements {
+		if p.Engine != "node-2" {
+			t.Errorf("expected placement next to the labeled container on node-2; received %s", p.Engine)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameEnginePools = "engine_pools"
+	poolCacheTTL       = 10 * time.Second
+)
+
+var (
+	ErrEnginePoolExists       = errors.New("engine pool already exists")
+	ErrEnginePoolDoesNotExist = errors.New("engine pool does not exist")
+	ErrEnginePoolInUse        = errors.New("engine pool is used by applications, jobs or templates")
+)
+
+// EnginePools returns every engine pool with the engines in it
+func (m *Manager) EnginePools() ([]*shipyard.EnginePool, error) {
+	pools := []*shipyard.EnginePool{}
+	if err := m.db.Find(tblNameEnginePools, ds.Where().Sort("name", false), &pools); err != nil {
+		return nil, err
+	}
+	for _, p := range pools {
+		p.Engines = m.poolEngines(p)
+	}
+	return pools, nil
+}
+
+func (m *Manager) EnginePool(name string) (*shipyard.EnginePool, error) {
+	var pool *shipyard.EnginePool
+	if err := m.db.FindOne(tblNameEnginePools, ds.Where(ds.Eq("name", name)), &pool); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrEnginePoolDoesNotExist
+		}
+		return nil, err
+	}
+	pool.Engines = m.poolEngines(pool)
+	return pool, nil
+}
+
+func (m *Manager) CreateEnginePool(pool *shipyard.EnginePool) error {
+	if err := pool.Validate(); err != nil {
+		return err
+	}
+	if _, err := m.EnginePool(pool.Name); err == nil {
+		return ErrEnginePoolExists
+	} else if err != ErrEnginePoolDoesNotExist {
+		return err
+	}
+	pool.ID = ""
+	pool.Engines = nil
+	id, err := m.db.Insert(tblNameEnginePools, pool)
+	if err != nil {
+		return err
+	}
+	pool.ID = id
+	pool.Engines = m.poolEngines(pool)
+	m.forgetEnginePools()
+	evt := &shipyard.Event{
+		Type:    "create-engine-pool",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s constraints=%s", pool.Name, strings.Join(pool.Constraints, " ")),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateEnginePool replaces the description and constraints of a pool.
+// Running containers stay where they are.
+func (m *Manager) UpdateEnginePool(pool *shipyard.EnginePool) error {
+	if err := pool.Validate(); err != nil {
+		return err
+	}
+	current, err := m.EnginePool(pool.Name)
+	if err != nil {
+		return err
+	}
+	pool.ID = current.ID
+	pool.Engines = nil
+	if err := m.db.Put(tblNameEnginePools, pool); err != nil {
+		return err
+	}
+	pool.Engines = m.poolEngines(pool)
+	m.forgetEnginePools()
+	evt := &shipyard.Event{
+		Type:    "update-engine-pool",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s constraints=%s", pool.Name, strings.Join(pool.Constraints, " ")),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteEnginePool removes a pool no application, job or template places
+// containers in
+func (m *Manager) DeleteEnginePool(name string) error {
+	pool, err := m.EnginePool(name)
+	if err != nil {
+		return err
+	}
+	apps, err := m.Applications()
+	if err != nil {
+		return err
+	}
+	for _, app := range apps {
+		if app.Pool == name {
+			return ErrEnginePoolInUse
+		}
+	}
+	jobs, err := m.Jobs()
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if shipyard.ContainerPool(job.Image) == name {
+			return ErrEnginePoolInUse
+		}
+	}
+	templates, err := m.Templates()
+	if err != nil {
+		return err
+	}
+	for _, t := range templates {
+		if t.Pool == name {
+			return ErrEnginePoolInUse
+		}
+	}
+	if _, err := m.db.Delete(tblNameEnginePools, ds.ByID(pool.ID)); err != nil {
+		return err
+	}
+	m.forgetEnginePools()
+	evt := &shipyard.Event{
+		Type:    "delete-engine-pool",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s", name),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// schedulingPool returns an engine pool for placing containers.  Pools
+// are cached for poolCacheTTL so schedulers do not query the datastore for
+// every engine; pools changed by other controllers are picked up after it.
+func (m *Manager) schedulingPool(name string) (*shipyard.EnginePool, error) {
+	m.poolsLock.Lock()
+	defer m.poolsLock.Unlock()
+	if m.pools == nil || time.Since(m.poolsLoaded) > poolCacheTTL {
+		all := []*shipyard.EnginePool{}
+		if err := m.db.Find(tblNameEnginePools, nil, &all); err != nil {
+			return nil, err
+		}
+		m.pools = make(map[string]*shipyard.EnginePool, len(all))
+		for _, p := range all {
+			m.pools[p.Name] = p
+		}
+		m.poolsLoaded = time.Now()
+	}
+	pool, ok := m.pools[name]
+	if !ok {
+		return nil, ErrEnginePoolDoesNotExist
+	}
+	return pool, nil
+}
+
+// forgetEnginePools drops the cached engine pools after a change
+func (m *Manager) forgetEnginePools() {
+	m.poolsLock.Lock()
+	defer m.poolsLock.Unlock()
+	m.pools = nil
+}
+
+// poolEngines returns the ids of the engines in a pool
+func (m *Manager) poolEngines(pool *shipyard.EnginePool) []string {
+	ids := []string{}
+	for _, e := range m.Engines() {
+		if pool.Match(e.Engine.Labels) {
+			ids = append(ids, e.Engine.ID)
+		}
+	}
+	return ids
+}
+
+// checkApplicationPool rejects applications placed in an unknown pool
+func (m *Manager) checkApplicationPool(app *shipyard.Application) error {
+	return m.checkPool(app.Pool, shipyard.ErrInvalidApplication)
+}
+
+// checkPool returns invalid wrapped when the pool name is set and does not
+// exist
+func (m *Manager) checkPool(name string, invalid error) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := m.EnginePool(name); err != nil {
+		if err == ErrEnginePoolDoesNotExist {
+			return fmt.Errorf("%w: engine pool %s does not exist", invalid, name)
+		}
+		return err
+	}
+	return nil
+}
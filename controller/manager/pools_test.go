@@ -0,0 +1,141 @@
+package manager
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+// zoneEngine is a docker api listing replicas of the web image that counts
+// the container listings
+type zoneEngine struct {
+	mu       sync.Mutex
+	listings int
+}
+
+func newZoneEngine(t *testing.T, id, name, zone string, replicas int) (*zoneEngine, *shipyard.Engine) {
+	z := &zoneEngine{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			z.mu.Lock()
+			z.listings++
+			z.mu.Unlock()
+			listed := []string{}
+			for i := 0; i < replicas; i++ {
+				listed = append(listed, fmt.Sprintf(`{"Id":"%s-%012d","Image":"web"}`, name, i))
+			}
+			fmt.Fprintf(w, "[%s]", strings.Join(listed, ","))
+		case strings.HasSuffix(r.URL.Path, "/json"):
+			fmt.Fprint(w, `{"State":{"Running":true},"Config":{},"HostConfig":{},"NetworkSettings":{"Ports":{}}}`)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	e := &citadel.Engine{ID: name, Addr: srv.URL, Cpus: 4, Memory: 4096, Labels: []string{"zone=" + zone}}
+	if err := e.Connect(nil); err != nil {
+		t.Fatal(err)
+	}
+	return z, &shipyard.Engine{ID: id, Engine: e, Health: &shipyard.Health{Status: EngineHealthUp}}
+}
+
+func (z *zoneEngine) count() int {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	return z.listings
+}
+
+func TestSpreadPlacement(t *testing.T) {
+	first, a1 := newZoneEngine(t, "5b0c3e9a", "node-1", "a", 1)
+	_, b := newZoneEngine(t, "7d41f2c8", "node-2", "b", 0)
+	_, a2 := newZoneEngine(t, "9e6a1b47", "node-3", "a", 0)
+	m := newTestManager(t, a1, b, a2)
+
+	image := &citadel.Image{Name: "web", Type: "service"}
+	if err := shipyard.SetAffinity(image, &shipyard.Affinity{SpreadBy: "zone"}); err != nil {
+		t.Fatal(err)
+	}
+	snapshots := []*citadel.EngineSnapshot{}
+	for _, e := range []*shipyard.Engine{a1, b, a2} {
+		snapshots = append(snapshots, &citadel.EngineSnapshot{ID: e.Engine.ID, Cpus: 4, Memory: 4096})
+	}
+	p := &placementManager{manager: m}
+	placed, err := p.PlaceContainer(&citadel.Container{Image: image}, snapshots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if placed.ID != "node-2" {
+		t.Errorf("expected the replica in the zone without one; placed on %s", placed.ID)
+	}
+	if n := first.count(); n != 1 {
+		t.Errorf("expected the containers of an engine to be listed once per placement; listed %d times", n)
+	}
+}
+
+func TestSchedulingPoolCached(t *testing.T) {
+	m := newTestManager(t)
+	if err := m.CreateEnginePool(&shipyard.EnginePool{Name: "gpu", Constraints: []string{"gpu=true"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.schedulingPool("gpu"); err != nil {
+		t.Fatal(err)
+	}
+	// removed by another controller
+	if _, err := m.db.Delete(tblNameEnginePools, ds.Where(ds.Eq("name", "gpu"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.schedulingPool("gpu"); err != nil {
+		t.Errorf("expected the cached pool; received %v", err)
+	}
+	m.forgetEnginePools()
+	if _, err := m.schedulingPool("gpu"); err != ErrEnginePoolDoesNotExist {
+		t.Errorf("expected ErrEnginePoolDoesNotExist once reloaded; received %v", err)
+	}
+}
+
+func TestDeleteEnginePoolInUse(t *testing.T) {
+	m := newTestManager(t)
+	if err := m.CreateEnginePool(&shipyard.EnginePool{Name: "gpu", Constraints: []string{"gpu=true"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &shipyard.LaunchTemplate{Name: "trainer", Image: "trainer", Pool: "gpu"}
+	if err := m.CreateTemplate(tmpl); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DeleteEnginePool("gpu"); err != ErrEnginePoolInUse {
+		t.Fatalf("expected the pool of a template to be in use; received %v", err)
+	}
+	if err := m.DeleteTemplate(tmpl.Name); err != nil {
+		t.Fatal(err)
+	}
+
+	image := &citadel.Image{Name: "trainer"}
+	shipyard.SetPool(image, "gpu")
+	job := &shipyard.Job{Schedule: "@hourly", Image: image, Count: 1}
+	if err := m.CreateJob(job); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DeleteEnginePool("gpu"); err != ErrEnginePoolInUse {
+		t.Fatalf("expected the pool of a job to be in use; received %v", err)
+	}
+	if err := m.RemoveJob(job.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.DeleteEnginePool("gpu"); err != nil {
+		t.Errorf("expected an unused pool to be deleted; received %v", err)
+	}
+	if err := m.CreateTemplate(&shipyard.LaunchTemplate{Name: "trainer", Image: "trainer", Pool: "gpu"}); err == nil {
+		t.Error("expected a template in a deleted pool to be rejected")
+	}
+}
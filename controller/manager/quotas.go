@@ -0,0 +1,205 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameQuotas = "quotas"
+)
+
+var (
+	ErrQuotaExists       = errors.New("quota already exists")
+	ErrQuotaDoesNotExist = errors.New("quota does not exist")
+)
+
+// Quotas returns every quota along with the running containers counted
+// against it
+func (m *Manager) Quotas() ([]*shipyard.QuotaUsage, error) {
+	quotas, err := m.quotas()
+	if err != nil {
+		return nil, err
+	}
+	return m.quotaUsage(quotas)
+}
+
+func (m *Manager) quotas() ([]*shipyard.Quota, error) {
+	quotas := []*shipyard.Quota{}
+	if err := m.db.Find(tblNameQuotas, ds.Where().Sort("name", false), &quotas); err != nil {
+		return nil, err
+	}
+	return quotas, nil
+}
+
+func (m *Manager) Quota(name string) (*shipyard.Quota, error) {
+	var quota *shipyard.Quota
+	if err := m.db.FindOne(tblNameQuotas, ds.Where(ds.Eq("name", name)), &quota); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrQuotaDoesNotExist
+		}
+		return nil, err
+	}
+	return quota, nil
+}
+
+func (m *Manager) CreateQuota(quota *shipyard.Quota) error {
+	if err := quota.Validate(); err != nil {
+		return err
+	}
+	if _, err := m.Quota(quota.Name); err == nil {
+		return ErrQuotaExists
+	} else if err != ErrQuotaDoesNotExist {
+		return err
+	}
+	quota.ID = ""
+	quota.Created = time.Now()
+	id, err := m.db.Insert(tblNameQuotas, quota)
+	if err != nil {
+		return err
+	}
+	quota.ID = id
+	evt := &shipyard.Event{
+		Type:    "create-quota",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s account=%s role=%s namespace=%s", quota.Name, quota.Account, quota.Role, quota.Namespace),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) DeleteQuota(name string) error {
+	n, err := m.db.Delete(tblNameQuotas, ds.Where(ds.Eq("name", name)))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrQuotaDoesNotExist
+	}
+	evt := &shipyard.Event{
+		Type:    "delete-quota",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s", name),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// CheckQuota returns an error wrapping shipyard.ErrQuotaExceeded if count
+// more containers of image launched by an account in a namespace would
+// exceed one of the quotas of the account, its role or the namespace.
+// Requests without an account, such as those made with service keys, are
+// only limited by namespace quotas.
+func (m *Manager) CheckQuota(username string, namespace string, image *citadel.Image, count int) error {
+	return m.CheckQuotaLaunches(username, namespace, []*QuotaLaunch{{Image: image, Count: count}})
+}
+
+// QuotaLaunch is a number of containers of an image to be launched
+type QuotaLaunch struct {
+	Image *citadel.Image
+	Count int
+}
+
+// CheckQuotaLaunches is CheckQuota for launching the containers of several
+// images together, such as the applications of a compose project
+func (m *Manager) CheckQuotaLaunches(username string, namespace string, launches []*QuotaLaunch) error {
+	total := 0
+	for _, l := range launches {
+		if l.Count > 0 {
+			total += l.Count
+		}
+	}
+	if (username == "" && namespace == "") || total == 0 {
+		return nil
+	}
+	var acct *shipyard.Account
+	if username != "" {
+		a, err := m.Account(username)
+		if err != nil {
+			return err
+		}
+		acct = a
+	}
+	all, err := m.quotas()
+	if err != nil {
+		return err
+	}
+	quotas := []*shipyard.Quota{}
+	for _, q := range all {
+		switch {
+		case namespace != "" && q.Namespace == namespace:
+		case acct != nil && q.Account == username:
+		case acct != nil && acct.Role != nil && q.Role == acct.Role.Name:
+		default:
+			continue
+		}
+		quotas = append(quotas, q)
+	}
+	if len(quotas) == 0 {
+		return nil
+	}
+	usage, err := m.quotaUsage(quotas)
+	if err != nil {
+		return err
+	}
+	for _, u := range usage {
+		for _, l := range launches {
+			if l.Count > 0 {
+				u.Add(l.Image, l.Count)
+			}
+		}
+		if err := u.Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quotaUsage totals the running containers of the accounts of each quota;
+// role quotas count the containers of every account with the role and
+// namespace quotas every container in the namespace
+func (m *Manager) quotaUsage(quotas []*shipyard.Quota) ([]*shipyard.QuotaUsage, error) {
+	accounts, err := m.Accounts()
+	if err != nil {
+		return nil, err
+	}
+	roles := map[string]string{}
+	for _, a := range accounts {
+		if a.Role != nil {
+			roles[a.Username] = a.Role.Name
+		}
+	}
+	containers := m.Containers(false)
+	usage := []*shipyard.QuotaUsage{}
+	for _, q := range quotas {
+		u := &shipyard.QuotaUsage{Quota: q}
+		for _, c := range containers {
+			if q.Namespace != "" {
+				if shipyard.ContainerNamespace(c) == q.Namespace {
+					u.Add(c.Image, 1)
+				}
+				continue
+			}
+			owner := shipyard.ContainerOwner(c)
+			if owner == "" {
+				continue
+			}
+			if owner == q.Account || (q.Role != "" && roles[owner] == q.Role) {
+				u.Add(c.Image, 1)
+			}
+		}
+		usage = append(usage, u)
+	}
+	return usage, nil
+}
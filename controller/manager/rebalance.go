@@ -0,0 +1,131 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+// Rebalance moves running containers from over utilized engines to under
+// utilized ones.  Moves are planned with the schedulers containers are
+// placed with, so constraints, pools and affinities keep holding.  Each
+// container is only destroyed once its replacement has started.  A dry
+// run returns the planned moves without running them.
+func (m *Manager) Rebalance(opts *shipyard.RebalanceOptions) ([]*shipyard.RebalanceMove, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	m.rebalanceLock.Lock()
+	defer m.rebalanceLock.Unlock()
+
+	loads := []*shipyard.EngineLoad{}
+	engines := map[string]*citadel.Engine{}
+	for _, eng := range m.Engines() {
+		if eng.Cordoned || (eng.Health != nil && eng.Health.Status != EngineHealthUp) {
+			continue
+		}
+		engines[eng.Engine.ID] = eng.Engine
+		loads = append(loads, &shipyard.EngineLoad{
+			ID:     eng.Engine.ID,
+			Cpus:   eng.Engine.Cpus,
+			Memory: eng.Engine.Memory,
+		})
+	}
+	containers := map[string]*citadel.Container{}
+	candidates := []*shipyard.RebalanceCandidate{}
+	for _, c := range m.Containers(false) {
+		if c.Engine == nil || c.Image == nil || engines[c.Engine.ID] == nil {
+			continue
+		}
+		for _, l := range loads {
+			if l.ID == c.Engine.ID {
+				l.ReservedCpus += c.Image.Cpus
+				l.ReservedMemory += c.Image.Memory
+			}
+		}
+		if !movable(c) {
+			continue
+		}
+		containers[c.ID] = c
+		candidates = append(candidates, &shipyard.RebalanceCandidate{
+			ID:     c.ID,
+			Image:  c.Image.Name,
+			Engine: c.Engine.ID,
+			Cpus:   c.Image.Cpus,
+			Memory: c.Image.Memory,
+		})
+	}
+
+	moves := shipyard.PlanRebalance(loads, candidates, opts, func(candidate *shipyard.RebalanceCandidate, engine string) bool {
+		c := containers[candidate.ID]
+		s := m.schedulers[c.Image.Type]
+		if s == nil {
+			return false
+		}
+		ok, err := s.Schedule(drainImage(c.Image, c.Engine.ID), engines[engine])
+		return err == nil && ok
+	})
+	if opts.DryRun {
+		return moves, nil
+	}
+	for _, move := range moves {
+		if err := m.move(containers[move.Container], move); err != nil {
+			return moves, fmt.Errorf("error moving container %s: %s", move.Container, err)
+		}
+	}
+	if len(moves) > 0 {
+		evt := &shipyard.Event{
+			Type:    "rebalance",
+			Time:    time.Now(),
+			Message: fmt.Sprintf("moves=%d", len(moves)),
+			Tags:    []string{"cluster"},
+		}
+		if err := m.SaveEvent(evt); err != nil {
+			return moves, err
+		}
+	}
+	return moves, nil
+}
+
+// move starts a replacement of the container on the engine it is moved to
+// and destroys the container.  The move fails if the engine no longer
+// accepts the container, leaving it in place; moves to the engine the
+// container runs on are skipped.
+func (m *Manager) move(c *citadel.Container, move *shipyard.RebalanceMove) error {
+	if move.To == c.Engine.ID {
+		return nil
+	}
+	img := drainImage(c.Image, c.Engine.ID)
+	launched, err := m.runOn(img, 1, true, move.To)
+	if err != nil {
+		return err
+	}
+	nc := launched[0]
+	move.Replacement = nc.ID
+	if err := m.Destroy(c); err != nil {
+		return err
+	}
+	logger.Infof("moved container %s to engine %s as %s", c.ID[:12], nc.Engine.ID, nc.ID[:12])
+	return nil
+}
+
+// movable reports whether a container may be moved to another engine.
+// Containers pinned to their engine, keeping data there or run as jobs
+// stay in place.
+func movable(c *citadel.Container) bool {
+	if c.Image.Type == "host" || len(c.Image.Volumes) > 0 || len(c.Image.Links) > 0 || c.Image.Environment[shipyard.JobRunEnv] != "" {
+		return false
+	}
+	if mounts, _ := shipyard.VolumeMounts(c.Image); len(mounts) > 0 {
+		return false
+	}
+	for _, l := range c.Image.Labels {
+		if strings.HasPrefix(l, "host:") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,43 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+func TestRebalanceMove(t *testing.T) {
+	from := newTestEngine(t, "5b0c3e9a", "node-1")
+	to := newTestEngine(t, "7d41f2c8", "node-2")
+	m := newTestManager(t, from, to)
+	c := &citadel.Container{
+		ID:     "node-1-000000000001",
+		Engine: from.Engine,
+		Image:  &citadel.Image{Name: "worker", Type: "service", Cpus: 1, Memory: 256},
+		State:  "running",
+	}
+
+	move := &shipyard.RebalanceMove{Container: c.ID, From: "node-1", To: "node-2"}
+	if err := m.move(c, move); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(move.Replacement, "node-2-") {
+		t.Errorf("expected a replacement on node-2; received %q", move.Replacement)
+	}
+
+	noop := &shipyard.RebalanceMove{Container: c.ID, From: "node-1", To: "node-1"}
+	if err := m.move(c, noop); err != nil {
+		t.Fatal(err)
+	}
+	if noop.Replacement != "" {
+		t.Errorf("expected a move to the same engine to be skipped; received replacement %s", noop.Replacement)
+	}
+
+	to.Cordoned = true
+	cordoned := &shipyard.RebalanceMove{Container: c.ID, From: "node-1", To: "node-2"}
+	if err := m.move(c, cordoned); err == nil {
+		t.Error("expected moving to a cordoned engine to fail")
+	}
+}
@@ -0,0 +1,216 @@
+package manager
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameRegistries = "registries"
+)
+
+var (
+	ErrRegistryExists       = errors.New("registry already exists")
+	ErrRegistryDoesNotExist = errors.New("registry does not exist")
+	ErrRegistryNameRequired = errors.New("registry name is required")
+	ErrRegistryAddrRequired = errors.New("registry address is required")
+)
+
+func (m *Manager) Registries() ([]*shipyard.Registry, error) {
+	registries := []*shipyard.Registry{}
+	if err := m.db.Find(tblNameRegistries, ds.Where().Sort("name", false), &registries); err != nil {
+		return nil, err
+	}
+	return registries, nil
+}
+
+func (m *Manager) Registry(name string) (*shipyard.Registry, error) {
+	var registry *shipyard.Registry
+	if err := m.db.FindOne(tblNameRegistries, ds.Where(ds.Eq("name", name)), &registry); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrRegistryDoesNotExist
+		}
+		return nil, err
+	}
+	return registry, nil
+}
+
+func (m *Manager) AddRegistry(registry *shipyard.Registry) error {
+	if registry.Name == "" {
+		return ErrRegistryNameRequired
+	}
+	if registry.Addr == "" {
+		return ErrRegistryAddrRequired
+	}
+	if _, err := m.Registry(registry.Name); err == nil {
+		return ErrRegistryExists
+	} else if err != ErrRegistryDoesNotExist {
+		return err
+	}
+	stored := *registry
+	stored.PasswordData = ""
+	if registry.Password != "" {
+		data, err := m.encryptSecret(registry.Password)
+		if err != nil {
+			return err
+		}
+		stored.PasswordData = data
+	}
+	id, err := m.db.Insert(tblNameRegistries, &stored)
+	if err != nil {
+		return err
+	}
+	registry.ID = id
+	evt := &shipyard.Event{
+		Type:    "add-registry",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s addr=%s", registry.Name, registry.Addr),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) RemoveRegistry(name string) error {
+	registry, err := m.Registry(name)
+	if err != nil {
+		return err
+	}
+	if _, err := m.db.Delete(tblNameRegistries, ds.ByID(registry.ID)); err != nil {
+		return err
+	}
+	evt := &shipyard.Event{
+		Type:    "remove-registry",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s addr=%s", registry.Name, registry.Addr),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// registryCredentials returns a copy of a stored registry with its
+// password decrypted
+func (m *Manager) registryCredentials(registry *shipyard.Registry) (*shipyard.Registry, error) {
+	reg := *registry
+	if reg.PasswordData == "" {
+		return &reg, nil
+	}
+	password, err := m.decryptSecret(reg.PasswordData)
+	if err != nil {
+		return nil, err
+	}
+	reg.Password = password
+	return &reg, nil
+}
+
+// registryAuth returns the stored credentials for the registry hosting
+// image or nil if there are none
+func (m *Manager) registryAuth(image string) (*dockerclient.AuthConfig, error) {
+	registries, err := m.Registries()
+	if err != nil {
+		return nil, err
+	}
+	host := shipyard.RegistryHost(image)
+	for _, stored := range registries {
+		if stored.Host() == host && stored.Username != "" {
+			reg, err := m.registryCredentials(stored)
+			if err != nil {
+				return nil, err
+			}
+			return &dockerclient.AuthConfig{
+				Username: reg.Username,
+				Password: reg.Password,
+				Email:    reg.Email,
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// pullImage pulls image on an engine using any stored registry credentials
+func (m *Manager) pullImage(engine *citadel.Engine, image string) error {
+	client, err := m.DockerClient(engine)
+	if err != nil {
+		return err
+	}
+	auth, err := m.registryAuth(image)
+	if err != nil {
+		return err
+	}
+	return client.PullImage(image, auth)
+}
+
+// pushImage pushes image from an engine to the configured registry hosting
+// it.  Docker reports push failures in the progress stream rather than the
+// response status so the stream is read to the end.
+func (m *Manager) pushImage(engine *citadel.Engine, image string) error {
+	registries, err := m.Registries()
+	if err != nil {
+		return err
+	}
+	var registry *shipyard.Registry
+	host := shipyard.RegistryHost(image)
+	for _, reg := range registries {
+		if reg.Host() == host {
+			registry = reg
+			break
+		}
+	}
+	if registry == nil {
+		return fmt.Errorf("%w: no registry is configured for %s", ErrRegistryDoesNotExist, host)
+	}
+	registry, err = m.registryCredentials(registry)
+	if err != nil {
+		return err
+	}
+	auth, err := json.Marshal(&dockerclient.AuthConfig{
+		Username: registry.Username,
+		Password: registry.Password,
+		Email:    registry.Email,
+	})
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("X-Registry-Auth", base64.URLEncoding.EncodeToString(auth))
+	repo, tag := shipyard.ParseImageName(image)
+	v := url.Values{}
+	if tag != "" {
+		v.Set("tag", tag)
+	}
+	resp, err := m.engineRequestHeader(engine, "POST", fmt.Sprintf("/images/%s/push?%s", repo, v.Encode()), nil, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Error string `json:"error"`
+		}
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if msg.Error != "" {
+			return errors.New(msg.Error)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package manager
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+func TestAddRegistryEncryptsPassword(t *testing.T) {
+	m := newTestManager(t)
+	reg := &shipyard.Registry{Name: "private", Addr: "https://registry.example.com", Username: "deploy", Password: "plaintext-password"}
+	if err := m.AddRegistry(reg); err != ErrNoSecretKey {
+		t.Fatalf("expected registry passwords to require a secret key; received %v", err)
+	}
+	m.SetSecretKey("passphrase")
+	if err := m.AddRegistry(reg); err != nil {
+		t.Fatal(err)
+	}
+	var docs []map[string]interface{}
+	if err := m.db.Find(tblNameRegistries, ds.Where(), &docs); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := json.Marshal(docs); strings.Contains(string(b), "plaintext-password") {
+		t.Errorf("expected the password to be stored encrypted: %s", b)
+	}
+
+	auth, err := m.registryAuth("registry.example.com/app:1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth == nil || auth.Username != "deploy" || auth.Password != "plaintext-password" {
+		t.Errorf("expected the decrypted credentials; received %+v", auth)
+	}
+}
@@ -0,0 +1,161 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameContainerRestarts = "container_restarts"
+	// containerCacheTTL is how long documents about containers applied to
+	// every container listing are cached; in ha mode other controllers
+	// change them
+	containerCacheTTL = 10 * time.Second
+)
+
+// applyContainerRestarts reports the restart counts of containers on their
+// image environment (see shipyard.ContainerRestartCount)
+func (m *Manager) applyContainerRestarts(containers ...*citadel.Container) {
+	m.restartsLock.Lock()
+	defer m.restartsLock.Unlock()
+	if m.restarts == nil || time.Since(m.restartsLoaded) > containerCacheTTL {
+		all := []*shipyard.ContainerRestarts{}
+		if err := m.db.Find(tblNameContainerRestarts, nil, &all); err != nil {
+			logger.Warnf("error loading container restarts: %s", err)
+			return
+		}
+		m.restarts = make(map[string]*shipyard.ContainerRestarts, len(all))
+		for _, r := range all {
+			m.restarts[r.ID] = r
+		}
+		m.restartsLoaded = time.Now()
+	}
+	for _, c := range containers {
+		if r, ok := m.restarts[c.ID]; ok && c.Image != nil {
+			r.SetRestartCount(c.Image)
+		}
+	}
+}
+
+// cacheContainerRestarts updates the cached restarts of a container, or
+// forgets them when r is nil
+func (m *Manager) cacheContainerRestarts(id string, r *shipyard.ContainerRestarts) {
+	m.restartsLock.Lock()
+	defer m.restartsLock.Unlock()
+	if m.restarts == nil {
+		return
+	}
+	if r == nil {
+		delete(m.restarts, id)
+		return
+	}
+	m.restarts[id] = r
+}
+
+func (m *Manager) containerRestarts(id string) (*shipyard.ContainerRestarts, error) {
+	var r *shipyard.ContainerRestarts
+	if err := m.db.Get(tblNameContainerRestarts, id, &r); err != nil {
+		if err == ds.ErrNotFound {
+			return &shipyard.ContainerRestarts{ID: id}, nil
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// restartable reports whether the controller restarts the container in
+// place when it exits.  Application and job containers are left to the
+// application reconciler and the job scheduler, and containers with a
+// docker restart policy to their engine.
+func restartable(c *citadel.Container) (*shipyard.RestartPolicy, bool) {
+	if shipyard.ApplicationName(c) != "" || shipyard.JobRunID(c) != "" {
+		return nil, false
+	}
+	if c.Image != nil && c.Image.RestartPolicy.Name != "" && c.Image.RestartPolicy.Name != shipyard.RestartNo {
+		return nil, false
+	}
+	p, err := shipyard.ContainerRestartPolicy(c.Image)
+	if err != nil || p.Name == shipyard.RestartNo {
+		return nil, false
+	}
+	return p, true
+}
+
+// restartExited restarts a container that exited on its own on its engine
+// after a backoff if its restart policy asks for it.  Containers that were
+// started, stopped through the api or removed meanwhile are left alone.
+func (m *Manager) restartExited(c *citadel.Container, p *shipyard.RestartPolicy) {
+	exitCode, err := m.exitCode(c)
+	if err != nil {
+		logger.Warnf("error inspecting exited container %s: %s", c.ID[:12], err)
+		return
+	}
+	restarts, err := m.containerRestarts(c.ID)
+	if err != nil {
+		logger.Errorf("error loading restarts of container %s: %s", c.ID[:12], err)
+		return
+	}
+	if !p.ShouldRestart(exitCode, restarts.Count) {
+		if exitCode != 0 && p.Name == shipyard.RestartOnFailure {
+			m.saveRestartEvent("restart-limit", c, fmt.Sprintf("container=%s exit=%d restarts=%d policy=%s", c.ID[:12], exitCode, restarts.Count, p))
+		}
+		return
+	}
+	time.Sleep(restarts.Backoff(time.Now()))
+
+	if m.stopExpected(c.ID) {
+		return
+	}
+	client, err := m.DockerClient(c.Engine)
+	if err != nil {
+		logger.Warnf("error inspecting exited container %s: %s", c.ID[:12], err)
+		return
+	}
+	info, err := client.InspectContainer(c.ID)
+	if err != nil || info.State.Running {
+		return
+	}
+	if err := m.Start(c); err != nil {
+		logger.Errorf("error restarting container %s: %s", c.ID[:12], err)
+		return
+	}
+	restarts.Restarted(time.Now())
+	if err := m.db.Put(tblNameContainerRestarts, restarts); err != nil {
+		logger.Errorf("error recording restart of container %s: %s", c.ID[:12], err)
+	} else {
+		m.cacheContainerRestarts(c.ID, restarts)
+	}
+	logger.Infof("restarted container %s (exit=%d restarts=%d)", c.ID[:12], exitCode, restarts.Count)
+	m.saveRestartEvent("auto-restart", c, fmt.Sprintf("container=%s exit=%d restarts=%d policy=%s", c.ID[:12], exitCode, restarts.Count, p))
+}
+
+func (m *Manager) saveRestartEvent(typ string, c *citadel.Container, message string) {
+	evt := &shipyard.Event{
+		Type:      typ,
+		Time:      time.Now(),
+		Container: c,
+		Engine:    c.Engine,
+		Message:   message,
+		Tags:      []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		logger.Errorf("error saving %s event: %s", typ, err)
+	}
+}
+
+// exitCode returns the exit status of an exited container
+func (m *Manager) exitCode(c *citadel.Container) (int, error) {
+	client, err := m.DockerClient(c.Engine)
+	if err != nil {
+		return 0, err
+	}
+	info, err := client.InspectContainer(c.ID)
+	if err != nil {
+		return 0, err
+	}
+	return info.State.ExitCode, nil
+}
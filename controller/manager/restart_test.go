@@ -0,0 +1,108 @@
+package manager
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/citadel/citadel"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+// restartEngine is a docker api with exited containers that counts the
+// containers started
+type restartEngine struct {
+	mu       sync.Mutex
+	exitCode int
+	started  int
+}
+
+func newRestartEngine(t *testing.T, m *Manager, exitCode int) (*restartEngine, *citadel.Engine) {
+	r := &restartEngine{exitCode: exitCode}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case req.Method == "POST" && strings.HasSuffix(req.URL.Path, "/start"):
+			r.started++
+			w.WriteHeader(http.StatusNoContent)
+		case req.Method == "GET" && strings.HasSuffix(req.URL.Path, "/json"):
+			fmt.Fprintf(w, `{"State":{"Running":false,"ExitCode":%d},"Config":{},"HostConfig":{},"NetworkSettings":{"Ports":{}}}`, r.exitCode)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	e := &citadel.Engine{ID: "local", Addr: srv.URL, Cpus: 4, Memory: 4096}
+	client, err := dockerclient.NewDockerClient(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.dockerClients = map[string]*dockerclient.DockerClient{e.ID: client}
+	return r, e
+}
+
+func (r *restartEngine) starts() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.started
+}
+
+func restartContainer(t *testing.T, e *citadel.Engine, policy string) *citadel.Container {
+	image := &citadel.Image{Name: "worker"}
+	p, err := shipyard.ParseRestartPolicy(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shipyard.SetRestartPolicy(image, p); err != nil {
+		t.Fatal(err)
+	}
+	return &citadel.Container{ID: "0123456789abcdef", Engine: e, Image: image, State: "stopped"}
+}
+
+func TestRestartExited(t *testing.T) {
+	m := newTestManager(t)
+	r, e := newRestartEngine(t, m, 1)
+	c := restartContainer(t, e, "on-failure:2")
+
+	m.handleContainerExit(c)
+	if n := r.starts(); n != 1 {
+		t.Fatalf("expected the failed container to be restarted; started %d times", n)
+	}
+	listed := &citadel.Container{ID: c.ID, Image: &citadel.Image{}}
+	m.applyContainerRestarts(listed)
+	if n := shipyard.ContainerRestartCount(listed); n != 1 {
+		t.Errorf("expected the restart to be counted; received %d", n)
+	}
+	events, err := m.Events(&shipyard.EventQuery{EventFilter: shipyard.EventFilter{Types: []string{"auto-restart"}}})
+	if err != nil || len(events) != 1 {
+		t.Errorf("expected an auto-restart event; received %v %v", events, err)
+	}
+}
+
+func TestHandleContainerExitSkips(t *testing.T) {
+	m := newTestManager(t)
+	r, e := newRestartEngine(t, m, 0)
+
+	// exited successfully with an on-failure policy
+	m.handleContainerExit(restartContainer(t, e, "on-failure"))
+
+	// stopped through the api
+	c := restartContainer(t, e, "always")
+	m.expectStop(c)
+	m.handleContainerExit(c)
+
+	// restarted by its engine
+	c = restartContainer(t, e, "always")
+	c.Image.RestartPolicy = citadel.RestartPolicy{Name: "always"}
+	m.handleContainerExit(c)
+
+	if n := r.starts(); n != 0 {
+		t.Errorf("expected no container to be restarted; started %d", n)
+	}
+}
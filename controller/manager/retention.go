@@ -0,0 +1,290 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	settingEventRetention = "event-retention"
+
+	retentionTick = time.Minute
+	// retentionBatch is the number of events archived and removed at once
+	retentionBatch = 1000
+	archiveTimeout = time.Minute
+)
+
+var archiveClient = &http.Client{
+	Timeout: archiveTimeout,
+	Transport: &http.Transport{
+		Proxy:       http.ProxyFromEnvironment,
+		DialContext: dialArchive,
+	},
+	// a redirect could lead the upload and its signature elsewhere
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// dialArchive connects to archive endpoints, refusing the addresses of
+// the controller host a hostname may resolve to
+func dialArchive(ctx context.Context, network string, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		if !shipyard.PublicArchiveAddress(ip.IP) {
+			return nil, fmt.Errorf("archive endpoint %s resolves to the refused address %s", host, ip.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("archive endpoint %s has no address", host)
+	}
+	d := &net.Dialer{Timeout: archiveTimeout}
+	return d.DialContext(ctx, network, net.JoinHostPort(ips[0].IP.String(), port))
+}
+
+// storedEvent is an event with the id of its document
+type storedEvent struct {
+	ID string `gorethink:"id"`
+	shipyard.Event
+}
+
+// EventRetention returns the stored retention policy or one keeping events
+// forever.  The archive secret key is only kept encrypted; see
+// archiveCredentials.
+func (m *Manager) EventRetention() (*shipyard.EventRetention, error) {
+	var setting struct {
+		Policy *shipyard.EventRetention `gorethink:"policy"`
+	}
+	if err := m.db.Get(tblNameSettings, settingEventRetention, &setting); err != nil {
+		if err == ds.ErrNotFound {
+			return &shipyard.EventRetention{Interval: shipyard.DefaultRetentionInterval}, nil
+		}
+		return nil, err
+	}
+	return setting.Policy, nil
+}
+
+// SetEventRetention stores the retention policy with its archive secret
+// key encrypted, which requires a secret key; see SetSecretKey.  An archive
+// without a secret key keeps the current one so redacted policies can be
+// updated.
+func (m *Manager) SetEventRetention(policy *shipyard.EventRetention) error {
+	stored := *policy
+	if policy.Archive != nil {
+		archive := *policy.Archive
+		if archive.SecretKey == "" {
+			current, err := m.EventRetention()
+			if err != nil {
+				return err
+			}
+			if current.Archive != nil {
+				credentials, err := m.archiveCredentials(current.Archive)
+				if err != nil {
+					return err
+				}
+				archive.SecretKey = credentials.SecretKey
+			}
+		}
+		stored.Archive = &archive
+	}
+	if err := stored.Validate(); err != nil {
+		return err
+	}
+	if stored.Archive != nil {
+		data, err := m.encryptSecret(stored.Archive.SecretKey)
+		if err != nil {
+			return err
+		}
+		stored.Archive.SecretKeyData = data
+	}
+	setting := map[string]interface{}{
+		"id":     settingEventRetention,
+		"policy": &stored,
+	}
+	if err := m.db.Put(tblNameSettings, setting); err != nil {
+		return err
+	}
+	archive := ""
+	if policy.Archive != nil {
+		archive = policy.Archive.Type + "://" + policy.Archive.Bucket + "/" + policy.Archive.Prefix
+	}
+	evt := &shipyard.Event{
+		Type:    "update-event-retention",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("enabled=%v max_age=%dh max_count=%d archive=%s", policy.Enabled, policy.MaxAge, policy.MaxCount, archive),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// archiveCredentials returns a copy of a stored archive with its secret
+// key decrypted
+func (m *Manager) archiveCredentials(archive *shipyard.EventArchive) (*shipyard.EventArchive, error) {
+	if archive.SecretKeyData == "" {
+		return nil, errors.New("the archive secret key is not stored; set the event retention again")
+	}
+	secret, err := m.decryptSecret(archive.SecretKeyData)
+	if err != nil {
+		return nil, err
+	}
+	a := *archive
+	a.SecretKey = secret
+	return &a, nil
+}
+
+// expireEvents applies the retention policy while it is enabled
+func (m *Manager) expireEvents() {
+	var last time.Time
+	t := time.NewTicker(retentionTick).C
+	for range t {
+		if !m.IsLeader() {
+			continue
+		}
+		policy, err := m.EventRetention()
+		if err != nil {
+			logger.Errorf("error getting event retention: %s", err)
+			continue
+		}
+		if !policy.Enabled || time.Since(last) < policy.Period() {
+			continue
+		}
+		last = time.Now()
+		if _, err := m.ApplyEventRetention(); err != nil {
+			logger.Errorf("error applying event retention: %s", err)
+		}
+	}
+}
+
+// ApplyEventRetention archives and removes the events expired by the
+// policy, oldest first.  Events are only removed once their archive is
+// uploaded; a failed upload stops the run and is retried by the next.
+// Without an archive the expired events are removed at once.
+func (m *Manager) ApplyEventRetention() (*shipyard.EventRetentionResult, error) {
+	policy, err := m.EventRetention()
+	if err != nil {
+		return nil, err
+	}
+	m.retentionLock.Lock()
+	defer m.retentionLock.Unlock()
+	result := &shipyard.EventRetentionResult{Started: time.Now()}
+	cutoff, err := m.eventCutoff(policy, result.Started)
+	if err != nil || cutoff.IsZero() {
+		return result, err
+	}
+	var archive *shipyard.EventArchive
+	if policy.Archive != nil {
+		if archive, err = m.archiveCredentials(policy.Archive); err != nil {
+			return result, err
+		}
+	}
+	if archive == nil {
+		n, err := m.db.Delete(tblNameEvents, ds.Where(ds.Le("Time", cutoff)))
+		if err != nil {
+			return result, err
+		}
+		result.Removed = n
+	}
+	for archive != nil {
+		batch := []*storedEvent{}
+		// the events are sorted by their indexed time
+		q := ds.Where(ds.Le("Time", cutoff)).Sort("Time", false).Page(0, retentionBatch)
+		if err := m.db.Find(tblNameEvents, q, &batch); err != nil {
+			return result, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		name, err := archiveEvents(archive, batch)
+		if err != nil {
+			return result, err
+		}
+		result.Objects = append(result.Objects, name)
+		ids := make([]string, len(batch))
+		for i, e := range batch {
+			ids[i] = e.ID
+		}
+		n, err := m.db.Delete(tblNameEvents, ds.Where(ds.In("id", ids)))
+		if err != nil {
+			return result, err
+		}
+		result.Removed += n
+		if len(batch) < retentionBatch {
+			break
+		}
+	}
+	if result.Removed == 0 {
+		return result, nil
+	}
+	evt := &shipyard.Event{
+		Type:    "expire-events",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("removed=%d archives=%d", result.Removed, len(result.Objects)),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// eventCutoff returns the time events at or before expire: the later of
+// the age limit and the time of the newest event beyond the count limit
+func (m *Manager) eventCutoff(policy *shipyard.EventRetention, now time.Time) (time.Time, error) {
+	cutoff := policy.Cutoff(now)
+	if policy.MaxCount == 0 {
+		return cutoff, nil
+	}
+	beyond := []*storedEvent{}
+	q := ds.Where().Sort("Time", true).Page(policy.MaxCount, 1)
+	if err := m.db.Find(tblNameEvents, q, &beyond); err != nil {
+		return time.Time{}, err
+	}
+	if len(beyond) > 0 && beyond[0].Time.After(cutoff) {
+		cutoff = beyond[0].Time
+	}
+	return cutoff, nil
+}
+
+// archiveEvents uploads events as json lines and returns the object name
+func archiveEvents(archive *shipyard.EventArchive, events []*storedEvent) (string, error) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	for _, e := range events {
+		if err := enc.Encode(&e.Event); err != nil {
+			return "", err
+		}
+	}
+	name := archive.ObjectName(events[0].Time, len(events))
+	req, err := archive.NewUploadRequest(name, buf.Bytes(), time.Now())
+	if err != nil {
+		return "", err
+	}
+	resp, err := archiveClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("error uploading %s: %s", name, resp.Status)
+	}
+	return name, nil
+}
@@ -0,0 +1,65 @@
+package manager
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shipyard/shipyard"
+)
+
+func TestSetEventRetentionEncryptsSecretKey(t *testing.T) {
+	m := newTestManager(t)
+	policy := &shipyard.EventRetention{
+		Archive: &shipyard.EventArchive{Type: shipyard.ArchiveS3, Bucket: "logs", AccessKey: "AK", SecretKey: "plaintext-secret"},
+	}
+	if err := m.SetEventRetention(policy); err != ErrNoSecretKey {
+		t.Fatalf("expected archives to require a secret key; received %v", err)
+	}
+	m.SetSecretKey("passphrase")
+	if err := m.SetEventRetention(policy); err != nil {
+		t.Fatal(err)
+	}
+	var setting map[string]interface{}
+	if err := m.db.Get(tblNameSettings, settingEventRetention, &setting); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := json.Marshal(setting); strings.Contains(string(b), "plaintext-secret") {
+		t.Errorf("expected the secret key to be stored encrypted: %s", b)
+	}
+
+	// a redacted policy keeps the stored secret key
+	stored, err := m.EventRetention()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetEventRetention(stored.Redacted()); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ = m.EventRetention()
+	archive, err := m.archiveCredentials(stored.Archive)
+	if err != nil || archive.SecretKey != "plaintext-secret" {
+		t.Errorf("expected the secret key to be kept; received %v %v", archive, err)
+	}
+}
+
+func TestApplyEventRetention(t *testing.T) {
+	m := newTestManager(t)
+	now := time.Now()
+	for _, age := range []time.Duration{72 * time.Hour, 48 * time.Hour, time.Hour} {
+		if _, err := m.db.Insert(tblNameEvents, &shipyard.Event{Type: "test", Time: now.Add(-age)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.SetEventRetention(&shipyard.EventRetention{MaxAge: 24}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := m.ApplyEventRetention()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Removed != 2 {
+		t.Errorf("expected the 2 events older than a day to be removed; received %d", result.Removed)
+	}
+}
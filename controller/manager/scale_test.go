@@ -0,0 +1,76 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+// newScaleEngine is a docker api listing containers of the images given by
+// container id, launched with the environment given by container id
+func newScaleEngine(t *testing.T, images map[string]string, env map[string][]string) *shipyard.Engine {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			listed := []string{}
+			for id, image := range images {
+				listed = append(listed, fmt.Sprintf(`{"Id":%q,"Image":%q}`, id, image))
+			}
+			fmt.Fprintf(w, "[%s]", strings.Join(listed, ","))
+		case strings.HasSuffix(r.URL.Path, "/json"):
+			id := strings.TrimSuffix(r.URL.Path[strings.LastIndex(r.URL.Path, "/containers/")+len("/containers/"):], "/json")
+			b, _ := json.Marshal(append([]string{"_citadel_type=service"}, env[id]...))
+			fmt.Fprintf(w, `{"State":{"Running":true},"Config":{"Env":%s},"HostConfig":{},"NetworkSettings":{"Ports":{}}}`, b)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	e := &citadel.Engine{ID: "node-1", Addr: srv.URL, Cpus: 4, Memory: 4096}
+	if err := e.Connect(nil); err != nil {
+		t.Fatal(err)
+	}
+	return &shipyard.Engine{ID: "5b0c3e9a", Engine: e, Health: &shipyard.Health{Status: EngineHealthUp}}
+}
+
+func TestScaleTargets(t *testing.T) {
+	eng := newScaleEngine(t, map[string]string{
+		"000000000001": "web",
+		"000000000002": "web",
+		"000000000003": "web",
+		"000000000004": "webapp",
+		"000000000005": "web",
+	}, map[string][]string{
+		"000000000001": {shipyard.OwnerEnv + "=alice", shipyard.NamespaceEnv + "=team-a"},
+		"000000000002": {shipyard.OwnerEnv + "=bob", shipyard.NamespaceEnv + "=team-a"},
+		"000000000003": {shipyard.OwnerEnv + "=alice", shipyard.NamespaceEnv + "=team-b"},
+		"000000000004": {shipyard.OwnerEnv + "=alice", shipyard.NamespaceEnv + "=team-a"},
+		"000000000005": {shipyard.OwnerEnv + "=alice"},
+	})
+	m := newTestManager(t, eng)
+
+	image := &citadel.Image{Name: "web", Type: "service"}
+	shipyard.SetOwner(image, "alice")
+	shipyard.SetNamespace(image, "team-a")
+	targets, err := m.ScaleTargets(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 || targets[0].ID != "000000000001" {
+		ids := []string{}
+		for _, c := range targets {
+			ids = append(ids, c.ID)
+		}
+		t.Errorf("expected only the container of the owner in the namespace with the exact image; received %v", ids)
+	}
+	if err := m.ScaleImage(image, -1); err != ErrInvalidScaleCount {
+		t.Errorf("expected ErrInvalidScaleCount; received %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package manager
+
+import (
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+// cordonScheduler wraps a scheduler and rejects engines that are cordoned
+// or failed their last health check
+type cordonScheduler struct {
+	manager   *Manager
+	scheduler citadel.Scheduler
+}
+
+func (m *Manager) newCordonScheduler(s citadel.Scheduler) *cordonScheduler {
+	return &cordonScheduler{
+		manager:   m,
+		scheduler: s,
+	}
+}
+
+func (s *cordonScheduler) Schedule(i *citadel.Image, e *citadel.Engine) (bool, error) {
+	if eng := s.manager.clusterEngine(e.ID); eng != nil {
+		if eng.Cordoned || (eng.Health != nil && eng.Health.Status != EngineHealthUp) {
+			return false, nil
+		}
+	}
+	return s.scheduler.Schedule(i, e)
+}
+
+// constraintScheduler places images on engines whose labels satisfy the
+// image labels.  Each image label is a constraint expression (see
+// shipyard.ParseConstraints); plain labels keep the exact match behavior of
+// the citadel label scheduler.
+type constraintScheduler struct{}
+
+func (s *constraintScheduler) Schedule(i *citadel.Image, e *citadel.Engine) (bool, error) {
+	if len(i.Labels) == 0 {
+		return true, nil
+	}
+	labels := shipyard.ParseLabels(e.Labels)
+	for _, l := range i.Labels {
+		constraints, err := shipyard.ParseConstraints(l)
+		if err != nil {
+			return false, err
+		}
+		for _, c := range constraints {
+			if !c.Match(labels) {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// poolScheduler restricts images placed in an engine pool to the engines
+// of the pool
+type poolScheduler struct {
+	manager   *Manager
+	scheduler citadel.Scheduler
+}
+
+func (s *poolScheduler) Schedule(i *citadel.Image, e *citadel.Engine) (bool, error) {
+	if name := shipyard.ContainerPool(i); name != "" {
+		pool, err := s.manager.schedulingPool(name)
+		if err != nil {
+			return false, err
+		}
+		if !pool.Match(e.Labels) {
+			return false, nil
+		}
+	}
+	return s.scheduler.Schedule(i, e)
+}
+
+// affinityScheduler applies the placement affinity of an image (see
+// shipyard.Affinity) to the engines accepted by another scheduler
+type affinityScheduler struct {
+	manager   *Manager
+	scheduler citadel.Scheduler
+}
+
+func (m *Manager) newAffinityScheduler(s citadel.Scheduler) *affinityScheduler {
+	return &affinityScheduler{
+		manager:   m,
+		scheduler: &poolScheduler{manager: m, scheduler: s},
+	}
+}
+
+func (s *affinityScheduler) Schedule(i *citadel.Image, e *citadel.Engine) (bool, error) {
+	ok, err := s.scheduler.Schedule(i, e)
+	if err != nil || !ok {
+		return ok, err
+	}
+	affinity, err := shipyard.ContainerAffinity(i)
+	if err != nil || affinity == nil {
+		return err == nil, err
+	}
+	containers, err := e.ListContainers(false, false, "")
+	if err != nil {
+		return false, err
+	}
+	// spreading replicas depends on every accepted engine, so it is done
+	// when placing (see placementManager.spread)
+	return affinity.Allows(i, containers), nil
+}
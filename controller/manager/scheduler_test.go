@@ -0,0 +1,44 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+type acceptScheduler struct{}
+
+func (s *acceptScheduler) Schedule(i *citadel.Image, e *citadel.Engine) (bool, error) {
+	return true, nil
+}
+
+func TestCordonScheduler(t *testing.T) {
+	up := &citadel.Engine{ID: "node-1"}
+	cordoned := &citadel.Engine{ID: "node-2"}
+	down := &citadel.Engine{ID: "node-3"}
+	m := &Manager{
+		engines: []*shipyard.Engine{
+			{ID: "5b0c3e9a", Engine: up, Health: &shipyard.Health{Status: EngineHealthUp}},
+			{ID: "7d41f2c8", Engine: cordoned, Cordoned: true},
+			{ID: "9e6a1b47", Engine: down, Health: &shipyard.Health{Status: "down"}},
+		},
+	}
+	s := m.newCordonScheduler(&acceptScheduler{})
+	for _, test := range []struct {
+		engine   *citadel.Engine
+		expected bool
+	}{
+		{up, true},
+		{cordoned, false},
+		{down, false},
+	} {
+		ok, err := s.Schedule(&citadel.Image{Name: "nginx"}, test.engine)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != test.expected {
+			t.Errorf("%s: expected %t; received %t", test.engine.ID, test.expected, ok)
+		}
+	}
+}
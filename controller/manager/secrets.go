@@ -0,0 +1,205 @@
+package manager
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameSecrets = "secrets"
+)
+
+var (
+	ErrSecretExists       = errors.New("secret already exists")
+	ErrSecretDoesNotExist = errors.New("secret does not exist")
+	ErrNoSecretKey        = errors.New("secrets require the controller to be started with a secret key")
+)
+
+// SetSecretKey sets the passphrase secrets are encrypted with.  Secrets can
+// not be stored or injected without one.
+func (m *Manager) SetSecretKey(passphrase string) {
+	key := sha256.Sum256([]byte(passphrase))
+	m.secretKey = key[:]
+}
+
+func (m *Manager) secretCipher() (cipher.AEAD, error) {
+	if m.secretKey == nil {
+		return nil, ErrNoSecretKey
+	}
+	block, err := aes.NewCipher(m.secretKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (m *Manager) encryptSecret(value string) (string, error) {
+	gcm, err := m.secretCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (m *Manager) decryptSecret(data string) (string, error) {
+	gcm, err := m.secretCipher()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("invalid secret data")
+	}
+	value, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to decrypt secret; was the secret key changed? %s", err)
+	}
+	return string(value), nil
+}
+
+// Secrets returns the stored secrets without their values
+func (m *Manager) Secrets() ([]*shipyard.Secret, error) {
+	secrets := []*shipyard.Secret{}
+	if err := m.db.Find(tblNameSecrets, ds.Where().Sort("name", false), &secrets); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// Secret returns a secret with its decrypted value
+func (m *Manager) Secret(name string) (*shipyard.Secret, error) {
+	var secret *shipyard.Secret
+	if err := m.db.FindOne(tblNameSecrets, ds.Where(ds.Eq("name", name)), &secret); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrSecretDoesNotExist
+		}
+		return nil, err
+	}
+	value, err := m.decryptSecret(secret.Data)
+	if err != nil {
+		return nil, err
+	}
+	secret.Value = value
+	return secret, nil
+}
+
+// CreateSecret encrypts and stores a secret
+func (m *Manager) CreateSecret(secret *shipyard.Secret) error {
+	if err := secret.Validate(); err != nil {
+		return err
+	}
+	n, err := m.db.Count(tblNameSecrets, ds.Where(ds.Eq("name", secret.Name)))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrSecretExists
+	}
+	data, err := m.encryptSecret(secret.Value)
+	if err != nil {
+		return err
+	}
+	secret.ID = ""
+	secret.Data = data
+	secret.Created = time.Now()
+	id, err := m.db.Insert(tblNameSecrets, secret)
+	if err != nil {
+		return err
+	}
+	secret.ID = id
+	evt := &shipyard.Event{
+		Type:    "create-secret",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s", secret.Name),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteSecret removes a secret.  Running containers keep the injected
+// value; new containers referencing it fail to launch.
+func (m *Manager) DeleteSecret(name string) error {
+	n, err := m.db.Delete(tblNameSecrets, ds.Where(ds.Eq("name", name)))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrSecretDoesNotExist
+	}
+	evt := &shipyard.Event{
+		Type:    "delete-secret",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s", name),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// injectSecrets returns a copy of the image with the referenced env secrets
+// set and the secret files to write into its containers
+func (m *Manager) injectSecrets(image *citadel.Image) (*citadel.Image, []*containerFile, error) {
+	refs, err := shipyard.SecretRefs(image)
+	if err != nil || len(refs) == 0 {
+		return image, nil, err
+	}
+	img := *image
+	img.Environment = map[string]string{}
+	for k, v := range image.Environment {
+		img.Environment[k] = v
+	}
+	files := []*containerFile{}
+	for _, ref := range refs {
+		secret, err := m.Secret(ref.Name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("secret %s: %s", ref.Name, err)
+		}
+		if ref.Env != "" {
+			img.Environment[ref.Env] = secret.Value
+			continue
+		}
+		files = append(files, &containerFile{path: ref.Path, value: secret.Value})
+	}
+	return &img, files, nil
+}
+
+// redactedContainer returns a copy of the container with the values of
+// injected env secrets replaced, or the container itself if it has none
+func redactedContainer(c *citadel.Container) *citadel.Container {
+	if c == nil || c.Image == nil || c.Image.Environment[shipyard.SecretsEnv] == "" {
+		return c
+	}
+	img := *c.Image
+	img.Environment = map[string]string{}
+	for k, v := range c.Image.Environment {
+		img.Environment[k] = v
+	}
+	shipyard.RedactSecrets(&img)
+	redacted := *c
+	redacted.Image = &img
+	return &redacted
+}
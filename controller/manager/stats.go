@@ -0,0 +1,145 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+type (
+	// dockerStats is the subset of the docker stats api response used to
+	// build shipyard stats
+	dockerStats struct {
+		Read    string `json:"read"`
+		Network struct {
+			RxBytes uint64 `json:"rx_bytes"`
+			TxBytes uint64 `json:"tx_bytes"`
+		} `json:"network"`
+		Networks map[string]struct {
+			RxBytes uint64 `json:"rx_bytes"`
+			TxBytes uint64 `json:"tx_bytes"`
+		} `json:"networks"`
+		CpuStats    dockerCpuStats `json:"cpu_stats"`
+		PreCpuStats dockerCpuStats `json:"precpu_stats"`
+		MemoryStats struct {
+			Usage uint64 `json:"usage"`
+			Limit uint64 `json:"limit"`
+		} `json:"memory_stats"`
+		BlkioStats struct {
+			IoServiceBytesRecursive []struct {
+				Op    string `json:"op"`
+				Value uint64 `json:"value"`
+			} `json:"io_service_bytes_recursive"`
+		} `json:"blkio_stats"`
+	}
+
+	dockerCpuStats struct {
+		CpuUsage struct {
+			TotalUsage  uint64   `json:"total_usage"`
+			PercpuUsage []uint64 `json:"percpu_usage"`
+		} `json:"cpu_usage"`
+		SystemUsage uint64 `json:"system_cpu_usage"`
+		OnlineCpus  int    `json:"online_cpus"`
+	}
+)
+
+// network returns the bytes received and sent on all container networks
+func (s *dockerStats) network() (uint64, uint64) {
+	rx, tx := s.Network.RxBytes, s.Network.TxBytes
+	for _, n := range s.Networks {
+		rx += n.RxBytes
+		tx += n.TxBytes
+	}
+	return rx, tx
+}
+
+// disk returns the bytes read and written by the container
+func (s *dockerStats) disk() (uint64, uint64) {
+	var read, write uint64
+	for _, e := range s.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			read += e.Value
+		case "write":
+			write += e.Value
+		}
+	}
+	return read, write
+}
+
+// cpuPercent is the cpu usage between prev and s.  Usage is reported as
+// counters; 100 percent is one fully used cpu.
+func (s *dockerCpuStats) cpuPercent(prev *dockerCpuStats) float64 {
+	cpu, system := s.CpuUsage.TotalUsage, s.SystemUsage
+	if prev.SystemUsage == 0 || system <= prev.SystemUsage || cpu < prev.CpuUsage.TotalUsage {
+		return 0
+	}
+	cpus := s.OnlineCpus
+	if cpus == 0 {
+		cpus = len(s.CpuUsage.PercpuUsage)
+	}
+	cpuDelta := float64(cpu - prev.CpuUsage.TotalUsage)
+	systemDelta := float64(system - prev.SystemUsage)
+	return cpuDelta / systemDelta * float64(cpus) * 100.0
+}
+
+// Stats streams resource usage samples of a container to fn until the engine
+// closes the stream or fn returns an error
+func (m *Manager) Stats(container *citadel.Container, fn func(*shipyard.ContainerStats) error) error {
+	resp, err := m.engineRequest(container.Engine, "GET", fmt.Sprintf("/containers/%s/stats", container.ID), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var prev dockerCpuStats
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var s *dockerStats
+		if err := dec.Decode(&s); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		stats := &shipyard.ContainerStats{
+			ContainerID: container.ID,
+			MemoryUsage: s.MemoryStats.Usage,
+			MemoryLimit: s.MemoryStats.Limit,
+		}
+		stats.Time, _ = parseDockerTime(s.Read)
+		stats.NetworkRxBytes, stats.NetworkTxBytes = s.network()
+		// the percentage is the delta against the previous sample
+		stats.CpuPercent = s.CpuStats.cpuPercent(&prev)
+		prev = s.CpuStats
+
+		if err := fn(stats); err != nil {
+			return err
+		}
+	}
+}
+
+// Top returns the processes running in a container.  psArgs are passed to
+// ps on the engine; empty uses the docker default.
+func (m *Manager) Top(container *citadel.Container, psArgs string) (*shipyard.ProcessList, error) {
+	path := fmt.Sprintf("/containers/%s/top", container.ID)
+	if psArgs != "" {
+		path += "?ps_args=" + url.QueryEscape(psArgs)
+	}
+	var resp struct {
+		Titles    []string
+		Processes [][]string
+	}
+	if err := m.engineJSON(container.Engine, "GET", path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &shipyard.ProcessList{
+		Titles:    resp.Titles,
+		Processes: resp.Processes,
+	}, nil
+}
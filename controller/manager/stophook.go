@@ -0,0 +1,106 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
+)
+
+const (
+	// preStopPoll is how often a running pre-stop command is inspected
+	preStopPoll = 500 * time.Millisecond
+)
+
+// stopHook returns the stop hook of a running container or nil
+func stopHook(c *citadel.Container) *shipyard.StopHook {
+	if c.State != "running" {
+		return nil
+	}
+	h, err := shipyard.ContainerStopHook(c.Image)
+	if err != nil {
+		logger.Warnf("ignoring stop hook of container %s: %s", c.ID[:12], err)
+		return nil
+	}
+	return h
+}
+
+// prepareStop runs the pre-stop command of a container with a stop hook and
+// returns the timeout to stop it with: the larger of timeout and the grace
+// period of the hook less the time the pre-stop command took, so the
+// command and the stop share the grace period
+func (m *Manager) prepareStop(c *citadel.Container, timeout int) int {
+	h := stopHook(c)
+	if h == nil {
+		return timeout
+	}
+	if t := h.Timeout(); t > timeout {
+		timeout = t
+	}
+	if len(h.PreStop) == 0 {
+		return timeout
+	}
+	started := time.Now()
+	if err := m.runPreStop(c, h); err != nil {
+		logger.Warnf("error running pre-stop command of container %s: %s", c.ID[:12], err)
+	}
+	return remainingTimeout(timeout, time.Since(started))
+}
+
+// remainingTimeout returns the whole seconds left of a timeout after
+// elapsed, at least none
+func remainingTimeout(timeout int, elapsed time.Duration) int {
+	left := timeout - int(elapsed/time.Second)
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// runPreStop runs the pre-stop command in the container and waits up to the
+// grace period for it to exit
+func (m *Manager) runPreStop(c *citadel.Container, h *shipyard.StopHook) error {
+	cfg := &dockerclient.ExecConfig{
+		AttachStdout: false,
+		AttachStderr: false,
+		Cmd:          h.PreStop,
+		Container:    c.ID,
+	}
+	var resp struct {
+		Id string
+	}
+	if err := m.engineJSON(c.Engine, "POST", fmt.Sprintf("/containers/%s/exec", c.ID), cfg, &resp); err != nil {
+		return err
+	}
+	start := map[string]bool{
+		"Detach": true,
+		"Tty":    false,
+	}
+	if err := m.engineJSON(c.Engine, "POST", fmt.Sprintf("/exec/%s/start", resp.Id), start, nil); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(time.Duration(h.Timeout()) * time.Second)
+	result := "timeout"
+	for time.Now().Before(deadline) {
+		info, err := m.InspectExec(c, resp.Id)
+		if err != nil {
+			return err
+		}
+		if !info.Running {
+			result = fmt.Sprintf("exit=%d", info.ExitCode)
+			break
+		}
+		time.Sleep(preStopPoll)
+	}
+	evt := &shipyard.Event{
+		Type:      "pre-stop",
+		Time:      time.Now(),
+		Container: c,
+		Engine:    c.Engine,
+		Message:   fmt.Sprintf("cmd=%v %s", h.PreStop, result),
+		Tags:      []string{"docker"},
+	}
+	return m.SaveEvent(evt)
+}
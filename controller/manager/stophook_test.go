@@ -0,0 +1,41 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+func TestRemainingTimeout(t *testing.T) {
+	cases := []struct {
+		timeout  int
+		elapsed  time.Duration
+		expected int
+	}{
+		{30, 0, 30},
+		{30, 12500 * time.Millisecond, 18},
+		{30, time.Minute, 0},
+	}
+	for _, c := range cases {
+		if left := remainingTimeout(c.timeout, c.elapsed); left != c.expected {
+			t.Errorf("%d after %s: expected %d; received %d", c.timeout, c.elapsed, c.expected, left)
+		}
+	}
+}
+
+func TestPrepareStopGracePeriod(t *testing.T) {
+	m := newTestManager(t)
+	image := &citadel.Image{Name: "nginx"}
+	if err := shipyard.SetStopHook(image, &shipyard.StopHook{GracePeriod: 30}); err != nil {
+		t.Fatal(err)
+	}
+	c := &citadel.Container{ID: "0123456789abcdef", State: "running", Image: image}
+	if timeout := m.prepareStop(c, 10); timeout != 30 {
+		t.Errorf("expected the grace period; received %d", timeout)
+	}
+	if timeout := m.prepareStop(c, 60); timeout != 60 {
+		t.Errorf("expected the larger timeout; received %d", timeout)
+	}
+}
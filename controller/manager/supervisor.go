@@ -0,0 +1,206 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+const (
+	superviseInterval = 10 * time.Second
+)
+
+// supervise keeps track of the containers with a reschedule policy on each
+// healthy engine so they can be replaced once their engine fails and can no
+// longer be listed
+func (m *Manager) supervise() {
+	t := time.NewTicker(superviseInterval).C
+	for range t {
+		if !m.IsLeader() {
+			continue
+		}
+		m.refreshSupervised()
+	}
+}
+
+func (m *Manager) refreshSupervised() {
+	m.superviseContainers(m.Containers(false))
+}
+
+// superviseContainers records the reschedulable running containers of each
+// healthy engine by engine id
+func (m *Manager) superviseContainers(containers []*citadel.Container) {
+	byEngine := map[string][]*citadel.Container{}
+	for _, c := range containers {
+		if c.Engine == nil || !reschedulable(c) {
+			continue
+		}
+		byEngine[c.Engine.ID] = append(byEngine[c.Engine.ID], c)
+	}
+	m.supervisorLock.Lock()
+	defer m.supervisorLock.Unlock()
+	for _, eng := range m.Engines() {
+		if eng.Health == nil || eng.Health.Status != EngineHealthUp {
+			// keep the last known containers of failed engines
+			continue
+		}
+		m.supervised[eng.ID] = byEngine[eng.Engine.ID]
+	}
+}
+
+// reschedulable reports whether the supervisor replaces the container.
+// Application containers are left to the application reconciler and job
+// containers are expected to exit.
+func reschedulable(c *citadel.Container) bool {
+	return shipyard.ReschedulePolicy(c.Image) != shipyard.RescheduleNo && shipyard.ApplicationName(c) == "" && shipyard.JobRunID(c) == ""
+}
+
+// expectStop marks a container as stopped through the api so its exit is
+// not treated as a failure
+func (m *Manager) expectStop(c *citadel.Container) {
+	if m.haEnabled() {
+		// the exit is handled by the leader
+		m.markStopping(c.ID)
+		return
+	}
+	m.supervisorLock.Lock()
+	defer m.supervisorLock.Unlock()
+	m.stopping[c.ID] = true
+}
+
+// stopExpected reports whether the container was stopped through the api
+// and forgets it
+func (m *Manager) stopExpected(id string) bool {
+	m.supervisorLock.Lock()
+	expected := m.stopping[id]
+	delete(m.stopping, id)
+	m.supervisorLock.Unlock()
+	if !expected && m.haEnabled() {
+		expected = m.takeStopMarker(id)
+	}
+	return expected
+}
+
+// handleContainerExit restarts a container that exited on its own if its
+// restart policy asks for it, or else replaces it if its reschedule policy
+// does
+func (m *Manager) handleContainerExit(c *citadel.Container) {
+	if m.stopExpected(c.ID) {
+		return
+	}
+	if p, ok := restartable(c); ok {
+		m.restartExited(c, p)
+		return
+	}
+	if !reschedulable(c) {
+		return
+	}
+	// the engine restarts containers with a docker restart policy itself
+	if rp := c.Image.RestartPolicy.Name; rp != "" && rp != "no" {
+		return
+	}
+	switch shipyard.ReschedulePolicy(c.Image) {
+	case shipyard.RescheduleAlways:
+	case shipyard.RescheduleOnFailure:
+		exitCode, err := m.exitCode(c)
+		if err != nil {
+			logger.Warnf("error inspecting exited container %s: %s", c.ID[:12], err)
+			return
+		}
+		if exitCode == 0 {
+			return
+		}
+	default:
+		return
+	}
+	if err := m.reschedule(c, "exited", ""); err != nil {
+		logger.Errorf("error rescheduling container %s: %s", c.ID[:12], err)
+		return
+	}
+	if err := m.ClusterManager().Remove(c); err != nil {
+		logger.Warnf("error removing exited container %s: %s", c.ID[:12], err)
+	}
+}
+
+// rescheduleEngine starts replacements for the containers of a failed
+// engine.  The replaced containers are removed if the engine comes back;
+// containers with volumes are restarted there instead.
+func (m *Manager) rescheduleEngine(eng *shipyard.Engine) {
+	m.supervisorLock.Lock()
+	containers := m.supervised[eng.ID]
+	delete(m.supervised, eng.ID)
+	m.supervisorLock.Unlock()
+
+	for _, c := range containers {
+		if mounts, _ := shipyard.VolumeMounts(c.Image); len(mounts) > 0 {
+			// the data is on the failed engine
+			logger.Warnf("container %s waits for engine %s holding its volumes", c.ID[:12], eng.ID)
+			m.supervisorLock.Lock()
+			m.stranded[eng.ID] = append(m.stranded[eng.ID], c.ID)
+			m.supervisorLock.Unlock()
+			continue
+		}
+		if err := m.reschedule(c, "engine-failure", eng.Engine.ID); err != nil {
+			logger.Errorf("error rescheduling container %s off engine %s: %s", c.ID[:12], eng.ID, err)
+			continue
+		}
+		m.supervisorLock.Lock()
+		m.rescheduled[eng.ID] = append(m.rescheduled[eng.ID], c.ID)
+		m.supervisorLock.Unlock()
+	}
+}
+
+// removeRescheduled destroys the containers of a recovered engine that were
+// replaced while it was down
+func (m *Manager) removeRescheduled(eng *shipyard.Engine) {
+	m.supervisorLock.Lock()
+	ids := m.rescheduled[eng.ID]
+	delete(m.rescheduled, eng.ID)
+	m.supervisorLock.Unlock()
+
+	for _, id := range ids {
+		c, err := m.Container(id)
+		if err != nil || c == nil {
+			continue
+		}
+		if err := m.Destroy(c); err != nil {
+			logger.Warnf("error removing rescheduled container %s: %s", id[:12], err)
+			continue
+		}
+		logger.Infof("removed rescheduled container %s from recovered engine %s", id[:12], eng.ID)
+	}
+}
+
+// reschedule starts a replacement for a container on any eligible engine
+// other than exclude, which may be empty
+func (m *Manager) reschedule(c *citadel.Container, reason string, exclude string) error {
+	img := drainImage(c.Image, c.Engine.ID)
+	engine := ""
+	if exclude != "" {
+		placed, err := m.placeExcluding(img, exclude)
+		if err != nil {
+			return err
+		}
+		engine = placed
+	}
+	launched, err := m.runOn(img, 1, true, engine)
+	if err != nil {
+		return err
+	}
+	nc := launched[0]
+	logger.Infof("rescheduled container %s as %s on engine %s (%s)", c.ID[:12], nc.ID[:12], nc.Engine.ID, reason)
+	evt := &shipyard.Event{
+		Type:      "reschedule",
+		Time:      time.Now(),
+		Container: nc,
+		Engine:    nc.Engine,
+		Message:   fmt.Sprintf("container=%s engine=%s reason=%s", c.ID[:12], c.Engine.ID, reason),
+		Tags:      []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
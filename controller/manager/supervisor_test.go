@@ -0,0 +1,95 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+func TestSuperviseContainers(t *testing.T) {
+	up := newTestEngine(t, "5b0c3e9a", "node-1")
+	down := newTestEngine(t, "7d41f2c8", "node-2")
+	down.Health = &shipyard.Health{Status: "down"}
+	m := newTestManager(t, up, down)
+
+	image := func(policy string) *citadel.Image {
+		return &citadel.Image{
+			Name:        "nginx",
+			Environment: map[string]string{shipyard.ReschedulePolicyEnv: policy},
+		}
+	}
+	lost := &citadel.Container{ID: "c3", Engine: down.Engine, Image: image(shipyard.RescheduleOnEngineFailure)}
+	m.supervised[down.ID] = []*citadel.Container{lost}
+
+	m.superviseContainers([]*citadel.Container{
+		{ID: "c1", Engine: up.Engine, Image: image(shipyard.RescheduleOnEngineFailure)},
+		{ID: "c2", Engine: up.Engine, Image: image(shipyard.RescheduleNo)},
+	})
+
+	if c := m.supervised[up.ID]; len(c) != 1 || c[0].ID != "c1" {
+		t.Errorf("expected c1 supervised on %s; received %v", up.ID, c)
+	}
+	if c := m.supervised[down.ID]; len(c) != 1 || c[0] != lost {
+		t.Errorf("expected the last known containers of %s to be kept; received %v", down.ID, c)
+	}
+}
+
+func TestPlaceExcluding(t *testing.T) {
+	m := newTestManager(t,
+		newTestEngine(t, "5b0c3e9a", "node-1"),
+		newTestEngine(t, "7d41f2c8", "node-2"),
+	)
+	image := &citadel.Image{Name: "nginx", Type: "service", Cpus: 1, Memory: 256}
+	for _, test := range []struct {
+		exclude  string
+		expected string
+	}{
+		{"node-1", "node-2"},
+		{"node-2", "node-1"},
+	} {
+		engine, err := m.placeExcluding(image, test.exclude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if engine != test.expected {
+			t.Errorf("excluding %s: expected %s; received %s", test.exclude, test.expected, engine)
+		}
+	}
+
+	m = newTestManager(t, newTestEngine(t, "5b0c3e9a", "node-1"))
+	if _, err := m.placeExcluding(image, "node-1"); err == nil {
+		t.Error("expected an error without another engine")
+	}
+}
+
+func TestRescheduleEngine(t *testing.T) {
+	failed := newTestEngine(t, "5b0c3e9a", "node-1")
+	up := newTestEngine(t, "7d41f2c8", "node-2")
+	m := newTestManager(t, failed, up)
+	// the engine is rescheduled before its health is known to be down
+	c := &citadel.Container{
+		ID:     "node-1-000000000001",
+		Engine: failed.Engine,
+		Image: &citadel.Image{
+			Name:        "worker",
+			Type:        "service",
+			Environment: map[string]string{shipyard.ReschedulePolicyEnv: shipyard.RescheduleOnEngineFailure},
+		},
+	}
+	m.supervised[failed.ID] = []*citadel.Container{c}
+
+	m.rescheduleEngine(failed)
+
+	if ids := m.rescheduled[failed.ID]; len(ids) != 1 || ids[0] != c.ID {
+		t.Fatalf("expected %s to be rescheduled; received %v", c.ID, ids)
+	}
+	events, err := m.Events(&shipyard.EventQuery{EventFilter: shipyard.EventFilter{Types: []string{"reschedule"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || !strings.HasPrefix(events[0].Container.ID, "node-2-") {
+		t.Errorf("expected a replacement on node-2; received %v", events)
+	}
+}
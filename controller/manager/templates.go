@@ -0,0 +1,132 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameTemplates = "templates"
+)
+
+var (
+	ErrTemplateExists       = errors.New("template already exists")
+	ErrTemplateDoesNotExist = errors.New("template does not exist")
+)
+
+func (m *Manager) Templates() ([]*shipyard.LaunchTemplate, error) {
+	templates := []*shipyard.LaunchTemplate{}
+	if err := m.db.Find(tblNameTemplates, ds.Where().Sort("name", false), &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (m *Manager) Template(name string) (*shipyard.LaunchTemplate, error) {
+	var t *shipyard.LaunchTemplate
+	if err := m.db.FindOne(tblNameTemplates, ds.Where(ds.Eq("name", name)), &t); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrTemplateDoesNotExist
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+func (m *Manager) CreateTemplate(t *shipyard.LaunchTemplate) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	if err := m.checkPool(t.Pool, shipyard.ErrInvalidTemplate); err != nil {
+		return err
+	}
+	if _, err := m.Template(t.Name); err == nil {
+		return ErrTemplateExists
+	} else if err != ErrTemplateDoesNotExist {
+		return err
+	}
+	t.ID = ""
+	t.Created = time.Now()
+	t.Updated = t.Created
+	id, err := m.db.Insert(tblNameTemplates, t)
+	if err != nil {
+		return err
+	}
+	t.ID = id
+	evt := &shipyard.Event{
+		Type:    "create-template",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s image=%s", t.Name, t.Image),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateTemplate replaces a template.  Containers run from it are left
+// unchanged.
+func (m *Manager) UpdateTemplate(t *shipyard.LaunchTemplate) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	if err := m.checkPool(t.Pool, shipyard.ErrInvalidTemplate); err != nil {
+		return err
+	}
+	current, err := m.Template(t.Name)
+	if err != nil {
+		return err
+	}
+	t.ID = current.ID
+	t.Created = current.Created
+	t.Updated = time.Now()
+	if err := m.db.Put(tblNameTemplates, t); err != nil {
+		return err
+	}
+	evt := &shipyard.Event{
+		Type:    "update-template",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s image=%s", t.Name, t.Image),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) DeleteTemplate(name string) error {
+	n, err := m.db.Delete(tblNameTemplates, ds.Where(ds.Eq("name", name)))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrTemplateDoesNotExist
+	}
+	evt := &shipyard.Event{
+		Type:    "delete-template",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s", name),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// TemplateImage returns the image containers of a template are run with
+// after applying the overrides
+func (m *Manager) TemplateImage(name string, overrides *shipyard.TemplateOverrides) (*citadel.Image, error) {
+	t, err := m.Template(name)
+	if err != nil {
+		return nil, err
+	}
+	return t.ContainerImage(overrides)
+}
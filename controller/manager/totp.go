@@ -0,0 +1,127 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	// totpIssuer labels the account in authenticator apps
+	totpIssuer = "Shipyard"
+)
+
+var (
+	ErrInvalidOTP         = shipyard.ErrInvalidOTP
+	ErrTOTPAlreadyEnabled = errors.New("two-factor auth is already enabled")
+	ErrTOTPNotEnabled     = errors.New("two-factor auth is not enabled")
+	ErrTOTPNotEnrolled    = errors.New("two-factor auth enrollment has not been started")
+)
+
+// Enable2FA starts two-factor enrollment for the account.  Codes are not
+// required at login until the enrollment is confirmed with Confirm2FA.
+func (m *Manager) Enable2FA(username string) (*shipyard.TOTPEnrollment, error) {
+	acct, err := m.Account(username)
+	if err != nil {
+		return nil, err
+	}
+	if acct.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	secret, err := shipyard.NewTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.updateTOTP(acct, map[string]interface{}{"totp_secret": secret, "totp_enabled": false}); err != nil {
+		return nil, err
+	}
+	return &shipyard.TOTPEnrollment{
+		Secret: secret,
+		URI:    shipyard.TOTPURI(totpIssuer, username, secret),
+	}, nil
+}
+
+// Confirm2FA enables two-factor auth once a code from the enrolled secret
+// is given
+func (m *Manager) Confirm2FA(username string, code string) error {
+	acct, err := m.Account(username)
+	if err != nil {
+		return err
+	}
+	if acct.TOTPEnabled {
+		return ErrTOTPAlreadyEnabled
+	}
+	if acct.TOTPSecret == "" {
+		return ErrTOTPNotEnrolled
+	}
+	step, ok := shipyard.ValidateTOTP(acct.TOTPSecret, code, time.Now())
+	if !ok {
+		return ErrInvalidOTP
+	}
+	if err := m.updateTOTP(acct, map[string]interface{}{"totp_enabled": true, "totp_last_step": step}); err != nil {
+		return err
+	}
+	return m.saveTOTPEvent("enable-2fa", username)
+}
+
+// Disable2FA turns off two-factor auth; a current code is required
+func (m *Manager) Disable2FA(username string, code string) error {
+	acct, err := m.Account(username)
+	if err != nil {
+		return err
+	}
+	if !acct.TOTPEnabled {
+		return ErrTOTPNotEnabled
+	}
+	if err := m.verifyTOTP(acct, code); err != nil {
+		return err
+	}
+	if err := m.updateTOTP(acct, map[string]interface{}{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0}); err != nil {
+		return err
+	}
+	return m.saveTOTPEvent("disable-2fa", username)
+}
+
+// VerifyOTP checks the login code of accounts with two-factor auth enabled.
+// A code is accepted only once.
+func (m *Manager) VerifyOTP(username string, code string) error {
+	acct, err := m.Account(username)
+	if err != nil {
+		return err
+	}
+	if !acct.TOTPEnabled {
+		return nil
+	}
+	if code == "" {
+		return shipyard.ErrOTPRequired
+	}
+	return m.verifyTOTP(acct, code)
+}
+
+func (m *Manager) verifyTOTP(acct *shipyard.Account, code string) error {
+	step, ok := shipyard.ValidateTOTP(acct.TOTPSecret, code, time.Now())
+	if !ok || step <= acct.TOTPLastStep {
+		return ErrInvalidOTP
+	}
+	return m.updateTOTP(acct, map[string]interface{}{"totp_last_step": step})
+}
+
+func (m *Manager) updateTOTP(acct *shipyard.Account, update map[string]interface{}) error {
+	if _, err := m.db.Update(tblNameAccounts, ds.ByID(acct.ID), update); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) saveTOTPEvent(typ string, username string) error {
+	evt := &shipyard.Event{
+		Type:    typ,
+		Time:    time.Now(),
+		Message: fmt.Sprintf("username=%s", username),
+		Tags:    []string{"cluster", "security"},
+	}
+	return m.SaveEvent(evt)
+}
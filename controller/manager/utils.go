@@ -5,19 +5,23 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/hex"
+	"fmt"
 	"net/url"
 	"time"
 
 	"github.com/citadel/citadel"
+	"github.com/samalba/dockerclient"
+	"github.com/shipyard/shipyard"
 )
 
 func getTLSConfig(caCert, sslCert, sslKey []byte) (*tls.Config, error) {
-	// TLS config
+	// TLS config; the engine is verified against its ca
 	var tlsConfig tls.Config
-	tlsConfig.InsecureSkipVerify = true
 	certPool := x509.NewCertPool()
 
-	certPool.AppendCertsFromPEM(caCert)
+	if !certPool.AppendCertsFromPEM(caCert) {
+		return &tlsConfig, fmt.Errorf("%w: no pem encoded ca certificate", shipyard.ErrInvalidCertificate)
+	}
 	tlsConfig.RootCAs = certPool
 	cert, err := tls.X509KeyPair(sslCert, sslKey)
 	if err != nil {
@@ -28,18 +32,33 @@ func getTLSConfig(caCert, sslCert, sslKey []byte) (*tls.Config, error) {
 	return &tlsConfig, nil
 }
 
-func setEngineClient(docker *citadel.Engine, tlsConfig *tls.Config) error {
+// engineTLSConfig returns the tls configuration for an engine; it is empty
+// unless all certificates are set
+func engineTLSConfig(engine *shipyard.Engine) (*tls.Config, error) {
+	if engine.CACertificate == "" || engine.SSLCertificate == "" || engine.SSLKey == "" {
+		return &tls.Config{}, nil
+	}
+	return getTLSConfig([]byte(engine.CACertificate), []byte(engine.SSLCertificate), []byte(engine.SSLKey))
+}
+
+func setEngineClient(docker *citadel.Engine, tlsConfig *tls.Config) (*dockerclient.DockerClient, error) {
 	var tc *tls.Config
 	u, err := url.Parse(docker.Addr)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if u.Scheme == "https" {
 		tc = tlsConfig
 	}
 
-	return docker.Connect(tc)
+	c, err := dockerclient.NewDockerClient(docker.Addr, tc)
+	if err != nil {
+		return nil, err
+	}
+	docker.SetClient(c)
+
+	return c, nil
 }
 
 func generateId(n int) string {
@@ -49,3 +68,7 @@ func generateId(n int) string {
 	mdStr := hex.EncodeToString(md)
 	return mdStr[:n]
 }
+
+func parseDockerTime(t string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, t)
+}
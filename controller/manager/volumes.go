@@ -0,0 +1,180 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameVolumes = "volumes"
+)
+
+var (
+	ErrVolumeExists       = errors.New("volume already exists")
+	ErrVolumeDoesNotExist = errors.New("volume does not exist")
+	ErrVolumeInUse        = errors.New("volume is used by containers")
+	ErrVolumeEngines      = errors.New("volumes are on different engines")
+)
+
+func (m *Manager) Volumes() ([]*shipyard.Volume, error) {
+	volumes := []*shipyard.Volume{}
+	if err := m.db.Find(tblNameVolumes, ds.Where().Sort("name", false), &volumes); err != nil {
+		return nil, err
+	}
+	return volumes, nil
+}
+
+func (m *Manager) Volume(name string) (*shipyard.Volume, error) {
+	var volume *shipyard.Volume
+	if err := m.db.FindOne(tblNameVolumes, ds.Where(ds.Eq("name", name)), &volume); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrVolumeDoesNotExist
+		}
+		return nil, err
+	}
+	return volume, nil
+}
+
+// CreateVolume registers a volume on an engine.  The directory is created
+// by the engine when the first container mounting it starts.
+func (m *Manager) CreateVolume(volume *shipyard.Volume) error {
+	if err := volume.Validate(); err != nil {
+		return err
+	}
+	eng := m.volumeEngine(volume.Engine)
+	if eng == nil {
+		return fmt.Errorf("%w: unknown engine %s", shipyard.ErrInvalidVolume, volume.Engine)
+	}
+	volume.Engine = eng.ID
+	if _, err := m.Volume(volume.Name); err == nil {
+		return ErrVolumeExists
+	} else if err != ErrVolumeDoesNotExist {
+		return err
+	}
+	volume.ID = ""
+	volume.Created = time.Now()
+	id, err := m.db.Insert(tblNameVolumes, volume)
+	if err != nil {
+		return err
+	}
+	volume.ID = id
+	evt := &shipyard.Event{
+		Type:    "create-volume",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s engine=%s path=%s", volume.Name, volume.Engine, volume.Path),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// volumeEngine returns the engine of a volume by id or by the engine name
+// containers refer to it by
+func (m *Manager) volumeEngine(id string) *shipyard.Engine {
+	if eng := m.Engine(id); eng != nil {
+		return eng
+	}
+	return m.clusterEngine(id)
+}
+
+// RemoveVolume unregisters a volume no container mounts any longer.  The
+// data is left in place on the engine.
+func (m *Manager) RemoveVolume(name string) error {
+	volume, err := m.Volume(name)
+	if err != nil {
+		return err
+	}
+	for _, c := range m.Containers(true) {
+		mounts, err := shipyard.VolumeMounts(c.Image)
+		if err != nil {
+			continue
+		}
+		for _, mount := range mounts {
+			if mount.Name == volume.Name {
+				return fmt.Errorf("%w: %s", ErrVolumeInUse, c.ID[:12])
+			}
+		}
+	}
+	if _, err := m.db.Delete(tblNameVolumes, ds.ByID(volume.ID)); err != nil {
+		return err
+	}
+	evt := &shipyard.Event{
+		Type:    "remove-volume",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("name=%s engine=%s", volume.Name, volume.Engine),
+		Tags:    []string{"cluster"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// injectVolumes returns a copy of the image with the binds of its volume
+// mounts added and its placement pinned to the engine holding the volumes
+func (m *Manager) injectVolumes(image *citadel.Image) (*citadel.Image, error) {
+	mounts, err := shipyard.VolumeMounts(image)
+	if err != nil || len(mounts) == 0 {
+		return image, err
+	}
+	mounted := map[string]bool{}
+	for _, mount := range mounts {
+		mounted[mount.Path] = true
+	}
+	img := *image
+	img.Volumes = []string{}
+	// volumes of listed containers only report the container path
+	for _, v := range image.Volumes {
+		parts := strings.Split(v, ":")
+		if mounted[parts[0]] || (len(parts) > 1 && mounted[parts[1]]) {
+			continue
+		}
+		img.Volumes = append(img.Volumes, v)
+	}
+	var eng *shipyard.Engine
+	for _, mount := range mounts {
+		volume, err := m.Volume(mount.Name)
+		if err != nil {
+			return nil, fmt.Errorf("volume %s: %s", mount.Name, err)
+		}
+		if eng != nil && eng.ID != volume.Engine {
+			return nil, ErrVolumeEngines
+		}
+		if eng = m.volumeEngine(volume.Engine); eng == nil {
+			return nil, fmt.Errorf("volume %s: engine %s is not in the cluster", volume.Name, volume.Engine)
+		}
+		img.Volumes = append(img.Volumes, mount.Bind(volume))
+	}
+	img.Type = "host"
+	img.Labels = []string{fmt.Sprintf("host:%s", eng.Engine.ID)}
+	return &img, nil
+}
+
+// restartStranded starts the containers of a recovered engine that could
+// not be rescheduled elsewhere because their volumes are on it
+func (m *Manager) restartStranded(eng *shipyard.Engine) {
+	m.supervisorLock.Lock()
+	ids := m.stranded[eng.ID]
+	delete(m.stranded, eng.ID)
+	m.supervisorLock.Unlock()
+
+	for _, id := range ids {
+		c, err := m.Container(id)
+		if err != nil || c == nil || c.State == "running" {
+			continue
+		}
+		if err := m.Start(c); err != nil {
+			logger.Warnf("error restarting container %s on recovered engine %s: %s", id[:12], eng.ID, err)
+			continue
+		}
+		logger.Infof("restarted container %s on recovered engine %s", id[:12], eng.ID)
+	}
+}
@@ -0,0 +1,28 @@
+package manager
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shipyard/shipyard"
+)
+
+func TestCreateVolumeResolvesEngine(t *testing.T) {
+	m := newTestManager(t, newTestEngine(t, "5b0c3e9a", "node-1"))
+	for _, engine := range []string{"5b0c3e9a", "node-1"} {
+		v := &shipyard.Volume{Name: "data-" + engine, Engine: engine}
+		if err := m.CreateVolume(v); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := m.Volume(v.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Engine != "5b0c3e9a" {
+			t.Errorf("%s: expected the volume on the engine id; received %s", engine, stored.Engine)
+		}
+	}
+	if err := m.CreateVolume(&shipyard.Volume{Name: "data", Engine: "node-2"}); !errors.Is(err, shipyard.ErrInvalidVolume) {
+		t.Errorf("expected ErrInvalidVolume for an unknown engine; received %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/shipyard/shipyard"
+	ds "github.com/shipyard/shipyard/controller/datastore"
+)
+
+const (
+	tblNameWebhooks = "webhooks"
+
+	webhookTimeout     = 10 * time.Second
+	webhookMaxAttempts = 3
+	webhookBackoff     = 2 * time.Second
+)
+
+var (
+	ErrWebhookDoesNotExist = errors.New("webhook does not exist")
+	ErrInvalidWebhookURL   = errors.New("webhook url must be an absolute http or https url")
+
+	webhookClient = &http.Client{Timeout: webhookTimeout}
+)
+
+func (m *Manager) Webhooks() ([]*shipyard.Webhook, error) {
+	hooks := []*shipyard.Webhook{}
+	if err := m.db.Find(tblNameWebhooks, ds.Where().Sort("url", false), &hooks); err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+func (m *Manager) Webhook(id string) (*shipyard.Webhook, error) {
+	var hook *shipyard.Webhook
+	if err := m.db.Get(tblNameWebhooks, id, &hook); err != nil {
+		if err == ds.ErrNotFound {
+			return nil, ErrWebhookDoesNotExist
+		}
+		return nil, err
+	}
+	return hook, nil
+}
+
+// AddWebhook registers a webhook.  A secret is generated when none is
+// given.
+func (m *Manager) AddWebhook(hook *shipyard.Webhook) error {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	if hook.Secret == "" {
+		secret, err := m.authenticator.GenerateToken()
+		if err != nil {
+			return err
+		}
+		hook.Secret = secret
+	}
+	hook.ID = ""
+	id, err := m.db.Insert(tblNameWebhooks, hook)
+	if err != nil {
+		return err
+	}
+	hook.ID = id
+	evt := &shipyard.Event{
+		Type:    "add-webhook",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("id=%s url=%s", hook.ID, u.Host),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) RemoveWebhook(id string) error {
+	hook, err := m.Webhook(id)
+	if err != nil {
+		return err
+	}
+	if _, err := m.db.Delete(tblNameWebhooks, ds.ByID(hook.ID)); err != nil {
+		return err
+	}
+	evt := &shipyard.Event{
+		Type:    "remove-webhook",
+		Time:    time.Now(),
+		Message: fmt.Sprintf("id=%s", hook.ID),
+		Tags:    []string{"cluster", "security"},
+	}
+	if err := m.SaveEvent(evt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// dispatchWebhooks delivers every saved event to the matching webhooks
+func (m *Manager) dispatchWebhooks() {
+	events := m.SubscribeEvents(nil)
+	for evt := range events {
+		if !m.IsLeader() {
+			continue
+		}
+		hooks, err := m.Webhooks()
+		if err != nil {
+			logger.Errorf("error loading webhooks: %s", err)
+			continue
+		}
+		for _, hook := range hooks {
+			if hook.Match(evt) {
+				go deliverWebhook(hook, evt)
+			}
+		}
+	}
+}
+
+// deliverWebhook posts the event, retrying failed deliveries
+func deliverWebhook(hook *shipyard.Webhook, evt *shipyard.Event) {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		logger.Errorf("error encoding webhook payload: %s", err)
+		return
+	}
+	backoff := webhookBackoff
+	for attempt := 1; ; attempt++ {
+		err = postWebhook(hook, evt.Type, payload)
+		if err == nil {
+			return
+		}
+		if attempt == webhookMaxAttempts {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	logger.Warnf("giving up delivering %s event to webhook %s: %s", evt.Type, hook.ID, err)
+}
+
+func postWebhook(hook *shipyard.Webhook, eventType string, payload []byte) error {
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(shipyard.WebhookEventHeader, eventType)
+	req.Header.Set(shipyard.WebhookSignatureHeader, shipyard.WebhookSignature(hook.Secret, payload))
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
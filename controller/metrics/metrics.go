@@ -0,0 +1,298 @@
+// Package metrics exposes controller metrics in the Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard/controller/manager"
+)
+
+var (
+	// durationBuckets are the request latency histogram bounds in seconds
+	durationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+)
+
+type requestKey struct {
+	method string
+	route  string
+	code   int
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range durationBuckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// Metrics counts api requests and saved events, and reports engine and
+// cluster capacity when scraped
+type Metrics struct {
+	manager *manager.Manager
+	// router labels requests with the path template of their route
+	router *mux.Router
+
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	durations map[requestKey]*histogram
+	events    map[string]uint64
+}
+
+// NewMetrics returns metrics for the manager and the api routes of router,
+// named after their path template, and starts counting events
+func NewMetrics(m *manager.Manager, router *mux.Router) *Metrics {
+	metrics := &Metrics{
+		manager:   m,
+		router:    router,
+		requests:  make(map[requestKey]uint64),
+		durations: make(map[requestKey]*histogram),
+		events:    make(map[string]uint64),
+	}
+	if m != nil {
+		go metrics.countEvents()
+	}
+	return metrics
+}
+
+func (m *Metrics) countEvents() {
+	events := m.manager.SubscribeEvents(nil)
+	for evt := range events {
+		m.mu.Lock()
+		m.events[evt.Type]++
+		m.mu.Unlock()
+	}
+}
+
+// HandlerFuncWithNext records the count and latency of every request
+func (m *Metrics) HandlerFuncWithNext(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if next == nil {
+		return
+	}
+	start := time.Now()
+	rw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	next(rw, r)
+	m.observe(r.Method, m.route(r), rw.status, time.Since(start))
+}
+
+func (m *Metrics) observe(method string, route string, code int, d time.Duration) {
+	key := requestKey{method: method, route: route, code: code}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[key]++
+	h, ok := m.durations[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(durationBuckets))}
+		m.durations[key] = h
+	}
+	h.observe(d.Seconds())
+}
+
+// route returns the path template of the route of a request, like
+// /api/containers/{id}/logs, or "unmatched" so requests for unknown paths
+// do not add labels
+func (m *Metrics) route(r *http.Request) string {
+	var match mux.RouteMatch
+	if m.router != nil && m.router.Match(r, &match) && match.Route.GetName() != "" {
+		return match.Route.GetName()
+	}
+	return "unmatched"
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "text/plain; version=0.0.4")
+	m.writeRequestMetrics(w)
+	if m.manager != nil {
+		m.writeClusterMetrics(w)
+	}
+}
+
+func (m *Metrics) writeRequestMetrics(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.code < b.code
+	})
+
+	header(w, "shipyard_http_requests_total", "counter", "API requests by method, route and status code.")
+	for _, k := range keys {
+		sample(w, "shipyard_http_requests_total", requestLabels(k), float64(m.requests[k]))
+	}
+
+	header(w, "shipyard_http_request_duration_seconds", "histogram", "API request latency.")
+	for _, k := range keys {
+		h := m.durations[k]
+		labels := requestLabels(k)
+		for i, b := range durationBuckets {
+			sample(w, "shipyard_http_request_duration_seconds_bucket", append(labels, "le", strconv.FormatFloat(b, 'g', -1, 64)), float64(h.counts[i]))
+		}
+		sample(w, "shipyard_http_request_duration_seconds_bucket", append(labels, "le", "+Inf"), float64(h.count))
+		sample(w, "shipyard_http_request_duration_seconds_sum", labels, h.sum)
+		sample(w, "shipyard_http_request_duration_seconds_count", labels, float64(h.count))
+	}
+
+	types := make([]string, 0, len(m.events))
+	for t := range m.events {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	header(w, "shipyard_events_total", "counter", "Cluster events saved since the controller started, by type.")
+	for _, t := range types {
+		sample(w, "shipyard_events_total", []string{"type", t}, float64(m.events[t]))
+	}
+}
+
+func (m *Metrics) writeClusterMetrics(w io.Writer) {
+	info := m.manager.ClusterInfo()
+	header(w, "shipyard_cluster_cpus", "gauge", "Total cpus of all engines.")
+	sample(w, "shipyard_cluster_cpus", nil, info.Cpus)
+	header(w, "shipyard_cluster_reserved_cpus", "gauge", "Cpus reserved by containers.")
+	sample(w, "shipyard_cluster_reserved_cpus", nil, info.ReservedCpus)
+	header(w, "shipyard_cluster_memory_bytes", "gauge", "Total memory of all engines.")
+	sample(w, "shipyard_cluster_memory_bytes", nil, mb(info.Memory))
+	header(w, "shipyard_cluster_reserved_memory_bytes", "gauge", "Memory reserved by containers.")
+	sample(w, "shipyard_cluster_reserved_memory_bytes", nil, mb(info.ReservedMemory))
+
+	engines := m.manager.Engines()
+	counts := map[string]int{}
+	reservedCpus := map[string]float64{}
+	reservedMemory := map[string]float64{}
+	for _, c := range m.manager.Containers(false) {
+		if c.Engine == nil {
+			continue
+		}
+		counts[c.Engine.ID]++
+		if c.Image != nil {
+			reservedCpus[c.Engine.ID] += c.Image.Cpus
+			reservedMemory[c.Engine.ID] += c.Image.Memory
+		}
+	}
+
+	header(w, "shipyard_engine_up", "gauge", "Whether the last engine health check succeeded.")
+	for _, e := range engines {
+		up := 0.0
+		if e.Health != nil && e.Health.Status == manager.EngineHealthUp {
+			up = 1
+		}
+		sample(w, "shipyard_engine_up", []string{"engine", e.Engine.ID}, up)
+	}
+	header(w, "shipyard_engine_containers", "gauge", "Running containers per engine.")
+	for _, e := range engines {
+		sample(w, "shipyard_engine_containers", []string{"engine", e.Engine.ID}, float64(counts[e.Engine.ID]))
+	}
+	header(w, "shipyard_engine_cpus", "gauge", "Cpus per engine.")
+	for _, e := range engines {
+		sample(w, "shipyard_engine_cpus", []string{"engine", e.Engine.ID}, e.Engine.Cpus)
+	}
+	header(w, "shipyard_engine_reserved_cpus", "gauge", "Cpus reserved by running containers per engine.")
+	for _, e := range engines {
+		sample(w, "shipyard_engine_reserved_cpus", []string{"engine", e.Engine.ID}, reservedCpus[e.Engine.ID])
+	}
+	header(w, "shipyard_engine_memory_bytes", "gauge", "Memory per engine.")
+	for _, e := range engines {
+		sample(w, "shipyard_engine_memory_bytes", []string{"engine", e.Engine.ID}, mb(e.Engine.Memory))
+	}
+	header(w, "shipyard_engine_reserved_memory_bytes", "gauge", "Memory reserved by running containers per engine.")
+	for _, e := range engines {
+		sample(w, "shipyard_engine_reserved_memory_bytes", []string{"engine", e.Engine.ID}, mb(reservedMemory[e.Engine.ID]))
+	}
+}
+
+// mb converts citadel memory sizes to bytes
+func mb(v float64) float64 {
+	return v * 1024 * 1024
+}
+
+func requestLabels(k requestKey) []string {
+	return []string{"method", k.method, "route", k.route, "code", strconv.Itoa(k.code)}
+}
+
+func header(w io.Writer, name string, typ string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes a metric line; labels are name, value pairs
+func sample(w io.Writer, name string, labels []string, v float64) {
+	fmt.Fprint(w, name)
+	if len(labels) > 0 {
+		pairs := []string{}
+		for i := 0; i+1 < len(labels); i += 2 {
+			pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", labels[i], escape(labels[i+1])))
+		}
+		fmt.Fprintf(w, "{%s}", strings.Join(pairs, ","))
+	}
+	fmt.Fprintf(w, " %s\n", strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+// statusWriter records the response status
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	w.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRequestMetrics(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/containers/{id}/logs", handler).Methods("GET").Name("/api/containers/{id}/logs")
+	m := NewMetrics(nil, router)
+	for _, path := range []string{"/api/containers/abc/logs", "/api/containers/def/logs", "/api/abc", "/api/def"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		m.HandlerFuncWithNext(httptest.NewRecorder(), req, handler)
+	}
+
+	res := httptest.NewRecorder()
+	m.ServeHTTP(res, nil)
+	body := res.Body.String()
+	for _, expected := range []string{
+		`shipyard_http_requests_total{method="GET",route="/api/containers/{id}/logs",code="404"} 2`,
+		`shipyard_http_requests_total{method="GET",route="unmatched",code="404"} 2`,
+		`shipyard_http_request_duration_seconds_bucket{method="GET",route="/api/containers/{id}/logs",code="404",le="+Inf"} 2`,
+		`# TYPE shipyard_http_request_duration_seconds histogram`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected %q in:\n%s", expected, body)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	if e := escape(`a"b\c`); e != `a\"b\\c` {
+		t.Errorf("unexpected escaped value %s", e)
+	}
+}
@@ -21,27 +21,12 @@ func defaultDeniedHandler(w http.ResponseWriter, r *http.Request) {
 type AccessRequired struct {
 	deniedHandler http.Handler
 	manager       *manager.Manager
-	acl           map[string][]string
-}
-
-func defaultAccessLevels() map[string][]string {
-	acl := make(map[string][]string)
-	acl["admin"] = []string{"*"}
-	acl["user"] = []string{
-		"/api/containers",
-		"/api/cluster/info",
-		"/api/events",
-		"/api/engines",
-	}
-	return acl
 }
 
 func NewAccessRequired(m *manager.Manager) *AccessRequired {
-	acl := defaultAccessLevels()
 	a := &AccessRequired{
 		deniedHandler: http.HandlerFunc(defaultDeniedHandler),
 		manager:       m,
-		acl:           acl,
 	}
 	return a
 }
@@ -70,9 +55,8 @@ func (a *AccessRequired) handleRequest(w http.ResponseWriter, r *http.Request) e
 			if err != nil {
 				return err
 			}
-			role := acct.Role
 			// check role
-			valid = a.checkAccess(r.URL.Path, role)
+			valid = a.checkAccess(r.Method, r.URL.Path, a.requestRole(r, acct))
 		}
 	} else { // only check access for users; not service keys
 		valid = true
@@ -86,15 +70,118 @@ func (a *AccessRequired) handleRequest(w http.ResponseWriter, r *http.Request) e
 	return nil
 }
 
-func (a *AccessRequired) checkAccess(path string, role *shipyard.Role) bool {
-	valid := false
-	for _, v := range a.acl[role.Name] {
-		if v == "*" || strings.HasPrefix(path, v) {
-			valid = true
-			break
+// requestRole returns the role granting the request.  Requests for
+// namespaced resources in a namespace use the role of the account in the
+// namespace; accounts without one need a global role granting everything.
+// Requests in unknown namespaces get no role.
+func (a *AccessRequired) requestRole(r *http.Request, acct *shipyard.Account) *shipyard.Role {
+	role := acct.Role
+	ns := r.Header.Get(shipyard.NamespaceHeader)
+	if ns != "" {
+		if _, err := a.manager.Namespace(ns); err != nil {
+			return nil
 		}
+		resource := strings.SplitN(RequiredPermission(r.Method, r.URL.Path), ":", 2)[0]
+		if shipyard.NamespaceResources[resource] {
+			if name := acct.NamespaceRole(ns); name != "" {
+				role = &shipyard.Role{Name: name}
+			} else if role == nil || !role.HasPermission(shipyard.PermissionAll) {
+				return nil
+			}
+		}
+	}
+	// use the current role definition; accounts hold a copy
+	if role != nil {
+		if current, err := a.manager.Role(role.Name); err == nil {
+			role = current
+		}
+	}
+	return role
+}
+
+func (a *AccessRequired) checkAccess(method string, path string, role *shipyard.Role) bool {
+	if AuthenticationOnly(method, path) {
+		return true
+	}
+	if role == nil {
+		return false
+	}
+	return role.HasPermission(RequiredPermission(method, path))
+}
+
+// AuthenticationOnly reports whether an api request is granted to any
+// authenticated account or service key, as pings are
+func AuthenticationOnly(method string, path string) bool {
+	return method == "GET" && strings.TrimSuffix(path, "/") == "/api/ping"
+}
+
+// getActions are the GET endpoints (/api/<resource>/<id>/<action>) that
+// change state or, like archive and export, expose container files
+var getActions = map[string]bool{
+	"attach":   true,
+	"archive":  true,
+	"export":   true,
+	"start":    true,
+	"stop":     true,
+	"restart":  true,
+	"pause":    true,
+	"unpause":  true,
+	"scale":    true,
+	"cordon":   true,
+	"uncordon": true,
+	"drain":    true,
+}
+
+// RequiredPermission maps an api request to the permission it needs.  The
+// resource is the first path element after /api; reads need resource:read,
+// launching or scaling containers, including from a template, needs
+// containers:run, reading secret values and backups, which hold
+// credentials, needs resource:admin and anything else needs
+// resource:write.  Pings only need authentication and require no
+// permission; the prometheus metrics need cluster:read.
+func RequiredPermission(method string, path string) string {
+	if strings.TrimSuffix(path, "/") == "/metrics" {
+		return shipyard.Permission("cluster", shipyard.ActionRead)
+	}
+	parts := strings.Split(strings.TrimPrefix(path, "/api/"), "/")
+	resource := parts[0]
+	action := ""
+	if len(parts) == 3 {
+		action = parts[2]
+	}
+	switch {
+	case resource == "ping" && len(parts) == 1:
+		return ""
+	case resource == "containers" && isContainerRun(method, parts):
+		return shipyard.Permission(resource, shipyard.ActionRun)
+	case resource == "templates" && method == "POST" && action == "run":
+		return shipyard.Permission("containers", shipyard.ActionRun)
+	case resource == "applications" && method == "POST" && action == "diff":
+		return shipyard.Permission(resource, shipyard.ActionRead)
+	case resource == "secrets" && action == "value", resource == "backup":
+		return shipyard.Permission(resource, shipyard.ActionAdmin)
+	case method == "GET" && !getActions[action]:
+		return shipyard.Permission(resource, shipyard.ActionRead)
+	}
+	return shipyard.Permission(resource, shipyard.ActionWrite)
+}
+
+// ReadOnly reports whether an api request leaves cluster state unchanged
+func ReadOnly(method string, path string) bool {
+	return AuthenticationOnly(method, path) || strings.HasSuffix(RequiredPermission(method, path), ":"+shipyard.ActionRead)
+}
+
+// isContainerRun reports whether a request launches or scales containers
+func isContainerRun(method string, parts []string) bool {
+	switch {
+	case method == "POST" && len(parts) == 1:
+		return true
+	case method == "POST" && len(parts) == 2 && parts[1] == "scale":
+		return true
+	case len(parts) == 3 && parts[2] == "scale":
+		return true
 	}
-	return valid
+	return false
 }
 
 func (a *AccessRequired) HandlerFuncWithNext(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
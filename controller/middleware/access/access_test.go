@@ -0,0 +1,91 @@
+package access
+
+import (
+	"testing"
+)
+
+func TestRequiredPermission(t *testing.T) {
+	tests := []struct {
+		method     string
+		path       string
+		permission string
+	}{
+		{"GET", "/api/containers", "containers:read"},
+		{"GET", "/metrics", "cluster:read"},
+		{"GET", "/api/containers/abc/logs", "containers:read"},
+		{"POST", "/api/containers", "containers:run"},
+		{"POST", "/api/containers/scale", "containers:run"},
+		{"GET", "/api/containers/abc/scale", "containers:run"},
+		{"POST", "/api/templates/web/run", "containers:run"},
+		{"POST", "/api/templates", "templates:write"},
+		{"GET", "/api/templates/web", "templates:read"},
+		{"POST", "/api/applications/web/diff", "applications:read"},
+		{"PUT", "/api/applications/web", "applications:write"},
+		{"POST", "/api/applications/web/rollback", "applications:write"},
+		{"POST", "/api/deployments/abc/promote", "deployments:write"},
+		{"GET", "/api/containers/abc/stop", "containers:write"},
+		{"DELETE", "/api/containers/abc", "containers:write"},
+		{"POST", "/api/containers/abc/exec", "containers:write"},
+		{"POST", "/api/containers/groups/destroy", "containers:write"},
+		{"PATCH", "/api/containers/abc", "containers:write"},
+		{"POST", "/api/containers/abc/rename", "containers:write"},
+		{"POST", "/api/containers/abc/commit", "containers:write"},
+		{"GET", "/api/containers/abc/archive", "containers:write"},
+		{"PUT", "/api/containers/abc/archive", "containers:write"},
+		{"GET", "/api/containers/abc/top", "containers:read"},
+		{"GET", "/api/containers/abc/wait", "containers:read"},
+		{"GET", "/api/containers/abc/attach", "containers:write"},
+		{"POST", "/api/images/build", "images:write"},
+		{"GET", "/api/containers/abc/changes", "containers:read"},
+		{"GET", "/api/containers/abc/export", "containers:write"},
+		{"GET", "/api/engines", "engines:read"},
+		{"POST", "/api/engines", "engines:write"},
+		{"GET", "/api/engines/abc/drain", "engines:write"},
+		{"GET", "/api/engines/abc/certificates", "engines:read"},
+		{"PUT", "/api/engines/abc/certificates", "engines:write"},
+		{"GET", "/api/engines/join-tokens", "engines:read"},
+		{"POST", "/api/engines/join-tokens", "engines:write"},
+		{"GET", "/api/events/stream", "events:read"},
+		{"PUT", "/api/accounts", "accounts:write"},
+		{"GET", "/api/secrets", "secrets:read"},
+		{"GET", "/api/secrets/db/value", "secrets:admin"},
+		{"GET", "/api/gc/policy", "gc:read"},
+		{"PUT", "/api/gc/policy", "gc:write"},
+		{"POST", "/api/gc/run", "gc:write"},
+		{"GET", "/api/events/retention", "events:read"},
+		{"PUT", "/api/events/retention", "events:write"},
+		{"POST", "/api/events/retention/run", "events:write"},
+		{"GET", "/api/backup", "backup:admin"},
+		{"POST", "/api/backup/restore", "backup:admin"},
+		{"GET", "/api/pools/zone-a", "pools:read"},
+		{"DELETE", "/api/pools/zone-a", "pools:write"},
+		{"POST", "/api/cluster/rebalance", "cluster:write"},
+		{"POST", "/api/containers/batch", "containers:write"},
+		{"GET", "/api/ping", ""},
+		{"GET", "/api/containers/abc/metrics", "containers:read"},
+		{"GET", "/api/engines/abc/metrics", "engines:read"},
+		{"GET", "/api/alerts", "alerts:read"},
+		{"DELETE", "/api/alerts/rules/abc", "alerts:write"},
+	}
+	for _, test := range tests {
+		if p := RequiredPermission(test.method, test.path); p != test.permission {
+			t.Errorf("expected %s for %s %s; received %s", test.permission, test.method, test.path, p)
+		}
+	}
+}
+
+func TestAuthenticationOnly(t *testing.T) {
+	a := &AccessRequired{}
+	if !a.checkAccess("GET", "/api/ping", nil) {
+		t.Error("expected pings to be granted without a role")
+	}
+	if a.checkAccess("GET", "/api/containers", nil) {
+		t.Error("expected requests without a role to be denied")
+	}
+	if a.checkAccess("POST", "/api/ping", nil) {
+		t.Error("expected only GET pings to be granted without a role")
+	}
+	if !ReadOnly("GET", "/api/ping") || ReadOnly("POST", "/api/pings") {
+		t.Error("expected only pings among the unmapped requests to be read only")
+	}
+}
@@ -0,0 +1,177 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/manager"
+	"github.com/shipyard/shipyard/controller/middleware/access"
+)
+
+const (
+	// maxSummaryLength bounds the payload stored with each entry
+	maxSummaryLength = 1024
+	redacted         = "<redacted>"
+)
+
+var (
+	logger = logrus.New()
+
+	// secretFields are payload keys whose values are never stored
+	secretFields = []string{"password", "token", "key", "secret", "ssl_cert", "ca_cert", "webhook_url"}
+)
+
+// AuditLog records every state changing request in the audit log
+type AuditLog struct {
+	manager *manager.Manager
+}
+
+func NewAuditLog(m *manager.Manager) *AuditLog {
+	return &AuditLog{
+		manager: m,
+	}
+}
+
+func (a *AuditLog) HandlerFuncWithNext(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if next == nil {
+		return
+	}
+	if r.Method == "GET" && access.ReadOnly(r.Method, r.URL.Path) {
+		next(w, r)
+		return
+	}
+
+	entry := &shipyard.AuditEntry{
+		Time:       time.Now(),
+		RemoteAddr: r.RemoteAddr,
+		Method:     r.Method,
+		Endpoint:   r.URL.RequestURI(),
+	}
+	if key := r.Header.Get("X-Service-Key"); key != "" {
+		entry.ServiceKey = keyFingerprint(key)
+		if k, err := a.manager.ServiceKey(key); err == nil && k.Description != "" {
+			entry.ServiceKey = k.Description
+		}
+	} else if parts := strings.Split(r.Header.Get("X-Access-Token"), ":"); len(parts) == 2 {
+		entry.Username = parts[0]
+	}
+	// backups hold credentials; only the request is recorded
+	if r.Body != nil && access.RequiredPermission(r.Method, r.URL.Path) != "backup:"+shipyard.ActionAdmin {
+		buf, _ := ioutil.ReadAll(io.LimitReader(r.Body, maxSummaryLength))
+		r.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(buf), r.Body))
+		entry.Summary = summarize(buf)
+	}
+
+	rw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	next(rw, r)
+
+	entry.StatusCode = rw.status
+	entry.Success = rw.status < 400
+	if err := a.manager.SaveAuditEntry(entry); err != nil {
+		logger.Errorf("error saving audit entry: %s", err)
+	}
+}
+
+// summarize returns the payload with secret fields redacted.  Payloads that
+// are not json objects are stored as is.
+func summarize(payload []byte) string {
+	if len(payload) == 0 {
+		return ""
+	}
+	var v interface{}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		if len(payload) >= maxSummaryLength {
+			return string(payload[:maxSummaryLength]) + "..."
+		}
+		return string(payload)
+	}
+	b, err := json.Marshal(redact(v))
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func redact(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if isSecret(k) {
+				t[k] = redacted
+				continue
+			}
+			t[k] = redact(val)
+		}
+	case []interface{}:
+		for i, val := range t {
+			t[i] = redact(val)
+		}
+	}
+	return v
+}
+
+func isSecret(field string) bool {
+	field = strings.ToLower(field)
+	for _, s := range secretFields {
+		if strings.Contains(field, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyFingerprint identifies a service key without a description by a hash
+// so no part of the key is stored
+func keyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:])[:12]
+}
+
+// statusWriter records the response status while keeping streaming and
+// connection upgrades working
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	// hijacked streams report the upgrade status
+	w.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
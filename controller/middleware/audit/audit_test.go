@@ -0,0 +1,33 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSummarizeRedactsSecrets(t *testing.T) {
+	s := summarize([]byte(`{"username":"admin","password":"secret","engine":{"ssl_key":"pem","addr":"tcp://1.2.3.4"}}`))
+	if strings.Contains(s, "secret") || strings.Contains(s, "pem") {
+		t.Errorf("expected secrets to be redacted: %s", s)
+	}
+	if !strings.Contains(s, "admin") || !strings.Contains(s, "tcp://1.2.3.4") {
+		t.Errorf("expected non secret values to be kept: %s", s)
+	}
+}
+
+func TestSummarizeRaw(t *testing.T) {
+	if s := summarize([]byte("not json")); s != "not json" {
+		t.Errorf("expected raw payload; received %s", s)
+	}
+}
+
+func TestKeyFingerprint(t *testing.T) {
+	key := "0123456789abcdef"
+	f := keyFingerprint(key)
+	if !strings.HasPrefix(f, "sha256:") || strings.Contains(f, key[:8]) {
+		t.Errorf("expected a hash of the key; received %s", f)
+	}
+	if f != keyFingerprint(key) || f == keyFingerprint("fedcba9876543210") {
+		t.Errorf("expected a stable fingerprint per key; received %s", f)
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"strings"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/shipyard/shipyard"
 	"github.com/shipyard/shipyard/controller/manager"
+	"github.com/shipyard/shipyard/controller/middleware/access"
 )
 
 var (
@@ -45,7 +47,22 @@ func (a *AuthRequired) handleRequest(w http.ResponseWriter, r *http.Request) err
 	// service key takes priority
 	serviceKey := r.Header.Get("X-Service-Key")
 	if serviceKey != "" {
-		if err := a.manager.VerifyServiceKey(serviceKey); err == nil {
+		k, err := a.manager.VerifyServiceKey(serviceKey)
+		if err == nil {
+			// scoped keys are limited to their permissions
+			perm := access.RequiredPermission(r.Method, r.URL.Path)
+			if !access.AuthenticationOnly(r.Method, r.URL.Path) && !k.HasPermission(perm) {
+				http.Error(w, "access denied", http.StatusForbidden)
+				return fmt.Errorf("service key %s lacks %s", k.Description, perm)
+			}
+			// namespaced keys only act in their namespace
+			if k.Namespace != "" {
+				if ns := r.Header.Get(shipyard.NamespaceHeader); ns != "" && ns != k.Namespace {
+					http.Error(w, "access denied", http.StatusForbidden)
+					return fmt.Errorf("service key %s is confined to namespace %s", k.Description, k.Namespace)
+				}
+				r.Header.Set(shipyard.NamespaceHeader, k.Namespace)
+			}
 			valid = true
 		}
 	} else { // check for authHeader
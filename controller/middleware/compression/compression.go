@@ -0,0 +1,168 @@
+// Package compression gzips api responses for clients accepting it.
+package compression
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultMinSize is the smallest response body compressed; smaller
+	// bodies gain little and cost a gzip header
+	DefaultMinSize = 1024
+)
+
+// Compression gzips json and text responses of at least MinSize bytes.
+// Responses flushed before reaching MinSize, like event streams, and
+// hijacked connections are sent uncompressed.
+type Compression struct {
+	MinSize int
+	Level   int
+}
+
+func NewCompression() *Compression {
+	return &Compression{
+		MinSize: DefaultMinSize,
+		Level:   gzip.DefaultCompression,
+	}
+}
+
+func (c *Compression) HandlerFuncWithNext(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if next == nil {
+		return
+	}
+	if !acceptsGzip(r) || r.Method == "HEAD" || r.Header.Get("Upgrade") != "" {
+		next(w, r)
+		return
+	}
+	cw := &compressWriter{
+		ResponseWriter: w,
+		compression:    c,
+	}
+	next(cw, r)
+	cw.Close()
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, e := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		e = strings.TrimSpace(strings.SplitN(e, ";", 2)[0])
+		if e == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+func compressible(contentType string) bool {
+	return strings.HasPrefix(contentType, "application/json") || strings.HasPrefix(contentType, "text/")
+}
+
+// compressWriter buffers the start of the body until it knows whether the
+// response is worth compressing
+type compressWriter struct {
+	http.ResponseWriter
+	compression *Compression
+
+	status  int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		h := w.Header()
+		if h.Get("Content-Type") == "" {
+			h.Set("Content-Type", http.DetectContentType(b))
+		}
+		if h.Get("Content-Encoding") != "" || !compressible(h.Get("Content-Type")) {
+			w.decide(false)
+		} else {
+			w.buf = append(w.buf, b...)
+			if len(w.buf) >= w.compression.MinSize {
+				w.decide(true)
+			}
+			return len(b), nil
+		}
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// decide writes the header and the buffered body, compressed or not
+func (w *compressWriter) decide(compress bool) {
+	w.decided = true
+	if compress {
+		h := w.Header()
+		h.Set("Content-Encoding", "gzip")
+		h.Add("Vary", "Accept-Encoding")
+		h.Del("Content-Length")
+		gz, err := gzip.NewWriterLevel(w.ResponseWriter, w.compression.Level)
+		if err != nil {
+			gz = gzip.NewWriter(w.ResponseWriter)
+		}
+		w.gz = gz
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(w.buf) == 0 {
+		return
+	}
+	if w.gz != nil {
+		w.gz.Write(w.buf)
+	} else {
+		w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	w.decided = true
+	return h.Hijack()
+}
+
+// Close writes what is left of the response
+func (w *compressWriter) Close() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
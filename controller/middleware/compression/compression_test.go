@@ -0,0 +1,77 @@
+package compression
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serve(c *Compression, acceptEncoding string, h http.HandlerFunc) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest("GET", "/api/containers", nil)
+	if acceptEncoding != "" {
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	c.HandlerFuncWithNext(w, r, h)
+	return w
+}
+
+func TestCompressLargeJSON(t *testing.T) {
+	body := "[" + strings.Repeat(`{"id":"abc"},`, 200) + `{"id":"abc"}]`
+	w := serve(NewCompression(), "gzip, deflate", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		w.Write([]byte(body))
+	})
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a gzip response; received %v", w.Header())
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != body {
+		t.Errorf("expected the decompressed body to match")
+	}
+}
+
+func TestCompressSkipped(t *testing.T) {
+	large := strings.Repeat("a", 2*DefaultMinSize)
+	tests := []struct {
+		acceptEncoding string
+		contentType    string
+		body           string
+	}{
+		{"", "application/json", large},
+		{"gzip", "application/json", "{}"},
+		{"gzip", "application/x-tar", large},
+	}
+	for _, test := range tests {
+		w := serve(NewCompression(), test.acceptEncoding, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("content-type", test.contentType)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(test.body))
+		})
+		if w.Header().Get("Content-Encoding") != "" || w.Body.String() != test.body || w.Code != http.StatusCreated {
+			t.Errorf("expected an uncompressed %s response for %q; received %v", test.contentType, test.acceptEncoding, w.Header())
+		}
+	}
+}
+
+func TestFlushSendsUncompressed(t *testing.T) {
+	w := serve(NewCompression(), "gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		w.Write([]byte(`{"type":"start"}`))
+		w.(http.Flusher).Flush()
+		w.Write([]byte(strings.Repeat(" ", 2*DefaultMinSize)))
+	})
+	if w.Header().Get("Content-Encoding") != "" || !w.Flushed {
+		t.Errorf("expected a flushed uncompressed stream; received %v", w.Header())
+	}
+}
@@ -0,0 +1,159 @@
+// Package etag tags api responses so clients can revalidate what they
+// cached with If-None-Match instead of transferring it again.
+package etag
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultMaxSize is the largest response body tagged; larger bodies,
+	// like exports, are sent as they are written
+	DefaultMaxSize = 1 << 20
+)
+
+// ETag buffers successful GET responses of up to MaxSize bytes, tags them
+// with a hash of their body and answers 304 Not Modified when the request
+// already has the tag.  Flushed responses, like event streams, and
+// hijacked connections are sent untagged.
+type ETag struct {
+	MaxSize int
+}
+
+func NewETag() *ETag {
+	return &ETag{
+		MaxSize: DefaultMaxSize,
+	}
+}
+
+func (e *ETag) HandlerFuncWithNext(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if next == nil {
+		return
+	}
+	if (r.Method != "GET" && r.Method != "HEAD") || r.Header.Get("Upgrade") != "" {
+		next(w, r)
+		return
+	}
+	ew := &etagWriter{
+		ResponseWriter: w,
+		etag:           e,
+	}
+	next(ew, r)
+	ew.Close(r.Header.Get("If-None-Match"))
+}
+
+// Tag returns the entity tag of a body
+func Tag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Matches reports whether an If-None-Match header lists tag; weak tags
+// match their strong equivalent
+func Matches(ifNoneMatch string, tag string) bool {
+	for _, t := range strings.Split(ifNoneMatch, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// etagWriter buffers the response until the handler returns or it is too
+// large or flushed to tag
+type etagWriter struct {
+	http.ResponseWriter
+	etag *ETag
+
+	status  int
+	buf     []byte
+	passing bool
+}
+
+func (w *etagWriter) WriteHeader(code int) {
+	if w.passing {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *etagWriter) Write(b []byte) (int, error) {
+	if !w.passing {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		if len(w.buf)+len(b) <= w.etag.MaxSize {
+			w.buf = append(w.buf, b...)
+			return len(b), nil
+		}
+		w.pass()
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// pass writes the header and the buffered body and sends the rest of the
+// response as it is written
+func (w *etagWriter) pass() {
+	w.passing = true
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(w.buf) > 0 {
+		w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+}
+
+func (w *etagWriter) Flush() {
+	if !w.passing {
+		w.pass()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	w.passing = true
+	return h.Hijack()
+}
+
+// Close tags a buffered successful response and writes it, or only the
+// header when the client has it already
+func (w *etagWriter) Close(ifNoneMatch string) {
+	if w.passing {
+		return
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	h := w.Header()
+	if w.status != http.StatusOK || h.Get("ETag") != "" {
+		w.pass()
+		return
+	}
+	tag := Tag(w.buf)
+	h.Set("ETag", tag)
+	if ifNoneMatch != "" && Matches(ifNoneMatch, tag) {
+		h.Del("Content-Length")
+		h.Del("Content-Type")
+		w.passing = true
+		w.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.pass()
+}
@@ -0,0 +1,73 @@
+package etag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serve(e *ETag, method string, ifNoneMatch string, h http.HandlerFunc) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(method, "/api/engines", nil)
+	if ifNoneMatch != "" {
+		r.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+	e.HandlerFuncWithNext(w, r, h)
+	return w
+}
+
+func engines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+	w.Write([]byte(`[{"id":"local"}]`))
+}
+
+func TestETagNotModified(t *testing.T) {
+	w := serve(NewETag(), "GET", "", engines)
+	tag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || tag != Tag([]byte(`[{"id":"local"}]`)) || w.Body.String() != `[{"id":"local"}]` {
+		t.Fatalf("expected a tagged response; received %d %v", w.Code, w.Header())
+	}
+
+	w = serve(NewETag(), "GET", `"other", W/`+tag, engines)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != tag {
+		t.Errorf("expected 304 without a body; received %d %q", w.Code, w.Body.String())
+	}
+
+	w = serve(NewETag(), "GET", `"other"`, engines)
+	if w.Code != http.StatusOK || w.Body.String() != `[{"id":"local"}]` {
+		t.Errorf("expected the body for another tag; received %d", w.Code)
+	}
+}
+
+func TestETagSkipped(t *testing.T) {
+	tests := []struct {
+		method string
+		status int
+		body   string
+	}{
+		{"POST", http.StatusOK, "{}"},
+		{"GET", http.StatusNotFound, "not found"},
+		{"GET", http.StatusOK, strings.Repeat("a", DefaultMaxSize+1)},
+	}
+	for _, test := range tests {
+		w := serve(NewETag(), test.method, "*", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			w.Write([]byte(test.body))
+		})
+		if w.Header().Get("ETag") != "" || w.Code != test.status || w.Body.String() != test.body {
+			t.Errorf("expected an untagged %s %d response; received %d %v", test.method, test.status, w.Code, w.Header())
+		}
+	}
+}
+
+func TestFlushSendsUntagged(t *testing.T) {
+	w := serve(NewETag(), "GET", "", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: {}\n\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("data: {}\n\n"))
+	})
+	if w.Header().Get("ETag") != "" || !w.Flushed || w.Body.String() != "data: {}\n\ndata: {}\n\n" {
+		t.Errorf("expected a flushed untagged stream; received %v", w.Header())
+	}
+}
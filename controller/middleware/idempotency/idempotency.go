@@ -0,0 +1,183 @@
+// Package idempotency replays the response of a completed request when a
+// client retries it with the same Idempotency-Key header.
+package idempotency
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/manager"
+)
+
+const (
+	// DefaultWindow is how long responses are kept for replay
+	DefaultWindow = time.Hour
+	// maxBodySize is the largest response body kept; larger responses,
+	// like image build output, are not replayed and their key is
+	// forgotten
+	maxBodySize = 1 << 20
+)
+
+var logger = logrus.New()
+
+// Idempotency caches the responses of POST, PUT and PATCH requests sent
+// with an Idempotency-Key header.  Keys are scoped to the account or
+// service key of the request.  A retry of a completed request gets the
+// cached response, a retry of a request in progress 409 Conflict and a key
+// reused for another request 422 Unprocessable Entity.  Server errors are
+// not cached so the request can be retried.  Responses are kept in the
+// datastore, shared by the controllers, for the window; see
+// manager.ClaimIdempotencyKey.
+type Idempotency struct {
+	manager *manager.Manager
+	window  time.Duration
+}
+
+func NewIdempotency(m *manager.Manager, window time.Duration) *Idempotency {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Idempotency{
+		manager: m,
+		window:  window,
+	}
+}
+
+// scope returns the id of the key scoped to the caller of the request.
+// It is hashed so service keys are not stored.
+func scope(r *http.Request, key string) string {
+	var s string
+	if k := r.Header.Get("X-Service-Key"); k != "" {
+		s = "key:" + k + ":" + key
+	} else {
+		username := strings.SplitN(r.Header.Get("X-Access-Token"), ":", 2)[0]
+		s = "account:" + username + ":" + key
+	}
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func (i *Idempotency) HandlerFuncWithNext(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if next == nil {
+		return
+	}
+	key := r.Header.Get(shipyard.IdempotencyKeyHeader)
+	if key == "" || (r.Method != "POST" && r.Method != "PUT" && r.Method != "PATCH") {
+		next(w, r)
+		return
+	}
+	id := scope(r, key)
+	request := r.Method + " " + r.URL.RequestURI()
+
+	held, err := i.manager.ClaimIdempotencyKey(id, request, i.window)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if held != nil {
+		switch {
+		case held.Request != request:
+			http.Error(w, "idempotency key was used for another request", http.StatusUnprocessableEntity)
+		case !held.Done:
+			http.Error(w, "a request with this idempotency key is in progress", http.StatusConflict)
+		default:
+			replay(w, held)
+		}
+		return
+	}
+
+	rw := &recordWriter{ResponseWriter: w, status: http.StatusOK}
+	next(rw, r)
+
+	if rw.status >= 500 || rw.hijacked || rw.overflow {
+		if err := i.manager.ReleaseIdempotencyKey(id); err != nil {
+			logger.Warnf("error releasing idempotency key: %s", err)
+		}
+		return
+	}
+	header := w.Header().Clone()
+	// the body is kept as handled, before any compression
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+	done := &shipyard.IdempotentRequest{
+		ID:      id,
+		Request: request,
+		Done:    true,
+		Expires: time.Now().Add(i.window),
+		Status:  rw.status,
+		Header:  header,
+		Body:    rw.body,
+	}
+	if err := i.manager.SaveIdempotentRequest(done); err != nil {
+		logger.Warnf("error saving idempotent request: %s", err)
+		if err := i.manager.ReleaseIdempotencyKey(id); err != nil {
+			logger.Warnf("error releasing idempotency key: %s", err)
+		}
+	}
+}
+
+func replay(w http.ResponseWriter, req *shipyard.IdempotentRequest) {
+	// headers of this request, like its traceparent, are kept
+	for k, v := range req.Header {
+		if _, ok := w.Header()[k]; !ok {
+			w.Header()[k] = v
+		}
+	}
+	w.Header().Set(shipyard.IdempotentReplayedHeader, "true")
+	w.WriteHeader(req.Status)
+	w.Write(req.Body)
+}
+
+// recordWriter keeps a copy of the response
+type recordWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        []byte
+	overflow    bool
+	hijacked    bool
+}
+
+func (w *recordWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	if !w.overflow {
+		if len(w.body)+len(b) > maxBodySize {
+			w.overflow = true
+			w.body = nil
+		} else {
+			w.body = append(w.body, b...)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *recordWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	w.hijacked = true
+	return h.Hijack()
+}
@@ -0,0 +1,114 @@
+package idempotency
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/datastore"
+	"github.com/shipyard/shipyard/controller/manager"
+)
+
+func newTestIdempotency(t *testing.T) *Idempotency {
+	dir, err := ioutil.TempDir("", "idempotency")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := datastore.NewEmbeddedStore(filepath.Join(dir, "shipyard.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.RemoveAll(dir)
+	})
+	m, err := manager.NewManager(db, "test", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewIdempotency(m, 0)
+}
+
+func send(i *Idempotency, method string, path string, key string, next http.HandlerFunc) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest(method, path, nil)
+	r.Header.Set("X-Access-Token", "admin:token")
+	r.Header.Set(shipyard.IdempotencyKeyHeader, key)
+	w := httptest.NewRecorder()
+	i.HandlerFuncWithNext(w, r, next)
+	return w
+}
+
+func TestReplay(t *testing.T) {
+	i := newTestIdempotency(t)
+	runs := 0
+	run := func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`["` + strconv.Itoa(runs) + `"]`))
+	}
+	first := send(i, "POST", "/api/containers?count=1", "abc", run)
+	retry := send(i, "POST", "/api/containers?count=1", "abc", run)
+	if runs != 1 {
+		t.Fatalf("expected the request to run once; ran %d times", runs)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() || retry.Header().Get(shipyard.IdempotentReplayedHeader) != "true" {
+		t.Errorf("expected the first response to be replayed; received %d %s", retry.Code, retry.Body)
+	}
+
+	if w := send(i, "POST", "/api/containers?count=2", "abc", run); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected a reused key to be rejected; received %d", w.Code)
+	}
+	if send(i, "POST", "/api/containers?count=1", "def", run); runs != 2 {
+		t.Errorf("expected a new key to run the request")
+	}
+}
+
+func TestServerErrorsNotCached(t *testing.T) {
+	i := newTestIdempotency(t)
+	runs := 0
+	fail := func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		http.Error(w, "engine unavailable", http.StatusInternalServerError)
+	}
+	send(i, "POST", "/api/containers", "abc", fail)
+	send(i, "POST", "/api/containers", "abc", fail)
+	if runs != 2 {
+		t.Errorf("expected failed requests to run again; ran %d times", runs)
+	}
+}
+
+func TestInProgress(t *testing.T) {
+	i := newTestIdempotency(t)
+	var retry *httptest.ResponseRecorder
+	send(i, "POST", "/api/containers", "abc", func(w http.ResponseWriter, r *http.Request) {
+		retry = send(i, "POST", "/api/containers", "abc", func(w http.ResponseWriter, r *http.Request) {
+			t.Error("expected the retry not to run")
+		})
+	})
+	if retry.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a request in progress; received %d", retry.Code)
+	}
+}
+
+func TestKeysScopedToCaller(t *testing.T) {
+	i := newTestIdempotency(t)
+	runs := 0
+	run := func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		w.WriteHeader(http.StatusCreated)
+	}
+	send(i, "POST", "/api/containers", "abc", run)
+	r, _ := http.NewRequest("POST", "/api/containers", nil)
+	r.Header.Set("X-Service-Key", "servicekey")
+	r.Header.Set(shipyard.IdempotencyKeyHeader, "abc")
+	i.HandlerFuncWithNext(httptest.NewRecorder(), r, run)
+	if runs != 2 {
+		t.Errorf("expected the key of another caller to run the request; ran %d times", runs)
+	}
+}
@@ -0,0 +1,149 @@
+// Package ratelimit rejects api requests above the configured rates with
+// 429 Too Many Requests.
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// sweepInterval is how often idle buckets are dropped
+	sweepInterval = time.Minute
+)
+
+// Limits are request rates in requests per second; zero is unlimited
+type Limits struct {
+	// Global limits all requests together
+	Global float64
+	// Account limits the requests of each account
+	Account float64
+	// ServiceKey limits the requests of each service key
+	ServiceKey float64
+	// Burst is the number of requests allowed at once above the rate;
+	// zero allows one second worth of requests
+	Burst int
+}
+
+// Enabled reports whether any limit is set
+func (l Limits) Enabled() bool {
+	return l.Global > 0 || l.Account > 0 || l.ServiceKey > 0
+}
+
+// bucket is a token bucket refilled at the limit rate
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take removes a token and returns zero, or returns how long until a
+// token is available
+func (b *bucket) take(now time.Time, rate float64, burst float64) time.Duration {
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+type RateLimit struct {
+	limits Limits
+	now    func() time.Time
+
+	mu        sync.Mutex
+	global    *bucket
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewRateLimit(limits Limits) *RateLimit {
+	return &RateLimit{
+		limits:  limits,
+		now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+}
+
+func (l *RateLimit) burst(rate float64) float64 {
+	if l.limits.Burst > 0 {
+		return float64(l.limits.Burst)
+	}
+	return math.Max(1, rate)
+}
+
+// limit takes a token from the bucket of key and returns how long to wait
+// when it is empty
+func (l *RateLimit) limit(now time.Time, key string, rate float64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	b := l.global
+	if key != "" {
+		b = l.buckets[key]
+	}
+	if b == nil {
+		b = &bucket{tokens: l.burst(rate), last: now}
+		if key == "" {
+			l.global = b
+		} else {
+			l.buckets[key] = b
+		}
+	}
+	return b.take(now, rate, l.burst(rate))
+}
+
+// sweep drops the buckets refilled since their last request; they hold
+// no state a new bucket would not
+func (l *RateLimit) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		rate := l.limits.Account
+		if strings.HasPrefix(key, "key:") {
+			rate = l.limits.ServiceKey
+		}
+		if b.tokens+now.Sub(b.last).Seconds()*rate >= l.burst(rate) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// Allow reports whether the request is within the limits and otherwise
+// how long until it would be
+func (l *RateLimit) Allow(r *http.Request) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.lastSweep) > sweepInterval {
+		l.sweep(now)
+	}
+	// a rejected request does not count towards the global limit so a
+	// runaway client can not starve the others
+	wait := time.Duration(0)
+	if key := r.Header.Get("X-Service-Key"); key != "" {
+		wait = l.limit(now, "key:"+key, l.limits.ServiceKey)
+	} else if token := r.Header.Get("X-Access-Token"); token != "" {
+		username := strings.SplitN(token, ":", 2)[0]
+		wait = l.limit(now, "account:"+username, l.limits.Account)
+	}
+	if wait == 0 {
+		wait = l.limit(now, "", l.limits.Global)
+	}
+	return wait == 0, wait
+}
+
+func (l *RateLimit) HandlerFuncWithNext(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if ok, wait := l.Allow(r); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+	if next != nil {
+		next(w, r)
+	}
+}
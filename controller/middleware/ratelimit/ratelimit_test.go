@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func request(header string, value string) *http.Request {
+	r, _ := http.NewRequest("GET", "/api/containers", nil)
+	r.Header.Set(header, value)
+	return r
+}
+
+func TestAccountLimit(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewRateLimit(Limits{Account: 2})
+	l.now = func() time.Time { return now }
+
+	admin := request("X-Access-Token", "admin:token")
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow(admin); !ok {
+			t.Fatalf("expected request %d to be allowed", i)
+		}
+	}
+	ok, wait := l.Allow(admin)
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("expected to wait 500ms; received %v %s", ok, wait)
+	}
+	if ok, _ := l.Allow(request("X-Access-Token", "user:token")); !ok {
+		t.Errorf("expected other accounts to be allowed")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.Allow(admin); !ok {
+		t.Errorf("expected the bucket to refill")
+	}
+}
+
+func TestGlobalLimit(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewRateLimit(Limits{Global: 1, ServiceKey: 10})
+	l.now = func() time.Time { return now }
+
+	if ok, _ := l.Allow(request("X-Service-Key", "a")); !ok {
+		t.Fatal("expected the first request to be allowed")
+	}
+	if ok, _ := l.Allow(request("X-Service-Key", "b")); ok {
+		t.Error("expected the global limit to reject the second request")
+	}
+}
+
+func TestHandlerRetryAfter(t *testing.T) {
+	l := NewRateLimit(Limits{ServiceKey: 0.5})
+	r := request("X-Service-Key", "key")
+	l.HandlerFuncWithNext(httptest.NewRecorder(), r, nil)
+
+	w := httptest.NewRecorder()
+	called := false
+	l.HandlerFuncWithNext(w, r, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+	if called || w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+		t.Errorf("expected 429 with Retry-After 2; received %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/shipyard/shipyard"
+)
+
+// Tracing records a server span for every request.  The span continues the
+// trace of the traceparent header of the request and its context is
+// returned in the traceparent header of the response.
+type Tracing struct {
+	tracer *shipyard.Tracer
+}
+
+func NewTracing(t *shipyard.Tracer) *Tracing {
+	return &Tracing{
+		tracer: t,
+	}
+}
+
+func (t *Tracing) HandlerFuncWithNext(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if next == nil {
+		return
+	}
+	ctx := r.Context()
+	if parent, err := shipyard.ParseTraceparent(r.Header.Get(shipyard.TraceparentHeader)); err == nil {
+		ctx = shipyard.ContextWithSpan(ctx, parent)
+	}
+	ctx, span := t.tracer.StartSpan(ctx, r.Method+" "+route(r.URL.Path), shipyard.SpanKindServer)
+	span.SetAttribute("http.method", r.Method)
+	span.SetAttribute("http.target", r.URL.Path)
+	w.Header().Set(shipyard.TraceparentHeader, span.SpanContext.Traceparent())
+
+	rw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	next(rw, r.WithContext(ctx))
+
+	span.SetAttribute("http.status_code", strconv.Itoa(rw.status))
+	if rw.status >= 500 {
+		span.Error = http.StatusText(rw.status)
+	}
+	span.Finish()
+}
+
+// route returns a low cardinality span name for a request path: the api
+// resource (/api/containers/<id>/logs is "/api/containers") or the first
+// path element
+func route(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if parts[0] == "api" && len(parts) > 1 {
+		return "/api/" + parts[1]
+	}
+	return "/" + parts[0]
+}
+
+// statusWriter records the response status
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	w.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shipyard/shipyard"
+)
+
+func TestRoute(t *testing.T) {
+	tests := map[string]string{
+		"/api/containers/abc/logs": "/api/containers",
+		"/api/events":              "/api/events",
+		"/auth/login":              "/auth",
+		"/":                        "/",
+	}
+	for path, expected := range tests {
+		if r := route(path); r != expected {
+			t.Errorf("expected %s for %s; received %s", expected, path, r)
+		}
+	}
+}
+
+func TestHandlerContinuesTrace(t *testing.T) {
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	r, _ := http.NewRequest("GET", "/api/containers", nil)
+	r.Header.Set(shipyard.TraceparentHeader, parent)
+	w := httptest.NewRecorder()
+
+	var sc shipyard.SpanContext
+	NewTracing(nil).HandlerFuncWithNext(w, r, func(w http.ResponseWriter, r *http.Request) {
+		sc, _ = shipyard.SpanFromContext(r.Context())
+	})
+	if sc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID == "00f067aa0ba902b7" {
+		t.Errorf("expected a child span of the request trace; received %+v", sc)
+	}
+	if h := w.Header().Get(shipyard.TraceparentHeader); h != sc.Traceparent() {
+		t.Errorf("expected the span traceparent in the response; received %s", h)
+	}
+}
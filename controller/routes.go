@@ -0,0 +1,231 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/middleware/access"
+)
+
+// route is an endpoint of the controller.  The route tables below are
+// registered on the routers in main and documented by /api/spec.
+type route struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+}
+
+// apiRoutes require authentication and the permission of the route
+var apiRoutes = []route{
+	{"GET", "/api/accounts", accounts},
+	{"POST", "/api/accounts", addAccount},
+	{"PUT", "/api/accounts", updateAccount},
+	{"DELETE", "/api/accounts", deleteAccount},
+	{"PUT", "/api/accounts/{username}/role", setAccountRole},
+	{"PUT", "/api/accounts/{username}/namespaces/{namespace}", setAccountNamespaceRole},
+	{"GET", "/api/roles", roles},
+	{"GET", "/api/roles/{name}", role},
+	{"POST", "/api/roles", addRole},
+	{"PUT", "/api/roles", updateRole},
+	{"DELETE", "/api/roles", deleteRole},
+	{"GET", "/api/ping", ping},
+	{"GET", "/api/cluster/info", clusterInfo},
+	{"GET", "/api/cluster/usage", clusterUsage},
+	{"POST", "/api/cluster/rebalance", rebalance},
+	{"GET", "/api/containers", containers},
+	{"POST", "/api/containers", run},
+	{"POST", "/api/containers/scale", scaleImage},
+	{"POST", "/api/containers/batch", batchContainers},
+	{"GET", "/api/containers/endpoints", endpoints},
+	{"POST", "/api/containers/groups/{action}", containerGroup},
+	{"GET", "/api/containers/{id}", inspectContainer},
+	{"DELETE", "/api/containers/{id}", destroy},
+	{"PATCH", "/api/containers/{id}", updateContainer},
+	{"POST", "/api/containers/{id}/rename", renameContainer},
+	{"POST", "/api/containers/{id}/commit", commitContainer},
+	{"GET", "/api/containers/{id}/archive", copyFromContainer},
+	{"GET", "/api/containers/{id}/top", containerTop},
+	{"GET", "/api/containers/{id}/attach", attachContainer},
+	{"GET", "/api/containers/{id}/wait", waitContainer},
+	{"GET", "/api/containers/{id}/changes", containerChanges},
+	{"GET", "/api/containers/{id}/export", exportContainer},
+	{"PUT", "/api/containers/{id}/archive", copyToContainer},
+	{"GET", "/api/containers/{id}/start", startContainer},
+	{"GET", "/api/containers/{id}/stop", stopContainer},
+	{"GET", "/api/containers/{id}/restart", restartContainer},
+	{"GET", "/api/containers/{id}/pause", pauseContainer},
+	{"GET", "/api/containers/{id}/unpause", unpauseContainer},
+	{"GET", "/api/containers/{id}/scale", scaleContainer},
+	{"GET", "/api/containers/{id}/logs", containerLogs},
+	{"GET", "/api/containers/{id}/stats", containerStats},
+	{"GET", "/api/containers/{id}/metrics", containerMetrics},
+	{"POST", "/api/containers/{id}/exec", createExec},
+	{"GET", "/api/containers/{id}/exec/{execId}", inspectExec},
+	{"POST", "/api/containers/{id}/exec/{execId}/start", startExec},
+	{"GET", "/api/images", images},
+	{"POST", "/api/images/pull", pullImage},
+	{"POST", "/api/images/build", buildImage},
+	{"DELETE", "/api/images/{name:.*}", removeImage},
+	{"GET", "/api/registries", registries},
+	{"POST", "/api/registries", addRegistry},
+	{"DELETE", "/api/registries/{name}", removeRegistry},
+	{"GET", "/api/events", events},
+	{"DELETE", "/api/events", purgeEvents},
+	{"GET", "/api/audit", auditLog},
+	{"GET", "/api/events/stream", streamEvents},
+	{"GET", "/api/events/retention", eventRetention},
+	{"PUT", "/api/events/retention", setEventRetention},
+	{"POST", "/api/events/retention/run", runEventRetention},
+	{"GET", "/api/engines", engines},
+	{"POST", "/api/engines", addEngine},
+	{"GET", "/api/engines/join-tokens", joinTokens},
+	{"POST", "/api/engines/join-tokens", createJoinToken},
+	{"DELETE", "/api/engines/join-tokens/{id}", deleteJoinToken},
+	{"GET", "/api/engines/{id}", inspectEngine},
+	{"PUT", "/api/engines/{id}", updateEngine},
+	{"DELETE", "/api/engines/{id}", removeEngine},
+	{"GET", "/api/engines/{id}/health", engineHealth},
+	{"GET", "/api/engines/{id}/metrics", engineMetrics},
+	{"PUT", "/api/engines/{id}/labels", updateEngineLabels},
+	{"GET", "/api/engines/{id}/certificates", engineCertificates},
+	{"PUT", "/api/engines/{id}/certificates", setEngineCertificates},
+	{"GET", "/api/engines/{id}/cordon", cordonEngine},
+	{"GET", "/api/engines/{id}/uncordon", uncordonEngine},
+	{"GET", "/api/engines/{id}/drain", drainEngine},
+	{"GET", "/api/extensions", extensions},
+	{"GET", "/api/extensions/{id}", extension},
+	{"POST", "/api/extensions", addExtension},
+	{"DELETE", "/api/extensions/{id}", deleteExtension},
+	{"GET", "/api/servicekeys", serviceKeys},
+	{"POST", "/api/servicekeys", addServiceKey},
+	{"DELETE", "/api/servicekeys", removeServiceKey},
+	{"GET", "/api/webhookkeys", webhookKeys},
+	{"GET", "/api/webhookkeys/{id}", webhookKey},
+	{"POST", "/api/webhookkeys", addWebhookKey},
+	{"DELETE", "/api/webhookkeys/{id}", deleteWebhookKey},
+	{"GET", "/api/webhooks", webhooks},
+	{"POST", "/api/webhooks", addWebhook},
+	{"DELETE", "/api/webhooks/{id}", removeWebhook},
+	{"GET", "/api/notifiers", notifiers},
+	{"POST", "/api/notifiers", addNotifier},
+	{"DELETE", "/api/notifiers/{id}", removeNotifier},
+	{"GET", "/api/alerts", alerts},
+	{"GET", "/api/alerts/rules", alertRules},
+	{"POST", "/api/alerts/rules", addAlertRule},
+	{"DELETE", "/api/alerts/rules/{id}", removeAlertRule},
+	{"GET", "/api/applications", applications},
+	{"POST", "/api/applications", createApplication},
+	{"POST", "/api/applications/compose", deployCompose},
+	{"GET", "/api/applications/{name}", application},
+	{"PUT", "/api/applications/{name}", updateApplication},
+	{"DELETE", "/api/applications/{name}", removeApplication},
+	{"POST", "/api/applications/{name}/diff", diffApplication},
+	{"GET", "/api/applications/{name}/containers", applicationContainers},
+	{"POST", "/api/applications/{name}/deploy", deployApplication},
+	{"POST", "/api/applications/{name}/rollback", rollbackApplication},
+	{"GET", "/api/deployments", deployments},
+	{"GET", "/api/deployments/{id}", deployment},
+	{"POST", "/api/deployments/{id}/promote", promoteDeployment},
+	{"GET", "/api/jobs", jobs},
+	{"POST", "/api/jobs", createJob},
+	{"GET", "/api/jobs/{id}", job},
+	{"DELETE", "/api/jobs/{id}", removeJob},
+	{"GET", "/api/jobs/{id}/runs", jobRuns},
+	{"GET", "/api/secrets", secrets},
+	{"POST", "/api/secrets", createSecret},
+	{"DELETE", "/api/secrets/{name}", deleteSecret},
+	{"GET", "/api/secrets/{name}/value", secretValue},
+	{"GET", "/api/configs", configs},
+	{"POST", "/api/configs", createConfig},
+	{"GET", "/api/configs/{name}", config},
+	{"PUT", "/api/configs/{name}", updateConfig},
+	{"DELETE", "/api/configs/{name}", deleteConfig},
+	{"GET", "/api/templates", templates},
+	{"POST", "/api/templates", createTemplate},
+	{"GET", "/api/templates/{name}", template},
+	{"PUT", "/api/templates/{name}", updateTemplate},
+	{"DELETE", "/api/templates/{name}", deleteTemplate},
+	{"POST", "/api/templates/{name}/run", runTemplate},
+	{"GET", "/api/volumes", volumes},
+	{"POST", "/api/volumes", createVolume},
+	{"DELETE", "/api/volumes/{name}", removeVolume},
+	{"GET", "/api/quotas", quotas},
+	{"POST", "/api/quotas", createQuota},
+	{"DELETE", "/api/quotas/{name}", deleteQuota},
+	{"GET", "/api/namespaces", namespaces},
+	{"POST", "/api/namespaces", createNamespace},
+	{"DELETE", "/api/namespaces/{name}", deleteNamespace},
+	{"GET", "/api/pools", enginePools},
+	{"POST", "/api/pools", createEnginePool},
+	{"GET", "/api/pools/{name}", enginePool},
+	{"PUT", "/api/pools/{name}", updateEnginePool},
+	{"DELETE", "/api/pools/{name}", deleteEnginePool},
+	{"GET", "/api/gc/policy", gcPolicy},
+	{"PUT", "/api/gc/policy", setGCPolicy},
+	{"POST", "/api/gc/run", runGC},
+	{"GET", "/api/backup", backup},
+	{"POST", "/api/backup/restore", restoreBackup},
+}
+
+// accountRoutes act on the authenticated account
+var accountRoutes = []route{
+	{"POST", "/account/changepassword", changePassword},
+	{"POST", "/account/token", refreshToken},
+	{"POST", "/account/logout", logout},
+	{"POST", "/account/2fa", enable2FA},
+	{"POST", "/account/2fa/confirm", confirm2FA},
+	{"DELETE", "/account/2fa", disable2FA},
+}
+
+// publicRoutes need no authentication
+var publicRoutes = []route{
+	{"POST", "/auth/login", login},
+	{"GET", "/api/version", versionInfo},
+	{"GET", "/api/spec", openAPISpec},
+	{"POST", "/hub/webhook/{id}", hubWebhook},
+	{"POST", "/join", joinEngine},
+}
+
+// apiSpec documents the route tables; it is built in main since the spec
+// handler can not refer to the tables it is part of
+var apiSpec *shipyard.OpenAPISpec
+
+// name returns the name of the route handler
+func (r route) name() string {
+	name := runtime.FuncForPC(reflect.ValueOf(r.handler).Pointer()).Name()
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// handleRoutes registers routes named after their path template
+func handleRoutes(router *mux.Router, routes []route) {
+	for _, r := range routes {
+		router.HandleFunc(r.path, r.handler).Methods(r.method).Name(r.path)
+	}
+}
+
+// newAPISpec documents the route tables
+func newAPISpec() *shipyard.OpenAPISpec {
+	routes := []*shipyard.APIRoute{}
+	add := func(rs []route, permission bool, public bool) {
+		for _, r := range rs {
+			ar := &shipyard.APIRoute{
+				Method: r.method,
+				Path:   r.path,
+				Name:   r.name(),
+				Public: public,
+			}
+			if permission {
+				ar.Permission = access.RequiredPermission(r.method, r.path)
+			}
+			routes = append(routes, ar)
+		}
+	}
+	add(apiRoutes, true, false)
+	add(accountRoutes, false, false)
+	add(publicRoutes, false, true)
+	return shipyard.NewOpenAPISpec(VERSION, routes)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestRoutesGenerated checks the client path helpers were generated from
+// the current route tables; run go generate in the client if it fails
+func TestRoutesGenerated(t *testing.T) {
+	b, err := ioutil.ReadFile("../client/routes_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := string(b)
+	ops := newAPISpec().Operations()
+	for _, r := range ops {
+		doc := fmt.Sprintf("// %sPath is the path of %s %s\n", r.Name, r.Method, r.Path)
+		if !strings.Contains(src, doc) {
+			t.Errorf("no client path for %s %s (%s)", r.Method, r.Path, r.Name)
+		}
+	}
+	if n := strings.Count(src, "Path is the path of "); n != len(ops) {
+		t.Errorf("expected %d client paths; routes_gen.go has %d", len(ops), n)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/controller/ldap"
+	"github.com/shipyard/shipyard/websocket"
+)
+
+// groupRolesFlag collects repeated <group-dn>=<role> flags.  The role is
+// split at the last "=" since group dns contain "=".
+type groupRolesFlag []ldap.GroupRole
+
+func (f *groupRolesFlag) String() string {
+	s := []string{}
+	for _, gr := range *f {
+		s = append(s, gr.Group+"="+gr.Role)
+	}
+	return strings.Join(s, ",")
+}
+
+func (f *groupRolesFlag) Set(v string) error {
+	i := strings.LastIndex(v, "=")
+	if i <= 0 || i == len(v)-1 {
+		return fmt.Errorf("expected <group-dn>=<role>: %s", v)
+	}
+	*f = append(*f, ldap.GroupRole{Group: v[:i], Role: v[i+1:]})
+	return nil
+}
+
+// flushWriter flushes after every write so streamed responses reach the
+// client as they are produced
+type flushWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+func newFlushWriter(w http.ResponseWriter) *flushWriter {
+	fw := &flushWriter{w: w}
+	if f, ok := w.(http.Flusher); ok {
+		fw.flusher = f
+	}
+	return fw
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if fw.flusher != nil {
+		fw.flusher.Flush()
+	}
+	return n, err
+}
+
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// proxyRawStream completes an upgrade handshake with the client, takes over
+// its connection and pipes it to upstream in both directions until upstream
+// closes its output
+func proxyRawStream(w http.ResponseWriter, upstream net.Conn, upstreamReader io.Reader) error {
+	defer upstream.Close()
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return errors.New("connection does not support hijacking")
+	}
+	conn, buf, err := hj.Hijack()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+	go func() {
+		io.Copy(upstream, buf)
+		if cw, ok := upstream.(closeWriter); ok {
+			cw.CloseWrite()
+		}
+	}()
+	_, err = io.Copy(conn, upstreamReader)
+	return err
+}
+
+// proxyWebSocket pipes a websocket to upstream in both directions until
+// upstream closes its output
+func proxyWebSocket(ws *websocket.Conn, upstream net.Conn, upstreamReader io.Reader) error {
+	defer upstream.Close()
+	defer ws.Close()
+	go func() {
+		io.Copy(upstream, ws)
+		if cw, ok := upstream.(closeWriter); ok {
+			cw.CloseWrite()
+		}
+	}()
+	_, err := io.Copy(ws, upstreamReader)
+	return err
+}
+
+// listenAndServe serves handler on a tcp address or a unix:// socket.  A
+// stale socket file left by a previous controller is removed.
+func listenAndServe(addr string, handler http.Handler) error {
+	path, ok := shipyard.UnixSocketPath(addr)
+	if !ok {
+		return http.ListenAndServe(addr, handler)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return http.Serve(l, handler)
+}
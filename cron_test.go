@@ -0,0 +1,42 @@
+package shipyard
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	from := time.Date(2015, time.March, 14, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2015, time.March, 14, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2015, time.March, 14, 10, 15, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2015, time.March, 15, 3, 0, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2015, time.March, 16, 9, 30, 0, 0, time.UTC)},
+		{"0 0 1 */2 *", time.Date(2015, time.May, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2015, time.March, 15, 12, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2015, time.March, 20, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2015, time.March, 14, 11, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, test := range tests {
+		s, err := ParseCronSchedule(test.spec)
+		if err != nil {
+			t.Fatalf("%s: %s", test.spec, err)
+		}
+		if next := s.Next(from); !next.Equal(test.next) {
+			t.Errorf("%s: expected %s; received %s", test.spec, test.next, next)
+		}
+	}
+}
+
+func TestParseCronScheduleInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "@fortnightly"} {
+		if _, err := ParseCronSchedule(spec); err == nil {
+			t.Errorf("expected %q to be rejected", spec)
+		}
+	}
+}
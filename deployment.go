@@ -0,0 +1,231 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	DeploymentRunning    = "running"
+	DeploymentSucceeded  = "succeeded"
+	DeploymentRolledBack = "rolled-back"
+	// DeploymentBaking is reported while the canary containers of a canary
+	// deployment bake before the rest are updated
+	DeploymentBaking = "baking"
+	// DeploymentFailed is reported when a rollback could not restore the
+	// previous containers
+	DeploymentFailed = "failed"
+
+	// DeployRolling replaces containers in batches
+	DeployRolling = "rolling"
+	// DeployBlueGreen starts a full set of new containers, switches
+	// traffic to them and then retires the old set
+	DeployBlueGreen = "blue-green"
+	// DeployCanary updates a share of the containers first and holds them
+	// for a bake period, or until the deployment is promoted, before
+	// updating the rest in batches
+	DeployCanary = "canary"
+
+	DefaultDeployBatchSize = 1
+	DefaultDeployMonitor   = 10
+	DefaultCanaryPercent   = 10
+)
+
+var (
+	ErrInvalidDeployOptions = errors.New("invalid deploy options")
+	ErrInvalidRevision      = errors.New("invalid revision")
+)
+
+// DeployOptions controls how an application is updated
+type DeployOptions struct {
+	// Strategy is DeployRolling (the default) or DeployBlueGreen
+	Strategy string `json:"strategy,omitempty" gorethink:"strategy"`
+	// BatchSize is the number of containers replaced at a time
+	BatchSize int `json:"batch_size,omitempty" gorethink:"batch_size"`
+	// Delay is the number of seconds to wait between batches
+	Delay int `json:"delay,omitempty" gorethink:"delay"`
+	// Monitor is the number of seconds new containers must keep running
+	// before the old containers of a batch are stopped
+	Monitor int `json:"monitor,omitempty" gorethink:"monitor"`
+	// SwitchURL receives a DeploySwitch POST once the new set of a
+	// blue-green deployment is healthy; the old set is retired when it
+	// responds with a 2xx status.  Without it, switching traffic is left to
+	// load balancers following the application image.
+	SwitchURL string `json:"switch_url,omitempty" gorethink:"switch_url"`
+	// CanaryPercent is the share of containers a canary deployment updates
+	// first; at least one container is updated
+	CanaryPercent int `json:"canary_percent,omitempty" gorethink:"canary_percent"`
+	// BakePeriod is the number of seconds the canary containers run before
+	// the rest are updated; with 0 the deployment waits to be promoted and
+	// is rolled back if it is not promoted within a day
+	BakePeriod int `json:"bake_period,omitempty" gorethink:"bake_period"`
+}
+
+// Validate checks the options and fills in defaults
+func (o *DeployOptions) Validate() error {
+	if o.BatchSize < 0 || o.Delay < 0 || o.Monitor < 0 || o.BakePeriod < 0 {
+		return fmt.Errorf("%w: values must not be negative", ErrInvalidDeployOptions)
+	}
+	switch o.Strategy {
+	case "":
+		o.Strategy = DeployRolling
+	case DeployRolling, DeployBlueGreen, DeployCanary:
+	default:
+		return fmt.Errorf("%w: unknown strategy %q", ErrInvalidDeployOptions, o.Strategy)
+	}
+	if o.SwitchURL != "" {
+		if o.Strategy != DeployBlueGreen {
+			return fmt.Errorf("%w: a switch url is only used by %s deployments", ErrInvalidDeployOptions, DeployBlueGreen)
+		}
+		u, err := url.Parse(o.SwitchURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: switch url must be an absolute http or https url", ErrInvalidDeployOptions)
+		}
+	}
+	if o.Strategy == DeployCanary {
+		if o.CanaryPercent < 0 || o.CanaryPercent > 100 {
+			return fmt.Errorf("%w: canary percent must be between 1 and 100", ErrInvalidDeployOptions)
+		}
+		if o.CanaryPercent == 0 {
+			o.CanaryPercent = DefaultCanaryPercent
+		}
+	} else if o.CanaryPercent != 0 || o.BakePeriod != 0 {
+		return fmt.Errorf("%w: a canary percent and bake period are only used by %s deployments", ErrInvalidDeployOptions, DeployCanary)
+	}
+	if o.BatchSize == 0 {
+		o.BatchSize = DefaultDeployBatchSize
+	}
+	if o.Monitor == 0 {
+		o.Monitor = DefaultDeployMonitor
+	}
+	return nil
+}
+
+// CanaryCount returns the number of canary containers of a deployment of
+// total containers
+func (o *DeployOptions) CanaryCount(total int) int {
+	n := (total*o.CanaryPercent + 99) / 100
+	if n < 1 {
+		n = 1
+	}
+	if n > total {
+		n = total
+	}
+	return n
+}
+
+// Surge returns the most containers a deployment of total containers runs
+// beyond total at a time; they count against quotas while it runs
+func (o *DeployOptions) Surge(total int) int {
+	n := o.BatchSize
+	switch o.Strategy {
+	case DeployBlueGreen:
+		n = total
+	case DeployCanary:
+		if c := o.CanaryCount(total); c > n {
+			n = c
+		}
+	}
+	if n > total {
+		n = total
+	}
+	return n
+}
+
+// DeployRequest asks for an application to be updated to an image
+type DeployRequest struct {
+	Image   string        `json:"image,omitempty"`
+	Options DeployOptions `json:"options"`
+}
+
+// Deployment is the progress of updating an application to a new image
+type Deployment struct {
+	ID            string        `json:"id,omitempty" gorethink:"id,omitempty"`
+	Application   string        `json:"application,omitempty" gorethink:"application"`
+	Image         string        `json:"image,omitempty" gorethink:"image"`
+	PreviousImage string        `json:"previous_image,omitempty" gorethink:"previous_image"`
+	Options       DeployOptions `json:"options" gorethink:"options"`
+	Status        string        `json:"status,omitempty" gorethink:"status"`
+	// Updated is the number of containers running the new image
+	Updated int       `json:"updated" gorethink:"updated"`
+	Total   int       `json:"total" gorethink:"total"`
+	Message string    `json:"message,omitempty" gorethink:"message"`
+	Started time.Time `json:"started,omitempty" gorethink:"started"`
+	// Finished is zero while the deployment is running
+	Finished time.Time `json:"finished,omitempty" gorethink:"finished"`
+	// Revision numbers the specs an application was deployed with, from 1
+	Revision int `json:"revision,omitempty" gorethink:"revision"`
+	// Spec is the application spec rolled out
+	Spec *Application `json:"spec,omitempty" gorethink:"spec"`
+	// User is the account that started the deployment
+	User string `json:"user,omitempty" gorethink:"user"`
+	// RollbackTo is the revision whose spec a rollback restores
+	RollbackTo int `json:"rollback_to,omitempty" gorethink:"rollback_to"`
+	// Promoted is set to end the bake period of a baking canary deployment
+	Promoted bool `json:"promoted,omitempty" gorethink:"promoted"`
+	// Controller is the instance id of the controller running the
+	// deployment in ha mode
+	Controller string `json:"controller,omitempty" gorethink:"controller"`
+}
+
+// RollbackRequest asks for an application to be redeployed with the spec of
+// a previous revision
+type RollbackRequest struct {
+	// Revision is the revision to restore; 0 is the last revision that
+	// succeeded before the current one
+	Revision int           `json:"revision,omitempty"`
+	Options  DeployOptions `json:"options"`
+}
+
+// DeploySwitch is sent to the switch url of a blue-green deployment
+type DeploySwitch struct {
+	Deployment *Deployment `json:"deployment"`
+	// Containers are the new containers traffic should be sent to
+	Containers []*citadel.Container `json:"containers"`
+}
+
+// Done reports whether the deployment has finished
+func (d *Deployment) Done() bool {
+	return d.Status != DeploymentRunning && d.Status != DeploymentBaking
+}
+
+// RollbackRevision returns the deployment of an application history whose
+// spec a rollback to revision restores.  Only revisions that succeeded can
+// be restored; the latest of them is the current spec.
+func RollbackRevision(history []*Deployment, revision int) (*Deployment, error) {
+	if revision < 0 {
+		return nil, fmt.Errorf("%w: revision must not be negative", ErrInvalidRevision)
+	}
+	succeeded := []*Deployment{}
+	var target *Deployment
+	for _, d := range history {
+		if d.Status == DeploymentSucceeded && d.Spec != nil {
+			succeeded = append(succeeded, d)
+		}
+		if d.Revision == revision {
+			target = d
+		}
+	}
+	sort.Slice(succeeded, func(i, j int) bool {
+		return succeeded[i].Revision > succeeded[j].Revision
+	})
+	switch {
+	case revision == 0:
+		if len(succeeded) < 2 {
+			return nil, fmt.Errorf("%w: no previous revision", ErrInvalidRevision)
+		}
+		return succeeded[1], nil
+	case target == nil:
+		return nil, fmt.Errorf("%w: revision %d does not exist", ErrInvalidRevision, revision)
+	case target.Status != DeploymentSucceeded || target.Spec == nil:
+		return nil, fmt.Errorf("%w: revision %d did not succeed", ErrInvalidRevision, revision)
+	case target == succeeded[0]:
+		return nil, fmt.Errorf("%w: revision %d is the current revision", ErrInvalidRevision, revision)
+	}
+	return target, nil
+}
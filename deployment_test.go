@@ -0,0 +1,89 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDeployOptionsDefaults(t *testing.T) {
+	opts := &DeployOptions{Delay: 5}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Strategy != DeployRolling || opts.BatchSize != DefaultDeployBatchSize || opts.Monitor != DefaultDeployMonitor || opts.Delay != 5 {
+		t.Errorf("unexpected options %+v", opts)
+	}
+	if err := (&DeployOptions{BatchSize: -1}).Validate(); !errors.Is(err, ErrInvalidDeployOptions) {
+		t.Errorf("expected ErrInvalidDeployOptions; received %v", err)
+	}
+}
+
+func TestDeployOptionsSwitchURL(t *testing.T) {
+	opts := &DeployOptions{Strategy: DeployBlueGreen, SwitchURL: "https://lb.example.com/switch"}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range []*DeployOptions{
+		{Strategy: "recreate"},
+		{SwitchURL: "https://lb.example.com/switch"},
+		{Strategy: DeployBlueGreen, SwitchURL: "lb.example.com"},
+	} {
+		if err := o.Validate(); !errors.Is(err, ErrInvalidDeployOptions) {
+			t.Errorf("expected ErrInvalidDeployOptions for %+v; received %v", o, err)
+		}
+	}
+}
+
+func TestDeployOptionsCanary(t *testing.T) {
+	opts := &DeployOptions{Strategy: DeployCanary, BakePeriod: 60}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if opts.CanaryPercent != DefaultCanaryPercent {
+		t.Errorf("expected the default canary percent; received %d", opts.CanaryPercent)
+	}
+	tests := map[int]int{0: 0, 1: 1, 4: 1, 10: 1, 11: 2, 40: 4}
+	for total, expected := range tests {
+		if n := opts.CanaryCount(total); n != expected {
+			t.Errorf("%d containers: expected %d canaries; received %d", total, expected, n)
+		}
+	}
+	if n := opts.Surge(40); n != 4 {
+		t.Errorf("expected the canaries to surge; received %d", n)
+	}
+	for _, o := range []*DeployOptions{
+		{Strategy: DeployCanary, CanaryPercent: 101},
+		{Strategy: DeployCanary, BakePeriod: -1},
+		{CanaryPercent: 20},
+		{Strategy: DeployBlueGreen, BakePeriod: 60},
+	} {
+		if err := o.Validate(); !errors.Is(err, ErrInvalidDeployOptions) {
+			t.Errorf("expected ErrInvalidDeployOptions for %+v; received %v", o, err)
+		}
+	}
+}
+
+func TestRollbackRevision(t *testing.T) {
+	history := []*Deployment{
+		{Revision: 4, Status: DeploymentRolledBack, Spec: &Application{Image: "web:4"}},
+		{Revision: 3, Status: DeploymentSucceeded, Spec: &Application{Image: "web:3"}},
+		{Revision: 2, Status: DeploymentSucceeded, Spec: &Application{Image: "web:2"}},
+		{Revision: 1, Status: DeploymentSucceeded, Spec: &Application{Image: "web:1"}},
+		{Status: DeploymentSucceeded, Image: "web:0"},
+	}
+	d, err := RollbackRevision(history, 0)
+	if err != nil || d.Revision != 2 {
+		t.Errorf("expected the previous revision 2; received %v %v", d, err)
+	}
+	if d, err := RollbackRevision(history, 1); err != nil || d.Spec.Image != "web:1" {
+		t.Errorf("expected revision 1; received %v %v", d, err)
+	}
+	for _, revision := range []int{-1, 3, 4, 9} {
+		if _, err := RollbackRevision(history, revision); !errors.Is(err, ErrInvalidRevision) {
+			t.Errorf("revision %d: expected ErrInvalidRevision; received %v", revision, err)
+		}
+	}
+	if _, err := RollbackRevision(history[3:], 0); !errors.Is(err, ErrInvalidRevision) {
+		t.Errorf("expected no previous revision; received %v", err)
+	}
+}
@@ -5,5 +5,6 @@ type (
 		PushedAt int      `json:"pushed_at,omitempty"`
 		Images   []string `json:"images,omitempty"`
 		Pusher   string   `json:"pusher,omitempty"`
+		Tag      string   `json:"tag,omitempty"`
 	}
 )
@@ -0,0 +1,109 @@
+package shipyard
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/citadel/citadel"
+)
+
+// Endpoint is an address where a published container port is reachable
+type Endpoint struct {
+	Container     string `json:"container,omitempty"`
+	Engine        string `json:"engine,omitempty"`
+	Image         string `json:"image,omitempty"`
+	Application   string `json:"application,omitempty"`
+	Proto         string `json:"proto,omitempty"`
+	ContainerPort int    `json:"container_port,omitempty"`
+	// Addr is the host:port to connect to
+	Addr string `json:"addr,omitempty"`
+	// Health is the health check status of the container, if it has one
+	Health string `json:"health,omitempty"`
+}
+
+// EndpointFilter selects the containers endpoints are returned for; empty
+// fields match every container
+type EndpointFilter struct {
+	// Image matches the image name with or without tag
+	Image string
+	// Label matches one of the constraint labels of the container
+	Label       string
+	Application string
+	// ContainerPort only returns the endpoints of this container port
+	ContainerPort int
+	// Healthy leaves out containers failing their health check
+	Healthy bool
+	// Namespace only returns the endpoints of containers in the namespace
+	Namespace string
+	// Selector matches the container labels (see SetLabels)
+	Selector Selector
+}
+
+// Match reports whether the filter selects the container
+func (f *EndpointFilter) Match(c *citadel.Container) bool {
+	if c.Image == nil {
+		return false
+	}
+	if f.Image != "" && !ImageMatches(f.Image, c) {
+		return false
+	}
+	if f.Application != "" && ApplicationName(c) != f.Application {
+		return false
+	}
+	if f.Namespace != "" && ContainerNamespace(c) != f.Namespace {
+		return false
+	}
+	if !f.Selector.Match(c) {
+		return false
+	}
+	if f.Label != "" {
+		found := false
+		for _, l := range c.Image.Labels {
+			if l == f.Label {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainerEndpoints returns the endpoints of the published ports of a
+// container.  Ports bound to all interfaces are reached through the
+// address of the engine.
+func ContainerEndpoints(c *citadel.Container) []*Endpoint {
+	endpoints := []*Endpoint{}
+	for _, p := range c.Ports {
+		if p.Port == 0 {
+			continue
+		}
+		host := p.HostIp
+		if host == "" || host == "0.0.0.0" || host == "::" {
+			if c.Engine == nil {
+				continue
+			}
+			u, err := url.Parse(c.Engine.Addr)
+			if err != nil {
+				continue
+			}
+			host = u.Hostname()
+		}
+		e := &Endpoint{
+			Container:     c.ID,
+			Image:         c.Image.Name,
+			Application:   ApplicationName(c),
+			Proto:         p.Proto,
+			ContainerPort: p.ContainerPort,
+			Addr:          net.JoinHostPort(host, strconv.Itoa(p.Port)),
+		}
+		if c.Engine != nil {
+			e.Engine = c.Engine.ID
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints
+}
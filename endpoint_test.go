@@ -0,0 +1,49 @@
+package shipyard
+
+import (
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestContainerEndpoints(t *testing.T) {
+	c := &citadel.Container{
+		ID:     "abc",
+		Engine: &citadel.Engine{ID: "local", Addr: "tcp://10.0.0.5:2375"},
+		Image:  &citadel.Image{Name: "redis:2.8", Labels: []string{"cache"}},
+		Ports: []*citadel.Port{
+			{Proto: "tcp", ContainerPort: 6379, Port: 49153},
+			{Proto: "tcp", HostIp: "127.0.0.1", ContainerPort: 8080, Port: 8080},
+			{Proto: "tcp", ContainerPort: 9000},
+		},
+	}
+	endpoints := ContainerEndpoints(c)
+	if len(endpoints) != 2 {
+		t.Fatalf("expected 2 endpoints; got %d", len(endpoints))
+	}
+	if endpoints[0].Addr != "10.0.0.5:49153" || endpoints[0].Engine != "local" {
+		t.Errorf("unexpected endpoint %+v", endpoints[0])
+	}
+	if endpoints[1].Addr != "127.0.0.1:8080" {
+		t.Errorf("unexpected endpoint %+v", endpoints[1])
+	}
+
+	tests := []struct {
+		filter *EndpointFilter
+		match  bool
+	}{
+		{&EndpointFilter{}, true},
+		{&EndpointFilter{Image: "redis"}, true},
+		{&EndpointFilter{Image: "redis:2.8"}, true},
+		{&EndpointFilter{Image: "redis:3.0"}, false},
+		{&EndpointFilter{Image: "red"}, false},
+		{&EndpointFilter{Label: "cache"}, true},
+		{&EndpointFilter{Label: "db"}, false},
+		{&EndpointFilter{Application: "web"}, false},
+	}
+	for _, test := range tests {
+		if m := test.filter.Match(c); m != test.match {
+			t.Errorf("%+v: expected match %v", test.filter, test.match)
+		}
+	}
+}
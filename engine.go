@@ -20,6 +20,11 @@ type (
 	Health struct {
 		Status       string `json:"status,omitempty" gorethink:"status,omitempty"`
 		ResponseTime int64  `json:"response_time,omitempty" gorethink:"response_time,omitempty"`
+		// LastSeen is the last time the engine answered a ping
+		LastSeen    time.Time `json:"last_seen,omitempty" gorethink:"last_seen,omitempty"`
+		LastChecked time.Time `json:"last_checked,omitempty" gorethink:"last_checked,omitempty"`
+		// Error is the reason of the last failed check
+		Error string `json:"error,omitempty" gorethink:"error,omitempty"`
 	}
 
 	Engine struct {
@@ -30,6 +35,10 @@ type (
 		Engine         *citadel.Engine `json:"engine,omitempty" gorethink:"engine,omitempty"`
 		Health         *Health         `json:"health,omitempty" gorethink:"health,omitempty"`
 		DockerVersion  string          `json:"docker_version,omitempty"`
+		// Cordoned engines are excluded from new container placements
+		Cordoned bool `json:"cordoned,omitempty" gorethink:"cordoned"`
+		// CAdvisor, when set, is scraped for container metrics
+		CAdvisor *CAdvisor `json:"cadvisor,omitempty" gorethink:"cadvisor,omitempty"`
 	}
 )
 
@@ -57,20 +66,20 @@ func (e *Engine) Ping() (int, error) {
 		if err != nil {
 			return 0, err
 		}
-
-		tlsConfig = &tls.Config{
-			Certificates: []tls.Certificate{*cert},
+		if cert != nil {
+			tlsConfig.Certificates = []tls.Certificate{*cert}
 		}
 
-		// use custom ca cert if specified
+		// verify the engine with its ca cert if specified, otherwise
+		// with the system roots
 		if e.CACertificate != "" {
 			caCertPool := x509.NewCertPool()
-			caCertPool.AppendCertsFromPEM([]byte(e.CACertificate))
+			if !caCertPool.AppendCertsFromPEM([]byte(e.CACertificate)) {
+				return 0, fmt.Errorf("%w: no pem encoded ca certificate", ErrInvalidCertificate)
+			}
 			tlsConfig.RootCAs = caCertPool
 		}
 	}
-	// allow insecure
-	tlsConfig.InsecureSkipVerify = true
 
 	transport := http.Transport{
 		Dial:            dialTimeout,
@@ -90,3 +99,11 @@ func (e *Engine) Ping() (int, error) {
 	}
 	return status, nil
 }
+
+// Redacted returns a copy of the engine without the client key for api
+// responses
+func (e *Engine) Redacted() *Engine {
+	redacted := *e
+	redacted.SSLKey = ""
+	return &redacted
+}
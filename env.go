@@ -0,0 +1,52 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// ReservedEnvPrefix starts the environment variables the controller
+	// keeps container settings and bookkeeping in
+	ReservedEnvPrefix = "_SHIPYARD_"
+)
+
+var (
+	ErrReservedEnv = errors.New("reserved environment variable")
+
+	// ImageSettingsEnv are the reserved variables clients set on the images
+	// they run to pass launch settings, such as with SetSecretRefs.  The
+	// others are only set by the controller.
+	ImageSettingsEnv = map[string]bool{
+		AffinityEnv:         true,
+		ConfigsEnv:          true,
+		DescriptionEnv:      true,
+		HealthCheckEnv:      true,
+		LabelsEnv:           true,
+		PlacementEnv:        true,
+		PoolEnv:             true,
+		ReschedulePolicyEnv: true,
+		RestartPolicyEnv:    true,
+		SecretsEnv:          true,
+		StopHookEnv:         true,
+		VolumesEnv:          true,
+	}
+)
+
+// ValidateEnvironment checks env sets no reserved variable other than the
+// allowed ones, which may be nil
+func ValidateEnvironment(env map[string]string, allowed map[string]bool) error {
+	keys := []string{}
+	for k := range env {
+		if strings.HasPrefix(k, ReservedEnvPrefix) && !allowed[k] {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		return fmt.Errorf("%w: %s", ErrReservedEnv, strings.Join(keys, ", "))
+	}
+	return nil
+}
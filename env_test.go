@@ -0,0 +1,28 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateEnvironment(t *testing.T) {
+	if err := ValidateEnvironment(map[string]string{"MODE": "prod"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateEnvironment(map[string]string{SecretsEnv: "[]"}, ImageSettingsEnv); err != nil {
+		t.Fatal(err)
+	}
+	for _, env := range []map[string]string{
+		{SecretsEnv: "[]"},
+		{"_SHIPYARD_OTHER": "x"},
+	} {
+		if err := ValidateEnvironment(env, nil); !errors.Is(err, ErrReservedEnv) {
+			t.Errorf("%v: expected ErrReservedEnv; received %v", env, err)
+		}
+	}
+	for _, k := range []string{OwnerEnv, ApplicationEnv, NamespaceEnv, HealthStatusEnv} {
+		if err := ValidateEnvironment(map[string]string{k: "x"}, ImageSettingsEnv); !errors.Is(err, ErrReservedEnv) {
+			t.Errorf("%s: expected ErrReservedEnv; received %v", k, err)
+		}
+	}
+}
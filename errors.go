@@ -0,0 +1,68 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrBadRequest = errors.New("bad request")
+	ErrForbidden  = errors.New("forbidden")
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	// ErrRateLimited is returned for requests rejected by the controller
+	// rate limits; see APIError.RetryAfter
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// APIError is returned when the controller responds with an unexpected
+// status.  It unwraps to one of the sentinel errors for well known statuses
+// so callers can use errors.Is.
+type APIError struct {
+	StatusCode int    `json:"status_code,omitempty"`
+	Method     string `json:"method,omitempty"`
+	Endpoint   string `json:"endpoint,omitempty"`
+	Message    string `json:"message,omitempty"`
+	// RetryAfter is how long the controller asked to wait before
+	// retrying a rate limited request
+	RetryAfter time.Duration `json:"retry_after,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.Endpoint, e.StatusCode, msg)
+}
+
+// Unwrap returns the sentinel error for the status code, if any
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// Is reports whether a forbidden login failed for a one time code, so
+// callers can use errors.Is with ErrOTPRequired and ErrInvalidOTP
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrOTPRequired, ErrInvalidOTP:
+		return e.StatusCode == http.StatusForbidden && e.Message == target.Error()
+	}
+	return false
+}
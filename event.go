@@ -1,16 +1,94 @@
 package shipyard
 
 import (
+	"strings"
 	"time"
 
 	"github.com/citadel/citadel"
 )
 
 type Event struct {
+	// ID is set when the event is saved
+	ID        string             `json:"id,omitempty" gorethink:"id,omitempty"`
 	Type      string             `json:"type,omitempty"`
 	Container *citadel.Container `json:"container,omitempty"`
 	Engine    *citadel.Engine    `json:"engine,omitempty"`
 	Time      time.Time          `json:"time,omitempty"`
 	Message   string             `json:"message,omitempty"`
 	Tags      []string           `json:"tags,omitempty"`
+	// Severity is set from the event type when the event is saved
+	Severity string `json:"severity,omitempty"`
+	// Namespace is set from the container when the event is saved
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// EventFilter restricts a set of events.  Empty fields match any event.
+type EventFilter struct {
+	Types       []string `json:"types,omitempty"`
+	EngineID    string   `json:"engine_id,omitempty"`
+	ContainerID string   `json:"container_id,omitempty"`
+	Namespace   string   `json:"namespace,omitempty"`
+}
+
+// Match reports whether the event passes the filter
+func (f *EventFilter) Match(e *Event) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			if t == e.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.EngineID != "" && (e.Engine == nil || e.Engine.ID != f.EngineID) {
+		return false
+	}
+	if f.ContainerID != "" && (e.Container == nil || !strings.HasPrefix(e.Container.ID, f.ContainerID)) {
+		return false
+	}
+	if f.Namespace != "" && e.Namespace != f.Namespace {
+		return false
+	}
+	return true
+}
+
+// EventQuery selects a page of stored events, newest first.  Zero values
+// leave the corresponding bound unset.
+type EventQuery struct {
+	EventFilter
+	Since  time.Time `json:"since,omitempty"`
+	Until  time.Time `json:"until,omitempty"`
+	Limit  int       `json:"limit,omitempty"`
+	Offset int       `json:"offset,omitempty"`
+}
+
+// EventCSVHeader is the header row of the csv event export
+var EventCSVHeader = []string{"time", "type", "severity", "namespace", "engine", "container", "message", "tags"}
+
+// CSVRecord returns the event as a row matching EventCSVHeader
+func (e *Event) CSVRecord() []string {
+	engine, container := "", ""
+	if e.Engine != nil {
+		engine = e.Engine.ID
+	}
+	if e.Container != nil {
+		container = e.Container.ID
+	}
+	return []string{
+		e.Time.Format(time.RFC3339),
+		e.Type,
+		e.Severity,
+		e.Namespace,
+		engine,
+		container,
+		e.Message,
+		strings.Join(e.Tags, ","),
+	}
 }
@@ -0,0 +1,25 @@
+package shipyard
+
+const (
+	// TtyHeader tells attach clients whether the container has a tty
+	TtyHeader = "X-Shipyard-Tty"
+)
+
+type (
+	ExecConfig struct {
+		Cmd []string `json:"cmd,omitempty"`
+		// Tty allocates a pseudo terminal; output is not multiplexed
+		Tty bool `json:"tty,omitempty"`
+		// Interactive attaches stdin to the command
+		Interactive bool `json:"interactive,omitempty"`
+	}
+	ExecInfo struct {
+		ID       string `json:"id,omitempty"`
+		Running  bool   `json:"running"`
+		ExitCode int    `json:"exit_code"`
+	}
+	// ContainerExit is returned once a waited for container stops
+	ContainerExit struct {
+		ExitCode int `json:"exit_code"`
+	}
+)
@@ -0,0 +1,75 @@
+package shipyard
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	ExportCSV       = "csv"
+	ExportJSONLines = "jsonl"
+)
+
+var (
+	ErrUnsupportedExportFormat = errors.New("unsupported export format")
+)
+
+type (
+	// CSVRecorder is a record of a csv export
+	CSVRecorder interface {
+		CSVRecord() []string
+	}
+
+	// Exporter writes records one at a time as csv rows or json lines
+	Exporter struct {
+		csv  *csv.Writer
+		json *json.Encoder
+	}
+)
+
+// ExportContentType returns the content type of an export format
+func ExportContentType(format string) (string, error) {
+	switch format {
+	case ExportCSV:
+		return "text/csv", nil
+	case ExportJSONLines:
+		return "application/x-ndjson", nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedExportFormat, format)
+}
+
+// NewExporter returns an exporter writing format to w; csv exports start
+// with header
+func NewExporter(w io.Writer, format string, header []string) (*Exporter, error) {
+	switch format {
+	case ExportCSV:
+		e := &Exporter{csv: csv.NewWriter(w)}
+		if err := e.csv.Write(header); err != nil {
+			return nil, err
+		}
+		return e, nil
+	case ExportJSONLines:
+		return &Exporter{json: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedExportFormat, format)
+}
+
+// Export writes a record
+func (e *Exporter) Export(v CSVRecorder) error {
+	if e.csv != nil {
+		return e.csv.Write(v.CSVRecord())
+	}
+	return e.json.Encode(v)
+}
+
+// Flush writes buffered records
+func (e *Exporter) Flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package shipyard
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/citadel/citadel"
+)
+
+func TestExporter(t *testing.T) {
+	events := []*Event{
+		{Type: "start", Time: time.Date(2015, 3, 1, 12, 0, 0, 0, time.UTC), Engine: &citadel.Engine{ID: "e1"}, Message: "a, \"b\"", Tags: []string{"cluster", "docker"}},
+		{Type: "stop", Time: time.Date(2015, 3, 1, 13, 0, 0, 0, time.UTC)},
+	}
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{ExportCSV, "time,type,severity,namespace,engine,container,message,tags\n" +
+			"2015-03-01T12:00:00Z,start,,,e1,,\"a, \"\"b\"\"\",\"cluster,docker\"\n" +
+			"2015-03-01T13:00:00Z,stop,,,,,,\n"},
+		{ExportJSONLines, "{\"type\":\"start\",\"engine\":{\"id\":\"e1\"},\"time\":\"2015-03-01T12:00:00Z\",\"message\":\"a, \\\"b\\\"\",\"tags\":[\"cluster\",\"docker\"]}\n" +
+			"{\"type\":\"stop\",\"time\":\"2015-03-01T13:00:00Z\"}\n"},
+	}
+	for _, test := range tests {
+		buf := &bytes.Buffer{}
+		e, err := NewExporter(buf, test.format, EventCSVHeader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, evt := range events {
+			if err := e.Export(evt); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := e.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("%s: expected\n%s\nreceived\n%s", test.format, test.expected, buf.String())
+		}
+	}
+	if _, err := NewExporter(&bytes.Buffer{}, "xml", nil); !errors.Is(err, ErrUnsupportedExportFormat) {
+		t.Errorf("expected ErrUnsupportedExportFormat; received %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	DefaultGCInterval = 60
+)
+
+var (
+	ErrInvalidGCPolicy = errors.New("invalid gc policy")
+)
+
+type (
+	// GCPolicy controls the removal of exited containers and dangling
+	// images from engines.  Containers of applications, compose projects
+	// and jobs are managed by the controller and never collected (see
+	// GCExempt).
+	GCPolicy struct {
+		// Enabled runs collections automatically every Interval
+		Enabled bool `json:"enabled" gorethink:"enabled"`
+		// Interval is the number of minutes between automatic
+		// collections; zero uses DefaultGCInterval (see Period)
+		Interval int `json:"interval,omitempty" gorethink:"interval"`
+		// ContainerAge is the number of hours exited containers are kept;
+		// zero collects them regardless of age
+		ContainerAge int `json:"container_age,omitempty" gorethink:"container_age"`
+		// KeepExited is the number of most recently exited containers of
+		// each image kept regardless of age
+		KeepExited int `json:"keep_exited,omitempty" gorethink:"keep_exited"`
+		// Containers collects exited containers
+		Containers bool `json:"containers" gorethink:"containers"`
+		// DanglingImages collects untagged images no container uses
+		DanglingImages bool `json:"dangling_images" gorethink:"dangling_images"`
+	}
+
+	// GCCandidate is an exited container considered for collection
+	GCCandidate struct {
+		ID       string
+		Image    string
+		Finished time.Time
+	}
+
+	// GCResult lists what a collection removed
+	GCResult struct {
+		Started    time.Time `json:"started,omitempty"`
+		Containers []string  `json:"containers"`
+		Images     []string  `json:"images"`
+		// Errors are the failed removals; they are retried by the next
+		// collection
+		Errors []string `json:"errors,omitempty"`
+	}
+)
+
+func (p *GCPolicy) Validate() error {
+	if p.Interval < 0 || p.ContainerAge < 0 || p.KeepExited < 0 {
+		return fmt.Errorf("%w: values must not be negative", ErrInvalidGCPolicy)
+	}
+	return nil
+}
+
+// Period returns the time between automatic collections
+func (p *GCPolicy) Period() time.Duration {
+	if p.Interval == 0 {
+		return DefaultGCInterval * time.Minute
+	}
+	return time.Duration(p.Interval) * time.Minute
+}
+
+// GCExempt reports whether a container is managed by the controller as
+// part of an application, compose project or job run
+func GCExempt(c *citadel.Container) bool {
+	if c.Image == nil {
+		return true
+	}
+	return ApplicationName(c) != "" || c.Image.Environment[ProjectEnv] != "" || c.Image.Environment[JobRunEnv] != ""
+}
+
+// Collect returns the ids of the candidates the policy removes: those older
+// than ContainerAge that are not among the KeepExited most recently exited
+// containers of their image
+func (p *GCPolicy) Collect(candidates []*GCCandidate, now time.Time) []string {
+	if !p.Containers {
+		return nil
+	}
+	byImage := map[string][]*GCCandidate{}
+	for _, c := range candidates {
+		byImage[c.Image] = append(byImage[c.Image], c)
+	}
+	ids := []string{}
+	maxAge := time.Duration(p.ContainerAge) * time.Hour
+	for _, cs := range byImage {
+		sort.Slice(cs, func(i, j int) bool { return cs[i].Finished.After(cs[j].Finished) })
+		for i, c := range cs {
+			if i < p.KeepExited || now.Sub(c.Finished) < maxAge {
+				continue
+			}
+			ids = append(ids, c.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,69 @@
+package shipyard
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/citadel/citadel"
+)
+
+func TestGCPolicyCollect(t *testing.T) {
+	now := time.Now()
+	candidates := []*GCCandidate{
+		{ID: "a1", Image: "a", Finished: now.Add(-1 * time.Hour)},
+		{ID: "a2", Image: "a", Finished: now.Add(-30 * time.Hour)},
+		{ID: "a3", Image: "a", Finished: now.Add(-50 * time.Hour)},
+		{ID: "b1", Image: "b", Finished: now.Add(-100 * time.Hour)},
+	}
+	tests := []struct {
+		policy   GCPolicy
+		expected []string
+	}{
+		{GCPolicy{}, nil},
+		{GCPolicy{Containers: true}, []string{"a1", "a2", "a3", "b1"}},
+		{GCPolicy{Containers: true, ContainerAge: 24}, []string{"a2", "a3", "b1"}},
+		{GCPolicy{Containers: true, KeepExited: 1}, []string{"a2", "a3"}},
+		{GCPolicy{Containers: true, ContainerAge: 40, KeepExited: 1}, []string{"a3"}},
+	}
+	for _, test := range tests {
+		ids := test.policy.Collect(candidates, now)
+		if len(ids) == 0 && len(test.expected) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(ids, test.expected) {
+			t.Errorf("%+v: expected %v; received %v", test.policy, test.expected, ids)
+		}
+	}
+}
+
+func TestGCPolicyValidate(t *testing.T) {
+	p := &GCPolicy{}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Interval != 0 || p.Period() != DefaultGCInterval*time.Minute {
+		t.Errorf("expected the policy unchanged with the default period; received %d %s", p.Interval, p.Period())
+	}
+	if err := (&GCPolicy{KeepExited: -1}).Validate(); !errors.Is(err, ErrInvalidGCPolicy) {
+		t.Errorf("expected ErrInvalidGCPolicy; received %v", err)
+	}
+}
+
+func TestGCExempt(t *testing.T) {
+	for env, exempt := range map[string]bool{
+		"":             false,
+		ApplicationEnv: true,
+		ProjectEnv:     true,
+		JobRunEnv:      true,
+	} {
+		img := &citadel.Image{Name: "worker", Environment: map[string]string{}}
+		if env != "" {
+			img.Environment[env] = "x"
+		}
+		if GCExempt(&citadel.Container{Image: img}) != exempt {
+			t.Errorf("%q: expected exempt %v", env, exempt)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/citadel/citadel"
+)
+
+var (
+	ErrInvalidGroup = errors.New("invalid group")
+)
+
+// ContainerGroup selects a set of containers managed as a unit by exactly
+// one of its fields
+type ContainerGroup struct {
+	// Label matches one of the constraint labels of the container
+	Label       string `json:"label,omitempty"`
+	Application string `json:"application,omitempty"`
+	// Project matches the containers of the applications of a compose
+	// project
+	Project string `json:"project,omitempty"`
+	// Selector matches the container labels, i.e. "tier=web,env!=dev"
+	Selector string `json:"selector,omitempty"`
+	// Namespace further limits the group to the containers of a namespace
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// GroupResult is the outcome of an operation on a container group
+type GroupResult struct {
+	Action string `json:"action,omitempty"`
+	Group  string `json:"group,omitempty"`
+	// Containers are the ids of the containers the action was applied to
+	Containers []string `json:"containers"`
+	// Errors are the failures by container id
+	Errors map[string]string `json:"errors,omitempty"`
+	// RolledBack is set when containers were returned to their previous
+	// state after a failure
+	RolledBack bool `json:"rolled_back,omitempty"`
+}
+
+func (g *ContainerGroup) Validate() error {
+	n := 0
+	for _, v := range []string{g.Label, g.Application, g.Project, g.Selector} {
+		if v != "" {
+			n++
+		}
+	}
+	if n != 1 {
+		return fmt.Errorf("%w: select the group by one of label, application, project or selector", ErrInvalidGroup)
+	}
+	if g.Selector != "" {
+		if _, err := ParseSelector(g.Selector); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidGroup, err)
+		}
+	}
+	return nil
+}
+
+// Match reports whether the container belongs to the group
+func (g *ContainerGroup) Match(c *citadel.Container) bool {
+	if c.Image == nil {
+		return false
+	}
+	if g.Namespace != "" && ContainerNamespace(c) != g.Namespace {
+		return false
+	}
+	switch {
+	case g.Application != "":
+		return ApplicationName(c) == g.Application
+	case g.Project != "":
+		return c.Image.Environment[ProjectEnv] == g.Project
+	case g.Selector != "":
+		selector, err := ParseSelector(g.Selector)
+		return err == nil && selector.Match(c)
+	case g.Label != "":
+		for _, l := range c.Image.Labels {
+			if l == g.Label {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (g *ContainerGroup) String() string {
+	switch {
+	case g.Application != "":
+		return "application=" + g.Application
+	case g.Project != "":
+		return "project=" + g.Project
+	case g.Selector != "":
+		return "selector=" + g.Selector
+	}
+	return "label=" + g.Label
+}
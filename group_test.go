@@ -0,0 +1,43 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestContainerGroup(t *testing.T) {
+	app := &Application{Name: "shop_web", Image: "web", Project: "shop", Constraints: []string{"frontend"}}
+	c := &citadel.Container{ID: "abc", Image: app.ContainerImage()}
+	other := &citadel.Container{ID: "def", Image: &citadel.Image{Name: "redis", Labels: []string{"cache"}}}
+
+	tests := []struct {
+		group *ContainerGroup
+		match bool
+		other bool
+	}{
+		{&ContainerGroup{Application: "shop_web"}, true, false},
+		{&ContainerGroup{Application: "shop_db"}, false, false},
+		{&ContainerGroup{Project: "shop"}, true, false},
+		{&ContainerGroup{Label: "frontend"}, true, false},
+		{&ContainerGroup{Label: "cache"}, false, true},
+	}
+	for _, test := range tests {
+		if err := test.group.Validate(); err != nil {
+			t.Fatalf("%s: %s", test.group, err)
+		}
+		if m := test.group.Match(c); m != test.match {
+			t.Errorf("%s: expected match %v; got %v", test.group, test.match, m)
+		}
+		if m := test.group.Match(other); m != test.other {
+			t.Errorf("%s: expected match of other container %v; got %v", test.group, test.other, m)
+		}
+	}
+
+	for _, g := range []*ContainerGroup{{}, {Label: "a", Project: "b"}} {
+		if err := g.Validate(); !errors.Is(err, ErrInvalidGroup) {
+			t.Errorf("expected ErrInvalidGroup for %+v; got %v", g, err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	// HealthCheckEnv holds the json encoded health check of a container
+	HealthCheckEnv = "_SHIPYARD_HEALTH_CHECK"
+	// HealthStatusEnv is set by the controller on the containers it returns
+	// to the current health of containers with a health check
+	HealthStatusEnv = "_SHIPYARD_HEALTH"
+
+	HealthCheckHTTP = "http"
+	HealthCheckTCP  = "tcp"
+	HealthCheckExec = "exec"
+
+	// HealthStarting is reported until the first check of a container
+	// passes
+	HealthStarting  = "starting"
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+
+	DefaultHealthCheckInterval = 10
+	DefaultHealthCheckTimeout  = 5
+	DefaultHealthCheckRetries  = 3
+)
+
+var (
+	ErrInvalidHealthCheck = errors.New("invalid health check")
+)
+
+// HealthCheck is probed periodically by the controller while the container
+// is running
+type HealthCheck struct {
+	// Type is HealthCheckHTTP, HealthCheckTCP or HealthCheckExec
+	Type string `json:"type,omitempty"`
+	// Port is the container port http and tcp checks connect to; it may be
+	// omitted for containers publishing a single port
+	Port int `json:"port,omitempty"`
+	// Path is requested by http checks; any 2xx or 3xx status passes
+	Path string `json:"path,omitempty"`
+	// Command is run in the container by exec checks; a zero exit status
+	// passes
+	Command []string `json:"command,omitempty"`
+	// Interval is the number of seconds between checks
+	Interval int `json:"interval,omitempty"`
+	// Timeout is the number of seconds a check may take
+	Timeout int `json:"timeout,omitempty"`
+	// Retries is the number of consecutive failures before the container
+	// is unhealthy
+	Retries int `json:"retries,omitempty"`
+}
+
+// Validate checks the health check and fills in defaults
+func (h *HealthCheck) Validate() error {
+	switch h.Type {
+	case HealthCheckHTTP:
+		if h.Path == "" {
+			h.Path = "/"
+		}
+		if !strings.HasPrefix(h.Path, "/") {
+			return fmt.Errorf("%w: path must start with /", ErrInvalidHealthCheck)
+		}
+	case HealthCheckTCP:
+	case HealthCheckExec:
+		if len(h.Command) == 0 {
+			return fmt.Errorf("%w: command is required", ErrInvalidHealthCheck)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidHealthCheck, h.Type)
+	}
+	if h.Port < 0 || h.Interval < 0 || h.Timeout < 0 || h.Retries < 0 {
+		return fmt.Errorf("%w: values must not be negative", ErrInvalidHealthCheck)
+	}
+	if h.Interval == 0 {
+		h.Interval = DefaultHealthCheckInterval
+	}
+	if h.Timeout == 0 {
+		h.Timeout = DefaultHealthCheckTimeout
+	}
+	if h.Retries == 0 {
+		h.Retries = DefaultHealthCheckRetries
+	}
+	return nil
+}
+
+// ParseHealthCheck parses the short form of a health check used on the
+// command line: http:<port><path>, tcp:<port> or exec:<command>
+func ParseHealthCheck(s string) (*HealthCheck, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("%w: expected http:<port><path>, tcp:<port> or exec:<command>", ErrInvalidHealthCheck)
+	}
+	h := &HealthCheck{
+		Type: parts[0],
+	}
+	switch h.Type {
+	case HealthCheckHTTP:
+		port := parts[1]
+		if i := strings.Index(port, "/"); i >= 0 {
+			port, h.Path = port[:i], port[i:]
+		}
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid port %q", ErrInvalidHealthCheck, port)
+		}
+		h.Port = p
+	case HealthCheckTCP:
+		p, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid port %q", ErrInvalidHealthCheck, parts[1])
+		}
+		h.Port = p
+	case HealthCheckExec:
+		h.Command = strings.Fields(parts[1])
+	}
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// ContainerHealthCheck returns the health check of an image or nil if it
+// has none
+func ContainerHealthCheck(image *citadel.Image) (*HealthCheck, error) {
+	if image == nil || image.Environment[HealthCheckEnv] == "" {
+		return nil, nil
+	}
+	var h *HealthCheck
+	if err := json.Unmarshal([]byte(image.Environment[HealthCheckEnv]), &h); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidHealthCheck, err)
+	}
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// SetHealthCheck sets the health check containers of the image are run with
+func SetHealthCheck(image *citadel.Image, h *HealthCheck) error {
+	if err := h.Validate(); err != nil {
+		return err
+	}
+	b, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	if image.Environment == nil {
+		image.Environment = map[string]string{}
+	}
+	image.Environment[HealthCheckEnv] = string(b)
+	return nil
+}
+
+// ContainerHealth returns the health status reported for a container or an
+// empty string if it has no health check
+func ContainerHealth(c *citadel.Container) string {
+	if c.Image == nil {
+		return ""
+	}
+	return c.Image.Environment[HealthStatusEnv]
+}
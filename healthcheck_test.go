@@ -0,0 +1,51 @@
+package shipyard
+
+import (
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestParseHealthCheck(t *testing.T) {
+	h, err := ParseHealthCheck("http:8080/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Type != HealthCheckHTTP || h.Port != 8080 || h.Path != "/healthz" {
+		t.Errorf("unexpected health check %+v", h)
+	}
+	if h.Interval != DefaultHealthCheckInterval || h.Retries != DefaultHealthCheckRetries {
+		t.Errorf("expected defaults; received %+v", h)
+	}
+	if h, err = ParseHealthCheck("tcp:5432"); err != nil || h.Port != 5432 {
+		t.Errorf("unexpected tcp health check %+v: %v", h, err)
+	}
+	if h, err = ParseHealthCheck("exec:pg_isready -U postgres"); err != nil || len(h.Command) != 3 {
+		t.Errorf("unexpected exec health check %+v: %v", h, err)
+	}
+	for _, s := range []string{"http", "tcp:db", "udp:53", "exec:"} {
+		if _, err := ParseHealthCheck(s); err == nil {
+			t.Errorf("expected %q to be rejected", s)
+		}
+	}
+}
+
+func TestContainerHealthCheck(t *testing.T) {
+	img := &citadel.Image{Name: "redis"}
+	if h, err := ContainerHealthCheck(img); err != nil || h != nil {
+		t.Errorf("expected no health check; received %+v: %v", h, err)
+	}
+	if err := SetHealthCheck(img, &HealthCheck{Type: HealthCheckTCP, Port: 6379}); err != nil {
+		t.Fatal(err)
+	}
+	h, err := ContainerHealthCheck(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Type != HealthCheckTCP || h.Port != 6379 || h.Timeout != DefaultHealthCheckTimeout {
+		t.Errorf("unexpected health check %+v", h)
+	}
+	if err := SetHealthCheck(img, &HealthCheck{Type: HealthCheckExec}); err == nil {
+		t.Error("expected exec check without a command to be rejected")
+	}
+}
@@ -0,0 +1,34 @@
+package shipyard
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader identifies a POST, PUT or PATCH request so the
+	// controller runs it once however often it is retried
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed for a retried
+	// request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// IdempotentRequest is a request sent with an idempotency key and, once
+// done, its response.  The id is a hash of the key scoped to the caller.
+type IdempotentRequest struct {
+	ID string `json:"id,omitempty" gorethink:"id,omitempty"`
+	// Request is the method and uri the key was first used with
+	Request string    `json:"request,omitempty" gorethink:"request"`
+	Done    bool      `json:"done,omitempty" gorethink:"done"`
+	Expires time.Time `json:"expires,omitempty" gorethink:"expires"`
+
+	Status int         `json:"status,omitempty" gorethink:"status,omitempty"`
+	Header http.Header `json:"header,omitempty" gorethink:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty" gorethink:"body,omitempty"`
+}
+
+// NewIdempotencyKey returns a random idempotency key
+func NewIdempotencyKey() string {
+	return randomHex(16)
+}
@@ -0,0 +1,64 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidCommit = errors.New("invalid commit")
+)
+
+type (
+	// Image is a docker image present on one or more engines
+	Image struct {
+		ID          string   `json:"id,omitempty"`
+		RepoTags    []string `json:"repo_tags,omitempty"`
+		Created     int64    `json:"created,omitempty"`
+		Size        int64    `json:"size,omitempty"`
+		VirtualSize int64    `json:"virtual_size,omitempty"`
+		// Engines are the ids of the engines holding the image
+		Engines []string `json:"engines,omitempty"`
+	}
+
+	// CommitOptions describe the image created from a container.  When Push
+	// is set the image is pushed to the registry hosting Repo, which must
+	// be configured in the controller.
+	CommitOptions struct {
+		Repo    string `json:"repo,omitempty"`
+		Tag     string `json:"tag,omitempty"`
+		Comment string `json:"comment,omitempty"`
+		Author  string `json:"author,omitempty"`
+		Push    bool   `json:"push,omitempty"`
+	}
+)
+
+func (o *CommitOptions) Validate() error {
+	if o.Repo == "" {
+		return fmt.Errorf("%w: repository is required", ErrInvalidCommit)
+	}
+	if strings.ContainsAny(o.Repo, " @") || strings.ContainsAny(o.Tag, " :/@") {
+		return fmt.Errorf("%w: %s is not a valid image name", ErrInvalidCommit, o.Image())
+	}
+	return nil
+}
+
+// Image returns the name of the committed image; an empty tag is latest
+func (o *CommitOptions) Image() string {
+	tag := o.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+	return fmt.Sprintf("%s:%s", o.Repo, tag)
+}
+
+// ParseImageName splits an image name into repository and tag.  A colon in
+// the registry host, i.e. localhost:5000/app, is not taken as a tag.
+func ParseImageName(name string) (string, string) {
+	i := strings.LastIndex(name, ":")
+	if i == -1 || strings.Contains(name[i+1:], "/") {
+		return name, ""
+	}
+	return name[:i], name[i+1:]
+}
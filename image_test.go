@@ -0,0 +1,36 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseImageName(t *testing.T) {
+	tests := map[string][2]string{
+		"nginx":                    {"nginx", ""},
+		"nginx:1.9":                {"nginx", "1.9"},
+		"localhost:5000/app":       {"localhost:5000/app", ""},
+		"localhost:5000/app:debug": {"localhost:5000/app", "debug"},
+	}
+	for name, expected := range tests {
+		repo, tag := ParseImageName(name)
+		if repo != expected[0] || tag != expected[1] {
+			t.Errorf("%s: expected %s %s; received %s %s", name, expected[0], expected[1], repo, tag)
+		}
+	}
+}
+
+func TestCommitOptionsValidate(t *testing.T) {
+	opts := &CommitOptions{Repo: "localhost:5000/app"}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Image() != "localhost:5000/app:latest" {
+		t.Errorf("expected latest tag; received %s", opts.Image())
+	}
+	for _, o := range []*CommitOptions{{}, {Repo: "app", Tag: "a:b"}, {Repo: "my app"}} {
+		if err := o.Validate(); !errors.Is(err, ErrInvalidCommit) {
+			t.Errorf("%+v: expected ErrInvalidCommit; received %v", o, err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package shipyard
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// dockerHubAPIHost serves the registry api of DefaultRegistryHost
+	dockerHubAPIHost = "registry-1.docker.io"
+)
+
+type (
+	// ImageWatch is the digest the image tag of an auto-deploy application
+	// was last seen with in its registry
+	ImageWatch struct {
+		ID          string    `json:"id,omitempty" gorethink:"id,omitempty"`
+		Application string    `json:"application,omitempty" gorethink:"application"`
+		Image       string    `json:"image,omitempty" gorethink:"image"`
+		Digest      string    `json:"digest,omitempty" gorethink:"digest"`
+		Checked     time.Time `json:"checked,omitempty" gorethink:"checked"`
+	}
+
+	// ImageReference is an image name split the way the registry api
+	// addresses it
+	ImageReference struct {
+		// Host is the registry host; DefaultRegistryHost for docker hub
+		Host string
+		// Repository is the repository on the registry, such as
+		// library/nginx
+		Repository string
+		Tag        string
+		// Digest is set for images pinned to a digest
+		Digest string
+	}
+)
+
+// ParseImageReference splits an image name into its registry host,
+// repository and tag, which defaults to latest
+func ParseImageReference(image string) *ImageReference {
+	ref := &ImageReference{}
+	name := image
+	if i := strings.Index(name, "@"); i != -1 {
+		name, ref.Digest = name[:i], name[i+1:]
+	}
+	ref.Host = RegistryHost(name)
+	if ref.Host != DefaultRegistryHost {
+		name = name[len(ref.Host)+1:]
+	}
+	switch ref.Host {
+	case "docker.io", "registry-1.docker.io":
+		ref.Host = DefaultRegistryHost
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+	if ref.Host == DefaultRegistryHost && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	ref.Repository = name
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+	return ref
+}
+
+// Moving reports whether the image is referred to by a tag that can be
+// pushed again rather than only by a digest.  Images pinned to the digest
+// of a tag, as auto-deploy deploys them, still follow the tag.
+func (r *ImageReference) Moving() bool {
+	return r.Tag != ""
+}
+
+// PinImage returns the image name pinned to the digest a tag points to, such
+// as nginx:1.25@sha256:...; images without a tag are pinned with latest
+func PinImage(image string, digest string) string {
+	name := image
+	if i := strings.Index(name, "@"); i != -1 {
+		name = name[:i]
+	}
+	if strings.LastIndex(name, ":") <= strings.LastIndex(name, "/") {
+		name += ":latest"
+	}
+	return name + "@" + digest
+}
+
+// APIHost returns the host serving the registry api for the image
+func (r *ImageReference) APIHost() string {
+	if r.Host == DefaultRegistryHost {
+		return dockerHubAPIHost
+	}
+	return r.Host
+}
+
+// ParseBearerChallenge returns the parameters of a WWW-Authenticate header
+// asking for a bearer token, such as realm, service and scope
+func ParseBearerChallenge(header string) (map[string]string, bool) {
+	const scheme = "bearer "
+	if len(header) < len(scheme) || strings.ToLower(header[:len(scheme)]) != scheme {
+		return nil, false
+	}
+	params := map[string]string{}
+	rest := header[len(scheme):]
+	for rest != "" {
+		i := strings.Index(rest, "=")
+		if i == -1 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:i]))
+		rest = rest[i+1:]
+		value := ""
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end == -1 {
+				return nil, false
+			}
+			value, rest = rest[1:end+1], rest[end+2:]
+		} else if end := strings.Index(rest, ","); end != -1 {
+			value, rest = rest[:end], rest[end:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[key] = value
+		rest = strings.TrimLeft(rest, ", ")
+	}
+	return params, params["realm"] != ""
+}
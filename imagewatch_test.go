@@ -0,0 +1,57 @@
+package shipyard
+
+import (
+	"testing"
+)
+
+func TestParseImageReference(t *testing.T) {
+	tests := map[string]ImageReference{
+		"nginx":                        {Host: DefaultRegistryHost, Repository: "library/nginx", Tag: "latest"},
+		"ehazlett/app:1.2":             {Host: DefaultRegistryHost, Repository: "ehazlett/app", Tag: "1.2"},
+		"docker.io/ehazlett/app":       {Host: DefaultRegistryHost, Repository: "ehazlett/app", Tag: "latest"},
+		"registry.local:5000/team/api": {Host: "registry.local:5000", Repository: "team/api", Tag: "latest"},
+		"localhost/api:dev":            {Host: "localhost", Repository: "api", Tag: "dev"},
+		"nginx@sha256:abc":             {Host: DefaultRegistryHost, Repository: "library/nginx", Digest: "sha256:abc"},
+		"nginx:1.25@sha256:abc":        {Host: DefaultRegistryHost, Repository: "library/nginx", Tag: "1.25", Digest: "sha256:abc"},
+	}
+	for image, expected := range tests {
+		if ref := ParseImageReference(image); *ref != expected {
+			t.Errorf("%s: expected %+v; received %+v", image, expected, *ref)
+		}
+	}
+	if ParseImageReference("nginx@sha256:abc").Moving() || !ParseImageReference("nginx").Moving() || !ParseImageReference("nginx:1.25@sha256:abc").Moving() {
+		t.Error("expected only tags to move")
+	}
+	if h := ParseImageReference("nginx").APIHost(); h != "registry-1.docker.io" {
+		t.Errorf("unexpected docker hub api host %s", h)
+	}
+}
+
+func TestPinImage(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                        "nginx:latest@sha256:def",
+		"nginx:1.25":                   "nginx:1.25@sha256:def",
+		"nginx:1.25@sha256:abc":        "nginx:1.25@sha256:def",
+		"registry.local:5000/team/api": "registry.local:5000/team/api:latest@sha256:def",
+	}
+	for image, expected := range tests {
+		if pinned := PinImage(image, "sha256:def"); pinned != expected {
+			t.Errorf("%s: expected %s; received %s", image, expected, pinned)
+		}
+	}
+}
+
+func TestParseBearerChallenge(t *testing.T) {
+	params, ok := ParseBearerChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`)
+	if !ok {
+		t.Fatal("expected a bearer challenge")
+	}
+	if params["realm"] != "https://auth.docker.io/token" || params["service"] != "registry.docker.io" || params["scope"] != "repository:library/nginx:pull" {
+		t.Errorf("unexpected params %v", params)
+	}
+	for _, h := range []string{"", `Basic realm="registry"`, `Bearer service="x"`} {
+		if _, ok := ParseBearerChallenge(h); ok {
+			t.Errorf("%q: expected no bearer challenge", h)
+		}
+	}
+}
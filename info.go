@@ -10,5 +10,11 @@ type (
 		ReservedCpus   float64 `json:"reserved_cpus,omitempty"`
 		ReservedMemory float64 `json:"reserved_memory,omitempty"`
 		Version        string  `json:"version,omitempty"`
+		// Placement is the default placement strategy
+		Placement string `json:"placement,omitempty"`
+		// Instance and Leader are the ids of the controller answering and
+		// of the leader in high availability mode
+		Instance string `json:"instance,omitempty"`
+		Leader   string `json:"leader,omitempty"`
 	}
 )
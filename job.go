@@ -0,0 +1,92 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	// JobRunEnv marks the containers launched by a job run with the run id
+	JobRunEnv = "_SHIPYARD_JOB_RUN"
+
+	JobRunRunning   = "running"
+	JobRunSucceeded = "succeeded"
+	// JobRunFailed is reported when the containers could not be started or
+	// any of them exited with a non-zero status
+	JobRunFailed = "failed"
+)
+
+var (
+	ErrInvalidJob = errors.New("invalid job")
+)
+
+// Job launches one-off containers of an image on a cron schedule
+type Job struct {
+	ID string `json:"id,omitempty" gorethink:"id,omitempty"`
+	// Schedule is a cron expression (see ParseCronSchedule)
+	Schedule string         `json:"schedule,omitempty" gorethink:"schedule"`
+	Image    *citadel.Image `json:"image,omitempty" gorethink:"image"`
+	// Count is the number of containers launched per run
+	Count   int       `json:"count" gorethink:"count"`
+	Created time.Time `json:"created,omitempty" gorethink:"created"`
+	// LastRun is the scheduled time of the latest run
+	LastRun time.Time `json:"last_run,omitempty" gorethink:"last_run"`
+	// NextRun is the next scheduled time; it is computed and not stored
+	NextRun time.Time `json:"next_run,omitempty" gorethink:"-"`
+}
+
+// JobRun is the history of one scheduled run of a job
+type JobRun struct {
+	ID    string `json:"id,omitempty" gorethink:"id,omitempty"`
+	JobID string `json:"job_id,omitempty" gorethink:"job_id"`
+	// Scheduled is the time the run was due; Started is when its
+	// containers were launched
+	Scheduled time.Time `json:"scheduled,omitempty" gorethink:"scheduled"`
+	Started   time.Time `json:"started,omitempty" gorethink:"started"`
+	// Finished is zero while containers of the run are running
+	Finished   time.Time `json:"finished,omitempty" gorethink:"finished"`
+	Status     string    `json:"status,omitempty" gorethink:"status"`
+	Containers []string  `json:"containers,omitempty" gorethink:"containers"`
+	// ExitCodes are the exit codes of the exited containers by id
+	ExitCodes map[string]int `json:"exit_codes,omitempty" gorethink:"exit_codes"`
+	Error     string         `json:"error,omitempty" gorethink:"error"`
+}
+
+// jobEnv are the reserved variables job images may set: the launch settings
+// and the namespace and owner the controller places the job in
+var jobEnv = func() map[string]bool {
+	env := map[string]bool{NamespaceEnv: true, OwnerEnv: true}
+	for k := range ImageSettingsEnv {
+		env[k] = true
+	}
+	return env
+}()
+
+// Validate checks the job can be scheduled and run
+func (j *Job) Validate() error {
+	if _, err := ParseCronSchedule(j.Schedule); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidJob, err)
+	}
+	if j.Image == nil || j.Image.Name == "" {
+		return fmt.Errorf("%w: image is required", ErrInvalidJob)
+	}
+	if j.Count < 1 {
+		return fmt.Errorf("%w: count must be at least 1", ErrInvalidJob)
+	}
+	if err := ValidateEnvironment(j.Image.Environment, jobEnv); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidJob, err)
+	}
+	return nil
+}
+
+// JobRunID returns the id of the job run that launched a container or an
+// empty string
+func JobRunID(c *citadel.Container) string {
+	if c.Image == nil {
+		return ""
+	}
+	return c.Image.Environment[JobRunEnv]
+}
@@ -0,0 +1,118 @@
+package shipyard
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/citadel/citadel"
+)
+
+var (
+	ErrInvalidJoinToken   = errors.New("invalid or expired join token")
+	ErrInvalidJoinRequest = errors.New("invalid join request")
+)
+
+type (
+	// JoinToken lets a docker host register itself as an engine once.  Only
+	// a hash of the token is stored; the token is returned when it is
+	// created.
+	JoinToken struct {
+		ID    string `json:"id,omitempty" gorethink:"id,omitempty"`
+		Token string `json:"token,omitempty" gorethink:"-"`
+		Hash  string `json:"-" gorethink:"hash"`
+		// Labels are added to the labels of the engine joining with the
+		// token
+		Labels    []string  `json:"labels,omitempty" gorethink:"labels"`
+		CreatedBy string    `json:"created_by,omitempty" gorethink:"created_by"`
+		Created   time.Time `json:"created,omitempty" gorethink:"created"`
+		// ExpiresAt is when the token stops working; zero never expires
+		ExpiresAt time.Time `json:"expires_at,omitempty" gorethink:"expires_at"`
+		// ExpiresIn is the lifetime in seconds used when creating a token
+		ExpiresIn int64 `json:"expires_in,omitempty" gorethink:"-"`
+	}
+
+	// JoinRequest is sent by a docker host registering itself with a join
+	// token.  The engine gets the labels of the token only so hosts can not
+	// place themselves in pools or zones.
+	JoinRequest struct {
+		Token string `json:"token"`
+		// ID names the engine, usually the hostname; it must be unique in
+		// the cluster
+		ID string `json:"id"`
+		// Addr is the docker api url the controller connects to
+		Addr           string  `json:"addr"`
+		Cpus           float64 `json:"cpus"`
+		Memory         float64 `json:"memory"`
+		SSLCertificate string  `json:"ssl_cert,omitempty"`
+		SSLKey         string  `json:"ssl_key,omitempty"`
+		CACertificate  string  `json:"ca_cert,omitempty"`
+	}
+)
+
+// NewJoinToken returns a random join token
+func NewJoinToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashJoinToken returns the stored form of a join token
+func HashJoinToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+// Expired reports whether the token has expired at now
+func (t *JoinToken) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)
+}
+
+func (j *JoinRequest) Validate() error {
+	if j.Token == "" {
+		return ErrInvalidJoinToken
+	}
+	if j.ID == "" {
+		return fmt.Errorf("%w: id is required", ErrInvalidJoinRequest)
+	}
+	u, err := url.Parse(j.Addr)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: addr must be an http or https url", ErrInvalidJoinRequest)
+	}
+	if j.Cpus <= 0 || j.Memory <= 0 {
+		return fmt.Errorf("%w: cpus and memory must be positive", ErrInvalidJoinRequest)
+	}
+	certs := &EngineCertificates{
+		CACertificate:  j.CACertificate,
+		SSLCertificate: j.SSLCertificate,
+		SSLKey:         j.SSLKey,
+	}
+	if err := certs.Validate(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidJoinRequest, err)
+	}
+	return nil
+}
+
+// Engine returns the engine registered for the request with the labels of
+// the token it joins with
+func (j *JoinRequest) Engine(token *JoinToken) *Engine {
+	return &Engine{
+		SSLCertificate: j.SSLCertificate,
+		SSLKey:         j.SSLKey,
+		CACertificate:  j.CACertificate,
+		Engine: &citadel.Engine{
+			ID:     j.ID,
+			Addr:   j.Addr,
+			Cpus:   j.Cpus,
+			Memory: j.Memory,
+			Labels: append([]string{}, token.Labels...),
+		},
+		Health: &Health{Status: "pending"},
+	}
+}
@@ -0,0 +1,62 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJoinRequestValidate(t *testing.T) {
+	valid := JoinRequest{Token: "t", ID: "node-1", Addr: "http://10.0.0.5:2375", Cpus: 2, Memory: 1024}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	noToken := valid
+	noToken.Token = ""
+	if err := noToken.Validate(); !errors.Is(err, ErrInvalidJoinToken) {
+		t.Errorf("expected ErrInvalidJoinToken; got %v", err)
+	}
+	for _, f := range []func(*JoinRequest){
+		func(j *JoinRequest) { j.ID = "" },
+		func(j *JoinRequest) { j.Addr = "10.0.0.5:2375" },
+		func(j *JoinRequest) { j.Addr = "unix:///var/run/docker.sock" },
+		func(j *JoinRequest) { j.Cpus = 0 },
+		func(j *JoinRequest) { j.SSLCertificate = "cert" },
+		func(j *JoinRequest) { j.SSLCertificate, j.SSLKey = testCertificate(t, "node-1") },
+	} {
+		req := valid
+		f(&req)
+		if err := req.Validate(); !errors.Is(err, ErrInvalidJoinRequest) {
+			t.Errorf("%+v: expected ErrInvalidJoinRequest; got %v", req, err)
+		}
+	}
+}
+
+func TestJoinRequestEngine(t *testing.T) {
+	req := &JoinRequest{ID: "node-1", Addr: "http://10.0.0.5:2375", Cpus: 2, Memory: 1024}
+	engine := req.Engine(&JoinToken{Labels: []string{"zone=east", "build"}})
+	if engine.Engine.ID != "node-1" || engine.Engine.Addr != req.Addr || engine.Health.Status != "pending" {
+		t.Fatalf("unexpected engine %+v", engine.Engine)
+	}
+	if len(engine.Engine.Labels) != 2 || engine.Engine.Labels[0] != "zone=east" {
+		t.Errorf("expected the token labels; got %v", engine.Engine.Labels)
+	}
+}
+
+func TestJoinToken(t *testing.T) {
+	a, err := NewJoinToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewJoinToken()
+	if a == b || HashJoinToken(a) == HashJoinToken(b) || HashJoinToken(a) == a {
+		t.Error("expected distinct tokens and hashes")
+	}
+	now := time.Now()
+	if (&JoinToken{}).Expired(now) {
+		t.Error("expected tokens without expiry to stay valid")
+	}
+	if !(&JoinToken{ExpiresAt: now}).Expired(now) {
+		t.Error("expected the token to expire at ExpiresAt")
+	}
+}
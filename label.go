@@ -0,0 +1,127 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	// LabelsEnv holds the json encoded labels of a container.  Unlike
+	// citadel image labels, which constrain placement, container labels are
+	// arbitrary metadata for selecting containers.
+	LabelsEnv = "_SHIPYARD_LABELS"
+)
+
+var (
+	ErrInvalidLabel    = errors.New("invalid label")
+	ErrInvalidSelector = errors.New("invalid label selector")
+)
+
+// Selector selects containers by their labels.  It uses the constraint
+// syntax, i.e. "tier=web,env!=dev"; a key alone requires the key to be set
+// to an empty value.
+type Selector []*Constraint
+
+// ParseSelector parses a comma separated label selector
+func ParseSelector(expr string) (Selector, error) {
+	constraints, err := ParseConstraints(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSelector, err)
+	}
+	return Selector(constraints), nil
+}
+
+// Match reports whether the labels of the container satisfy every
+// constraint of the selector; an empty selector matches every container
+func (s Selector) Match(c *citadel.Container) bool {
+	labels := ContainerLabels(c)
+	for _, constraint := range s {
+		if !constraint.Match(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Selector) String() string {
+	parts := []string{}
+	for _, c := range s {
+		parts = append(parts, c.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// ContainerLabels returns the labels of a container; containers without
+// labels return an empty map
+func ContainerLabels(c *citadel.Container) map[string]string {
+	if c == nil {
+		return map[string]string{}
+	}
+	labels, err := ImageLabels(c.Image)
+	if err != nil {
+		return map[string]string{}
+	}
+	return labels
+}
+
+// FormatLabels returns the labels as sorted key=value pairs separated by
+// commas, the form used by selectors
+func FormatLabels(labels map[string]string) string {
+	parts := []string{}
+	for k, v := range labels {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// ImageLabels returns the labels given to containers of the image
+func ImageLabels(image *citadel.Image) (map[string]string, error) {
+	labels := map[string]string{}
+	if image == nil || image.Environment[LabelsEnv] == "" {
+		return labels, nil
+	}
+	if err := json.Unmarshal([]byte(image.Environment[LabelsEnv]), &labels); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidLabel, err)
+	}
+	if err := validateLabels(labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// SetLabels sets the labels of containers of the image; an empty map
+// removes them
+func SetLabels(image *citadel.Image, labels map[string]string) error {
+	if image.Environment == nil {
+		image.Environment = map[string]string{}
+	}
+	if len(labels) == 0 {
+		delete(image.Environment, LabelsEnv)
+		return nil
+	}
+	if err := validateLabels(labels); err != nil {
+		return err
+	}
+	b, err := json.Marshal(labels)
+	if err != nil {
+		return err
+	}
+	image.Environment[LabelsEnv] = string(b)
+	return nil
+}
+
+// validateLabels checks label keys can be used in selectors
+func validateLabels(labels map[string]string) error {
+	for k := range labels {
+		if k == "" || strings.ContainsAny(k, "=!, ") {
+			return fmt.Errorf("%w: key %q must not be empty or contain '=', '!', ',' or spaces", ErrInvalidLabel, k)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestSelectorMatch(t *testing.T) {
+	img := &citadel.Image{Name: "nginx"}
+	if err := SetLabels(img, map[string]string{"tier": "web", "env": "prod", "canary": ""}); err != nil {
+		t.Fatal(err)
+	}
+	c := &citadel.Container{Image: img}
+	tests := []struct {
+		selector string
+		match    bool
+	}{
+		{"", true},
+		{"tier=web", true},
+		{"tier=web,env=prod", true},
+		{"tier=web,env!=prod", false},
+		{"env!=dev", true},
+		{"region!=eu", true},
+		{"canary", true},
+		{"tier=db", false},
+		{"region=eu", false},
+	}
+	for _, test := range tests {
+		s, err := ParseSelector(test.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if match := s.Match(c); match != test.match {
+			t.Errorf("%q: expected match %v; got %v", test.selector, test.match, match)
+		}
+	}
+	if s, _ := ParseSelector("tier=web"); s.Match(&citadel.Container{Image: &citadel.Image{Name: "nginx"}}) {
+		t.Error("expected containers without labels not to match")
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	if s := FormatLabels(map[string]string{"tier": "web", "env": "prod"}); s != "env=prod,tier=web" {
+		t.Errorf("expected the labels sorted by key; received %q", s)
+	}
+	if s := FormatLabels(nil); s != "" {
+		t.Errorf("expected no labels to format empty; received %q", s)
+	}
+}
+
+func TestSetLabels(t *testing.T) {
+	img := &citadel.Image{Name: "app"}
+	if err := SetLabels(img, map[string]string{"tier": "web"}); err != nil {
+		t.Fatal(err)
+	}
+	labels, err := ImageLabels(img)
+	if err != nil || labels["tier"] != "web" {
+		t.Fatalf("unexpected labels %v, %v", labels, err)
+	}
+	for _, key := range []string{"", "a=b", "a,b", "a b"} {
+		if err := SetLabels(img, map[string]string{key: "x"}); !errors.Is(err, ErrInvalidLabel) {
+			t.Errorf("expected ErrInvalidLabel for %q; got %v", key, err)
+		}
+	}
+	if err := SetLabels(img, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := img.Environment[LabelsEnv]; ok {
+		t.Fatal("expected the labels to be removed")
+	}
+}
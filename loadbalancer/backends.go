@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+const (
+	// DomainEnv lists the comma separated domains a container serves
+	DomainEnv = "SHIPYARD_LB_DOMAIN"
+	// PortEnv is the container port traffic is sent to; it may be omitted
+	// for containers publishing a single port
+	PortEnv = "SHIPYARD_LB_PORT"
+	// DomainLabel and PortLabel are container labels that may be used in
+	// place of the environment variables
+	DomainLabel = "lb.domain"
+	PortLabel   = "lb.port"
+)
+
+// domainPattern matches the host names, optionally with a leading
+// wildcard label, that are written into the proxy configuration
+var domainPattern = regexp.MustCompile(`^(\*\.)?([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)*[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// Backend is a domain and the addresses of the containers serving it
+type Backend struct {
+	Domain  string
+	Servers []string
+}
+
+// Name is the domain made safe for use as a configuration identifier
+func (b *Backend) Name() string {
+	return strings.NewReplacer(".", "_", "*", "wildcard", "-", "_").Replace(b.Domain)
+}
+
+// Backends groups the running containers with a domain by domain.  The
+// result is sorted so unchanged containers render identical configuration.
+func Backends(containers []*citadel.Container) []*Backend {
+	byDomain := map[string]*Backend{}
+	for _, c := range containers {
+		if c.State != "running" || c.Image == nil {
+			continue
+		}
+		domains := lbSetting(c, DomainEnv, DomainLabel)
+		if domains == "" {
+			continue
+		}
+		addr, err := containerAddr(c)
+		if err != nil {
+			logger.Warnf("skipping container %s: %s", shortID(c.ID), err)
+			continue
+		}
+		for _, d := range strings.Split(domains, ",") {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if d == "" {
+				continue
+			}
+			if len(d) > 253 || !domainPattern.MatchString(d) {
+				logger.Warnf("skipping invalid domain %q of container %s", d, shortID(c.ID))
+				continue
+			}
+			b, ok := byDomain[d]
+			if !ok {
+				b = &Backend{Domain: d}
+				byDomain[d] = b
+			}
+			b.Servers = append(b.Servers, addr)
+		}
+	}
+	backends := []*Backend{}
+	for _, b := range byDomain {
+		sort.Strings(b.Servers)
+		backends = append(backends, b)
+	}
+	sort.Slice(backends, func(i, j int) bool {
+		return backends[i].Domain < backends[j].Domain
+	})
+	return backends
+}
+
+// containerAddr returns the engine host and host port the container
+// publishes its load balanced port on
+func containerAddr(c *citadel.Container) (string, error) {
+	var port *citadel.Port
+	if p := lbSetting(c, PortEnv, PortLabel); p != "" {
+		containerPort, err := strconv.Atoi(p)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s %q", PortEnv, p)
+		}
+		for _, cp := range c.Ports {
+			if cp.ContainerPort == containerPort {
+				port = cp
+				break
+			}
+		}
+	} else if len(c.Ports) == 1 {
+		port = c.Ports[0]
+	}
+	if port == nil || port.Port == 0 {
+		return "", fmt.Errorf("no published port to balance")
+	}
+	host := port.HostIp
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		if c.Engine == nil {
+			return "", fmt.Errorf("unknown engine")
+		}
+		h, err := engineHost(c.Engine.Addr)
+		if err != nil {
+			return "", err
+		}
+		host = h
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port.Port)), nil
+}
+
+// lbSetting returns a setting from the container environment or, when
+// unset, from its labels
+func lbSetting(c *citadel.Container, env string, label string) string {
+	if v := c.Image.Environment[env]; v != "" {
+		return v
+	}
+	return shipyard.ContainerLabels(c)[label]
+}
+
+// engineHost returns the host of an engine address such as
+// tcp://10.0.0.1:2375
+func engineHost(addr string) (string, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", err
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("invalid engine address %s", addr)
+	}
+	return u.Hostname(), nil
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+func TestBackends(t *testing.T) {
+	eng := &citadel.Engine{ID: "e1", Addr: "tcp://10.0.0.1:2375"}
+	web := func(id string, hostPort int, env map[string]string) *citadel.Container {
+		return &citadel.Container{
+			ID:     id,
+			Engine: eng,
+			State:  "running",
+			Image:  &citadel.Image{Name: "nginx", Environment: env},
+			Ports:  []*citadel.Port{{Proto: "tcp", HostIp: "0.0.0.0", Port: hostPort, ContainerPort: 80}},
+		}
+	}
+	stopped := web("3", 49155, map[string]string{DomainEnv: "example.com"})
+	stopped.State = "stopped"
+	labeled := web("5", 49157, nil)
+	if err := shipyard.SetLabels(labeled.Image, map[string]string{DomainLabel: "example.com", PortLabel: "80"}); err != nil {
+		t.Fatal(err)
+	}
+	backends := Backends([]*citadel.Container{
+		web("1", 49153, map[string]string{DomainEnv: "example.com, www.example.com", PortEnv: "80"}),
+		web("2", 49154, map[string]string{DomainEnv: "example.com"}),
+		stopped,
+		web("4", 49156, nil),
+		labeled,
+		web("6", 49158, map[string]string{DomainEnv: "evil.com;}, server {"}),
+		web("7", 49159, map[string]string{DomainEnv: "bad domain.com"}),
+	})
+	if len(backends) != 2 {
+		t.Fatalf("expected 2 backends; received %d", len(backends))
+	}
+	b := backends[0]
+	if b.Domain != "example.com" || strings.Join(b.Servers, " ") != "10.0.0.1:49153 10.0.0.1:49154 10.0.0.1:49157" {
+		t.Errorf("unexpected backend %+v", b)
+	}
+	if backends[1].Domain != "www.example.com" || len(backends[1].Servers) != 1 {
+		t.Errorf("unexpected backend %+v", backends[1])
+	}
+}
+
+func TestRender(t *testing.T) {
+	backends := []*Backend{{Domain: "www.example.com", Servers: []string{"10.0.0.1:49153"}}}
+	for _, proxy := range []string{ProxyNginx, ProxyHAProxy} {
+		cfg, err := Render(proxy, 80, backends)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(cfg), "10.0.0.1:49153") || !strings.Contains(string(cfg), "www_example_com") {
+			t.Errorf("unexpected %s configuration:\n%s", proxy, cfg)
+		}
+	}
+	if _, err := Render("apache", 80, backends); err == nil {
+		t.Error("expected unknown proxy to be rejected")
+	}
+}
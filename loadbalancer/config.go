@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+const (
+	ProxyNginx   = "nginx"
+	ProxyHAProxy = "haproxy"
+)
+
+var (
+	nginxTemplate = template.Must(template.New("nginx").Parse(`# generated by the shipyard load balancer; changes are overwritten
+events {
+    worker_connections 1024;
+}
+
+http {
+    proxy_set_header Host $host;
+    proxy_set_header X-Real-IP $remote_addr;
+    proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+{{range .Backends}}
+    upstream {{.Name}} {
+{{- range .Servers}}
+        server {{.}};
+{{- end}}
+    }
+
+    server {
+        listen {{$.Port}};
+        server_name {{.Domain}};
+
+        location / {
+            proxy_pass http://{{.Name}};
+        }
+    }
+{{end}}
+    server {
+        listen {{.Port}} default_server;
+        return 503;
+    }
+}
+`))
+
+	haproxyTemplate = template.Must(template.New("haproxy").Parse(`# generated by the shipyard load balancer; changes are overwritten
+global
+    maxconn 4096
+
+defaults
+    mode http
+    option forwardfor
+    timeout connect 5s
+    timeout client 60s
+    timeout server 60s
+
+frontend http
+    bind *:{{.Port}}
+{{- range .Backends}}
+    acl host_{{.Name}} hdr(host) -i {{.Domain}} {{.Domain}}:{{$.Port}}
+    use_backend {{.Name}} if host_{{.Name}}
+{{- end}}
+{{range .Backends}}
+backend {{.Name}}
+    balance roundrobin
+{{- $name := .Name}}
+{{- range $i, $s := .Servers}}
+    server {{$name}}_{{$i}} {{$s}} check
+{{- end}}
+{{end}}`))
+)
+
+// Render returns the proxy configuration for the backends
+func Render(proxy string, port int, backends []*Backend) ([]byte, error) {
+	var t *template.Template
+	switch proxy {
+	case ProxyNginx:
+		t = nginxTemplate
+	case ProxyHAProxy:
+		t = haproxyTemplate
+	default:
+		return nil, fmt.Errorf("unknown proxy %s", proxy)
+	}
+	buf := &bytes.Buffer{}
+	data := struct {
+		Port     int
+		Backends []*Backend
+	}{
+		Port:     port,
+		Backends: backends,
+	}
+	if err := t.Execute(buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
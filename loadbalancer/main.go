@@ -0,0 +1,147 @@
+// Command loadbalancer is a shipyard extension that routes http traffic for
+// each domain to the containers serving it.  Containers opt in with the
+// SHIPYARD_LB_DOMAIN and SHIPYARD_LB_PORT environment variables or the
+// lb.domain and lb.port labels; the nginx
+// or haproxy configuration is regenerated and reloaded as containers start
+// and stop anywhere in the cluster.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/shipyard/shipyard"
+	"github.com/shipyard/shipyard/client"
+)
+
+const (
+	// settle is how long events are collected before the configuration is
+	// regenerated
+	settle = time.Second
+)
+
+var (
+	shipyardURL     string
+	serviceKey      string
+	allowInsecure   bool
+	proxy           string
+	configPath      string
+	listenPort      int
+	startCmd        string
+	reloadCmd       string
+	refreshInterval time.Duration
+	selector        string
+	logger          = logrus.New()
+)
+
+func init() {
+	flag.StringVar(&shipyardURL, "shipyard-url", "http://shipyard:8080", "shipyard controller url")
+	flag.StringVar(&serviceKey, "service-key", os.Getenv("SHIPYARD_SERVICE_KEY"), "service key with containers:read and events:read (or SHIPYARD_SERVICE_KEY)")
+	flag.BoolVar(&allowInsecure, "allow-insecure", false, "skip controller certificate verification")
+	flag.StringVar(&proxy, "proxy", ProxyNginx, "proxy to configure (nginx or haproxy)")
+	flag.StringVar(&configPath, "config", "/etc/nginx/nginx.conf", "path of the generated configuration")
+	flag.IntVar(&listenPort, "port", 80, "port the proxy listens on")
+	flag.StringVar(&startCmd, "start-cmd", "", "command starting the proxy once the first configuration is written")
+	flag.StringVar(&reloadCmd, "reload-cmd", "nginx -s reload", "command reloading the proxy configuration")
+	flag.DurationVar(&refreshInterval, "refresh-interval", 30*time.Second, "interval of full refreshes in case events are missed")
+	flag.StringVar(&selector, "selector", "", "only balance containers with matching labels, i.e. tier=web,env!=dev")
+}
+
+type loadBalancer struct {
+	manager  *client.Manager
+	selector shipyard.Selector
+	started  bool
+	last     []byte
+}
+
+// update regenerates the configuration and reloads the proxy if it changed
+func (lb *loadBalancer) update() {
+	containers, err := lb.manager.QueryContainers(&shipyard.ContainerQuery{Selector: lb.selector})
+	if err != nil {
+		logger.Warnf("error getting containers: %s", err)
+		return
+	}
+	backends := Backends(containers)
+	cfg, err := Render(proxy, listenPort, backends)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if lb.started && bytes.Equal(cfg, lb.last) {
+		return
+	}
+	if err := ioutil.WriteFile(configPath, cfg, 0644); err != nil {
+		logger.Errorf("error writing configuration: %s", err)
+		return
+	}
+	lb.last = cfg
+	cmd := reloadCmd
+	if !lb.started && startCmd != "" {
+		cmd = startCmd
+	}
+	if cmd != "" {
+		if out, err := exec.Command("sh", "-c", cmd).CombinedOutput(); err != nil {
+			logger.Errorf("error running %s: %s: %s", cmd, err, out)
+			return
+		}
+	}
+	lb.started = true
+	logger.Infof("configured %d domains", len(backends))
+}
+
+// watch updates the configuration on cluster events until the event stream
+// closes
+func (lb *loadBalancer) watch() error {
+	events, err := lb.manager.StreamEvents(nil)
+	if err != nil {
+		return err
+	}
+	// events may have been missed while reconnecting
+	lb.update()
+	refresh := time.NewTicker(refreshInterval)
+	defer refresh.Stop()
+	var pending <-chan time.Time
+	for {
+		select {
+		case evt, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if evt.Container != nil && pending == nil {
+				pending = time.After(settle)
+			}
+		case <-pending:
+			pending = nil
+			lb.update()
+		case <-refresh.C:
+			lb.update()
+		}
+	}
+}
+
+func main() {
+	flag.Parse()
+	cfg := &client.ShipyardConfig{
+		Url:           shipyardURL,
+		ServiceKey:    serviceKey,
+		AllowInsecure: allowInsecure,
+	}
+	s, err := shipyard.ParseSelector(selector)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	lb := &loadBalancer{
+		manager:  client.NewManager(cfg),
+		selector: s,
+	}
+	for {
+		if err := lb.watch(); err != nil {
+			logger.Warnf("error watching events: %s", err)
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
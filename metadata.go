@@ -0,0 +1,90 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	// DescriptionEnv holds the description of a container
+	DescriptionEnv = "_SHIPYARD_DESCRIPTION"
+)
+
+var (
+	ErrInvalidContainerName = errors.New("invalid container name")
+)
+
+// ContainerMetadata are the mutable details of a container.  The controller
+// keeps them by container id and they replace the labels and description
+// the container was launched with.
+type ContainerMetadata struct {
+	ID          string            `json:"id,omitempty" gorethink:"id"`
+	Labels      map[string]string `json:"labels,omitempty" gorethink:"labels"`
+	Description string            `json:"description,omitempty" gorethink:"description"`
+}
+
+// ContainerPatch updates container metadata in place.  Labels are merged
+// into the current labels and a null value removes a label; a nil
+// description is left unchanged.
+type ContainerPatch struct {
+	Labels      map[string]*string `json:"labels,omitempty"`
+	Description *string            `json:"description,omitempty"`
+}
+
+// Apply returns the metadata of the container with the patch applied
+func (p *ContainerPatch) Apply(c *citadel.Container) (*ContainerMetadata, error) {
+	labels, err := ImageLabels(c.Image)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range p.Labels {
+		if v == nil {
+			delete(labels, k)
+			continue
+		}
+		labels[k] = *v
+	}
+	if err := validateLabels(labels); err != nil {
+		return nil, err
+	}
+	md := &ContainerMetadata{
+		ID:          c.ID,
+		Labels:      labels,
+		Description: ContainerDescription(c),
+	}
+	if p.Description != nil {
+		md.Description = *p.Description
+	}
+	return md, nil
+}
+
+// SetMetadata replaces the labels and description of the image
+func (md *ContainerMetadata) SetMetadata(image *citadel.Image) error {
+	if err := SetLabels(image, md.Labels); err != nil {
+		return err
+	}
+	if md.Description == "" {
+		delete(image.Environment, DescriptionEnv)
+	} else {
+		image.Environment[DescriptionEnv] = md.Description
+	}
+	return nil
+}
+
+// ContainerDescription returns the description of a container
+func ContainerDescription(c *citadel.Container) string {
+	if c == nil || c.Image == nil {
+		return ""
+	}
+	return c.Image.Environment[DescriptionEnv]
+}
+
+// ValidateContainerName checks a name can be given to a container
+func ValidateContainerName(name string) error {
+	if !secretNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: name must be letters, digits, '_', '.' or '-'", ErrInvalidContainerName)
+	}
+	return nil
+}
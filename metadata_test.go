@@ -0,0 +1,63 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestContainerPatchApply(t *testing.T) {
+	img := &citadel.Image{Name: "nginx"}
+	if err := SetLabels(img, map[string]string{"tier": "web", "env": "dev"}); err != nil {
+		t.Fatal(err)
+	}
+	c := &citadel.Container{ID: "abc", Image: img}
+
+	prod := "prod"
+	description := "frontend"
+	patch := &ContainerPatch{
+		Labels:      map[string]*string{"env": &prod, "tier": nil},
+		Description: &description,
+	}
+	md, err := patch.Apply(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.ID != "abc" || len(md.Labels) != 1 || md.Labels["env"] != "prod" {
+		t.Fatalf("unexpected metadata %+v", md)
+	}
+	if err := md.SetMetadata(c.Image); err != nil {
+		t.Fatal(err)
+	}
+	if ContainerDescription(c) != "frontend" {
+		t.Errorf("expected description frontend; got %q", ContainerDescription(c))
+	}
+	if labels := ContainerLabels(c); labels["env"] != "prod" || labels["tier"] != "" {
+		t.Errorf("unexpected labels %v", labels)
+	}
+
+	md, err = (&ContainerPatch{}).Apply(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Description != "frontend" || md.Labels["env"] != "prod" {
+		t.Errorf("expected an empty patch to keep metadata; got %+v", md)
+	}
+
+	invalid := "x"
+	if _, err := (&ContainerPatch{Labels: map[string]*string{"a=b": &invalid}}).Apply(c); !errors.Is(err, ErrInvalidLabel) {
+		t.Errorf("expected ErrInvalidLabel; got %v", err)
+	}
+}
+
+func TestValidateContainerName(t *testing.T) {
+	if err := ValidateContainerName("web-1"); err != nil {
+		t.Error(err)
+	}
+	for _, name := range []string{"", "web 1", "/web"} {
+		if err := ValidateContainerName(name); !errors.Is(err, ErrInvalidContainerName) {
+			t.Errorf("%q: expected ErrInvalidContainerName; got %v", name, err)
+		}
+	}
+}
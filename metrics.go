@@ -0,0 +1,78 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	MetricsContainers = "containers"
+	MetricsEngines    = "engines"
+
+	// DefaultMetricsInterval is how often the controller samples resource
+	// usage
+	DefaultMetricsInterval = 30 * time.Second
+	// DefaultContainerMetricsInterval is how often the samples of
+	// containers are stored
+	DefaultContainerMetricsInterval = 5 * time.Minute
+	// DefaultMetricsWindow is how long samples are kept
+	DefaultMetricsWindow = 24 * time.Hour
+)
+
+var ErrInvalidMetricsResource = errors.New("invalid metrics resource")
+
+type (
+	// MetricSample is the resource usage of a container, or the total of
+	// the running containers of an engine, at a point in time.  Disk and
+	// network bytes are counters since the containers started.
+	MetricSample struct {
+		Resource       string    `json:"resource,omitempty" gorethink:"resource"`
+		ResourceID     string    `json:"resource_id,omitempty" gorethink:"resource_id"`
+		Time           time.Time `json:"time" gorethink:"time"`
+		CpuPercent     float64   `json:"cpu_percent" gorethink:"cpu_percent"`
+		MemoryUsage    uint64    `json:"memory_usage" gorethink:"memory_usage"`
+		MemoryLimit    uint64    `json:"memory_limit" gorethink:"memory_limit"`
+		DiskReadBytes  uint64    `json:"disk_read_bytes" gorethink:"disk_read_bytes"`
+		DiskWriteBytes uint64    `json:"disk_write_bytes" gorethink:"disk_write_bytes"`
+		NetworkRxBytes uint64    `json:"network_rx_bytes" gorethink:"network_rx_bytes"`
+		NetworkTxBytes uint64    `json:"network_tx_bytes" gorethink:"network_tx_bytes"`
+		// Source is where container samples were read from:
+		// MetricsSourceDocker or MetricsSourceCAdvisor
+		Source string `json:"source,omitempty" gorethink:"source,omitempty"`
+		// Containers is the number of containers in an engine sample
+		Containers int `json:"containers,omitempty" gorethink:"containers,omitempty"`
+	}
+)
+
+// ValidateMetricsResource returns ErrInvalidMetricsResource unless resource
+// is MetricsContainers or MetricsEngines
+func ValidateMetricsResource(resource string) error {
+	switch resource {
+	case MetricsContainers, MetricsEngines:
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidMetricsResource, resource)
+}
+
+// EngineSample totals the samples of the containers of an engine, which is
+// identified by its name.  The memory limit is the memory of the engine in
+// MB.
+func EngineSample(engineID string, memory float64, t time.Time, containers []*MetricSample) *MetricSample {
+	s := &MetricSample{
+		Resource:    MetricsEngines,
+		ResourceID:  engineID,
+		Time:        t,
+		MemoryLimit: uint64(memory * 1024 * 1024),
+		Containers:  len(containers),
+	}
+	for _, c := range containers {
+		s.CpuPercent += c.CpuPercent
+		s.MemoryUsage += c.MemoryUsage
+		s.DiskReadBytes += c.DiskReadBytes
+		s.DiskWriteBytes += c.DiskWriteBytes
+		s.NetworkRxBytes += c.NetworkRxBytes
+		s.NetworkTxBytes += c.NetworkTxBytes
+	}
+	return s
+}
@@ -0,0 +1,42 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateMetricsResource(t *testing.T) {
+	for _, r := range []string{MetricsContainers, MetricsEngines} {
+		if err := ValidateMetricsResource(r); err != nil {
+			t.Errorf("%s: %s", r, err)
+		}
+	}
+	if err := ValidateMetricsResource("images"); !errors.Is(err, ErrInvalidMetricsResource) {
+		t.Errorf("expected ErrInvalidMetricsResource; received %v", err)
+	}
+}
+
+func TestEngineSample(t *testing.T) {
+	now := time.Now()
+	s := EngineSample("local", 2048, now, []*MetricSample{
+		{CpuPercent: 12.5, MemoryUsage: 100, DiskReadBytes: 1, NetworkTxBytes: 10},
+		{CpuPercent: 50, MemoryUsage: 200, DiskWriteBytes: 2, NetworkRxBytes: 20},
+	})
+	expected := MetricSample{
+		Resource:       MetricsEngines,
+		ResourceID:     "local",
+		Time:           now,
+		CpuPercent:     62.5,
+		MemoryUsage:    300,
+		MemoryLimit:    2048 * 1024 * 1024,
+		DiskReadBytes:  1,
+		DiskWriteBytes: 2,
+		NetworkRxBytes: 20,
+		NetworkTxBytes: 10,
+		Containers:     2,
+	}
+	if *s != expected {
+		t.Errorf("expected %+v; received %+v", expected, *s)
+	}
+}
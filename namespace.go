@@ -0,0 +1,87 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	// NamespaceEnv marks containers with the namespace they were launched
+	// in
+	NamespaceEnv = "_SHIPYARD_NAMESPACE"
+
+	// NamespaceHeader scopes an api request to a namespace
+	NamespaceHeader = "X-Shipyard-Namespace"
+)
+
+var (
+	ErrInvalidNamespace = errors.New("invalid namespace")
+
+	// NamespaceResources are the resources scoped to the namespace of a
+	// request.  Permissions on them are granted by the role of the account
+	// in the namespace; other resources always use the account role.
+	NamespaceResources = map[string]bool{
+		"applications": true,
+		"containers":   true,
+		"deployments":  true,
+		"events":       true,
+		"jobs":         true,
+		"servicekeys":  true,
+	}
+)
+
+// Namespace partitions a shared cluster between teams.  Requests scoped to
+// a namespace only see its containers, applications, jobs, events and
+// service keys, and what they launch belongs to it.
+type Namespace struct {
+	ID          string    `json:"id,omitempty" gorethink:"id,omitempty"`
+	Name        string    `json:"name,omitempty" gorethink:"name"`
+	Description string    `json:"description,omitempty" gorethink:"description"`
+	Created     time.Time `json:"created,omitempty" gorethink:"created"`
+}
+
+func (n *Namespace) Validate() error {
+	if !secretNamePattern.MatchString(n.Name) {
+		return fmt.Errorf("%w: name must be letters, digits, '_', '.' or '-'", ErrInvalidNamespace)
+	}
+	return nil
+}
+
+// ContainerNamespace returns the namespace of a container; containers
+// launched outside of namespaces return an empty string
+func ContainerNamespace(c *citadel.Container) string {
+	if c == nil {
+		return ""
+	}
+	return ImageNamespace(c.Image)
+}
+
+// ImageNamespace returns the namespace containers of the image are launched
+// in, such as the image of a job
+func ImageNamespace(image *citadel.Image) string {
+	if image == nil {
+		return ""
+	}
+	return image.Environment[NamespaceEnv]
+}
+
+// SetNamespace places the containers of the image in a namespace
+func SetNamespace(image *citadel.Image, namespace string) {
+	if image.Environment == nil {
+		image.Environment = map[string]string{}
+	}
+	if namespace == "" {
+		delete(image.Environment, NamespaceEnv)
+		return
+	}
+	image.Environment[NamespaceEnv] = namespace
+}
+
+// NamespaceRole returns the name of the role of the account in a namespace
+// or an empty string if it has none
+func (a *Account) NamespaceRole(namespace string) string {
+	return a.NamespaceRoles[namespace]
+}
@@ -0,0 +1,45 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestNamespaceValidate(t *testing.T) {
+	if err := (&Namespace{Name: "team-a"}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"", "team a", "team/a"} {
+		if err := (&Namespace{Name: name}).Validate(); !errors.Is(err, ErrInvalidNamespace) {
+			t.Errorf("expected ErrInvalidNamespace for %q; got %v", name, err)
+		}
+	}
+}
+
+func TestSetNamespace(t *testing.T) {
+	img := &citadel.Image{Name: "app"}
+	c := &citadel.Container{Image: img}
+	if ns := ContainerNamespace(c); ns != "" {
+		t.Fatalf("expected no namespace; got %q", ns)
+	}
+	SetNamespace(img, "dev")
+	if ns := ContainerNamespace(c); ns != "dev" {
+		t.Fatalf("expected namespace dev; got %q", ns)
+	}
+	SetNamespace(img, "")
+	if _, ok := img.Environment[NamespaceEnv]; ok {
+		t.Fatal("expected the namespace to be cleared")
+	}
+}
+
+func TestEventFilterNamespace(t *testing.T) {
+	f := &EventFilter{Namespace: "dev"}
+	if !f.Match(&Event{Type: "start", Namespace: "dev"}) {
+		t.Error("expected events in the namespace to match")
+	}
+	if f.Match(&Event{Type: "start"}) {
+		t.Error("expected events outside the namespace not to match")
+	}
+}
@@ -0,0 +1,96 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	NotifierSlack = "slack"
+	NotifierEmail = "email"
+)
+
+var (
+	ErrInvalidNotifier = errors.New("invalid notifier")
+)
+
+// Notifier sends a message for each matching cluster event to Slack or
+// over email
+type Notifier struct {
+	ID   string `json:"id,omitempty" gorethink:"id,omitempty"`
+	Name string `json:"name,omitempty" gorethink:"name"`
+	// Type is NotifierSlack or NotifierEmail
+	Type string `json:"type,omitempty" gorethink:"type"`
+	// MinSeverity skips events below the severity; empty sends all
+	MinSeverity string `json:"min_severity,omitempty" gorethink:"min_severity"`
+	// EventTypes limits the notifier to these types; empty sends all
+	EventTypes []string     `json:"event_types,omitempty" gorethink:"event_types"`
+	Slack      *SlackConfig `json:"slack,omitempty" gorethink:"slack,omitempty"`
+	Email      *EmailConfig `json:"email,omitempty" gorethink:"email,omitempty"`
+}
+
+// SlackConfig posts to a Slack incoming webhook
+type SlackConfig struct {
+	WebhookURL string `json:"webhook_url,omitempty" gorethink:"webhook_url"`
+	// Channel overrides the webhook default channel
+	Channel string `json:"channel,omitempty" gorethink:"channel"`
+}
+
+// EmailConfig sends mail through an SMTP server
+type EmailConfig struct {
+	// SMTPAddr is the server host:port
+	SMTPAddr string   `json:"smtp_addr,omitempty" gorethink:"smtp_addr"`
+	Username string   `json:"username,omitempty" gorethink:"username"`
+	Password string   `json:"password,omitempty" gorethink:"password"`
+	From     string   `json:"from,omitempty" gorethink:"from"`
+	To       []string `json:"to,omitempty" gorethink:"to"`
+}
+
+// Validate checks the notifier has the settings for its type
+func (n *Notifier) Validate() error {
+	if n.MinSeverity != "" && !ValidSeverity(n.MinSeverity) {
+		return fmt.Errorf("%w: unknown severity %s", ErrInvalidNotifier, n.MinSeverity)
+	}
+	switch n.Type {
+	case NotifierSlack:
+		if n.Slack == nil || n.Slack.WebhookURL == "" {
+			return fmt.Errorf("%w: slack webhook url is required", ErrInvalidNotifier)
+		}
+	case NotifierEmail:
+		if n.Email == nil || n.Email.SMTPAddr == "" || n.Email.From == "" || len(n.Email.To) == 0 {
+			return fmt.Errorf("%w: smtp address, from and to are required", ErrInvalidNotifier)
+		}
+	default:
+		return fmt.Errorf("%w: type must be slack or email", ErrInvalidNotifier)
+	}
+	return nil
+}
+
+// Match reports whether the notifier sends the event
+func (n *Notifier) Match(e *Event) bool {
+	severity := e.Severity
+	if severity == "" {
+		severity = EventSeverity(e.Type)
+	}
+	if !SeverityAtLeast(severity, n.MinSeverity) {
+		return false
+	}
+	f := &EventFilter{Types: n.EventTypes}
+	return f.Match(e)
+}
+
+// Redacted returns a copy of the notifier without credentials
+func (n *Notifier) Redacted() *Notifier {
+	r := *n
+	if n.Slack != nil {
+		slack := *n.Slack
+		slack.WebhookURL = ""
+		r.Slack = &slack
+	}
+	if n.Email != nil {
+		email := *n.Email
+		email.Password = ""
+		r.Email = &email
+	}
+	return &r
+}
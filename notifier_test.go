@@ -0,0 +1,40 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNotifierMatch(t *testing.T) {
+	n := &Notifier{MinSeverity: SeverityWarning}
+	if n.Match(&Event{Type: "start"}) {
+		t.Error("expected info event to be skipped")
+	}
+	if !n.Match(&Event{Type: "engine-unreachable"}) {
+		t.Error("expected critical event to match")
+	}
+	n.EventTypes = []string{"die"}
+	if n.Match(&Event{Type: "engine-unreachable"}) {
+		t.Error("expected event type filter to apply")
+	}
+}
+
+func TestNotifierValidate(t *testing.T) {
+	for _, n := range []*Notifier{
+		{Type: "pager"},
+		{Type: NotifierSlack},
+		{Type: NotifierEmail, Email: &EmailConfig{SMTPAddr: "mail:25", From: "shipyard@example.com"}},
+		{Type: NotifierSlack, Slack: &SlackConfig{WebhookURL: "https://hooks.slack.com/x"}, MinSeverity: "urgent"},
+	} {
+		if err := n.Validate(); !errors.Is(err, ErrInvalidNotifier) {
+			t.Errorf("expected ErrInvalidNotifier for %+v; received %v", n, err)
+		}
+	}
+	n := &Notifier{Type: NotifierSlack, Slack: &SlackConfig{WebhookURL: "https://hooks.slack.com/x"}}
+	if err := n.Validate(); err != nil {
+		t.Error(err)
+	}
+	if r := n.Redacted(); r.Slack.WebhookURL != "" || n.Slack.WebhookURL == "" {
+		t.Error("expected redacted copy without changing the notifier")
+	}
+}
@@ -0,0 +1,198 @@
+package shipyard
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type (
+	// APIRoute is an endpoint served by the controller
+	APIRoute struct {
+		Method string
+		// Path is the route template; variables are {name} or
+		// {name:pattern}
+		Path string
+		// Name identifies the operation, i.e. the name of its handler
+		Name string
+		// Permission is the role permission the route requires; empty
+		// for routes that do not check access
+		Permission string
+		// Public routes do not require authentication
+		Public bool
+	}
+
+	// OpenAPISpec is an OpenAPI 3 document describing the api
+	OpenAPISpec struct {
+		OpenAPI    string                                  `json:"openapi"`
+		Info       OpenAPIInfo                             `json:"info"`
+		Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+		Components OpenAPIComponents                       `json:"components"`
+		Security   []map[string][]string                   `json:"security"`
+	}
+
+	OpenAPIInfo struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	}
+
+	OpenAPIOperation struct {
+		OperationID string                      `json:"operationId"`
+		Summary     string                      `json:"summary"`
+		Tags        []string                    `json:"tags,omitempty"`
+		Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+		Responses   map[string]*OpenAPIResponse `json:"responses"`
+		// Security is empty for public operations, overriding the
+		// document default
+		Security []map[string][]string `json:"security,omitempty"`
+		// Permission is the role permission the operation requires
+		Permission string `json:"x-shipyard-permission,omitempty"`
+	}
+
+	OpenAPIParameter struct {
+		Name     string            `json:"name"`
+		In       string            `json:"in"`
+		Required bool              `json:"required"`
+		Schema   map[string]string `json:"schema"`
+	}
+
+	OpenAPIResponse struct {
+		Description string `json:"description"`
+	}
+
+	OpenAPIComponents struct {
+		SecuritySchemes map[string]*OpenAPISecurityScheme `json:"securitySchemes"`
+	}
+
+	OpenAPISecurityScheme struct {
+		Type        string `json:"type"`
+		In          string `json:"in"`
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+	}
+)
+
+var routeVariable = regexp.MustCompile(`{([^}:]+)(:[^}]*)?}`)
+
+// OpenAPIPath converts a route template to an OpenAPI path, dropping
+// variable patterns, and returns the names of its variables
+func OpenAPIPath(path string) (string, []string) {
+	names := []string{}
+	for _, m := range routeVariable.FindAllStringSubmatch(path, -1) {
+		names = append(names, m[1])
+	}
+	return routeVariable.ReplaceAllString(path, "{$1}"), names
+}
+
+// OperationSummary turns an operation name like addAccount into the
+// summary "add account"
+func OperationSummary(name string) string {
+	runes := []rune(name)
+	words := []string{}
+	word := []rune{}
+	for i, r := range runes {
+		if i > 0 && len(word) > 0 {
+			prev := runes[i-1]
+			// a new word starts after a lower case letter (addAccount,
+			// enable2FA) or at the end of an acronym (setGCPolicy)
+			if (unicode.IsUpper(r) || unicode.IsDigit(r)) && unicode.IsLower(prev) ||
+				unicode.IsUpper(r) && unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+				words = append(words, string(word))
+				word = nil
+			}
+		}
+		word = append(word, unicode.ToLower(r))
+	}
+	words = append(words, string(word))
+	return strings.Join(words, " ")
+}
+
+// NewOpenAPISpec documents routes as an OpenAPI 3 document.  Operations
+// are tagged with the first path element after /api.
+func NewOpenAPISpec(version string, routes []*APIRoute) *OpenAPISpec {
+	token := []map[string][]string{{"accessToken": {}}}
+	spec := &OpenAPISpec{
+		OpenAPI: "3.0.3",
+		Info: OpenAPIInfo{
+			Title:   "Shipyard",
+			Version: version,
+		},
+		Paths: map[string]map[string]*OpenAPIOperation{},
+		Components: OpenAPIComponents{
+			SecuritySchemes: map[string]*OpenAPISecurityScheme{
+				"accessToken": {
+					Type:        "apiKey",
+					In:          "header",
+					Name:        "X-Access-Token",
+					Description: "<username>:<token> from /auth/login",
+				},
+				"serviceKey": {
+					Type: "apiKey",
+					In:   "header",
+					Name: "X-Service-Key",
+				},
+			},
+		},
+		Security: append(token, map[string][]string{"serviceKey": {}}),
+	}
+	for _, r := range routes {
+		path, vars := OpenAPIPath(r.Path)
+		op := &OpenAPIOperation{
+			OperationID: r.Name,
+			Summary:     OperationSummary(r.Name),
+			Tags:        []string{routeTag(path)},
+			Responses: map[string]*OpenAPIResponse{
+				"default": {Description: "the response of the operation; errors are returned as text"},
+			},
+			Permission: r.Permission,
+		}
+		if r.Public {
+			op.Security = []map[string][]string{{}}
+		}
+		for _, v := range vars {
+			op.Parameters = append(op.Parameters, &OpenAPIParameter{
+				Name:     v,
+				In:       "path",
+				Required: true,
+				Schema:   map[string]string{"type": "string"},
+			})
+		}
+		if spec.Paths[path] == nil {
+			spec.Paths[path] = map[string]*OpenAPIOperation{}
+		}
+		spec.Paths[path][strings.ToLower(r.Method)] = op
+	}
+	return spec
+}
+
+// Operations returns the operations of the spec sorted by path and method
+func (s *OpenAPISpec) Operations() []*APIRoute {
+	routes := []*APIRoute{}
+	for path, ops := range s.Paths {
+		for method, op := range ops {
+			routes = append(routes, &APIRoute{
+				Method:     strings.ToUpper(method),
+				Path:       path,
+				Name:       op.OperationID,
+				Permission: op.Permission,
+				Public:     op.Security != nil,
+			})
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+func routeTag(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if parts[0] == "api" && len(parts) > 1 {
+		return parts[1]
+	}
+	return parts[0]
+}
@@ -0,0 +1,53 @@
+package shipyard
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOpenAPIPath(t *testing.T) {
+	path, vars := OpenAPIPath("/api/containers/{id}/exec/{execId}")
+	if path != "/api/containers/{id}/exec/{execId}" || !reflect.DeepEqual(vars, []string{"id", "execId"}) {
+		t.Errorf("unexpected path %s %v", path, vars)
+	}
+	if path, _ := OpenAPIPath("/api/images/{name:.*}"); path != "/api/images/{name}" {
+		t.Errorf("expected the pattern to be dropped; received %s", path)
+	}
+}
+
+func TestOperationSummary(t *testing.T) {
+	tests := map[string]string{
+		"addAccount":  "add account",
+		"enable2FA":   "enable 2fa",
+		"setGCPolicy": "set gc policy",
+		"containers":  "containers",
+	}
+	for name, expected := range tests {
+		if s := OperationSummary(name); s != expected {
+			t.Errorf("expected %q for %s; received %q", expected, name, s)
+		}
+	}
+}
+
+func TestNewOpenAPISpec(t *testing.T) {
+	spec := NewOpenAPISpec(VERSION, []*APIRoute{
+		{Method: "GET", Path: "/api/containers/{id}", Name: "inspectContainer", Permission: "containers:read"},
+		{Method: "DELETE", Path: "/api/containers/{id}", Name: "destroy", Permission: "containers:write"},
+		{Method: "POST", Path: "/auth/login", Name: "login", Public: true},
+	})
+	ops := spec.Paths["/api/containers/{id}"]
+	if len(ops) != 2 || ops["get"].Permission != "containers:read" || ops["get"].Tags[0] != "containers" {
+		t.Fatalf("unexpected operations %+v", ops)
+	}
+	if len(ops["delete"].Parameters) != 1 || ops["delete"].Parameters[0].Name != "id" {
+		t.Errorf("expected the id path parameter; received %+v", ops["delete"].Parameters)
+	}
+	if spec.Paths["/auth/login"]["post"].Security == nil {
+		t.Errorf("expected the public operation to override security")
+	}
+
+	routes := spec.Operations()
+	if len(routes) != 3 || routes[0].Name != "destroy" || !routes[2].Public {
+		t.Errorf("unexpected operations %+v", routes)
+	}
+}
@@ -0,0 +1,123 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// PermissionAll grants every action on every resource
+	PermissionAll = "*"
+
+	ActionRead  = "read"
+	ActionRun   = "run"
+	ActionWrite = "write"
+	// ActionAdmin grants every action on a resource
+	ActionAdmin = "admin"
+)
+
+var (
+	ErrInvalidPermission = errors.New("invalid permission")
+
+	// PermissionResources are the resources permissions apply to
+	PermissionResources = []string{
+		"containers",
+		"images",
+		"registries",
+		"engines",
+		"cluster",
+		"events",
+		"accounts",
+		"roles",
+		"servicekeys",
+		"extensions",
+		"webhookkeys",
+		"webhooks",
+		"notifiers",
+		"alerts",
+		"audit",
+		"applications",
+		"deployments",
+		"jobs",
+		"secrets",
+		"configs",
+		"templates",
+		"volumes",
+		"quotas",
+		"namespaces",
+		"gc",
+		"pools",
+		"backup",
+	}
+
+	// DefaultRolePermissions are used for the built in roles when they
+	// have no permissions stored
+	DefaultRolePermissions = map[string][]string{
+		"admin": {PermissionAll},
+		"user": {
+			"containers:admin",
+			"cluster:read",
+			"events:read",
+			"engines:admin",
+		},
+	}
+)
+
+// Permission returns the permission string for an action on a resource
+func Permission(resource string, action string) string {
+	return resource + ":" + action
+}
+
+// ValidatePermission checks that p names a known resource and action
+func ValidatePermission(p string) error {
+	if p == PermissionAll {
+		return nil
+	}
+	parts := strings.SplitN(p, ":", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("%w %q: expected resource:action", ErrInvalidPermission, p)
+	}
+	known := false
+	for _, r := range PermissionResources {
+		if r == parts[0] {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("%w %q: unknown resource %s", ErrInvalidPermission, p, parts[0])
+	}
+	switch parts[1] {
+	case ActionRead, ActionRun, ActionWrite, ActionAdmin, "*":
+		return nil
+	}
+	return fmt.Errorf("%w %q: unknown action %s", ErrInvalidPermission, p, parts[1])
+}
+
+// EffectivePermissions returns the role permissions, falling back to the
+// defaults for built in roles created before permissions existed
+func (r *Role) EffectivePermissions() []string {
+	if len(r.Permissions) == 0 {
+		return DefaultRolePermissions[r.Name]
+	}
+	return r.Permissions
+}
+
+// HasPermission reports whether the role grants p.  "*", resource:admin and
+// resource:* grant every action on the resource.
+func (r *Role) HasPermission(p string) bool {
+	return grants(r.EffectivePermissions(), p)
+}
+
+// grants reports whether any of perms grants p
+func grants(perms []string, p string) bool {
+	resource := strings.SplitN(p, ":", 2)[0]
+	for _, perm := range perms {
+		switch perm {
+		case PermissionAll, p, Permission(resource, ActionAdmin), Permission(resource, "*"):
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package shipyard
+
+import (
+	"testing"
+)
+
+func TestRoleHasPermission(t *testing.T) {
+	role := &Role{
+		Name:        "ci",
+		Permissions: []string{"containers:run", "containers:read", "engines:admin"},
+	}
+	tests := map[string]bool{
+		"containers:run":   true,
+		"containers:read":  true,
+		"containers:write": false,
+		"engines:write":    true,
+		"accounts:read":    false,
+		"":                 false,
+	}
+	for p, expected := range tests {
+		if role.HasPermission(p) != expected {
+			t.Errorf("expected %v for %s", expected, p)
+		}
+	}
+}
+
+func TestRoleDefaultPermissions(t *testing.T) {
+	admin := &Role{Name: "admin"}
+	if !admin.HasPermission("accounts:write") {
+		t.Error("expected admin to have all permissions")
+	}
+	user := &Role{Name: "user"}
+	if !user.HasPermission("containers:write") {
+		t.Error("expected user to manage containers")
+	}
+	if user.HasPermission("accounts:read") {
+		t.Error("expected user to not read accounts")
+	}
+}
+
+func TestValidatePermission(t *testing.T) {
+	for _, p := range []string{"*", "containers:run", "engines:admin", "events:*"} {
+		if err := ValidatePermission(p); err != nil {
+			t.Error(err)
+		}
+	}
+	for _, p := range []string{"containers", "foo:read", "containers:destroy"} {
+		if err := ValidatePermission(p); err == nil {
+			t.Errorf("expected error for %s", p)
+		}
+	}
+}
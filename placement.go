@@ -0,0 +1,153 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	// PlacementEnv holds the placement strategy of a container
+	PlacementEnv = "_SHIPYARD_PLACEMENT"
+
+	// PlacementSpread places containers on the least utilized engine
+	PlacementSpread = "spread"
+	// PlacementBinpack fills the most utilized engine that still fits
+	// the container, keeping other engines free for large containers
+	PlacementBinpack = "binpack"
+	// PlacementRandom places containers on any engine that fits them
+	PlacementRandom = "random"
+	// PlacementLabelAffinity places containers next to the most running
+	// containers sharing a label with them, spreading the rest
+	PlacementLabelAffinity = "label-affinity"
+
+	// DefaultPlacement is binpack, the way the citadel resource manager
+	// placed containers before strategies could be chosen
+	DefaultPlacement = PlacementBinpack
+)
+
+var (
+	ErrInvalidPlacement = errors.New("invalid placement strategy")
+	ErrNoEngineCapacity = errors.New("no resources available to schedule container")
+
+	PlacementStrategies = []string{
+		PlacementBinpack,
+		PlacementSpread,
+		PlacementRandom,
+		PlacementLabelAffinity,
+	}
+)
+
+// RunPreview is where the containers of a run would be placed
+type RunPreview struct {
+	// Placements are the engines receiving each container
+	Placements []*RunPlacement `json:"placements"`
+	// Engines are the accepting engines with the reservations of the
+	// placed containers added
+	Engines []*EngineLoad `json:"engines"`
+	// Unplaced is the number of containers no engine could receive
+	Unplaced int `json:"unplaced,omitempty"`
+	// Error is why the unplaced containers could not be placed
+	Error string `json:"error,omitempty"`
+}
+
+// RunPlacement is the engine a container would be placed on
+type RunPlacement struct {
+	Engine string  `json:"engine"`
+	Cpus   float64 `json:"cpus"`
+	Memory float64 `json:"memory"`
+}
+
+func ValidatePlacement(strategy string) error {
+	for _, s := range PlacementStrategies {
+		if s == strategy {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidPlacement, strategy)
+}
+
+// ContainerPlacement returns the placement strategy of an image; images
+// without one are placed with the controller default
+func ContainerPlacement(image *citadel.Image) string {
+	if image == nil {
+		return ""
+	}
+	return image.Environment[PlacementEnv]
+}
+
+// SetPlacement sets the strategy containers of the image are placed with
+func SetPlacement(image *citadel.Image, strategy string) error {
+	if strategy == "" {
+		return nil
+	}
+	if err := ValidatePlacement(strategy); err != nil {
+		return err
+	}
+	if image.Environment == nil {
+		image.Environment = map[string]string{}
+	}
+	image.Environment[PlacementEnv] = strategy
+	return nil
+}
+
+// LabelAffinity counts the containers sharing a label with image
+func LabelAffinity(image *citadel.Image, containers []*citadel.Container) int {
+	labels, err := ImageLabels(image)
+	if err != nil || len(labels) == 0 {
+		return 0
+	}
+	n := 0
+	for _, c := range containers {
+		for k, v := range ContainerLabels(c) {
+			if labels[k] == v {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+// PlaceContainer picks the engine a container reserving cpus and memory
+// is placed on with strategy.  Engines the container does not fit on are
+// skipped.  Affinity holds the label affinity of each engine (see
+// LabelAffinity) and is only used by the label-affinity strategy.
+func PlaceContainer(strategy string, loads []*EngineLoad, cpus, memory float64, affinity map[string]int) (string, error) {
+	fits := []*EngineLoad{}
+	for _, l := range loads {
+		if l.Cpus < cpus || l.Memory < memory || l.utilization(cpus, memory) > 100 {
+			continue
+		}
+		fits = append(fits, l)
+	}
+	if len(fits) == 0 {
+		return "", ErrNoEngineCapacity
+	}
+	best := fits[0]
+	switch strategy {
+	case PlacementRandom:
+		best = fits[rand.Intn(len(fits))]
+	case PlacementBinpack:
+		for _, l := range fits[1:] {
+			if l.utilization(cpus, memory) > best.utilization(cpus, memory) {
+				best = l
+			}
+		}
+	case PlacementLabelAffinity:
+		for _, l := range fits[1:] {
+			if a, b := affinity[l.ID], affinity[best.ID]; a > b || a == b && l.utilization(cpus, memory) < best.utilization(cpus, memory) {
+				best = l
+			}
+		}
+	default:
+		for _, l := range fits[1:] {
+			if l.utilization(cpus, memory) < best.utilization(cpus, memory) {
+				best = l
+			}
+		}
+	}
+	return best.ID, nil
+}
@@ -0,0 +1,76 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestPlaceContainer(t *testing.T) {
+	loads := []*EngineLoad{
+		{ID: "empty", Cpus: 4, Memory: 4096},
+		{ID: "half", Cpus: 4, Memory: 4096, ReservedCpus: 2, ReservedMemory: 2048},
+		{ID: "full", Cpus: 4, Memory: 4096, ReservedCpus: 4, ReservedMemory: 4096},
+	}
+	tests := []struct {
+		strategy string
+		affinity map[string]int
+		expected string
+	}{
+		{PlacementSpread, nil, "empty"},
+		{PlacementBinpack, nil, "half"},
+		{PlacementLabelAffinity, map[string]int{"half": 2}, "half"},
+		{PlacementLabelAffinity, map[string]int{"full": 5}, "empty"},
+	}
+	for _, test := range tests {
+		id, err := PlaceContainer(test.strategy, loads, 1, 1024, test.affinity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != test.expected {
+			t.Errorf("%s: expected %s; received %s", test.strategy, test.expected, id)
+		}
+	}
+	id, err := PlaceContainer(PlacementRandom, loads, 1, 1024, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == "full" {
+		t.Error("expected random placement to skip the full engine")
+	}
+	if _, err := PlaceContainer(PlacementSpread, loads, 8, 1024, nil); err != ErrNoEngineCapacity {
+		t.Errorf("expected ErrNoEngineCapacity; received %v", err)
+	}
+}
+
+func TestSetPlacement(t *testing.T) {
+	image := &citadel.Image{Name: "worker"}
+	if err := SetPlacement(image, PlacementBinpack); err != nil {
+		t.Fatal(err)
+	}
+	if p := ContainerPlacement(image); p != PlacementBinpack {
+		t.Errorf("expected %s; received %s", PlacementBinpack, p)
+	}
+	if err := SetPlacement(image, "fastest"); !errors.Is(err, ErrInvalidPlacement) {
+		t.Errorf("expected ErrInvalidPlacement; received %v", err)
+	}
+}
+
+func TestLabelAffinity(t *testing.T) {
+	image := &citadel.Image{Name: "web"}
+	if err := SetLabels(image, map[string]string{"tier": "frontend"}); err != nil {
+		t.Fatal(err)
+	}
+	frontend := &citadel.Image{Name: "nginx"}
+	if err := SetLabels(frontend, map[string]string{"tier": "frontend", "env": "prod"}); err != nil {
+		t.Fatal(err)
+	}
+	containers := []*citadel.Container{
+		{Image: frontend},
+		{Image: &citadel.Image{Name: "redis"}},
+	}
+	if n := LabelAffinity(image, containers); n != 1 {
+		t.Errorf("expected 1 container with a shared label; received %d", n)
+	}
+}
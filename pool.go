@@ -0,0 +1,126 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	// PoolEnv holds the engine pool containers are placed in
+	PoolEnv = "_SHIPYARD_POOL"
+)
+
+var (
+	ErrInvalidEnginePool = errors.New("invalid engine pool")
+)
+
+// EnginePool is a named group of engines, such as a zone or a tier.
+// Engines belong to the pool while their labels satisfy its constraints.
+type EnginePool struct {
+	ID          string `json:"id,omitempty" gorethink:"id,omitempty"`
+	Name        string `json:"name,omitempty" gorethink:"name"`
+	Description string `json:"description,omitempty" gorethink:"description"`
+	// Constraints are engine label expressions (see ParseConstraints)
+	Constraints []string `json:"constraints,omitempty" gorethink:"constraints"`
+	// Engines are the ids of the engines in the pool; they are reported
+	// by the controller
+	Engines []string `json:"engines,omitempty" gorethink:"-"`
+}
+
+func (p *EnginePool) Validate() error {
+	if !secretNamePattern.MatchString(p.Name) {
+		return fmt.Errorf("%w: name must be letters, digits, '_', '.' or '-'", ErrInvalidEnginePool)
+	}
+	if len(p.Constraints) == 0 {
+		return fmt.Errorf("%w: at least one constraint is required", ErrInvalidEnginePool)
+	}
+	for _, c := range p.Constraints {
+		if _, err := ParseConstraints(c); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidEnginePool, err)
+		}
+	}
+	return nil
+}
+
+// Match reports whether engine labels satisfy the pool constraints
+func (p *EnginePool) Match(labels []string) bool {
+	parsed := ParseLabels(labels)
+	for _, expr := range p.Constraints {
+		constraints, err := ParseConstraints(expr)
+		if err != nil {
+			return false
+		}
+		for _, c := range constraints {
+			if !c.Match(parsed) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ContainerPool returns the engine pool of an image; images without a
+// pool may be placed on any engine
+func ContainerPool(image *citadel.Image) string {
+	if image == nil {
+		return ""
+	}
+	return image.Environment[PoolEnv]
+}
+
+// SetPool places the containers of the image in an engine pool
+func SetPool(image *citadel.Image, pool string) {
+	if pool == "" {
+		return
+	}
+	if image.Environment == nil {
+		image.Environment = map[string]string{}
+	}
+	image.Environment[PoolEnv] = pool
+}
+
+// SpreadAllows reports whether a replica may be placed in zone given the
+// replicas already running in each zone.  Replicas go to the zones with
+// the fewest of them, so they stay balanced across zones.
+func SpreadAllows(zone string, counts map[string]int) bool {
+	n, ok := counts[zone]
+	if !ok {
+		return false
+	}
+	for _, c := range counts {
+		if c < n {
+			return false
+		}
+	}
+	return true
+}
+
+// SpreadCounts counts the replicas of image running in each zone.  Zones
+// maps engine ids to their zone; every zone is counted even without
+// replicas.
+func SpreadCounts(image *citadel.Image, zones map[string]string, containers []*citadel.Container) map[string]int {
+	counts := map[string]int{}
+	for _, zone := range zones {
+		counts[zone] = 0
+	}
+	for _, c := range containers {
+		if c.Engine == nil {
+			continue
+		}
+		if zone, ok := zones[c.Engine.ID]; ok && isReplica(image, c) {
+			counts[zone]++
+		}
+	}
+	return counts
+}
+
+// validateSpreadBy checks an engine label key used to spread replicas
+func validateSpreadBy(key string) error {
+	if strings.TrimSpace(key) != key || strings.ContainsAny(key, "=!, ") {
+		return fmt.Errorf("%w: invalid spread label %q", ErrInvalidAffinity, key)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestEnginePoolValidate(t *testing.T) {
+	if err := (&EnginePool{Name: "zone-a", Constraints: []string{"zone=a"}}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+	invalid := []*EnginePool{
+		{Name: "zone a", Constraints: []string{"zone=a"}},
+		{Name: "zone-a"},
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); !errors.Is(err, ErrInvalidEnginePool) {
+			t.Errorf("%+v: expected ErrInvalidEnginePool; received %v", p, err)
+		}
+	}
+}
+
+func TestEnginePoolMatch(t *testing.T) {
+	p := &EnginePool{Name: "ssd-a", Constraints: []string{"zone=a", "ssd"}}
+	if !p.Match([]string{"zone=a", "ssd"}) {
+		t.Error("expected engine to match")
+	}
+	if p.Match([]string{"zone=a"}) {
+		t.Error("expected engine without ssd not to match")
+	}
+	if p.Match([]string{"zone=b", "ssd"}) {
+		t.Error("expected engine in zone b not to match")
+	}
+}
+
+func TestSpreadCounts(t *testing.T) {
+	image := &citadel.Image{Name: "worker"}
+	zones := map[string]string{"e1": "a", "e2": "a", "e3": "b", "e4": "c"}
+	containers := []*citadel.Container{
+		{Engine: &citadel.Engine{ID: "e1"}, Image: &citadel.Image{Name: "worker:latest"}},
+		{Engine: &citadel.Engine{ID: "e2"}, Image: &citadel.Image{Name: "worker"}},
+		{Engine: &citadel.Engine{ID: "e3"}, Image: &citadel.Image{Name: "worker"}},
+		{Engine: &citadel.Engine{ID: "e3"}, Image: &citadel.Image{Name: "redis"}},
+	}
+	counts := SpreadCounts(image, zones, containers)
+	if counts["a"] != 2 || counts["b"] != 1 || counts["c"] != 0 {
+		t.Fatalf("unexpected counts %v", counts)
+	}
+	if !SpreadAllows("c", counts) {
+		t.Error("expected the empty zone to be allowed")
+	}
+	if SpreadAllows("a", counts) || SpreadAllows("b", counts) {
+		t.Error("expected zones with replicas not to be allowed")
+	}
+	if SpreadAllows("d", counts) {
+		t.Error("expected an unknown zone not to be allowed")
+	}
+}
+
+func TestAffinitySpreadByValidate(t *testing.T) {
+	if err := (&Affinity{SpreadBy: "zone"}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Affinity{SpreadBy: "zone=a"}).Validate(); !errors.Is(err, ErrInvalidAffinity) {
+		t.Errorf("expected ErrInvalidAffinity; received %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	// OwnerEnv marks containers with the account that launched them
+	OwnerEnv = "_SHIPYARD_OWNER"
+)
+
+var (
+	ErrInvalidQuota  = errors.New("invalid quota")
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// Quota limits the running containers of an account, of all accounts with a
+// role together or of a namespace.  Zero limits are not enforced.
+type Quota struct {
+	ID   string `json:"id,omitempty" gorethink:"id,omitempty"`
+	Name string `json:"name,omitempty" gorethink:"name"`
+	// Account is the username the quota applies to
+	Account string `json:"account,omitempty" gorethink:"account"`
+	// Role is the role whose accounts share the quota
+	Role string `json:"role,omitempty" gorethink:"role"`
+	// Namespace is the namespace whose containers share the quota
+	Namespace     string  `json:"namespace,omitempty" gorethink:"namespace"`
+	MaxContainers int     `json:"max_containers,omitempty" gorethink:"max_containers"`
+	MaxCpus       float64 `json:"max_cpus,omitempty" gorethink:"max_cpus"`
+	// MaxMemory is in MB
+	MaxMemory float64   `json:"max_memory,omitempty" gorethink:"max_memory"`
+	Created   time.Time `json:"created,omitempty" gorethink:"created"`
+}
+
+// QuotaUsage is a quota along with the running containers counted against
+// it
+type QuotaUsage struct {
+	Quota      *Quota  `json:"quota,omitempty"`
+	Containers int     `json:"containers"`
+	Cpus       float64 `json:"cpus"`
+	Memory     float64 `json:"memory"`
+}
+
+func (q *Quota) Validate() error {
+	if !secretNamePattern.MatchString(q.Name) {
+		return fmt.Errorf("%w: name must be letters, digits, '_', '.' or '-'", ErrInvalidQuota)
+	}
+	targets := 0
+	for _, t := range []string{q.Account, q.Role, q.Namespace} {
+		if t != "" {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return fmt.Errorf("%w: set one of an account, a role or a namespace", ErrInvalidQuota)
+	}
+	if q.MaxContainers < 0 || q.MaxCpus < 0 || q.MaxMemory < 0 {
+		return fmt.Errorf("%w: limits must not be negative", ErrInvalidQuota)
+	}
+	return nil
+}
+
+// Add counts the reservations of containers of image against the quota
+func (u *QuotaUsage) Add(image *citadel.Image, count int) {
+	u.Containers += count
+	u.Cpus += image.Cpus * float64(count)
+	u.Memory += image.Memory * float64(count)
+}
+
+// Check returns ErrQuotaExceeded if the usage is over any limit of the
+// quota
+func (u *QuotaUsage) Check() error {
+	q := u.Quota
+	switch {
+	case q.MaxContainers > 0 && u.Containers > q.MaxContainers:
+		return fmt.Errorf("%w: %s allows %d containers; %d requested", ErrQuotaExceeded, q.Name, q.MaxContainers, u.Containers)
+	case q.MaxCpus > 0 && u.Cpus > q.MaxCpus:
+		return fmt.Errorf("%w: %s allows %.2f cpus; %.2f requested", ErrQuotaExceeded, q.Name, q.MaxCpus, u.Cpus)
+	case q.MaxMemory > 0 && u.Memory > q.MaxMemory:
+		return fmt.Errorf("%w: %s allows %.0f MB memory; %.0f MB requested", ErrQuotaExceeded, q.Name, q.MaxMemory, u.Memory)
+	}
+	return nil
+}
+
+// ContainerOwner returns the account that launched a container
+func ContainerOwner(c *citadel.Container) string {
+	if c == nil || c.Image == nil {
+		return ""
+	}
+	return c.Image.Environment[OwnerEnv]
+}
+
+// SetOwner marks the containers of the image with the account launching
+// them
+func SetOwner(image *citadel.Image, username string) {
+	if image.Environment == nil {
+		image.Environment = map[string]string{}
+	}
+	if username == "" {
+		delete(image.Environment, OwnerEnv)
+		return
+	}
+	image.Environment[OwnerEnv] = username
+}
@@ -0,0 +1,56 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestQuotaValidate(t *testing.T) {
+	for _, valid := range []*Quota{
+		{Name: "team-a", Role: "team-a", MaxContainers: 10},
+		{Name: "dev", Namespace: "dev", MaxMemory: 4096},
+	} {
+		if err := valid.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, q := range []*Quota{
+		{Name: "q"},
+		{Name: "q", Account: "alice", Role: "team-a"},
+		{Name: "q", Role: "team-a", Namespace: "dev"},
+		{Name: "q", Account: "alice", MaxCpus: -1},
+		{Name: "bad name", Account: "alice"},
+	} {
+		if err := q.Validate(); !errors.Is(err, ErrInvalidQuota) {
+			t.Errorf("expected ErrInvalidQuota for %+v; got %v", q, err)
+		}
+	}
+}
+
+func TestQuotaUsageCheck(t *testing.T) {
+	img := &citadel.Image{Name: "app", Cpus: 0.5, Memory: 256}
+	u := &QuotaUsage{Quota: &Quota{Name: "q", Account: "alice", MaxContainers: 4, MaxMemory: 1024}}
+	u.Add(img, 3)
+	if err := u.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if u.Cpus != 1.5 || u.Memory != 768 {
+		t.Fatalf("unexpected usage %+v", u)
+	}
+	u.Add(img, 1)
+	if err := u.Check(); err != nil {
+		t.Fatal(err)
+	}
+	u.Add(&citadel.Image{Name: "tiny"}, 1)
+	if err := u.Check(); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected container limit to be exceeded; got %v", err)
+	}
+
+	u = &QuotaUsage{Quota: &Quota{Name: "q", Account: "alice", MaxMemory: 512}}
+	u.Add(img, 3)
+	if err := u.Check(); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected memory limit to be exceeded; got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	// DefaultRebalanceThreshold is the utilization difference in percent
+	// between two engines above which containers are moved
+	DefaultRebalanceThreshold = 10
+)
+
+var (
+	ErrInvalidRebalanceOptions = errors.New("invalid rebalance options")
+)
+
+type (
+	// RebalanceOptions control how containers are moved from over
+	// utilized engines to under utilized ones
+	RebalanceOptions struct {
+		// DryRun plans the moves without running them
+		DryRun bool `json:"dry_run,omitempty"`
+		// Threshold is the utilization difference in percent between
+		// two engines above which a container is moved
+		Threshold float64 `json:"threshold,omitempty"`
+		// MaxMoves limits the number of moved containers; zero is no
+		// limit
+		MaxMoves int `json:"max_moves,omitempty"`
+	}
+
+	// RebalanceMove is a container moved between engines
+	RebalanceMove struct {
+		Container string `json:"container"`
+		Image     string `json:"image"`
+		From      string `json:"from"`
+		To        string `json:"to"`
+		// Replacement is the id of the container started on To; it is
+		// empty for dry runs
+		Replacement string `json:"replacement,omitempty"`
+	}
+
+	// EngineLoad is the capacity and the resources reserved by the
+	// running containers of an engine
+	EngineLoad struct {
+		ID             string  `json:"id"`
+		Cpus           float64 `json:"cpus"`
+		Memory         float64 `json:"memory"`
+		ReservedCpus   float64 `json:"reserved_cpus"`
+		ReservedMemory float64 `json:"reserved_memory"`
+	}
+
+	// RebalanceCandidate is a running container that may be moved
+	RebalanceCandidate struct {
+		ID     string
+		Image  string
+		Engine string
+		Cpus   float64
+		Memory float64
+	}
+)
+
+func (o *RebalanceOptions) Validate() error {
+	if o.Threshold < 0 || o.Threshold > 100 {
+		return fmt.Errorf("%w: threshold must be between 0 and 100", ErrInvalidRebalanceOptions)
+	}
+	if o.MaxMoves < 0 {
+		return fmt.Errorf("%w: max moves must not be negative", ErrInvalidRebalanceOptions)
+	}
+	return nil
+}
+
+// threshold returns the threshold moves are planned with;
+// DefaultRebalanceThreshold when none is set
+func (o *RebalanceOptions) threshold() float64 {
+	if o.Threshold == 0 {
+		return DefaultRebalanceThreshold
+	}
+	return o.Threshold
+}
+
+// Utilization returns the reserved share of the engine resources in
+// percent, scored like the cluster resource manager places containers
+func (l *EngineLoad) Utilization() float64 {
+	return l.utilization(0, 0)
+}
+
+func (l *EngineLoad) utilization(cpus, memory float64) float64 {
+	if l.Cpus <= 0 || l.Memory <= 0 {
+		return 100
+	}
+	cpuScore := (l.ReservedCpus + cpus) / l.Cpus * 100
+	memoryScore := (l.ReservedMemory + memory) / l.Memory * 100
+	return (cpuScore + memoryScore) / 2
+}
+
+// PlanRebalance returns the moves that even out the utilization of the
+// engines.  Containers leave the most utilized engines for the least
+// utilized ones accepting them, as long as the difference between the
+// two is above the threshold and the move lowers the higher of the two.
+// Each container is moved at most once.  The loads are updated with the
+// planned moves.
+func PlanRebalance(loads []*EngineLoad, candidates []*RebalanceCandidate, opts *RebalanceOptions, accepts func(c *RebalanceCandidate, engine string) bool) []*RebalanceMove {
+	moves := []*RebalanceMove{}
+	// larger containers first so fewer moves are needed
+	candidates = append([]*RebalanceCandidate{}, candidates...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Cpus+candidates[i].Memory > candidates[j].Cpus+candidates[j].Memory
+	})
+	moved := map[string]bool{}
+	for opts.MaxMoves == 0 || len(moves) < opts.MaxMoves {
+		sorted := append([]*EngineLoad{}, loads...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].Utilization() > sorted[j].Utilization()
+		})
+		move := planMove(sorted, candidates, moved, opts.threshold(), accepts)
+		if move == nil {
+			break
+		}
+		moves = append(moves, move)
+	}
+	return moves
+}
+
+// planMove finds a single move off the most utilized engine possible;
+// sorted are the loads by descending utilization
+func planMove(sorted []*EngineLoad, candidates []*RebalanceCandidate, moved map[string]bool, threshold float64, accepts func(c *RebalanceCandidate, engine string) bool) *RebalanceMove {
+	for i, from := range sorted {
+		for _, c := range candidates {
+			// containers without reservations do not count towards
+			// utilization
+			if moved[c.ID] || c.Engine != from.ID || c.Cpus+c.Memory <= 0 {
+				continue
+			}
+			// least utilized first
+			for j := len(sorted) - 1; j > i; j-- {
+				to := sorted[j]
+				if from.Utilization()-to.Utilization() <= threshold {
+					break
+				}
+				toAfter := to.utilization(c.Cpus, c.Memory)
+				if toAfter > 100 || toAfter >= from.Utilization() {
+					continue
+				}
+				if !accepts(c, to.ID) {
+					continue
+				}
+				from.ReservedCpus -= c.Cpus
+				from.ReservedMemory -= c.Memory
+				to.ReservedCpus += c.Cpus
+				to.ReservedMemory += c.Memory
+				moved[c.ID] = true
+				return &RebalanceMove{
+					Container: c.ID,
+					Image:     c.Image,
+					From:      from.ID,
+					To:        to.ID,
+				}
+			}
+		}
+	}
+	return nil
+}
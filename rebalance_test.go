@@ -0,0 +1,75 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPlanRebalance(t *testing.T) {
+	loads := []*EngineLoad{
+		{ID: "e1", Cpus: 4, Memory: 4096, ReservedCpus: 4, ReservedMemory: 4096},
+		{ID: "e2", Cpus: 4, Memory: 4096},
+	}
+	candidates := []*RebalanceCandidate{}
+	for _, id := range []string{"c1", "c2", "c3", "c4"} {
+		candidates = append(candidates, &RebalanceCandidate{ID: id, Image: "worker", Engine: "e1", Cpus: 1, Memory: 1024})
+	}
+	all := func(*RebalanceCandidate, string) bool { return true }
+
+	moves := PlanRebalance(loads, candidates, &RebalanceOptions{Threshold: 10}, all)
+	if len(moves) != 2 {
+		t.Fatalf("expected 2 moves; received %d", len(moves))
+	}
+	for _, m := range moves {
+		if m.From != "e1" || m.To != "e2" {
+			t.Errorf("unexpected move %+v", m)
+		}
+	}
+	if u := loads[0].Utilization(); u != 50 {
+		t.Errorf("expected e1 at 50%%; received %v", u)
+	}
+	if moves := PlanRebalance(loads, candidates, &RebalanceOptions{Threshold: 10}, all); len(moves) != 0 {
+		t.Errorf("expected a balanced cluster; received %d moves", len(moves))
+	}
+}
+
+func TestPlanRebalanceLimits(t *testing.T) {
+	newLoads := func() []*EngineLoad {
+		return []*EngineLoad{
+			{ID: "e1", Cpus: 4, Memory: 4096, ReservedCpus: 3, ReservedMemory: 3072},
+			{ID: "e2", Cpus: 4, Memory: 4096},
+		}
+	}
+	candidates := []*RebalanceCandidate{
+		{ID: "c1", Engine: "e1", Cpus: 1, Memory: 1024},
+		{ID: "c2", Engine: "e1", Cpus: 1, Memory: 1024},
+		{ID: "c3", Engine: "e1", Cpus: 1, Memory: 1024},
+	}
+	all := func(*RebalanceCandidate, string) bool { return true }
+	if moves := PlanRebalance(newLoads(), candidates, &RebalanceOptions{Threshold: 10, MaxMoves: 1}, all); len(moves) != 1 {
+		t.Errorf("expected max moves to be honored; received %d", len(moves))
+	}
+	if moves := PlanRebalance(newLoads(), candidates, &RebalanceOptions{Threshold: 80}, all); len(moves) != 0 {
+		t.Errorf("expected no moves below the threshold; received %d", len(moves))
+	}
+	none := func(*RebalanceCandidate, string) bool { return false }
+	if moves := PlanRebalance(newLoads(), candidates, &RebalanceOptions{Threshold: 10}, none); len(moves) != 0 {
+		t.Errorf("expected no moves to rejecting engines; received %d", len(moves))
+	}
+}
+
+func TestRebalanceOptionsValidate(t *testing.T) {
+	opts := &RebalanceOptions{}
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Threshold != 0 {
+		t.Errorf("expected the options to be unchanged; received threshold %v", opts.Threshold)
+	}
+	if th := opts.threshold(); th != DefaultRebalanceThreshold {
+		t.Errorf("expected default threshold; received %v", th)
+	}
+	if err := (&RebalanceOptions{MaxMoves: -1}).Validate(); !errors.Is(err, ErrInvalidRebalanceOptions) {
+		t.Errorf("expected ErrInvalidRebalanceOptions; received %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// consulRegistry registers services through the consul catalog so each
+// service is listed on a node named like the engine running it
+type consulRegistry struct {
+	addr string
+}
+
+type consulService struct {
+	ID      string
+	Service string
+	Address string
+	Port    int
+	Tags    []string
+}
+
+type consulCheck struct {
+	Node      string
+	CheckID   string
+	Name      string
+	Status    string
+	ServiceID string
+	Output    string
+}
+
+type consulRegistration struct {
+	Node    string
+	Address string
+	Service *consulService
+	Check   *consulCheck
+}
+
+type consulDeregistration struct {
+	Node      string
+	ServiceID string
+}
+
+type consulCatalogService struct {
+	Node           string
+	Address        string
+	ServiceID      string
+	ServiceName    string
+	ServiceAddress string
+	ServicePort    int
+	ServiceTags    []string
+}
+
+type consulHealthCheck struct {
+	ServiceID string
+	Status    string
+}
+
+func (c *consulRegistry) url(path string) string {
+	return strings.TrimSuffix(c.addr, "/") + path
+}
+
+func (c *consulRegistry) Services() (map[string]*Service, error) {
+	names := map[string][]string{}
+	if err := doJSON("GET", c.url("/v1/catalog/services"), nil, &names); err != nil {
+		return nil, err
+	}
+	services := map[string]*Service{}
+	for name, tags := range names {
+		if !hasTag(tags, tag) {
+			continue
+		}
+		entries := []*consulCatalogService{}
+		if err := doJSON("GET", c.url("/v1/catalog/service/"+url.PathEscape(name)), nil, &entries); err != nil {
+			return nil, err
+		}
+		checks := []*consulHealthCheck{}
+		if err := doJSON("GET", c.url("/v1/health/checks/"+url.PathEscape(name)), nil, &checks); err != nil {
+			return nil, err
+		}
+		status := map[string]string{}
+		for _, chk := range checks {
+			status[chk.ServiceID] = chk.Status
+		}
+		for _, e := range entries {
+			if !hasTag(e.ServiceTags, tag) {
+				continue
+			}
+			s := &Service{
+				ID:   e.ServiceID,
+				Name: e.ServiceName,
+				Node: e.Node,
+				Host: e.ServiceAddress,
+				Port: e.ServicePort,
+				Tags: e.ServiceTags,
+			}
+			if status[e.ServiceID] == "critical" {
+				s.Health = "unhealthy"
+			}
+			services[s.ID] = s
+		}
+	}
+	return services, nil
+}
+
+// Register adds the service with a check reflecting the container health
+// so unhealthy instances are left out of consul dns and health queries
+func (c *consulRegistry) Register(s *Service) error {
+	status := "passing"
+	output := s.Health
+	if !s.Healthy() {
+		status = "critical"
+	}
+	reg := &consulRegistration{
+		Node:    s.Node,
+		Address: s.Host,
+		Service: &consulService{
+			ID:      s.ID,
+			Service: s.Name,
+			Address: s.Host,
+			Port:    s.Port,
+			Tags:    s.Tags,
+		},
+		Check: &consulCheck{
+			Node:      s.Node,
+			CheckID:   fmt.Sprintf("service:%s", s.ID),
+			Name:      "shipyard container health",
+			Status:    status,
+			ServiceID: s.ID,
+			Output:    output,
+		},
+	}
+	return doJSON("PUT", c.url("/v1/catalog/register"), reg, nil)
+}
+
+func (c *consulRegistry) Deregister(s *Service) error {
+	dereg := &consulDeregistration{
+		Node:      s.Node,
+		ServiceID: s.ID,
+	}
+	return doJSON("PUT", c.url("/v1/catalog/deregister"), dereg, nil)
+}
+
+func hasTag(tags []string, t string) bool {
+	for _, v := range tags {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
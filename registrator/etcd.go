@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// etcdRegistry stores each service as the json encoded key
+// <prefix>/<name>/<id> through the etcd v2 keys api.  Unhealthy services
+// are removed until their container passes its health check again.
+type etcdRegistry struct {
+	addr   string
+	prefix string
+}
+
+type etcdNode struct {
+	Key   string      `json:"key"`
+	Value string      `json:"value"`
+	Dir   bool        `json:"dir"`
+	Nodes []*etcdNode `json:"nodes"`
+}
+
+type etcdResponse struct {
+	Node *etcdNode `json:"node"`
+}
+
+func (e *etcdRegistry) url(key string) string {
+	return strings.TrimSuffix(e.addr, "/") + "/v2/keys" + path.Join("/", e.prefix, key)
+}
+
+func (e *etcdRegistry) key(s *Service) string {
+	return path.Join(s.Name, s.ID)
+}
+
+func (e *etcdRegistry) Services() (map[string]*Service, error) {
+	resp := &etcdResponse{}
+	if err := doJSON("GET", e.url("")+"?recursive=true", nil, resp, http.StatusNotFound); err != nil {
+		return nil, err
+	}
+	services := map[string]*Service{}
+	var walk func(n *etcdNode)
+	walk = func(n *etcdNode) {
+		if n == nil {
+			return
+		}
+		if !n.Dir {
+			s := &Service{}
+			if err := json.Unmarshal([]byte(n.Value), s); err == nil && s.ID != "" && hasTag(s.Tags, tag) {
+				services[s.ID] = s
+			}
+			return
+		}
+		for _, c := range n.Nodes {
+			walk(c)
+		}
+	}
+	walk(resp.Node)
+	return services, nil
+}
+
+func (e *etcdRegistry) Register(s *Service) error {
+	if !s.Healthy() {
+		return e.Deregister(s)
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	v := url.Values{}
+	v.Set("value", string(b))
+	return doRequest("PUT", e.url(e.key(s)), "application/x-www-form-urlencoded", strings.NewReader(v.Encode()), nil, http.StatusCreated)
+}
+
+func (e *etcdRegistry) Deregister(s *Service) error {
+	return doJSON("DELETE", e.url(e.key(s)), nil, nil, http.StatusNotFound)
+}
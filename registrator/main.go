@@ -0,0 +1,133 @@
+// Command registrator is a shipyard extension that registers the published
+// ports of running containers as services in consul or etcd and
+// deregisters them when containers stop, so tools discovering services
+// through those stores find the workloads shipyard schedules.
+package main
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/shipyard/shipyard/client"
+)
+
+const (
+	// settle is how long events are collected before services are synced
+	settle = time.Second
+)
+
+var (
+	shipyardURL     string
+	serviceKey      string
+	allowInsecure   bool
+	backend         string
+	registryAddr    string
+	prefix          string
+	refreshInterval time.Duration
+	logger          = logrus.New()
+)
+
+func init() {
+	flag.StringVar(&shipyardURL, "shipyard-url", "http://shipyard:8080", "shipyard controller url")
+	flag.StringVar(&serviceKey, "service-key", os.Getenv("SHIPYARD_SERVICE_KEY"), "service key with containers:read and events:read (or SHIPYARD_SERVICE_KEY)")
+	flag.BoolVar(&allowInsecure, "allow-insecure", false, "skip controller certificate verification")
+	flag.StringVar(&backend, "registry", "consul", "registry to register services in (consul or etcd)")
+	flag.StringVar(&registryAddr, "registry-addr", "http://consul:8500", "registry http api url")
+	flag.StringVar(&prefix, "prefix", "/services", "etcd key prefix")
+	flag.DurationVar(&refreshInterval, "refresh-interval", 30*time.Second, "interval of full syncs in case events are missed")
+}
+
+type registrator struct {
+	manager  *client.Manager
+	registry Registry
+}
+
+// sync registers new and changed services and deregisters the services of
+// containers that are gone
+func (r *registrator) sync() {
+	containers, err := r.manager.Containers()
+	if err != nil {
+		logger.Warnf("error getting containers: %s", err)
+		return
+	}
+	desired := Services(containers)
+	current, err := r.registry.Services()
+	if err != nil {
+		logger.Warnf("error getting registered services: %s", err)
+		return
+	}
+	for id, s := range desired {
+		if c, ok := current[id]; ok && c.Equal(s) {
+			continue
+		}
+		if err := r.registry.Register(s); err != nil {
+			logger.Errorf("error registering %s: %s", s.ID, err)
+			continue
+		}
+		logger.Infof("registered %s as %s at %s:%d (%s)", s.ID, s.Name, s.Host, s.Port, s.Health)
+	}
+	for id, s := range current {
+		if _, ok := desired[id]; ok {
+			continue
+		}
+		if err := r.registry.Deregister(s); err != nil {
+			logger.Errorf("error deregistering %s: %s", s.ID, err)
+			continue
+		}
+		logger.Infof("deregistered %s", s.ID)
+	}
+}
+
+// watch syncs services on cluster events until the event stream closes
+func (r *registrator) watch() error {
+	events, err := r.manager.StreamEvents(nil)
+	if err != nil {
+		return err
+	}
+	// events may have been missed while reconnecting
+	r.sync()
+	refresh := time.NewTicker(refreshInterval)
+	defer refresh.Stop()
+	var pending <-chan time.Time
+	for {
+		select {
+		case evt, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if evt.Container != nil && pending == nil {
+				pending = time.After(settle)
+			}
+		case <-pending:
+			pending = nil
+			r.sync()
+		case <-refresh.C:
+			r.sync()
+		}
+	}
+}
+
+func main() {
+	flag.Parse()
+	registry, err := NewRegistry(backend, registryAddr, prefix)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	cfg := &client.ShipyardConfig{
+		Url:           shipyardURL,
+		ServiceKey:    serviceKey,
+		AllowInsecure: allowInsecure,
+	}
+	r := &registrator{
+		manager:  client.NewManager(cfg),
+		registry: registry,
+	}
+	for {
+		if err := r.watch(); err != nil {
+			logger.Warnf("error watching events: %s", err)
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Registry stores services for discovery
+type Registry interface {
+	// Services returns the services registered by the registrator by id
+	Services() (map[string]*Service, error)
+	Register(s *Service) error
+	Deregister(s *Service) error
+}
+
+// NewRegistry returns the registry of a backend at addr
+func NewRegistry(backend string, addr string, prefix string) (Registry, error) {
+	switch backend {
+	case "consul":
+		return &consulRegistry{addr: addr}, nil
+	case "etcd":
+		return &etcdRegistry{addr: addr, prefix: prefix}, nil
+	}
+	return nil, fmt.Errorf("unknown registry %s; use consul or etcd", backend)
+}
+
+// doJSON sends a request with an optional json body and decodes a json
+// response into out when it is not nil
+func doJSON(method string, url string, body interface{}, out interface{}, ok ...int) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	return doRequest(method, url, "application/json", r, out, ok...)
+}
+
+func doRequest(method string, url string, contentType string, body io.Reader, out interface{}, ok ...int) error {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	expected := false
+	for _, code := range append(ok, http.StatusOK) {
+		if resp.StatusCode == code {
+			expected = true
+		}
+	}
+	if !expected {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
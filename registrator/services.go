@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+const (
+	// NameEnv overrides the service name of a container
+	NameEnv = "SHIPYARD_SERVICE_NAME"
+	// TagsEnv lists comma separated tags registered with the services of a
+	// container
+	TagsEnv = "SHIPYARD_SERVICE_TAGS"
+	// IgnoreEnv leaves a container unregistered when set to true
+	IgnoreEnv = "SHIPYARD_SERVICE_IGNORE"
+
+	// tag marks the services managed by the registrator so services
+	// registered by other tools are left alone
+	tag = "shipyard"
+)
+
+// Service is one published port of a running container
+type Service struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Node is the engine running the container
+	Node      string   `json:"node"`
+	Container string   `json:"container"`
+	Host      string   `json:"host"`
+	Port      int      `json:"port"`
+	Tags      []string `json:"tags,omitempty"`
+	// Health is the health check status of the container, if it has one
+	Health string `json:"health,omitempty"`
+}
+
+// Healthy reports whether the container passes its health check or has
+// none
+func (s *Service) Healthy() bool {
+	return s.Health == "" || s.Health == shipyard.HealthHealthy
+}
+
+// Equal reports whether two registrations are the same
+func (s *Service) Equal(o *Service) bool {
+	return s.ID == o.ID && s.Name == o.Name && s.Node == o.Node && s.Host == o.Host &&
+		s.Port == o.Port && strings.Join(s.Tags, ",") == strings.Join(o.Tags, ",") && s.Healthy() == o.Healthy()
+}
+
+// Services returns the services of the published ports of the running
+// containers by id
+func Services(containers []*citadel.Container) map[string]*Service {
+	services := map[string]*Service{}
+	for _, c := range containers {
+		if c.State != "running" || c.Image == nil || c.Image.Environment[IgnoreEnv] == "true" {
+			continue
+		}
+		endpoints := shipyard.ContainerEndpoints(c)
+		name := serviceName(c)
+		tags := []string{tag}
+		for _, t := range strings.Split(c.Image.Environment[TagsEnv], ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tags = append(tags, t)
+			}
+		}
+		sort.Strings(tags)
+		for _, e := range endpoints {
+			host, port := splitAddr(e.Addr)
+			s := &Service{
+				ID:        fmt.Sprintf("shipyard-%s-%d", shortID(c.ID), e.ContainerPort),
+				Name:      name,
+				Node:      e.Engine,
+				Container: c.ID,
+				Host:      host,
+				Port:      port,
+				Tags:      tags,
+				Health:    shipyard.ContainerHealth(c),
+			}
+			// containers publishing several ports get a service per port
+			if len(endpoints) > 1 {
+				s.Name = fmt.Sprintf("%s-%d", name, e.ContainerPort)
+			}
+			services[s.ID] = s
+		}
+	}
+	return services
+}
+
+// serviceName is the name set on the container, its application or the
+// repository of its image
+func serviceName(c *citadel.Container) string {
+	if n := c.Image.Environment[NameEnv]; n != "" {
+		return n
+	}
+	if n := shipyard.ApplicationName(c); n != "" {
+		return n
+	}
+	n := c.Image.Name
+	if i := strings.LastIndex(n, ":"); i > strings.LastIndex(n, "/") {
+		n = n[:i]
+	}
+	return n[strings.LastIndex(n, "/")+1:]
+}
+
+func splitAddr(addr string) (string, int) {
+	host, p, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(p)
+	return host, port
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
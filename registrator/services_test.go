@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/citadel/citadel"
+	"github.com/shipyard/shipyard"
+)
+
+func TestServices(t *testing.T) {
+	eng := &citadel.Engine{ID: "node1", Addr: "tcp://10.0.0.5:2375"}
+	containers := []*citadel.Container{
+		{
+			ID:     "0123456789abcdef",
+			State:  "running",
+			Engine: eng,
+			Image:  &citadel.Image{Name: "registry.local/team/redis:2.8"},
+			Ports:  []*citadel.Port{{Proto: "tcp", ContainerPort: 6379, Port: 49153}},
+		},
+		{
+			ID:     "fedcba9876543210",
+			State:  "running",
+			Engine: eng,
+			Image: &citadel.Image{Name: "web", Environment: map[string]string{
+				NameEnv:                  "frontend",
+				TagsEnv:                  "v2, public",
+				shipyard.HealthStatusEnv: shipyard.HealthUnhealthy,
+			}},
+			Ports: []*citadel.Port{
+				{Proto: "tcp", ContainerPort: 80, Port: 8080},
+				{Proto: "tcp", ContainerPort: 443, Port: 8443},
+			},
+		},
+		{
+			ID:     "aaaaaaaaaaaaaaaa",
+			State:  "stopped",
+			Engine: eng,
+			Image:  &citadel.Image{Name: "redis"},
+			Ports:  []*citadel.Port{{Proto: "tcp", ContainerPort: 6379, Port: 49154}},
+		},
+		{
+			ID:     "bbbbbbbbbbbbbbbb",
+			State:  "running",
+			Engine: eng,
+			Image:  &citadel.Image{Name: "redis", Environment: map[string]string{IgnoreEnv: "true"}},
+			Ports:  []*citadel.Port{{Proto: "tcp", ContainerPort: 6379, Port: 49155}},
+		},
+	}
+	services := Services(containers)
+	if len(services) != 3 {
+		t.Fatalf("expected 3 services; got %d", len(services))
+	}
+	redis := services["shipyard-0123456789ab-6379"]
+	if redis == nil || redis.Name != "redis" || redis.Host != "10.0.0.5" || redis.Port != 49153 || redis.Node != "node1" {
+		t.Fatalf("unexpected service %+v", redis)
+	}
+	https := services["shipyard-fedcba987654-443"]
+	if https == nil || https.Name != "frontend-443" || https.Healthy() {
+		t.Fatalf("unexpected service %+v", https)
+	}
+	if len(https.Tags) != 3 || https.Tags[0] != "public" || https.Tags[1] != tag || https.Tags[2] != "v2" {
+		t.Fatalf("unexpected tags %v", https.Tags)
+	}
+}
+
+func TestConsulRegister(t *testing.T) {
+	var reg *consulRegistration
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != "/v1/catalog/register" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte("true"))
+	}))
+	defer srv.Close()
+
+	registry, err := NewRegistry("consul", srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{ID: "shipyard-abc-80", Name: "web", Node: "node1", Host: "10.0.0.5", Port: 8080, Tags: []string{tag}, Health: shipyard.HealthUnhealthy}
+	if err := registry.Register(s); err != nil {
+		t.Fatal(err)
+	}
+	if reg == nil || reg.Service.ID != s.ID || reg.Service.Port != 8080 || reg.Check.Status != "critical" {
+		t.Fatalf("unexpected registration %+v", reg)
+	}
+}
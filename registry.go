@@ -0,0 +1,50 @@
+package shipyard
+
+import (
+	"strings"
+)
+
+const (
+	// DefaultRegistryHost is used for images without a registry host
+	DefaultRegistryHost = "index.docker.io"
+)
+
+type (
+	// Registry holds the address and credentials of a docker registry used
+	// when pulling images hosted there
+	Registry struct {
+		ID       string `json:"id,omitempty" gorethink:"id,omitempty"`
+		Name     string `json:"name,omitempty" gorethink:"name"`
+		Addr     string `json:"addr,omitempty" gorethink:"addr"`
+		Username string `json:"username,omitempty" gorethink:"username"`
+		Password string `json:"password,omitempty" gorethink:"-"`
+		// PasswordData is the password encrypted by the controller
+		PasswordData string `json:"-" gorethink:"password_data,omitempty"`
+		Email        string `json:"email,omitempty" gorethink:"email"`
+	}
+)
+
+// Host returns the registry address without scheme or path
+func (r *Registry) Host() string {
+	h := r.Addr
+	if i := strings.Index(h, "://"); i != -1 {
+		h = h[i+3:]
+	}
+	if i := strings.Index(h, "/"); i != -1 {
+		h = h[:i]
+	}
+	return h
+}
+
+// RegistryHost returns the registry host an image name refers to
+func RegistryHost(image string) string {
+	i := strings.Index(image, "/")
+	if i == -1 {
+		return DefaultRegistryHost
+	}
+	h := image[:i]
+	if !strings.ContainsAny(h, ".:") && h != "localhost" {
+		return DefaultRegistryHost
+	}
+	return h
+}
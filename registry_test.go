@@ -0,0 +1,27 @@
+package shipyard
+
+import (
+	"testing"
+)
+
+func TestRegistryHost(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                           DefaultRegistryHost,
+		"shipyard/shipyard:latest":        DefaultRegistryHost,
+		"registry.example.com/app":        "registry.example.com",
+		"registry.example.com:5000/a/b:1": "registry.example.com:5000",
+		"localhost/app":                   "localhost",
+	}
+	for image, expected := range tests {
+		if h := RegistryHost(image); h != expected {
+			t.Errorf("expected host %s for %s; received %s", expected, image, h)
+		}
+	}
+}
+
+func TestRegistryAddrHost(t *testing.T) {
+	r := &Registry{Addr: "https://registry.example.com:5000/v1/"}
+	if h := r.Host(); h != "registry.example.com:5000" {
+		t.Errorf("expected host registry.example.com:5000; received %s", h)
+	}
+}
@@ -0,0 +1,66 @@
+package shipyard
+
+import (
+	"fmt"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	// ReschedulePolicyEnv holds the reschedule policy of a container
+	ReschedulePolicyEnv = "_SHIPYARD_RESCHEDULE"
+
+	RescheduleNo = "no"
+	// RescheduleOnEngineFailure moves the container to another engine
+	// when its engine goes down or becomes unreachable
+	RescheduleOnEngineFailure = "on-engine-failure"
+	// RescheduleOnFailure also replaces the container when it exits with
+	// a non-zero status
+	RescheduleOnFailure = "on-failure"
+	// RescheduleAlways also replaces the container when it exits with any
+	// status, unless it was stopped through the api
+	RescheduleAlways = "always"
+)
+
+var (
+	ReschedulePolicies = []string{
+		RescheduleNo,
+		RescheduleOnEngineFailure,
+		RescheduleOnFailure,
+		RescheduleAlways,
+	}
+)
+
+// ReschedulePolicy returns the reschedule policy of an image; images without
+// one are not rescheduled
+func ReschedulePolicy(image *citadel.Image) string {
+	if image == nil {
+		return RescheduleNo
+	}
+	if p := image.Environment[ReschedulePolicyEnv]; p != "" {
+		return p
+	}
+	return RescheduleNo
+}
+
+// SetReschedulePolicy sets the reschedule policy containers of the image are
+// run with
+func SetReschedulePolicy(image *citadel.Image, policy string) error {
+	if err := ValidateReschedulePolicy(policy); err != nil {
+		return err
+	}
+	if image.Environment == nil {
+		image.Environment = map[string]string{}
+	}
+	image.Environment[ReschedulePolicyEnv] = policy
+	return nil
+}
+
+func ValidateReschedulePolicy(policy string) error {
+	for _, p := range ReschedulePolicies {
+		if p == policy {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown reschedule policy %q", policy)
+}
@@ -0,0 +1,23 @@
+package shipyard
+
+import (
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestReschedulePolicy(t *testing.T) {
+	img := &citadel.Image{Name: "redis"}
+	if p := ReschedulePolicy(img); p != RescheduleNo {
+		t.Errorf("expected images without a policy not to be rescheduled; received %s", p)
+	}
+	if err := SetReschedulePolicy(img, RescheduleOnFailure); err != nil {
+		t.Fatal(err)
+	}
+	if p := ReschedulePolicy(img); p != RescheduleOnFailure {
+		t.Errorf("expected %s; received %s", RescheduleOnFailure, p)
+	}
+	if err := SetReschedulePolicy(img, "sometimes"); err == nil {
+		t.Error("expected unknown policy to be rejected")
+	}
+}
@@ -0,0 +1,114 @@
+package shipyard
+
+import (
+	"sort"
+
+	"github.com/citadel/citadel"
+)
+
+// ResourceUsage is the capacity and reservations of an engine or the
+// cluster.  Containers reserve the cpus and memory (MB) of their image;
+// only running containers count against capacity.
+type ResourceUsage struct {
+	Cpus              float64 `json:"cpus"`
+	ReservedCpus      float64 `json:"reserved_cpus"`
+	FreeCpus          float64 `json:"free_cpus"`
+	Memory            float64 `json:"memory"`
+	ReservedMemory    float64 `json:"reserved_memory"`
+	FreeMemory        float64 `json:"free_memory"`
+	Containers        int     `json:"containers"`
+	RunningContainers int     `json:"running_containers"`
+}
+
+// EngineUsage is the resource usage of a single engine
+type EngineUsage struct {
+	EngineID string `json:"engine_id,omitempty"`
+	Addr     string `json:"addr,omitempty"`
+	ResourceUsage
+}
+
+// ImageUsage totals the containers of an image across the cluster
+type ImageUsage struct {
+	Image             string  `json:"image,omitempty"`
+	Containers        int     `json:"containers"`
+	RunningContainers int     `json:"running_containers"`
+	ReservedCpus      float64 `json:"reserved_cpus"`
+	ReservedMemory    float64 `json:"reserved_memory"`
+}
+
+// ClusterUsage breaks down cluster resources by engine and image
+type ClusterUsage struct {
+	Total   ResourceUsage  `json:"total"`
+	Engines []*EngineUsage `json:"engines"`
+	Images  []*ImageUsage  `json:"images"`
+}
+
+// ComputeUsage totals the reservations of containers on engines
+func ComputeUsage(engines []*citadel.Engine, containers []*citadel.Container) *ClusterUsage {
+	usage := &ClusterUsage{
+		Engines: []*EngineUsage{},
+		Images:  []*ImageUsage{},
+	}
+	byEngine := map[string]*EngineUsage{}
+	for _, e := range engines {
+		eu := &EngineUsage{
+			EngineID: e.ID,
+			Addr:     e.Addr,
+			ResourceUsage: ResourceUsage{
+				Cpus:   e.Cpus,
+				Memory: e.Memory,
+			},
+		}
+		byEngine[e.ID] = eu
+		usage.Engines = append(usage.Engines, eu)
+	}
+	byImage := map[string]*ImageUsage{}
+	for _, c := range containers {
+		running := c.State == "running"
+		var cpus, memory float64
+		name := ""
+		if c.Image != nil {
+			cpus, memory, name = c.Image.Cpus, c.Image.Memory, c.Image.Name
+		}
+		iu, ok := byImage[name]
+		if !ok {
+			iu = &ImageUsage{Image: name}
+			byImage[name] = iu
+			usage.Images = append(usage.Images, iu)
+		}
+		iu.Containers++
+		if c.Engine == nil {
+			continue
+		}
+		eu, ok := byEngine[c.Engine.ID]
+		if !ok {
+			continue
+		}
+		eu.Containers++
+		if running {
+			iu.RunningContainers++
+			iu.ReservedCpus += cpus
+			iu.ReservedMemory += memory
+			eu.RunningContainers++
+			eu.ReservedCpus += cpus
+			eu.ReservedMemory += memory
+		}
+	}
+	for _, eu := range usage.Engines {
+		eu.FreeCpus = eu.Cpus - eu.ReservedCpus
+		eu.FreeMemory = eu.Memory - eu.ReservedMemory
+		t := &usage.Total
+		t.Cpus += eu.Cpus
+		t.ReservedCpus += eu.ReservedCpus
+		t.FreeCpus += eu.FreeCpus
+		t.Memory += eu.Memory
+		t.ReservedMemory += eu.ReservedMemory
+		t.FreeMemory += eu.FreeMemory
+		t.Containers += eu.Containers
+		t.RunningContainers += eu.RunningContainers
+	}
+	sort.Slice(usage.Images, func(i, j int) bool {
+		return usage.Images[i].Image < usage.Images[j].Image
+	})
+	return usage
+}
@@ -0,0 +1,32 @@
+package shipyard
+
+import (
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestComputeUsage(t *testing.T) {
+	e1 := &citadel.Engine{ID: "e1", Cpus: 4, Memory: 4096}
+	e2 := &citadel.Engine{ID: "e2", Cpus: 2, Memory: 2048}
+	web := &citadel.Image{Name: "web", Cpus: 1, Memory: 512}
+	db := &citadel.Image{Name: "db", Cpus: 2, Memory: 1024}
+	usage := ComputeUsage([]*citadel.Engine{e1, e2}, []*citadel.Container{
+		{ID: "1", Image: web, Engine: e1, State: "running"},
+		{ID: "2", Image: web, Engine: e2, State: "running"},
+		{ID: "3", Image: db, Engine: e1, State: "stopped"},
+	})
+
+	if u := usage.Engines[0]; u.ReservedCpus != 1 || u.FreeCpus != 3 || u.Containers != 2 || u.RunningContainers != 1 {
+		t.Errorf("unexpected e1 usage %+v", u.ResourceUsage)
+	}
+	if u := usage.Total; u.Cpus != 6 || u.ReservedMemory != 1024 || u.FreeMemory != 5120 || u.Containers != 3 {
+		t.Errorf("unexpected total usage %+v", u)
+	}
+	if len(usage.Images) != 2 || usage.Images[1].Image != "web" || usage.Images[1].RunningContainers != 2 {
+		t.Errorf("unexpected image usage %+v", usage.Images)
+	}
+	if usage.Images[0].ReservedCpus != 0 {
+		t.Error("expected stopped containers not to reserve resources")
+	}
+}
@@ -0,0 +1,179 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	// RestartPolicyEnv holds the restart policy the controller restarts a
+	// container with
+	RestartPolicyEnv = "_SHIPYARD_RESTART"
+	// RestartCountEnv is set by the controller on the containers it returns
+	// to the number of times it restarted them
+	RestartCountEnv = "_SHIPYARD_RESTARTS"
+
+	RestartNo = "no"
+	// RestartOnFailure restarts the container when it exits with a non-zero
+	// status, at most MaxRetries times if set
+	RestartOnFailure = "on-failure"
+	// RestartAlways restarts the container whenever it exits, unless it was
+	// stopped through the api
+	RestartAlways = "always"
+
+	// RestartBackoffMin is the delay before the first restart; it doubles
+	// with every restart up to RestartBackoffMax.  Restarts further apart
+	// than RestartBackoffMax start over from RestartBackoffMin.
+	RestartBackoffMin = time.Second
+	RestartBackoffMax = 5 * time.Minute
+)
+
+var (
+	ErrInvalidRestartPolicy = errors.New("invalid restart policy")
+)
+
+type (
+	// RestartPolicy is how the controller restarts a container in place
+	// when it exits.  Unlike a docker restart policy the restarts are
+	// delayed with a backoff, counted on the container and recorded as
+	// events.
+	RestartPolicy struct {
+		Name       string `json:"name,omitempty"`
+		MaxRetries int    `json:"max_retries,omitempty"`
+	}
+
+	// ContainerRestarts counts the restarts of a container by the controller
+	ContainerRestarts struct {
+		ID    string `json:"id,omitempty" gorethink:"id"`
+		Count int    `json:"count" gorethink:"count"`
+		// Consecutive counts the restarts since the backoff started over
+		Consecutive int       `json:"consecutive" gorethink:"consecutive"`
+		LastRestart time.Time `json:"last_restart,omitempty" gorethink:"last_restart"`
+	}
+)
+
+// ParseRestartPolicy parses policies such as "always", "on-failure" and
+// "on-failure:5"; an empty policy is RestartNo
+func ParseRestartPolicy(s string) (*RestartPolicy, error) {
+	p := &RestartPolicy{Name: s}
+	if s == "" {
+		p.Name = RestartNo
+	}
+	if i := strings.Index(s, ":"); i > -1 {
+		n, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRestartPolicy, s)
+		}
+		p.Name, p.MaxRetries = s[:i], n
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *RestartPolicy) Validate() error {
+	switch p.Name {
+	case RestartNo, RestartAlways:
+		if p.MaxRetries != 0 {
+			return fmt.Errorf("%w: only on-failure takes a maximum", ErrInvalidRestartPolicy)
+		}
+	case RestartOnFailure:
+		if p.MaxRetries < 0 {
+			return fmt.Errorf("%w: negative maximum", ErrInvalidRestartPolicy)
+		}
+	default:
+		return fmt.Errorf("%w: unknown policy %q", ErrInvalidRestartPolicy, p.Name)
+	}
+	return nil
+}
+
+func (p *RestartPolicy) String() string {
+	if p.MaxRetries > 0 {
+		return fmt.Sprintf("%s:%d", p.Name, p.MaxRetries)
+	}
+	return p.Name
+}
+
+// ShouldRestart reports whether a container that exited with exitCode after
+// restarts restarts is restarted
+func (p *RestartPolicy) ShouldRestart(exitCode int, restarts int) bool {
+	switch p.Name {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return exitCode != 0 && (p.MaxRetries == 0 || restarts < p.MaxRetries)
+	}
+	return false
+}
+
+// Backoff returns the delay before restarting the container at now
+func (r *ContainerRestarts) Backoff(now time.Time) time.Duration {
+	if r.Count == 0 || now.Sub(r.LastRestart) > RestartBackoffMax {
+		return RestartBackoffMin
+	}
+	d := RestartBackoffMin
+	for i := 0; i < r.Consecutive; i++ {
+		d *= 2
+		if d >= RestartBackoffMax {
+			return RestartBackoffMax
+		}
+	}
+	return d
+}
+
+// Restarted counts a restart at now
+func (r *ContainerRestarts) Restarted(now time.Time) {
+	if now.Sub(r.LastRestart) > RestartBackoffMax {
+		r.Consecutive = 0
+	}
+	r.Count++
+	r.Consecutive++
+	r.LastRestart = now
+}
+
+// ContainerRestartPolicy returns the restart policy of an image; images
+// without one are not restarted
+func ContainerRestartPolicy(image *citadel.Image) (*RestartPolicy, error) {
+	if image == nil {
+		return &RestartPolicy{Name: RestartNo}, nil
+	}
+	return ParseRestartPolicy(image.Environment[RestartPolicyEnv])
+}
+
+// SetRestartPolicy sets the restart policy containers of the image are run
+// with
+func SetRestartPolicy(image *citadel.Image, p *RestartPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if image.Environment == nil {
+		image.Environment = map[string]string{}
+	}
+	image.Environment[RestartPolicyEnv] = p.String()
+	return nil
+}
+
+// SetRestartCount reports the restarts of a container on its image
+// environment (see ContainerRestartCount)
+func (r *ContainerRestarts) SetRestartCount(image *citadel.Image) {
+	if image.Environment == nil {
+		image.Environment = map[string]string{}
+	}
+	image.Environment[RestartCountEnv] = strconv.Itoa(r.Count)
+}
+
+// ContainerRestartCount returns the number of times the controller
+// restarted a container
+func ContainerRestartCount(c *citadel.Container) int {
+	if c.Image == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(c.Image.Environment[RestartCountEnv])
+	return n
+}
@@ -0,0 +1,93 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/citadel/citadel"
+)
+
+func TestParseRestartPolicy(t *testing.T) {
+	tests := map[string]RestartPolicy{
+		"":             {Name: RestartNo},
+		"no":           {Name: RestartNo},
+		"always":       {Name: RestartAlways},
+		"on-failure":   {Name: RestartOnFailure},
+		"on-failure:5": {Name: RestartOnFailure, MaxRetries: 5},
+	}
+	for s, expected := range tests {
+		p, err := ParseRestartPolicy(s)
+		if err != nil {
+			t.Errorf("%q: %s", s, err)
+			continue
+		}
+		if *p != expected {
+			t.Errorf("%q: expected %+v; received %+v", s, expected, *p)
+		}
+	}
+	for _, s := range []string{"unless-stopped", "on-failure:x", "on-failure:-1", "always:3"} {
+		if _, err := ParseRestartPolicy(s); !errors.Is(err, ErrInvalidRestartPolicy) {
+			t.Errorf("%q: expected ErrInvalidRestartPolicy; received %v", s, err)
+		}
+	}
+}
+
+func TestRestartPolicyShouldRestart(t *testing.T) {
+	p := &RestartPolicy{Name: RestartOnFailure, MaxRetries: 2}
+	if p.ShouldRestart(0, 0) {
+		t.Error("expected a clean exit not to restart")
+	}
+	if !p.ShouldRestart(1, 1) || p.ShouldRestart(1, 2) {
+		t.Error("expected restarts up to the maximum")
+	}
+	if !(&RestartPolicy{Name: RestartAlways}).ShouldRestart(0, 100) {
+		t.Error("expected always to restart")
+	}
+	if (&RestartPolicy{Name: RestartNo}).ShouldRestart(1, 0) {
+		t.Error("expected no to never restart")
+	}
+}
+
+func TestContainerRestartsBackoff(t *testing.T) {
+	now := time.Now()
+	r := &ContainerRestarts{}
+	if d := r.Backoff(now); d != RestartBackoffMin {
+		t.Errorf("expected %s; received %s", RestartBackoffMin, d)
+	}
+	for i := 0; i < 3; i++ {
+		r.Restarted(now)
+	}
+	if d := r.Backoff(now.Add(time.Minute)); d != 8*time.Second {
+		t.Errorf("expected 8s; received %s", d)
+	}
+	r.Consecutive = 1000
+	if d := r.Backoff(now); d != RestartBackoffMax {
+		t.Errorf("expected %s; received %s", RestartBackoffMax, d)
+	}
+
+	later := now.Add(time.Hour)
+	if d := r.Backoff(later); d != RestartBackoffMin {
+		t.Errorf("expected the backoff to start over; received %s", d)
+	}
+	r.Restarted(later)
+	if r.Count != 4 || r.Consecutive != 1 || !r.LastRestart.Equal(later) {
+		t.Errorf("unexpected restarts %+v", r)
+	}
+}
+
+func TestSetRestartPolicy(t *testing.T) {
+	img := &citadel.Image{}
+	if err := SetRestartPolicy(img, &RestartPolicy{Name: RestartOnFailure, MaxRetries: 3}); err != nil {
+		t.Fatal(err)
+	}
+	p, err := ContainerRestartPolicy(img)
+	if err != nil || p.String() != "on-failure:3" {
+		t.Errorf("expected on-failure:3; received %v %v", p, err)
+	}
+	c := &citadel.Container{Image: img}
+	(&ContainerRestarts{Count: 4}).SetRestartCount(img)
+	if n := ContainerRestartCount(c); n != 4 {
+		t.Errorf("expected 4 restarts; received %d", n)
+	}
+}
@@ -0,0 +1,254 @@
+package shipyard
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	ArchiveS3  = "s3"
+	ArchiveGCS = "gcs"
+
+	// DefaultRetentionInterval is the number of minutes between
+	// retention runs
+	DefaultRetentionInterval = 60
+
+	gcsEndpoint = "https://storage.googleapis.com"
+)
+
+var (
+	ErrInvalidEventRetention = errors.New("invalid event retention")
+)
+
+type (
+	// EventRetention removes events older than MaxAge or beyond the
+	// MaxCount most recent ones.  Zero limits keep events forever.
+	EventRetention struct {
+		// Enabled applies the retention automatically every Interval
+		Enabled bool `json:"enabled" gorethink:"enabled"`
+		// Interval is the number of minutes between automatic runs;
+		// DefaultRetentionInterval when zero
+		Interval int `json:"interval,omitempty" gorethink:"interval"`
+		// MaxAge is the number of hours events are kept
+		MaxAge int `json:"max_age,omitempty" gorethink:"max_age"`
+		// MaxCount is the number of most recent events kept
+		MaxCount int `json:"max_count,omitempty" gorethink:"max_count"`
+		// Archive uploads expired events before they are removed
+		Archive *EventArchive `json:"archive,omitempty" gorethink:"archive,omitempty"`
+	}
+
+	// EventArchive is a bucket expired events are uploaded to as json
+	// lines.  GCS buckets are written through their S3 compatible api
+	// with HMAC keys.
+	EventArchive struct {
+		// Type is ArchiveS3 or ArchiveGCS
+		Type   string `json:"type,omitempty" gorethink:"type"`
+		Bucket string `json:"bucket,omitempty" gorethink:"bucket"`
+		// Prefix is prepended to the object names
+		Prefix string `json:"prefix,omitempty" gorethink:"prefix"`
+		// Region defaults to us-east-1 for S3 and auto for GCS
+		Region string `json:"region,omitempty" gorethink:"region"`
+		// Endpoint is the https url of an S3 compatible service; buckets
+		// are addressed by path when it is set
+		Endpoint  string `json:"endpoint,omitempty" gorethink:"endpoint"`
+		AccessKey string `json:"access_key,omitempty" gorethink:"access_key"`
+		SecretKey string `json:"secret_key,omitempty" gorethink:"-"`
+		// SecretKeyData is the secret key encrypted by the controller
+		SecretKeyData string `json:"-" gorethink:"secret_key_data,omitempty"`
+	}
+
+	// EventRetentionResult reports a retention run
+	EventRetentionResult struct {
+		Started time.Time `json:"started,omitempty"`
+		// Removed is the number of expired events removed
+		Removed int `json:"removed"`
+		// Objects are the names of the archives uploaded
+		Objects []string `json:"objects,omitempty"`
+	}
+)
+
+func (p *EventRetention) Validate() error {
+	if p.Interval < 0 || p.MaxAge < 0 || p.MaxCount < 0 {
+		return fmt.Errorf("%w: values must not be negative", ErrInvalidEventRetention)
+	}
+	if p.Archive != nil {
+		if err := p.Archive.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Period returns the time between automatic runs
+func (p *EventRetention) Period() time.Duration {
+	if p.Interval == 0 {
+		return DefaultRetentionInterval * time.Minute
+	}
+	return time.Duration(p.Interval) * time.Minute
+}
+
+// Cutoff returns the time events older than MaxAge expire at, or the zero
+// time when there is no age limit
+func (p *EventRetention) Cutoff(now time.Time) time.Time {
+	if p.MaxAge == 0 {
+		return time.Time{}
+	}
+	return now.Add(-time.Duration(p.MaxAge) * time.Hour)
+}
+
+// Redacted returns a copy of the policy without the archive secret key
+func (p *EventRetention) Redacted() *EventRetention {
+	r := *p
+	if p.Archive != nil {
+		archive := *p.Archive
+		archive.SecretKey = ""
+		r.Archive = &archive
+	}
+	return &r
+}
+
+func (a *EventArchive) Validate() error {
+	switch a.Type {
+	case ArchiveS3, ArchiveGCS:
+	default:
+		return fmt.Errorf("%w: archive type must be s3 or gcs", ErrInvalidEventRetention)
+	}
+	if a.Bucket == "" || a.AccessKey == "" || a.SecretKey == "" {
+		return fmt.Errorf("%w: archive bucket, access key and secret key are required", ErrInvalidEventRetention)
+	}
+	if a.Endpoint != "" {
+		u, err := url.Parse(a.Endpoint)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("%w: archive endpoint must be an https url", ErrInvalidEventRetention)
+		}
+		if ip := net.ParseIP(u.Hostname()); ip != nil && !PublicArchiveAddress(ip) {
+			return fmt.Errorf("%w: archive endpoint must not be a loopback, link local or unspecified address", ErrInvalidEventRetention)
+		}
+	}
+	return nil
+}
+
+// PublicArchiveAddress reports whether archives may be uploaded to ip.
+// Loopback, link local and unspecified addresses, like the metadata
+// service of cloud instances, are refused so an endpoint can not reach the
+// controller host.
+func PublicArchiveAddress(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsUnspecified()
+}
+
+// ObjectName returns the name of the archive of events starting at from
+func (a *EventArchive) ObjectName(from time.Time, count int) string {
+	return fmt.Sprintf("%sevents-%s-%d.jsonl", a.Prefix, from.UTC().Format("20060102T150405.000Z"), count)
+}
+
+func (a *EventArchive) region() string {
+	switch {
+	case a.Region != "":
+		return a.Region
+	case a.Type == ArchiveGCS:
+		return "auto"
+	}
+	return "us-east-1"
+}
+
+// objectURL returns the url of an object: path style on a configured or
+// GCS endpoint, virtual hosted on AWS
+func (a *EventArchive) objectURL(name string) (*url.URL, error) {
+	endpoint := a.Endpoint
+	if endpoint == "" && a.Type == ArchiveGCS {
+		endpoint = gcsEndpoint
+	}
+	if endpoint == "" {
+		return url.Parse(fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", a.Bucket, a.region(), escapePath(name)))
+	}
+	return url.Parse(fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(endpoint, "/"), a.Bucket, escapePath(name)))
+}
+
+// escapePath escapes each segment of an object name as signature v4
+// expects
+func escapePath(name string) string {
+	parts := strings.Split(name, "/")
+	for i, p := range parts {
+		parts[i] = strings.Replace(url.QueryEscape(p), "+", "%20", -1)
+	}
+	return strings.Join(parts, "/")
+}
+
+// NewUploadRequest returns a signed request putting data as the object
+// name
+func (a *EventArchive) NewUploadRequest(name string, data []byte, now time.Time) (*http.Request, error) {
+	u, err := a.objectURL(name)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("PUT", u.String(), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	a.sign(req, data, now)
+	return req, nil
+}
+
+// sign adds an AWS signature version 4 to a request
+func (a *EventArchive) sign(req *http.Request, body []byte, now time.Time) {
+	now = now.UTC()
+	stamp := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payload := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(payload[:])
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	names := []string{}
+	for k := range req.Header {
+		names = append(names, strings.ToLower(k))
+	}
+	sort.Strings(names)
+	headers := ""
+	for _, k := range names {
+		headers += k + ":" + strings.TrimSpace(req.Header.Get(k)) + "\n"
+	}
+	signed := strings.Join(names, ";")
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		headers,
+		signed,
+		payloadHash,
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	scope := day + "/" + a.region() + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+	signature := hex.EncodeToString(hmacSHA256(SigningKey(a.SecretKey, day, a.region(), "s3"), toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", a.AccessKey, scope, signed, signature))
+	// net/http sends the host from the url
+	req.Header.Del("Host")
+}
+
+// SigningKey derives the signature version 4 key of a day, region and
+// service
+func SigningKey(secret string, day string, region string, service string) []byte {
+	k := hmacSHA256([]byte("AWS4"+secret), day)
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, service)
+	return hmacSHA256(k, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,95 @@
+package shipyard
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventRetentionValidate(t *testing.T) {
+	p := &EventRetention{MaxAge: 24}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Interval != 0 || p.Period() != DefaultRetentionInterval*time.Minute {
+		t.Errorf("expected the default period without changing the policy; received %d %s", p.Interval, p.Period())
+	}
+	invalid := []*EventRetention{
+		{MaxCount: -1},
+		{Archive: &EventArchive{Type: "ftp", Bucket: "b", AccessKey: "a", SecretKey: "s"}},
+		{Archive: &EventArchive{Type: ArchiveS3, Bucket: "b"}},
+		{Archive: &EventArchive{Type: ArchiveS3, Bucket: "b", AccessKey: "a", SecretKey: "s", Endpoint: "minio:9000"}},
+		{Archive: &EventArchive{Type: ArchiveS3, Bucket: "b", AccessKey: "a", SecretKey: "s", Endpoint: "http://minio:9000"}},
+		{Archive: &EventArchive{Type: ArchiveS3, Bucket: "b", AccessKey: "a", SecretKey: "s", Endpoint: "https://169.254.169.254"}},
+		{Archive: &EventArchive{Type: ArchiveS3, Bucket: "b", AccessKey: "a", SecretKey: "s", Endpoint: "https://[::1]:9000"}},
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); !errors.Is(err, ErrInvalidEventRetention) {
+			t.Errorf("%+v: expected ErrInvalidEventRetention; received %v", p, err)
+		}
+	}
+}
+
+func TestEventRetentionRedacted(t *testing.T) {
+	p := &EventRetention{Archive: &EventArchive{Type: ArchiveS3, Bucket: "b", AccessKey: "a", SecretKey: "s"}}
+	r := p.Redacted()
+	if r.Archive.SecretKey != "" || r.Archive.AccessKey != "a" {
+		t.Errorf("unexpected redacted archive %+v", r.Archive)
+	}
+	if p.Archive.SecretKey != "s" {
+		t.Error("expected redacted copy without changing the policy")
+	}
+}
+
+func TestSigningKey(t *testing.T) {
+	// example of the aws signature version 4 documentation
+	k := SigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	expected := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if hex.EncodeToString(k) != expected {
+		t.Errorf("expected %s; received %x", expected, k)
+	}
+}
+
+func TestEventArchiveUploadRequest(t *testing.T) {
+	now := time.Date(2015, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		archive *EventArchive
+		url     string
+		scope   string
+	}{
+		{
+			&EventArchive{Type: ArchiveS3, Bucket: "logs", Prefix: "shipyard/", AccessKey: "AK", SecretKey: "SK"},
+			"https://logs.s3.us-east-1.amazonaws.com/shipyard/events-20150301T120000.000Z-2.jsonl",
+			"AK/20150301/us-east-1/s3/aws4_request",
+		},
+		{
+			&EventArchive{Type: ArchiveGCS, Bucket: "logs", AccessKey: "AK", SecretKey: "SK"},
+			"https://storage.googleapis.com/logs/events-20150301T120000.000Z-2.jsonl",
+			"AK/20150301/auto/s3/aws4_request",
+		},
+		{
+			&EventArchive{Type: ArchiveS3, Bucket: "logs", Region: "eu-west-1", Endpoint: "http://minio:9000/", AccessKey: "AK", SecretKey: "SK"},
+			"http://minio:9000/logs/events-20150301T120000.000Z-2.jsonl",
+			"AK/20150301/eu-west-1/s3/aws4_request",
+		},
+	}
+	for _, test := range tests {
+		name := test.archive.ObjectName(now, 2)
+		req, err := test.archive.NewUploadRequest(name, []byte("{}\n{}\n"), now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if req.Method != "PUT" || req.URL.String() != test.url {
+			t.Errorf("expected PUT %s; received %s %s", test.url, req.Method, req.URL)
+		}
+		auth := req.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential="+test.scope+", SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature=") {
+			t.Errorf("unexpected authorization %s", auth)
+		}
+		if req.Header.Get("X-Amz-Date") != "20150301T120000Z" {
+			t.Errorf("unexpected date %s", req.Header.Get("X-Amz-Date"))
+		}
+	}
+}
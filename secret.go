@@ -0,0 +1,150 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	// SecretsEnv holds the json encoded secret references of a container
+	SecretsEnv = "_SHIPYARD_SECRETS"
+	// RedactedSecret replaces injected secret values in api responses and
+	// events
+	RedactedSecret = "<secret>"
+)
+
+var (
+	ErrInvalidSecret = errors.New("invalid secret")
+
+	secretNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+	envNamePattern    = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Secret is a named value stored encrypted by the controller.  Value is
+// only returned to callers allowed to read secret values.
+type Secret struct {
+	ID      string    `json:"id,omitempty" gorethink:"id,omitempty"`
+	Name    string    `json:"name,omitempty" gorethink:"name"`
+	Value   string    `json:"value,omitempty" gorethink:"-"`
+	Created time.Time `json:"created,omitempty" gorethink:"created"`
+	// Data is the encrypted value
+	Data string `json:"-" gorethink:"data"`
+}
+
+// SecretRef injects a secret into the containers of an image, either as the
+// environment variable Env or as the file Path.  Files are written right
+// after the container starts; place them on a tmpfs such as /dev/shm so the
+// value never reaches the engine's disk.
+type SecretRef struct {
+	Name string `json:"name" gorethink:"name"`
+	Env  string `json:"env,omitempty" gorethink:"env"`
+	Path string `json:"path,omitempty" gorethink:"path"`
+}
+
+func (s *Secret) Validate() error {
+	if !secretNamePattern.MatchString(s.Name) {
+		return fmt.Errorf("%w: name must be letters, digits, '_', '.' or '-'", ErrInvalidSecret)
+	}
+	if s.Value == "" {
+		return fmt.Errorf("%w: value is required", ErrInvalidSecret)
+	}
+	return nil
+}
+
+func (r *SecretRef) Validate() error {
+	if !secretNamePattern.MatchString(r.Name) {
+		return fmt.Errorf("%w: invalid secret name %q", ErrInvalidSecret, r.Name)
+	}
+	switch {
+	case r.Env != "" && r.Path != "":
+		return fmt.Errorf("%w: secret %s has both an env var and a path", ErrInvalidSecret, r.Name)
+	case r.Env != "" && !envNamePattern.MatchString(r.Env):
+		return fmt.Errorf("%w: invalid env var %q", ErrInvalidSecret, r.Env)
+	case r.Path != "" && !strings.HasPrefix(r.Path, "/"):
+		return fmt.Errorf("%w: path %q must be absolute", ErrInvalidSecret, r.Path)
+	case r.Env == "" && r.Path == "":
+		return fmt.Errorf("%w: secret %s needs an env var or a path", ErrInvalidSecret, r.Name)
+	}
+	return nil
+}
+
+// ParseSecretRef parses the command line form of a secret reference:
+// <name> injects the secret as the env var named like the secret in upper
+// case, <name>=<VAR> as the env var VAR and <name>=/<path> as a file
+func ParseSecretRef(s string) (*SecretRef, error) {
+	parts := strings.SplitN(s, "=", 2)
+	ref := &SecretRef{
+		Name: parts[0],
+	}
+	switch {
+	case len(parts) == 1:
+		ref.Env = strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(ref.Name))
+	case strings.HasPrefix(parts[1], "/"):
+		ref.Path = parts[1]
+	default:
+		ref.Env = parts[1]
+	}
+	if err := ref.Validate(); err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
+
+// SecretRefs returns the secret references of an image
+func SecretRefs(image *citadel.Image) ([]*SecretRef, error) {
+	if image == nil || image.Environment[SecretsEnv] == "" {
+		return nil, nil
+	}
+	refs := []*SecretRef{}
+	if err := json.Unmarshal([]byte(image.Environment[SecretsEnv]), &refs); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSecret, err)
+	}
+	for _, ref := range refs {
+		if err := ref.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return refs, nil
+}
+
+// SetSecretRefs sets the secrets injected into containers of the image
+func SetSecretRefs(image *citadel.Image, refs []*SecretRef) error {
+	if image.Environment == nil {
+		image.Environment = map[string]string{}
+	}
+	if len(refs) == 0 {
+		delete(image.Environment, SecretsEnv)
+		return nil
+	}
+	for _, ref := range refs {
+		if err := ref.Validate(); err != nil {
+			return err
+		}
+	}
+	b, err := json.Marshal(refs)
+	if err != nil {
+		return err
+	}
+	image.Environment[SecretsEnv] = string(b)
+	return nil
+}
+
+// RedactSecrets replaces the values of injected secret env vars in the
+// image environment
+func RedactSecrets(image *citadel.Image) {
+	refs, err := SecretRefs(image)
+	if err != nil {
+		return
+	}
+	for _, ref := range refs {
+		if _, ok := image.Environment[ref.Env]; ok && ref.Env != "" {
+			image.Environment[ref.Env] = RedactedSecret
+		}
+	}
+}
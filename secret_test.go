@@ -0,0 +1,55 @@
+package shipyard
+
+import (
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestParseSecretRef(t *testing.T) {
+	tests := []struct {
+		spec string
+		env  string
+		path string
+	}{
+		{"db-password", "DB_PASSWORD", ""},
+		{"db-password=PGPASSWORD", "PGPASSWORD", ""},
+		{"tls.key=/dev/shm/tls.key", "", "/dev/shm/tls.key"},
+	}
+	for _, test := range tests {
+		ref, err := ParseSecretRef(test.spec)
+		if err != nil {
+			t.Fatalf("%s: %s", test.spec, err)
+		}
+		if ref.Env != test.env || ref.Path != test.path {
+			t.Errorf("%s: unexpected reference %+v", test.spec, ref)
+		}
+	}
+	for _, spec := range []string{"", "db=1VAR", "-db", "db=relative/path"} {
+		if _, err := ParseSecretRef(spec); err == nil {
+			t.Errorf("expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	img := &citadel.Image{
+		Name:        "app",
+		Environment: map[string]string{"DB_PASSWORD": "hunter2", "DEBUG": "1"},
+	}
+	refs := []*SecretRef{
+		{Name: "db-password", Env: "DB_PASSWORD"},
+		{Name: "tls.key", Path: "/dev/shm/tls.key"},
+	}
+	if err := SetSecretRefs(img, refs); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := SecretRefs(img)
+	if err != nil || len(parsed) != 2 {
+		t.Fatalf("unexpected references %v: %v", parsed, err)
+	}
+	RedactSecrets(img)
+	if img.Environment["DB_PASSWORD"] != RedactedSecret || img.Environment["DEBUG"] != "1" {
+		t.Errorf("unexpected environment after redacting: %v", img.Environment)
+	}
+}
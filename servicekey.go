@@ -0,0 +1,33 @@
+package shipyard
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrServiceKeyExpired = errors.New("service key expired")
+)
+
+// Expired reports whether the key has expired at now
+func (k *ServiceKey) Expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt)
+}
+
+// Remaining returns the lifetime left at now.  Keys that never expire and
+// expired keys return zero.
+func (k *ServiceKey) Remaining(now time.Time) time.Duration {
+	if k.ExpiresAt.IsZero() || k.Expired(now) {
+		return 0
+	}
+	return k.ExpiresAt.Sub(now)
+}
+
+// HasPermission reports whether the key grants p.  Keys without a
+// permission scope grant everything.
+func (k *ServiceKey) HasPermission(p string) bool {
+	if len(k.Permissions) == 0 {
+		return true
+	}
+	return grants(k.Permissions, p)
+}
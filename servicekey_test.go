@@ -0,0 +1,43 @@
+package shipyard
+
+import (
+	"testing"
+	"time"
+)
+
+func TestServiceKeyExpiry(t *testing.T) {
+	now := time.Now()
+	k := &ServiceKey{}
+	if k.Expired(now) || k.Remaining(now) != 0 {
+		t.Fatal("expected key without expiry to never expire")
+	}
+	k.ExpiresAt = now.Add(time.Hour)
+	if k.Expired(now) {
+		t.Fatal("expected key to be valid")
+	}
+	if r := k.Remaining(now); r != time.Hour {
+		t.Fatalf("expected 1h remaining; received %s", r)
+	}
+	if !k.Expired(now.Add(time.Hour)) {
+		t.Fatal("expected key to be expired")
+	}
+}
+
+func TestServiceKeyHasPermission(t *testing.T) {
+	k := &ServiceKey{}
+	if !k.HasPermission("accounts:write") {
+		t.Fatal("expected unscoped key to grant everything")
+	}
+	k.Permissions = []string{"containers:admin", "events:read"}
+	for p, expected := range map[string]bool{
+		"containers:run":  true,
+		"events:read":     true,
+		"events:write":    false,
+		"accounts:read":   false,
+		"containers:read": true,
+	} {
+		if k.HasPermission(p) != expected {
+			t.Errorf("%s: expected %v", p, expected)
+		}
+	}
+}
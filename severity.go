@@ -0,0 +1,50 @@
+package shipyard
+
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+var (
+	// eventSeverities are the event types above info severity
+	eventSeverities = map[string]string{
+		"engine-unreachable":  SeverityCritical,
+		"engine-down":         SeverityCritical,
+		"oom":                 SeverityCritical,
+		"die":                 SeverityWarning,
+		"kill":                SeverityWarning,
+		"drain-engine":        SeverityWarning,
+		"remove-engine":       SeverityWarning,
+		"disable-2fa":         SeverityWarning,
+		"delete-account":      SeverityWarning,
+		"deploy-rollback":     SeverityWarning,
+		"container-unhealthy": SeverityWarning,
+		"restart-limit":       SeverityCritical,
+	}
+	severityLevels = map[string]int{
+		SeverityInfo:     0,
+		SeverityWarning:  1,
+		SeverityCritical: 2,
+	}
+)
+
+// EventSeverity returns the default severity of an event type
+func EventSeverity(eventType string) string {
+	if s, ok := eventSeverities[eventType]; ok {
+		return s
+	}
+	return SeverityInfo
+}
+
+// ValidSeverity reports whether s is a known severity
+func ValidSeverity(s string) bool {
+	_, ok := severityLevels[s]
+	return ok
+}
+
+// SeverityAtLeast reports whether severity s is at or above min.  An empty
+// min accepts every severity.
+func SeverityAtLeast(s string, min string) bool {
+	return severityLevels[s] >= severityLevels[min]
+}
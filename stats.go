@@ -0,0 +1,23 @@
+package shipyard
+
+import "time"
+
+type (
+	// ContainerStats is a single resource usage sample for a container
+	ContainerStats struct {
+		ContainerID    string    `json:"container_id,omitempty"`
+		Time           time.Time `json:"time,omitempty"`
+		CpuPercent     float64   `json:"cpu_percent"`
+		MemoryUsage    uint64    `json:"memory_usage"`
+		MemoryLimit    uint64    `json:"memory_limit"`
+		NetworkRxBytes uint64    `json:"network_rx_bytes"`
+		NetworkTxBytes uint64    `json:"network_tx_bytes"`
+	}
+
+	// ProcessList is the process table of a container as reported by ps on
+	// its engine; each process has a value for every title
+	ProcessList struct {
+		Titles    []string   `json:"titles"`
+		Processes [][]string `json:"processes"`
+	}
+)
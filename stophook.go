@@ -0,0 +1,89 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	// StopHookEnv holds the stop hook of a container as json
+	StopHookEnv = "_SHIPYARD_STOP_HOOK"
+
+	// DefaultStopGracePeriod is the seconds containers without a grace
+	// period are given to exit when destroyed
+	DefaultStopGracePeriod = 10
+	// MaxStopGracePeriod bounds the grace period so stopping a container
+	// can not hold a request forever
+	MaxStopGracePeriod = 3600
+)
+
+var (
+	ErrInvalidStopHook = errors.New("invalid stop hook")
+)
+
+// StopHook is how a container is stopped gracefully.  Stop, restart and
+// destroy run the pre-stop command in the container, then send it SIGTERM
+// and kill it if it has not exited after the grace period.  Containers
+// without a stop hook are killed right away when destroyed.
+type StopHook struct {
+	// PreStop is run in the container before it is sent SIGTERM, for up to
+	// the grace period
+	PreStop []string `json:"pre_stop,omitempty" gorethink:"pre_stop"`
+	// GracePeriod is the seconds the container is given to exit; it is
+	// the least timeout of a stop or restart.  0 is DefaultStopGracePeriod.
+	GracePeriod int `json:"grace_period,omitempty" gorethink:"grace_period"`
+}
+
+func (h *StopHook) Validate() error {
+	if h.GracePeriod < 0 || h.GracePeriod > MaxStopGracePeriod {
+		return fmt.Errorf("%w: grace period must be between 0 and %d seconds", ErrInvalidStopHook, MaxStopGracePeriod)
+	}
+	for _, arg := range h.PreStop {
+		if arg == "" {
+			return fmt.Errorf("%w: empty pre-stop argument", ErrInvalidStopHook)
+		}
+	}
+	return nil
+}
+
+// Timeout returns the grace period of the hook
+func (h *StopHook) Timeout() int {
+	if h.GracePeriod == 0 {
+		return DefaultStopGracePeriod
+	}
+	return h.GracePeriod
+}
+
+// ContainerStopHook returns the stop hook of an image or nil
+func ContainerStopHook(image *citadel.Image) (*StopHook, error) {
+	if image == nil || image.Environment[StopHookEnv] == "" {
+		return nil, nil
+	}
+	var h *StopHook
+	if err := json.Unmarshal([]byte(image.Environment[StopHookEnv]), &h); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidStopHook, err)
+	}
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// SetStopHook sets the stop hook containers of the image are run with
+func SetStopHook(image *citadel.Image, h *StopHook) error {
+	if err := h.Validate(); err != nil {
+		return err
+	}
+	b, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	if image.Environment == nil {
+		image.Environment = map[string]string{}
+	}
+	image.Environment[StopHookEnv] = string(b)
+	return nil
+}
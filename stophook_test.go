@@ -0,0 +1,34 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestContainerStopHook(t *testing.T) {
+	img := &citadel.Image{Name: "postgres"}
+	if h, err := ContainerStopHook(img); err != nil || h != nil {
+		t.Errorf("expected no stop hook; received %+v: %v", h, err)
+	}
+	hook := &StopHook{PreStop: []string{"pg_ctl", "stop", "-m", "fast"}, GracePeriod: 60}
+	if err := SetStopHook(img, hook); err != nil {
+		t.Fatal(err)
+	}
+	h, err := ContainerStopHook(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.PreStop) != 4 || h.Timeout() != 60 {
+		t.Errorf("unexpected stop hook %+v", h)
+	}
+	if (&StopHook{}).Timeout() != DefaultStopGracePeriod {
+		t.Error("expected the default grace period")
+	}
+	for _, h := range []*StopHook{{GracePeriod: -1}, {GracePeriod: MaxStopGracePeriod + 1}, {PreStop: []string{"sync", ""}}} {
+		if err := SetStopHook(img, h); !errors.Is(err, ErrInvalidStopHook) {
+			t.Errorf("expected ErrInvalidStopHook for %+v; received %v", h, err)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package shipyard
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	// TemplateEnv holds the launch template a container was run from
+	TemplateEnv = "_SHIPYARD_TEMPLATE"
+)
+
+var (
+	ErrInvalidTemplate = errors.New("invalid template")
+)
+
+type (
+	// LaunchTemplate is a named preset of how containers of a service are
+	// launched, so callers only give what differs from it
+	LaunchTemplate struct {
+		ID          string            `json:"id,omitempty" gorethink:"id,omitempty"`
+		Name        string            `json:"name,omitempty" gorethink:"name"`
+		Description string            `json:"description,omitempty" gorethink:"description"`
+		Image       string            `json:"image,omitempty" gorethink:"image"`
+		Cpus        float64           `json:"cpus,omitempty" gorethink:"cpus"`
+		Memory      float64           `json:"memory,omitempty" gorethink:"memory"`
+		Args        []string          `json:"args,omitempty" gorethink:"args"`
+		Environment map[string]string `json:"environment,omitempty" gorethink:"environment"`
+		Ports       []*citadel.Port   `json:"ports,omitempty" gorethink:"ports"`
+		// Constraints are engine label expressions (see ParseConstraints)
+		Constraints []string `json:"constraints,omitempty" gorethink:"constraints"`
+		// Pool is the engine pool the containers are placed in
+		Pool string `json:"pool,omitempty" gorethink:"pool"`
+		// Labels are given to every container (see SetLabels)
+		Labels map[string]string `json:"labels,omitempty" gorethink:"labels"`
+		// RestartPolicy is a restart policy such as on-failure:5 (see
+		// ParseRestartPolicy)
+		RestartPolicy string    `json:"restart_policy,omitempty" gorethink:"restart_policy"`
+		Created       time.Time `json:"created,omitempty" gorethink:"created"`
+		Updated       time.Time `json:"updated,omitempty" gorethink:"updated"`
+	}
+
+	// TemplateOverrides change a launch template for a single run.  Empty
+	// fields keep the template values; environment variables and labels
+	// are merged into those of the template and constraints are added.
+	TemplateOverrides struct {
+		Image         string            `json:"image,omitempty"`
+		ContainerName string            `json:"container_name,omitempty"`
+		Cpus          float64           `json:"cpus,omitempty"`
+		Memory        float64           `json:"memory,omitempty"`
+		Args          []string          `json:"args,omitempty"`
+		Environment   map[string]string `json:"environment,omitempty"`
+		Constraints   []string          `json:"constraints,omitempty"`
+		Labels        map[string]string `json:"labels,omitempty"`
+	}
+)
+
+func (t *LaunchTemplate) Validate() error {
+	if !secretNamePattern.MatchString(t.Name) {
+		return fmt.Errorf("%w: name must be letters, digits, '_', '.' or '-'", ErrInvalidTemplate)
+	}
+	if t.Image == "" {
+		return fmt.Errorf("%w: image is required", ErrInvalidTemplate)
+	}
+	if t.Cpus < 0 || t.Memory < 0 {
+		return fmt.Errorf("%w: cpus and memory must not be negative", ErrInvalidTemplate)
+	}
+	for k := range t.Environment {
+		if !envNamePattern.MatchString(k) {
+			return fmt.Errorf("%w: invalid env var %q", ErrInvalidTemplate, k)
+		}
+	}
+	if err := ValidateEnvironment(t.Environment, nil); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidTemplate, err)
+	}
+	for _, c := range t.Constraints {
+		if _, err := ParseConstraints(c); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidTemplate, err)
+		}
+	}
+	if err := validateLabels(t.Labels); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidTemplate, err)
+	}
+	if _, err := ParseRestartPolicy(t.RestartPolicy); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidTemplate, err)
+	}
+	return nil
+}
+
+// ContainerImage returns the image containers of the template are run with
+// after applying the overrides, which may be nil
+func (t *LaunchTemplate) ContainerImage(o *TemplateOverrides) (*citadel.Image, error) {
+	if o == nil {
+		o = &TemplateOverrides{}
+	}
+	env := map[string]string{}
+	for k, v := range t.Environment {
+		env[k] = v
+	}
+	for k, v := range o.Environment {
+		if !envNamePattern.MatchString(k) {
+			return nil, fmt.Errorf("%w: invalid env var %q", ErrInvalidTemplate, k)
+		}
+		env[k] = v
+	}
+	if err := ValidateEnvironment(o.Environment, nil); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTemplate, err)
+	}
+	env[TemplateEnv] = t.Name
+	if t.Pool != "" {
+		env[PoolEnv] = t.Pool
+	}
+	for _, c := range o.Constraints {
+		if _, err := ParseConstraints(c); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidTemplate, err)
+		}
+	}
+	ports := []*citadel.Port{}
+	for _, p := range t.Ports {
+		port := *p
+		ports = append(ports, &port)
+	}
+	img := &citadel.Image{
+		Name:          t.Image,
+		ContainerName: o.ContainerName,
+		Cpus:          t.Cpus,
+		Memory:        t.Memory,
+		Args:          append([]string{}, t.Args...),
+		Environment:   env,
+		BindPorts:     ports,
+		Labels:        append(append([]string{}, t.Constraints...), o.Constraints...),
+		Type:          "service",
+		Publish:       len(ports) > 0,
+	}
+	if o.Image != "" {
+		img.Name = o.Image
+	}
+	if o.Cpus > 0 {
+		img.Cpus = o.Cpus
+	}
+	if o.Memory > 0 {
+		img.Memory = o.Memory
+	}
+	if len(o.Args) > 0 {
+		img.Args = o.Args
+	}
+	labels := map[string]string{}
+	for k, v := range t.Labels {
+		labels[k] = v
+	}
+	for k, v := range o.Labels {
+		labels[k] = v
+	}
+	if err := SetLabels(img, labels); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTemplate, err)
+	}
+	p, err := ParseRestartPolicy(t.RestartPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTemplate, err)
+	}
+	if p.Name != RestartNo {
+		if err := SetRestartPolicy(img, p); err != nil {
+			return nil, err
+		}
+	}
+	return img, nil
+}
+
+// ContainerTemplate returns the launch template a container was run from
+func ContainerTemplate(c *citadel.Container) string {
+	if c == nil || c.Image == nil {
+		return ""
+	}
+	return c.Image.Environment[TemplateEnv]
+}
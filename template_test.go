@@ -0,0 +1,77 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLaunchTemplateValidate(t *testing.T) {
+	valid := &LaunchTemplate{Name: "web", Image: "nginx", Constraints: []string{"region=us-east"}, RestartPolicy: "on-failure:3"}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tmpl := range []*LaunchTemplate{
+		{Image: "nginx"},
+		{Name: "web"},
+		{Name: "web", Image: "nginx", Memory: -1},
+		{Name: "web", Image: "nginx", Environment: map[string]string{"BAD-NAME": "x"}},
+		{Name: "web", Image: "nginx", Environment: map[string]string{OwnerEnv: "admin"}},
+		{Name: "web", Image: "nginx", RestartPolicy: "sometimes"},
+	} {
+		if err := tmpl.Validate(); !errors.Is(err, ErrInvalidTemplate) {
+			t.Errorf("expected ErrInvalidTemplate for %+v; received %v", tmpl, err)
+		}
+	}
+}
+
+func TestLaunchTemplateContainerImage(t *testing.T) {
+	tmpl := &LaunchTemplate{
+		Name:          "web",
+		Image:         "nginx:1.7",
+		Cpus:          0.5,
+		Memory:        256,
+		Environment:   map[string]string{"MODE": "prod", "WORKERS": "4"},
+		Constraints:   []string{"region=us-east"},
+		Labels:        map[string]string{"tier": "web"},
+		RestartPolicy: "always",
+	}
+	img, err := tmpl.ContainerImage(&TemplateOverrides{
+		Image:       "nginx:1.8",
+		Memory:      512,
+		Environment: map[string]string{"WORKERS": "8"},
+		Constraints: []string{"ssd=true"},
+		Labels:      map[string]string{"team": "edge"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Name != "nginx:1.8" || img.Cpus != 0.5 || img.Memory != 512 {
+		t.Errorf("unexpected image %+v", img)
+	}
+	if img.Environment["MODE"] != "prod" || img.Environment["WORKERS"] != "8" || img.Environment[TemplateEnv] != "web" {
+		t.Errorf("unexpected environment %v", img.Environment)
+	}
+	if len(img.Labels) != 2 {
+		t.Errorf("expected both constraints; received %v", img.Labels)
+	}
+	labels, err := ImageLabels(img)
+	if err != nil || labels["tier"] != "web" || labels["team"] != "edge" {
+		t.Errorf("expected merged labels; received %v %v", labels, err)
+	}
+	if p, _ := ContainerRestartPolicy(img); p.Name != RestartAlways {
+		t.Errorf("expected the template restart policy; received %v", p)
+	}
+	if tmpl.Environment["WORKERS"] != "4" || len(tmpl.Constraints) != 1 {
+		t.Error("expected the template to be left unchanged")
+	}
+
+	if _, err := tmpl.ContainerImage(&TemplateOverrides{Constraints: []string{"=us-east"}}); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("expected ErrInvalidTemplate; received %v", err)
+	}
+	if _, err := tmpl.ContainerImage(&TemplateOverrides{Environment: map[string]string{TemplateEnv: "other"}}); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("expected ErrInvalidTemplate for a reserved variable; received %v", err)
+	}
+	if img, err := tmpl.ContainerImage(nil); err != nil || img.Name != "nginx:1.7" {
+		t.Errorf("expected the template image; received %+v %v", img, err)
+	}
+}
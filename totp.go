@@ -0,0 +1,104 @@
+package shipyard
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// TOTPPeriod is the lifetime of a one time code (RFC 6238)
+	TOTPPeriod = 30 * time.Second
+	// TOTPDigits is the length of a one time code
+	TOTPDigits = 6
+	// totpSkew is the number of periods either side of now that are
+	// accepted to allow for clock drift
+	totpSkew = 1
+)
+
+var (
+	// ErrOTPRequired is returned by login when the account has two-factor
+	// auth enabled and no code was given
+	ErrOTPRequired = errors.New("one time code required")
+	// ErrInvalidOTP is returned by login when the code given is wrong or
+	// was already used
+	ErrInvalidOTP = errors.New("invalid one time code")
+
+	totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+)
+
+// TOTPEnrollment is returned when two-factor auth is enabled for an
+// account.  URI is the otpauth:// payload to render as a QR code.
+type TOTPEnrollment struct {
+	Secret string `json:"secret,omitempty"`
+	URI    string `json:"uri,omitempty"`
+}
+
+// NewTOTPSecret returns a random base32 encoded secret
+func NewTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TOTPURI returns the otpauth:// provisioning uri for authenticator apps
+func TOTPURI(issuer string, account string, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("digits", fmt.Sprint(TOTPDigits))
+	v.Set("period", fmt.Sprint(int(TOTPPeriod/time.Second)))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
+
+// TOTPStep returns the time step containing t
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod/time.Second)
+}
+
+// TOTPCode returns the code for secret at the time step
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", err
+	}
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", TOTPDigits, code%1000000), nil
+}
+
+// ValidateTOTP checks code against secret at t and returns the matching
+// time step so callers can reject reuse of a code
+func ValidateTOTP(secret string, code string, t time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+	now := TOTPStep(t)
+	for step := now - totpSkew; step <= now+totpSkew; step++ {
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,41 @@
+package shipyard
+
+import (
+	"testing"
+	"time"
+)
+
+// secret "12345678901234567890" from the RFC 6238 test vectors
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	for unix, expected := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		code, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != expected {
+			t.Errorf("%d: expected %s; received %s", unix, expected, code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	previous, _ := TOTPCode(rfcSecret, TOTPStep(now)-1)
+	if _, ok := ValidateTOTP(rfcSecret, previous, now); !ok {
+		t.Error("expected code from the previous period to be accepted")
+	}
+	old, _ := TOTPCode(rfcSecret, TOTPStep(now)-3)
+	if _, ok := ValidateTOTP(rfcSecret, old, now); ok {
+		t.Error("expected stale code to be rejected")
+	}
+	if _, ok := ValidateTOTP(rfcSecret, "", now); ok {
+		t.Error("expected empty code to be rejected")
+	}
+}
@@ -0,0 +1,381 @@
+package shipyard
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// TraceparentHeader carries the w3c trace context of a request
+	TraceparentHeader = "Traceparent"
+
+	SpanKindInternal = 1
+	SpanKindServer   = 2
+	SpanKindClient   = 3
+
+	// traceBatchSize is the number of spans buffered before an export
+	traceBatchSize = 256
+	// traceFlushInterval is the longest time spans are buffered
+	traceFlushInterval = 5 * time.Second
+)
+
+var (
+	ErrInvalidTraceparent = errors.New("invalid traceparent")
+)
+
+type (
+	// SpanContext identifies a span within a trace
+	SpanContext struct {
+		TraceID string
+		SpanID  string
+		Sampled bool
+	}
+
+	// Span is a timed operation of a trace
+	Span struct {
+		SpanContext
+		ParentID   string
+		Name       string
+		Kind       int
+		Start      time.Time
+		End        time.Time
+		Attributes map[string]string
+		Error      string
+
+		tracer *Tracer
+	}
+
+	// SpanExporter sends finished spans to a tracing backend
+	SpanExporter interface {
+		Export(spans []*Span) error
+	}
+
+	// Tracer records spans and exports them in batches.  A nil *Tracer
+	// is valid and records nothing.
+	Tracer struct {
+		exporter SpanExporter
+		mu       sync.Mutex
+		spans    []*Span
+		done     chan struct{}
+		closed   sync.Once
+		// ErrorHandler is called with failed exports
+		ErrorHandler func(err error)
+	}
+
+	// OTLPExporter exports spans with the OpenTelemetry protocol over
+	// http in its json encoding
+	OTLPExporter struct {
+		// Endpoint is the collector url, i.e. http://localhost:4318
+		Endpoint string
+		Service  string
+		Headers  map[string]string
+		Client   *http.Client
+	}
+
+	spanContextKey struct{}
+)
+
+// ParseTraceparent parses a w3c traceparent header value
+func ParseTraceparent(v string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	for _, p := range parts[:4] {
+		if _, err := hex.DecodeString(p); err != nil || strings.ToLower(p) != p {
+			return SpanContext{}, ErrInvalidTraceparent
+		}
+	}
+	if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	flags, _ := strconv.ParseUint(parts[3], 16, 8)
+	return SpanContext{
+		TraceID: parts[1],
+		SpanID:  parts[2],
+		Sampled: flags&1 == 1,
+	}, nil
+}
+
+// Traceparent returns the w3c traceparent header value of the span context
+func (c SpanContext) Traceparent() string {
+	flags := "00"
+	if c.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", c.TraceID, c.SpanID, flags)
+}
+
+// IsValid reports whether the span context identifies a span
+func (c SpanContext) IsValid() bool {
+	return c.TraceID != "" && c.SpanID != ""
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ContextWithSpan returns a context carrying the span context
+func ContextWithSpan(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanFromContext returns the span context carried by ctx
+func SpanFromContext(ctx context.Context) (SpanContext, bool) {
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// NewTracer returns a tracer exporting to exporter in the background;
+// Close flushes the remaining spans.
+func NewTracer(exporter SpanExporter) *Tracer {
+	t := &Tracer{
+		exporter: exporter,
+		done:     make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// NewTracerFromEnv returns a tracer exporting to the collector set by the
+// OTEL_EXPORTER_OTLP_ENDPOINT (or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT)
+// environment variable or nil when tracing is not configured.
+// OTEL_SERVICE_NAME overrides service.
+func NewTracerFromEnv(service string) *Tracer {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		if e := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); e != "" {
+			endpoint = strings.TrimSuffix(e, "/") + "/v1/traces"
+		}
+	}
+	if endpoint == "" {
+		return nil
+	}
+	if s := os.Getenv("OTEL_SERVICE_NAME"); s != "" {
+		service = s
+	}
+	headers := map[string]string{}
+	for _, h := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		kv := strings.SplitN(h, "=", 2)
+		if len(kv) == 2 {
+			headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	return NewTracer(&OTLPExporter{
+		Endpoint: endpoint,
+		Service:  service,
+		Headers:  headers,
+	})
+}
+
+// StartSpan starts a span that is a child of the span carried by ctx, or
+// the root of a new trace, and returns a context carrying it
+func (t *Tracer) StartSpan(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	s := &Span{
+		Name:       name,
+		Kind:       kind,
+		Start:      time.Now(),
+		Attributes: map[string]string{},
+		tracer:     t,
+	}
+	if parent, ok := SpanFromContext(ctx); ok {
+		s.TraceID = parent.TraceID
+		s.ParentID = parent.SpanID
+		s.Sampled = parent.Sampled
+	} else {
+		s.TraceID = randomHex(16)
+		s.Sampled = true
+	}
+	s.SpanID = randomHex(8)
+	return ContextWithSpan(ctx, s.SpanContext), s
+}
+
+// SetAttribute records a key value pair on the span
+func (s *Span) SetAttribute(key string, value string) {
+	s.Attributes[key] = value
+}
+
+// SetError marks the span as failed
+func (s *Span) SetError(err error) {
+	if err != nil {
+		s.Error = err.Error()
+	}
+}
+
+// Finish ends the span and queues it for export; unsampled spans and
+// spans of a nil tracer are dropped
+func (s *Span) Finish() {
+	s.End = time.Now()
+	t := s.tracer
+	if t == nil || !s.Sampled {
+		return
+	}
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	full := len(t.spans) >= traceBatchSize
+	t.mu.Unlock()
+	if full {
+		go t.Flush()
+	}
+}
+
+func (t *Tracer) run() {
+	tick := time.NewTicker(traceFlushInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			t.Flush()
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// Flush exports the finished spans
+func (t *Tracer) Flush() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return
+	}
+	if err := t.exporter.Export(spans); err != nil && t.ErrorHandler != nil {
+		t.ErrorHandler(err)
+	}
+}
+
+// Close stops the background export and flushes the remaining spans
+func (t *Tracer) Close() {
+	if t == nil {
+		return
+	}
+	t.closed.Do(func() {
+		close(t.done)
+	})
+	t.Flush()
+}
+
+type (
+	otlpKeyValue struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue string `json:"stringValue"`
+		} `json:"value"`
+	}
+
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            struct {
+			Code    int    `json:"code,omitempty"`
+			Message string `json:"message,omitempty"`
+		} `json:"status"`
+	}
+)
+
+func otlpAttributes(attrs map[string]string) []otlpKeyValue {
+	kvs := []otlpKeyValue{}
+	for k, v := range attrs {
+		kv := otlpKeyValue{Key: k}
+		kv.Value.StringValue = v
+		kvs = append(kvs, kv)
+	}
+	return kvs
+}
+
+// Encode returns the otlp json request exporting spans
+func (e *OTLPExporter) Encode(spans []*Span) ([]byte, error) {
+	encoded := []*otlpSpan{}
+	for _, s := range spans {
+		o := &otlpSpan{
+			TraceID:           s.TraceID,
+			SpanID:            s.SpanID,
+			ParentSpanID:      s.ParentID,
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.Attributes),
+		}
+		if s.Error != "" {
+			// STATUS_CODE_ERROR
+			o.Status.Code = 2
+			o.Status.Message = s.Error
+		}
+		encoded = append(encoded, o)
+	}
+	req := map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]string{"service.name": e.Service}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]string{"name": "shipyard", "version": VERSION},
+						"spans": encoded,
+					},
+				},
+			},
+		},
+	}
+	return json.Marshal(req)
+}
+
+func (e *OTLPExporter) Export(spans []*Span) error {
+	b, err := e.Encode(spans)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", e.Endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+	client := e.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("error exporting %d spans: %s", len(spans), resp.Status)
+	}
+	return nil
+}
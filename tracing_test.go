@@ -0,0 +1,76 @@
+package shipyard
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type testExporter struct {
+	spans []*Span
+}
+
+func (e *testExporter) Export(spans []*Span) error {
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func TestParseTraceparent(t *testing.T) {
+	v := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID != "00f067aa0ba902b7" || !sc.Sampled {
+		t.Errorf("unexpected span context %+v", sc)
+	}
+	if sc.Traceparent() != v {
+		t.Errorf("expected %s; received %s", v, sc.Traceparent())
+	}
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceparent(invalid); err != ErrInvalidTraceparent {
+			t.Errorf("expected %q to be invalid; received %v", invalid, err)
+		}
+	}
+}
+
+func TestTracerStartSpan(t *testing.T) {
+	e := &testExporter{}
+	tracer := NewTracer(e)
+	ctx, root := tracer.StartSpan(context.Background(), "root", SpanKindServer)
+	_, child := tracer.StartSpan(ctx, "child", SpanKindInternal)
+	child.Finish()
+	root.Finish()
+	tracer.Close()
+
+	if child.TraceID != root.TraceID || child.ParentID != root.SpanID {
+		t.Errorf("expected child of %+v; received %+v", root.SpanContext, child)
+	}
+	if len(e.spans) != 2 {
+		t.Fatalf("expected 2 exported spans; received %d", len(e.spans))
+	}
+
+	b, err := (&OTLPExporter{Service: "test"}).Encode(e.spans)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"traceId":"`+root.TraceID+`"`) {
+		t.Errorf("expected the trace id to be encoded: %s", b)
+	}
+}
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	_, span := tracer.StartSpan(nil, "span", SpanKindInternal)
+	span.Finish()
+	tracer.Close()
+	if !span.IsValid() {
+		t.Errorf("expected a valid span context")
+	}
+}
@@ -0,0 +1,15 @@
+package shipyard
+
+import "strings"
+
+// UnixScheme prefixes controller addresses on a unix socket, i.e.
+// unix:///var/run/shipyard.sock
+const UnixScheme = "unix://"
+
+// UnixSocketPath returns the socket path of a unix:// address
+func UnixSocketPath(addr string) (string, bool) {
+	if !strings.HasPrefix(addr, UnixScheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(addr, UnixScheme), true
+}
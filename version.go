@@ -1,3 +1,48 @@
 package shipyard
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
 const VERSION = "2.0.10"
+
+// APIVersion is the version of the api spoken by this client and
+// controller.  It changes whenever a request or response changes in a way
+// older clients or controllers cannot decode.
+const APIVersion = "1"
+
+var (
+	ErrIncompatibleAPIVersion = errors.New("incompatible api version")
+
+	// SupportedAPIVersions are the api versions the controller serves
+	SupportedAPIVersions = []string{APIVersion}
+)
+
+// VersionInfo is the version of a controller and the api versions it
+// supports
+type VersionInfo struct {
+	Version     string   `json:"version"`
+	APIVersion  string   `json:"api_version"`
+	APIVersions []string `json:"api_versions"`
+}
+
+// Supports reports whether the controller serves apiVersion
+func (v *VersionInfo) Supports(apiVersion string) bool {
+	for _, s := range v.APIVersions {
+		if s == apiVersion {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckAPIVersion returns ErrIncompatibleAPIVersion when the controller
+// does not serve the api version of this client
+func CheckAPIVersion(v *VersionInfo) error {
+	if v.Supports(APIVersion) {
+		return nil
+	}
+	return fmt.Errorf("%w: client %s speaks api %s; controller %s supports %s", ErrIncompatibleAPIVersion, VERSION, APIVersion, v.Version, strings.Join(v.APIVersions, ", "))
+}
@@ -0,0 +1,17 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckAPIVersion(t *testing.T) {
+	v := &VersionInfo{Version: VERSION, APIVersion: APIVersion, APIVersions: SupportedAPIVersions}
+	if err := CheckAPIVersion(v); err != nil {
+		t.Fatal(err)
+	}
+	v.APIVersions = []string{"0"}
+	if err := CheckAPIVersion(v); !errors.Is(err, ErrIncompatibleAPIVersion) {
+		t.Errorf("expected ErrIncompatibleAPIVersion; received %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package shipyard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/citadel/citadel"
+)
+
+const (
+	// VolumesEnv holds the json encoded volume mounts of a container
+	VolumesEnv = "_SHIPYARD_VOLUMES"
+
+	// VolumeRoot is the directory of volumes created without a path
+	VolumeRoot = "/var/lib/shipyard/volumes"
+)
+
+var (
+	ErrInvalidVolume = errors.New("invalid volume")
+)
+
+// Volume is a named directory on one engine.  Containers mounting it are
+// always placed on that engine so they find their data again when they are
+// relaunched or rescheduled.
+type Volume struct {
+	ID   string `json:"id,omitempty" gorethink:"id,omitempty"`
+	Name string `json:"name,omitempty" gorethink:"name"`
+	// Engine is the id of the engine holding the data; it may be created
+	// with the engine name
+	Engine string `json:"engine,omitempty" gorethink:"engine"`
+	// Path is the directory on the engine host
+	Path    string    `json:"path,omitempty" gorethink:"path"`
+	Created time.Time `json:"created,omitempty" gorethink:"created"`
+}
+
+// VolumeMount mounts a volume at Path in the containers of an image
+type VolumeMount struct {
+	Name     string `json:"name" gorethink:"name"`
+	Path     string `json:"path" gorethink:"path"`
+	ReadOnly bool   `json:"read_only,omitempty" gorethink:"read_only"`
+}
+
+// Validate checks the volume and defaults its path to a directory named
+// like it under VolumeRoot
+func (v *Volume) Validate() error {
+	if !secretNamePattern.MatchString(v.Name) {
+		return fmt.Errorf("%w: name must be letters, digits, '_', '.' or '-'", ErrInvalidVolume)
+	}
+	if v.Engine == "" {
+		return fmt.Errorf("%w: engine is required", ErrInvalidVolume)
+	}
+	if v.Path == "" {
+		v.Path = path.Join(VolumeRoot, v.Name)
+	}
+	if !strings.HasPrefix(v.Path, "/") || strings.Contains(v.Path, ":") {
+		return fmt.Errorf("%w: path %q must be absolute", ErrInvalidVolume, v.Path)
+	}
+	return nil
+}
+
+func (m *VolumeMount) Validate() error {
+	if !secretNamePattern.MatchString(m.Name) {
+		return fmt.Errorf("%w: invalid volume name %q", ErrInvalidVolume, m.Name)
+	}
+	if !strings.HasPrefix(m.Path, "/") || strings.Contains(m.Path, ":") {
+		return fmt.Errorf("%w: mount path %q must be absolute", ErrInvalidVolume, m.Path)
+	}
+	return nil
+}
+
+// Bind returns the docker bind of the mount for a volume
+func (m *VolumeMount) Bind(v *Volume) string {
+	bind := fmt.Sprintf("%s:%s", v.Path, m.Path)
+	if m.ReadOnly {
+		bind += ":ro"
+	}
+	return bind
+}
+
+// ParseVolumeMount parses the command line form of a volume mount:
+// <name>:<container-path>[:ro]
+func ParseVolumeMount(s string) (*VolumeMount, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 || (len(parts) == 3 && parts[2] != "ro") {
+		return nil, fmt.Errorf("%w: mounts must be <name>:<path>[:ro]", ErrInvalidVolume)
+	}
+	mount := &VolumeMount{
+		Name:     parts[0],
+		Path:     parts[1],
+		ReadOnly: len(parts) == 3,
+	}
+	if err := mount.Validate(); err != nil {
+		return nil, err
+	}
+	return mount, nil
+}
+
+// VolumeMounts returns the volume mounts of an image
+func VolumeMounts(image *citadel.Image) ([]*VolumeMount, error) {
+	if image == nil || image.Environment[VolumesEnv] == "" {
+		return nil, nil
+	}
+	mounts := []*VolumeMount{}
+	if err := json.Unmarshal([]byte(image.Environment[VolumesEnv]), &mounts); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidVolume, err)
+	}
+	for _, m := range mounts {
+		if err := m.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return mounts, nil
+}
+
+// SetVolumeMounts sets the volumes mounted into containers of the image
+func SetVolumeMounts(image *citadel.Image, mounts []*VolumeMount) error {
+	if image.Environment == nil {
+		image.Environment = map[string]string{}
+	}
+	if len(mounts) == 0 {
+		delete(image.Environment, VolumesEnv)
+		return nil
+	}
+	paths := map[string]bool{}
+	for _, m := range mounts {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+		if paths[m.Path] {
+			return fmt.Errorf("%w: %s is mounted more than once", ErrInvalidVolume, m.Path)
+		}
+		paths[m.Path] = true
+	}
+	b, err := json.Marshal(mounts)
+	if err != nil {
+		return err
+	}
+	image.Environment[VolumesEnv] = string(b)
+	return nil
+}
@@ -0,0 +1,69 @@
+package shipyard
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/citadel/citadel"
+)
+
+func TestParseVolumeMount(t *testing.T) {
+	m, err := ParseVolumeMount("pgdata:/var/lib/postgresql/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "pgdata" || m.Path != "/var/lib/postgresql/data" || m.ReadOnly {
+		t.Fatalf("unexpected mount %+v", m)
+	}
+	m, err = ParseVolumeMount("assets:/srv:ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.ReadOnly {
+		t.Fatal("expected a read only mount")
+	}
+	for _, spec := range []string{"pgdata", "pgdata:data", "pgdata:/data:rw", ":/data"} {
+		if _, err := ParseVolumeMount(spec); !errors.Is(err, ErrInvalidVolume) {
+			t.Errorf("expected %q to be rejected; got %v", spec, err)
+		}
+	}
+}
+
+func TestVolumeValidate(t *testing.T) {
+	v := &Volume{Name: "pgdata", Engine: "local"}
+	if err := v.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if v.Path != "/var/lib/shipyard/volumes/pgdata" {
+		t.Fatalf("unexpected default path %s", v.Path)
+	}
+	if err := (&Volume{Name: "pgdata"}).Validate(); err == nil {
+		t.Fatal("expected a volume without engine to be rejected")
+	}
+	if err := (&Volume{Name: "pgdata", Engine: "local", Path: "data"}).Validate(); err == nil {
+		t.Fatal("expected a relative path to be rejected")
+	}
+	mount := &VolumeMount{Name: "pgdata", Path: "/data", ReadOnly: true}
+	if b := mount.Bind(v); b != "/var/lib/shipyard/volumes/pgdata:/data:ro" {
+		t.Fatalf("unexpected bind %s", b)
+	}
+}
+
+func TestSetVolumeMounts(t *testing.T) {
+	img := &citadel.Image{Name: "postgres"}
+	mounts := []*VolumeMount{{Name: "pgdata", Path: "/data"}}
+	if err := SetVolumeMounts(img, mounts); err != nil {
+		t.Fatal(err)
+	}
+	got, err := VolumeMounts(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "pgdata" || got[0].Path != "/data" {
+		t.Fatalf("unexpected mounts %v", got)
+	}
+	dup := []*VolumeMount{{Name: "a", Path: "/data"}, {Name: "b", Path: "/data"}}
+	if err := SetVolumeMounts(img, dup); err == nil {
+		t.Fatal("expected duplicate mount paths to be rejected")
+	}
+}
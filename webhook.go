@@ -0,0 +1,44 @@
+package shipyard
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const (
+	// WebhookSignatureHeader carries "sha256=<hex hmac>" of the payload
+	// keyed with the webhook secret
+	WebhookSignatureHeader = "X-Shipyard-Signature"
+	// WebhookEventHeader carries the event type
+	WebhookEventHeader = "X-Shipyard-Event"
+)
+
+// Webhook receives cluster events as signed json POST requests
+type Webhook struct {
+	ID     string `json:"id,omitempty" gorethink:"id,omitempty"`
+	URL    string `json:"url,omitempty" gorethink:"url"`
+	Secret string `json:"secret,omitempty" gorethink:"secret"`
+	// EventTypes limits delivery to these event types; empty delivers
+	// every event
+	EventTypes []string `json:"event_types,omitempty" gorethink:"event_types"`
+}
+
+// Match reports whether the event should be delivered to the webhook
+func (w *Webhook) Match(e *Event) bool {
+	f := &EventFilter{Types: w.EventTypes}
+	return f.Match(e)
+}
+
+// WebhookSignature returns the signature header value for payload.
+// Receivers verify deliveries by computing it with the shared secret.
+func WebhookSignature(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature reports whether signature is valid for payload
+func VerifyWebhookSignature(secret string, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(WebhookSignature(secret, payload)), []byte(signature))
+}
@@ -0,0 +1,27 @@
+package shipyard
+
+import (
+	"testing"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	payload := []byte(`{"type":"start"}`)
+	sig := WebhookSignature("secret", payload)
+	if !VerifyWebhookSignature("secret", payload, sig) {
+		t.Fatal("expected signature to verify")
+	}
+	if VerifyWebhookSignature("other", payload, sig) {
+		t.Fatal("expected signature with another secret to fail")
+	}
+}
+
+func TestWebhookMatch(t *testing.T) {
+	h := &Webhook{}
+	if !h.Match(&Event{Type: "die"}) {
+		t.Fatal("expected webhook without event types to match")
+	}
+	h.EventTypes = []string{"start"}
+	if h.Match(&Event{Type: "die"}) {
+		t.Fatal("expected die event not to match")
+	}
+}
@@ -0,0 +1,316 @@
+// Package websocket implements the subset of RFC 6455 used to carry
+// container streams between the controller, browsers and the cli.  A Conn
+// is a byte stream: writes are sent as binary messages and reads return the
+// payload of data messages in order.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+
+	finBit  = 0x80
+	maskBit = 0x80
+
+	// maxControlPayload is the largest payload of a close, ping or pong
+	maxControlPayload = 125
+
+	closeNormal = 1000
+
+	acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+var (
+	ErrBadHandshake = errors.New("websocket: bad handshake")
+	ErrBadOrigin    = errors.New("websocket: origin does not match host")
+	ErrBadFrame     = errors.New("websocket: bad frame")
+)
+
+// IsUpgrade reports whether a request asks for a websocket connection
+func IsUpgrade(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") &&
+		strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// Upgrade completes the server handshake and takes over the connection of
+// the request.  Header is added to the handshake response.  The Origin must
+// be given and match the request host so other sites cannot use the session
+// cookie of a user.
+func Upgrade(w http.ResponseWriter, r *http.Request, header http.Header) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != "GET" || !IsUpgrade(r) || r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		http.Error(w, ErrBadHandshake.Error(), http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+	u, err := url.Parse(r.Header.Get("Origin"))
+	if err != nil || u.Host == "" || !strings.EqualFold(u.Host, r.Host) {
+		http.Error(w, ErrBadOrigin.Error(), http.StatusForbidden)
+		return nil, ErrBadOrigin
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("websocket: connection does not support hijacking")
+	}
+	nc, buf, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	resp := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"
+	resp += fmt.Sprintf("Sec-WebSocket-Accept: %s\r\n", AcceptKey(key))
+	for k, vs := range header {
+		for _, v := range vs {
+			resp += fmt.Sprintf("%s: %s\r\n", k, v)
+		}
+	}
+	if _, err := io.WriteString(nc, resp+"\r\n"); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return newConn(nc, buf.Reader, false), nil
+}
+
+// NewKey returns a random Sec-WebSocket-Key for a client handshake
+func NewKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// AcceptKey returns the Sec-WebSocket-Accept value for a handshake key
+func AcceptKey(key string) string {
+	h := sha1.New()
+	io.WriteString(h, key+acceptGUID)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// SetHandshakeHeaders adds the client handshake headers to req and returns
+// the key to check the response with CheckHandshake
+func SetHandshakeHeaders(req *http.Request) string {
+	key := NewKey()
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Origin", req.URL.Scheme+"://"+req.URL.Host)
+	return key
+}
+
+// CheckHandshake verifies the server accepted the handshake made with key
+func CheckHandshake(resp *http.Response, key string) error {
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		!strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") ||
+		resp.Header.Get("Sec-WebSocket-Accept") != AcceptKey(key) {
+		return ErrBadHandshake
+	}
+	return nil
+}
+
+// NewClientConn returns the client side of a connection whose handshake
+// has completed
+func NewClientConn(rwc io.ReadWriteCloser) *Conn {
+	return newConn(rwc, bufio.NewReader(rwc), true)
+}
+
+// Conn is a websocket connection.  Read and Write may be called from
+// different goroutines.
+type Conn struct {
+	rwc    io.ReadWriteCloser
+	br     *bufio.Reader
+	client bool
+
+	// remaining, mask and maskPos describe the data frame being read
+	remaining int64
+	masked    bool
+	mask      [4]byte
+	maskPos   int
+
+	wmu    sync.Mutex
+	closed bool
+}
+
+func newConn(rwc io.ReadWriteCloser, br *bufio.Reader, client bool) *Conn {
+	return &Conn{
+		rwc:    rwc,
+		br:     br,
+		client: client,
+	}
+}
+
+// Read reads the payload of data messages.  It answers pings and returns
+// io.EOF once the peer closes the connection.
+func (c *Conn) Read(p []byte) (int, error) {
+	for c.remaining == 0 {
+		if err := c.nextFrame(); err != nil {
+			return 0, err
+		}
+	}
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.br.Read(p)
+	if c.masked {
+		for i := 0; i < n; i++ {
+			p[i] ^= c.mask[c.maskPos%4]
+			c.maskPos++
+		}
+	}
+	c.remaining -= int64(n)
+	return n, err
+}
+
+// nextFrame reads frame headers, handling control frames, until a data
+// frame starts
+func (c *Conn) nextFrame() error {
+	for {
+		var hdr [2]byte
+		if _, err := io.ReadFull(c.br, hdr[:]); err != nil {
+			return err
+		}
+		op := hdr[0] & 0x0f
+		length := int64(hdr[1] & 0x7f)
+		switch length {
+		case 126:
+			var b [2]byte
+			if _, err := io.ReadFull(c.br, b[:]); err != nil {
+				return err
+			}
+			length = int64(binary.BigEndian.Uint16(b[:]))
+		case 127:
+			var b [8]byte
+			if _, err := io.ReadFull(c.br, b[:]); err != nil {
+				return err
+			}
+			length = int64(binary.BigEndian.Uint64(b[:]))
+			if length < 0 {
+				return ErrBadFrame
+			}
+		}
+		c.masked = hdr[1]&maskBit != 0
+		c.maskPos = 0
+		if c.masked {
+			if _, err := io.ReadFull(c.br, c.mask[:]); err != nil {
+				return err
+			}
+		}
+
+		switch op {
+		case opContinuation, opText, opBinary:
+			c.remaining = length
+			return nil
+		case opClose, opPing, opPong:
+			if length > maxControlPayload || hdr[0]&finBit == 0 {
+				return ErrBadFrame
+			}
+			payload := make([]byte, length)
+			if _, err := io.ReadFull(c.br, payload); err != nil {
+				return err
+			}
+			if c.masked {
+				for i := range payload {
+					payload[i] ^= c.mask[i%4]
+				}
+			}
+			switch op {
+			case opPing:
+				if err := c.writeFrame(opPong, payload); err != nil {
+					return err
+				}
+			case opClose:
+				c.writeClose()
+				return io.EOF
+			}
+		default:
+			return ErrBadFrame
+		}
+	}
+}
+
+// Write sends p as a binary message
+func (c *Conn) Write(p []byte) (int, error) {
+	if err := c.writeFrame(opBinary, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close sends a close message and closes the underlying connection
+func (c *Conn) Close() error {
+	c.writeClose()
+	return c.rwc.Close()
+}
+
+func (c *Conn) writeClose() {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, closeNormal)
+	c.writeFrame(opClose, b)
+}
+
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return io.ErrClosedPipe
+	}
+	if op == opClose {
+		c.closed = true
+	}
+	buf := []byte{finBit | op}
+	var lenByte byte
+	if c.client {
+		lenByte = maskBit
+	}
+	switch n := len(payload); {
+	case n < 126:
+		buf = append(buf, lenByte|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, lenByte|126, 0, 0)
+		binary.BigEndian.PutUint16(buf[2:], uint16(n))
+	default:
+		buf = append(buf, lenByte|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(buf[2:], uint64(n))
+	}
+	if c.client {
+		var mask [4]byte
+		rand.Read(mask[:])
+		buf = append(buf, mask[:]...)
+		start := len(buf)
+		buf = append(buf, payload...)
+		for i := range buf[start:] {
+			buf[start+i] ^= mask[i%4]
+		}
+	} else {
+		buf = append(buf, payload...)
+	}
+	_, err := c.rwc.Write(buf)
+	return err
+}
+
+// headerContains reports whether a comma separated header has a token
+func headerContains(h http.Header, name string, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package websocket
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func dial(t *testing.T, url string, origin string) (*Conn, *http.Response) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := SetHandshakeHeaders(req)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, resp
+	}
+	if err := CheckHandshake(resp, key); err != nil {
+		t.Fatal(err)
+	}
+	return NewClientConn(resp.Body.(io.ReadWriteCloser)), resp
+}
+
+func TestEcho(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, http.Header{"X-Test": {"1"}})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(conn, conn)
+	}))
+	defer srv.Close()
+
+	conn, resp := dial(t, srv.URL, "")
+	if conn == nil {
+		t.Fatalf("expected upgrade; received %s", resp.Status)
+	}
+	if resp.Header.Get("X-Test") != "1" {
+		t.Error("expected the handshake header")
+	}
+	large := bytes.Repeat([]byte("x"), 70000)
+	for _, msg := range [][]byte{[]byte("hello"), large} {
+		if _, err := conn.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, len(msg))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf, msg) {
+			t.Errorf("expected %d echoed bytes", len(msg))
+		}
+	}
+	if err := conn.writeFrame(opPing, []byte("p")); err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte("after ping"))
+	buf := make([]byte, 10)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "after ping" {
+		t.Errorf("expected data after pong; received %q %v", buf, err)
+	}
+	conn.writeClose()
+	if _, err := conn.Read(buf); err != io.EOF {
+		t.Errorf("expected EOF after close; received %v", err)
+	}
+}
+
+func TestUpgradeChecksOrigin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, nil)
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	conn, resp := dial(t, srv.URL, "http://evil.example.com")
+	if conn != nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected forbidden; received %s", resp.Status)
+	}
+	conn, _ = dial(t, srv.URL, srv.URL)
+	if conn == nil {
+		t.Fatal("expected an upgrade from the same origin")
+	}
+	conn.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	SetHandshakeHeaders(req)
+	req.Header.Del("Origin")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected forbidden without an origin; received %s", resp.Status)
+	}
+
+	resp, err = http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected bad request without a handshake; received %s", resp.Status)
+	}
+}
@@ -0,0 +1,480 @@
+package shipyard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a non blank line of a yaml document without its comment
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// yamlParser parses the block subset of yaml used by compose files:
+// mappings, sequences, plain and quoted scalars and single line flow
+// collections.  Scalars are returned as strings, mappings as
+// map[string]interface{} and sequences as []interface{}.
+type yamlParser struct {
+	lines []*yamlLine
+	pos   int
+}
+
+func parseYAML(data []byte) (interface{}, error) {
+	p := &yamlParser{}
+	for i, l := range strings.Split(string(data), "\n") {
+		l = strings.TrimRight(stripYAMLComment(l), " \r")
+		trimmed := strings.TrimLeft(l, " ")
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		p.lines = append(p.lines, &yamlLine{num: i + 1, indent: len(l) - len(trimmed), text: trimmed})
+	}
+	if len(p.lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	v, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return v, nil
+}
+
+func (p *yamlParser) parseBlock(indent int) (interface{}, error) {
+	if isYAMLSequenceItem(p.lines[p.pos].text) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func (p *yamlParser) parseMapping(indent int) (interface{}, error) {
+	m := map[string]interface{}{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent || isYAMLSequenceItem(l.text) {
+			return nil, fmt.Errorf("line %d: unexpected indentation", l.num)
+		}
+		key, rest, ok := splitYAMLKey(l.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected a key", l.num)
+		}
+		k, err := parseYAMLScalar(key)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", l.num, err)
+		}
+		if _, exists := m[k]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %s", l.num, k)
+		}
+		p.pos++
+		if rest != "" {
+			v, err := parseYAMLValue(rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", l.num, err)
+			}
+			m[k] = v
+			continue
+		}
+		m[k] = nil
+		if p.pos == len(p.lines) {
+			continue
+		}
+		next := p.lines[p.pos]
+		// sequences may be indented like the key they belong to
+		if next.indent > indent || (next.indent == indent && isYAMLSequenceItem(next.text)) {
+			v, err := p.parseBlock(next.indent)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+	}
+	return m, nil
+}
+
+func (p *yamlParser) parseSequence(indent int) (interface{}, error) {
+	s := []interface{}{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent || (l.indent == indent && !isYAMLSequenceItem(l.text)) {
+			break
+		}
+		if l.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", l.num)
+		}
+		item := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+		if item == "" {
+			p.pos++
+			if p.pos == len(p.lines) || p.lines[p.pos].indent <= indent {
+				s = append(s, nil)
+				continue
+			}
+			v, err := p.parseBlock(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, v)
+			continue
+		}
+		if _, _, ok := splitYAMLKey(item); ok {
+			// a mapping starting on the line of the item; its other keys
+			// are aligned with the first one
+			l.indent += len(l.text) - len(item)
+			l.text = item
+			v, err := p.parseMapping(l.indent)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, v)
+			continue
+		}
+		v, err := parseYAMLValue(item)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", l.num, err)
+		}
+		s = append(s, v)
+		p.pos++
+	}
+	return s, nil
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits "key: value" on the first colon outside quotes that
+// ends the text or is followed by a space
+func splitYAMLKey(text string) (string, string, bool) {
+	if strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+		return "", "", false
+	}
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && i == 0:
+			quote = c
+		case c == ':' && (i == len(text)-1 || text[i+1] == ' '):
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// stripYAMLComment removes a comment starting with a # at the beginning of
+// the line or after a space outside quotes
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || line[i-1] == ' ' || line[i-1] == '[' || line[i-1] == ',' || line[i-1] == ':' || line[i-1] == '-' {
+				quote = c
+			}
+		case c == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func parseYAMLValue(text string) (interface{}, error) {
+	switch {
+	case text == "|" || text == ">" || strings.HasPrefix(text, "|") || strings.HasPrefix(text, ">"):
+		return nil, fmt.Errorf("block scalars are not supported")
+	case strings.HasPrefix(text, "&") || strings.HasPrefix(text, "*"):
+		return nil, fmt.Errorf("anchors and aliases are not supported")
+	case strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{"):
+		f := &yamlFlow{text: text}
+		v, err := f.parse()
+		if err != nil {
+			return nil, err
+		}
+		if f.skipSpace(); f.pos != len(f.text) {
+			return nil, fmt.Errorf("unexpected %q after flow collection", f.text[f.pos:])
+		}
+		return v, nil
+	}
+	return parseYAMLScalar(text)
+}
+
+func parseYAMLScalar(text string) (string, error) {
+	switch {
+	case text == "~" || text == "null":
+		return "", nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return "", fmt.Errorf("unterminated string %s", text)
+		}
+		return strings.Replace(text[1:len(text)-1], "''", "'", -1), nil
+	case strings.HasPrefix(text, "\""):
+		if len(text) < 2 || !strings.HasSuffix(text, "\"") {
+			return "", fmt.Errorf("unterminated string %s", text)
+		}
+		return unescapeYAML(text[1 : len(text)-1])
+	}
+	return text, nil
+}
+
+func unescapeYAML(s string) (string, error) {
+	b := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i++; i == len(s) {
+			return "", fmt.Errorf("invalid escape at end of string")
+		}
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\', '/':
+			b.WriteByte(s[i])
+		default:
+			return "", fmt.Errorf("unsupported escape \\%c", s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// yamlFlow parses a single line flow collection such as [a, "b"] or
+// {a: 1}
+type yamlFlow struct {
+	text string
+	pos  int
+}
+
+func (f *yamlFlow) skipSpace() {
+	for f.pos < len(f.text) && f.text[f.pos] == ' ' {
+		f.pos++
+	}
+}
+
+func (f *yamlFlow) parse() (interface{}, error) {
+	f.skipSpace()
+	if f.pos == len(f.text) {
+		return nil, fmt.Errorf("unterminated flow collection")
+	}
+	switch f.text[f.pos] {
+	case '[':
+		f.pos++
+		s := []interface{}{}
+		for {
+			f.skipSpace()
+			if f.pos < len(f.text) && f.text[f.pos] == ']' {
+				f.pos++
+				return s, nil
+			}
+			v, err := f.parse()
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, v)
+			if err := f.separator(']'); err != nil {
+				return nil, err
+			}
+		}
+	case '{':
+		f.pos++
+		m := map[string]interface{}{}
+		for {
+			f.skipSpace()
+			if f.pos < len(f.text) && f.text[f.pos] == '}' {
+				f.pos++
+				return m, nil
+			}
+			k, err := f.scalar(":")
+			if err != nil {
+				return nil, err
+			}
+			if f.pos == len(f.text) || f.text[f.pos] != ':' {
+				return nil, fmt.Errorf("expected : after %s", k)
+			}
+			f.pos++
+			v, err := f.parse()
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+			if err := f.separator('}'); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return f.scalar("")
+}
+
+// separator consumes a comma or leaves the closing bracket of the
+// collection for the caller
+func (f *yamlFlow) separator(end byte) error {
+	f.skipSpace()
+	switch {
+	case f.pos == len(f.text):
+		return fmt.Errorf("unterminated flow collection")
+	case f.text[f.pos] == ',':
+		f.pos++
+	case f.text[f.pos] != end:
+		return fmt.Errorf("expected , or %c", end)
+	}
+	return nil
+}
+
+func (f *yamlFlow) scalar(extraEnd string) (string, error) {
+	f.skipSpace()
+	start := f.pos
+	if f.pos < len(f.text) && (f.text[f.pos] == '"' || f.text[f.pos] == '\'') {
+		q := f.text[f.pos]
+		f.pos++
+		for f.pos < len(f.text) {
+			if f.text[f.pos] == '\\' && q == '"' {
+				f.pos += 2
+				continue
+			}
+			if f.text[f.pos] == q {
+				// '' escapes a quote in single quoted strings
+				if q == '\'' && f.pos+1 < len(f.text) && f.text[f.pos+1] == '\'' {
+					f.pos += 2
+					continue
+				}
+				f.pos++
+				return parseYAMLScalar(f.text[start:f.pos])
+			}
+			f.pos++
+		}
+		return "", fmt.Errorf("unterminated string %s", f.text[start:])
+	}
+	for f.pos < len(f.text) && !strings.ContainsRune(",]}"+extraEnd, rune(f.text[f.pos])) {
+		f.pos++
+	}
+	return parseYAMLScalar(strings.TrimSpace(f.text[start:f.pos]))
+}
+
+// MarshalYAML encodes the json encoding of v as a block yaml document
+// parseYAML reads back: objects as mappings with sorted keys, arrays as
+// sequences and strings quoted only when they would read as another type
+func MarshalYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	b := &bytes.Buffer{}
+	if s, ok := yamlInline(doc); ok {
+		b.WriteString(s + "\n")
+	} else {
+		writeYAMLBlock(b, doc, 0)
+	}
+	return b.Bytes(), nil
+}
+
+// yamlInline returns scalars and empty collections as they are written
+// after a key or item marker
+func yamlInline(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case nil:
+		return "null", true
+	case bool:
+		return strconv.FormatBool(v), true
+	case json.Number:
+		return v.String(), true
+	case string:
+		return quoteYAML(v), true
+	case map[string]interface{}:
+		return "{}", len(v) == 0
+	case []interface{}:
+		return "[]", len(v) == 0
+	}
+	return "", false
+}
+
+// writeYAMLBlock writes a non empty mapping or sequence indented by indent
+// spaces
+func writeYAMLBlock(b *bytes.Buffer, v interface{}, indent int) {
+	pad := strings.Repeat(" ", indent)
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := []string{}
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b.WriteString(pad + quoteYAML(k) + ":")
+			if s, ok := yamlInline(v[k]); ok {
+				b.WriteString(" " + s + "\n")
+				continue
+			}
+			b.WriteString("\n")
+			writeYAMLBlock(b, v[k], indent+2)
+		}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := yamlInline(item); ok {
+				b.WriteString(pad + "- " + s + "\n")
+				continue
+			}
+			if _, ok := item.(map[string]interface{}); ok {
+				// the first key goes on the line of the item marker
+				nested := &bytes.Buffer{}
+				writeYAMLBlock(nested, item, indent+2)
+				b.WriteString(pad + "- ")
+				b.Write(nested.Bytes()[indent+2:])
+				continue
+			}
+			b.WriteString(pad + "-\n")
+			writeYAMLBlock(b, item, indent+2)
+		}
+	}
+}
+
+// quoteYAML double quotes strings that are not plain scalars of
+// themselves
+func quoteYAML(s string) string {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return strconv.Quote(s)
+	}
+	for _, r := range s {
+		if r < ' ' || r == 0x7f {
+			return strconv.Quote(s)
+		}
+	}
+	switch strings.ToLower(s) {
+	case "~", "null", "true", "false", "yes", "no", "on", "off":
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	return s
+}
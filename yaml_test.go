@@ -0,0 +1,75 @@
+package shipyard
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMarshalYAML(t *testing.T) {
+	v := []interface{}{
+		map[string]interface{}{
+			"name":   "web",
+			"count":  2,
+			"labels": map[string]string{"env": "prod", "note": "a: b"},
+			"ports":  []int{80, 443},
+			"empty":  []string{},
+			"nested": [][]string{{"a"}},
+		},
+		"true",
+		"",
+		nil,
+	}
+	data, err := MarshalYAML(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `- count: 2
+  empty: []
+  labels:
+    env: prod
+    note: "a: b"
+  name: web
+  nested:
+    -
+      - a
+  ports:
+    - 80
+    - 443
+- "true"
+- ""
+- null
+`
+	if string(data) != expected {
+		t.Fatalf("expected\n%s\nreceived\n%s", expected, data)
+	}
+	parsed, err := parseYAML(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back := []interface{}{
+		map[string]interface{}{
+			"name":   "web",
+			"count":  "2",
+			"labels": map[string]interface{}{"env": "prod", "note": "a: b"},
+			"ports":  []interface{}{"80", "443"},
+			"empty":  []interface{}{},
+			"nested": []interface{}{[]interface{}{"a"}},
+		},
+		"true",
+		"",
+		"",
+	}
+	if !reflect.DeepEqual(parsed, back) {
+		t.Errorf("expected %#v; received %#v", back, parsed)
+	}
+}
+
+func TestMarshalYAMLScalar(t *testing.T) {
+	data, err := MarshalYAML("- item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "\"- item\"\n" {
+		t.Errorf("unexpected yaml %q", data)
+	}
+}